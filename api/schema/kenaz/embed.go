@@ -0,0 +1,10 @@
+// Package kenaz embeds the generated OpenAPI specification so the server can
+// serve it without depending on the source tree at runtime.
+package kenaz
+
+import _ "embed"
+
+// OpenAPIYAML is the OpenAPI 3.1 spec generated by `make openapi`.
+//
+//go:embed openapi.yaml
+var OpenAPIYAML []byte
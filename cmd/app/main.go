@@ -3,14 +3,19 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/starford/kenaz/internal"
+	"github.com/starford/kenaz/internal/backup"
 	"github.com/starford/kenaz/internal/index"
 	"github.com/starford/kenaz/internal/mcpserver"
 	"github.com/starford/kenaz/internal/noteservice"
-	"github.com/starford/kenaz/internal/storage"
 	pkgconfig "github.com/starford/kenaz/pkg/config"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/urfave/cli/v3"
@@ -42,18 +47,24 @@ func runMCP(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return err
 	}
+	if cmd.Bool("http") {
+		if err := internal.Run(ctx, internal.WithConfig(cfg), internal.WithMCPOverHTTP()); err != nil {
+			return fmt.Errorf("app run error: %w", err)
+		}
+		return nil
+	}
 
 	// Ensure vault exists.
 	if err := os.MkdirAll(cfg.Vault.Path, 0o755); err != nil {
 		return fmt.Errorf("create vault dir: %w", err)
 	}
 
-	store, err := storage.NewFS(cfg.Vault.Path, cfg.Vault.IgnoreDirs)
+	store, err := cfg.Vault.Storage()
 	if err != nil {
 		return fmt.Errorf("init storage: %w", err)
 	}
 
-	db, err := index.Open(cfg.SQLite.Path)
+	db, err := index.Open(cfg.SQLite.Path, cfg.IndexOptions()...)
 	if err != nil {
 		return fmt.Errorf("init index: %w", err)
 	}
@@ -61,13 +72,184 @@ func runMCP(ctx context.Context, cmd *cli.Command) error {
 
 	// Sync index before serving MCP.
 	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	var callLogger *slog.Logger
+	if cfg.MCP.LogFile != "" {
+		f, err := os.OpenFile(cfg.MCP.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("open mcp log file: %w", err)
+		}
+		defer f.Close()
+		logger = slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{Level: cfg.App.LogLevel}))
+		callLogger = logger
+	}
 	index.Sync(db, store, logger)
 
-	svc := noteservice.NewService(store, db)
-	srv := mcpserver.New(svc, store)
+	svc := noteservice.NewService(store, db, cfg.ServiceOptions()...)
+	mcpOpts := []mcpserver.Option{mcpserver.WithTools(cfg.MCP.Tools)}
+	if callLogger != nil {
+		mcpOpts = append(mcpOpts, mcpserver.WithLogger(callLogger))
+	}
+	if p := cfg.Attachments.Pipeline(); p != nil {
+		mcpOpts = append(mcpOpts, mcpserver.WithPipeline(p))
+	}
+	if cfg.Attachments.ContentAddressed {
+		mcpOpts = append(mcpOpts, mcpserver.WithContentAddressing())
+	}
+	srv := mcpserver.New(svc, store, mcpOpts...)
 	return srv.ServeStdio()
 }
 
+func runNew(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	title := strings.Join(cmd.Args().Slice(), " ")
+	var content []byte
+	if cmd.Bool("stdin") {
+		if content, err = io.ReadAll(os.Stdin); err != nil {
+			return fmt.Errorf("read stdin: %w", err)
+		}
+	}
+	if title == "" && len(content) == 0 {
+		return fmt.Errorf("usage: kenaz new <title> (or pipe content with a title and pass --stdin)")
+	}
+
+	if err := os.MkdirAll(cfg.Vault.Path, 0o755); err != nil {
+		return fmt.Errorf("create vault dir: %w", err)
+	}
+	store, err := cfg.Vault.Storage()
+	if err != nil {
+		return fmt.Errorf("init storage: %w", err)
+	}
+	db, err := index.Open(cfg.SQLite.Path, cfg.IndexOptions()...)
+	if err != nil {
+		return fmt.Errorf("init index: %w", err)
+	}
+	defer db.Close()
+
+	svc := noteservice.NewService(store, db, cfg.ServiceOptions()...)
+	note, err := svc.CreateNoteTitled(ctx, title, cmd.String("folder"), content)
+	if err != nil {
+		return fmt.Errorf("create note: %w", err)
+	}
+	fmt.Println(note.Path)
+	return nil
+}
+
+func runReindex(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cfg.Vault.Path, 0o755); err != nil {
+		return fmt.Errorf("create vault dir: %w", err)
+	}
+	store, err := cfg.Vault.Storage()
+	if err != nil {
+		return fmt.Errorf("init storage: %w", err)
+	}
+	db, err := index.Open(cfg.SQLite.Path, cfg.IndexOptions()...)
+	if err != nil {
+		return fmt.Errorf("init index: %w", err)
+	}
+	defer db.Close()
+
+	// Interrupting stops between batches, leaving the rest for the next sync.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p, err := index.Reindex(ctx, db, store, logger, func(p index.ReindexProgress) {
+		fmt.Fprintf(os.Stderr, "\rreindexed %d/%d notes", p.Processed, p.Total)
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return fmt.Errorf("reindex: %w", err)
+	}
+	for _, e := range p.Errors {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", e.Path, e.Error)
+	}
+	if len(p.Errors) > 0 {
+		return fmt.Errorf("reindex: %d of %d notes failed", len(p.Errors), p.Total)
+	}
+	return nil
+}
+
+func runBackup(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cfg.Vault.Path, 0o755); err != nil {
+		return fmt.Errorf("create vault dir: %w", err)
+	}
+	store, err := cfg.Vault.Storage()
+	if err != nil {
+		return fmt.Errorf("init storage: %w", err)
+	}
+	db, err := index.Open(cfg.SQLite.Path, cfg.IndexOptions()...)
+	if err != nil {
+		return fmt.Errorf("init index: %w", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	info, err := backup.Create(ctx, store, db, cfg.Backup.Policy(), cmd.Bool("index"))
+	if err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	for _, name := range info.Removed {
+		fmt.Fprintf(os.Stderr, "removed old backup %s\n", name)
+	}
+	fmt.Println(filepath.Join(cfg.Backup.Dir, info.Name))
+	return nil
+}
+
+func runRestore(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("usage: kenaz restore <archive>")
+	}
+	if cfg.Vault.ReadOnly {
+		return fmt.Errorf("restore: the vault is read-only (vault.read_only)")
+	}
+	if err := os.MkdirAll(cfg.Vault.Path, 0o755); err != nil {
+		return fmt.Errorf("create vault dir: %w", err)
+	}
+	store, err := cfg.Vault.Storage()
+	if err != nil {
+		return fmt.Errorf("init storage: %w", err)
+	}
+	indexPath := ""
+	if cmd.Bool("index") {
+		indexPath = cfg.SQLite.Path
+	}
+	res, err := backup.Restore(ctx, cmd.Args().First(), store, indexPath)
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "restored %d files\n", res.Files)
+	for _, e := range res.Trashed {
+		fmt.Fprintf(os.Stderr, "moved to trash: %s\n", e.Path)
+	}
+
+	// Bring the index up to date with the restored files, and record the
+	// trashed ones so they can be restored from the trash.
+	db, err := index.Open(cfg.SQLite.Path, cfg.IndexOptions()...)
+	if err != nil {
+		return fmt.Errorf("init index: %w", err)
+	}
+	defer db.Close()
+	if err := index.Sync(db, store, slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		return err
+	}
+	return db.AddTrash(res.Trashed)
+}
+
 var configFlag = &cli.StringFlag{
 	Name:        "config",
 	Aliases:     []string{"c"},
@@ -101,8 +283,44 @@ func main() {
 				Name:   "mcp",
 				Usage:  "Start the MCP server on stdio for LLM integration",
 				Action: runMCP,
+				Flags: []cli.Flag{configFlag, &cli.BoolFlag{
+					Name:  "http",
+					Usage: "Serve MCP over HTTP at /mcp on app.http.port, alongside the REST API, instead of stdio",
+				}},
+			},
+			{
+				Name:      "new",
+				Usage:     "Create a note named after its title (Cyrillic is transliterated) and print its path",
+				ArgsUsage: "<title>",
+				Action:    runNew,
+				Flags: []cli.Flag{configFlag,
+					&cli.StringFlag{Name: "folder", Usage: "Folder to create the note in"},
+					&cli.BoolFlag{Name: "stdin", Usage: "Read the note content from stdin; the title defaults to its title"},
+				},
+			},
+			{
+				Name:   "reindex",
+				Usage:  "Drop and rebuild the index from the vault, printing progress and the notes that failed",
+				Action: runReindex,
 				Flags:  []cli.Flag{configFlag},
 			},
+			{
+				Name:   "backup",
+				Usage:  "Write a zip of the vault and the index to backup.dir, print its path, and delete old backups",
+				Action: runBackup,
+				Flags: []cli.Flag{configFlag,
+					&cli.BoolFlag{Name: "index", Value: true, Usage: "Include the index"},
+				},
+			},
+			{
+				Name:      "restore",
+				Usage:     "Roll the vault back to a backup; files added since are moved to the trash. Stop the server first",
+				ArgsUsage: "<archive>",
+				Action:    runRestore,
+				Flags: []cli.Flag{configFlag,
+					&cli.BoolFlag{Name: "index", Usage: "Also replace the index with the backup's, restoring review history"},
+				},
+			},
 		},
 	}
 
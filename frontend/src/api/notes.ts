@@ -7,6 +7,7 @@ import type { components } from "./schema";
 
 // Re-export schema types used by components.
 export type NoteListItem = components["schemas"]["NoteListItem"];
+export type NoteListResponse = components["schemas"]["NoteListResponse"];
 export type NoteDetail = components["schemas"]["NoteDetail"];
 export type SearchResult = components["schemas"]["SearchResult"];
 export type GraphNode = components["schemas"]["GraphNode"];
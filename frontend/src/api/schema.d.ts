@@ -466,6 +466,8 @@ export interface components {
             source: string;
             /** @example notes/world.md */
             target: string;
+            /** @example 3 */
+            weight?: number;
         };
         GraphNode: {
             /** @example notes/hello.md */
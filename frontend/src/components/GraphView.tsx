@@ -18,8 +18,13 @@ interface GraphNode {
 interface GraphLink {
   source: string | GraphNode;
   target: string | GraphNode;
+  weight: number;
 }
 
+/** Stroke width of an edge: notes that reference each other often get a
+ * thicker line, growing logarithmically so hubs stay readable. */
+const linkWidth = (l: GraphLink) => 1 + Math.log2(Math.max(l.weight, 1));
+
 /** Interactive 2D force-directed graph of notes and their links. */
 export default function GraphView() {
   const { openTab } = useUIStore();
@@ -98,6 +103,7 @@ export default function GraphView() {
       links: data.links.map((l) => ({
         source: l.source,
         target: l.target,
+        weight: l.weight ?? 1,
       })) as GraphLink[],
     }),
     [data],
@@ -116,7 +122,7 @@ export default function GraphView() {
         nodeCanvasObject={nodeCanvasObject as never}
         onNodeClick={handleNodeClick as never}
         linkColor={() => c.border}
-        linkWidth={1}
+        linkWidth={linkWidth as never}
         backgroundColor={c.bgBase}
         width={containerRef.current?.clientWidth ?? 800}
         height={containerRef.current?.clientHeight ?? 600}
@@ -0,0 +1,60 @@
+import { useEffect, useState } from "react";
+
+/**
+ * Directory of the KaTeX distribution (katex.mjs and katex.min.css), loaded
+ * on first use. Set VITE_KATEX_URL to a self-hosted copy, or to an empty
+ * string to leave $math$ as plain text.
+ */
+const katexUrl: string = import.meta.env.VITE_KATEX_URL ?? "https://cdn.jsdelivr.net/npm/katex@0.16/dist";
+
+/** Whether $math$ and ```math fences are typeset. */
+export const mathEnabled = katexUrl !== "";
+
+interface KatexAPI {
+  renderToString(tex: string, options: Record<string, unknown>): string;
+}
+
+let loading: Promise<KatexAPI> | null = null;
+
+function loadKatex(): Promise<KatexAPI> {
+  if (!loading) {
+    const css = document.createElement("link");
+    css.rel = "stylesheet";
+    css.href = `${katexUrl}/katex.min.css`;
+    document.head.appendChild(css);
+    loading = import(/* @vite-ignore */ `${katexUrl}/katex.mjs`).then((mod) => mod.default as KatexAPI);
+    loading.catch(() => {
+      css.remove();
+      loading = null;
+    });
+  }
+  return loading;
+}
+
+/** Typesets a TeX formula, showing the source until KaTeX has loaded. */
+export default function MathExpr({ tex, display }: { tex: string; display: boolean }) {
+  const [html, setHtml] = useState<string | null>(null);
+
+  useEffect(() => {
+    let cancelled = false;
+    loadKatex()
+      .then((katex) => {
+        // throwOnError: false renders invalid TeX in red instead of throwing;
+        // trust stays off, so \href and \htmlId are ignored.
+        const out = katex.renderToString(tex, { displayMode: display, throwOnError: false });
+        if (!cancelled) setHtml(out);
+      })
+      .catch(() => {
+        if (!cancelled) setHtml(null);
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [tex, display]);
+
+  if (html === null) {
+    return <code className="math-source">{display ? `$$${tex}$$` : `$${tex}$`}</code>;
+  }
+  // A span even for display math, which may sit inside a paragraph.
+  return <span className={display ? "math-display" : "math-inline"} dangerouslySetInnerHTML={{ __html: html }} />;
+}
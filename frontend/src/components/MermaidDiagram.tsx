@@ -0,0 +1,75 @@
+import { useEffect, useId, useState } from "react";
+import { Typography } from "antd";
+
+const { Text } = Typography;
+
+/**
+ * ES module build of mermaid, loaded on first use. Set VITE_MERMAID_URL to a
+ * self-hosted copy, or to an empty string to show ```mermaid fences as code.
+ */
+const mermaidUrl: string =
+  import.meta.env.VITE_MERMAID_URL ?? "https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.esm.min.mjs";
+
+/** Whether ```mermaid fences are rendered as diagrams. */
+export const mermaidEnabled = mermaidUrl !== "";
+
+interface MermaidAPI {
+  initialize(config: Record<string, unknown>): void;
+  render(id: string, text: string): Promise<{ svg: string }>;
+}
+
+let loading: Promise<MermaidAPI> | null = null;
+
+function loadMermaid(): Promise<MermaidAPI> {
+  if (!loading) {
+    loading = import(/* @vite-ignore */ mermaidUrl).then((mod) => {
+      const mermaid = mod.default as MermaidAPI;
+      mermaid.initialize({ startOnLoad: false, theme: "dark", securityLevel: "strict" });
+      return mermaid;
+    });
+    loading.catch(() => {
+      loading = null;
+    });
+  }
+  return loading;
+}
+
+/** Renders a mermaid diagram, falling back to its source when it fails. */
+export default function MermaidDiagram({ code }: { code: string }) {
+  const id = "mermaid-" + useId().replace(/[^a-zA-Z0-9]/g, "");
+  const [svg, setSvg] = useState<string | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    let cancelled = false;
+    setError(null);
+    loadMermaid()
+      .then((mermaid) => mermaid.render(id, code))
+      .then((res) => {
+        if (!cancelled) setSvg(res.svg);
+      })
+      .catch((err: unknown) => {
+        if (!cancelled) setError(err instanceof Error ? err.message : String(err));
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [id, code]);
+
+  if (error || svg === null) {
+    return (
+      <>
+        <pre>
+          <code className="language-mermaid">{code}</code>
+        </pre>
+        {error && (
+          <Text type="danger" style={{ fontSize: 12 }}>
+            Diagram failed to render: {error}
+          </Text>
+        )}
+      </>
+    );
+  }
+  // mermaid sanitizes labels itself (securityLevel "strict").
+  return <div className="mermaid-diagram" dangerouslySetInnerHTML={{ __html: svg }} />;
+}
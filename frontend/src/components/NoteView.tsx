@@ -11,6 +11,9 @@ import { useIsMobile } from "../hooks/useIsMobile";
 import { slugify, extractText } from "../utils/slugify";
 import { scrollToHeading } from "../utils/scrollToHeading";
 import MarkdownEditor from "./MarkdownEditor";
+import MermaidDiagram, { mermaidEnabled } from "./MermaidDiagram";
+import MathExpr, { mathEnabled } from "./MathExpr";
+import { extractMath, MATH_DISPLAY, MATH_INLINE } from "../utils/math";
 import { downloadNote } from "../utils/downloadNote";
 import { c } from "../styles/colors";
 
@@ -136,21 +139,27 @@ export default function NoteView({ path }: Props) {
     return () => window.removeEventListener("keydown", handler);
   }, [editing, enterEdit, exitEdit, handleSave]);
 
-  const markdownContent = useMemo(() => {
+  const { markdownContent, formulas } = useMemo(() => {
     const raw = note?.content ?? "";
     // Strip YAML frontmatter in preview mode.
     const withoutFrontmatter = raw.replace(/^---\n[\s\S]*?\n---\n?/, "");
 
+    // Swap $math$ for tokens first so the TeX is not mistaken for wikilinks.
+    const { text, formulas } = mathEnabled
+      ? extractMath(withoutFrontmatter)
+      : { text: withoutFrontmatter, formulas: [] };
+
     // Convert wikilinks to standard markdown links so the renderer creates anchors.
     // [[target]] -> [target](target)
     // [[target|label]] -> [label](target)
-    return withoutFrontmatter.replace(/\[\[(.*?)\]\]/g, (_, inner: string) => {
+    const markdown = text.replace(/\[\[(.*?)\]\]/g, (_, inner: string) => {
       const pipeIdx = inner.indexOf("|");
       const target = (pipeIdx >= 0 ? inner.slice(0, pipeIdx) : inner).trim();
       const label = (pipeIdx >= 0 ? inner.slice(pipeIdx + 1) : inner).trim();
       if (!target) return "";
       return `[${label || target}](${encodeURI(target)})`;
     });
+    return { markdownContent: markdown, formulas };
   }, [note?.content]);
 
   const handlePreviewClickCapture = useCallback(
@@ -286,13 +295,38 @@ export default function NoteView({ path }: Props) {
             <ReactMarkdown
               remarkPlugins={[remarkGfm]}
               components={{
+                pre: ({ children, node }) => {
+                  const code = node?.children[0];
+                  const classes = code?.type === "element" ? code.properties.className : undefined;
+                  if (mermaidEnabled && Array.isArray(classes) && classes.includes("language-mermaid")) {
+                    return <MermaidDiagram code={extractText(children).trimEnd()} />;
+                  }
+                  if (mathEnabled && Array.isArray(classes) && classes.includes("language-math")) {
+                    return <MathExpr tex={extractText(children).trim()} display />;
+                  }
+                  return <pre>{children}</pre>;
+                },
+                code: ({ className, children }) => {
+                  const text = extractText(children);
+                  const display = text.startsWith(MATH_DISPLAY);
+                  if (display || text.startsWith(MATH_INLINE)) {
+                    const tex = formulas[Number(text.slice(1))];
+                    if (tex !== undefined) return <MathExpr tex={tex} display={display} />;
+                  }
+                  return <code className={className}>{children}</code>;
+                },
                 h1: ({ children }) => <h1 id={slugify(extractText(children))}>{children}</h1>,
-                h2: ({ children }) => <h2 id={slugify(extractText(children))}>{children}</h2>,
+                // remark-gfm labels the footnotes section with a visually hidden h2; keep its id and class.
+                h2: ({ children, id, className }) => (
+                  <h2 id={id ?? slugify(extractText(children))} className={className}>
+                    {children}
+                  </h2>
+                ),
                 h3: ({ children }) => <h3 id={slugify(extractText(children))}>{children}</h3>,
                 h4: ({ children }) => <h4 id={slugify(extractText(children))}>{children}</h4>,
                 h5: ({ children }) => <h5 id={slugify(extractText(children))}>{children}</h5>,
                 h6: ({ children }) => <h6 id={slugify(extractText(children))}>{children}</h6>,
-                a: ({ href, children }) => {
+                a: ({ href, children, id }) => {
                   const rawHref = String(href ?? "").trim();
 
                   const renderInternal = (targetRaw: string, labelRaw?: string) => {
@@ -324,9 +358,11 @@ export default function NoteView({ path }: Props) {
                   }
 
                   if (rawHref.startsWith("#")) {
+                    // Footnote references and back-references link to each other by id.
                     const anchorId = rawHref.slice(1);
                     return (
                       <a
+                        id={id}
                         href={rawHref}
                         onClick={(e) => {
                           e.preventDefault();
@@ -1,8 +1,46 @@
 import { useEffect } from "react";
-import { useQueryClient } from "@tanstack/react-query";
+import { useQueryClient, type QueryClient } from "@tanstack/react-query";
+import type { NoteListItem, NoteListResponse } from "../api/notes";
+
+/** Payload of note.created / note.updated / note.deleted. Created and updated
+ * events carry the note's indexed fields; deleted ones only the path. */
+interface NoteEvent {
+  path: string;
+  title?: string;
+  tags?: string[];
+  checksum?: string;
+  updated_at?: string;
+}
+
+/** Writes a created or updated note into the cached notes list. Returns false
+ * when the event lacks the note's fields or there is no list to patch, so
+ * the caller falls back to refetching. */
+function patchNoteList(qc: QueryClient, ev: NoteEvent): boolean {
+  if (!ev.checksum || !ev.updated_at) return false;
+  const item: NoteListItem = {
+    path: ev.path,
+    title: ev.title ?? "",
+    tags: ev.tags ?? [],
+    checksum: ev.checksum,
+    updated_at: ev.updated_at,
+  };
+  let patched = false;
+  qc.setQueryData<NoteListResponse>(["notes"], (list) => {
+    if (!list) return list;
+    patched = true;
+    const i = list.notes.findIndex((n) => n.path === ev.path);
+    if (i < 0) {
+      return { ...list, notes: [item, ...list.notes], total: list.total + 1 };
+    }
+    const notes = list.notes.slice();
+    notes[i] = item;
+    return { ...list, notes };
+  });
+  return patched;
+}
 
 /**
- * Connects to the SSE endpoint and invalidates react-query caches
+ * Connects to the SSE endpoint and keeps react-query caches in sync
  * when the backend reports note changes.
  */
 export function useSSE() {
@@ -13,16 +51,29 @@ export function useSSE() {
     const url = `${base}/events`;
     const es = new EventSource(url);
 
-    es.addEventListener("note.created", () => {
-      qc.invalidateQueries({ queryKey: ["notes"] });
+    const parse = (e: MessageEvent): NoteEvent | null => {
+      try {
+        return JSON.parse(e.data) as NoteEvent;
+      } catch {
+        return null;
+      }
+    };
+
+    es.addEventListener("note.created", (e) => {
+      const ev = parse(e);
+      if (!ev || !patchNoteList(qc, ev)) {
+        qc.invalidateQueries({ queryKey: ["notes"] });
+      }
     });
 
     es.addEventListener("note.updated", (e) => {
-      try {
-        const { path } = JSON.parse(e.data);
-        qc.invalidateQueries({ queryKey: ["note", path] });
+      const ev = parse(e);
+      if (!ev) {
         qc.invalidateQueries({ queryKey: ["notes"] });
-      } catch {
+        return;
+      }
+      qc.invalidateQueries({ queryKey: ["note", ev.path] });
+      if (!patchNoteList(qc, ev)) {
         qc.invalidateQueries({ queryKey: ["notes"] });
       }
     });
@@ -0,0 +1,74 @@
+/** Prefix of the inline code tokens that stand in for extracted formulas. */
+export const MATH_INLINE = "\uE000";
+export const MATH_DISPLAY = "\uE001";
+
+/**
+ * Replaces $inline$ and $$display$$ math outside code with inline code
+ * tokens (MATH_INLINE or MATH_DISPLAY followed by an index into
+ * `formulas`), so the Markdown renderer and the wikilink rewrite leave the
+ * TeX untouched. Uses the same rules as the backend parser: the opening $
+ * must be followed and the closing $ preceded by a non-space, and the
+ * closing $ must not be followed by a digit ("$5 and $10" is not math).
+ */
+export function extractMath(md: string): { text: string; formulas: string[] } {
+  const formulas: string[] = [];
+  const token = (tex: string, display: boolean) => {
+    formulas.push(tex.trim());
+    return "`" + (display ? MATH_DISPLAY : MATH_INLINE) + (formulas.length - 1) + "`";
+  };
+
+  const out: string[] = [];
+  let fence = "";
+  let block: string[] | null = null;
+  for (const line of md.split("\n")) {
+    const trimmed = line.trim();
+    if (block) {
+      const end = line.indexOf("$$");
+      if (end < 0) {
+        block.push(line);
+        continue;
+      }
+      block.push(line.slice(0, end));
+      out.push(token(block.join("\n"), true) + inlineMath(line.slice(end + 2), token));
+      block = null;
+      continue;
+    }
+    if (fence) {
+      if (trimmed.startsWith(fence)) fence = "";
+      out.push(line);
+      continue;
+    }
+    if (trimmed.startsWith("```") || trimmed.startsWith("~~~")) {
+      fence = trimmed.slice(0, 3);
+      out.push(line);
+      continue;
+    }
+    const open = unclosedDisplay(line);
+    if (open >= 0) {
+      out.push(inlineMath(line.slice(0, open), token));
+      block = [line.slice(open + 2)];
+      continue;
+    }
+    out.push(inlineMath(line, token));
+  }
+  if (block) out.push("$$" + block.join("\n"));
+  return { text: out.join("\n"), formulas };
+}
+
+/** Offset of a $$ that opens a multi-line display block on line, or -1. */
+function unclosedDisplay(line: string): number {
+  const stripped = line.replace(/(`+)[\s\S]*?\1/g, (m) => " ".repeat(m.length));
+  const i = stripped.indexOf("$$");
+  if (i < 0 || stripped.indexOf("$$", i + 2) >= 0) return -1;
+  return i;
+}
+
+const inlineRe = /(`+)[\s\S]*?\1|\\.|\$\$([\s\S]+?)\$\$|\$(?![\s$])((?:\\.|[^\\$])*?[^\s\\])\$(?!\d)/g;
+
+function inlineMath(line: string, token: (tex: string, display: boolean) => string): string {
+  return line.replace(inlineRe, (m, _ticks, display?: string, inline?: string) => {
+    if (display !== undefined) return token(display, true);
+    if (inline !== undefined) return token(inline, false);
+    return m;
+  });
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/starford/kenaz/internal/noteservice"
+)
+
+// Calendar handles GET /api/calendar.
+//
+//	@Summary		Per-day note activity for a month
+//	@Description	Returns every day of the month with the number of notes created that day
+//	@Description	(frontmatter created/created_at/date, else first indexed) and the path of its
+//	@Description	daily note, if one exists.
+//	@Tags			activity
+//	@Produce		json
+//	@Param			month	query		string	false	"Month as YYYY-MM (default: current month)"
+//	@Success		200		{object}	CalendarResponse
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/calendar [get]
+func (h *Handler) Calendar(w http.ResponseWriter, r *http.Request) {
+	month := time.Now()
+	if v := r.URL.Query().Get("month"); v != "" {
+		t, err := time.ParseInLocation("2006-01", v, time.Local)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody("month must be YYYY-MM"))
+			return
+		}
+		month = t
+	}
+
+	res, err := h.svc.Calendar(r.Context(), month)
+	if err != nil {
+		slog.Error("calendar failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Timeline handles GET /api/timeline.
+//
+//	@Summary		Notes grouped by day
+//	@Description	Returns a page of notes, newest first, grouped by the day they were last
+//	@Description	modified (by=updated) or created (by=created).
+//	@Tags			activity
+//	@Produce		json
+//	@Param			by		query		string	false	"Date to group by"	Enums(updated, created)
+//	@Param			limit	query		int		false	"Page size (default 50)"
+//	@Param			offset	query		int		false	"Page offset"
+//	@Success		200		{object}	TimelineResponse
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/timeline [get]
+func (h *Handler) Timeline(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	by := q.Get("by")
+	if by != "" && by != noteservice.TimelineByUpdated && by != noteservice.TimelineByCreated {
+		writeJSON(w, http.StatusBadRequest, errorBody("by must be updated or created"))
+		return
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+
+	page, err := h.svc.Timeline(r.Context(), by, limit, offset)
+	if err != nil {
+		slog.Error("timeline failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// maxAnalyticsDays bounds the range of an analytics query.
+const maxAnalyticsDays = 366
+
+// WritingAnalytics handles GET /api/analytics/writing.
+//
+//	@Summary		Writing activity over time
+//	@Description	Returns edits and words added/removed for every day of the range (default: the
+//	@Description	last 30 days), range totals, and the current and longest daily writing streaks.
+//	@Tags			activity
+//	@Produce		json
+//	@Param			from	query		string	false	"First day as YYYY-MM-DD"
+//	@Param			to		query		string	false	"Last day as YYYY-MM-DD (default: today)"
+//	@Success		200		{object}	WritingStatsResponse
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/analytics/writing [get]
+func (h *Handler) WritingAnalytics(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := dayRange(w, r, 30)
+	if !ok {
+		return
+	}
+	st, err := h.svc.WritingStats(r.Context(), from, to)
+	if err != nil {
+		slog.Error("writing analytics failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// Heatmap handles GET /api/analytics/heatmap.
+//
+//	@Summary		Edit activity heatmap for the past year
+//	@Description	Returns edit counts for each of the 365 days ending today (or on to), with a
+//	@Description	0-4 intensity level relative to the busiest day, GitHub-style.
+//	@Tags			activity
+//	@Produce		json
+//	@Param			to	query		string	false	"Last day as YYYY-MM-DD (default: today)"
+//	@Success		200	{object}	HeatmapResponse
+//	@Failure		400	{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/analytics/heatmap [get]
+func (h *Handler) Heatmap(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("from") != "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("the heatmap always covers a year; pass only to"))
+		return
+	}
+	_, to, ok := dayRange(w, r, 1)
+	if !ok {
+		return
+	}
+	hm, err := h.svc.Heatmap(r.Context(), to)
+	if err != nil {
+		slog.Error("heatmap failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, hm)
+}
+
+// dayRange parses the from/to query parameters (YYYY-MM-DD, local time).
+// to defaults to today and from to defaultDays days up to to. On a bad range
+// it writes a 400 and returns ok == false.
+func dayRange(w http.ResponseWriter, r *http.Request, defaultDays int) (from, to time.Time, ok bool) {
+	q := r.URL.Query()
+	now := time.Now()
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var err error
+	if v := q.Get("to"); v != "" {
+		if to, err = time.ParseInLocation(time.DateOnly, v, time.Local); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody("to must be YYYY-MM-DD"))
+			return from, to, false
+		}
+	}
+	from = to.AddDate(0, 0, 1-defaultDays)
+	if v := q.Get("from"); v != "" {
+		if from, err = time.ParseInLocation(time.DateOnly, v, time.Local); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody("from must be YYYY-MM-DD"))
+			return from, to, false
+		}
+	}
+	if from.After(to) || to.Sub(from) >= maxAnalyticsDays*24*time.Hour {
+		writeJSON(w, http.StatusBadRequest, errorBody("from must be on or before to, at most 366 days apart"))
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// UpcomingTasks handles GET /api/tasks/upcoming.
+//
+//	@Summary		Agenda of open tasks
+//	@Description	Groups open checklist items with a due date ("📅 2026-10-20" or "due:2026-10-20")
+//	@Description	across the vault into overdue, due today, and due in the next seven days.
+//	@Tags			activity
+//	@Produce		json
+//	@Param			date	query		string	false	"Reference date as YYYY-MM-DD (default: today)"
+//	@Success		200		{object}	UpcomingTasksResponse
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/tasks/upcoming [get]
+func (h *Handler) UpcomingTasks(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	if v := r.URL.Query().Get("date"); v != "" {
+		t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody("date must be YYYY-MM-DD"))
+			return
+		}
+		now = t
+	}
+	res, err := h.svc.UpcomingTasks(r.Context(), now)
+	if err != nil {
+		slog.Error("upcoming tasks failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// Reindex handles POST /api/admin/reindex.
+//
+//	@Summary		Rebuild the index
+//	@Description	Starts dropping and rebuilding the notes, links, and full-text tables from
+//	@Description	the vault in the background and returns its initial status; poll
+//	@Description	GET /admin/reindex for progress. Review history is kept. Fails with 409
+//	@Description	while a reindex is already running.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		202	{object}	ReindexStatusResponse
+//	@Failure		409	{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/admin/reindex [post]
+func (h *Handler) Reindex(w http.ResponseWriter, r *http.Request) {
+	status, err := h.svc.StartReindex(r.Context())
+	if err != nil {
+		if errors.Is(err, apperr.ErrConflict) {
+			writeJSON(w, http.StatusConflict, errorBody("a reindex is already running"))
+			return
+		}
+		slog.Error("reindex failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// ReindexStatus handles GET /api/admin/reindex.
+//
+//	@Summary		Reindex status
+//	@Description	Returns the progress of the running reindex, or the outcome of the last
+//	@Description	one: notes processed out of the total, and the notes that failed to index.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	ReindexStatusResponse
+//	@Security		BearerAuth
+//	@Router			/admin/reindex [get]
+func (h *Handler) ReindexStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.svc.ReindexStatus(r.Context()))
+}
+
+// IndexStats handles GET /api/admin/index/stats.
+//
+//	@Summary		Index statistics
+//	@Description	Returns the number of notes, links, and full-text entries in the index, the
+//	@Description	database size, when the index was last synced with the vault, the notes per
+//	@Description	folder, and the number of notes in the vault, to check the index against it.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	IndexStatsResponse
+//	@Security		BearerAuth
+//	@Router			/admin/index/stats [get]
+func (h *Handler) IndexStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.svc.IndexStats(r.Context())
+	if err != nil {
+		slog.Error("index stats failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// OptimizeIndex handles POST /api/admin/index/optimize.
+//
+//	@Summary		Optimize the index
+//	@Description	Runs index maintenance now and waits for it: merges the full-text index
+//	@Description	segments and runs PRAGMA optimize, and with vacuum=true also VACUUMs the
+//	@Description	database to return free pages to the disk, which rewrites the whole file.
+//	@Description	Other index writes wait while each step runs. Returns the database size
+//	@Description	before and after.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			vacuum	query		bool	false	"Also VACUUM the database"
+//	@Success		200		{object}	OptimizeIndexResponse
+//	@Security		BearerAuth
+//	@Router			/admin/index/optimize [post]
+func (h *Handler) OptimizeIndex(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.OptimizeIndex(r.Context(), r.URL.Query().Get("vacuum") == "true")
+	if err != nil {
+		slog.Error("index optimize failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// GitPush handles POST /api/admin/git/push.
+//
+//	@Summary		Push the vault history
+//	@Description	Pushes the vault's git history to the configured remote (vault.git.remote)
+//	@Description	and returns the latest commit pushed. Fails with 404 when the vault is not
+//	@Description	versioned with git, 409 when no remote is configured, and 502 when the
+//	@Description	push fails, e.g. because the remote is unreachable.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	GitPushResponse
+//	@Failure		404	{object}	errResponse
+//	@Failure		409	{object}	errResponse
+//	@Failure		502	{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/admin/git/push [post]
+func (h *Handler) GitPush(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.PushVault(r.Context())
+	switch {
+	case errors.Is(err, apperr.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, errorBody("the vault is not versioned with git"))
+	case errors.Is(err, apperr.ErrConflict):
+		writeJSON(w, http.StatusConflict, errorBody("no git remote configured"))
+	case err != nil:
+		slog.Error("git push failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusBadGateway, errorBody("push failed"))
+	default:
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+// Backup handles POST /api/admin/backup.
+//
+//	@Summary		Back up the vault
+//	@Description	Writes a timestamped zip of every note and attachment, and unless
+//	@Description	index=false a snapshot of the index, to the backup directory
+//	@Description	(backup.dir), then deletes the backups the retention policy no longer keeps
+//	@Description	(backup.keep, backup.max_age). Restore with kenaz restore.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			index	query		bool	false	"Include the index (default true)"
+//	@Success		201		{object}	BackupResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/admin/backup [post]
+func (h *Handler) Backup(w http.ResponseWriter, r *http.Request) {
+	info, err := h.svc.Backup(r.Context(), r.URL.Query().Get("index") != "false")
+	switch {
+	case errors.Is(err, apperr.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, errorBody("backups are not configured"))
+	case err != nil:
+		slog.Error("backup failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+	default:
+		writeJSON(w, http.StatusCreated, info)
+	}
+}
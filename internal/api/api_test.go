@@ -1,22 +1,36 @@
 package api
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"gopkg.in/yaml.v3"
+
+	apischema "github.com/starford/kenaz/api/schema/kenaz"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/backup"
+	"github.com/starford/kenaz/internal/checksum"
 	"github.com/starford/kenaz/internal/index"
 	"github.com/starford/kenaz/internal/noteservice"
+	"github.com/starford/kenaz/internal/parser"
 	"github.com/starford/kenaz/internal/storage"
 )
 
@@ -162,6 +176,40 @@ func TestUpdateWithoutIfMatch(t *testing.T) {
 	}
 }
 
+func TestUpdateRequireIfMatch(t *testing.T) {
+	store, err := storage.NewFS(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	svc := noteservice.NewService(store, db, noteservice.WithRequireIfMatch(true))
+	router := NewRouter(svc, false, "", nil, t.TempDir())
+
+	note, err := svc.CreateNote(context.Background(), "r.md", []byte("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"content":"v2"}`
+	req := httptest.NewRequest(http.MethodPut, "/notes/r.md", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionRequired {
+		t.Errorf("update without If-Match = %d, want 428", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/notes/r.md", strings.NewReader(body))
+	req.Header.Set("If-Match", `"`+note.Checksum+`"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("update with If-Match = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
 func TestDeleteNote(t *testing.T) {
 	_, router := testEnv(t, "")
 
@@ -243,6 +291,117 @@ func TestListNotes(t *testing.T) {
 	}
 }
 
+func TestDateFilters(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	body, _ := json.Marshal(map[string]string{"path": "a.md", "content": "datetoken"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+
+	now := time.Now()
+	tomorrow := now.AddDate(0, 0, 1).Format(time.DateOnly)
+	hourAgo := url.QueryEscape(now.Add(-time.Hour).Format(time.RFC3339))
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"updated_after=" + tomorrow, 0},
+		{"updated_before=" + tomorrow, 1},
+		{"created_after=" + hourAgo, 1},
+		{"created_before=" + hourAgo, 0},
+		{"created_before=" + tomorrow, 1},
+		{"updated_after=" + hourAgo + "&updated_before=" + hourAgo, 0},
+	} {
+		for _, target := range []string{"/notes?", "/search?q=datetoken&"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target+tc.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s%s = %d, body = %s", target, tc.query, w.Code, w.Body.String())
+			}
+			var resp struct{ Total int }
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.Total != tc.want {
+				t.Errorf("%s%s total = %d, want %d", target, tc.query, resp.Total, tc.want)
+			}
+		}
+	}
+
+	for _, target := range []string{"/notes?updated_after=yesterday", "/search?q=datetoken&created_after=2024-13-01"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", target, w.Code)
+		}
+	}
+}
+
+func TestCreatedAt(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	for path, content := range map[string]string{
+		"old.md": "---\ncreated: 2024-05-01\n---\nOld\n",
+		"new.md": "New\n",
+	} {
+		body, _ := json.Marshal(map[string]string{"path": path, "content": content})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %s = %d, body = %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes/old.md", nil))
+	var note struct {
+		CreatedAt time.Time `json:"created_at"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if want := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC); !note.CreatedAt.Equal(want) {
+		t.Errorf("note created_at = %v, want %v", note.CreatedAt, want)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes?sort=created_at", nil))
+	var list struct {
+		Notes []struct {
+			Path      string    `json:"path"`
+			CreatedAt time.Time `json:"created_at"`
+		} `json:"notes"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Notes) != 2 || list.Notes[0].Path != "new.md" || list.Notes[1].CreatedAt.Year() != 2024 {
+		t.Errorf("notes by created_at = %+v", list.Notes)
+	}
+}
+
+func TestListNotes_PropertyFilter(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	for name, status := range map[string]string{"a.md": "in-progress", "b.md": "done", "c.md": "todo"} {
+		body, _ := json.Marshal(map[string]string{"path": name, "content": "---\nstatus: " + status + "\n---\n# " + name})
+		req := httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notes?prop.status=in-progress&prop.status=Done&sort=path", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp NoteListResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Total != 2 || len(resp.Notes) != 2 || resp.Notes[0].Path != "b.md" || resp.Notes[1].Path != "a.md" {
+		t.Errorf("filtered = %+v, want b.md and a.md", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/notes?prop.=x", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("empty key = %d, want 400", w.Code)
+	}
+}
+
 func TestSearchEndpoint(t *testing.T) {
 	_, router := testEnv(t, "")
 
@@ -261,7 +420,18 @@ func TestSearchEndpoint(t *testing.T) {
 	_ = json.Unmarshal(w.Body.Bytes(), &resp)
 	results := resp["results"].([]any)
 	if len(results) != 1 {
-		t.Errorf("search results = %d, want 1", len(results))
+		t.Fatalf("search results = %d, want 1", len(results))
+	}
+	hit := results[0].(map[string]any)
+	if hit["path"] != "find.md" {
+		t.Errorf("path = %v, want find.md", hit["path"])
+	}
+	matches, _ := hit["matches"].([]any)
+	if len(matches) == 0 {
+		t.Fatalf("matches = %v, want the body match", hit["matches"])
+	}
+	if m := matches[len(matches)-1].(map[string]any); m["field"] != "body" || m["start"] != 0.0 || m["end"] != 11.0 {
+		t.Errorf("body match = %v, want body 0-11", m)
 	}
 }
 
@@ -296,6 +466,205 @@ func TestGraphEndpoint(t *testing.T) {
 	}
 }
 
+func TestLocalGraphEndpoint(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	for _, n := range []struct{ path, content string }{
+		{"notes/a.md", "links to [[b]]"},
+		{"b.md", "links to [[c]]"},
+		{"c.md", "end"},
+	} {
+		body, _ := json.Marshal(map[string]string{"path": n.path, "content": n.content})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph/local/notes/a.md?depth=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("local graph = %d, body = %s", w.Code, w.Body.String())
+	}
+	var g LocalGraphResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &g)
+	if g.Center != "notes/a.md" || len(g.Nodes) != 2 || g.Nodes[1].ID != "b.md" || g.Nodes[1].Distance != 1 || len(g.Links) != 1 {
+		t.Errorf("local graph = %+v, want a.md and b.md", g)
+	}
+
+	for target, want := range map[string]int{
+		"/graph/local/notes/a.md?depth=0": http.StatusBadRequest,
+		"/graph/local/notes/a.md?depth=x": http.StatusBadRequest,
+		"/graph/local/missing.md":         http.StatusNotFound,
+		"/graph/local/notes/a.md?depth=5": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Errorf("%s = %d, want %d", target, w.Code, want)
+		}
+	}
+}
+
+func TestGraphHealthEndpoint(t *testing.T) {
+	_, router, vaultDir := testEnvWithVault(t, false, "")
+	if err := os.MkdirAll(filepath.Join(vaultDir, "assets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vaultDir, "assets", "Diagram.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []struct{ path, content string }{
+		{"a.md", "---\ntitle: Same\n---\n[[b]], ![[diagram.png]], ![[missing.png]], [[ghost]]"},
+		{"b.md", "---\ntitle: same\n---\nback to [[a]]"},
+		{"lonely.md", "no links"},
+	} {
+		body, _ := json.Marshal(map[string]string{"path": n.path, "content": n.content})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("graph health = %d, body = %s", w.Code, w.Body.String())
+	}
+	var h GraphHealthResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &h)
+	if !slices.Equal(h.Orphans, []string{"lonely.md"}) {
+		t.Errorf("orphans = %v, want [lonely.md]", h.Orphans)
+	}
+	var broken []string
+	for _, l := range h.BrokenLinks {
+		broken = append(broken, l.Target)
+	}
+	if !slices.Equal(broken, []string{"ghost", "missing.png"}) {
+		t.Errorf("broken links = %v, want [ghost missing.png]", broken)
+	}
+	if len(h.DuplicateTitles) != 1 || !slices.Equal(h.DuplicateTitles[0].Paths, []string{"a.md", "b.md"}) {
+		t.Errorf("duplicate titles = %+v, want a.md and b.md", h.DuplicateTitles)
+	}
+}
+
+func TestGraphClustersEndpoint(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	for _, n := range []struct{ path, content string }{
+		{"a.md", "links to [[b]]"},
+		{"b.md", "links to [[a]]"},
+		{"c.md", "no links"},
+	} {
+		body, _ := json.Marshal(map[string]string{"path": n.path, "content": n.content})
+		req := httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph/clusters", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("clusters = %d: %s", w.Code, w.Body.String())
+	}
+	var resp GraphClustersResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Clusters) != 2 || resp.Clusters[0].Size != 2 {
+		t.Fatalf("clusters = %+v", resp.Clusters)
+	}
+	if resp.Assignments["a.md"] != 0 || resp.Assignments["c.md"] != 1 {
+		t.Errorf("assignments = %v", resp.Assignments)
+	}
+
+	// The same assignments on the graph's nodes.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph?clusters=true", nil))
+	var g GraphResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &g)
+	for _, n := range g.Nodes {
+		if n.Cluster == nil || *n.Cluster != resp.Assignments[n.ID] {
+			t.Errorf("node %s cluster = %v, want %d", n.ID, n.Cluster, resp.Assignments[n.ID])
+		}
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph", nil))
+	if strings.Contains(w.Body.String(), `"cluster"`) {
+		t.Errorf("graph without clusters = %s", w.Body.String())
+	}
+}
+
+func TestGraphMetricsEndpoint(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	for _, n := range []struct{ path, content string }{
+		{"a.md", "links to [[b]]"},
+		{"b.md", "b"},
+		{"c.md", "links to [[b]]"},
+	} {
+		body, _ := json.Marshal(map[string]string{"path": n.path, "content": n.content})
+		req := httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("metrics = %d: %s", w.Code, w.Body.String())
+	}
+	var resp GraphMetricsResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Nodes) != 3 || resp.Nodes[0].ID != "b.md" || resp.Nodes[0].InDegree != 2 {
+		t.Fatalf("metrics = %+v, want b.md first with in_degree 2", resp.Nodes)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph", nil))
+	var graph struct {
+		Nodes []GraphNode `json:"nodes"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &graph)
+	for _, n := range graph.Nodes {
+		if n.ID == "b.md" && (n.InDegree != 2 || n.PageRank != resp.Nodes[0].PageRank) {
+			t.Errorf("graph node = %+v, want the metrics of b.md", n)
+		}
+	}
+}
+
+func TestGraphPathEndpoint(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	for _, n := range []struct{ path, content string }{
+		{"a.md", "links to [[b]]"},
+		{"b.md", "b"},
+		{"c.md", "no links"},
+	} {
+		body, _ := json.Marshal(map[string]string{"path": n.path, "content": n.content})
+		req := httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tests := []struct {
+		query string
+		code  int
+	}{
+		{"from=b.md&to=a.md", http.StatusOK},
+		{"from=a.md", http.StatusBadRequest},
+		{"from=a.md&to=c.md", http.StatusNotFound},
+		{"from=a.md&to=zzz.md", http.StatusNotFound},
+		{"from=b.md&to=a.md&limit=3", http.StatusOK},
+		{"from=b.md&to=a.md&limit=0", http.StatusBadRequest},
+		{"from=b.md&to=a.md&limit=21", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graph/path?"+tt.query, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.query, w.Code, tt.code)
+		}
+		if tt.code == http.StatusOK {
+			var resp GraphPathResponse
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+			if !slices.Equal(resp.Nodes, []string{"b.md", "a.md"}) || len(resp.Links) != 1 {
+				t.Errorf("path = %+v", resp)
+			}
+		}
+	}
+}
+
 func TestAuthMiddleware_ValidToken(t *testing.T) {
 	_, router := testEnv(t, "secret123")
 
@@ -377,6 +746,37 @@ func TestSearchMissingQuery(t *testing.T) {
 	}
 }
 
+func TestSearchPaging(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	for _, title := range []string{"Gamma", "alpha", "Beta"} {
+		body, _ := json.Marshal(map[string]string{"path": strings.ToLower(title) + ".md", "content": "# " + title + "\n\npagingtoken"})
+		req := httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=pagingtoken&sort=title&limit=2&offset=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("search = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp SearchResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Total != 3 || len(resp.Results) != 2 || resp.Results[0].Title != "Beta" || resp.Results[1].Title != "Gamma" {
+		t.Errorf("page = %+v, want Beta, Gamma of 3", resp)
+	}
+
+	for _, q := range []string{"sort=size", "offset=-1", "limit=x"} {
+		req := httptest.NewRequest(http.MethodGet, "/search?q=pagingtoken&"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("search %s = %d, want 400", q, w.Code)
+		}
+	}
+}
+
 // SSE endpoint auth tests.
 
 func TestSSEEvents_AuthProtected(t *testing.T) {
@@ -490,6 +890,9 @@ func TestUploadAndServeAttachment(t *testing.T) {
 	if resp["filename"] != "test.png" {
 		t.Errorf("filename = %v", resp["filename"])
 	}
+	if v, _ := resp["versioned_url"].(string); !strings.HasPrefix(v, "/attachments/test.png?v=") {
+		t.Errorf("versioned_url = %v", resp["versioned_url"])
+	}
 
 	// Verify file on disk.
 	data, err := os.ReadFile(filepath.Join(vaultDir, "attachments", "test.png"))
@@ -501,6 +904,53 @@ func TestUploadAndServeAttachment(t *testing.T) {
 	}
 }
 
+func TestServeAttachment_CacheHeaders(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "attachments"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "attachments", "a.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ah := NewAttachmentHandler(dir)
+	r := chi.NewRouter()
+	r.Get("/attachments/{filename}", ah.ServeFile)
+
+	req := httptest.NewRequest(http.MethodGet, "/attachments/a.png", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("serve = %d, etag = %q", w.Code, etag)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != cacheRevalidate {
+		t.Errorf("Cache-Control = %q, want %q", cc, cacheRevalidate)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/attachments/a.png", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", w.Code)
+	}
+
+	sum := strings.Trim(etag, `"`)
+	req = httptest.NewRequest(http.MethodGet, versionedURL("a.png", sum), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if cc := w.Header().Get("Cache-Control"); cc != cacheImmutable {
+		t.Errorf("versioned Cache-Control = %q, want %q", cc, cacheImmutable)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/attachments/a.png?v=0000000000000000", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if cc := w.Header().Get("Cache-Control"); cc != cacheRevalidate {
+		t.Errorf("stale version Cache-Control = %q, want %q", cc, cacheRevalidate)
+	}
+}
+
 func TestServeAttachment_NotFound(t *testing.T) {
 	ah := NewAttachmentHandler(t.TempDir())
 	req := httptest.NewRequest(http.MethodGet, "/attachments/nope.png", nil)
@@ -750,3 +1200,2109 @@ func TestRenameNote_API_AuthProtected(t *testing.T) {
 		t.Errorf("rename with token should not 401, got %d", w.Code)
 	}
 }
+
+func TestSlugify(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "meetings/weekly-standup.md", []byte("# Weekly")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/slugify?title=Weekly+Standup&folder=meetings/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("slugify = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp SlugifyResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Slug != "weekly-standup" || resp.Path != "meetings/weekly-standup-2.md" {
+		t.Errorf("resp = %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/slugify?title=%D0%9A%D0%B5%D0%BD%D0%B0%D0%B7", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Path != "kenaz.md" {
+		t.Errorf("cyrillic path = %q, want kenaz.md", resp.Path)
+	}
+
+	for _, q := range []string{"", "?title=x&folder=../etc"} {
+		req = httptest.NewRequest(http.MethodGet, "/slugify"+q, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("slugify%s = %d, want 400", q, w.Code)
+		}
+	}
+}
+
+func TestCreateNote_FromTitle(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	body := `{"title":"Заметки: план","folder":"meetings","content":"Повестка"}`
+	req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create = %d, body = %s", w.Code, w.Body.String())
+	}
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if note.Path != "meetings/zametki-plan.md" || note.Title != "Заметки: план" {
+		t.Errorf("note = %s %q", note.Path, note.Title)
+	}
+
+	for _, body := range []string{`{"content":"untitled text"}`, `{}`, `{"path":"a.md"}`, `{"title":"x","folder":"../up"}`} {
+		req = httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("create %s = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestGetNoteByID(t *testing.T) {
+	svc, router := testEnv(t, "")
+	note, err := svc.CreateNote(context.Background(), "a.md", []byte("# A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.ID == "" {
+		t.Fatal("CreateNote did not assign an id")
+	}
+	if _, err := svc.RenameNote(context.Background(), "a.md", "moved/a.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notes/by-id/"+note.ID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("by-id = %d, body = %s", w.Code, w.Body.String())
+	}
+	var got NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Path != "moved/a.md" || got.ID != note.ID {
+		t.Errorf("note = %s (%s), want moved/a.md (%s)", got.Path, got.ID, note.ID)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/notes/by-id/nope", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown id = %d, want 404", w.Code)
+	}
+}
+
+func TestVersionHeader(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get(VersionHeader); got != APIVersion {
+		t.Errorf("%s = %q, want %q", VersionHeader, got, APIVersion)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/notes", nil)
+	req.Header.Set(VersionHeader, "v9")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("pinned v9 = %d, want 400", w.Code)
+	}
+}
+
+func TestVersionedMount(t *testing.T) {
+	_, api := testEnv(t, "")
+	r := chi.NewRouter()
+	r.Mount("/api/"+APIVersion, api)
+	r.Mount("/api", api)
+
+	for _, p := range []string{"/api/v1/notes", "/api/notes"} {
+		req := httptest.NewRequest(http.MethodGet, p, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", p, w.Code)
+		}
+	}
+}
+
+func TestDocs(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("openapi.json = %d", w.Code)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec is not JSON: %v", err)
+	}
+	if _, ok := spec["paths"]; !ok {
+		t.Error("spec has no paths")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "swagger-ui") {
+		t.Errorf("docs page = %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "http") || strings.Contains(w.Body.String(), "<script>") {
+		t.Error("docs page must load no remote assets and no inline scripts")
+	}
+	for _, asset := range []string{"/docs/ui/swagger-ui-bundle.js", "/docs/ui/swagger-ui.css", "/docs/init.js"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, asset, nil))
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("%s = %d", asset, w.Code)
+		}
+	}
+}
+
+// TestDocs_SpecCoversRoutes is the drift check for the embedded spec: every
+// route must be documented and every documented operation routed. Run
+// `make openapi` after changing routes or their annotations.
+func TestDocs_SpecCoversRoutes(t *testing.T) {
+	svc, _ := testEnv(t, "")
+	router := NewRouter(svc, false, "", http.NotFoundHandler(), t.TempDir())
+	var spec struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(apischema.OpenAPIYAML, &spec); err != nil {
+		t.Fatal(err)
+	}
+	param := regexp.MustCompile(`\{[^}]+\}`)
+	documented := map[string]bool{}
+	for p, ops := range spec.Paths {
+		for method := range ops {
+			m := strings.ToUpper(method)
+			rctx := chi.NewRouteContext()
+			if !router.Match(rctx, m, param.ReplaceAllString(p, "x")) {
+				t.Errorf("%s %s is documented but not routed", m, p)
+				continue
+			}
+			documented[m+" "+rctx.RoutePattern()] = true
+		}
+	}
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !documented[method+" "+route] && !strings.HasPrefix(route, "/docs") {
+			t.Errorf("%s %s is routed but not documented", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDocs_Auth(t *testing.T) {
+	_, router := testEnv(t, "secret")
+	req := httptest.NewRequest(http.MethodGet, "/docs/openapi.yaml", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("docs without token = %d, want 401", w.Code)
+	}
+}
+
+func TestChunkedUpload(t *testing.T) {
+	_, router, vaultDir := testEnvWithVault(t, false, "")
+	do := func(method, target string, body io.Reader, offset string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, body)
+		if offset != "" {
+			req.Header.Set("Upload-Offset", offset)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/attachments/uploads", strings.NewReader(`{"filename":"big.bin","size":10}`), "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create = %d, body = %s", w.Code, w.Body.String())
+	}
+	var st UploadStatus
+	_ = json.Unmarshal(w.Body.Bytes(), &st)
+	base := "/attachments/uploads/" + st.ID
+
+	if w = do(http.MethodPatch, base, strings.NewReader("hello"), "0"); w.Code != http.StatusOK {
+		t.Fatalf("chunk 1 = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w = do(http.MethodPatch, base, strings.NewReader("again"), "0"); w.Code != http.StatusConflict {
+		t.Errorf("stale offset = %d, want 409", w.Code)
+	}
+	w = do(http.MethodGet, base, nil, "")
+	_ = json.Unmarshal(w.Body.Bytes(), &st)
+	if st.Offset != 5 {
+		t.Errorf("offset = %d, want 5", st.Offset)
+	}
+	if w = do(http.MethodPatch, base, strings.NewReader("world!"), "5"); w.Code != http.StatusBadRequest {
+		t.Errorf("oversized chunk = %d, want 400", w.Code)
+	}
+	if w = do(http.MethodPatch, base, strings.NewReader("world"), "5"); w.Code != http.StatusCreated {
+		t.Fatalf("final chunk = %d, body = %s", w.Code, w.Body.String())
+	}
+	data, err := os.ReadFile(filepath.Join(vaultDir, "attachments", "big.bin"))
+	if err != nil || string(data) != "helloworld" {
+		t.Errorf("finalized file = %q, err = %v", data, err)
+	}
+	if w = do(http.MethodGet, base, nil, ""); w.Code != http.StatusNotFound {
+		t.Errorf("status after finalize = %d, want 404", w.Code)
+	}
+
+	// Images are rewritten by the pipeline, which needs them in memory.
+	if w = do(http.MethodPost, "/attachments/uploads", strings.NewReader(`{"filename":"huge.JPG","size":60000000}`), ""); w.Code != http.StatusBadRequest {
+		t.Errorf("create of a huge image = %d, want 400", w.Code)
+	}
+	if w = do(http.MethodPost, "/attachments/uploads", strings.NewReader(`{"filename":"huge.mp4","size":60000000}`), ""); w.Code != http.StatusCreated {
+		t.Errorf("create of a huge video = %d, want 201", w.Code)
+	}
+
+	w = do(http.MethodPost, "/attachments/uploads", strings.NewReader(`{"filename":"x.bin","size":3}`), "")
+	_ = json.Unmarshal(w.Body.Bytes(), &st)
+	if w = do(http.MethodDelete, "/attachments/uploads/"+st.ID, nil, ""); w.Code != http.StatusNoContent {
+		t.Errorf("cancel = %d, want 204", w.Code)
+	}
+	if w = do(http.MethodGet, "/attachments/uploads/../../etc", nil, ""); w.Code == http.StatusOK {
+		t.Error("non-uuid upload id should not resolve")
+	}
+}
+
+func TestChunkedUpload_OneChunkAtATime(t *testing.T) {
+	_, router, _ := testEnvWithVault(t, false, "")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/attachments/uploads", strings.NewReader(`{"filename":"slow.bin","size":10}`)))
+	var st UploadStatus
+	_ = json.Unmarshal(w.Body.Bytes(), &st)
+	base := "/attachments/uploads/" + st.ID
+
+	// A chunk whose body trickles in holds only its own upload.
+	pr, pw := io.Pipe()
+	done := make(chan int)
+	go func() {
+		req := httptest.NewRequest(http.MethodPatch, base, pr)
+		req.Header.Set("Upload-Offset", "0")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		done <- w.Code
+	}()
+	if _, err := pw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/attachments/uploads", strings.NewReader(`{"filename":"other.bin","size":1}`)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("other upload during a chunk = %d, want 201", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPatch, base, strings.NewReader("x"))
+	req.Header.Set("Upload-Offset", "0")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("concurrent chunk = %d, want 409", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, base, nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("cancel during a chunk = %d, want 409", w.Code)
+	}
+
+	_ = pw.Close()
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("trickled chunk = %d, want 200", code)
+	}
+}
+
+func TestChunkedUpload_StaleSwept(t *testing.T) {
+	_, router, vaultDir := testEnvWithVault(t, false, "")
+	create := func(name string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/attachments/uploads", strings.NewReader(`{"filename":"`+name+`","size":5}`)))
+		var st UploadStatus
+		_ = json.Unmarshal(w.Body.Bytes(), &st)
+		return st.ID
+	}
+	stale := create("stale.bin")
+	old := time.Now().Add(-DefaultUploadTTL - time.Hour)
+	for _, ext := range []string{".json", ".part"} {
+		if err := os.Chtimes(filepath.Join(vaultDir, "attachments", ".uploads", stale+ext), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fresh := create("fresh.bin")
+
+	for id, want := range map[string]int{stale: http.StatusNotFound, fresh: http.StatusOK} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/attachments/uploads/"+id, nil))
+		if w.Code != want {
+			t.Errorf("upload %s = %d, want %d", id, w.Code, want)
+		}
+	}
+}
+
+func TestUploadFromURL(t *testing.T) {
+	_, router, vaultDir := testEnvWithVault(t, false, "")
+	const pixel = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8/5+hHgAHggJ/PchI7wAAAABJRU5ErkJggg=="
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/attachments/from-url", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"url":"data:image/png;base64,` + pixel + `","filename":"pixel.png"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("from-url = %d, body = %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, "attachments", "pixel.png")); err != nil {
+		t.Errorf("file not on disk: %v", err)
+	}
+	if w = post(`{"url":"data:image/png;base64,` + pixel + `","filename":"pixel.png"}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate = %d, want 409", w.Code)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("x"))
+	}))
+	defer srv.Close()
+	if w = post(`{"url":"` + srv.URL + `/a.png"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("loopback url = %d, want 400", w.Code)
+	}
+	if w = post(`{"url":"data:image/png;base64,aGVsbG8=","filename":"fake.png"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("bad magic bytes = %d, want 400", w.Code)
+	}
+}
+
+// rejectScanner flags any content containing "EICAR" as infected.
+type rejectScanner struct{}
+
+func (rejectScanner) Scan(_ context.Context, name string, r io.Reader) error {
+	data, _ := io.ReadAll(r)
+	if bytes.Contains(data, []byte("EICAR")) {
+		return fmt.Errorf("%w: %s: Eicar-Test-Signature", asset.ErrInfected, name)
+	}
+	return nil
+}
+
+func TestUpload_VirusScan(t *testing.T) {
+	vaultDir := t.TempDir()
+	store, err := storage.NewFS(vaultDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	router := NewRouter(noteservice.NewService(store, db), false, "", nil, vaultDir, WithPipeline(&asset.Pipeline{Scanner: rejectScanner{}}))
+
+	if w := uploadFile(t, router, "ok.txt", []byte("clean")); w.Code != http.StatusCreated {
+		t.Errorf("clean upload = %d, body = %s", w.Code, w.Body.String())
+	}
+	w := uploadFile(t, router, "bad.txt", []byte("X5O EICAR"))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "Eicar") {
+		t.Errorf("infected upload = %d, body = %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, "attachments", "bad.txt")); !os.IsNotExist(err) {
+		t.Error("infected file was persisted")
+	}
+}
+
+func TestUpload_ContentAddressed(t *testing.T) {
+	vaultDir := t.TempDir()
+	store, err := storage.NewFS(vaultDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	router := NewRouter(noteservice.NewService(store, db), false, "", nil, vaultDir, WithContentAddressing())
+
+	content := []byte("fake-png-data")
+	name := checksum.Sum(content) + ".png"
+	var first, second AttachmentUploadResponse
+	for i, upload := range []struct {
+		filename string
+		resp     *AttachmentUploadResponse
+	}{{"Photo.PNG", &first}, {"copy.png", &second}} {
+		w := uploadFile(t, router, upload.filename, content)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("upload %d = %d, body = %s", i, w.Code, w.Body.String())
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), upload.resp)
+	}
+	if first.Filename != name || first.URL != "/attachments/"+name || first.OriginalName != "Photo.PNG" {
+		t.Errorf("first upload = %+v, want stored as %s", first, name)
+	}
+	if second.Filename != name || second.OriginalName != "copy.png" {
+		t.Errorf("second upload = %+v, want deduplicated to %s", second, name)
+	}
+	entries, _ := os.ReadDir(filepath.Join(vaultDir, "attachments"))
+	if len(entries) != 2 {
+		t.Errorf("attachments = %v, want the file and the name index", entries)
+	}
+	names, err := asset.OriginalNames(vaultFiles(vaultDir), name)
+	if err != nil || !slices.Equal(names, []string{"Photo.PNG", "copy.png"}) {
+		t.Errorf("OriginalNames = %v, %v", names, err)
+	}
+
+	ah := NewAttachmentHandler(vaultDir)
+	r := chi.NewRouter()
+	r.Get("/attachments/{filename}", ah.ServeFile)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/attachments/"+name, nil))
+	if cc := w.Header().Get("Cache-Control"); w.Code != http.StatusOK || cc != cacheImmutable {
+		t.Errorf("serve = %d, Cache-Control = %q, want %q", w.Code, cc, cacheImmutable)
+	}
+}
+
+func TestUploadAttachment_SVGSanitized(t *testing.T) {
+	_, router, vaultDir := testEnvWithVault(t, false, "")
+
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><script>alert(2)</script><rect width="1" height="1"/></svg>`
+	if w := uploadFile(t, router, "icon.svg", []byte(svg)); w.Code != http.StatusCreated {
+		t.Fatalf("upload = %d, body = %s", w.Code, w.Body.String())
+	}
+	data, err := os.ReadFile(filepath.Join(vaultDir, "attachments", "icon.svg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "alert") || !strings.Contains(string(data), "<rect") {
+		t.Errorf("stored svg = %s", data)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/attachments/{filename}", NewAttachmentHandler(vaultDir).ServeFile)
+	req := httptest.NewRequest(http.MethodGet, "/attachments/icon.svg", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if csp := w.Header().Get("Content-Security-Policy"); csp != asset.SVGContentSecurityPolicy {
+		t.Errorf("Content-Security-Policy = %q", csp)
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("missing X-Content-Type-Options: nosniff")
+	}
+
+	if w := uploadFile(t, router, "bad.svg", []byte("<svg><g></svg>")); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed svg upload = %d, want 400", w.Code)
+	}
+}
+
+func TestPreviewMerge(t *testing.T) {
+	svc, router := testEnv(t, "")
+	base := "---\nid: x\n---\n# T\n\nfirst\nsecond\n"
+	if _, err := svc.CreateNote(context.Background(), "m.md", []byte(base)); err != nil {
+		t.Fatal(err)
+	}
+	current := "---\nid: x\n---\n# T\n\nfirst edited\nsecond\n"
+	if _, err := svc.UpdateNote(context.Background(), "m.md", []byte(current), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	preview := func(content string) (int, MergePreview) {
+		body, _ := json.Marshal(PreviewMergeRequest{Base: base, Content: content})
+		req := httptest.NewRequest(http.MethodPost, "/notes/m.md/preview-merge", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var p MergePreview
+		_ = json.Unmarshal(w.Body.Bytes(), &p)
+		return w.Code, p
+	}
+
+	code, p := preview(base + "third\n")
+	if code != http.StatusOK || !p.Clean || p.Merged != current+"third\n" {
+		t.Errorf("clean merge = %d %+v", code, p)
+	}
+
+	code, p = preview("---\nid: x\n---\n# T\n\nfirst mine\nsecond\n")
+	if code != http.StatusOK || p.Clean || len(p.Conflicts) != 1 {
+		t.Fatalf("conflicting merge = %d %+v", code, p)
+	}
+	if c := p.Conflicts[0]; c.Current != "first edited\n" || c.Incoming != "first mine\n" || c.Line != 6 {
+		t.Errorf("conflict = %+v", c)
+	}
+	note, _ := svc.GetNote(context.Background(), "m.md")
+	if p.Checksum != note.Checksum || note.Content != current {
+		t.Error("preview must not write and must return the current checksum")
+	}
+
+	if code, _ := preview("x"); code != http.StatusOK {
+		t.Errorf("preview = %d", code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/notes/missing.md/preview-merge", strings.NewReader(`{"content":"x"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing note = %d, want 404", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/notes/m.md/bogus", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown action = %d, want 404", w.Code)
+	}
+}
+
+func TestCopyNote(t *testing.T) {
+	svc, router := testEnv(t, "")
+	src := "---\ntitle: Tpl\ncreated_at: \"2020-01-01T00:00:00Z\"\n---\nbody\n"
+	orig, err := svc.CreateNote(context.Background(), "tpl.md", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copyNote := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/notes/tpl.md/copy", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := copyNote(`{"to":"projects/a.md","reset_dates":true}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("copy = %d, body = %s", w.Code, w.Body.String())
+	}
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if note.Path != "projects/a.md" || note.ID == "" || note.ID == orig.ID {
+		t.Errorf("copy path/id = %q/%q (original id %q)", note.Path, note.ID, orig.ID)
+	}
+	if strings.Contains(note.Content, "2020-01-01") || !strings.Contains(note.Content, "body") {
+		t.Errorf("copy content = %q", note.Content)
+	}
+	if got, _ := svc.GetNoteByID(context.Background(), note.ID); got == nil || got.Path != "projects/a.md" {
+		t.Error("copy is not indexed by id")
+	}
+
+	w = copyNote(`{"to":"b.md"}`)
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusCreated || !strings.Contains(note.Content, "2020-01-01") {
+		t.Errorf("copy without reset = %d, content = %q", w.Code, note.Content)
+	}
+
+	w = copyNote(`{"target_path":"weekly/next.md","strip_fields":["created_at","id"]}`)
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusCreated || note.Path != "weekly/next.md" || strings.Contains(note.Content, "created_at") ||
+		!strings.Contains(note.Content, "title: Tpl") || note.ID == "" || note.ID == orig.ID {
+		t.Errorf("copy with strip_fields = %d, id = %q, content = %q", w.Code, note.ID, note.Content)
+	}
+
+	if w := copyNote(`{"to":"b.md"}`); w.Code != http.StatusConflict {
+		t.Errorf("copy onto existing = %d, want 409", w.Code)
+	}
+	if w := copyNote(`{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("copy without to = %d, want 400", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/notes/nope.md/copy", strings.NewReader(`{"to":"c.md"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("copy missing = %d, want 404", w.Code)
+	}
+}
+
+func TestMoveFolder_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	ctx := context.Background()
+	for p, c := range map[string]string{"docs/a.md": "# A", "home.md": "[[docs/a]]"} {
+		if _, err := svc.CreateNote(ctx, p, []byte(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	move := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/folders/move", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w := move(`{"from":"docs","to":"archive/docs"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("move = %d, body = %s", w.Code, w.Body.String())
+	}
+	var res FolderMoveResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	if len(res.Moved) != 1 || res.Moved[0].NewPath != "archive/docs/a.md" || len(res.Rewritten) != 1 || res.Rewritten[0] != "home.md" {
+		t.Errorf("response = %+v", res)
+	}
+
+	if w := move(`{"from":"archive","to":"archive/sub"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("move into itself = %d, want 400", w.Code)
+	}
+	if w := move(`{"from":"docs","to":"x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("move missing = %d, want 404", w.Code)
+	}
+	if w := move(`{"from":"docs"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("move without to = %d, want 400", w.Code)
+	}
+}
+
+func TestDeleteFolder_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "tmp/a.md", []byte("# A")); err != nil {
+		t.Fatal(err)
+	}
+	del := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/folders/tmp"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := del("?dry_run=true")
+	var preview FolderDeleteResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &preview)
+	if w.Code != http.StatusOK || !preview.DryRun || len(preview.Notes) != 1 {
+		t.Fatalf("preview = %d %s", w.Code, w.Body.String())
+	}
+	if w := del(""); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("delete without token = %d, want 428", w.Code)
+	}
+	if w := del("?confirm=bogus"); w.Code != http.StatusConflict {
+		t.Errorf("delete with wrong token = %d, want 409", w.Code)
+	}
+	if w := del("?confirm=" + preview.ConfirmToken); w.Code != http.StatusOK {
+		t.Errorf("delete = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := del("?dry_run=true"); w.Code != http.StatusNotFound {
+		t.Errorf("deleted folder preview = %d, want 404", w.Code)
+	}
+}
+
+func TestUndo_API(t *testing.T) {
+	store, err := storage.NewFS(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	svc := noteservice.NewService(store, db, noteservice.WithUndoWindow(time.Minute))
+	router := NewRouter(svc, false, "", nil, t.TempDir())
+
+	if _, err := svc.CreateNote(context.Background(), "d.md", []byte("keep me")); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodDelete, "/notes/d.md", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	id := w.Header().Get(MutationIDHeader)
+	if w.Code != http.StatusNoContent || id == "" {
+		t.Fatalf("delete = %d, mutation id = %q", w.Code, id)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/undo/"+id, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var m Mutation
+	_ = json.Unmarshal(w.Body.Bytes(), &m)
+	if w.Code != http.StatusOK || m.Kind != noteservice.MutationCreated || m.Path != "d.md" {
+		t.Errorf("undo = %d, body = %s", w.Code, w.Body.String())
+	}
+	if n, err := svc.GetNote(context.Background(), "d.md"); err != nil || !strings.HasSuffix(n.Content, "keep me") {
+		t.Errorf("note not restored: %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/undo/"+id, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("repeated undo = %d, want 404", w.Code)
+	}
+}
+
+func TestCapture_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+
+	for _, text := range []string{"first thought", "second\nline"} {
+		body := `{"text":` + strconv.Quote(text) + `,"tags":["#idea","todo"]}`
+		req := httptest.NewRequest(http.MethodPost, "/capture", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("capture = %d, body = %s", w.Code, w.Body.String())
+		}
+	}
+
+	n, err := svc.GetNote(context.Background(), "inbox.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(n.Content, " first thought #idea #todo\n- ") {
+		t.Errorf("first entry missing:\n%s", n.Content)
+	}
+	if !strings.HasSuffix(n.Content, " second\n  line #idea #todo\n") {
+		t.Errorf("second entry wrong:\n%s", n.Content)
+	}
+	if !slices.Contains(n.Tags, "idea") {
+		t.Errorf("tags = %v, want idea", n.Tags)
+	}
+
+	for _, body := range []string{`{"text":"  "}`, `{"text":"x","tags":["two words"]}`} {
+		req := httptest.NewRequest(http.MethodPost, "/capture", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestAppendDaily_API(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/daily/append", strings.NewReader(`{"text":"standup done"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusOK || !strings.HasPrefix(note.Path, "journal/") || !strings.HasSuffix(note.Content, " standup done\n") {
+		t.Errorf("append = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/daily/append", strings.NewReader(`{"text":""}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("empty text = %d, want 400", w.Code)
+	}
+}
+
+func TestGetDaily_API(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/daily/2025-01-20", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusOK || note.Path != "journal/2025-01-20.md" || !strings.HasSuffix(note.Content, "# 2025-01-20\n\n") {
+		t.Errorf("daily = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/daily/2025-01-20", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var again NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &again)
+	if w.Code != http.StatusOK || again.Checksum != note.Checksum {
+		t.Errorf("second get = %d, checksum %q, want %q", w.Code, again.Checksum, note.Checksum)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/daily/today", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if want := "journal/" + time.Now().Format(time.DateOnly) + ".md"; w.Code != http.StatusOK || note.Path != want {
+		t.Errorf("today = %d, path %q, want %q", w.Code, note.Path, want)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/daily/20-01-2025", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad date = %d, want 400", w.Code)
+	}
+}
+
+func TestAppendNote_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "log.md", []byte("# Log")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/notes/log.md/append", strings.NewReader(`{"content":"- entry"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusOK || !strings.HasSuffix(note.Content, "# Log\n- entry\n") {
+		t.Errorf("append = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/notes/missing.md/append", strings.NewReader(`{"content":"x"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing note = %d, want 404", w.Code)
+	}
+}
+
+func TestAppendNote_Heading(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "todo.md", []byte("## Inbox\n- a\n\n## Done\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/notes/todo.md/append", strings.NewReader(`{"content":"- b","heading":"Inbox"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusOK || !strings.HasSuffix(note.Content, "## Inbox\n- a\n- b\n\n## Done\n") {
+		t.Errorf("append = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/notes/todo.md/append", strings.NewReader(`{"content":"x","heading":"Later"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "heading") {
+		t.Errorf("missing heading = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPatchNote_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "log.md", []byte("## Today\n- old\n\n## Done\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/notes/log.md", strings.NewReader(`{"op":"replace_heading","heading":"## Today","content":"- new"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusOK || !strings.HasSuffix(note.Content, "## Today\n- new\n\n## Done\n") {
+		t.Errorf("patch = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/notes/log.md", strings.NewReader(`{"op":"append","content":"x"}`))
+	req.Header.Set("If-Match", `"stale"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("stale If-Match = %d, want 409", w.Code)
+	}
+
+	for body, want := range map[string]int{
+		`{"op":"rewrite","content":"x"}`:                    http.StatusBadRequest,
+		`{"op":"replace_heading","heading":"Later"}`:        http.StatusNotFound,
+		`{"op":"append","content":"x","heading":"Nowhere"}`: http.StatusNotFound,
+	} {
+		req = httptest.NewRequest(http.MethodPatch, "/notes/log.md", strings.NewReader(body))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s = %d, want %d", body, w.Code, want)
+		}
+	}
+}
+
+func TestCalendar_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	ctx := context.Background()
+	for p, content := range map[string]string{
+		"a.md":                  "---\ncreated: 2025-02-03\n---\nA",
+		"b.md":                  "---\ncreated: 2025-02-03\n---\nB",
+		"journal/2025-02-05.md": "---\ncreated: 2025-02-05\n---\n# 2025-02-05",
+	} {
+		if _, err := svc.CreateNote(ctx, p, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar?month=2025-02", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res CalendarResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("calendar = %d, body = %s", w.Code, w.Body.String())
+	}
+	if res.Month != "2025-02" || len(res.Days) != 28 {
+		t.Fatalf("month = %q with %d days", res.Month, len(res.Days))
+	}
+	if d := res.Days[2]; d.Date != "2025-02-03" || d.Notes != 2 || d.DailyNote != "" {
+		t.Errorf("Feb 3 = %+v", d)
+	}
+	if d := res.Days[4]; d.Notes != 1 || d.DailyNote != "journal/2025-02-05.md" {
+		t.Errorf("Feb 5 = %+v", d)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/calendar?month=feb", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad month = %d, want 400", w.Code)
+	}
+}
+
+func TestUpcomingTasks_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	ctx := context.Background()
+	if _, err := svc.CreateNote(ctx, "plan.md", []byte("# Plan\n"+
+		"- [ ] Late 📅 2026-10-10\n"+
+		"- [ ] Now due:2026-10-16\n"+
+		"- [x] Finished 📅 2026-10-16\n"+
+		"- [ ] Soon 📅 2026-10-23\n"+
+		"- [ ] Far off 📅 2026-11-30\n"+
+		"- [ ] Someday\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/upcoming?date=2026-10-16", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res UpcomingTasksResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("upcoming = %d, body = %s", w.Code, w.Body.String())
+	}
+	if len(res.Overdue) != 1 || res.Overdue[0].Due != "2026-10-10" || res.Overdue[0].Title != "Plan" {
+		t.Errorf("overdue = %+v", res.Overdue)
+	}
+	if len(res.Today) != 1 || res.Today[0].Text != "Now due:2026-10-16" || res.Today[0].Line != 6 {
+		t.Errorf("today = %+v", res.Today)
+	}
+	if len(res.ThisWeek) != 1 || res.ThisWeek[0].Due != "2026-10-23" {
+		t.Errorf("this week = %+v", res.ThisWeek)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks/upcoming?date=tomorrow", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad date = %d, want 400", w.Code)
+	}
+}
+
+func TestTimeline_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	ctx := context.Background()
+	for _, n := range []struct{ path, created string }{
+		{"a.md", "2025-02-03"}, {"b.md", "2025-02-03"}, {"c.md", "2025-01-10"},
+	} {
+		if _, err := svc.CreateNote(ctx, n.path, []byte("---\ncreated: "+n.created+"\n---\n"+n.path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/timeline?by=created&limit=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var page TimelineResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("timeline = %d, body = %s", w.Code, w.Body.String())
+	}
+	if page.Total != 3 || len(page.Groups) != 1 || page.Groups[0].Date != "2025-02-03" || len(page.Groups[0].Notes) != 2 {
+		t.Errorf("page 1 = %+v", page)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/timeline?by=created&limit=2&offset=2", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	page = TimelineResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Groups) != 1 || page.Groups[0].Date != "2025-01-10" || page.Groups[0].Notes[0].Path != "c.md" {
+		t.Errorf("page 2 = %+v", page)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/timeline?by=title", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("by=title = %d, want 400", w.Code)
+	}
+}
+
+func TestWritingAnalytics_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "a.md", []byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/writing", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var st WritingStatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &st); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("analytics = %d, body = %s", w.Code, w.Body.String())
+	}
+	if len(st.Days) != 30 || st.WordsAdded != 2 || st.CurrentStreak != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	for _, q := range []string{"?from=2025-02-01&to=2025-01-01", "?from=2020-01-01&to=2025-01-01", "?to=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/analytics/writing"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", q, w.Code)
+		}
+	}
+}
+
+func TestHeatmap_API(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/heatmap?to=2025-02-01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var hm HeatmapResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &hm); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("heatmap = %d, body = %s", w.Code, w.Body.String())
+	}
+	if hm.From != "2024-02-03" || hm.To != "2025-02-01" || len(hm.Days) != 365 {
+		t.Errorf("heatmap = %s..%s, %d days", hm.From, hm.To, len(hm.Days))
+	}
+}
+
+func TestMetadata_API(t *testing.T) {
+	store, err := storage.NewFS(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls, _ := parser.LookupExtractor("urls")
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"), index.WithExtractors([]parser.Extractor{urls}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	svc := noteservice.NewService(store, db)
+	router := NewRouter(svc, false, "", nil, t.TempDir())
+
+	ctx := context.Background()
+	if _, err := svc.CreateNote(ctx, "a.md", []byte("See https://go.dev and https://example.com.\n")); err != nil {
+		t.Fatal(err)
+	}
+	note, err := svc.CreateNote(ctx, "b.md", []byte("Docs: https://go.dev\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(note.Metadata["urls"], []string{"https://go.dev"}) {
+		t.Errorf("note metadata = %v", note.Metadata)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metadata", nil))
+	var keys MetadataKeysResponse
+	if err := json.NewDecoder(w.Body).Decode(&keys); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys.Keys, []string{"urls"}) {
+		t.Errorf("keys = %v", keys.Keys)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metadata/urls?value=https://go.dev", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var vals MetadataValuesResponse
+	if err := json.NewDecoder(w.Body).Decode(&vals); err != nil {
+		t.Fatal(err)
+	}
+	if len(vals.Values) != 1 || !slices.Equal(vals.Values[0].Paths, []string{"a.md", "b.md"}) {
+		t.Errorf("values = %+v", vals.Values)
+	}
+}
+
+func TestBookmarks_API(t *testing.T) {
+	_, router := testEnv(t, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks",
+		strings.NewReader(`{"url":"https://go.dev/blog","title":"The Go Blog","tags":["golang"],"fetch":false}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var note NoteDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &note); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("create = %d, body = %s", w.Code, w.Body.String())
+	}
+	if note.Path != "bookmarks/the-go-blog.md" {
+		t.Errorf("path = %q", note.Path)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/bookmarks", strings.NewReader(`{"url":"https://go.dev/blog","fetch":false}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate = %d, want 409", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/bookmarks", strings.NewReader(`{"url":"notaurl","fetch":false}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad url = %d, want 400", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/bookmarks/read", strings.NewReader(`{"path":"bookmarks/the-go-blog.md","read":true}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("read = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/bookmarks?site=go.dev", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var list BookmarkListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("list = %d, body = %s", w.Code, w.Body.String())
+	}
+	if list.Total != 1 || !list.Bookmarks[0].Read || list.Bookmarks[0].Tags[1] != "golang" {
+		t.Errorf("list = %+v", list)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/bookmarks?unread=true", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || list.Total != 0 {
+		t.Errorf("unread = %+v", list)
+	}
+}
+
+func TestCitations_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	ctx := context.Background()
+	for p, content := range map[string]string{
+		"references/smith2020.md": "---\ntitle: On Notes & Links\nauthors: [Smith, Jones]\nyear: 2020\njournal: J. Notes\ndoi: 10.1000/n1\n---\n",
+		"references/doe2019.md":   "---\ntitle: Unread\n---\n",
+		"essay.md":                "As shown in [[@smith2020]].\n",
+	} {
+		if _, err := svc.CreateNote(ctx, p, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/citations?note=essay.md", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res CitationsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("citations = %d, body = %s", w.Code, w.Body.String())
+	}
+	if res.Total != 1 || res.References[0].Key != "smith2020" || res.References[0].CitedBy[0] != "essay.md" {
+		t.Errorf("citations = %+v", res)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/citations/bibtex", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/x-bibtex") {
+		t.Fatalf("bibtex = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{"@misc{doe2019,\n", "@article{smith2020,\n", "  title = {On Notes \\& Links},\n", "  author = {Smith and Jones},\n", "  doi = {10.1000/n1},\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("bibtex missing %q:\n%s", want, body)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/citations?note=missing.md", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing note = %d, want 404", w.Code)
+	}
+}
+
+func TestGetNote_Footnotes(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "essay.md", []byte("# Essay\n\nA claim.[^src]\n\n[^src]: The source.\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notes/essay.md", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var got NoteDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("get = %d, body = %s", w.Code, w.Body.String())
+	}
+	if len(got.Footnotes) != 1 || got.Footnotes[0].Label != "src" || got.Footnotes[0].Number != 1 || got.Footnotes[0].Text != "The source." {
+		t.Errorf("footnotes = %+v", got.Footnotes)
+	}
+}
+
+func TestReplace_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "a.md", []byte("# A\n\nold name, old name\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/replace", strings.NewReader(`{"query":"old name","replacement":"new name","dry_run":true}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res ReplaceResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("dry run = %d, body = %s", w.Code, w.Body.String())
+	}
+	if res.Matches != 2 || len(res.Changes) != 1 || !strings.Contains(res.Changes[0].Diff, "+new name, new name") {
+		t.Errorf("dry run = %+v", res)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/replace", strings.NewReader(`{"query":"old name","replacement":"new name"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("apply = %d, body = %s", w.Code, w.Body.String())
+	}
+	if note, _ := svc.GetNote(context.Background(), "a.md"); !strings.Contains(note.Content, "new name, new name") {
+		t.Errorf("content = %q", note.Content)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/replace", strings.NewReader(`{"regex":"(","replacement":"x"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad regex = %d, want 400", w.Code)
+	}
+}
+
+func TestBulkEdit_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "a.md", []byte("---\nstatus: draft\n---\n# A\n\nFiled under #inbox.\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/tags/rename", strings.NewReader(`{"from":"inbox","to":"archive","dry_run":true}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res BulkEditResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("dry run = %d, body = %s", w.Code, w.Body.String())
+	}
+	if len(res.Changes) != 1 || !strings.Contains(res.Changes[0].Diff, "+Filed under #archive.") {
+		t.Errorf("dry run = %+v", res)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/tags/rename", strings.NewReader(`{"from":"inbox","to":"archive"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("rename = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/frontmatter", strings.NewReader(`{"tag":"archive","set":{"status":"done","rank":2},"unset":["missing"]}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("frontmatter edit = %d, body = %s", w.Code, w.Body.String())
+	}
+	note, _ := svc.GetNote(context.Background(), "a.md")
+	if !strings.Contains(note.Content, "#archive") || note.Frontmatter["status"] != "done" || note.Frontmatter["rank"] != 2 {
+		t.Errorf("note = %q, frontmatter = %v", note.Content, note.Frontmatter)
+	}
+
+	for path, body := range map[string]string{
+		"/tags/rename": `{"from":"a b","to":"x"}`,
+		"/frontmatter": `{"set":{"id":"x"}}`,
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s = %d, want 400", path, body, w.Code)
+		}
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/frontmatter", strings.NewReader(`{"paths":["missing.md"],"unset":["x"]}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing note = %d, want 404", w.Code)
+	}
+}
+
+func TestSRS_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "study/go.md", []byte("# Go\n\nQ: Zero value of a map?\nA: nil\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/srs/due", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var due DueCardsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &due); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("due = %d, body = %s", w.Code, w.Body.String())
+	}
+	if due.Total != 1 || due.Cards[0].Answer != "nil" {
+		t.Fatalf("due = %+v", due)
+	}
+	card := due.Cards[0]
+
+	review := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/srs/review", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w = review(`{"path":"study/go.md","card":"` + card.ID + `","grade":5}`)
+	var got Flashcard
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("review = %d, body = %s", w.Code, w.Body.String())
+	}
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	if got.Due != tomorrow || got.Interval != 1 || got.Repetitions != 1 || got.Reviews != 1 || got.Ease != 2.6 {
+		t.Errorf("reviewed card = %+v, want due %s", got, tomorrow)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/srs/due?date="+tomorrow, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if err := json.Unmarshal(w.Body.Bytes(), &due); err != nil || due.Total != 1 || due.Date != tomorrow {
+		t.Errorf("due tomorrow = %+v", due)
+	}
+
+	for body, code := range map[string]int{
+		`{"path":"study/go.md","card":"` + card.ID + `","grade":6}`: http.StatusBadRequest,
+		`{"path":"study/go.md","card":"` + card.ID + `"}`:           http.StatusBadRequest,
+		`{"path":"study/go.md","card":"nope","grade":3}`:            http.StatusNotFound,
+	} {
+		if w := review(body); w.Code != code {
+			t.Errorf("review %s = %d, want %d", body, w.Code, code)
+		}
+	}
+}
+
+func TestExportNote_Bundle(t *testing.T) {
+	svc, router, vaultDir := testEnvWithVault(t, false, "")
+	if err := os.MkdirAll(filepath.Join(vaultDir, "attachments"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vaultDir, "attachments", "pic.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := svc.CreateNote(ctx, "docs/guide.md", []byte("# Guide\n\n![pic](/attachments/pic.png)\n\n[[Other]]\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateNote(ctx, "other.md", []byte("# Other\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notes/docs/guide.md/export?format=bundle", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export = %d, body = %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=guide.zip` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "docs/guide.md" {
+			rc, _ := f.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if !strings.Contains(string(data), "](../attachments/pic.png)") {
+				t.Errorf("guide.md attachment link not rewritten: %s", data)
+			}
+		}
+	}
+	if want := []string{"docs/guide.md", "other.md", "attachments/pic.png"}; !slices.Equal(names, want) {
+		t.Errorf("zip entries = %v, want %v", names, want)
+	}
+
+	for _, tc := range []struct {
+		url  string
+		code int
+	}{
+		{"/notes/missing.md/export", http.StatusNotFound},
+		{"/notes/docs/guide.md/export?format=pdf", http.StatusBadRequest},
+		{"/notes/docs/guide.md/export?depth=x", http.StatusBadRequest},
+		{"/notes/docs/guide.md/export?depth=99", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		if w.Code != tc.code {
+			t.Errorf("GET %s = %d, want %d", tc.url, w.Code, tc.code)
+		}
+	}
+}
+
+func TestLinkPreviews_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "read.md", []byte("# Read\n\nhttps://go.dev/blog\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes/read.md/link-previews", nil))
+	var got LinkPreviewsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("link previews = %d, body = %s", w.Code, w.Body.String())
+	}
+	// Fetching is disabled by default: the URL comes back without metadata.
+	if got.Path != "read.md" || len(got.Links) != 1 || got.Links[0].URL != "https://go.dev/blog" || got.Links[0].FetchedAt != nil {
+		t.Errorf("link previews = %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes/nope.md/link-previews", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing note = %d, want 404", w.Code)
+	}
+}
+
+func TestRevisions_API(t *testing.T) {
+	store, err := storage.NewFS(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	svc := noteservice.NewService(store, db, noteservice.WithHistory(5))
+	router := NewRouter(svc, false, "", nil, t.TempDir())
+
+	ctx := context.Background()
+	if _, err := svc.CreateNote(ctx, "plan.md", []byte("# v1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.UpdateNote(ctx, "plan.md", []byte("# v2"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notes/plan.md/revisions", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var revs []Revision
+	_ = json.Unmarshal(w.Body.Bytes(), &revs)
+	if w.Code != http.StatusOK || len(revs) != 1 {
+		t.Fatalf("list = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/notes/plan.md/revisions/"+revs[0].ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var rev Revision
+	_ = json.Unmarshal(w.Body.Bytes(), &rev)
+	if w.Code != http.StatusOK || !strings.HasSuffix(rev.Content, "# v1") {
+		t.Errorf("get = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/notes/plan.md/revisions/"+revs[0].ID+"/restore", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusOK || !strings.HasSuffix(note.Content, "# v1") {
+		t.Errorf("restore = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/notes/plan.md/revisions/20200101T000000.000000000Z", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown revision = %d, want 404", w.Code)
+	}
+}
+
+func TestTrash_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "plan.md", []byte("# Plan")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/notes/plan.md", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete = %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/trash", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var entries []TrashEntry
+	_ = json.Unmarshal(w.Body.Bytes(), &entries)
+	if w.Code != http.StatusOK || len(entries) != 1 || entries[0].Path != "plan.md" {
+		t.Errorf("trash = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/trash/plan.md/restore", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if w.Code != http.StatusOK || note.Path != "plan.md" {
+		t.Errorf("restore = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/trash/plan.md/restore", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("restore twice = %d, want 404", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/notes/plan.md", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodDelete, "/trash/plan.md", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("purge = %d", w.Code)
+	}
+}
+
+func TestDeadLinks_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "read.md", []byte("# Read\n\nhttps://go.dev/blog\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/dead-links", nil))
+	var got DeadLinksResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("dead links = %d, body = %s", w.Code, w.Body.String())
+	}
+	// Nothing has been checked yet.
+	if got.URLs != 1 || got.Checked != 0 || len(got.Links) != 0 {
+		t.Errorf("dead links = %+v", got)
+	}
+
+	// Rechecking with no dead links and no body checks nothing.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reports/dead-links/recheck", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("recheck all = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reports/dead-links/recheck", strings.NewReader(`{"url":"https://nowhere.example/"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("recheck unlinked url = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reports/dead-links/recheck", strings.NewReader(`{`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad body = %d, want 400", w.Code)
+	}
+}
+
+func TestNavigation_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	ctx := context.Background()
+	if _, err := svc.CreateNote(ctx, "home.md", []byte("# Home\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateNote(ctx, "docs/guide.md", []byte("---\nparent: \"[[Home]]\"\n---\n# Guide\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes/docs/guide.md/breadcrumbs", nil))
+	var crumbs BreadcrumbsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &crumbs); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("breadcrumbs = %d, body = %s", w.Code, w.Body.String())
+	}
+	if len(crumbs.Folders) != 1 || crumbs.Folders[0].Path != "docs" || len(crumbs.Parents) != 1 || crumbs.Parents[0].Title != "Home" {
+		t.Errorf("breadcrumbs = %+v", crumbs)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sitemap", nil))
+	var sitemap SitemapResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &sitemap); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("sitemap = %d, body = %s", w.Code, w.Body.String())
+	}
+	if sitemap.Notes != 2 || len(sitemap.Children) != 2 || sitemap.Children[0].Path != "docs" {
+		t.Errorf("sitemap = %+v", sitemap)
+	}
+
+	for target, want := range map[string]int{
+		"/notes/nope.md/breadcrumbs": http.StatusNotFound,
+		"/sitemap?folder=nope":       http.StatusNotFound,
+		"/sitemap?folder=../x":       http.StatusBadRequest,
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Errorf("GET %s = %d, want %d", target, w.Code, want)
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	h := MetricsHandler(func() []Metric {
+		return []Metric{{Name: "kenaz_test_total", Help: "Test counter.", Type: "counter", Value: 3}}
+	})
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := "# HELP kenaz_test_total Test counter.\n# TYPE kenaz_test_total counter\nkenaz_test_total 3\n"
+	if w.Body.String() != want || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("metrics = %q (%s)", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+}
+
+func TestBatchNotes_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	if _, err := svc.CreateNote(context.Background(), "old.md", []byte("# Old\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"ops":[
+		{"op":"create","path":"a.md","content":"# A\n"},
+		{"op":"update","path":"a.md","content":"# A2\n"},
+		{"op":"delete","path":"old.md"},
+		{"op":"create","path":"a.md","content":"# Again\n"},
+		{"op":"delete","path":"missing.md"}
+	]}`
+	dry := `{"dry_run":true,` + body[1:]
+	req := httptest.NewRequest(http.MethodPost, "/notes/batch", strings.NewReader(dry))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var preview BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("dry run = %d, body = %s", w.Code, w.Body.String())
+	}
+	if !preview.DryRun || preview.Succeeded != 3 || len(preview.Changes) != 3 || preview.Changes[1].Action != "update" {
+		t.Errorf("dry run = %+v", preview)
+	}
+	if _, err := svc.GetNote(context.Background(), "a.md"); err == nil {
+		t.Error("dry run created a.md")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/notes/batch", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{http.StatusCreated, http.StatusOK, http.StatusNoContent, http.StatusConflict, http.StatusNotFound}
+	for i, res := range resp.Results {
+		if res.Status != want[i] {
+			t.Errorf("result %d = %+v, want status %d", i, res, want[i])
+		}
+	}
+	if resp.Succeeded != 3 || resp.Failed != 2 {
+		t.Errorf("succeeded = %d, failed = %d", resp.Succeeded, resp.Failed)
+	}
+	if n, err := svc.GetNote(context.Background(), "a.md"); err != nil || n.Title != "A2" {
+		t.Errorf("a.md = %+v, %v", n, err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/notes/batch", strings.NewReader(`{"ops":[]}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("empty batch = %d", w.Code)
+	}
+}
+
+func TestNoteHTML_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	ctx := context.Background()
+	if _, err := svc.CreateNote(ctx, "topics/b note.md", []byte("# B\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateNote(ctx, "a.md", []byte("---\ntitle: A\n---\n# A\nSee [[b note]] and [[missing]].\n\n![[pic.png]]\n\n<script>x</script>\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notes/a.md/html", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("html = %d %s, body = %s", w.Code, w.Header().Get("Content-Type"), body)
+	}
+	for _, want := range []string{
+		`<h1 id="a">A</h1>`,
+		`<a class="wikilink" href="/api/notes/topics/b%20note.md">b note</a>`,
+		`<span class="wikilink unresolved">missing</span>`,
+		`<img src="/attachments/pic.png" alt="pic.png">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("html lacks %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "title: A") || strings.Contains(body, "<script") {
+		t.Errorf("html leaks frontmatter or raw HTML:\n%s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/notes/missing.md/html", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing note = %d, want 404", w.Code)
+	}
+}
+
+func TestSuggestNotes_API(t *testing.T) {
+	svc, router := testEnv(t, "")
+	for p, c := range map[string]string{"projects/kenaz-roadmap.md": "# Kenaz Roadmap", "inbox.md": "# Inbox"} {
+		if _, err := svc.CreateNote(context.Background(), p, []byte(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notes/suggest?q=knz+road&limit=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp SuggestResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Results) != 1 || resp.Results[0].Path != "projects/kenaz-roadmap.md" || resp.Results[0].Title != "Kenaz Roadmap" {
+		t.Errorf("suggest = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/notes/suggest", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"results":[]`) {
+		t.Errorf("empty suggest = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReindexEndpoint(t *testing.T) {
+	_, router, vaultDir := testEnvWithVault(t, false, "")
+	body, _ := json.Marshal(map[string]string{"path": "a.md", "content": "# A"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+	// Written behind the index's back.
+	if err := os.WriteFile(filepath.Join(vaultDir, "b.md"), []byte("# B\n\n[[a]]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/reindex", nil))
+	var status ReindexStatusResponse
+	if _ = json.Unmarshal(w.Body.Bytes(), &status); w.Code != http.StatusOK || status.Running || status.StartedAt != nil {
+		t.Fatalf("status before reindex = %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/reindex", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("reindex = %d, body = %s", w.Code, w.Body.String())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/reindex", nil))
+		status = ReindexStatusResponse{}
+		_ = json.Unmarshal(w.Body.Bytes(), &status)
+		if !status.Running || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status.Running || status.FinishedAt == nil || status.Total != 2 || status.Processed != 2 || len(status.Errors) != 0 || status.Error != "" {
+		t.Fatalf("status after reindex = %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes/a.md", nil))
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if !slices.Equal(note.Backlinks, []string{"b.md"}) {
+		t.Errorf("backlinks of a.md after reindex = %v, want [b.md]", note.Backlinks)
+	}
+}
+
+func TestOutlineEndpoint(t *testing.T) {
+	_, router := testEnv(t, "")
+	body, _ := json.Marshal(map[string]string{"path": "docs/guide.md", "content": "# Guide\n\n## Install\nsteps\n\n## Install\nagain\n"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes/docs/guide.md/outline", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("outline = %d, body = %s", w.Code, w.Body.String())
+	}
+	var outline OutlineResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &outline)
+	var anchors []string
+	for _, h := range outline.Headings {
+		anchors = append(anchors, h.Anchor)
+	}
+	if outline.Path != "docs/guide.md" || !slices.Equal(anchors, []string{"guide", "install", "install-1"}) {
+		t.Errorf("outline = %+v", outline)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q=again", nil))
+	var resp SearchResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Results) != 1 || resp.Results[0].Section == nil || resp.Results[0].Section.Anchor != "install-1" {
+		t.Errorf("search = %s, want a hit in section install-1", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes/missing.md/outline", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("outline of missing note = %d, want 404", w.Code)
+	}
+}
+
+func TestIndexStatsEndpoint(t *testing.T) {
+	_, router, vaultDir := testEnvWithVault(t, false, "")
+	body, _ := json.Marshal(map[string]string{"path": "docs/a.md", "content": "# A"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+	// Not indexed yet.
+	if err := os.WriteFile(filepath.Join(vaultDir, "b.md"), []byte("# B"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/index/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("index stats = %d, body = %s", w.Code, w.Body.String())
+	}
+	var stats IndexStatsResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.Notes != 1 || stats.VaultNotes != 2 || stats.SizeBytes <= 0 {
+		t.Errorf("stats = %s, want 1 indexed of 2 notes", w.Body.String())
+	}
+	if len(stats.Folders) != 1 || stats.Folders[0] != (index.FolderCount{Folder: "docs", Notes: 1}) {
+		t.Errorf("folders = %+v", stats.Folders)
+	}
+}
+
+func TestOptimizeIndexEndpoint(t *testing.T) {
+	_, router, _ := testEnvWithVault(t, false, "")
+	body, _ := json.Marshal(map[string]string{"path": "a.md", "content": "# A"})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+
+	for _, vacuum := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/index/optimize?vacuum=%t", vacuum), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("optimize = %d, body = %s", w.Code, w.Body.String())
+		}
+		var res OptimizeIndexResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		if res.Vacuumed != vacuum || res.SizeBefore <= 0 || res.SizeAfter <= 0 || res.FinishedAt.IsZero() {
+			t.Errorf("optimize vacuum=%t = %s", vacuum, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/index/stats", nil))
+	var stats IndexStatsResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.OptimizedAt == nil {
+		t.Errorf("stats after optimize = %s, want optimized_at", w.Body.String())
+	}
+}
+
+func TestGitPushEndpoint(t *testing.T) {
+	_, router, _ := testEnvWithVault(t, false, "")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/git/push", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("push without git = %d, want 404", w.Code)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	fs, err := storage.NewFS(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	store, err := storage.NewGit(fs, storage.GitOptions{Remote: remote})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	router = NewRouter(noteservice.NewService(store, db), false, "", nil, t.TempDir())
+
+	body, _ := json.Marshal(map[string]string{"path": "a.md", "content": "# A"})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notes", bytes.NewReader(body)))
+	var note NoteDetail
+	_ = json.Unmarshal(w.Body.Bytes(), &note)
+	if note.LastCommit == nil || note.LastCommit.Message != "create a.md" {
+		t.Errorf("created note = %s, want last_commit", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/git/push", nil))
+	var res GitPushResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || res.Head == nil || res.Head.Hash != note.LastCommit.Hash {
+		t.Errorf("push = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBackupEndpoint(t *testing.T) {
+	_, router, _ := testEnvWithVault(t, false, "")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/backup", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("backup without backup dir = %d, want 404", w.Code)
+	}
+
+	store, err := storage.NewFS(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	dir := t.TempDir()
+	svc := noteservice.NewService(store, db, noteservice.WithBackups(backup.Policy{Dir: dir, Keep: 1}))
+	router = NewRouter(svc, false, "", nil, t.TempDir())
+	if _, err := svc.CreateNote(context.Background(), "a.md", []byte("# A")); err != nil {
+		t.Fatal(err)
+	}
+
+	var first BackupResponse
+	for _, q := range []string{"", "?index=false"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/backup"+q, nil))
+		var info BackupResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &info)
+		if w.Code != http.StatusCreated || info.Files != 1 || info.Index != (q == "") {
+			t.Fatalf("backup%s = %d, body = %s", q, w.Code, w.Body.String())
+		}
+		if q == "" {
+			first = info
+		} else if !slices.Equal(info.Removed, []string{first.Name}) {
+			t.Errorf("removed = %v, want the first backup", info.Removed)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("backup dir holds %d entries, want 1", len(entries))
+	}
+}
+
+func TestExportImportVault(t *testing.T) {
+	svc, router, _ := testEnvWithVault(t, false, "")
+	ctx := context.Background()
+	if _, err := svc.CreateNote(ctx, "projects/plan.md", []byte("---\ntags: [work]\n---\n# Plan\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateNote(ctx, "journal.md", []byte("# Journal\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export?folder=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("export of a missing folder = %d, want 404", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export?tag=work", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("export = %d, body = %s", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=kenaz-export.zip` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	archive := w.Body.Bytes()
+
+	dst, dstRouter, _ := testEnvWithVault(t, false, "")
+	if _, err := dst.CreateNote(ctx, "projects/plan.md", []byte("# Local plan\n")); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	dstRouter.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import?conflict=rename&dry_run=true", bytes.NewReader(archive)))
+	var preview ImportResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &preview)
+	if w.Code != http.StatusOK || !preview.DryRun || preview.Renamed != 1 || len(preview.Changes) != 1 {
+		t.Fatalf("dry-run import = %d, body = %s", w.Code, w.Body.String())
+	}
+	if _, err := dst.GetNote(ctx, "projects/plan-2.md"); err == nil {
+		t.Error("dry-run import wrote projects/plan-2.md")
+	}
+
+	w = httptest.NewRecorder()
+	dstRouter.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import?conflict=rename", bytes.NewReader(archive)))
+	var res ImportResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || res.Renamed != 1 || len(res.Files) != 1 || res.Files[0].RenamedTo != "projects/plan-2.md" {
+		t.Fatalf("import = %d, body = %s", w.Code, w.Body.String())
+	}
+	if note, err := dst.GetNote(ctx, "projects/plan-2.md"); err != nil || note.Title != "Plan" {
+		t.Errorf("imported note = %+v, %v", note, err)
+	}
+
+	for _, tc := range []struct {
+		query string
+		body  []byte
+	}{
+		{"?conflict=merge", archive},
+		{"", []byte("not a zip")},
+	} {
+		w = httptest.NewRecorder()
+		dstRouter.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/import"+tc.query, bytes.NewReader(tc.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("import%s = %d, want 400", tc.query, w.Code)
+		}
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	vaultDir := t.TempDir()
+	store, err := storage.NewFS(vaultDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := noteservice.NewService(store, db).CreateNote(context.Background(), "a.md", []byte("# A\n")); err != nil {
+		t.Fatal(err)
+	}
+	svc := noteservice.NewService(store, db, noteservice.WithReadOnly(true))
+	router := NewRouter(svc, false, "", nil, vaultDir)
+
+	for _, tc := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodGet, "/notes/a.md", "", http.StatusOK},
+		{http.MethodGet, "/search?q=A", "", http.StatusOK},
+		{http.MethodGet, "/graph", "", http.StatusOK},
+		{http.MethodGet, "/daily/2025-01-01", "", http.StatusNotFound},
+		{http.MethodPost, "/notes/a.md/preview-merge", `{"base":"# A\n","content":"# B\n"}`, http.StatusOK},
+		{http.MethodPost, "/notes", `{"path":"b.md","content":"# B"}`, http.StatusForbidden},
+		{http.MethodPut, "/notes/a.md", `{"content":"# B"}`, http.StatusForbidden},
+		{http.MethodDelete, "/notes/a.md", "", http.StatusForbidden},
+		{http.MethodPost, "/notes/a.md/append", `{"content":"more"}`, http.StatusForbidden},
+		{http.MethodPost, "/notes/batch", `{"ops":[]}`, http.StatusForbidden},
+		{http.MethodPost, "/capture", `{"text":"idea"}`, http.StatusForbidden},
+		{http.MethodPost, "/import", "", http.StatusForbidden},
+		{http.MethodPost, "/attachments", "", http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		if w.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d; body = %s", tc.method, tc.target, w.Code, tc.want, w.Body.String())
+		}
+	}
+	if got, err := os.ReadFile(filepath.Join(vaultDir, "a.md")); err != nil || !strings.Contains(string(got), "# A\n") {
+		t.Errorf("a.md = %q, %v", got, err)
+	}
+}
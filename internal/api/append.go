@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/noteservice"
+)
+
+// AppendNote handles POST /api/notes/{path}/append.
+//
+//	@Summary		Append to a note
+//	@Description	Atomically appends content to the end of an existing note (on a new line) and
+//	@Description	re-indexes it. With heading, the content goes to the end of that section instead.
+//	@Description	Concurrent appends are serialized, so none is lost.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			path	path		string				true	"Note path"
+//	@Param			body	body		AppendNoteRequest	true	"Content to append"
+//	@Success		200		{object}	NoteDetail
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/append [post]
+func (h *Handler) AppendNote(w http.ResponseWriter, r *http.Request, path string) {
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+	var req AppendNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("content is required"))
+		return
+	}
+
+	note, err := h.svc.AppendNote(r.Context(), path, req.Content, req.Heading)
+	if err != nil {
+		if errors.Is(err, noteservice.ErrHeadingNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("heading not found"))
+			return
+		}
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("append note failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
+// Capture handles POST /api/capture.
+//
+//	@Summary		Quick-capture text into the inbox
+//	@Description	Appends text as a timestamped bullet (followed by any tags) to the inbox note
+//	@Description	(vault.inbox_path), creating the note if it does not exist.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		CaptureRequest	true	"Text to capture"
+//	@Success		200		{object}	NoteDetail
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/capture [post]
+func (h *Handler) Capture(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req CaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("text is required"))
+		return
+	}
+	for _, t := range req.Tags {
+		if strings.ContainsAny(strings.TrimSpace(t), " \t\r\n") {
+			writeJSON(w, http.StatusBadRequest, errorBody("tags must not contain whitespace"))
+			return
+		}
+	}
+
+	note, err := h.svc.Capture(r.Context(), req.Text, req.Tags)
+	if err != nil {
+		slog.Error("capture failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
+// AppendDaily handles POST /api/daily/append.
+//
+//	@Summary		Append to today's daily note
+//	@Description	Appends text as a bullet prefixed with the current time to today's daily note,
+//	@Description	creating it from daily.template if it does not exist.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		DailyAppendRequest	true	"Text to append"
+//	@Success		200		{object}	NoteDetail
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/daily/append [post]
+func (h *Handler) AppendDaily(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req DailyAppendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("text is required"))
+		return
+	}
+
+	note, err := h.svc.AppendDaily(r.Context(), req.Text)
+	if err != nil {
+		slog.Error("daily append failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
+// GetDaily handles GET /api/daily/{date}.
+//
+//	@Summary		Get or create a daily note
+//	@Description	Returns the daily note for date (YYYY-MM-DD, or "today" in server local time),
+//	@Description	creating it from daily.template at the daily.folder/daily.format path first if
+//	@Description	it does not exist. A read-only vault answers 404 for a missing daily note.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			date	path		string	true	"YYYY-MM-DD or today"
+//	@Success		200		{object}	NoteDetail
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/daily/{date} [get]
+func (h *Handler) GetDaily(w http.ResponseWriter, r *http.Request) {
+	day := time.Now()
+	if v := chi.URLParam(r, "date"); v != "today" {
+		d, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody("date must be YYYY-MM-DD or today"))
+			return
+		}
+		day = d
+	}
+
+	note, err := h.svc.DailyNote(r.Context(), day)
+	if errors.Is(err, apperr.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		return
+	}
+	if err != nil {
+		slog.Error("daily note failed", slog.String("date", day.Format(time.DateOnly)), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
+// PatchNote handles PATCH /api/notes/{path}.
+//
+//	@Summary		Edit part of a note
+//	@Description	Applies one operation without resending the whole note: "append" (to the end
+//	@Description	of the note or of heading's section), "prepend" (to the start of the body or of
+//	@Description	the section), or "replace_heading" (replaces the body of heading's section).
+//	@Description	Patches are applied to the latest content, so If-Match is optional even when the
+//	@Description	server requires it for PUT; a stale one is rejected with 409.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			path		path		string			true	"Note path"
+//	@Param			If-Match	header		string			false	"SHA-256 checksum the patch is based on"
+//	@Param			body		body		PatchNoteRequest	true	"Operation"
+//	@Success		200			{object}	NoteDetail
+//	@Failure		400			{object}	errResponse
+//	@Failure		404			{object}	errResponse
+//	@Failure		409			{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path} [patch]
+func (h *Handler) PatchNote(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+	path := notePath(r)
+	if path == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("path is required"))
+		return
+	}
+	var req PatchNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+
+	ifMatch := strings.Trim(r.Header.Get("If-Match"), `"`)
+	note, err := h.svc.PatchNote(r.Context(), path, req, ifMatch)
+	if err != nil {
+		switch {
+		case errors.Is(err, noteservice.ErrInvalidPatch):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		case errors.Is(err, noteservice.ErrHeadingNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("heading not found"))
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		case errors.Is(err, apperr.ErrConflict):
+			writeJSON(w, http.StatusConflict, errorBody("checksum mismatch"))
+		default:
+			slog.Error("patch note failed", slog.String("path", path), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
@@ -1,29 +1,249 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/checksum"
 )
 
 const (
 	attachDir      = "attachments"
 	maxUploadBytes = 50 << 20 // 50 MB
+
+	// versionLen is the number of hash hex digits used in versioned
+	// attachment URLs (/attachments/name?v=<hash>).
+	versionLen = 16
+
+	cacheRevalidate = "no-cache"
+	cacheImmutable  = "public, max-age=31536000, immutable"
 )
 
 // AttachmentHandler serves and accepts attachment files.
 type AttachmentHandler struct {
 	vaultRoot string
+
+	mu     sync.Mutex
+	hashes map[string]fileHash
+
+	// uploadMu guards appending, the chunked uploads receiving a chunk. A
+	// chunk is copied without holding it, so one slow client does not
+	// block the others.
+	uploadMu  sync.Mutex
+	appending map[string]bool
+	// uploadTTL is how long a chunked upload may go without a chunk before
+	// it is discarded; zero keeps uploads forever.
+	uploadTTL time.Duration
+
+	// pipeline, if set, scans and transforms uploads before they are stored.
+	pipeline *asset.Pipeline
+
+	// contentAddressed stores uploads as <sha256>.<ext>.
+	contentAddressed bool
+}
+
+// AttachmentOption configures an AttachmentHandler.
+type AttachmentOption func(*AttachmentHandler)
+
+// WithPipeline runs p on every uploaded attachment before it is stored;
+// files its scanner flags as infected are rejected with 422.
+func WithPipeline(p *asset.Pipeline) AttachmentOption {
+	return func(h *AttachmentHandler) { h.pipeline = p }
+}
+
+// WithContentAddressing stores uploads under their content hash,
+// attachments/<sha256>.<ext>, instead of the uploaded file name. Uploading
+// the same content twice yields the same file, names never collide, and the
+// uploaded names are recorded in attachments/.names.json.
+func WithContentAddressing() AttachmentOption {
+	return func(h *AttachmentHandler) { h.contentAddressed = true }
+}
+
+// WithUploadTTL discards chunked uploads that received no chunk for ttl
+// (default DefaultUploadTTL); zero keeps them until they are completed or
+// cancelled.
+func WithUploadTTL(ttl time.Duration) AttachmentOption {
+	return func(h *AttachmentHandler) { h.uploadTTL = ttl }
+}
+
+// fileHash caches a content hash for a file at a given size and mtime.
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	sum     string
 }
 
 // NewAttachmentHandler creates a handler rooted at the vault directory.
-func NewAttachmentHandler(vaultRoot string) *AttachmentHandler {
-	return &AttachmentHandler{vaultRoot: vaultRoot}
+func NewAttachmentHandler(vaultRoot string, opts ...AttachmentOption) *AttachmentHandler {
+	h := &AttachmentHandler{
+		vaultRoot: vaultRoot,
+		hashes:    make(map[string]fileHash),
+		appending: make(map[string]bool),
+		uploadTTL: DefaultUploadTTL,
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+// writeProcessError reports a failed upload pipeline: 422 with the verdict
+// for infected files, 400 for content that cannot be made safe, and 500 when
+// scanning or processing itself failed.
+func writeProcessError(w http.ResponseWriter, name string, err error) {
+	if errors.Is(err, asset.ErrInfected) {
+		writeJSON(w, http.StatusUnprocessableEntity, errorBody(err.Error()))
+		return
+	}
+	if errors.Is(err, asset.ErrInvalidContent) {
+		writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		return
+	}
+	slog.Error("attachment processing failed", slog.String("file", name), slog.String("error", err.Error()))
+	writeJSON(w, http.StatusInternalServerError, errorBody("attachment processing failed"))
+}
+
+// store runs data through the pipeline and writes the result (and a kept
+// original, if any) under the attachments dir. abs must come from safeName;
+// with content addressing the file goes to its content name instead, and
+// content already stored is not written again.
+func (h *AttachmentHandler) store(ctx context.Context, abs, name string, data []byte) (AttachmentUploadResponse, error) {
+	res, err := h.pipeline.Process(ctx, name, data)
+	if err != nil {
+		return AttachmentUploadResponse{}, err
+	}
+	if err := os.MkdirAll(h.attachPath(), 0o755); err != nil {
+		return AttachmentUploadResponse{}, fmt.Errorf("create attachments dir: %w", err)
+	}
+	sum := checksum.Sum(res.Data)
+	stored := name
+	if h.contentAddressed {
+		stored = asset.ContentNameForSum(name, sum)
+		abs = filepath.Join(h.attachPath(), stored)
+	}
+	if res.Original != nil {
+		orig := asset.OriginalName(stored)
+		if err := os.WriteFile(filepath.Join(h.attachPath(), orig), res.Original, 0o644); err != nil {
+			return AttachmentUploadResponse{}, fmt.Errorf("write original: %w", err)
+		}
+	}
+	if _, err := os.Stat(abs); !h.contentAddressed || err != nil {
+		if err := writeFileAtomic(abs, res.Data); err != nil {
+			return AttachmentUploadResponse{}, err
+		}
+	}
+	resp, err := h.uploaded(stored, name, int64(len(res.Data)), sum)
+	if err != nil {
+		return AttachmentUploadResponse{}, err
+	}
+	if res.Original != nil {
+		resp.OriginalURL = "/attachments/" + asset.OriginalName(stored)
+	}
+	return resp, nil
+}
+
+// uploaded builds the response for an upload of name stored as the
+// attachment stored, recording the name first when the two differ.
+func (h *AttachmentHandler) uploaded(stored, name string, size int64, sum string) (AttachmentUploadResponse, error) {
+	resp := AttachmentUploadResponse{
+		Filename:     stored,
+		Size:         size,
+		URL:          "/attachments/" + stored,
+		Hash:         sum,
+		VersionedURL: versionedURL(stored, sum),
+	}
+	if stored != name {
+		if err := asset.RecordName(vaultFiles(h.vaultRoot), stored, name); err != nil {
+			return AttachmentUploadResponse{}, fmt.Errorf("record attachment name: %w", err)
+		}
+		resp.OriginalName = name
+	}
+	return resp, nil
+}
+
+// vaultFiles reads and writes files under a local vault directory.
+type vaultFiles string
+
+func (v vaultFiles) Read(path string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(v), filepath.FromSlash(path)))
+}
+
+func (v vaultFiles) Write(path string, content []byte) error {
+	abs := filepath.Join(string(v), filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(abs, content)
+}
+
+// writeFileAtomic writes data to a temp file next to abs and renames it into
+// place, so readers never observe a partially written attachment.
+func writeFileAtomic(abs string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(abs), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), abs); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("rename file: %w", err)
+	}
+	return nil
+}
+
+// contentHash returns the SHA-256 of the file at abs, reusing the cached
+// value while the file's size and mtime are unchanged.
+func (h *AttachmentHandler) contentHash(abs string, info os.FileInfo) (string, error) {
+	h.mu.Lock()
+	c, ok := h.hashes[abs]
+	h.mu.Unlock()
+	if ok && c.size == info.Size() && c.modTime.Equal(info.ModTime()) {
+		return c.sum, nil
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sum, err := checksum.SumReader(f)
+	if err != nil {
+		return "", err
+	}
+	h.mu.Lock()
+	h.hashes[abs] = fileHash{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	h.mu.Unlock()
+	return sum, nil
+}
+
+// versionedURL returns the cache-busting URL for an attachment with the
+// given content hash.
+func versionedURL(name, sum string) string {
+	return "/attachments/" + url.PathEscape(name) + "?v=" + sum[:versionLen]
 }
 
 // attachDir returns the absolute path to the attachments directory.
@@ -51,6 +271,11 @@ func (h *AttachmentHandler) safeName(name string) (string, error) {
 }
 
 // ServeFile handles GET /attachments/{filename}.
+//
+// Responses carry a content-hash ETag. Plain URLs must be revalidated
+// (conditional requests get 304); URLs whose ?v= matches the current hash,
+// and content-addressed names matching their content, are cached as
+// immutable.
 func (h *AttachmentHandler) ServeFile(w http.ResponseWriter, r *http.Request) {
 	filename := chi.URLParam(r, "filename")
 	abs, err := h.safeName(filename)
@@ -58,10 +283,28 @@ func (h *AttachmentHandler) ServeFile(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if _, statErr := os.Stat(abs); os.IsNotExist(statErr) {
+	info, statErr := os.Stat(abs)
+	if os.IsNotExist(statErr) || (statErr == nil && info.IsDir()) {
 		http.NotFound(w, r)
 		return
 	}
+	if statErr == nil {
+		if sum, err := h.contentHash(abs, info); err == nil {
+			w.Header().Set("ETag", `"`+sum+`"`)
+			cache := cacheRevalidate
+			if v := r.URL.Query().Get("v"); v != "" && strings.HasPrefix(sum, v) && len(v) >= versionLen {
+				cache = cacheImmutable
+			}
+			if asset.IsContentName(filename) && strings.HasPrefix(filename, sum) {
+				cache = cacheImmutable
+			}
+			w.Header().Set("Cache-Control", cache)
+		}
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if strings.EqualFold(filepath.Ext(abs), ".svg") {
+		w.Header().Set("Content-Security-Policy", asset.SVGContentSecurityPolicy)
+	}
 	http.ServeFile(w, r, abs)
 }
 
@@ -97,28 +340,64 @@ func (h *AttachmentHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Ensure attachments directory exists.
-	if err := os.MkdirAll(h.attachPath(), 0o755); err != nil {
-		writeJSON(w, http.StatusInternalServerError, errorBody("failed to create attachments dir"))
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("failed to read upload"))
 		return
 	}
 
-	dst, err := os.Create(abs)
+	resp, err := h.store(r.Context(), abs, header.Filename, data)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, errorBody("failed to create file"))
+		writeProcessError(w, header.Filename, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// UploadFromURL handles POST /api/attachments/from-url. The server downloads
+// the file itself (http, https, or a base64 data: URI) with the same checks
+// as the MCP upload_asset tool: blocked loopback/metadata hosts, a 10 MB
+// limit, allowed extensions, and magic-byte validation.
+//
+//	@Summary		Import an attachment from a URL
+//	@Tags			attachments
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		UploadFromURLRequest	true	"Source URL and optional file name"
+//	@Success		201		{object}	AttachmentUploadResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/attachments/from-url [post]
+func (h *AttachmentHandler) UploadFromURL(w http.ResponseWriter, r *http.Request) {
+	var req UploadFromURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if req.URL == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("url is required"))
 		return
 	}
-	defer dst.Close()
 
-	written, err := io.Copy(dst, file)
+	filename, data, err := asset.Prepare(req.URL, req.Filename)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, errorBody("failed to write file"))
+		writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
 		return
 	}
-
-	writeJSON(w, http.StatusCreated, map[string]any{
-		"filename": header.Filename,
-		"size":     written,
-		"url":      "/attachments/" + header.Filename,
-	})
+	abs, err := h.safeName(filename)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		return
+	}
+	if _, err := os.Stat(abs); err == nil && !h.contentAddressed {
+		writeJSON(w, http.StatusConflict, errorBody("file already exists: "+filename))
+		return
+	}
+	resp, err := h.store(r.Context(), abs, filename, data)
+	if err != nil {
+		writeProcessError(w, filename, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, resp)
 }
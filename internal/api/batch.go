@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/noteservice"
+)
+
+// BatchNotes handles POST /api/notes/batch.
+//
+//	@Summary		Create, update, and delete notes in bulk
+//	@Description	Runs up to 1000 operations in order. A failed operation does not stop the others;
+//	@Description	each gets its own result with the status it would have had as a single request.
+//	@Description	The index is updated in one transaction, and SSE clients get one notes.batch and
+//	@Description	one graph.updated event instead of an event per note. Deletes go to the trash;
+//	@Description	batch operations cannot be undone with /undo. With dry_run, nothing is written: the
+//	@Description	results report what each operation would do, and changes holds a unified diff per
+//	@Description	created or updated note.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		BatchRequest	true	"Operations"
+//	@Success		200		{object}	BatchResponse
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/batch [post]
+func (h *Handler) BatchNotes(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<20)
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	items, changes, err := h.svc.Batch(r.Context(), req.Ops, req.DryRun)
+	if err != nil {
+		if errors.Is(err, noteservice.ErrInvalidBatch) {
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+			return
+		}
+		slog.Error("batch failed", slog.Int("ops", len(req.Ops)), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+
+	resp := BatchResponse{Results: make([]BatchResult, len(items)), DryRun: req.DryRun, Changes: changes}
+	for i, it := range items {
+		res := BatchResult{Op: it.Op, Path: it.Path, Checksum: it.Checksum}
+		res.Status, res.Error = batchStatus(it)
+		if it.Err != nil {
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results[i] = res
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// batchStatus maps the outcome of a batch operation to an HTTP status and
+// error message.
+func batchStatus(it noteservice.BatchItem) (int, string) {
+	switch err := it.Err; {
+	case err == nil && it.Op == noteservice.BatchCreate:
+		return http.StatusCreated, ""
+	case err == nil && it.Op == noteservice.BatchDelete:
+		return http.StatusNoContent, ""
+	case err == nil:
+		return http.StatusOK, ""
+	case errors.Is(err, noteservice.ErrInvalidBatch), errors.Is(err, apperr.ErrInvalidPath):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, apperr.ErrNotFound):
+		return http.StatusNotFound, "not found"
+	case errors.Is(err, apperr.ErrAlreadyExists):
+		return http.StatusConflict, "note already exists"
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict, "checksum mismatch"
+	case errors.Is(err, apperr.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired, "checksum is required"
+	default:
+		slog.Error("batch operation failed", slog.String("op", it.Op), slog.String("path", it.Path), slog.String("error", err.Error()))
+		return http.StatusInternalServerError, "internal error"
+	}
+}
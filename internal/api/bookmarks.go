@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/noteservice"
+)
+
+// CreateBookmark handles POST /api/bookmarks.
+//
+//	@Summary		Save a bookmark
+//	@Description	Creates a bookmark note (type: bookmark, url in the frontmatter) under the bookmarks
+//	@Description	folder. Unless fetch is false, the page is downloaded for its title, description,
+//	@Description	and og:image; a page that cannot be fetched is saved with what the request gives.
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		BookmarkRequest	true	"Page to save"
+//	@Success		201		{object}	NoteDetail
+//	@Failure		400		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/bookmarks [post]
+func (h *Handler) CreateBookmark(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if strings.TrimSpace(req.URL) == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("url is required"))
+		return
+	}
+
+	b := noteservice.Bookmark{URL: strings.TrimSpace(req.URL), Title: req.Title, Description: req.Description, Tags: req.Tags}
+	if req.Fetch == nil || *req.Fetch {
+		page, err := asset.FetchPage(r.Context(), b.URL)
+		if err != nil {
+			slog.Warn("bookmark fetch failed", slog.String("url", b.URL), slog.String("error", err.Error()))
+		} else {
+			b.URL = page.URL
+			b.Image = page.Image
+			if b.Title == "" {
+				b.Title = page.Title
+			}
+			if b.Description == "" {
+				b.Description = page.Description
+			}
+		}
+	}
+
+	note, err := h.svc.CreateBookmark(r.Context(), b)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		case errors.Is(err, apperr.ErrAlreadyExists):
+			writeJSON(w, http.StatusConflict, errorBody(err.Error()))
+		default:
+			slog.Error("create bookmark failed", slog.String("url", b.URL), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusCreated, note)
+}
+
+// ListBookmarks handles GET /api/bookmarks.
+//
+//	@Summary		List bookmarks
+//	@Description	Returns the bookmark notes under the bookmarks folder, newest first, optionally
+//	@Description	filtered by tag, site, unread state, and a full-text query.
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Param			tag		query		string	false	"Only bookmarks with this tag"
+//	@Param			site	query		string	false	"Only bookmarks from this host, e.g. go.dev"
+//	@Param			unread	query		bool	false	"Only bookmarks not marked read"
+//	@Param			q		query		string	false	"Full-text query"
+//	@Success		200		{object}	BookmarkListResponse
+//	@Security		BearerAuth
+//	@Router			/bookmarks [get]
+func (h *Handler) ListBookmarks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	list, err := h.svc.ListBookmarks(r.Context(), noteservice.BookmarkFilter{
+		Tag:    q.Get("tag"),
+		Site:   q.Get("site"),
+		Unread: q.Get("unread") == "true",
+		Query:  q.Get("q"),
+	})
+	if err != nil {
+		slog.Error("list bookmarks failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, BookmarkListResponse{Bookmarks: list, Total: len(list)})
+}
+
+// MarkBookmarkRead handles POST /api/bookmarks/read.
+//
+//	@Summary		Mark a bookmark read or unread
+//	@Description	Sets the read field in the bookmark note's frontmatter.
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		BookmarkReadRequest	true	"Bookmark and state"
+//	@Success		200		{object}	NoteDetail
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/bookmarks/read [post]
+func (h *Handler) MarkBookmarkRead(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req BookmarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("path is required"))
+		return
+	}
+	note, err := h.svc.MarkBookmarkRead(r.Context(), req.Path, req.Read)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("bookmark not found"))
+		case errors.Is(err, apperr.ErrConflict):
+			writeJSON(w, http.StatusConflict, errorBody("checksum mismatch"))
+		default:
+			slog.Error("mark bookmark failed", slog.String("path", req.Path), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/noteservice"
+)
+
+// RenameTag handles POST /api/tags/rename.
+//
+//	@Summary		Rename or merge a tag
+//	@Description	Renames a tag in every note, in the frontmatter tags list and as inline #tags;
+//	@Description	nested tags move with it (from/x becomes to/x). Renaming onto a tag notes already
+//	@Description	have merges the two. With dry_run, returns a unified diff per note without
+//	@Description	writing. Applied changes are re-indexed and each gets an undoable mutation_id.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		RenameTagRequest	true	"Tag to rename and its new name"
+//	@Success		200		{object}	BulkEditResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/tags/rename [post]
+func (h *Handler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req RenameTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+
+	res, err := h.svc.RenameTag(r.Context(), req.From, req.To, req.DryRun)
+	if err != nil {
+		writeBulkEditError(w, "rename tag", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// EditFrontmatter handles POST /api/frontmatter.
+//
+//	@Summary		Edit frontmatter across notes
+//	@Description	Sets and removes frontmatter fields in the listed notes, or in every note
+//	@Description	(optionally under a folder or with a tag) when no paths are given. Values are
+//	@Description	written as YAML; the id field cannot be edited. Notes already as requested are
+//	@Description	left alone. With dry_run, returns a unified diff per note without writing.
+//	@Description	Applied changes are re-indexed and each gets an undoable mutation_id.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		EditFrontmatterRequest	true	"Notes and fields to edit"
+//	@Success		200		{object}	BulkEditResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/frontmatter [post]
+func (h *Handler) EditFrontmatter(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req EditFrontmatterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+
+	res, err := h.svc.EditFrontmatter(r.Context(), noteservice.FrontmatterEdit{
+		Paths:  req.Paths,
+		Folder: req.Folder,
+		Tag:    req.Tag,
+		Set:    req.Set,
+		Unset:  req.Unset,
+		DryRun: req.DryRun,
+	})
+	if err != nil {
+		writeBulkEditError(w, "edit frontmatter", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// writeBulkEditError writes the response for a failed bulk edit.
+func writeBulkEditError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, apperr.ErrInvalidPath):
+		writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+	case errors.Is(err, apperr.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, errorBody(err.Error()))
+	case errors.Is(err, apperr.ErrConflict):
+		writeJSON(w, http.StatusConflict, errorBody(err.Error()))
+	default:
+		slog.Error(op+" failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/noteservice"
+)
+
+// Citations handles GET /api/citations.
+//
+//	@Summary		List references
+//	@Description	Returns the reference notes (a citekey or doi in the frontmatter, or any note under
+//	@Description	references/) sorted by cite key, with the notes citing each one. Notes cite a
+//	@Description	reference with [[@citekey]]. Pass note to list only the references one note cites.
+//	@Tags			citations
+//	@Produce		json
+//	@Param			note	query		string	false	"Only references cited by this note"
+//	@Success		200		{object}	CitationsResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/citations [get]
+func (h *Handler) Citations(w http.ResponseWriter, r *http.Request) {
+	refs, ok := h.citations(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, CitationsResponse{References: refs, Total: len(refs)})
+}
+
+// CitationsBibTeX handles GET /api/citations/bibtex.
+//
+//	@Summary		Export references as BibTeX
+//	@Description	Renders the references listed by GET /api/citations as a BibTeX database. The entry
+//	@Description	type comes from the entry_type frontmatter field, else article (journal set), book
+//	@Description	(publisher set), or misc.
+//	@Tags			citations
+//	@Produce		plain
+//	@Param			note	query		string	false	"Only references cited by this note"
+//	@Success		200		{string}	string	"BibTeX database"
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/citations/bibtex [get]
+func (h *Handler) CitationsBibTeX(w http.ResponseWriter, r *http.Request) {
+	refs, ok := h.citations(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-bibtex; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="references.bib"`)
+	_, _ = w.Write([]byte(noteservice.BibTeX(refs)))
+}
+
+// citations loads the references for the citation handlers, writing the
+// error response and reporting false on failure.
+func (h *Handler) citations(w http.ResponseWriter, r *http.Request) ([]Reference, bool) {
+	note := r.URL.Query().Get("note")
+	refs, err := h.svc.Citations(r.Context(), note)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("note not found"))
+			return nil, false
+		}
+		slog.Error("citations failed", slog.String("note", note), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return nil, false
+	}
+	return refs, true
+}
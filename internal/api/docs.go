@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	swaggerfiles "github.com/swaggo/files/v2"
+	"gopkg.in/yaml.v3"
+
+	apischema "github.com/starford/kenaz/api/schema/kenaz"
+)
+
+// swaggerUIPage renders Swagger UI against the spec served next to it. The
+// Swagger UI assets and the script starting it are served by the docs
+// handler too, so the page works offline and without inline scripts. The
+// relative URLs keep the page working under both /api and /api/v1.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Kenaz API</title>
+<link rel="stylesheet" href="docs/ui/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="docs/ui/swagger-ui-bundle.js"></script>
+<script src="docs/init.js"></script>
+</body>
+</html>
+`
+
+// swaggerUIInit starts Swagger UI on the page.
+const swaggerUIInit = `window.ui = SwaggerUIBundle({ url: "docs/openapi.json", dom_id: "#swagger-ui" });
+`
+
+// DocsHandler serves the embedded OpenAPI spec and a Swagger UI page.
+type DocsHandler struct {
+	once    sync.Once
+	jsonDoc []byte
+	jsonErr error
+}
+
+// NewDocsHandler creates a docs handler backed by the embedded spec.
+func NewDocsHandler() *DocsHandler {
+	return &DocsHandler{}
+}
+
+// UI handles GET /api/docs.
+func (d *DocsHandler) UI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(swaggerUIPage))
+}
+
+// Init handles GET /api/docs/init.js.
+func (d *DocsHandler) Init(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	_, _ = w.Write([]byte(swaggerUIInit))
+}
+
+// Assets handles GET /api/docs/ui/*, serving the Swagger UI files embedded
+// from swagger-ui-dist.
+func (d *DocsHandler) Assets(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, swaggerfiles.FS, chi.URLParam(r, "*"))
+}
+
+// SpecYAML handles GET /api/docs/openapi.yaml.
+func (d *DocsHandler) SpecYAML(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(apischema.OpenAPIYAML)
+}
+
+// SpecJSON handles GET /api/docs/openapi.json. The YAML spec is converted
+// once and cached.
+func (d *DocsHandler) SpecJSON(w http.ResponseWriter, _ *http.Request) {
+	d.once.Do(func() {
+		var doc map[string]any
+		if err := yaml.Unmarshal(apischema.OpenAPIYAML, &doc); err != nil {
+			d.jsonErr = err
+			return
+		}
+		d.jsonDoc, d.jsonErr = json.Marshal(doc)
+	})
+	if d.jsonErr != nil {
+		slog.Error("openapi spec conversion failed", slog.String("error", d.jsonErr.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(d.jsonDoc)
+}
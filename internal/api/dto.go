@@ -3,13 +3,54 @@ package api
 import (
 	"time"
 
+	"github.com/starford/kenaz/internal/backup"
+	"github.com/starford/kenaz/internal/index"
 	"github.com/starford/kenaz/internal/noteservice"
 )
 
-// CreateNoteRequest is the request body for creating a note.
+// CreateNoteRequest is the request body for creating a note. Without Path
+// the note is created under Folder at a file name derived from Title.
 type CreateNoteRequest struct {
-	Path    string `json:"path" example:"notes/hello.md" validate:"required"`
-	Content string `json:"content" example:"# Hello\nWorld" validate:"required"`
+	Path    string `json:"path,omitempty" example:"notes/hello.md"`
+	Content string `json:"content" example:"# Hello\nWorld"`
+	// Title names a note created without Path; defaults to the content's
+	// title.
+	Title  string `json:"title,omitempty" example:"Заметки о встрече"`
+	Folder string `json:"folder,omitempty" example:"meetings"`
+}
+
+// BatchOp is one operation of a batch request (aliased from the domain
+// layer).
+type BatchOp = noteservice.BatchOp
+
+// BatchRequest is the request body for POST /api/notes/batch.
+type BatchRequest struct {
+	Ops []BatchOp `json:"ops" validate:"required"`
+	// DryRun reports what the operations would do without writing.
+	DryRun bool `json:"dry_run" example:"false"`
+}
+
+// BatchResult is the outcome of one batch operation.
+type BatchResult struct {
+	Op   string `json:"op" example:"create" validate:"required"`
+	Path string `json:"path" example:"imports/meeting.md" validate:"required"`
+	// Status is the HTTP status the operation would have had on its own:
+	// 201 created, 200 updated, 204 deleted, or an error status.
+	Status int `json:"status" example:"201" validate:"required"`
+	// Checksum is the note's new checksum after a create or update.
+	Checksum string `json:"checksum,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchResponse lists the outcome of every batch operation, in request
+// order. A dry run also lists the planned changes, with a diff per created
+// or updated note.
+type BatchResponse struct {
+	Results   []BatchResult               `json:"results" validate:"required"`
+	Succeeded int                         `json:"succeeded" validate:"required"`
+	Failed    int                         `json:"failed" validate:"required"`
+	DryRun    bool                        `json:"dry_run"`
+	Changes   []noteservice.PlannedChange `json:"changes,omitempty"`
 }
 
 // UpdateNoteRequest is the request body for updating a note.
@@ -17,6 +58,273 @@ type UpdateNoteRequest struct {
 	Content string `json:"content" example:"# Updated\nContent" validate:"required"`
 }
 
+// PreviewMergeRequest is the request body for a merge preview: the content
+// the client started editing from and its edited version.
+type PreviewMergeRequest struct {
+	Base    string `json:"base" example:"# Hello\nWorld"`
+	Content string `json:"content" example:"# Hello\nThere" validate:"required"`
+}
+
+// CopyNoteRequest is the request body for duplicating a note.
+type CopyNoteRequest struct {
+	// TargetPath is the path of the copy.
+	TargetPath string `json:"target_path" example:"projects/new-project.md"`
+	// To is the former name of TargetPath, still accepted.
+	To string `json:"to,omitempty" example:"projects/new-project.md"`
+	// ResetDates sets existing created_at/updated_at frontmatter fields to now.
+	ResetDates bool `json:"reset_dates" example:"true"`
+	// StripFields are frontmatter fields left out of the copy.
+	StripFields []string `json:"strip_fields,omitempty" example:"created,reviewed"`
+}
+
+// PatchNoteRequest is a partial edit of a note (aliased from the domain
+// layer).
+type PatchNoteRequest = noteservice.Patch
+
+// AppendNoteRequest is the request body for appending to a note.
+type AppendNoteRequest struct {
+	Content string `json:"content" example:"- 12:30 deployed v2" validate:"required"`
+	// Heading targets the end of that section ("Inbox" or "## Inbox")
+	// instead of the end of the note.
+	Heading string `json:"heading" example:"Inbox"`
+}
+
+// CaptureRequest is the request body for a quick capture.
+type CaptureRequest struct {
+	Text string `json:"text" example:"Call the dentist" validate:"required"`
+	// Tags are appended as #tags; a leading "#" is optional.
+	Tags []string `json:"tags" example:"todo"`
+}
+
+// BookmarkRequest is the request body for saving a bookmark.
+type BookmarkRequest struct {
+	URL string `json:"url" example:"https://go.dev/blog/go1.22" validate:"required"`
+	// Title and Description override the values read from the page.
+	Title       string   `json:"title" example:"Go 1.22 is released"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags" example:"golang"`
+	// Fetch controls whether the page is downloaded for its title,
+	// description, and og:image (default true).
+	Fetch *bool `json:"fetch"`
+}
+
+// BookmarkReadRequest marks a bookmark read or unread.
+type BookmarkReadRequest struct {
+	Path string `json:"path" example:"bookmarks/go-1-22-is-released.md" validate:"required"`
+	Read bool   `json:"read"`
+}
+
+// RecheckLinksRequest selects the external link to recheck; an empty URL
+// rechecks every dead link.
+type RecheckLinksRequest struct {
+	URL string `json:"url,omitempty" example:"https://example.com/gone"`
+}
+
+// DailyAppendRequest is the request body for appending to today's daily note.
+type DailyAppendRequest struct {
+	Text string `json:"text" example:"Shipped the release" validate:"required"`
+}
+
+// MoveFolderRequest is the request body for moving a folder.
+type MoveFolderRequest struct {
+	From string `json:"from" example:"projects/kenaz" validate:"required"`
+	To   string `json:"to" example:"archive/kenaz" validate:"required"`
+	// DryRun returns the planned changes without writing.
+	DryRun bool `json:"dry_run" example:"false"`
+}
+
+// CardReviewRequest grades a flashcard review.
+type CardReviewRequest struct {
+	Path string `json:"path" example:"study/go.md" validate:"required"`
+	Card string `json:"card" example:"3f2a9c1be044" validate:"required"`
+	// Grade is the SM-2 recall grade: 0 (blackout) to 5 (perfect); below 3
+	// counts as forgotten.
+	Grade *int `json:"grade" example:"4" validate:"required"`
+}
+
+// ReplaceRequest is the request body for a vault-wide find and replace.
+// Exactly one of Query and Regex is required.
+type ReplaceRequest struct {
+	// Query is literal text to find.
+	Query string `json:"query" example:"Kenaz v1"`
+	// Regex is an RE2 pattern; Replacement may then use $1 or ${name}.
+	Regex       string `json:"regex" example:"v(\\d+)\\.0"`
+	Replacement string `json:"replacement" example:"Kenaz v2"`
+	IgnoreCase  bool   `json:"ignore_case"`
+	// Folder and Tag restrict the notes searched.
+	Folder string `json:"folder" example:"projects"`
+	Tag    string `json:"tag"`
+	// DryRun returns the matches and a diff per note without writing.
+	DryRun bool `json:"dry_run" example:"true"`
+}
+
+// RenameTagRequest is the request body for renaming or merging a tag.
+type RenameTagRequest struct {
+	From string `json:"from" example:"draft" validate:"required"`
+	To   string `json:"to" example:"status/draft" validate:"required"`
+	// DryRun returns a diff per note without writing.
+	DryRun bool `json:"dry_run" example:"true"`
+}
+
+// EditFrontmatterRequest is the request body for a bulk frontmatter edit.
+// At least one of Set and Unset is required.
+type EditFrontmatterRequest struct {
+	// Paths lists the notes to edit; without it, every note under Folder
+	// with Tag is edited.
+	Paths  []string `json:"paths" example:"projects/kenaz.md"`
+	Folder string   `json:"folder" example:"projects"`
+	Tag    string   `json:"tag"`
+	// Set adds or replaces frontmatter fields.
+	Set map[string]any `json:"set"`
+	// Unset removes frontmatter fields.
+	Unset []string `json:"unset" example:"owner"`
+	// DryRun returns a diff per note without writing.
+	DryRun bool `json:"dry_run" example:"true"`
+}
+
+// CalendarResponse holds per-day note activity for a month (aliased from
+// the domain layer).
+type CalendarResponse = noteservice.CalendarMonth
+
+// TimelineResponse is a page of notes grouped by day (aliased from the
+// domain layer).
+type TimelineResponse = noteservice.TimelinePage
+
+// WritingStatsResponse is per-day writing activity with totals and streaks
+// (aliased from the domain layer).
+type WritingStatsResponse = noteservice.WritingStats
+
+// HeatmapResponse is a year of per-day edit counts (aliased from the domain
+// layer).
+type HeatmapResponse = noteservice.Heatmap
+
+// Bookmark is a saved web page (aliased from the domain layer).
+type Bookmark = noteservice.Bookmark
+
+// BookmarkListResponse lists bookmarks, newest first.
+type BookmarkListResponse struct {
+	Bookmarks []Bookmark `json:"bookmarks" validate:"required"`
+	Total     int        `json:"total" validate:"required"`
+}
+
+// UpcomingTasksResponse groups open tasks by due date (aliased from the
+// domain layer).
+type UpcomingTasksResponse = noteservice.UpcomingTasks
+
+// Flashcard is a flashcard with its review schedule (aliased from the
+// domain layer).
+type Flashcard = index.Card
+
+// DueCardsResponse is the flashcard review queue (aliased from the domain
+// layer).
+type DueCardsResponse = noteservice.DueCards
+
+// LinkMetadata is the cached preview of an external page (aliased from the
+// index layer).
+type LinkMetadata = index.LinkMetadata
+
+// LinkPreviewsResponse lists a note's external links with their previews
+// (aliased from the domain layer).
+type LinkPreviewsResponse = noteservice.LinkPreviews
+
+// BreadcrumbsResponse is a note's folder ancestry and parent chain (aliased
+// from the domain layer).
+type BreadcrumbsResponse = noteservice.Breadcrumbs
+
+// OutlineResponse is the heading structure of a note (aliased from the
+// domain layer).
+type OutlineResponse = noteservice.Outline
+
+// Suggestion is a quick-switcher match (aliased from the domain layer).
+type Suggestion = noteservice.Suggestion
+
+// SuggestResponse lists the notes matching a quick-switcher query, best
+// first.
+type SuggestResponse struct {
+	Results []Suggestion `json:"results" validate:"required"`
+}
+
+// SitemapResponse is the folder tree of the vault's notes (aliased from the
+// domain layer).
+type SitemapResponse = noteservice.Sitemap
+
+// Revision is an earlier version of a note (aliased from the domain layer).
+type Revision = noteservice.Revision
+
+// TrashEntry is a file in the trash (aliased from the index layer).
+type TrashEntry = index.TrashEntry
+
+// DeadLink is an external URL whose last check failed (aliased from the
+// index layer).
+type DeadLink = index.DeadLink
+
+// DeadLinksResponse is the dead external link report (aliased from the
+// domain layer).
+type DeadLinksResponse = noteservice.DeadLinks
+
+// GraphClustersResponse assigns graph nodes to clusters of related notes
+// (aliased from the domain layer).
+type GraphClustersResponse = noteservice.GraphClusters
+
+// GraphMetricsResponse lists the degree and centrality of every graph node
+// (aliased from the domain layer).
+type GraphMetricsResponse = noteservice.GraphMetrics
+
+// LocalGraphResponse is the neighborhood of a note (aliased from the index
+// layer).
+type LocalGraphResponse = index.LocalGraph
+
+// GraphHealthResponse reports orphan notes, broken wikilinks, and duplicate
+// titles (aliased from the index layer).
+type GraphHealthResponse = index.GraphHealth
+
+// ReindexStatusResponse is the state of the running or last reindex
+// (aliased from the domain layer).
+type ReindexStatusResponse = noteservice.ReindexStatus
+
+// IndexStatsResponse summarizes the index next to the vault (aliased from
+// the domain layer).
+type IndexStatsResponse = noteservice.IndexStats
+
+// OptimizeIndexResponse reports a run of index maintenance (aliased from
+// the domain layer).
+type OptimizeIndexResponse = index.OptimizeResult
+
+// GitPushResponse reports a push of the vault history (aliased from the
+// domain layer).
+type GitPushResponse = noteservice.GitPush
+
+// BackupResponse describes a backup archive (aliased from the domain
+// layer).
+type BackupResponse = backup.Info
+
+// GraphPathResponse is a shortest chain of links connecting two notes, with
+// alternatives as short when asked for (aliased from the domain layer).
+type GraphPathResponse = noteservice.GraphPath
+
+// FolderMoveResponse lists moved notes and notes whose links were rewritten
+// (aliased from the domain layer).
+type FolderMoveResponse = noteservice.FolderMove
+
+// ReplaceResponse lists the notes changed by a find and replace or its
+// preview (aliased from the domain layer).
+type ReplaceResponse = noteservice.ReplaceResult
+
+// BulkEditResponse lists the notes changed by a tag rename or frontmatter
+// edit or its preview (aliased from the domain layer).
+type BulkEditResponse = noteservice.BulkEdit
+
+// FolderDeleteResponse lists the files removed by a folder delete or its
+// preview (aliased from the domain layer).
+type FolderDeleteResponse = noteservice.FolderDelete
+
+// Mutation is a recorded note change that can be undone (aliased from the
+// domain layer).
+type Mutation = noteservice.Mutation
+
+// MergePreview is the merge preview response (aliased from the domain layer).
+type MergePreview = noteservice.MergePreview
+
 // NoteDetail is the full note response type (aliased from the domain layer).
 type NoteDetail = noteservice.NoteDetail
 
@@ -35,23 +343,56 @@ type SearchResult struct {
 	Path    string `json:"path" example:"notes/hello.md" validate:"required"`
 	Title   string `json:"title" example:"Hello" validate:"required"`
 	Snippet string `json:"snippet" example:"...matched text..." validate:"required"`
+	// Matches locates the query matches in the title and body, so editors
+	// can jump to and highlight them.
+	Matches []SearchMatch `json:"matches" validate:"required"`
+	// Section is the heading the first body match falls under, if any.
+	Section *SearchSection `json:"section,omitempty"`
 }
 
-// SearchResponse wraps search results.
+// SearchSection is the heading a search hit falls under (aliased from the
+// index layer).
+type SearchSection = index.SectionRef
+
+// SearchMatch is where a query matched in a note, as byte and rune offsets
+// into its title or body (aliased from the index layer).
+type SearchMatch = index.Match
+
+// SearchResponse wraps one page of search results.
 type SearchResponse struct {
 	Results []SearchResult `json:"results" validate:"required"`
+	// Total counts all matches, not just this page.
+	Total int `json:"total" example:"134" validate:"required"`
 }
 
 // GraphNode is a node in the knowledge graph.
 type GraphNode struct {
 	ID    string `json:"id" example:"notes/hello.md" validate:"required"`
 	Title string `json:"title,omitempty" example:"Hello"`
+	// X and Y are the server-computed layout position.
+	X float64 `json:"x" example:"-120.5" validate:"required"`
+	Y float64 `json:"y" example:"48.2" validate:"required"`
+	// InDegree and OutDegree count the notes linking to and from the node.
+	InDegree  int `json:"in_degree" example:"4" validate:"required"`
+	OutDegree int `json:"out_degree" example:"2" validate:"required"`
+	// PageRank is the node's share of the graph's PageRank, which sums to 1.
+	PageRank float64 `json:"pagerank" example:"0.012" validate:"required"`
+	// Betweenness is the share of shortest paths between other notes that
+	// pass through the node, from 0 to 1.
+	Betweenness float64 `json:"betweenness" example:"0.05" validate:"required"`
+	// Cluster is the node's cluster ID, with clusters=true only.
+	Cluster *int `json:"cluster,omitempty" example:"0"`
 }
 
 // GraphLink is an edge in the knowledge graph.
 type GraphLink struct {
 	Source string `json:"source" example:"notes/hello.md" validate:"required"`
 	Target string `json:"target" example:"notes/world.md" validate:"required"`
+	Type   string `json:"type" example:"inline" enums:"inline,frontmatter" validate:"required"`
+	// Types lists the distinct link types between the two notes.
+	Types []string `json:"types" example:"inline,frontmatter" validate:"required"`
+	// Weight is how many times the source references the target.
+	Weight int `json:"weight" example:"3" validate:"required"`
 }
 
 // GraphResponse wraps the knowledge graph.
@@ -60,11 +401,49 @@ type GraphResponse struct {
 	Links []GraphLink `json:"links" validate:"required"`
 }
 
+// SlugifyResponse is the suggested file name and vault path for a title.
+type SlugifyResponse struct {
+	Slug string `json:"slug" example:"weekly-standup" validate:"required"`
+	Path string `json:"path" example:"meetings/weekly-standup-2.md" validate:"required"`
+}
+
 // AttachmentUploadResponse is returned after a successful attachment upload.
 type AttachmentUploadResponse struct {
 	Filename string `json:"filename" example:"image.png" validate:"required"`
 	Size     int64  `json:"size" example:"12345" validate:"required"`
 	URL      string `json:"url" example:"/attachments/image.png" validate:"required"`
+	// Hash is the hex SHA-256 of the file content.
+	Hash string `json:"hash" example:"9f86d081884c7d65..." validate:"required"`
+	// VersionedURL embeds the content hash and is served with an immutable
+	// Cache-Control header.
+	VersionedURL string `json:"versioned_url" example:"/attachments/image.png?v=9f86d081884c7d65" validate:"required"`
+	// OriginalURL points at the untouched upload when the image was
+	// resized and originals are kept.
+	OriginalURL string `json:"original_url,omitempty" example:"/attachments/image.original.png"`
+	// OriginalName is the uploaded file name when the attachment is stored
+	// under its content hash (attachments.content_addressed).
+	OriginalName string `json:"original_name,omitempty" example:"image.png"`
+}
+
+// UploadFromURLRequest imports an attachment from a remote URL or data URI.
+type UploadFromURLRequest struct {
+	URL      string `json:"url" example:"https://example.com/diagram.png" validate:"required"`
+	Filename string `json:"filename,omitempty" example:"diagram.png"`
+}
+
+// CreateUploadRequest starts a resumable chunked upload.
+type CreateUploadRequest struct {
+	Filename string `json:"filename" example:"video.mp4" validate:"required"`
+	Size     int64  `json:"size" example:"52428800" validate:"required"`
+}
+
+// UploadStatus reports the progress of a chunked upload. Offset is the number
+// of bytes received; the next chunk must start there.
+type UploadStatus struct {
+	ID       string `json:"id" example:"3f1c2a4e-8b7d-4c1e-9a0f-5d6e7f8a9b0c" validate:"required"`
+	Filename string `json:"filename" example:"video.mp4" validate:"required"`
+	Size     int64  `json:"size" example:"52428800" validate:"required"`
+	Offset   int64  `json:"offset" example:"0" validate:"required"`
 }
 
 // RenameNoteRequest is the request body for renaming a note or directory.
@@ -101,3 +480,28 @@ type NoteListItemDTO struct {
 	Tags      []string  `json:"tags" example:"tag1,tag2"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// Reference is a reference note with the notes citing it.
+type Reference = index.Reference
+
+// CitationsResponse lists reference notes sorted by cite key.
+type CitationsResponse struct {
+	References []Reference `json:"references" validate:"required"`
+	Total      int         `json:"total" validate:"required"`
+}
+
+// MetadataKeysResponse lists the extractors with indexed values.
+type MetadataKeysResponse struct {
+	Keys []string `json:"keys" validate:"required"`
+}
+
+// MetadataValuesResponse lists the values indexed under one extractor with
+// the notes each occurs in.
+type MetadataValuesResponse struct {
+	Key    string                `json:"key" validate:"required"`
+	Values []index.MetadataValue `json:"values" validate:"required"`
+}
+
+// ImportResponse reports what POST /api/import did (aliased from the domain
+// layer).
+type ImportResponse = noteservice.ImportResult
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/noteservice"
+)
+
+// ExportNote handles GET /api/notes/{path}/export.
+//
+//	@Summary		Export a note bundle
+//	@Description	Zips the note, the notes it links to or embeds up to depth hops away (default 1,
+//	@Description	at most 5; 0 exports the note alone), and every attachment they reference. Notes
+//	@Description	keep their vault paths, attachments go to attachments/, and attachment URLs are
+//	@Description	rewritten relative to each note so the bundle is self-contained.
+//	@Tags			notes
+//	@Produce		application/zip
+//	@Param			path	path		string	true	"Note path"
+//	@Param			format	query		string	false	"Export format"	Enums(bundle)
+//	@Param			depth	query		int		false	"Link hops to follow"
+//	@Success		200		{file}		file	"Zip archive"
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/export [get]
+func (h *Handler) ExportNote(w http.ResponseWriter, r *http.Request, notePath string) {
+	q := r.URL.Query()
+	if f := q.Get("format"); f != "" && f != "bundle" {
+		writeJSON(w, http.StatusBadRequest, errorBody("unsupported format: "+f))
+		return
+	}
+	depth := noteservice.DefaultBundleDepth
+	if v := q.Get("depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody("depth must be an integer"))
+			return
+		}
+		depth = d
+	}
+
+	b, err := h.svc.ExportBundle(r.Context(), notePath, depth)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		default:
+			slog.Error("export note failed", slog.String("path", notePath), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+
+	name := strings.TrimSuffix(path.Base(notePath), ".md") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	if err := b.WriteZip(w); err != nil {
+		slog.Error("write note bundle failed", slog.String("path", notePath), slog.String("error", err.Error()))
+	}
+}
+
+// maxImportBytes caps the size of an archive uploaded to POST /api/import.
+const maxImportBytes = 512 << 20 // 512 MB
+
+// ExportVault handles GET /api/export.
+//
+//	@Summary		Export the vault
+//	@Description	Streams a zip of every note and attachment, keeping vault paths, for
+//	@Description	POST /import on another instance. With folder or tag, only the notes under
+//	@Description	the folder and carrying the tag are exported, with the attachments they
+//	@Description	reference.
+//	@Tags			vault
+//	@Produce		application/zip
+//	@Param			folder	query		string	false	"Export only this folder"
+//	@Param			tag		query		string	false	"Export only notes with this tag"
+//	@Success		200		{file}		file	"Zip archive"
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/export [get]
+func (h *Handler) ExportVault(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	folder := strings.Trim(q.Get("folder"), "/")
+	e, err := h.svc.ExportVault(r.Context(), folder, q.Get("tag"))
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("folder not found"))
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		default:
+			slog.Error("export vault failed", slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+
+	name := "kenaz-export.zip"
+	if folder != "" {
+		name = path.Base(folder) + ".zip"
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	if err := e.WriteZip(r.Context(), w); err != nil {
+		slog.Error("write vault export failed", slog.String("error", err.Error()))
+	}
+}
+
+// ImportVault handles POST /api/import.
+//
+//	@Summary		Import a vault archive
+//	@Description	Unpacks a zip sent as the request body (up to 512 MB), such as one from
+//	@Description	GET /export, into the vault and indexes its notes. conflict says what to do
+//	@Description	with files that already exist: skip them (default), overwrite them (the
+//	@Description	replaced note is kept as a revision), or rename the imported file with a
+//	@Description	numeric suffix. Files identical to the vault's are skipped. Attachments go
+//	@Description	through the upload pipeline (virus scan, SVG sanitizing, metadata stripping,
+//	@Description	resizing); one it rejects fails alone. The archive is
+//	@Description	rejected before anything is written if an entry lies outside the vault or in
+//	@Description	the trash, .kenaz, or .git. With dry_run=true, nothing is written: the result
+//	@Description	reports what the import would do, with a unified diff per note in changes.
+//	@Tags			vault
+//	@Accept			application/zip
+//	@Produce		json
+//	@Param			conflict	query		string	false	"Conflict strategy"	Enums(skip, overwrite, rename)
+//	@Param			dry_run		query		bool	false	"Only preview the import"
+//	@Success		200			{object}	ImportResponse
+//	@Failure		400			{object}	errResponse
+//	@Failure		413			{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/import [post]
+func (h *Handler) ImportVault(w http.ResponseWriter, r *http.Request) {
+	tmp, err := os.CreateTemp("", "kenaz-import-*.zip")
+	if err != nil {
+		slog.Error("import vault failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	defer tmp.Close()           //nolint:errcheck
+
+	size, err := io.Copy(tmp, http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorBody("archive too large"))
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, errorBody("failed to read body"))
+		return
+	}
+
+	q := r.URL.Query()
+	res, err := h.svc.ImportVault(r.Context(), tmp, size, q.Get("conflict"), q.Get("dry_run") == "true")
+	if err != nil {
+		switch {
+		case errors.Is(err, noteservice.ErrInvalidImport), errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		default:
+			slog.Error("import vault failed", slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -9,12 +9,19 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
 	"github.com/starford/kenaz/internal/noteservice"
+	"github.com/starford/kenaz/internal/slug"
 )
 
+// MutationIDHeader carries the undo ID of a delete (other writes return it as
+// mutation_id in the body).
+const MutationIDHeader = "X-Mutation-ID"
+
 // Handler holds API route handlers.
 type Handler struct {
 	svc *noteservice.Service
@@ -42,13 +49,21 @@ func notePath(r *http.Request) string {
 // ListNotes handles GET /api/notes.
 //
 //	@Summary		List notes with optional pagination and filtering
+//	@Description	prop.<key>=<value> filters by a frontmatter property, e.g. prop.status=in-progress.
+//	@Description	Values compare case-insensitively; repeating a key matches any of its values,
+//	@Description	and an empty value matches notes that set the key.
 //	@Tags			notes
 //	@Produce		json
-//	@Param			limit	query		int		false	"Page size"
-//	@Param			offset	query		int		false	"Page offset"
-//	@Param			tag		query		string	false	"Filter by tag"
-//	@Param			sort	query		string	false	"Sort field"	Enums(updated_at, title, path)
-//	@Success		200		{object}	NoteListResponse
+//	@Param			limit			query		int		false	"Page size"
+//	@Param			offset			query		int		false	"Page offset"
+//	@Param			tag				query		string	false	"Filter by tag"
+//	@Param			sort			query		string	false	"Sort field"	Enums(updated_at, created_at, title, path)
+//	@Param			updated_after	query		string	false	"Only notes updated at or after (YYYY-MM-DD or RFC 3339)"
+//	@Param			updated_before	query		string	false	"Only notes updated before (YYYY-MM-DD or RFC 3339)"
+//	@Param			created_after	query		string	false	"Only notes created at or after (YYYY-MM-DD or RFC 3339)"
+//	@Param			created_before	query		string	false	"Only notes created before (YYYY-MM-DD or RFC 3339)"
+//	@Success		200				{object}	NoteListResponse
+//	@Failure		400				{object}	errResponse
 //	@Security		BearerAuth
 //	@Router			/notes [get]
 func (h *Handler) ListNotes(w http.ResponseWriter, r *http.Request) {
@@ -57,8 +72,26 @@ func (h *Handler) ListNotes(w http.ResponseWriter, r *http.Request) {
 	offset, _ := strconv.Atoi(q.Get("offset"))
 	tag := q.Get("tag")
 	sort := q.Get("sort")
+	dates, ok := dateFilters(w, r)
+	if !ok {
+		return
+	}
+	filter := noteservice.NoteFilter{DateRange: dates}
+	for name, values := range q {
+		key, ok := strings.CutPrefix(name, "prop.")
+		if !ok {
+			continue
+		}
+		if strings.TrimSpace(key) == "" {
+			writeJSON(w, http.StatusBadRequest, errorBody("property filter needs a key: prop.<key>=<value>"))
+			return
+		}
+		for _, v := range values {
+			filter.Properties = append(filter.Properties, noteservice.Property{Key: key, Value: strings.TrimSpace(v)})
+		}
+	}
 
-	items, total, err := h.svc.ListNotes(r.Context(), limit, offset, tag, sort)
+	items, total, err := h.svc.ListNotes(r.Context(), limit, offset, tag, sort, filter)
 	if err != nil {
 		slog.Error("list notes failed", slog.String("error", err.Error()))
 		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
@@ -91,6 +124,37 @@ func (h *Handler) GetNote(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, errorBody("path is required"))
 		return
 	}
+	// chi wildcards cannot carry a suffix, so GET sub-resources of a note
+	// ({path}/export, {path}/link-previews, {path}/breadcrumbs,
+	// {path}/outline, {path}/html, {path}/revisions[/{id}]) are split off
+	// here.
+	if note, id, ok := splitRevisions(path); ok {
+		if id == "" {
+			h.ListRevisions(w, r, note)
+		} else {
+			h.GetRevision(w, r, note, id)
+		}
+		return
+	}
+	if i := strings.LastIndex(path, "/"); i > 0 && strings.HasSuffix(path[:i], ".md") {
+		switch path[i+1:] {
+		case "export":
+			h.ExportNote(w, r, path[:i])
+			return
+		case "link-previews":
+			h.LinkPreviews(w, r, path[:i])
+			return
+		case "breadcrumbs":
+			h.Breadcrumbs(w, r, path[:i])
+			return
+		case "outline":
+			h.Outline(w, r, path[:i])
+			return
+		case "html":
+			h.NoteHTML(w, r, path[:i])
+			return
+		}
+	}
 	note, err := h.svc.GetNote(r.Context(), path)
 	if err != nil {
 		if errors.Is(err, apperr.ErrNotFound) {
@@ -104,9 +168,37 @@ func (h *Handler) GetNote(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, note)
 }
 
+// GetNoteByID handles GET /api/notes/by-id/{id}.
+//
+//	@Summary		Get a single note by its stable ID
+//	@Tags			notes
+//	@Produce		json
+//	@Param			id	path		string	true	"Note ID (frontmatter id)"
+//	@Success		200	{object}	NoteDetail
+//	@Failure		404	{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/by-id/{id} [get]
+func (h *Handler) GetNoteByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	note, err := h.svc.GetNoteByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		} else {
+			slog.Error("get note by id failed", slog.String("id", id), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
 // CreateNote handles POST /api/notes.
 //
 //	@Summary		Create a new note
+//	@Description	Without a path, the note is created under folder at a kebab-case English
+//	@Description	file name derived from title (or the content's title), transliterating
+//	@Description	Cyrillic, with a numeric suffix when the name is taken.
 //	@Tags			notes
 //	@Accept			json
 //	@Produce		json
@@ -118,23 +210,33 @@ func (h *Handler) GetNote(w http.ResponseWriter, r *http.Request) {
 //	@Router			/notes [post]
 func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
-	var req struct {
-		Path    string `json:"path"`
-		Content string `json:"content"`
-	}
+	var req CreateNoteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
 		return
 	}
-	if req.Path == "" || req.Content == "" {
-		writeJSON(w, http.StatusBadRequest, errorBody("path and content are required"))
-		return
+	var note *NoteDetail
+	var err error
+	if req.Path == "" {
+		if req.Title == "" && req.Content == "" {
+			writeJSON(w, http.StatusBadRequest, errorBody("path or title is required"))
+			return
+		}
+		note, err = h.svc.CreateNoteTitled(r.Context(), req.Title, req.Folder, []byte(req.Content))
+	} else {
+		if req.Content == "" {
+			writeJSON(w, http.StatusBadRequest, errorBody("content is required"))
+			return
+		}
+		note, err = h.svc.CreateNote(r.Context(), req.Path, []byte(req.Content))
 	}
-	note, err := h.svc.CreateNote(r.Context(), req.Path, []byte(req.Content))
 	if err != nil {
-		if errors.Is(err, apperr.ErrAlreadyExists) {
+		switch {
+		case errors.Is(err, apperr.ErrAlreadyExists):
 			writeJSON(w, http.StatusConflict, errorBody("note already exists"))
-		} else {
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		default:
 			slog.Error("create note failed", slog.String("path", req.Path), slog.String("error", err.Error()))
 			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
 		}
@@ -156,6 +258,7 @@ func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) {
 //	@Failure		400		{object}	errResponse
 //	@Failure		404		{object}	errResponse
 //	@Failure		409		{object}	errResponse
+//	@Failure		428		{object}	errResponse
 //	@Security		BearerAuth
 //	@Router			/notes/{path} [put]
 func (h *Handler) UpdateNote(w http.ResponseWriter, r *http.Request) {
@@ -194,6 +297,8 @@ func (h *Handler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusNotFound, errorBody("not found"))
 		case errors.Is(err, apperr.ErrConflict):
 			writeJSON(w, http.StatusConflict, errorBody("checksum mismatch"))
+		case errors.Is(err, apperr.ErrPreconditionRequired):
+			writeJSON(w, http.StatusPreconditionRequired, errorBody("If-Match header is required"))
 		default:
 			slog.Error("update note failed", slog.String("path", path), slog.String("error", err.Error()))
 			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
@@ -203,6 +308,135 @@ func (h *Handler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, note)
 }
 
+// NoteAction handles POST /api/notes/{path}/{action}. chi wildcards cannot
+// carry a suffix, so the action is split off the note path here.
+func (h *Handler) NoteAction(w http.ResponseWriter, r *http.Request) {
+	full := notePath(r)
+	i := strings.LastIndex(full, "/")
+	if i <= 0 {
+		writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		return
+	}
+	path, action := full[:i], full[i+1:]
+	if action != "preview-merge" && h.svc.ReadOnly() {
+		writeReadOnly(w)
+		return
+	}
+	switch action {
+	case "preview-merge":
+		h.PreviewMerge(w, r, path)
+	case "copy":
+		h.CopyNote(w, r, path)
+	case "append":
+		h.AppendNote(w, r, path)
+	case "restore":
+		note, id, ok := splitRevisions(path)
+		if !ok || id == "" {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		h.RestoreRevision(w, r, note, id)
+	default:
+		writeJSON(w, http.StatusNotFound, errorBody("not found"))
+	}
+}
+
+// PreviewMerge handles POST /api/notes/{path}/preview-merge.
+//
+//	@Summary		Preview a three-way merge with the current note content
+//	@Description	Merges content (edited from base) into the stored note without writing.
+//	@Description	Conflicting regions are wrapped in git-style markers and listed as hunks.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			path	path		string				true	"Note path"
+//	@Param			body	body		PreviewMergeRequest	true	"Base and edited content"
+//	@Success		200		{object}	MergePreview
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/preview-merge [post]
+func (h *Handler) PreviewMerge(w http.ResponseWriter, r *http.Request, path string) {
+	r.Body = http.MaxBytesReader(w, r.Body, 20<<20)
+	var req PreviewMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if req.Content == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("content is required"))
+		return
+	}
+
+	preview, err := h.svc.PreviewMerge(r.Context(), path, []byte(req.Base), []byte(req.Content))
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("preview merge failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// CopyNote handles POST /api/notes/{path}/copy.
+//
+//	@Summary		Duplicate a note
+//	@Description	Clones the note to target_path with a fresh id and indexes the copy. strip_fields
+//	@Description	removes frontmatter fields (e.g. "created") from the copy and reset_dates sets
+//	@Description	created_at/updated_at to now. "to" is accepted in place of target_path.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			path	path		string			true	"Source note path"
+//	@Param			body	body		CopyNoteRequest	true	"Target path and options"
+//	@Success		201		{object}	NoteDetail
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/copy [post]
+func (h *Handler) CopyNote(w http.ResponseWriter, r *http.Request, path string) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req CopyNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	target := req.TargetPath
+	if target == "" {
+		target = req.To
+	}
+	if target == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("target_path is required"))
+		return
+	}
+	if target == path {
+		writeJSON(w, http.StatusBadRequest, errorBody("target_path must differ from the source path"))
+		return
+	}
+
+	note, err := h.svc.CopyNote(r.Context(), path, target, noteservice.CopyOptions{
+		ResetDates:  req.ResetDates,
+		StripFields: req.StripFields,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		case errors.Is(err, apperr.ErrAlreadyExists):
+			writeJSON(w, http.StatusConflict, errorBody("target path already exists"))
+		default:
+			slog.Error("copy note failed", slog.String("path", path), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusCreated, note)
+}
+
 // DeleteNote handles DELETE /api/notes/*.
 // If the path ends with "/" it deletes the entire directory and all notes inside.
 //
@@ -211,6 +445,7 @@ func (h *Handler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 //	@Param			path	path	string	true	"Note or directory path"
 //	@Param			dir		query	string	false	"Set to true to delete a directory recursively"
 //	@Success		204		"Deleted"
+//	@Header			204		{string}	X-Mutation-ID	"Undo ID (note deletes only)"
 //	@Failure		404		{object}	errResponse
 //	@Security		BearerAuth
 //	@Router			/notes/{path} [delete]
@@ -237,11 +472,15 @@ func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.svc.DeleteNote(r.Context(), path); err != nil {
+	mutationID, err := h.svc.DeleteNote(r.Context(), path)
+	if err != nil {
 		slog.Error("delete note failed", slog.String("path", path), slog.String("error", err.Error()))
 		writeJSON(w, http.StatusNotFound, errorBody("not found"))
 		return
 	}
+	if mutationID != "" {
+		w.Header().Set(MutationIDHeader, mutationID)
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -312,45 +551,256 @@ func (h *Handler) RenameNote(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, note)
 }
 
+// MoveFolder handles POST /api/folders/move.
+//
+//	@Summary		Move a folder with link rewriting
+//	@Description	Relocates every note under from to the same relative path under to and rewrites
+//	@Description	wikilinks that referenced the moved notes by their folder-qualified paths.
+//	@Description	With dry_run, returns the planned changes (moves and diffs) without writing.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		MoveFolderRequest	true	"Source and target folders"
+//	@Success		200		{object}	FolderMoveResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/folders/move [post]
+func (h *Handler) MoveFolder(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req MoveFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if req.From == "" || req.To == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("from and to are required"))
+		return
+	}
+
+	res, err := h.svc.MoveFolder(r.Context(), req.From, req.To, req.DryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("folder not found"))
+		case errors.Is(err, apperr.ErrAlreadyExists):
+			writeJSON(w, http.StatusConflict, errorBody(err.Error()))
+		default:
+			slog.Error("move folder failed", slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// DeleteFolder handles DELETE /api/folders/*.
+//
+//	@Summary		Delete a folder recursively (via the trash)
+//	@Description	With dry_run=true, lists the notes and attachments that would be removed and returns
+//	@Description	a confirm_token. Repeat without dry_run and with confirm=<token> to move them to the trash.
+//	@Description	The token is single-use, expires after 5 minutes, and is rejected (409) if the folder's
+//	@Description	contents changed since the preview.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			path	path		string	true	"Folder path"
+//	@Param			dry_run	query		bool	false	"Only preview the deletion"
+//	@Param			confirm	query		string	false	"confirm_token from the preview"
+//	@Success		200		{object}	FolderDeleteResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Failure		428		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/folders/{path} [delete]
+func (h *Handler) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	folder := notePath(r)
+	if folder == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("path is required"))
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	res, err := h.svc.DeleteFolder(r.Context(), folder, dryRun, r.URL.Query().Get("confirm"))
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody("invalid path"))
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("folder not found"))
+		case errors.Is(err, apperr.ErrPreconditionRequired):
+			writeJSON(w, http.StatusPreconditionRequired, errorBody("confirm token required; preview with dry_run=true"))
+		case errors.Is(err, apperr.ErrConflict):
+			writeJSON(w, http.StatusConflict, errorBody("folder changed since the preview"))
+		default:
+			slog.Error("delete folder failed", slog.String("path", folder), slog.String("error", err.Error())) //nolint:gosec // paths are validated by storage layer
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Undo handles POST /api/undo/{id}.
+//
+//	@Summary		Undo a recent note mutation
+//	@Description	Reverts the create, update, or delete identified by id (mutation_id in write responses,
+//	@Description	X-Mutation-ID on deletes, note.mutation SSE events) while it is within vault.undo_window.
+//	@Description	The undo is itself a mutation; undo its id to redo.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			id	path		string	true	"Mutation ID"
+//	@Success		200	{object}	Mutation
+//	@Failure		404	{object}	errResponse
+//	@Failure		409	{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/undo/{id} [post]
+func (h *Handler) Undo(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	m, err := h.svc.Undo(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("unknown or expired mutation"))
+		case errors.Is(err, apperr.ErrConflict):
+			writeJSON(w, http.StatusConflict, errorBody("note changed after the mutation"))
+		default:
+			slog.Error("undo failed", slog.String("id", id), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
 // Search handles GET /api/search.
 //
 //	@Summary		Full-text search across notes
+//	@Description	Returns one page of matches and the total number of matches. sort is "rank"
+//	@Description	(relevance, the default), "updated_at" (most recent first), or "title".
+//	@Description	Each result locates its matches as byte and rune offsets into the title or
+//	@Description	body (the content after the frontmatter). q supports "phrases", prefix*,
+//	@Description	title:/body:/tags: filters, NEAR(a b, N), AND, OR, NOT, and parentheses;
+//	@Description	malformed syntax is repaired rather than rejected, and quoting a word searches
+//	@Description	it literally.
 //	@Tags			search
 //	@Produce		json
-//	@Param			q		query		string	true	"Search query"
-//	@Param			limit	query		int		false	"Max results"
-//	@Success		200		{object}	SearchResponse
-//	@Failure		400		{object}	errResponse
+//	@Param			q				query		string	true	"Search query"
+//	@Param			limit			query		int		false	"Max results (default 20)"
+//	@Param			offset			query		int		false	"Results to skip"
+//	@Param			sort			query		string	false	"Result order"	Enums(rank, updated_at, title)
+//	@Param			folder			query		string	false	"Only notes under this folder"
+//	@Param			tag				query		string	false	"Only notes with this tag"
+//	@Param			updated_after	query		string	false	"Only notes updated at or after (YYYY-MM-DD or RFC 3339)"
+//	@Param			updated_before	query		string	false	"Only notes updated before (YYYY-MM-DD or RFC 3339)"
+//	@Param			created_after	query		string	false	"Only notes created at or after (YYYY-MM-DD or RFC 3339)"
+//	@Param			created_before	query		string	false	"Only notes created before (YYYY-MM-DD or RFC 3339)"
+//	@Success		200				{object}	SearchResponse
+//	@Failure		400				{object}	errResponse
 //	@Security		BearerAuth
 //	@Router			/search [get]
 func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query().Get("q")
+	params := r.URL.Query()
+	q := params.Get("q")
 	if q == "" {
 		writeJSON(w, http.StatusBadRequest, errorBody("query parameter 'q' is required"))
 		return
 	}
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	results, err := h.svc.Search(r.Context(), q, limit)
+	dates, ok := dateFilters(w, r)
+	if !ok {
+		return
+	}
+	opts := noteservice.SearchOptions{
+		Sort:      params.Get("sort"),
+		Folder:    params.Get("folder"),
+		Tag:       params.Get("tag"),
+		DateRange: dates,
+	}
+	switch opts.Sort {
+	case "", index.SearchSortRank, index.SearchSortUpdated, index.SearchSortTitle:
+	default:
+		writeJSON(w, http.StatusBadRequest, errorBody("sort must be rank, updated_at, or title"))
+		return
+	}
+	for name, dst := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		v := params.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, errorBody(name+" must be a non-negative integer"))
+			return
+		}
+		*dst = n
+	}
+
+	results, total, err := h.svc.Search(r.Context(), q, opts)
 	if err != nil {
 		slog.Error("search failed", slog.String("query", q), slog.String("error", err.Error()))
 		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
 		return
 	}
+	if results == nil {
+		results = []index.SearchResult{}
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"results": results,
+		"total":   total,
 	})
 }
 
+// dateFilters parses the updated_after, updated_before, created_after, and
+// created_before query parameters, each a date (YYYY-MM-DD, local
+// midnight) or an RFC 3339 time. On a bad value it writes a 400 and
+// returns ok == false.
+func dateFilters(w http.ResponseWriter, r *http.Request) (dates index.DateRange, ok bool) {
+	q := r.URL.Query()
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"updated_after", &dates.UpdatedAfter},
+		{"updated_before", &dates.UpdatedBefore},
+		{"created_after", &dates.CreatedAfter},
+		{"created_before", &dates.CreatedBefore},
+	} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, v); err != nil {
+				writeJSON(w, http.StatusBadRequest, errorBody(p.name+" must be YYYY-MM-DD or an RFC 3339 time"))
+				return dates, false
+			}
+		}
+		*p.dst = t
+	}
+	return dates, true
+}
+
 // Graph handles GET /api/graph.
 //
 //	@Summary		Get the knowledge graph
+//	@Description	With clusters=true every node also carries the ID of its cluster of densely linked
+//	@Description	notes, as in GET /api/graph/clusters, for coloring related notes.
 //	@Tags			graph
 //	@Produce		json
-//	@Success		200	{object}	GraphResponse
+//	@Param			clusters	query		bool	false	"Assign nodes to clusters"
+//	@Success		200			{object}	GraphResponse
 //	@Security		BearerAuth
 //	@Router			/graph [get]
 func (h *Handler) Graph(w http.ResponseWriter, r *http.Request) {
-	nodes, links, err := h.svc.Graph(r.Context())
+	graph := h.svc.Graph
+	if r.URL.Query().Get("clusters") == "true" {
+		graph = h.svc.ClusteredGraph
+	}
+	nodes, links, err := graph(r.Context())
 	if err != nil {
 		slog.Error("graph failed", slog.String("error", err.Error()))
 		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
@@ -361,3 +811,184 @@ func (h *Handler) Graph(w http.ResponseWriter, r *http.Request) {
 		"links": links,
 	})
 }
+
+// GraphClusters handles GET /api/graph/clusters.
+//
+//	@Summary		Get clusters of related notes
+//	@Description	Groups graph nodes into communities of densely linked notes (label propagation),
+//	@Description	numbered from 0 for the largest, so clients can color and group topic areas.
+//	@Tags			graph
+//	@Produce		json
+//	@Success		200	{object}	GraphClustersResponse
+//	@Security		BearerAuth
+//	@Router			/graph/clusters [get]
+func (h *Handler) GraphClusters(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.Clusters(r.Context())
+	if err != nil {
+		slog.Error("graph clusters failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// GraphMetrics handles GET /api/graph/metrics.
+//
+//	@Summary		Get graph node metrics
+//	@Description	Returns the in and out degree, PageRank, and betweenness centrality of every graph
+//	@Description	node, sorted by decreasing PageRank. GET /graph includes the same metrics on its nodes.
+//	@Tags			graph
+//	@Produce		json
+//	@Success		200	{object}	GraphMetricsResponse
+//	@Security		BearerAuth
+//	@Router			/graph/metrics [get]
+func (h *Handler) GraphMetrics(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GraphMetrics(r.Context())
+	if err != nil {
+		slog.Error("graph metrics failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// GraphPath handles GET /api/graph/path.
+//
+//	@Summary		Get the shortest path between two notes
+//	@Description	Returns a shortest chain of links connecting two notes, following links in either
+//	@Description	direction. from and to are note paths or any link target that resolves to a note.
+//	@Description	With limit above 1, other chains as short are listed in alternatives.
+//	@Tags			graph
+//	@Produce		json
+//	@Param			from	query		string	true	"Start note"
+//	@Param			to		query		string	true	"End note"
+//	@Param			limit	query		int		false	"Shortest chains to return, 1 to 20 (default 1)"
+//	@Success		200		{object}	GraphPathResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/graph/path [get]
+func (h *Handler) GraphPath(w http.ResponseWriter, r *http.Request) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("query parameters 'from' and 'to' are required"))
+		return
+	}
+	limit := 1
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > noteservice.MaxGraphPaths {
+			writeJSON(w, http.StatusBadRequest, errorBody("limit must be between 1 and "+strconv.Itoa(noteservice.MaxGraphPaths)))
+			return
+		}
+		limit = n
+	}
+	res, err := h.svc.Path(r.Context(), from, to, limit)
+	if err != nil {
+		if errors.Is(err, noteservice.ErrNoPath) {
+			writeJSON(w, http.StatusNotFound, errorBody("no path between notes"))
+			return
+		}
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("graph path failed", slog.String("from", from), slog.String("to", to), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// LocalGraph handles GET /api/graph/local/*.
+//
+//	@Summary		Get the neighborhood of a note
+//	@Description	Returns the nodes within depth links of the note, following links in either
+//	@Description	direction, each with its distance from the note, and the links among them.
+//	@Tags			graph
+//	@Produce		json
+//	@Param			path	path		string	true	"Note path"
+//	@Param			depth	query		int		false	"Links from the note, 1 to 5 (default 2)"
+//	@Success		200		{object}	LocalGraphResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/graph/local/{path} [get]
+func (h *Handler) LocalGraph(w http.ResponseWriter, r *http.Request) {
+	path := notePath(r)
+	if path == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("path is required"))
+		return
+	}
+	depth := noteservice.DefaultLocalGraphDepth
+	if v := r.URL.Query().Get("depth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > noteservice.MaxLocalGraphDepth {
+			writeJSON(w, http.StatusBadRequest, errorBody("depth must be between 1 and "+strconv.Itoa(noteservice.MaxLocalGraphDepth)))
+			return
+		}
+		depth = n
+	}
+	g, err := h.svc.LocalGraph(r.Context(), path, depth)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("local graph failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+// GraphHealth handles GET /api/graph/health.
+//
+//	@Summary		Get a health report of the link graph
+//	@Description	Lists orphan notes (no links to or from other notes), wikilink targets that resolve
+//	@Description	to no note or attachment with the notes linking to them, and titles shared by several notes.
+//	@Tags			graph
+//	@Produce		json
+//	@Success		200	{object}	GraphHealthResponse
+//	@Security		BearerAuth
+//	@Router			/graph/health [get]
+func (h *Handler) GraphHealth(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GraphHealth(r.Context())
+	if err != nil {
+		slog.Error("graph health failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Slugify handles GET /api/slugify.
+//
+//	@Summary		Suggest a contract-compliant file name for a title
+//	@Description	Returns an English kebab-case slug (Cyrillic is transliterated) and a free vault path, suffixed with -2, -3, ... on collision.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			title	query		string	true	"Note title"
+//	@Param			folder	query		string	false	"Target folder"
+//	@Success		200		{object}	SlugifyResponse
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/slugify [get]
+func (h *Handler) Slugify(w http.ResponseWriter, r *http.Request) {
+	title := r.URL.Query().Get("title")
+	if strings.TrimSpace(title) == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("query parameter 'title' is required"))
+		return
+	}
+	p, err := h.svc.SuggestPath(r.Context(), title, r.URL.Query().Get("folder"))
+	if err != nil {
+		if errors.Is(err, apperr.ErrInvalidPath) {
+			writeJSON(w, http.StatusBadRequest, errorBody("invalid folder"))
+		} else {
+			slog.Error("slugify failed", slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, SlugifyResponse{Slug: slug.Make(title), Path: p})
+}
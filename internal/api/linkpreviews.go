@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// LinkPreviews handles GET /api/notes/{path}/link-previews.
+//
+//	@Summary		Get previews of a note's external links
+//	@Description	Lists the http(s) URLs in the note body with their page title, description,
+//	@Description	image, and favicon for rendering link cards. With link_previews.enabled, pages not
+//	@Description	cached yet (or older than link_previews.ttl) are fetched first, at most 20 per
+//	@Description	request; otherwise only cached metadata is returned.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			path	path		string	true	"Note path"
+//	@Success		200		{object}	LinkPreviewsResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/link-previews [get]
+func (h *Handler) LinkPreviews(w http.ResponseWriter, r *http.Request, path string) {
+	previews, err := h.svc.LinkPreviews(r.Context(), path)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("link previews failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, previews)
+}
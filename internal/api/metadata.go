@@ -0,0 +1,51 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// MetadataKeys handles GET /api/metadata.
+//
+//	@Summary		List metadata keys
+//	@Description	Returns the names of the parser extractors (vault.extractors) that found values
+//	@Description	in at least one note, e.g. urls, mentions, isbn.
+//	@Tags			metadata
+//	@Produce		json
+//	@Success		200	{object}	MetadataKeysResponse
+//	@Security		BearerAuth
+//	@Router			/metadata [get]
+func (h *Handler) MetadataKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.svc.MetadataKeys(r.Context())
+	if err != nil {
+		slog.Error("metadata keys failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, MetadataKeysResponse{Keys: keys})
+}
+
+// MetadataValues handles GET /api/metadata/{key}.
+//
+//	@Summary		List values of a metadata key
+//	@Description	Returns the values an extractor found with the notes each occurs in, most
+//	@Description	widespread first. Pass value to find the notes containing one value.
+//	@Tags			metadata
+//	@Produce		json
+//	@Param			key		path		string	true	"Extractor name"
+//	@Param			value	query		string	false	"Only this value"
+//	@Success		200		{object}	MetadataValuesResponse
+//	@Security		BearerAuth
+//	@Router			/metadata/{key} [get]
+func (h *Handler) MetadataValues(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+	vals, err := h.svc.MetadataValues(r.Context(), key, r.URL.Query().Get("value"))
+	if err != nil {
+		slog.Error("metadata values failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, MetadataValuesResponse{Key: key, Values: vals})
+}
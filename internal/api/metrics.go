@@ -0,0 +1,31 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Metric is one sample served by MetricsHandler.
+type Metric struct {
+	Name string
+	Help string
+	// Type is the Prometheus metric type, "counter" or "gauge".
+	Type  string
+	Value float64
+}
+
+// MetricsHandler serves the metrics returned by collect in the Prometheus
+// text exposition format, for GET /metrics.
+func MetricsHandler(collect func() []Metric) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		var b strings.Builder
+		for _, m := range collect() {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+				m.Name, m.Help, m.Name, m.Type, m.Name, strconv.FormatFloat(m.Value, 'g', -1, 64))
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(b.String()))
+	}
+}
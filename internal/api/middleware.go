@@ -25,3 +25,42 @@ func AuthMiddleware(enabled bool, token string) func(http.Handler) http.Handler
 		})
 	}
 }
+
+// APIVersion is the current REST API version. Routes are mounted under
+// /api/<APIVersion>; the bare /api prefix stays an alias for it.
+const APIVersion = "v1"
+
+// VersionHeader carries the API version. It is set on every API response;
+// clients may also send it to pin a version.
+const VersionHeader = "X-API-Version"
+
+// VersionMiddleware stamps responses with the API version and rejects
+// requests that pin a version this server does not serve.
+func VersionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(VersionHeader, APIVersion)
+		if v := r.Header.Get(VersionHeader); v != "" && !strings.EqualFold(v, APIVersion) {
+			writeJSON(w, http.StatusBadRequest, errorBody("unsupported api version: "+v))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ReadOnlyMiddleware returns middleware for the routes that change the
+// vault: when readOnly is set they answer 403 without running.
+func ReadOnlyMiddleware(readOnly bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !readOnly {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			writeReadOnly(w)
+		})
+	}
+}
+
+// writeReadOnly answers a request that would change a read-only vault.
+func writeReadOnly(w http.ResponseWriter) {
+	writeJSON(w, http.StatusForbidden, errorBody("vault is read-only"))
+}
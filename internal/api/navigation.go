@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// Breadcrumbs handles GET /api/notes/{path}/breadcrumbs.
+//
+//	@Summary		Get a note's breadcrumbs
+//	@Description	Returns the folders containing the note, outermost first, and its chain of
+//	@Description	parent notes from the "parent" frontmatter field, root first.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			path	path		string	true	"Note path"
+//	@Success		200		{object}	BreadcrumbsResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/breadcrumbs [get]
+func (h *Handler) Breadcrumbs(w http.ResponseWriter, r *http.Request, path string) {
+	crumbs, err := h.svc.Breadcrumbs(r.Context(), path)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("breadcrumbs failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, crumbs)
+}
+
+// Outline handles GET /api/notes/{path}/outline.
+//
+//	@Summary		Get a note's outline
+//	@Description	Returns the headings of the note in document order, with their level, line,
+//	@Description	and anchor (the heading's id in rendered HTML, as "#heading" links target).
+//	@Tags			notes
+//	@Produce		json
+//	@Param			path	path		string	true	"Note path"
+//	@Success		200		{object}	OutlineResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/outline [get]
+func (h *Handler) Outline(w http.ResponseWriter, r *http.Request, path string) {
+	outline, err := h.svc.Outline(r.Context(), path)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("outline failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, outline)
+}
+
+// Sitemap handles GET /api/sitemap.
+//
+//	@Summary		Get the vault sitemap
+//	@Description	Returns the notes of the vault, or of one folder, as a tree of folders. Each
+//	@Description	folder lists its subfolders and then its notes, each by name.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			folder	query		string	false	"Folder to start at (default: vault root)"
+//	@Success		200		{object}	SitemapResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/sitemap [get]
+func (h *Handler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+	sitemap, err := h.svc.Sitemap(r.Context(), folder)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody("invalid folder"))
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("no notes in folder"))
+		default:
+			slog.Error("sitemap failed", slog.String("folder", folder), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, sitemap)
+}
+
+// SuggestNotes handles GET /api/notes/suggest.
+//
+//	@Summary		Fuzzy-match notes for a quick switcher
+//	@Description	Matches q against note titles, aliases, and paths as a case-insensitive
+//	@Description	subsequence ("knzrd" finds "Kenaz Roadmap"), ranking consecutive characters,
+//	@Description	word starts, and prefix or exact matches first. Titles are held in memory, so
+//	@Description	this is cheap enough to call on every keystroke. An empty q returns no results.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			q		query		string	true	"Query"
+//	@Param			limit	query		int		false	"Max results (default 10, at most 50)"
+//	@Success		200		{object}	SuggestResponse
+//	@Security		BearerAuth
+//	@Router			/notes/suggest [get]
+func (h *Handler) SuggestNotes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	results, err := h.svc.SuggestNotes(r.Context(), q.Get("q"), limit)
+	if err != nil {
+		slog.Error("suggest notes failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, SuggestResponse{Results: results})
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// renderCSP keeps rendered notes from running scripts or loading anything
+// but images, in case a client shows the response as a page.
+const renderCSP = "default-src 'none'; img-src 'self' https: data:; style-src 'unsafe-inline'"
+
+// NoteHTML handles GET /api/notes/{path}/html.
+//
+//	@Summary		Render a note to HTML
+//	@Description	Renders the note body (without frontmatter) to a sanitized HTML fragment with
+//	@Description	goldmark (GFM and footnotes). Raw HTML in the note is sanitized and only http(s),
+//	@Description	mailto, and relative URLs are kept.
+//	@Description	Wikilinks that resolve point at /api/notes/{path} (with "#heading" kept as the
+//	@Description	heading's id), ![[file.png]] embeds become images under /attachments/, and
+//	@Description	unresolved wikilinks are spans with class "wikilink unresolved".
+//	@Tags			notes
+//	@Produce		html
+//	@Param			path	path		string	true	"Note path"
+//	@Success		200		{string}	string	"HTML fragment"
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/html [get]
+func (h *Handler) NoteHTML(w http.ResponseWriter, r *http.Request, path string) {
+	out, err := h.svc.NoteHTML(r.Context(), path)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("render note failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", renderCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.WriteString(w, out)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/noteservice"
+)
+
+// Replace handles POST /api/replace.
+//
+//	@Summary		Find and replace across notes
+//	@Description	Replaces a literal query or an RE2 regex in the bodies of every note (optionally
+//	@Description	under a folder or with a tag); frontmatter is not touched. With dry_run, returns the
+//	@Description	match counts and a unified diff per note without writing. Applied changes are
+//	@Description	re-indexed and each gets an undoable mutation_id.
+//	@Tags			notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		ReplaceRequest	true	"Search, replacement, and filters"
+//	@Success		200		{object}	ReplaceResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/replace [post]
+func (h *Handler) Replace(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req ReplaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+
+	res, err := h.svc.ReplaceAll(r.Context(), noteservice.Replace{
+		Query:       req.Query,
+		Regex:       req.Regex,
+		Replacement: req.Replacement,
+		IgnoreCase:  req.IgnoreCase,
+		Folder:      req.Folder,
+		Tag:         req.Tag,
+		DryRun:      req.DryRun,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		case errors.Is(err, apperr.ErrConflict):
+			writeJSON(w, http.StatusConflict, errorBody(err.Error()))
+		default:
+			slog.Error("replace failed", slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
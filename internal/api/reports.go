@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// DeadLinks handles GET /api/reports/dead-links.
+//
+//	@Summary		List dead external links
+//	@Description	Lists the http(s) URLs linked from notes whose last check answered 404 or 410
+//	@Description	or failed (timeout, DNS, or connection error), with when they were checked and
+//	@Description	the notes linking to them. Links are checked in the background with
+//	@Description	link_check.enabled, or on demand via POST /reports/dead-links/recheck.
+//	@Tags			reports
+//	@Produce		json
+//	@Success		200	{object}	DeadLinksResponse
+//	@Security		BearerAuth
+//	@Router			/reports/dead-links [get]
+func (h *Handler) DeadLinks(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svc.DeadLinks(r.Context())
+	if err != nil {
+		slog.Error("dead links failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// RecheckDeadLinks handles POST /api/reports/dead-links/recheck.
+//
+//	@Summary		Recheck dead external links
+//	@Description	Checks url again, or every dead link when the body or url is empty, and
+//	@Description	returns the updated report.
+//	@Tags			reports
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		RecheckLinksRequest	false	"URL to recheck"
+//	@Success		200		{object}	DeadLinksResponse
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/reports/dead-links/recheck [post]
+func (h *Handler) RecheckDeadLinks(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req RecheckLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	report, err := h.svc.RecheckDeadLinks(r.Context(), req.URL)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("no note links to this url"))
+			return
+		}
+		slog.Error("recheck dead links failed", slog.String("url", req.URL), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// revisionsSegment separates a note path from its revisions sub-resource.
+const revisionsSegment = ".md/revisions"
+
+// splitRevisions splits "{path}/revisions" and "{path}/revisions/{id}" into
+// the note path and revision ID ("" for the list).
+func splitRevisions(full string) (note, id string, ok bool) {
+	i := strings.LastIndex(full, revisionsSegment)
+	if i <= 0 {
+		return "", "", false
+	}
+	note, rest := full[:i+len(".md")], full[i+len(revisionsSegment):]
+	if rest == "" {
+		return note, "", true
+	}
+	id, ok = strings.CutPrefix(rest, "/")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", "", false
+	}
+	return note, id, true
+}
+
+// ListRevisions handles GET /api/notes/{path}/revisions.
+//
+//	@Summary		List a note's revisions
+//	@Description	Lists the earlier versions kept of the note (see history.revisions), newest first.
+//	@Description	A version is saved whenever the note is replaced with PUT or a revision restore.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			path	path	string	true	"Note path"
+//	@Success		200		{array}	Revision
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/revisions [get]
+func (h *Handler) ListRevisions(w http.ResponseWriter, r *http.Request, path string) {
+	revs, err := h.svc.Revisions(r.Context(), path)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+			return
+		}
+		slog.Error("list revisions failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, revs)
+}
+
+// GetRevision handles GET /api/notes/{path}/revisions/{id}.
+//
+//	@Summary		Get a note revision
+//	@Tags			notes
+//	@Produce		json
+//	@Param			path	path		string	true	"Note path"
+//	@Param			id		path		string	true	"Revision ID"
+//	@Success		200		{object}	Revision
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/revisions/{id} [get]
+func (h *Handler) GetRevision(w http.ResponseWriter, r *http.Request, path, id string) {
+	rev, err := h.svc.Revision(r.Context(), path, id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("revision not found"))
+			return
+		}
+		slog.Error("get revision failed", slog.String("path", path), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, rev)
+}
+
+// RestoreRevision handles POST /api/notes/{path}/revisions/{id}/restore.
+//
+//	@Summary		Restore a note revision
+//	@Description	Replaces the note's content with the revision, like PUT; the replaced content is
+//	@Description	kept as a new revision.
+//	@Tags			notes
+//	@Produce		json
+//	@Param			path		path		string	true	"Note path"
+//	@Param			id			path		string	true	"Revision ID"
+//	@Param			If-Match	header		string	false	"SHA-256 checksum for optimistic concurrency"
+//	@Success		200			{object}	NoteDetail
+//	@Failure		404			{object}	errResponse
+//	@Failure		409			{object}	errResponse
+//	@Failure		428			{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/notes/{path}/revisions/{id}/restore [post]
+func (h *Handler) RestoreRevision(w http.ResponseWriter, r *http.Request, path, id string) {
+	ifMatch := strings.Trim(r.Header.Get("If-Match"), `"`)
+	note, err := h.svc.RestoreRevision(r.Context(), path, id, ifMatch)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		case errors.Is(err, apperr.ErrConflict):
+			writeJSON(w, http.StatusConflict, errorBody("checksum mismatch"))
+		case errors.Is(err, apperr.ErrPreconditionRequired):
+			writeJSON(w, http.StatusPreconditionRequired, errorBody("If-Match header is required"))
+		default:
+			slog.Error("restore revision failed", slog.String("path", path), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
@@ -10,30 +10,128 @@ import (
 // NewRouter creates a chi router with all API routes mounted.
 // authEnabled controls whether Bearer token auth is enforced.
 // sseHandler, if non-nil, is mounted at GET /events inside the auth group.
-// vaultRoot is used to resolve the attachments directory.
-func NewRouter(svc *noteservice.Service, authEnabled bool, token string, sseHandler http.Handler, vaultRoot string) chi.Router {
+// vaultRoot is used to resolve the attachments directory; attachOpts
+// configure the attachment upload handlers.
+func NewRouter(svc *noteservice.Service, authEnabled bool, token string, sseHandler http.Handler, vaultRoot string, attachOpts ...AttachmentOption) chi.Router {
 	h := NewHandler(svc)
-	ah := NewAttachmentHandler(vaultRoot)
+	ah := NewAttachmentHandler(vaultRoot, attachOpts...)
+	dh := NewDocsHandler()
 
 	r := chi.NewRouter()
+	r.Use(VersionMiddleware)
 	r.Use(AuthMiddleware(authEnabled, token))
+	// write serves the routes that change the vault, refused when it is
+	// read-only. POST /notes/{path}/{action} checks its actions itself.
+	write := r.With(ReadOnlyMiddleware(svc.ReadOnly()))
 
 	// Notes CRUD.
 	r.Get("/notes", h.ListNotes)
-	r.Post("/notes", h.CreateNote)
-	r.Post("/notes/rename", h.RenameNote)
+	write.Post("/notes", h.CreateNote)
+	write.Post("/notes/rename", h.RenameNote)
+	write.Post("/notes/batch", h.BatchNotes)
+	r.Get("/notes/by-id/{id}", h.GetNoteByID)
+	r.Get("/notes/suggest", h.SuggestNotes)
 	r.Get("/notes/*", h.GetNote)
-	r.Put("/notes/*", h.UpdateNote)
-	r.Delete("/notes/*", h.DeleteNote)
+	r.Post("/notes/*", h.NoteAction)
+	write.Put("/notes/*", h.UpdateNote)
+	write.Patch("/notes/*", h.PatchNote)
+	write.Delete("/notes/*", h.DeleteNote)
+
+	// Navigation.
+	r.Get("/sitemap", h.Sitemap)
+
+	// Capture and daily notes.
+	write.Post("/capture", h.Capture)
+	r.Get("/daily/{date}", h.GetDaily)
+	write.Post("/daily/append", h.AppendDaily)
+
+	// Bookmarks.
+	r.Get("/bookmarks", h.ListBookmarks)
+	write.Post("/bookmarks", h.CreateBookmark)
+	write.Post("/bookmarks/read", h.MarkBookmarkRead)
+
+	// Trash.
+	r.Get("/trash", h.ListTrash)
+	write.Post("/trash/*", h.RestoreTrash)
+	write.Delete("/trash/*", h.PurgeTrash)
+
+	// Undo.
+	write.Post("/undo/{id}", h.Undo)
+
+	// Folders.
+	write.Post("/folders/move", h.MoveFolder)
+	write.Delete("/folders/*", h.DeleteFolder)
+
+	// Find and replace.
+	write.Post("/replace", h.Replace)
+
+	// Bulk tag and frontmatter edits.
+	write.Post("/tags/rename", h.RenameTag)
+	write.Post("/frontmatter", h.EditFrontmatter)
+
+	// Helpers.
+	r.Get("/slugify", h.Slugify)
 
 	// Search.
 	r.Get("/search", h.Search)
 
+	// Extractor metadata.
+	r.Get("/metadata", h.MetadataKeys)
+	r.Get("/metadata/{key}", h.MetadataValues)
+
+	// Citations.
+	r.Get("/citations", h.Citations)
+	r.Get("/citations/bibtex", h.CitationsBibTeX)
+
+	// Activity.
+	r.Get("/calendar", h.Calendar)
+	r.Get("/timeline", h.Timeline)
+	r.Get("/analytics/writing", h.WritingAnalytics)
+	r.Get("/analytics/heatmap", h.Heatmap)
+	r.Get("/tasks/upcoming", h.UpcomingTasks)
+
+	// Spaced repetition.
+	r.Get("/srs/due", h.DueCards)
+	write.Post("/srs/review", h.ReviewCard)
+
 	// Graph.
 	r.Get("/graph", h.Graph)
+	r.Get("/graph/clusters", h.GraphClusters)
+	r.Get("/graph/metrics", h.GraphMetrics)
+	r.Get("/graph/path", h.GraphPath)
+	r.Get("/graph/local/*", h.LocalGraph)
+	r.Get("/graph/health", h.GraphHealth)
+
+	// Reports.
+	r.Get("/reports/dead-links", h.DeadLinks)
+	r.Post("/reports/dead-links/recheck", h.RecheckDeadLinks)
+
+	// Vault export and import.
+	r.Get("/export", h.ExportVault)
+	write.Post("/import", h.ImportVault)
+
+	// Admin.
+	r.Post("/admin/reindex", h.Reindex)
+	r.Get("/admin/reindex", h.ReindexStatus)
+	r.Get("/admin/index/stats", h.IndexStats)
+	r.Post("/admin/index/optimize", h.OptimizeIndex)
+	r.Post("/admin/git/push", h.GitPush)
+	r.Post("/admin/backup", h.Backup)
 
 	// Attachments upload (auth-protected).
-	r.Post("/attachments", ah.Upload)
+	write.Post("/attachments", ah.Upload)
+	write.Post("/attachments/from-url", ah.UploadFromURL)
+	write.Post("/attachments/uploads", ah.CreateUpload)
+	r.Get("/attachments/uploads/{id}", ah.UploadStatus)
+	write.Patch("/attachments/uploads/{id}", ah.AppendUpload)
+	write.Delete("/attachments/uploads/{id}", ah.CancelUpload)
+
+	// API docs: embedded OpenAPI spec and Swagger UI (same auth as the API).
+	r.Get("/docs", dh.UI)
+	r.Get("/docs/init.js", dh.Init)
+	r.Get("/docs/ui/*", dh.Assets)
+	r.Get("/docs/openapi.json", dh.SpecJSON)
+	r.Get("/docs/openapi.yaml", dh.SpecYAML)
 
 	// SSE endpoint (protected by same auth middleware).
 	if sseHandler != nil {
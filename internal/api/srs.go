@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// DueCards handles GET /api/srs/due.
+//
+//	@Summary		Flashcards due for review
+//	@Description	Returns the flashcards due on the given date: reviewed cards whose next review is due,
+//	@Description	oldest first, then cards never reviewed. Cards are "Q: ... / A: ..." blocks and
+//	@Description	headings tagged #flashcard (the section below is the answer).
+//	@Tags			srs
+//	@Produce		json
+//	@Param			date	query		string	false	"Reference date as YYYY-MM-DD (default: today)"
+//	@Param			limit	query		int		false	"Max cards (default 50, max 500)"
+//	@Success		200		{object}	DueCardsResponse
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/srs/due [get]
+func (h *Handler) DueCards(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	now := time.Now()
+	if v := q.Get("date"); v != "" {
+		t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody("date must be YYYY-MM-DD"))
+			return
+		}
+		now = t
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	res, err := h.svc.DueCards(r.Context(), now, limit)
+	if err != nil {
+		slog.Error("due cards failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// ReviewCard handles POST /api/srs/review.
+//
+//	@Summary		Record a flashcard review
+//	@Description	Grades a review of one card (0 = blackout through 5 = perfect recall) and reschedules
+//	@Description	it with SM-2: a grade below 3 restarts the card at 1 day, otherwise the interval grows
+//	@Description	1 day, 6 days, then by the card's ease factor. Returns the updated card.
+//	@Tags			srs
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		CardReviewRequest	true	"Card and grade"
+//	@Success		200		{object}	Flashcard
+//	@Failure		400		{object}	errResponse
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/srs/review [post]
+func (h *Handler) ReviewCard(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req CardReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if req.Path == "" || req.Card == "" || req.Grade == nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("path, card, and grade are required"))
+		return
+	}
+
+	card, err := h.svc.ReviewCard(r.Context(), req.Path, req.Card, *req.Grade, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrInvalidPath):
+			writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("card not found"))
+		default:
+			slog.Error("card review failed", slog.String("path", req.Path), slog.String("error", err.Error()))
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, card)
+}
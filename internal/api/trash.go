@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// ListTrash handles GET /api/trash.
+//
+//	@Summary		List the trash
+//	@Description	Lists the notes and attachments moved to the trash by note, directory, and folder
+//	@Description	deletes, most recently deleted first. Trashed notes are not indexed, so they do not
+//	@Description	appear in search, backlinks, or the graph until restored.
+//	@Tags			trash
+//	@Produce		json
+//	@Success		200	{array}	TrashEntry
+//	@Security		BearerAuth
+//	@Router			/trash [get]
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.svc.Trash(r.Context())
+	if err != nil {
+		slog.Error("list trash failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// RestoreTrash handles POST /api/trash/{path}/restore.
+//
+//	@Summary		Restore a file from the trash
+//	@Description	Moves the file deleted from path back and re-indexes it. Returns the note, or 204 for
+//	@Description	an attachment.
+//	@Tags			trash
+//	@Produce		json
+//	@Param			path	path		string	true	"Path the file was deleted from"
+//	@Success		200		{object}	NoteDetail
+//	@Success		204		"Attachment restored"
+//	@Failure		404		{object}	errResponse
+//	@Failure		409		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/trash/{path}/restore [post]
+func (h *Handler) RestoreTrash(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutSuffix(notePath(r), "/restore")
+	if !ok || path == "" {
+		writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		return
+	}
+	note, err := h.svc.RestoreTrash(r.Context(), path)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, errorBody("not in the trash"))
+		case errors.Is(err, apperr.ErrAlreadyExists):
+			writeJSON(w, http.StatusConflict, errorBody("a file exists at the original path"))
+		default:
+			slog.Error("restore from trash failed", slog.String("path", path), slog.String("error", err.Error())) //nolint:gosec // paths are validated by storage layer
+			writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		}
+		return
+	}
+	if note == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
+// PurgeTrash handles DELETE /api/trash/{path}.
+//
+//	@Summary		Permanently delete a file from the trash
+//	@Tags			trash
+//	@Param			path	path	string	true	"Path the file was deleted from"
+//	@Success		204		"Deleted"
+//	@Failure		404		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/trash/{path} [delete]
+func (h *Handler) PurgeTrash(w http.ResponseWriter, r *http.Request) {
+	path := notePath(r)
+	if path == "" {
+		writeJSON(w, http.StatusBadRequest, errorBody("path is required"))
+		return
+	}
+	if err := h.svc.PurgeTrash(r.Context(), path); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorBody("not in the trash"))
+			return
+		}
+		slog.Error("purge trash failed", slog.String("path", path), slog.String("error", err.Error())) //nolint:gosec // paths are validated by storage layer
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,394 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/checksum"
+)
+
+const (
+	// uploadsDir holds in-progress chunked uploads, relative to attachments/.
+	uploadsDir = ".uploads"
+	// maxChunkedUploadBytes caps the declared size of a chunked upload.
+	maxChunkedUploadBytes = 1 << 30 // 1 GB
+	// uploadOffsetHeader carries the byte offset of a chunk (tus-style).
+	uploadOffsetHeader = "Upload-Offset"
+)
+
+// DefaultUploadTTL is how long a chunked upload may go without a chunk
+// before it is discarded, unless set with WithUploadTTL.
+const DefaultUploadTTL = 24 * time.Hour
+
+// uploadSession is the persisted metadata of a chunked upload. The received
+// bytes live next to it in <id>.part; its size is the current offset.
+type uploadSession struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// uploadPath returns the staging directory for chunked uploads.
+func (h *AttachmentHandler) uploadPath() string {
+	return filepath.Join(h.attachPath(), uploadsDir)
+}
+
+// sessionFiles returns the metadata and data file paths for an upload ID.
+// The ID must be a UUID, which keeps it from escaping the staging dir.
+func (h *AttachmentHandler) sessionFiles(id string) (meta, part string, ok bool) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", "", false
+	}
+	base := filepath.Join(h.uploadPath(), id)
+	return base + ".json", base + ".part", true
+}
+
+// loadSession reads an upload's metadata and current offset.
+func (h *AttachmentHandler) loadSession(id string) (*uploadSession, int64, error) {
+	meta, part, ok := h.sessionFiles(id)
+	if !ok {
+		return nil, 0, os.ErrNotExist
+	}
+	data, err := os.ReadFile(meta)
+	if err != nil {
+		return nil, 0, err
+	}
+	var s uploadSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, 0, err
+	}
+	info, err := os.Stat(part)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &s, info.Size(), nil
+}
+
+// CreateUpload handles POST /api/attachments/uploads.
+//
+//	@Summary		Start a resumable chunked upload
+//	@Description	Files up to 1 GB may be uploaded in chunks. Images and SVGs, which the upload
+//	@Description	pipeline rewrites (sanitizing, metadata stripping, resizing), are limited to 50 MB.
+//	@Tags			attachments
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		CreateUploadRequest	true	"Target file name and total size"
+//	@Success		201		{object}	UploadStatus
+//	@Failure		400		{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/attachments/uploads [post]
+func (h *AttachmentHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	var req CreateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid JSON body"))
+		return
+	}
+	if _, err := h.safeName(req.Filename); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		return
+	}
+	if req.Size <= 0 || req.Size > maxChunkedUploadBytes {
+		writeJSON(w, http.StatusBadRequest, errorBody("size must be between 1 and "+strconv.Itoa(maxChunkedUploadBytes)))
+		return
+	}
+	if req.Size > maxUploadBytes && asset.Rewrites(req.Filename) {
+		writeJSON(w, http.StatusBadRequest, errorBody("images and SVGs must be at most "+strconv.Itoa(maxUploadBytes)+" bytes"))
+		return
+	}
+	if err := os.MkdirAll(h.uploadPath(), 0o755); err != nil {
+		slog.Error("create uploads dir failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	h.sweepUploads(time.Now())
+
+	s := uploadSession{ID: uuid.New().String(), Filename: req.Filename, Size: req.Size, CreatedAt: time.Now().UTC()}
+	meta, part, _ := h.sessionFiles(s.ID)
+	data, _ := json.Marshal(s)
+	if err := os.WriteFile(part, nil, 0o644); err != nil {
+		slog.Error("create upload failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	if err := os.WriteFile(meta, data, 0o644); err != nil {
+		_ = os.Remove(part)
+		slog.Error("create upload failed", slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	writeJSON(w, http.StatusCreated, UploadStatus{ID: s.ID, Filename: s.Filename, Size: s.Size})
+}
+
+// UploadStatus handles GET /api/attachments/uploads/{id}.
+//
+//	@Summary		Get the offset of a chunked upload
+//	@Tags			attachments
+//	@Produce		json
+//	@Param			id	path		string	true	"Upload ID"
+//	@Success		200	{object}	UploadStatus
+//	@Failure		404	{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/attachments/uploads/{id} [get]
+func (h *AttachmentHandler) UploadStatus(w http.ResponseWriter, r *http.Request) {
+	s, offset, err := h.loadSession(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		return
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(offset, 10))
+	writeJSON(w, http.StatusOK, UploadStatus{ID: s.ID, Filename: s.Filename, Size: s.Size, Offset: offset})
+}
+
+// AppendUpload handles PATCH /api/attachments/uploads/{id}. The body is the
+// next chunk and the Upload-Offset header must equal the current offset.
+// When the last byte arrives the file is moved atomically into attachments/.
+// An upload takes one chunk at a time; a second concurrent PATCH gets 409.
+//
+//	@Summary		Append a chunk to an upload
+//	@Tags			attachments
+//	@Accept			application/octet-stream
+//	@Produce		json
+//	@Param			id				path		string	true	"Upload ID"
+//	@Param			Upload-Offset	header		int		true	"Current offset"
+//	@Success		200				{object}	UploadStatus
+//	@Success		201				{object}	AttachmentUploadResponse
+//	@Failure		400				{object}	errResponse
+//	@Failure		404				{object}	errResponse
+//	@Failure		409				{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/attachments/uploads/{id} [patch]
+func (h *AttachmentHandler) AppendUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !h.beginAppend(id) {
+		writeJSON(w, http.StatusConflict, errorBody("another chunk is being uploaded"))
+		return
+	}
+	defer h.endAppend(id)
+
+	s, offset, err := h.loadSession(id)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		return
+	}
+	claimed, err := strconv.ParseInt(r.Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody(uploadOffsetHeader+" header is required"))
+		return
+	}
+	if claimed != offset {
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(offset, 10))
+		writeJSON(w, http.StatusConflict, errorBody("offset mismatch"))
+		return
+	}
+
+	_, part, _ := h.sessionFiles(id)
+	f, err := os.OpenFile(part, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		slog.Error("open upload failed", slog.String("id", id), slog.String("error", err.Error()))
+		writeJSON(w, http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	// Read at most the remaining bytes; a longer body is rejected below.
+	n, copyErr := io.Copy(f, io.LimitReader(r.Body, s.Size-offset+1))
+	if n > s.Size-offset {
+		// Drop the overflow so the upload stays resumable.
+		_ = f.Truncate(offset)
+		f.Close()
+		writeJSON(w, http.StatusBadRequest, errorBody("chunk exceeds declared size"))
+		return
+	}
+	if err := f.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	offset += n
+	// A dropped connection keeps whatever arrived; the client resumes from
+	// the offset reported by GET.
+	if copyErr != nil && !errors.Is(copyErr, io.ErrUnexpectedEOF) {
+		slog.Warn("upload chunk interrupted", slog.String("id", id), slog.String("error", copyErr.Error()))
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(offset, 10))
+	if offset < s.Size {
+		writeJSON(w, http.StatusOK, UploadStatus{ID: s.ID, Filename: s.Filename, Size: s.Size, Offset: offset})
+		return
+	}
+
+	resp, err := h.finalizeUpload(r.Context(), s)
+	if err != nil {
+		if errors.Is(err, asset.ErrInfected) {
+			h.discardUpload(s.ID)
+		}
+		writeProcessError(w, s.Filename, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// beginAppend claims the upload id for one chunk; it reports false when
+// another chunk of it is in progress.
+func (h *AttachmentHandler) beginAppend(id string) bool {
+	h.uploadMu.Lock()
+	defer h.uploadMu.Unlock()
+	if h.appending[id] {
+		return false
+	}
+	h.appending[id] = true
+	return true
+}
+
+// endAppend releases the claim of beginAppend.
+func (h *AttachmentHandler) endAppend(id string) {
+	h.uploadMu.Lock()
+	defer h.uploadMu.Unlock()
+	delete(h.appending, id)
+}
+
+// sweepUploads discards the chunked uploads, and files left of broken
+// ones, that have not changed for the upload TTL, except those receiving
+// a chunk.
+func (h *AttachmentHandler) sweepUploads(now time.Time) {
+	if h.uploadTTL <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(h.uploadPath())
+	if err != nil {
+		return
+	}
+	// An upload is as fresh as the newer of its two files.
+	latest := make(map[string]time.Time)
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if ext != ".json" && ext != ".part" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		id := strings.TrimSuffix(e.Name(), ext)
+		if t := info.ModTime(); t.After(latest[id]) {
+			latest[id] = t
+		}
+	}
+
+	h.uploadMu.Lock()
+	defer h.uploadMu.Unlock()
+	n := 0
+	for id, t := range latest {
+		if now.Sub(t) < h.uploadTTL || h.appending[id] {
+			continue
+		}
+		h.discardUpload(id)
+		n++
+	}
+	if n > 0 {
+		slog.Info("discarded stale chunked uploads", slog.Int("count", n), slog.Duration("ttl", h.uploadTTL))
+	}
+}
+
+// discardUpload removes an upload's metadata and data files.
+func (h *AttachmentHandler) discardUpload(id string) {
+	meta, part, ok := h.sessionFiles(id)
+	if !ok {
+		return
+	}
+	_ = os.Remove(meta)
+	_ = os.Remove(part)
+}
+
+// finalizeUpload stores a complete upload in attachments/ and drops its
+// session. Uploads up to maxUploadBytes, and files the pipeline rewrites
+// whatever their size, go through the full pipeline like a multipart
+// upload; larger opaque files are only scanned (streamed from disk) and
+// then renamed into place, which keeps readers from seeing a partial file.
+func (h *AttachmentHandler) finalizeUpload(ctx context.Context, s *uploadSession) (AttachmentUploadResponse, error) {
+	_, part, _ := h.sessionFiles(s.ID)
+	abs, err := h.safeName(s.Filename)
+	if err != nil {
+		return AttachmentUploadResponse{}, err
+	}
+	if s.Size <= maxUploadBytes || asset.Rewrites(s.Filename) {
+		data, err := os.ReadFile(part)
+		if err != nil {
+			return AttachmentUploadResponse{}, err
+		}
+		resp, err := h.store(ctx, abs, s.Filename, data)
+		if err != nil {
+			return AttachmentUploadResponse{}, err
+		}
+		h.discardUpload(s.ID)
+		return resp, nil
+	}
+
+	f, err := os.Open(part)
+	if err != nil {
+		return AttachmentUploadResponse{}, err
+	}
+	sum, err := checksum.SumReader(f)
+	if err == nil && h.pipeline != nil && h.pipeline.Scanner != nil {
+		if _, err = f.Seek(0, io.SeekStart); err == nil {
+			err = h.pipeline.Scanner.Scan(ctx, s.Filename, f)
+		}
+	}
+	f.Close()
+	if err != nil {
+		return AttachmentUploadResponse{}, err
+	}
+	stored := s.Filename
+	if h.contentAddressed {
+		stored = asset.ContentNameForSum(s.Filename, sum)
+		abs = filepath.Join(h.attachPath(), stored)
+	}
+	// Content already stored under its content name is not moved again.
+	if _, err := os.Stat(abs); !h.contentAddressed || err != nil {
+		if err := os.Rename(part, abs); err != nil {
+			return AttachmentUploadResponse{}, err
+		}
+	}
+	h.discardUpload(s.ID)
+	return h.uploaded(stored, s.Filename, s.Size, sum)
+}
+
+// CancelUpload handles DELETE /api/attachments/uploads/{id}.
+//
+//	@Summary		Abort a chunked upload
+//	@Tags			attachments
+//	@Param			id	path	string	true	"Upload ID"
+//	@Success		204
+//	@Failure		404	{object}	errResponse
+//	@Failure		409	{object}	errResponse
+//	@Security		BearerAuth
+//	@Router			/attachments/uploads/{id} [delete]
+func (h *AttachmentHandler) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	h.uploadMu.Lock()
+	defer h.uploadMu.Unlock()
+
+	id := chi.URLParam(r, "id")
+	meta, _, ok := h.sessionFiles(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		return
+	}
+	if _, err := os.Stat(meta); err != nil {
+		writeJSON(w, http.StatusNotFound, errorBody("not found"))
+		return
+	}
+	if h.appending[id] {
+		writeJSON(w, http.StatusConflict, errorBody("a chunk is being uploaded"))
+		return
+	}
+	h.discardUpload(id)
+	w.WriteHeader(http.StatusNoContent)
+}
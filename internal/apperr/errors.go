@@ -3,7 +3,14 @@ package apperr
 import "errors"
 
 var (
-	ErrNotFound     = errors.New("not found")
-	ErrConflict     = errors.New("conflict")
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
 	ErrAlreadyExists = errors.New("already exists")
+	ErrInvalidPath   = errors.New("invalid path")
+	// ErrPreconditionRequired means an update was unconditional (no
+	// checksum) while the server requires one.
+	ErrPreconditionRequired = errors.New("precondition required")
+	// ErrReadOnly means a change was refused because the vault is
+	// read-only.
+	ErrReadOnly = errors.New("read-only")
 )
@@ -0,0 +1,252 @@
+// Package asset fetches and validates remote attachments (images and PDFs)
+// before they are stored in the vault. It is shared by the MCP upload_asset
+// tool and the REST upload-from-URL endpoint.
+package asset
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxSize is the largest asset accepted from a URL or data URI.
+const MaxSize = 10 << 20 // 10 MB
+
+var (
+	allowedExtensions = map[string]bool{
+		".png": true, ".jpg": true, ".jpeg": true,
+		".gif": true, ".webp": true, ".svg": true, ".pdf": true,
+	}
+
+	mimeToExt = map[string]string{
+		"image/png":       ".png",
+		"image/jpeg":      ".jpg",
+		"image/gif":       ".gif",
+		"image/webp":      ".webp",
+		"image/svg+xml":   ".svg",
+		"application/pdf": ".pdf",
+	}
+
+	safeFilenameRe = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+)
+
+// Prepare downloads rawURL (http, https, or a base64 data: URI), picks a safe
+// file name (filename if given, otherwise derived from the URL), and checks
+// size, extension, and magic bytes. It returns the file name and content.
+func Prepare(rawURL, filename string) (string, []byte, error) {
+	var data []byte
+	var detectedExt string
+	var err error
+
+	if strings.HasPrefix(rawURL, "data:") {
+		data, detectedExt, err = decodeDataURI(rawURL)
+	} else {
+		data, detectedExt, err = fetchHTTP(rawURL)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(data) > MaxSize {
+		return "", nil, fmt.Errorf("file too large: %d bytes (max %d)", len(data), MaxSize)
+	}
+
+	if filename == "" {
+		filename = filenameFromURL(rawURL, detectedExt)
+	}
+	filename = SanitizeFilename(filename)
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !allowedExtensions[ext] {
+		return "", nil, fmt.Errorf("unsupported file extension: %s (allowed: png, jpg, jpeg, gif, webp, svg, pdf)", ext)
+	}
+
+	if err := ValidateMagicBytes(data, ext); err != nil {
+		return "", nil, err
+	}
+	return filename, data, nil
+}
+
+// decodeDataURI parses a data:[<mediatype>][;base64],<data> URI.
+func decodeDataURI(uri string) ([]byte, string, error) {
+	rest := strings.TrimPrefix(uri, "data:")
+	commaIdx := strings.Index(rest, ",")
+	if commaIdx < 0 {
+		return nil, "", fmt.Errorf("invalid data URI: missing comma separator")
+	}
+
+	meta := rest[:commaIdx]
+	encoded := rest[commaIdx+1:]
+
+	if !strings.Contains(meta, ";base64") {
+		return nil, "", fmt.Errorf("only base64 data URIs are supported")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		data, err = base64.RawStdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid base64 data: %w", err)
+		}
+	}
+
+	mime := strings.Split(strings.TrimSuffix(meta, ";base64"), ";")[0]
+	ext := mimeToExt[mime]
+	if ext == "" {
+		return nil, "", fmt.Errorf("unsupported MIME type in data URI: %s", mime)
+	}
+	return data, ext, nil
+}
+
+// fetchHTTP downloads a file from an HTTP/HTTPS URL with security checks.
+func fetchHTTP(rawURL string) ([]byte, string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, "", fmt.Errorf("unsupported scheme: %s (only http/https)", parsed.Scheme)
+	}
+
+	if err := checkBlockedHost(parsed.Hostname()); err != nil {
+		return nil, "", err
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects (max 5)")
+			}
+			return checkBlockedHost(req.URL.Hostname())
+		},
+	}
+
+	resp, err := client.Get(rawURL) //nolint:noctx
+	if err != nil {
+		return nil, "", fmt.Errorf("download failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	}
+
+	limited := io.LimitReader(resp.Body, MaxSize+1)
+	data, err := io.ReadAll(limited)
+	if err != nil {
+		return nil, "", fmt.Errorf("read body failed: %w", err)
+	}
+	if len(data) > MaxSize {
+		return nil, "", fmt.Errorf("file too large: exceeds %d bytes", MaxSize)
+	}
+
+	ct := resp.Header.Get("Content-Type")
+	ext := mimeToExt[strings.Split(ct, ";")[0]]
+	return data, ext, nil
+}
+
+// ErrBlockedHost is returned for URLs on loopback and cloud metadata
+// addresses, which are never fetched.
+var ErrBlockedHost = errors.New("blocked host")
+
+// checkBlockedHost rejects loopback and cloud metadata addresses.
+func checkBlockedHost(host string) error {
+	if host == "metadata.google.internal" {
+		return fmt.Errorf("%w: %s", ErrBlockedHost, host)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ips, lookupErr := net.LookupIP(host)
+		if lookupErr != nil || len(ips) == 0 {
+			return nil //nolint:nilerr // let http.Client handle DNS failures
+		}
+		ip = ips[0]
+	}
+
+	if ip.IsLoopback() {
+		return fmt.Errorf("%w: loopback address %s", ErrBlockedHost, host)
+	}
+	// AWS/GCP/Azure metadata endpoint.
+	if ip.Equal(net.ParseIP("169.254.169.254")) {
+		return fmt.Errorf("%w: cloud metadata address %s", ErrBlockedHost, host)
+	}
+	return nil
+}
+
+// filenameFromURL tries to extract a filename from a URL, falling back to UUID.
+func filenameFromURL(rawURL string, fallbackExt string) string {
+	if strings.HasPrefix(rawURL, "data:") {
+		ext := fallbackExt
+		if ext == "" {
+			ext = ".bin"
+		}
+		return uuid.New().String() + ext
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err == nil {
+		base := path.Base(parsed.Path)
+		if base != "" && base != "." && base != "/" && strings.Contains(base, ".") {
+			return base
+		}
+	}
+
+	ext := fallbackExt
+	if ext == "" {
+		ext = ".bin"
+	}
+	return uuid.New().String() + ext
+}
+
+// SanitizeFilename strips path separators and unsafe characters.
+func SanitizeFilename(name string) string {
+	name = filepath.Base(name)
+	name = safeFilenameRe.ReplaceAllString(name, "_")
+	if name == "" || name == "." {
+		name = uuid.New().String()
+	}
+	return name
+}
+
+// ValidateMagicBytes verifies file content matches the declared extension.
+func ValidateMagicBytes(data []byte, ext string) error {
+	if ext == ".svg" {
+		prefix := data
+		if len(prefix) > 1024 {
+			prefix = prefix[:1024]
+		}
+		if !bytes.Contains(prefix, []byte("<svg")) {
+			return fmt.Errorf("content does not appear to be a valid SVG (missing <svg tag)")
+		}
+		return nil
+	}
+
+	detected := http.DetectContentType(data)
+	expectedExts := mimeToExt[strings.Split(detected, ";")[0]]
+
+	switch ext {
+	case ".jpg", ".jpeg":
+		if expectedExts != ".jpg" && expectedExts != ".jpeg" {
+			return fmt.Errorf("content does not match extension %s (detected: %s)", ext, detected)
+		}
+	default:
+		if expectedExts != ext {
+			return fmt.Errorf("content does not match extension %s (detected: %s)", ext, detected)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package asset
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/starford/kenaz/internal/checksum"
+)
+
+// NamesFile is the content-addressed name index, relative to attachments/.
+const NamesFile = ".names.json"
+
+// contentNameRe matches a content-addressed name: a hex SHA-256 and an
+// optional extension, with ".original" for a kept original image.
+var contentNameRe = regexp.MustCompile(`^[0-9a-f]{64}(\.original)?(\.[a-z0-9]+)?$`)
+
+// ContentName returns the content-addressed name for data uploaded as
+// original: its hex SHA-256 followed by the original's lower-cased extension.
+func ContentName(original string, data []byte) string {
+	return ContentNameForSum(original, checksum.Sum(data))
+}
+
+// ContentNameForSum is ContentName for content with hex SHA-256 sum.
+func ContentNameForSum(original, sum string) string {
+	return sum + strings.ToLower(filepath.Ext(original))
+}
+
+// IsContentName reports whether name is a content-addressed name.
+func IsContentName(name string) bool {
+	return contentNameRe.MatchString(name)
+}
+
+// Files reads and writes vault files by slash-separated relative path.
+// storage.Provider satisfies it.
+type Files interface {
+	Read(path string) ([]byte, error)
+	Write(path string, content []byte) error
+}
+
+// namesMu serializes read-modify-write cycles of the name index.
+var namesMu sync.Mutex
+
+// RecordName adds original to the file names recorded for the
+// content-addressed attachment name in the index at attachments/.names.json.
+func RecordName(files Files, name, original string) error {
+	namesMu.Lock()
+	defer namesMu.Unlock()
+
+	names, err := readNames(files)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(names[name], original) {
+		return nil
+	}
+	names[name] = append(names[name], original)
+	data, err := json.MarshalIndent(names, "", "  ")
+	if err != nil {
+		return err
+	}
+	return files.Write("attachments/"+NamesFile, data)
+}
+
+// OriginalNames returns the file names the content-addressed attachment name
+// was uploaded as, oldest first.
+func OriginalNames(files Files, name string) ([]string, error) {
+	namesMu.Lock()
+	defer namesMu.Unlock()
+
+	names, err := readNames(files)
+	if err != nil {
+		return nil, err
+	}
+	return names[name], nil
+}
+
+func readNames(files Files) (map[string][]string, error) {
+	names := map[string][]string{}
+	data, err := files.Read("attachments/" + NamesFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return names, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
@@ -0,0 +1,66 @@
+package asset
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"testing"
+)
+
+// memFiles is an in-memory Files.
+type memFiles map[string][]byte
+
+func (m memFiles) Read(path string) ([]byte, error) {
+	data, ok := m[path]
+	if !ok {
+		return nil, fmt.Errorf("read %s: %w", path, fs.ErrNotExist)
+	}
+	return data, nil
+}
+
+func (m memFiles) Write(path string, content []byte) error {
+	m[path] = content
+	return nil
+}
+
+func TestContentName(t *testing.T) {
+	name := ContentName("Diagram.PNG", []byte("hello"))
+	if want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824.png"; name != want {
+		t.Errorf("ContentName = %q, want %q", name, want)
+	}
+	for n, want := range map[string]bool{
+		name:                    true,
+		OriginalName(name):      true,
+		name[:64]:               true,
+		"diagram.png":           false,
+		name[:63] + ".png":      false,
+		"../" + name:            false,
+		name[:64] + ".PNG":      false,
+		name[:64] + ".tar.gz.x": false,
+	} {
+		if got := IsContentName(n); got != want {
+			t.Errorf("IsContentName(%q) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestRecordName(t *testing.T) {
+	files := memFiles{}
+	if names, err := OriginalNames(files, "a.png"); err != nil || names != nil {
+		t.Fatalf("OriginalNames on empty index = %v, %v", names, err)
+	}
+	for _, n := range []string{"one.png", "two.png", "one.png"} {
+		if err := RecordName(files, "a.png", n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordName(files, "b.pdf", "doc.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := OriginalNames(files, "a.png"); !slices.Equal(names, []string{"one.png", "two.png"}) {
+		t.Errorf("OriginalNames(a.png) = %v", names)
+	}
+	if names, _ := OriginalNames(files, "b.pdf"); !slices.Equal(names, []string{"doc.pdf"}) {
+		t.Errorf("OriginalNames(b.pdf) = %v", names)
+	}
+}
@@ -0,0 +1,49 @@
+package asset
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// checkTimeout bounds a link check, redirects included.
+const checkTimeout = 10 * time.Second
+
+// CheckURL reports the HTTP status an http(s) URL answers with after
+// redirects, using a HEAD request and falling back to GET for servers that
+// do not support HEAD. It has the host guards of FetchPage; an error means
+// the URL could not be reached (timeout, DNS, connection, or a blocked host
+// wrapping ErrBlockedHost).
+func CheckURL(ctx context.Context, rawURL string) (int, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return 0, fmt.Errorf("unsupported scheme: %s (only http/https)", parsed.Scheme)
+	}
+	if err := hostCheck(parsed.Hostname()); err != nil {
+		return 0, err
+	}
+
+	client := guardedClient(checkTimeout)
+	status := 0
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return 0, fmt.Errorf("invalid URL: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("request failed: %w", err)
+		}
+		_ = resp.Body.Close()
+		status = resp.StatusCode
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented {
+			break
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,41 @@
+package asset
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	hostCheck = func(string) error { return nil }
+	t.Cleanup(func() { hostCheck = checkBlockedHost })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/gone", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for path, want := range map[string]int{"/ok": 200, "/moved": 404, "/no-head": 200} {
+		if got, err := CheckURL(context.Background(), srv.URL+path); err != nil || got != want {
+			t.Errorf("CheckURL(%s) = %d, %v, want %d", path, got, err, want)
+		}
+	}
+
+	hostCheck = checkBlockedHost
+	if _, err := CheckURL(context.Background(), "http://127.0.0.1/"); !errors.Is(err, ErrBlockedHost) {
+		t.Errorf("loopback: err = %v, want ErrBlockedHost", err)
+	}
+	if _, err := CheckURL(context.Background(), "ftp://example.com/"); err == nil {
+		t.Error("ftp: expected error")
+	}
+}
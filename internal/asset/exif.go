@@ -0,0 +1,201 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	jpegSOI   = []byte{0xFF, 0xD8}
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	exifMagic = []byte("Exif\x00\x00")
+	xmpMagic  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// exifOrientation is the TIFF tag that tells viewers how to rotate a photo.
+const exifOrientation = 0x0112
+
+// droppedPNGChunks are ancillary PNG chunks that carry metadata (EXIF,
+// free-form text such as XMP, and timestamps).
+var droppedPNGChunks = map[string]bool{
+	"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true,
+}
+
+// StripMetadata removes EXIF (including GPS), XMP, IPTC, and comments from
+// JPEG files and metadata chunks from PNG files, without re-encoding pixels.
+// A JPEG's EXIF orientation is preserved so photos keep displaying upright.
+// Other formats, and files that do not parse, are returned unchanged.
+func StripMetadata(name string, data []byte) ([]byte, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg":
+		return stripJPEG(data)
+	case ".png":
+		return stripPNG(data)
+	default:
+		return data, false
+	}
+}
+
+// stripJPEG drops APP1 (EXIF/XMP), APP13 (IPTC), and COM segments.
+func stripJPEG(data []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(data, jpegSOI) {
+		return data, false
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, jpegSOI...)
+	changed := false
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return data, false
+		}
+		marker := data[pos+1]
+		if marker == 0xFF { // fill byte
+			pos++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image
+			out = append(out, data[pos:]...)
+			return out, changed
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) { // standalone markers
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		}
+		segLen := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + segLen
+		if segLen < 2 || end > len(data) {
+			return data, false
+		}
+		payload := data[pos+4 : end]
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(payload, exifMagic):
+			if o := readOrientation(payload[len(exifMagic):]); o > 1 {
+				out = append(out, orientationSegment(o)...)
+			}
+			changed = true
+		case marker == 0xE1 && bytes.HasPrefix(payload, xmpMagic),
+			marker == 0xED, marker == 0xFE:
+			changed = true
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return data, false
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 0 when it has
+// none or does not parse.
+func jpegOrientation(data []byte) uint16 {
+	if !bytes.HasPrefix(data, jpegSOI) {
+		return 0
+	}
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			pos++
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			return 0
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			pos += 2
+			continue
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) || end < pos+4 {
+			return 0
+		}
+		if payload := data[pos+4 : end]; marker == 0xE1 && bytes.HasPrefix(payload, exifMagic) {
+			return readOrientation(payload[len(exifMagic):])
+		}
+		pos = end
+	}
+	return 0
+}
+
+// readOrientation returns the orientation tag from a TIFF-structured EXIF
+// block, or 0 when it is absent or malformed.
+func readOrientation(tiff []byte) uint16 {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(bo.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	n := int(bo.Uint16(tiff[ifd:]))
+	for i := 0; i < n; i++ {
+		e := ifd + 2 + i*12
+		if e+12 > len(tiff) {
+			return 0
+		}
+		if bo.Uint16(tiff[e:]) == exifOrientation {
+			return bo.Uint16(tiff[e+8:])
+		}
+	}
+	return 0
+}
+
+// orientationSegment builds a minimal APP1 EXIF segment holding only the
+// orientation tag.
+func orientationSegment(o uint16) []byte {
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	seg = append(seg, exifMagic...)
+	seg = append(seg, 'M', 'M', 0, 0x2A, 0, 0, 0, 8) // big-endian TIFF, IFD0 at 8
+	seg = binary.BigEndian.AppendUint16(seg, 1)      // one entry
+	seg = binary.BigEndian.AppendUint16(seg, exifOrientation)
+	seg = binary.BigEndian.AppendUint16(seg, 3) // SHORT
+	seg = binary.BigEndian.AppendUint32(seg, 1) // count
+	seg = binary.BigEndian.AppendUint16(seg, o)
+	seg = append(seg, 0, 0, 0, 0, 0, 0) // value padding, no next IFD
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(seg)-2))
+	return seg
+}
+
+// stripPNG drops metadata chunks; every chunk carries its own CRC, so the
+// remaining ones are copied verbatim.
+func stripPNG(data []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(data, pngMagic) {
+		return data, false
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, pngMagic...)
+	changed := false
+	pos := len(pngMagic)
+	for pos+12 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + n
+		if n < 0 || end > len(data) {
+			return data, false
+		}
+		typ := string(data[pos+4 : pos+8])
+		if droppedPNGChunks[typ] {
+			changed = true
+		} else {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if typ == "IEND" {
+			break
+		}
+	}
+	if !changed {
+		return data, false
+	}
+	return out, true
+}
@@ -0,0 +1,85 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifSegment builds an APP1 EXIF segment with orientation and a fake GPS
+// IFD pointer tag.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte{'I', 'I', 0x2A, 0, 8, 0, 0, 0}
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientation)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint32(tiff, uint32(orientation))
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x8825) // GPSInfo
+	tiff = binary.LittleEndian.AppendUint16(tiff, 4)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, []byte("GPS 55.7558N 37.6173E")...)
+	payload := append(append([]byte{}, exifMagic...), tiff...)
+	seg := []byte{0xFF, 0xE1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func TestStripMetadata_JPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	plain := buf.Bytes()
+	withExif := append(append(append([]byte{}, plain[:2]...), exifSegment(6)...), plain[2:]...)
+
+	out, changed := StripMetadata("photo.JPG", withExif)
+	if !changed {
+		t.Fatal("EXIF segment not stripped")
+	}
+	if bytes.Contains(out, []byte("GPS 55.7558N")) {
+		t.Error("GPS data survived stripping")
+	}
+	if o := readOrientation(out[4+2+len(exifMagic):]); o != 6 {
+		t.Errorf("orientation = %d, want 6", o)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("stripped JPEG does not decode: %v", err)
+	}
+
+	if _, changed := StripMetadata("photo.jpg", plain); changed {
+		t.Error("JPEG without metadata should be unchanged")
+	}
+	if _, changed := StripMetadata("photo.jpg", []byte("not a jpeg")); changed {
+		t.Error("garbage should be unchanged")
+	}
+}
+
+func TestStripMetadata_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	plain := buf.Bytes()
+	text := []byte("Comment\x00shot at home")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(append([]byte("tEXt"), text...)))
+	// Insert after the IHDR chunk (8 magic + 25 bytes).
+	withText := append(append(append([]byte{}, plain[:33]...), chunk...), plain[33:]...)
+
+	out, changed := StripMetadata("shot.png", withText)
+	if !changed || bytes.Contains(out, []byte("shot at home")) {
+		t.Fatalf("tEXt chunk not stripped (changed = %v)", changed)
+	}
+	if !bytes.Equal(out, plain) {
+		t.Error("stripped PNG differs from the original image")
+	}
+}
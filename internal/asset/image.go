@@ -0,0 +1,191 @@
+package asset
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+)
+
+// maxDecodePixels guards against decompression bombs: larger images are
+// stored as-is rather than decoded.
+const maxDecodePixels = 100_000_000
+
+// ImageOptions controls re-encoding of uploaded JPEG and PNG images. Images
+// wider than MaxWidth or taller than MaxHeight are scaled down to fit
+// (aspect ratio kept) and re-encoded; JPEGs use Quality (1-100). A zero
+// bound means no limit on that axis. KeepOriginal stores the untouched file
+// next to the optimized one as <name>.original<ext>.
+type ImageOptions struct {
+	MaxWidth     int
+	MaxHeight    int
+	Quality      int
+	KeepOriginal bool
+}
+
+// Enabled reports whether any resizing is configured.
+func (o ImageOptions) Enabled() bool {
+	return o.MaxWidth > 0 || o.MaxHeight > 0
+}
+
+// OriginalName returns the file name used for a kept original.
+func OriginalName(name string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + ".original" + ext
+}
+
+// IsOriginalName reports whether name is that of a kept original.
+func IsOriginalName(name string) bool {
+	ext := filepath.Ext(name)
+	return strings.HasSuffix(strings.TrimSuffix(name, ext), ".original")
+}
+
+// OptimizeImage scales a JPEG or PNG down to the configured bounds. It
+// returns the input unchanged (and false) for other formats, for content
+// that does not decode, for images already within bounds, and when
+// re-encoding would not shrink the file. A JPEG's EXIF orientation is
+// applied to the pixels, since the re-encoded file carries no EXIF: bounds
+// apply to the image as displayed, and the result is stored upright.
+func OptimizeImage(name string, data []byte, opts ImageOptions) ([]byte, bool, error) {
+	if !opts.Enabled() {
+		return data, false, nil
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return data, false, nil
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return data, false, nil //nolint:nilerr // not an image we can optimize
+	}
+	if cfg.Width*cfg.Height > maxDecodePixels {
+		return data, false, nil
+	}
+	var o uint16
+	if format == "jpeg" {
+		o = jpegOrientation(data)
+	}
+	width, height := cfg.Width, cfg.Height
+	if o >= 5 && o <= 8 { // rotated a quarter turn
+		width, height = height, width
+	}
+	w, h := fitWithin(width, height, opts.MaxWidth, opts.MaxHeight)
+	if w == width && h == height {
+		return data, false, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, false, nil //nolint:nilerr // truncated or corrupt image
+	}
+	dst := downscale(orient(toRGBA(src), o), w, h)
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		q := opts.Quality
+		if q <= 0 || q > 100 {
+			q = jpeg.DefaultQuality
+		}
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: q})
+	case "png":
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, dst)
+	default:
+		return data, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("asset: encode image %s: %w", name, err)
+	}
+	if buf.Len() >= len(data) {
+		return data, false, nil
+	}
+	return buf.Bytes(), true, nil
+}
+
+// fitWithin scales w×h down to fit maxW×maxH, keeping the aspect ratio.
+func fitWithin(w, h, maxW, maxH int) (int, int) {
+	scale := 1.0
+	if maxW > 0 && w > maxW {
+		scale = float64(maxW) / float64(w)
+	}
+	if maxH > 0 && h > maxH {
+		if s := float64(maxH) / float64(h); s < scale {
+			scale = s
+		}
+	}
+	if scale == 1.0 {
+		return w, h
+	}
+	return max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+}
+
+// toRGBA copies src into an RGBA image with its origin at 0,0.
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+	return img
+}
+
+// orient returns src as displayed under EXIF orientation o (2-8 flip
+// and/or rotate it; other values leave it alone).
+func orient(src *image.RGBA, o uint16) *image.RGBA {
+	if o < 2 || o > 8 {
+		return src
+	}
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := sw, sh
+	if o >= 5 {
+		dw, dh = sh, sw
+	}
+	// at maps a destination pixel to the source pixel shown there.
+	at := map[uint16]func(x, y int) (int, int){
+		2: func(x, y int) (int, int) { return sw - 1 - x, y },
+		3: func(x, y int) (int, int) { return sw - 1 - x, sh - 1 - y },
+		4: func(x, y int) (int, int) { return x, sh - 1 - y },
+		5: func(x, y int) (int, int) { return y, x },
+		6: func(x, y int) (int, int) { return y, sh - 1 - x },
+		7: func(x, y int) (int, int) { return sw - 1 - y, sh - 1 - x },
+		8: func(x, y int) (int, int) { return sw - 1 - y, x },
+	}[o]
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := at(x, y)
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:])
+		}
+	}
+	return dst
+}
+
+// downscale resizes src to w×h with a box filter: each destination pixel is
+// the average of the source pixels it covers.
+func downscale(in *image.RGBA, w, h int) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := in.Rect.Dx(), in.Rect.Dy()
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := in.Pix[sy*in.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					bl += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			o := out.Pix[y*out.Stride+x*4:]
+			o[0], o[1], o[2], o[3] = uint8(r/n), uint8(g/n), uint8(bl/n), uint8(a/n)
+		}
+	}
+	return out
+}
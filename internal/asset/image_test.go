@@ -0,0 +1,153 @@
+package asset
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x + y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOptimizeImage(t *testing.T) {
+	opts := ImageOptions{MaxWidth: 100, MaxHeight: 100, Quality: 80}
+	data := testJPEG(t, 400, 200)
+
+	out, changed, err := OptimizeImage("photo.jpg", data, opts)
+	if err != nil || !changed {
+		t.Fatalf("OptimizeImage changed = %v, err = %v", changed, err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 100 || cfg.Height != 50 {
+		t.Errorf("resized to %dx%d, want 100x50", cfg.Width, cfg.Height)
+	}
+
+	small := testJPEG(t, 50, 50)
+	if out, changed, _ := OptimizeImage("small.jpg", small, opts); changed || !bytes.Equal(out, small) {
+		t.Error("image within bounds should be left alone")
+	}
+	if _, changed, _ := OptimizeImage("doc.pdf", data, opts); changed {
+		t.Error("non-image should be left alone")
+	}
+	if _, changed, _ := OptimizeImage("photo.jpg", data, ImageOptions{}); changed {
+		t.Error("disabled options should be a no-op")
+	}
+}
+
+func TestOptimizeImage_Orientation(t *testing.T) {
+	// Stored 400x200, left half red and right half blue, tagged to be
+	// rotated a quarter turn clockwise: displayed 200x400, red on top.
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= 200 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	plain := buf.Bytes()
+	data := append(append(append([]byte{}, plain[:2]...), exifSegment(6)...), plain[2:]...)
+
+	out, changed, err := OptimizeImage("photo.jpg", data, ImageOptions{MaxWidth: 100, MaxHeight: 100})
+	if err != nil || !changed {
+		t.Fatalf("changed = %v, err = %v", changed, err)
+	}
+	if o := jpegOrientation(out); o != 0 {
+		t.Errorf("resized image keeps orientation %d, want none", o)
+	}
+	res, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := res.Bounds(); b.Dx() != 50 || b.Dy() != 100 {
+		t.Fatalf("resized to %dx%d, want 50x100", b.Dx(), b.Dy())
+	}
+	if r, _, bl, _ := res.At(25, 10).RGBA(); r < bl {
+		t.Error("top of the resized image is not red")
+	}
+	if r, _, bl, _ := res.At(25, 90).RGBA(); bl < r {
+		t.Error("bottom of the resized image is not blue")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 2x1 image: pixel 0 at the left, pixel 1 at the right.
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Pix[0], src.Pix[4] = 10, 20
+	for o, want := range map[uint16][]uint8{
+		1: {10, 20}, 2: {20, 10}, 3: {20, 10}, 4: {10, 20},
+		5: {10, 20}, 6: {10, 20}, 7: {20, 10}, 8: {20, 10},
+	} {
+		dst := orient(src, o)
+		var got []uint8
+		for i := 0; i < len(dst.Pix); i += 4 {
+			got = append(got, dst.Pix[i])
+		}
+		if (o >= 5) != (dst.Rect.Dy() == 2) || !bytes.Equal(got, want) {
+			t.Errorf("orientation %d: %dx%d %v, want %v", o, dst.Rect.Dx(), dst.Rect.Dy(), got, want)
+		}
+	}
+}
+
+func TestOptimizeImage_PNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 300))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	out, changed, err := OptimizeImage("shot.png", buf.Bytes(), ImageOptions{MaxWidth: 150})
+	if err != nil || !changed {
+		t.Fatalf("changed = %v, err = %v", changed, err)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(out))
+	if err != nil || cfg.Width != 150 || cfg.Height != 150 {
+		t.Errorf("png resized to %dx%d (err %v), want 150x150", cfg.Width, cfg.Height, err)
+	}
+}
+
+func TestPipeline_KeepOriginal(t *testing.T) {
+	data := testJPEG(t, 400, 400)
+	p := &Pipeline{Images: ImageOptions{MaxWidth: 100, KeepOriginal: true}}
+	res, err := p.Process(t.Context(), "photo.jpg", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res.Original, data) || len(res.Data) >= len(data) {
+		t.Errorf("original kept = %v, data %d -> %d bytes", res.Original != nil, len(data), len(res.Data))
+	}
+	if got := OriginalName("dir/photo.jpg"); got != "dir/photo.original.jpg" {
+		t.Errorf("OriginalName = %q", got)
+	}
+
+	var nilPipeline *Pipeline
+	if res, err := nilPipeline.Process(t.Context(), "photo.jpg", data); err != nil || !bytes.Equal(res.Data, data) {
+		t.Errorf("nil pipeline should pass data through, err = %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package asset
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxPageSize caps how much of a web page is read for its metadata; the
+// <head> is almost always well within it.
+const maxPageSize = 2 << 20
+
+// Page is the metadata of a web page, as used for bookmarks.
+type Page struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// Image is the absolute URL of the page's og:image, if any.
+	Image string `json:"image,omitempty"`
+	// Favicon is the absolute URL of the page's icon: its <link rel="icon">,
+	// else /favicon.ico on its host.
+	Favicon string `json:"favicon,omitempty"`
+}
+
+// hostCheck vets every host a page fetch connects to; tests relax it to
+// reach httptest servers.
+var hostCheck = checkBlockedHost
+
+// FetchPage downloads an http(s) page and reads its title, description,
+// preview image, and icon from the <title> element, the description/Open
+// Graph meta tags, and the icon links. URL is the final URL after redirects.
+func FetchPage(ctx context.Context, rawURL string) (*Page, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme: %s (only http/https)", parsed.Scheme)
+	}
+	if err := hostCheck(parsed.Hostname()); err != nil {
+		return nil, err
+	}
+
+	client := guardedClient(15 * time.Second)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.5")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch failed: HTTP %d", resp.StatusCode)
+	}
+
+	page := &Page{URL: resp.Request.URL.String()}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return page, nil
+	}
+	parsePage(io.LimitReader(resp.Body, maxPageSize), resp.Request.URL, page)
+	return page, nil
+}
+
+// guardedClient returns an HTTP client that follows at most 5 redirects,
+// vetting each target with hostCheck.
+func guardedClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects (max 5)")
+			}
+			return hostCheck(req.URL.Hostname())
+		},
+	}
+}
+
+// parsePage fills page from the <head> of an HTML document, preferring
+// Open Graph values over the plain <title> and description, and icon links
+// over apple-touch-icon ones. Relative image and icon URLs are resolved
+// against base.
+func parsePage(r io.Reader, base *url.URL, page *Page) {
+	var title, desc, ogTitle, ogDesc, ogImage, icon, touchIcon string
+	z := html.NewTokenizer(r)
+	inTitle := false
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "title":
+				inTitle = title == ""
+			case "meta":
+				key, content := "", ""
+				for _, a := range tok.Attr {
+					switch a.Key {
+					case "name", "property":
+						key = strings.ToLower(a.Val)
+					case "content":
+						content = strings.TrimSpace(a.Val)
+					}
+				}
+				switch key {
+				case "description":
+					desc = content
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDesc = content
+				case "og:image", "og:image:url":
+					if ogImage == "" {
+						ogImage = content
+					}
+				}
+			case "link":
+				rel, href := "", ""
+				for _, a := range tok.Attr {
+					switch a.Key {
+					case "rel":
+						rel = strings.ToLower(a.Val)
+					case "href":
+						href = strings.TrimSpace(a.Val)
+					}
+				}
+				for _, v := range strings.Fields(rel) {
+					switch {
+					case v == "icon" && icon == "":
+						icon = href
+					case v == "apple-touch-icon" && touchIcon == "":
+						touchIcon = href
+					}
+				}
+			case "body":
+				break loop
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case html.EndTagToken:
+			if tok := z.Token(); tok.Data == "title" {
+				inTitle = false
+			} else if tok.Data == "head" {
+				break loop
+			}
+		}
+	}
+
+	page.Title = collapseSpace(firstNonEmpty(ogTitle, title))
+	page.Description = collapseSpace(firstNonEmpty(ogDesc, desc))
+	page.Image = absoluteURL(base, ogImage)
+	page.Favicon = absoluteURL(base, firstNonEmpty(icon, touchIcon, "/favicon.ico"))
+}
+
+// absoluteURL resolves ref against base, returning "" unless the result is
+// an http(s) URL.
+func absoluteURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// collapseSpace trims s and collapses runs of whitespace to single spaces.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
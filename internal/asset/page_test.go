@@ -0,0 +1,78 @@
+package asset
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFetchPage(t *testing.T) {
+	hostCheck = func(string) error { return nil }
+	t.Cleanup(func() { hostCheck = checkBlockedHost })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/article", http.StatusFound)
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<!doctype html><html><head>
+<title>
+  Plain   title
+</title>
+<meta name="description" content="Plain description">
+<meta property="og:description" content="  Open Graph description ">
+<meta property="og:image" content="/img/cover.png">
+<link rel="apple-touch-icon" href="/touch.png">
+<link rel="shortcut icon" href="static/fav.png">
+</head><body><title>ignored</title></body></html>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	page, err := FetchPage(context.Background(), srv.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.URL != srv.URL+"/article" {
+		t.Errorf("url = %q, want the redirect target", page.URL)
+	}
+	if page.Title != "Plain title" {
+		t.Errorf("title = %q", page.Title)
+	}
+	if page.Description != "Open Graph description" {
+		t.Errorf("description = %q", page.Description)
+	}
+	if page.Image != srv.URL+"/img/cover.png" {
+		t.Errorf("image = %q", page.Image)
+	}
+	if page.Favicon != srv.URL+"/static/fav.png" {
+		t.Errorf("favicon = %q", page.Favicon)
+	}
+
+	page = &Page{}
+	base, _ := url.Parse("https://example.com/a/b")
+	parsePage(strings.NewReader(`<head><title>x</title></head>`), base, page)
+	if page.Favicon != "https://example.com/favicon.ico" {
+		t.Errorf("default favicon = %q", page.Favicon)
+	}
+}
+
+func TestFetchPage_Rejects(t *testing.T) {
+	for _, u := range []string{"ftp://example.com/", "http://127.0.0.1/", "http://169.254.169.254/latest"} {
+		if _, err := FetchPage(context.Background(), u); err == nil {
+			t.Errorf("%s: expected error", u)
+		}
+	}
+
+	hostCheck = func(string) error { return nil }
+	t.Cleanup(func() { hostCheck = checkBlockedHost })
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	if _, err := FetchPage(context.Background(), srv.URL); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("404 page: err = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package asset
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidContent is returned (wrapped) when an upload cannot be made safe
+// to store, e.g. an SVG that does not parse.
+var ErrInvalidContent = errors.New("invalid attachment content")
+
+// Pipeline prepares uploaded attachments for storage. It is shared by every
+// upload path (REST multipart, chunked, from-url, and MCP upload_asset).
+// A nil *Pipeline only sanitizes SVGs.
+type Pipeline struct {
+	// Scanner, if set, vets content before anything is written.
+	Scanner Scanner
+	// StripMetadata removes EXIF/GPS and other metadata from JPEG/PNG files.
+	StripMetadata bool
+	// Images configures resizing of large JPEG/PNG images.
+	Images ImageOptions
+}
+
+// Processed is the outcome of running an upload through the pipeline.
+type Processed struct {
+	// Data is the content to store under the requested name.
+	Data []byte
+	// Original holds the full-size upload (metadata already stripped) when
+	// it was resized and ImageOptions.KeepOriginal is set; store it under
+	// OriginalName.
+	Original []byte
+}
+
+// Rewrites reports whether Process may change the content of a file named
+// name (SVGs, JPEGs, and PNGs), so that it needs the whole file in memory
+// instead of only being scanned.
+func Rewrites(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".svg", ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// Scan runs the configured scanner, if any.
+func (p *Pipeline) Scan(ctx context.Context, name string, data []byte) error {
+	if p == nil || p.Scanner == nil {
+		return nil
+	}
+	return p.Scanner.Scan(ctx, name, bytes.NewReader(data))
+}
+
+// Process scans data and then applies the transformations: SVG
+// sanitization (always), metadata stripping, and resizing. Errors wrapping
+// ErrInfected or ErrInvalidContent mean the upload must be rejected.
+func (p *Pipeline) Process(ctx context.Context, name string, data []byte) (Processed, error) {
+	if err := p.Scan(ctx, name, data); err != nil {
+		return Processed{}, err
+	}
+	if strings.EqualFold(filepath.Ext(name), ".svg") {
+		clean, err := SanitizeSVG(data)
+		if err != nil {
+			return Processed{}, err
+		}
+		data = clean
+	}
+	if p == nil {
+		return Processed{Data: data}, nil
+	}
+	if p.StripMetadata {
+		data, _ = StripMetadata(name, data)
+	}
+	out, changed, err := OptimizeImage(name, data, p.Images)
+	if err != nil {
+		return Processed{}, err
+	}
+	res := Processed{Data: out}
+	if changed && p.Images.KeepOriginal {
+		res.Original = data
+	}
+	return res, nil
+}
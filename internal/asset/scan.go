@@ -0,0 +1,166 @@
+package asset
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrInfected is returned (wrapped, with the scanner's verdict) when a scan
+// finds malware.
+var ErrInfected = errors.New("file rejected by virus scan")
+
+// defaultScanTimeout bounds a single scan when the caller sets none.
+const defaultScanTimeout = 60 * time.Second
+
+// Scanner inspects an attachment before it is persisted. Scan returns an
+// error wrapping ErrInfected when the content is rejected, and any other
+// error when the scan itself failed.
+type Scanner interface {
+	Scan(ctx context.Context, name string, r io.Reader) error
+}
+
+// CommandScanner pipes the file to a command on stdin, e.g.
+// "clamdscan --no-summary -". Exit status 0 means clean and 1 means
+// infected (the clamscan convention); anything else is a scan failure.
+type CommandScanner struct {
+	Args    []string
+	Timeout time.Duration
+}
+
+// Scan implements Scanner.
+func (s *CommandScanner) Scan(ctx context.Context, name string, r io.Reader) error {
+	if len(s.Args) == 0 {
+		return fmt.Errorf("asset: scan command is empty")
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeoutOr(s.Timeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.Args[0], s.Args[1:]...)
+	cmd.Stdin = r
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return fmt.Errorf("%w: %s: %s", ErrInfected, name, verdict(string(out)))
+	}
+	return fmt.Errorf("asset: scan %s: %w", name, err)
+}
+
+// verdict trims scanner output down to its first meaningful line.
+func verdict(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return strings.TrimPrefix(line, "stream: ")
+		}
+	}
+	return "infected"
+}
+
+// ICAPScanner submits the file to an ICAP antivirus service (RFC 3507)
+// via RESPMOD, e.g. "icap://clamav:1344/avscan". A 204 reply means clean;
+// a 200 reply (the server replaced the content) means infected.
+type ICAPScanner struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// icapChunkSize is the size of the chunks the body is streamed in.
+const icapChunkSize = 32 << 10
+
+// Scan implements Scanner.
+func (s *ICAPScanner) Scan(ctx context.Context, name string, r io.Reader) error {
+	u, err := url.Parse(s.URL)
+	if err != nil || u.Scheme != "icap" || u.Host == "" {
+		return fmt.Errorf("asset: invalid icap url %q", s.URL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "1344")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeoutOr(s.Timeout))
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return fmt.Errorf("asset: icap dial: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	reqHdr := "GET /" + url.PathEscape(name) + " HTTP/1.1\r\nHost: kenaz\r\n\r\n"
+	resHdr := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\n\r\n"
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\nHost: %s\r\nAllow: 204\r\nEncapsulated: req-hdr=0, res-hdr=%d, res-body=%d\r\n\r\n",
+		s.URL, u.Host, len(reqHdr), len(reqHdr)+len(resHdr))
+	w.WriteString(reqHdr)
+	w.WriteString(resHdr)
+	buf := make([]byte, icapChunkSize)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			w.WriteString("\r\n")
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return fmt.Errorf("asset: icap read body: %w", rerr)
+		}
+	}
+	w.WriteString("0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("asset: icap send: %w", err)
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	status, err := tp.ReadLine()
+	if err != nil {
+		return fmt.Errorf("asset: icap read status: %w", err)
+	}
+	hdr, _ := tp.ReadMIMEHeader()
+	code := ""
+	if f := strings.Fields(status); len(f) >= 2 {
+		code = f[1]
+	}
+	switch code {
+	case "204":
+		return nil
+	case "200":
+		v := hdr.Get("X-Infection-Found")
+		if v == "" {
+			v = hdr.Get("X-Virus-ID")
+		}
+		if v == "" {
+			v = hdr.Get("X-Violations-Found")
+		}
+		if v == "" {
+			v = "infected"
+		}
+		return fmt.Errorf("%w: %s: %s", ErrInfected, name, v)
+	default:
+		return fmt.Errorf("asset: icap scan %s: unexpected status %q", name, status)
+	}
+}
+
+func timeoutOr(d time.Duration) time.Duration {
+	if d <= 0 {
+		return defaultScanTimeout
+	}
+	return d
+}
@@ -0,0 +1,83 @@
+package asset
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestCommandScanner(t *testing.T) {
+	sc := &CommandScanner{Args: []string{"sh", "-c", `if grep -q EICAR; then echo "stream: Eicar-Test-Signature FOUND"; exit 1; fi`}}
+
+	if err := sc.Scan(context.Background(), "clean.png", strings.NewReader("hello")); err != nil {
+		t.Errorf("clean file: %v", err)
+	}
+	err := sc.Scan(context.Background(), "bad.png", strings.NewReader("X5O EICAR test"))
+	if !errors.Is(err, ErrInfected) {
+		t.Fatalf("infected file err = %v, want ErrInfected", err)
+	}
+	if !strings.Contains(err.Error(), "Eicar-Test-Signature") {
+		t.Errorf("verdict missing from %q", err)
+	}
+
+	broken := &CommandScanner{Args: []string{"sh", "-c", "exit 2"}}
+	if err := broken.Scan(context.Background(), "x", strings.NewReader("")); err == nil || errors.Is(err, ErrInfected) {
+		t.Errorf("scanner failure err = %v, want non-infected error", err)
+	}
+}
+
+// fakeICAP answers one RESPMOD request, replying 200 with an infection
+// header when the body contains "EICAR" and 204 otherwise.
+func fakeICAP(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Read everything up to the terminating zero-length chunk.
+			tp := textproto.NewReader(bufio.NewReader(conn))
+			var body strings.Builder
+			for {
+				line, err := tp.ReadLine()
+				if err != nil || line == "0" {
+					break
+				}
+				body.WriteString(line)
+			}
+			if strings.Contains(body.String(), "EICAR") {
+				_, _ = io.WriteString(conn, "ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Eicar;\r\n\r\n")
+			} else {
+				_, _ = io.WriteString(conn, "ICAP/1.0 204 No Content\r\n\r\n")
+			}
+			conn.Close()
+		}
+	}()
+	return "icap://" + ln.Addr().String() + "/avscan"
+}
+
+func TestICAPScanner(t *testing.T) {
+	sc := &ICAPScanner{URL: fakeICAP(t)}
+
+	if err := sc.Scan(context.Background(), "clean.png", strings.NewReader("hello")); err != nil {
+		t.Errorf("clean file: %v", err)
+	}
+	err := sc.Scan(context.Background(), "bad.png", strings.NewReader("EICAR"))
+	if !errors.Is(err, ErrInfected) || !strings.Contains(err.Error(), "Threat=Eicar") {
+		t.Errorf("infected file err = %v", err)
+	}
+	if err := (&ICAPScanner{URL: "http://x"}).Scan(context.Background(), "x", strings.NewReader("")); err == nil {
+		t.Error("non-icap url should fail")
+	}
+}
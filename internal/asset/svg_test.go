@@ -0,0 +1,44 @@
+package asset
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeSVG(t *testing.T) {
+	in := `<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY x "boom">]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" onload="alert(1)" width="10">
+  <!-- comment -->
+  <script>alert(2)</script>
+  <foreignObject><div xmlns="http://www.w3.org/1999/xhtml"><iframe src="x"></iframe></div></foreignObject>
+  <a xlink:href="java&#9;script:alert(3)"><rect width="5" height="5" ONCLICK="x()"/></a>
+  <image href="data:text/html;base64,PHNjcmlwdD4="/>
+  <image href="data:image/png;base64,iVBORw0KGgo="/>
+  <text x="1">a &lt; b</text>
+</svg>`
+	out, err := SanitizeSVG([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	for _, bad := range []string{"onload", "ONCLICK", "script", "foreignObject", "iframe", "DOCTYPE", "comment", "data:text/html"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("output still contains %q:\n%s", bad, got)
+		}
+	}
+	for _, keep := range []string{`<?xml version="1.0"?>`, `width="10"`, `<rect width="5" height="5">`, "data:image/png", "a &lt; b", "xmlns:xlink="} {
+		if !strings.Contains(got, keep) {
+			t.Errorf("output lost %q:\n%s", keep, got)
+		}
+	}
+}
+
+func TestSanitizeSVG_Invalid(t *testing.T) {
+	for _, in := range []string{"<html><body/></html>", "<svg><g></svg>"} {
+		if _, err := SanitizeSVG([]byte(in)); !errors.Is(err, ErrInvalidContent) {
+			t.Errorf("SanitizeSVG(%q) err = %v, want ErrInvalidContent", in, err)
+		}
+	}
+}
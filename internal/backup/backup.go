@@ -0,0 +1,372 @@
+// Package backup writes vault backups as zip archives and restores them.
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/parser"
+	"github.com/starford/kenaz/internal/storage"
+)
+
+// Archive layout: vault files keep their paths under vaultDir, the index
+// snapshot is indexFile.
+const (
+	vaultDir  = "vault/"
+	indexFile = "index/kenaz.db"
+)
+
+// Archive names are namePrefix, the UTC creation time in timeLayout, and
+// nameSuffix, so they sort by age.
+const (
+	namePrefix = "kenaz-backup-"
+	nameSuffix = ".zip"
+	timeLayout = "20060102-150405.000"
+)
+
+// Policy says where backups are written and which are kept: the Keep
+// newest (0 keeps all), none older than MaxAge (0 keeps them at any age).
+type Policy struct {
+	Dir    string
+	Keep   int
+	MaxAge time.Duration
+}
+
+// Info describes a backup archive.
+type Info struct {
+	Name string `json:"name" example:"kenaz-backup-20250203-101500.000.zip" validate:"required"`
+	// Size is the archive size in bytes.
+	Size int64 `json:"size" example:"1048576" validate:"required"`
+	// Files is the number of vault files in the archive.
+	Files int `json:"files" example:"1200" validate:"required"`
+	// Index reports whether the archive holds a snapshot of the index.
+	Index     bool      `json:"index" validate:"required"`
+	CreatedAt time.Time `json:"created_at" validate:"required"`
+	// Removed lists the older backups the retention policy deleted.
+	Removed []string `json:"removed" validate:"required"`
+}
+
+// Create backs up every file of the vault in store (notes and
+// attachments, but not the trash or .kenaz; see storage.Provider.ListFiles)
+// and, with withIndex, a snapshot of db, to a new archive in p.Dir. It then
+// deletes the backups p no longer keeps. The archive is written under a
+// temporary name and renamed when complete, so a failed or cancelled
+// backup leaves no partial archive behind.
+func Create(ctx context.Context, store storage.Provider, db *index.DB, p Policy, withIndex bool) (*Info, error) {
+	if err := os.MkdirAll(p.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("backup: create dir: %w", err)
+	}
+	now := time.Now().UTC()
+	info := &Info{Name: namePrefix + now.Format(timeLayout) + nameSuffix, Index: withIndex, CreatedAt: now}
+
+	tmp, err := os.CreateTemp(p.Dir, ".kenaz-backup-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("backup: create archive: %w", err)
+	}
+	success := false
+	defer func() {
+		if !success {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	files, err := store.ListFiles("")
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	zw := zip.NewWriter(tmp)
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := store.Read(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := addFile(zw, vaultDir+f, data, now); err != nil {
+			return nil, err
+		}
+		info.Files++
+	}
+	if withIndex {
+		if err := addIndex(ctx, zw, db, p.Dir, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("backup: write archive: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return nil, fmt.Errorf("backup: fsync: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("backup: close archive: %w", err)
+	}
+	dst := filepath.Join(p.Dir, info.Name)
+	// Never replace an archive made within the same millisecond.
+	for {
+		if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		info.CreatedAt = info.CreatedAt.Add(time.Millisecond)
+		info.Name = namePrefix + info.CreatedAt.Format(timeLayout) + nameSuffix
+		dst = filepath.Join(p.Dir, info.Name)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return nil, fmt.Errorf("backup: rename archive: %w", err)
+	}
+	success = true
+	if st, err := os.Stat(dst); err == nil {
+		info.Size = st.Size()
+	}
+
+	if info.Removed, err = Prune(p, info.CreatedAt); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// addFile adds a file to the archive.
+func addFile(zw *zip.Writer, name string, data []byte, modified time.Time) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("backup: add %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("backup: add %s: %w", name, err)
+	}
+	return nil
+}
+
+// addIndex adds a snapshot of db to the archive, taken into a temporary
+// file in dir.
+func addIndex(ctx context.Context, zw *zip.Writer, db *index.DB, dir string, modified time.Time) error {
+	tmpDir, err := os.MkdirTemp(dir, ".kenaz-index-*")
+	if err != nil {
+		return fmt.Errorf("backup: index snapshot: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	snap := filepath.Join(tmpDir, "kenaz.db")
+	if err := db.Snapshot(ctx, snap); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(snap)
+	if err != nil {
+		return fmt.Errorf("backup: index snapshot: %w", err)
+	}
+	return addFile(zw, indexFile, data, modified)
+}
+
+// Prune deletes the backups in p.Dir beyond the p.Keep newest or created
+// more than p.MaxAge before now, and returns their names. Files not named
+// like backups are left alone.
+func Prune(p Policy, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("backup: list backups: %w", err)
+	}
+	type archive struct {
+		name    string
+		created time.Time
+	}
+	var archives []archive
+	for _, e := range entries {
+		if created, ok := parseName(e.Name()); ok && !e.IsDir() {
+			archives = append(archives, archive{e.Name(), created})
+		}
+	}
+	// Newest first.
+	slices.SortFunc(archives, func(a, b archive) int { return b.created.Compare(a.created) })
+
+	removed := []string{}
+	for i, a := range archives {
+		tooMany := p.Keep > 0 && i >= p.Keep
+		tooOld := p.MaxAge > 0 && now.Sub(a.created) > p.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(filepath.Join(p.Dir, a.name)); err != nil {
+			return removed, fmt.Errorf("backup: remove %s: %w", a.name, err)
+		}
+		removed = append(removed, a.name)
+	}
+	return removed, nil
+}
+
+// parseName returns the creation time encoded in a backup's file name,
+// reporting false for other files.
+func parseName(name string) (time.Time, bool) {
+	ts, ok := strings.CutPrefix(name, namePrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	if ts, ok = strings.CutSuffix(ts, nameSuffix); !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(timeLayout, ts)
+	return t, err == nil
+}
+
+// RestoreResult reports what Restore did.
+type RestoreResult struct {
+	// Files is the number of vault files written from the archive.
+	Files int
+	// Trashed lists the vault files the archive did not hold, moved into
+	// storage.TrashDir. Record them with index.DB.AddTrash once the index
+	// is open, so they show up in the trash and can be restored from it.
+	Trashed []index.TrashEntry
+	// Index reports whether the index was replaced by the archive's.
+	Index bool
+}
+
+// Restore rolls the vault in store back to the archive: every file in the
+// archive is written, and vault files the archive does not hold are moved
+// into storage.TrashDir, so nothing is lost: a file whose path is taken in
+// the trash by an earlier deletion is trashed under a free name with a
+// numeric suffix ("-2", "-3", ...) instead. When indexPath is set and the
+// archive holds an index snapshot, it replaces the database at indexPath,
+// which must not be open; otherwise the next sync brings the index up to
+// date. Entries outside the vault, such as "../x", fail the restore with
+// apperr.ErrInvalidPath before anything is written.
+func Restore(ctx context.Context, archive string, store storage.Provider, indexPath string) (*RestoreResult, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("backup: open archive: %w", err)
+	}
+	defer zr.Close()
+
+	var vault []*zip.File
+	var snapshot *zip.File
+	keep := map[string]bool{}
+	for _, f := range zr.File {
+		if f.Name == indexFile {
+			snapshot = f
+			continue
+		}
+		rel, ok := strings.CutPrefix(f.Name, vaultDir)
+		if !ok || f.FileInfo().IsDir() {
+			continue
+		}
+		if !fs.ValidPath(rel) || rel == "." || storage.InReserved(rel) {
+			return nil, fmt.Errorf("%w: %s", apperr.ErrInvalidPath, f.Name)
+		}
+		vault = append(vault, f)
+		keep[rel] = true
+	}
+
+	res := &RestoreResult{Trashed: []index.TrashEntry{}}
+	for _, f := range vault {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		data, err := readEntry(f)
+		if err != nil {
+			return res, err
+		}
+		if err := store.Write(strings.TrimPrefix(f.Name, vaultDir), data); err != nil {
+			return res, err
+		}
+		res.Files++
+	}
+
+	current, err := store.ListFiles("")
+	if err != nil {
+		return res, err
+	}
+	slices.Sort(current)
+	now := time.Now()
+	for _, f := range current {
+		if keep[f] {
+			continue
+		}
+		e, err := trashFile(store, f, now)
+		if err != nil {
+			return res, err
+		}
+		res.Trashed = append(res.Trashed, e)
+	}
+
+	if indexPath != "" && snapshot != nil {
+		if err := restoreIndex(snapshot, indexPath); err != nil {
+			return res, err
+		}
+		res.Index = true
+	}
+	return res, nil
+}
+
+// trashFile moves the vault file f into storage.TrashDir, under the first
+// of f, f-2, f-3, ... that is free there, and returns its trash entry.
+func trashFile(store storage.Provider, f string, now time.Time) (index.TrashEntry, error) {
+	e := index.TrashEntry{Path: f, DeletedAt: now}
+	if strings.HasSuffix(f, ".md") {
+		if data, err := store.Read(f); err == nil {
+			if parsed, err := parser.Parse(data); err == nil {
+				e.Title = parsed.Title
+			}
+		}
+	}
+	ext := path.Ext(f)
+	for i := 2; ; i++ {
+		if _, err := store.Read(path.Join(storage.TrashDir, e.Path)); errors.Is(err, os.ErrNotExist) {
+			break
+		} else if err != nil {
+			return e, err
+		}
+		e.Path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(f, ext), i, ext)
+	}
+	if err := store.Move(f, path.Join(storage.TrashDir, e.Path)); err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+// readEntry returns the content of an archive entry.
+func readEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("backup: read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("backup: read %s: %w", f.Name, err)
+	}
+	return data, nil
+}
+
+// restoreIndex replaces the database at indexPath, and its WAL files, with
+// the snapshot.
+func restoreIndex(snapshot *zip.File, indexPath string) error {
+	data, err := readEntry(snapshot)
+	if err != nil {
+		return err
+	}
+	tmp := indexPath + ".restore"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("backup: restore index: %w", err)
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(indexPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("backup: restore index: %w", err)
+		}
+	}
+	if err := os.Rename(tmp, indexPath); err != nil {
+		return fmt.Errorf("backup: restore index: %w", err)
+	}
+	return nil
+}
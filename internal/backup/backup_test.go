@@ -0,0 +1,204 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/storage"
+)
+
+func testVault(t *testing.T) (*storage.FS, *index.DB) {
+	t.Helper()
+	store, err := storage.NewFS(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for p, content := range map[string]string{
+		"a.md":                       "# A\n[[b]]",
+		"notes/b.md":                 "# B",
+		"attachments/img.png":        "png",
+		storage.TrashDir + "/old.md": "# Old",
+	} {
+		if err := store.Write(p, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := index.Sync(db, store, slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatal(err)
+	}
+	return store, db
+}
+
+func TestCreate(t *testing.T) {
+	store, db := testVault(t)
+	p := Policy{Dir: filepath.Join(t.TempDir(), "backups")}
+	info, err := Create(context.Background(), store, db, p, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Files != 3 || !info.Index || info.Size <= 0 || len(info.Removed) != 0 {
+		t.Errorf("info = %+v", info)
+	}
+	if _, ok := parseName(info.Name); !ok {
+		t.Errorf("name %q does not parse", info.Name)
+	}
+
+	zr, err := zip.OpenReader(filepath.Join(p.Dir, info.Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	want := []string{"vault/a.md", "vault/attachments/img.png", "vault/notes/b.md", "index/kenaz.db"}
+	if !slices.Equal(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+	entries, _ := os.ReadDir(p.Dir)
+	if len(entries) != 1 {
+		t.Errorf("backup dir holds %d entries, want only the archive", len(entries))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Create(ctx, store, db, p, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled backup = %v", err)
+	}
+	if entries, _ := os.ReadDir(p.Dir); len(entries) != 1 {
+		t.Errorf("cancelled backup left %d entries", len(entries))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	var names []string
+	for _, age := range []time.Duration{0, time.Hour, 48 * time.Hour, 72 * time.Hour} {
+		name := namePrefix + now.Add(-age).Format(timeLayout) + nameSuffix
+		names = append(names, name)
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Prune(Policy{Dir: dir, Keep: 3, MaxAge: 50 * time.Hour}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := names[3:]; !slices.Equal(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+	if removed, err = Prune(Policy{Dir: dir, MaxAge: 24 * time.Hour}, now); err != nil || !slices.Equal(removed, names[2:3]) {
+		t.Errorf("removed by age = %v, %v, want %v", removed, err, names[2:3])
+	}
+	if removed, err = Prune(Policy{Dir: dir}, now); err != nil || len(removed) != 0 {
+		t.Errorf("removed without limits = %v, %v", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("other files must be kept: %v", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	store, db := testVault(t)
+	p := Policy{Dir: t.TempDir()}
+	info, err := Create(context.Background(), store, db, p, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(p.Dir, info.Name)
+
+	if err := store.Write("a.md", []byte("# A changed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("notes/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Write("c.md", []byte("# C")); err != nil {
+		t.Fatal(err)
+	}
+	// An earlier deletion of old.md is in the trash already.
+	if err := store.Write("old.md", []byte("# Old again")); err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(t.TempDir(), "restored.db")
+	res, err := Restore(context.Background(), archive, store, indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trashed []string
+	for _, e := range res.Trashed {
+		trashed = append(trashed, e.Path+" "+e.Title)
+	}
+	if res.Files != 3 || !res.Index || !slices.Equal(trashed, []string{"c.md C", "old-2.md Old again"}) {
+		t.Errorf("restore = %+v", res)
+	}
+	for p, want := range map[string]string{
+		"a.md":                         "# A\n[[b]]",
+		"notes/b.md":                   "# B",
+		storage.TrashDir + "/c.md":     "# C",
+		storage.TrashDir + "/old.md":   "# Old",
+		storage.TrashDir + "/old-2.md": "# Old again",
+	} {
+		if got, err := store.Read(p); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", p, got, err, want)
+		}
+	}
+	restored, err := index.Open(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if row, err := restored.GetNote("notes/b.md"); err != nil || row == nil {
+		t.Errorf("restored index lacks notes/b.md: %v", err)
+	}
+}
+
+func TestRestore_RejectsPathsOutsideVault(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "evil.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"vault/ok.md", "vault/../escape.md"} {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	store, err := storage.NewFS(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(context.Background(), archive, store, ""); !errors.Is(err, apperr.ErrInvalidPath) {
+		t.Errorf("restore = %v, want ErrInvalidPath", err)
+	}
+	if _, err := store.Read("ok.md"); err == nil {
+		t.Error("nothing should be written from a rejected archive")
+	}
+}
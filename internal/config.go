@@ -3,8 +3,23 @@ package internal
 import (
 	"fmt"
 	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/starford/kenaz/internal/api"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/backup"
+	"github.com/starford/kenaz/internal/hook"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/mcpserver"
+	"github.com/starford/kenaz/internal/noteservice"
+	"github.com/starford/kenaz/internal/parser"
+	"github.com/starford/kenaz/internal/storage"
 )
 
 // Auth modes.
@@ -15,11 +30,28 @@ const (
 
 // Config represents the application configuration.
 type Config struct {
-	App      ApplicationConfig `yaml:"app"`
-	Vault    VaultConfig       `yaml:"vault"`
-	SQLite   SQLiteConfig      `yaml:"sqlite"`
-	Auth     AuthConfig        `yaml:"auth"`
-	Frontend FrontendConfig    `yaml:"frontend"`
+	App         ApplicationConfig `yaml:"app"`
+	Vault       VaultConfig       `yaml:"vault"`
+	SQLite      SQLiteConfig      `yaml:"sqlite"`
+	Search      SearchConfig      `yaml:"search"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Frontend    FrontendConfig    `yaml:"frontend"`
+	Attachments AttachmentsConfig `yaml:"attachments"`
+	Daily       DailyConfig       `yaml:"daily"`
+	MCP         MCPConfig         `yaml:"mcp"`
+	Hooks       []HookConfig      `yaml:"hooks"`
+	// HookConcurrency bounds how many hook commands run at once
+	// (hook.DefaultConcurrency when zero); further runs queue.
+	HookConcurrency int             `yaml:"hook_concurrency"`
+	Reminders       RemindersConfig `yaml:"reminders"`
+	// LinkPreviews controls fetching metadata of external links in notes.
+	LinkPreviews LinkPreviewsConfig `yaml:"link_previews"`
+	// LinkCheck controls the background checker of external links.
+	LinkCheck LinkCheckConfig `yaml:"link_check"`
+	// History controls the revisions kept of each note.
+	History HistoryConfig `yaml:"history"`
+	// Backup controls where vault backups go and how many are kept.
+	Backup BackupConfig `yaml:"backup"`
 }
 
 // Validate validates the configuration.
@@ -36,7 +68,76 @@ func (c *Config) Validate() error {
 	if err := c.Auth.Validate(); err != nil {
 		return err
 	}
-	return c.Frontend.Validate()
+	if err := c.Frontend.Validate(); err != nil {
+		return err
+	}
+	if err := c.Daily.Validate(); err != nil {
+		return err
+	}
+	if err := c.MCP.Validate(); err != nil {
+		return err
+	}
+	if err := c.Reminders.Validate(); err != nil {
+		return err
+	}
+	if err := c.LinkPreviews.Validate(); err != nil {
+		return err
+	}
+	if err := c.LinkCheck.Validate(); err != nil {
+		return err
+	}
+	if err := c.History.Validate(); err != nil {
+		return err
+	}
+	if err := c.Backup.Validate(); err != nil {
+		return err
+	}
+	if c.HookConcurrency < 0 {
+		return fmt.Errorf("hook_concurrency: must not be negative")
+	}
+	for i := range c.Hooks {
+		if err := c.Hooks[i].Validate(); err != nil {
+			return fmt.Errorf("hooks[%d]: %w", i, err)
+		}
+	}
+	return c.Attachments.Validate()
+}
+
+// IndexOptions returns the index options for the configured vault and
+// search settings. Serve and stdio MCP mode open the index with the same
+// options so both agree on link resolution and tokenization.
+func (c *Config) IndexOptions() []index.Option {
+	return []index.Option{
+		index.WithStrictLinks(c.Vault.StrictLinks),
+		index.WithLinkFields(c.Vault.LinkFields),
+		index.WithExtractors(c.Vault.ExtractorList()),
+		index.WithStopWords(c.Search.StopWords),
+		index.WithSynonyms(c.Search.Synonyms),
+		index.WithTransliteration(c.Search.Transliterate),
+		index.WithBusyTimeout(c.SQLite.BusyTimeout),
+		index.WithBusyRetry(c.SQLite.Retry.BusyRetry()),
+	}
+}
+
+// ServiceOptions returns the note service options shared by every entry
+// point. Callers append mode-specific options such as a mutation hook.
+func (c *Config) ServiceOptions() []noteservice.Option {
+	opts := []noteservice.Option{
+		noteservice.WithRequireIfMatch(c.Vault.RequireIfMatch),
+		noteservice.WithReadOnly(c.Vault.ReadOnly),
+		noteservice.WithUndoWindow(c.Vault.UndoWindow),
+		noteservice.WithInboxPath(c.Vault.InboxPath),
+		noteservice.WithBookmarksFolder(c.Vault.BookmarksFolder),
+		noteservice.WithDailyNotes(c.Daily.Notes()),
+		noteservice.WithLinkChecker(asset.CheckURL),
+		noteservice.WithHistory(c.History.Revisions),
+		noteservice.WithBackups(c.Backup.Policy()),
+		noteservice.WithPipeline(c.Attachments.Pipeline()),
+	}
+	if c.LinkPreviews.Enabled {
+		opts = append(opts, noteservice.WithLinkPreviews(asset.FetchPage, c.LinkPreviews.TTL))
+	}
+	return opts
 }
 
 // ApplicationConfig holds application-level configuration.
@@ -52,7 +153,8 @@ func (c *ApplicationConfig) Validate() error {
 
 // HTTPConfig holds HTTP server configuration.
 type HTTPConfig struct {
-	Port int `yaml:"port"`
+	Port        int               `yaml:"port"`
+	Compression CompressionConfig `yaml:"compression"`
 }
 
 // Address returns HTTP server address.
@@ -62,36 +164,229 @@ func (c *HTTPConfig) Address() string {
 
 // Validate validates the HTTP configuration.
 func (c *HTTPConfig) Validate() error {
-	return validation.ValidateStruct(c,
+	if err := validation.ValidateStruct(c,
 		validation.Field(&c.Port, validation.Required, validation.Min(1), validation.Max(65535)),
+	); err != nil {
+		return err
+	}
+	return c.Compression.Validate()
+}
+
+// CompressionConfig controls gzip/deflate compression of JSON and HTML
+// responses. Level follows compress/flate: 1 (fastest) to 9 (smallest).
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Level   int  `yaml:"level"`
+}
+
+// Validate validates the compression configuration.
+func (c *CompressionConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Level, validation.Required, validation.Min(1), validation.Max(9)),
 	)
 }
 
 // VaultConfig holds the path to the Markdown vault directory.
+//
+// StrictLinks disables case- and spacing-tolerant wikilink matching, so
+// [[My Note]] no longer links to my-note.md. LinkFields lists frontmatter
+// fields whose values are indexed as links of type "frontmatter".
+// RequireIfMatch rejects note updates that carry no checksum (REST If-Match
+// or MCP checksum) instead of overwriting unconditionally. UndoWindow is how
+// long note creates, updates, and deletes can be reverted (0 disables undo).
+// InboxPath is the note POST /api/capture appends to. BookmarksFolder holds
+// the notes saved via POST /api/bookmarks. Extractors names
+// parser extractors (e.g. urls, mentions, isbn) whose output is indexed as
+// queryable note metadata. WebDAV (when its URL
+// is set) or SFTP (when its host is set) serves the vault from a remote
+// server instead of Path. Git, when enabled, commits every change to a
+// local vault to git. PollInterval > 0 replaces file system
+// notifications with periodic rescans; remote vaults are always polled.
+// ReadOnly refuses every change to the vault from the API and MCP tools.
 type VaultConfig struct {
-	Path       string   `yaml:"path"`
-	IgnoreDirs []string `yaml:"ignore_dirs"`
+	Path            string        `yaml:"path"`
+	IgnoreDirs      []string      `yaml:"ignore_dirs"`
+	StrictLinks     bool          `yaml:"strict_links"`
+	LinkFields      []string      `yaml:"link_fields"`
+	RequireIfMatch  bool          `yaml:"require_if_match"`
+	UndoWindow      time.Duration `yaml:"undo_window"`
+	InboxPath       string        `yaml:"inbox_path"`
+	BookmarksFolder string        `yaml:"bookmarks_folder"`
+	Extractors      []string      `yaml:"extractors"`
+	WebDAV          WebDAVConfig  `yaml:"webdav"`
+	SFTP            SFTPConfig    `yaml:"sftp"`
+	Git             GitConfig     `yaml:"git"`
+	PollInterval    time.Duration `yaml:"poll_interval"`
+	ReadOnly        bool          `yaml:"read_only"`
 }
 
 // Validate validates the vault configuration.
 func (c *VaultConfig) Validate() error {
+	if c.WebDAV.URL != "" && c.SFTP.Host != "" {
+		return fmt.Errorf("vault: set either webdav or sftp, not both")
+	}
+	if c.Git.Enabled && c.Remote() {
+		return fmt.Errorf("vault.git: needs a local vault, not webdav or sftp")
+	}
+	if c.Git.Enabled && c.ReadOnly {
+		return fmt.Errorf("vault.git: cannot commit to a read-only vault")
+	}
+	for _, name := range c.Extractors {
+		if _, err := parser.LookupExtractor(name); err != nil {
+			return fmt.Errorf("vault.extractors: unknown extractor %q (available: %s)", name, strings.Join(parser.ExtractorNames(), ", "))
+		}
+	}
 	return validation.ValidateStruct(c,
 		validation.Field(&c.Path, validation.Required),
+		validation.Field(&c.UndoWindow, validation.Min(time.Duration(0))),
+		validation.Field(&c.InboxPath, validation.Required),
+		validation.Field(&c.BookmarksFolder, validation.Required),
+		validation.Field(&c.WebDAV),
+		validation.Field(&c.SFTP),
+		validation.Field(&c.Git),
+		validation.Field(&c.PollInterval, validation.Min(time.Duration(0))),
 	)
 }
 
-// SQLiteConfig holds SQLite database configuration.
+// ExtractorList returns the configured parser extractors. Names are checked
+// by Validate; unknown ones are skipped.
+func (c *VaultConfig) ExtractorList() []parser.Extractor {
+	var out []parser.Extractor
+	for _, name := range c.Extractors {
+		if e, err := parser.LookupExtractor(name); err == nil {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// DefaultPollInterval is how often a remote vault is rescanned when
+// vault.poll_interval is not set.
+const DefaultPollInterval = 30 * time.Second
+
+// Remote reports whether the vault lives on a remote server.
+func (c *VaultConfig) Remote() bool {
+	return c.WebDAV.URL != "" || c.SFTP.Host != ""
+}
+
+// Storage opens the vault: the WebDAV or SFTP server if configured,
+// otherwise the local directory at Path, committing to git when enabled.
+func (c *VaultConfig) Storage() (storage.Provider, error) {
+	switch {
+	case c.WebDAV.URL != "":
+		return storage.NewWebDAV(c.WebDAV.URL, c.WebDAV.Username, c.WebDAV.Password, c.IgnoreDirs)
+	case c.SFTP.Host != "":
+		return storage.NewSFTP(c.SFTP.Options(), c.IgnoreDirs)
+	}
+	fs, err := storage.NewFS(c.Path, c.IgnoreDirs)
+	if err != nil || !c.Git.Enabled {
+		return fs, err
+	}
+	return storage.NewGit(fs, c.Git.Options())
+}
+
+// Poll returns how often the watcher rescans the vault, or 0 to watch it
+// with file system notifications.
+func (c *VaultConfig) Poll() time.Duration {
+	if c.PollInterval == 0 && c.Remote() {
+		return DefaultPollInterval
+	}
+	return c.PollInterval
+}
+
+// WebDAVConfig points the vault at a WebDAV collection, e.g.
+// https://cloud.example.com/remote.php/dav/files/me/vault on Nextcloud.
+// Username and Password are sent with HTTP Basic or Digest auth.
+type WebDAVConfig struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Validate validates the WebDAV configuration.
+func (c WebDAVConfig) Validate() error {
+	if c.URL == "" {
+		return nil
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("vault.webdav: url must look like https://host/path")
+	}
+	return nil
+}
+
+// SQLiteConfig holds SQLite database configuration. BusyTimeout is how long
+// SQLite waits for a lock held by another connection before failing with
+// SQLITE_BUSY; Retry controls how failed index writes are then retried.
 type SQLiteConfig struct {
-	Path string `yaml:"path"`
+	Path        string                  `yaml:"path"`
+	BusyTimeout time.Duration           `yaml:"busy_timeout"`
+	Retry       SQLiteRetryConfig       `yaml:"retry"`
+	Maintenance SQLiteMaintenanceConfig `yaml:"maintenance"`
+}
+
+// SQLiteMaintenanceConfig controls background index maintenance. When
+// Enabled, every Interval the server waits until the index has seen no
+// writes for Idle, then runs PRAGMA optimize and merges the full-text
+// index segments, and with Vacuum also rebuilds the database file to
+// return free space (see index.DB.Optimize).
+type SQLiteMaintenanceConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	Idle     time.Duration `yaml:"idle"`
+	Vacuum   bool          `yaml:"vacuum"`
+}
+
+// SQLiteRetryConfig controls retries of index writes failing with
+// SQLITE_BUSY: up to MaxRetries more attempts, waiting Backoff before the
+// first and doubling up to MaxBackoff.
+type SQLiteRetryConfig struct {
+	MaxRetries int           `yaml:"max_retries"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
 }
 
 // Validate validates the SQLite configuration.
 func (c *SQLiteConfig) Validate() error {
+	if err := validation.ValidateStruct(&c.Retry,
+		validation.Field(&c.Retry.MaxRetries, validation.Min(0)),
+		validation.Field(&c.Retry.Backoff, validation.Min(time.Duration(0))),
+		validation.Field(&c.Retry.MaxBackoff, validation.Min(c.Retry.Backoff)),
+	); err != nil {
+		return fmt.Errorf("sqlite.retry: %w", err)
+	}
+	if c.Maintenance.Enabled {
+		if err := validation.ValidateStruct(&c.Maintenance,
+			validation.Field(&c.Maintenance.Interval, validation.Required, validation.Min(time.Minute)),
+			validation.Field(&c.Maintenance.Idle, validation.Min(time.Duration(0))),
+		); err != nil {
+			return fmt.Errorf("sqlite.maintenance: %w", err)
+		}
+	}
 	return validation.ValidateStruct(c,
 		validation.Field(&c.Path, validation.Required),
+		validation.Field(&c.BusyTimeout, validation.Min(time.Duration(0))),
 	)
 }
 
+// BusyRetry converts the retry configuration into an index.BusyRetry.
+func (c *SQLiteRetryConfig) BusyRetry() index.BusyRetry {
+	return index.BusyRetry{MaxRetries: c.MaxRetries, Backoff: c.Backoff, MaxBackoff: c.MaxBackoff}
+}
+
+// SearchConfig tunes full-text search. StopWords are dropped from queries
+// (case-insensitive), so common words do not dominate ranking for short
+// queries. Synonyms expands query terms at search time, e.g. "k8s" also
+// matches "kubernetes", without changing stored content. Transliterate
+// makes Cyrillic and Latin spellings find each other ("kenaz" ↔ "кеназ").
+type SearchConfig struct {
+	StopWords     []string            `yaml:"stop_words"`
+	Synonyms      map[string][]string `yaml:"synonyms"`
+	Transliterate bool                `yaml:"transliterate"`
+}
+
 // AuthConfig holds authentication configuration.
 //
 // Mode controls how authentication is enforced:
@@ -140,6 +435,383 @@ func (c *FrontendConfig) Validate() error {
 	)
 }
 
+// DailyConfig controls daily notes: they live at <Folder>/<date>.md, with
+// the date rendered by the Go time layout Format, and new ones are seeded
+// from the Template note ("{{date}}" is replaced with YYYY-MM-DD).
+type DailyConfig struct {
+	Folder   string `yaml:"folder"`
+	Format   string `yaml:"format"`
+	Template string `yaml:"template"`
+}
+
+// Validate validates the daily notes configuration.
+func (c *DailyConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Folder, validation.Required),
+		validation.Field(&c.Format, validation.Required),
+	)
+}
+
+// Notes returns the daily notes settings for the note service.
+func (c *DailyConfig) Notes() noteservice.DailyNotes {
+	return noteservice.DailyNotes{Folder: c.Folder, Format: c.Format, Template: c.Template}
+}
+
+// MCPConfig controls the MCP server. Tools lists the tools it registers,
+// e.g. leaving out delete_note and upload_asset; empty registers all.
+// LogFile, when set, receives a JSON record of every stdio tool call along
+// with the server's own warnings, for hosts that discard stderr.
+type MCPConfig struct {
+	Tools   []string `yaml:"tools"`
+	LogFile string   `yaml:"log_file"`
+}
+
+// Validate validates the MCP configuration.
+func (c *MCPConfig) Validate() error {
+	known := mcpserver.ToolNames()
+	for _, name := range c.Tools {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("mcp.tools: unknown tool %q (available: %s)", name, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// HookConfig runs Command after every note event matching Event
+// ("note.created", "note.updated", "note.deleted", or "note.*"), with the
+// note path and change kind appended as arguments. Runs slower than Timeout
+// (default 30s) are killed; failures are logged.
+type HookConfig struct {
+	Event   string        `yaml:"event"`
+	Command string        `yaml:"command"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Validate validates the hook configuration.
+func (c *HookConfig) Validate() error {
+	events := append(slices.Clone(hook.Events), "note.*")
+	if !slices.Contains(events, c.Event) {
+		return fmt.Errorf("unknown event %q (available: %s)", c.Event, strings.Join(events, ", "))
+	}
+	if strings.TrimSpace(c.Command) == "" {
+		return fmt.Errorf("command is required")
+	}
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Timeout, validation.Min(time.Duration(0))),
+	)
+}
+
+// Hook converts the configuration into a hook.Hook.
+func (c *HookConfig) Hook() hook.Hook {
+	return hook.Hook{Event: c.Event, Args: strings.Fields(c.Command), Timeout: c.Timeout}
+}
+
+// RemindersConfig controls the reminder scheduler of the server. Notes come
+// due at the times in their "remind" frontmatter field and open tasks at
+// TaskTime ("HH:MM", local time) on their due date. Every Interval due
+// reminders are published as "reminder.due" SSE events and POSTed to each
+// of Webhooks.
+type RemindersConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	TaskTime string        `yaml:"task_time"`
+	Webhooks []string      `yaml:"webhooks"`
+}
+
+// Validate validates the reminders configuration.
+func (c *RemindersConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if _, err := time.Parse("15:04", c.TaskTime); err != nil {
+		return fmt.Errorf("reminders.task_time: must be HH:MM, got %q", c.TaskTime)
+	}
+	for _, w := range c.Webhooks {
+		if u, err := url.Parse(w); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("reminders.webhooks: invalid URL %q", w)
+		}
+	}
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Interval, validation.Required, validation.Min(time.Second)),
+	)
+}
+
+// TaskOffset returns TaskTime as an offset from midnight.
+func (c *RemindersConfig) TaskOffset() time.Duration {
+	t, err := time.Parse("15:04", c.TaskTime)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// LinkPreviewsConfig controls link previews. When Enabled, the pages of
+// http(s) URLs in a note are downloaded (with the SSRF guards of
+// upload_asset) as its previews are requested, and their title,
+// description, image, and favicon are cached in the index for TTL.
+type LinkPreviewsConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	TTL     time.Duration `yaml:"ttl"`
+}
+
+// Validate validates the link previews configuration.
+func (c *LinkPreviewsConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.TTL, validation.Min(time.Duration(0))),
+	)
+}
+
+// LinkCheckConfig controls the dead-link checker. When Enabled, every
+// Interval the server checks the http(s) URLs in notes that were never
+// checked or last checked more than MaxAge ago, at most 100 at a time, and
+// reports those answering 404 or 410 or not answering at all.
+type LinkCheckConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	MaxAge   time.Duration `yaml:"max_age"`
+}
+
+// Validate validates the link check configuration.
+func (c *LinkCheckConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Interval, validation.Required, validation.Min(time.Second)),
+		validation.Field(&c.MaxAge, validation.Required, validation.Min(time.Minute)),
+	)
+}
+
+// HistoryConfig controls note revision history. Whenever a note is
+// replaced (PUT /api/notes, MCP update_note, a revision restore), its
+// previous content is kept under .kenaz/history in the vault, up to
+// Revisions versions per note (0 disables history).
+type HistoryConfig struct {
+	Revisions int `yaml:"revisions"`
+}
+
+// Validate validates the history configuration.
+func (c *HistoryConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Revisions, validation.Min(0)),
+	)
+}
+
+// BackupConfig controls vault backups (kenaz backup, POST
+// /api/admin/backup): zip archives of the notes, attachments, and
+// optionally the index, written to Dir. After each backup only the Keep
+// newest are kept (0 keeps all), and none older than MaxAge (0 keeps them
+// at any age).
+type BackupConfig struct {
+	Dir    string        `yaml:"dir"`
+	Keep   int           `yaml:"keep"`
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// Validate validates the backup configuration.
+func (c *BackupConfig) Validate() error {
+	if err := validation.ValidateStruct(c,
+		validation.Field(&c.Dir, validation.Required),
+		validation.Field(&c.Keep, validation.Min(0)),
+		validation.Field(&c.MaxAge, validation.Min(time.Duration(0))),
+	); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	return nil
+}
+
+// Policy returns the backup settings for the service.
+func (c *BackupConfig) Policy() backup.Policy {
+	return backup.Policy{Dir: c.Dir, Keep: c.Keep, MaxAge: c.MaxAge}
+}
+
+// AttachmentsConfig controls processing of uploaded attachments.
+// ContentAddressed stores uploads as attachments/<sha256>.<ext>, recording
+// the uploaded names in attachments/.names.json. UploadTTL discards chunked
+// uploads that received no chunk for that long (0 keeps them).
+type AttachmentsConfig struct {
+	Scan             ScanConfig    `yaml:"scan"`
+	Images           ImageConfig   `yaml:"images"`
+	ContentAddressed bool          `yaml:"content_addressed"`
+	UploadTTL        time.Duration `yaml:"upload_ttl"`
+}
+
+// Validate validates the attachments configuration.
+func (c *AttachmentsConfig) Validate() error {
+	if err := c.Scan.Validate(); err != nil {
+		return err
+	}
+	if c.UploadTTL < 0 {
+		return fmt.Errorf("attachments.upload_ttl must not be negative")
+	}
+	return c.Images.Validate()
+}
+
+// Pipeline builds the upload pipeline, or returns nil when no scanning or
+// image processing is configured.
+func (c *AttachmentsConfig) Pipeline() *asset.Pipeline {
+	p := &asset.Pipeline{
+		Scanner:       c.Scan.Scanner(),
+		StripMetadata: c.Images.StripMetadata,
+		Images: asset.ImageOptions{
+			MaxWidth:     c.Images.MaxWidth,
+			MaxHeight:    c.Images.MaxHeight,
+			Quality:      c.Images.Quality,
+			KeepOriginal: c.Images.KeepOriginal,
+		},
+	}
+	if p.Scanner == nil && !p.StripMetadata && !p.Images.Enabled() {
+		return nil
+	}
+	return p
+}
+
+// ImageConfig controls optimization of uploaded JPEG/PNG images. Images
+// larger than MaxWidth×MaxHeight are scaled down and re-encoded (JPEG at
+// Quality); zero bounds disable resizing. KeepOriginal stores the full-size
+// upload as <name>.original<ext>. StripMetadata (on by default) removes
+// EXIF/GPS, XMP, and text metadata, keeping only the JPEG orientation.
+type ImageConfig struct {
+	StripMetadata bool `yaml:"strip_metadata"`
+	MaxWidth      int  `yaml:"max_width"`
+	MaxHeight     int  `yaml:"max_height"`
+	Quality       int  `yaml:"quality"`
+	KeepOriginal  bool `yaml:"keep_original"`
+}
+
+// Validate validates the image configuration.
+func (c *ImageConfig) Validate() error {
+	return validation.ValidateStruct(c,
+		validation.Field(&c.MaxWidth, validation.Min(0)),
+		validation.Field(&c.MaxHeight, validation.Min(0)),
+		validation.Field(&c.Quality, validation.Min(0), validation.Max(100)),
+	)
+}
+
+// ScanConfig configures the optional virus-scan hook run on uploads before
+// they are stored. Set at most one of Command (run with the file on stdin,
+// e.g. "clamdscan --no-summary -") and ICAPURL (e.g.
+// "icap://clamav:1344/avscan"). Both empty disables scanning.
+type ScanConfig struct {
+	Command string        `yaml:"command"`
+	ICAPURL string        `yaml:"icap_url"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Validate validates the scan configuration.
+func (c *ScanConfig) Validate() error {
+	if c.Command != "" && c.ICAPURL != "" {
+		return fmt.Errorf("attachments.scan: set either command or icap_url, not both")
+	}
+	if c.ICAPURL != "" {
+		if u, err := url.Parse(c.ICAPURL); err != nil || u.Scheme != "icap" || u.Host == "" {
+			return fmt.Errorf("attachments.scan: icap_url must look like icap://host[:port]/service")
+		}
+	}
+	return validation.ValidateStruct(c,
+		validation.Field(&c.Timeout, validation.Min(time.Duration(0))),
+	)
+}
+
+// Scanner builds the configured scanner, or returns nil when scanning is
+// disabled.
+func (c *ScanConfig) Scanner() asset.Scanner {
+	switch {
+	case c.Command != "":
+		return &asset.CommandScanner{Args: strings.Fields(c.Command), Timeout: c.Timeout}
+	case c.ICAPURL != "":
+		return &asset.ICAPScanner{URL: c.ICAPURL, Timeout: c.Timeout}
+	default:
+		return nil
+	}
+}
+
+// SFTPConfig points the vault at a directory on an SSH server. Host is
+// "host" or "host:port"; Path is the vault directory there (relative paths
+// start at the login directory). Authentication uses KeyFile (a private
+// key), Password, or both; the host key is checked against KnownHosts
+// (default ~/.ssh/known_hosts). Connections sizes the connection pool
+// (default storage.DefaultSFTPConnections).
+type SFTPConfig struct {
+	Host        string `yaml:"host"`
+	User        string `yaml:"user"`
+	KeyFile     string `yaml:"key_file"`
+	Password    string `yaml:"password"`
+	KnownHosts  string `yaml:"known_hosts"`
+	Path        string `yaml:"path"`
+	Connections int    `yaml:"connections"`
+}
+
+// Validate validates the SFTP configuration.
+func (c SFTPConfig) Validate() error {
+	if c.Host == "" {
+		return nil
+	}
+	if c.KeyFile == "" && c.Password == "" {
+		return fmt.Errorf("vault.sftp: set key_file or password")
+	}
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.User, validation.Required),
+		validation.Field(&c.Path, validation.Required),
+		validation.Field(&c.Connections, validation.Min(0), validation.Max(32)),
+	)
+}
+
+// Options returns the SFTP settings for the storage provider.
+func (c SFTPConfig) Options() storage.SFTPOptions {
+	return storage.SFTPOptions{
+		Host:        c.Host,
+		User:        c.User,
+		KeyFile:     c.KeyFile,
+		Password:    c.Password,
+		KnownHosts:  c.KnownHosts,
+		Path:        c.Path,
+		Connections: c.Connections,
+	}
+}
+
+// GitConfig makes the local vault a git repository (initialized on first
+// start if needed) and commits every note write, delete, and move, signed
+// by AuthorName and AuthorEmail, with Message as a text/template over the
+// change ({{.Action}}, {{.Path}}, {{.From}}). POST /api/admin/git/push
+// pushes to Remote (a remote name or URL), to Branch when set; AutoPush
+// pushes after every commit.
+type GitConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	AuthorName  string `yaml:"author_name"`
+	AuthorEmail string `yaml:"author_email"`
+	Message     string `yaml:"message"`
+	Remote      string `yaml:"remote"`
+	Branch      string `yaml:"branch"`
+	AutoPush    bool   `yaml:"auto_push"`
+}
+
+// Validate validates the git configuration.
+func (c GitConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if _, err := template.New("message").Parse(c.Message); err != nil {
+		return fmt.Errorf("vault.git.message: %w", err)
+	}
+	if c.AutoPush && c.Remote == "" {
+		return fmt.Errorf("vault.git: auto_push needs a remote")
+	}
+	return nil
+}
+
+// Options returns the git settings for the storage provider.
+func (c GitConfig) Options() storage.GitOptions {
+	return storage.GitOptions{
+		AuthorName:  c.AuthorName,
+		AuthorEmail: c.AuthorEmail,
+		Message:     c.Message,
+		Remote:      c.Remote,
+		Branch:      c.Branch,
+		AutoPush:    c.AutoPush,
+	}
+}
+
 // NewDefaultConfig returns a new Config with sensible default values.
 func NewDefaultConfig() *Config {
 	return &Config{
@@ -147,14 +819,37 @@ func NewDefaultConfig() *Config {
 			LogLevel: slog.LevelInfo,
 			HTTP: HTTPConfig{
 				Port: 8080,
+				Compression: CompressionConfig{
+					Enabled: true,
+					Level:   5,
+				},
 			},
 		},
 		Vault: VaultConfig{
-			Path:       "./vault",
-			IgnoreDirs: []string{".git", ".obsidian", "attachments"},
+			Path:            "./vault",
+			IgnoreDirs:      []string{".git", ".obsidian", "attachments"},
+			LinkFields:      []string{"related", "parent", "source"},
+			UndoWindow:      10 * time.Minute,
+			InboxPath:       "inbox.md",
+			BookmarksFolder: noteservice.DefaultBookmarksFolder,
+			Git: GitConfig{
+				AuthorName:  storage.DefaultGitAuthorName,
+				AuthorEmail: storage.DefaultGitAuthorEmail,
+				Message:     storage.DefaultGitMessage,
+			},
 		},
 		SQLite: SQLiteConfig{
-			Path: "./kenaz.db",
+			Path:        "./kenaz.db",
+			BusyTimeout: index.DefaultBusyTimeout,
+			Retry: SQLiteRetryConfig{
+				MaxRetries: index.DefaultBusyRetry.MaxRetries,
+				Backoff:    index.DefaultBusyRetry.Backoff,
+				MaxBackoff: index.DefaultBusyRetry.MaxBackoff,
+			},
+			Maintenance: SQLiteMaintenanceConfig{
+				Interval: 24 * time.Hour,
+				Idle:     5 * time.Minute,
+			},
 		},
 		Auth: AuthConfig{
 			Mode: AuthModeDisabled,
@@ -163,5 +858,32 @@ func NewDefaultConfig() *Config {
 			Enabled:  true,
 			DistPath: "./frontend/dist",
 		},
+		Attachments: AttachmentsConfig{
+			Images:    ImageConfig{StripMetadata: true, Quality: 85},
+			UploadTTL: api.DefaultUploadTTL,
+		},
+		Daily: DailyConfig{
+			Folder: noteservice.DefaultDailyFolder,
+			Format: noteservice.DefaultDailyFormat,
+		},
+		Reminders: RemindersConfig{
+			Enabled:  true,
+			Interval: time.Minute,
+			TaskTime: "09:00",
+		},
+		LinkPreviews: LinkPreviewsConfig{
+			TTL: noteservice.DefaultLinkPreviewTTL,
+		},
+		LinkCheck: LinkCheckConfig{
+			Interval: 10 * time.Minute,
+			MaxAge:   24 * time.Hour,
+		},
+		History: HistoryConfig{
+			Revisions: 20,
+		},
+		Backup: BackupConfig{
+			Dir:  "./backups",
+			Keep: 10,
+		},
 	}
 }
@@ -3,6 +3,10 @@ package internal
 import (
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/storage"
 )
 
 func TestAuthConfig_DisabledMode(t *testing.T) {
@@ -63,3 +67,224 @@ func TestFullConfig_AuthValidationCalled(t *testing.T) {
 		t.Fatal("full config validate should catch auth error")
 	}
 }
+
+func TestCompressionConfig(t *testing.T) {
+	if err := (&CompressionConfig{Enabled: false}).Validate(); err != nil {
+		t.Errorf("disabled compression should pass: %v", err)
+	}
+	if err := (&CompressionConfig{Enabled: true, Level: 5}).Validate(); err != nil {
+		t.Errorf("level 5 should pass: %v", err)
+	}
+	for _, lvl := range []int{0, 10} {
+		if err := (&CompressionConfig{Enabled: true, Level: lvl}).Validate(); err == nil {
+			t.Errorf("level %d should fail", lvl)
+		}
+	}
+}
+
+func TestScanConfig(t *testing.T) {
+	if sc := (&ScanConfig{}).Scanner(); sc != nil {
+		t.Errorf("empty scan config should disable scanning, got %T", sc)
+	}
+	both := ScanConfig{Command: "clamdscan -", ICAPURL: "icap://clamav/avscan"}
+	if err := both.Validate(); err == nil {
+		t.Error("command and icap_url together should fail")
+	}
+	if err := (&ScanConfig{ICAPURL: "http://clamav/avscan"}).Validate(); err == nil {
+		t.Error("non-icap url should fail")
+	}
+	cmd := ScanConfig{Command: "clamdscan --no-summary -"}
+	if err := cmd.Validate(); err != nil {
+		t.Fatalf("command config: %v", err)
+	}
+	if _, ok := cmd.Scanner().(*asset.CommandScanner); !ok {
+		t.Errorf("scanner = %T, want *asset.CommandScanner", cmd.Scanner())
+	}
+}
+
+func TestMCPConfig_Tools(t *testing.T) {
+	if err := (&MCPConfig{}).Validate(); err != nil {
+		t.Errorf("empty tools should pass: %v", err)
+	}
+	if err := (&MCPConfig{Tools: []string{"read_note", "search_notes"}}).Validate(); err != nil {
+		t.Errorf("known tools should pass: %v", err)
+	}
+	err := (&MCPConfig{Tools: []string{"read_note", "drop_vault"}}).Validate()
+	if err == nil || !strings.Contains(err.Error(), `"drop_vault"`) {
+		t.Errorf("unknown tool: err = %v", err)
+	}
+}
+
+func TestVaultConfig_Extractors(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Vault.Extractors = []string{"urls", "mentions"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("built-in extractors should pass: %v", err)
+	}
+	if got := cfg.Vault.ExtractorList(); len(got) != 2 || got[1].Name() != "mentions" {
+		t.Errorf("extractors = %v", got)
+	}
+	cfg.Vault.Extractors = []string{"phones"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `"phones"`) {
+		t.Errorf("unknown extractor: err = %v", err)
+	}
+}
+
+func TestRemindersConfig(t *testing.T) {
+	cfg := NewDefaultConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults should pass: %v", err)
+	}
+	if got := cfg.Reminders.TaskOffset(); got != 9*time.Hour {
+		t.Errorf("task offset = %v, want 9h", got)
+	}
+	cfg.Reminders.TaskTime = "9am"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "task_time") {
+		t.Errorf("bad task_time: err = %v", err)
+	}
+	cfg.Reminders.TaskTime = "18:30"
+	cfg.Reminders.Webhooks = []string{"ftp://example.com/hook"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "webhooks") {
+		t.Errorf("bad webhook: err = %v", err)
+	}
+	cfg.Reminders.Enabled = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("disabled reminders are not validated: %v", err)
+	}
+}
+
+func TestSQLiteConfig_Maintenance(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.SQLite.Maintenance.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults should pass: %v", err)
+	}
+	cfg.SQLite.Maintenance.Interval = time.Second
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "Interval") {
+		t.Errorf("short interval: err = %v", err)
+	}
+	cfg.SQLite.Maintenance.Enabled = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("disabled maintenance is not validated: %v", err)
+	}
+}
+
+func TestHookConfig(t *testing.T) {
+	h := HookConfig{Event: "note.updated", Command: "./scripts/publish.sh --quiet"}
+	if err := h.Validate(); err != nil {
+		t.Fatalf("valid hook: %v", err)
+	}
+	if got := h.Hook().Args; len(got) != 2 || got[0] != "./scripts/publish.sh" {
+		t.Errorf("args = %q", got)
+	}
+	if err := (&HookConfig{Event: "note.*", Command: "true"}).Validate(); err != nil {
+		t.Errorf("wildcard event should pass: %v", err)
+	}
+
+	cfg := NewDefaultConfig()
+	cfg.Hooks = []HookConfig{{Event: "note.saved", Command: "true"}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `hooks[0]: unknown event "note.saved"`) {
+		t.Errorf("unknown event: err = %v", err)
+	}
+	cfg.Hooks = []HookConfig{{Event: "note.deleted"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("missing command should fail")
+	}
+
+	cfg.Hooks = nil
+	cfg.HookConcurrency = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("negative hook_concurrency should fail")
+	}
+}
+
+func TestVaultConfig_WebDAV(t *testing.T) {
+	cfg := NewDefaultConfig()
+	if cfg.Vault.Poll() != 0 {
+		t.Errorf("local vault should use notifications, poll = %v", cfg.Vault.Poll())
+	}
+	cfg.Vault.WebDAV.URL = "https://cloud.example.com/remote.php/dav/files/me/vault"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("webdav url should pass: %v", err)
+	}
+	if cfg.Vault.Poll() != DefaultPollInterval {
+		t.Errorf("webdav vault poll = %v, want %v", cfg.Vault.Poll(), DefaultPollInterval)
+	}
+	cfg.Vault.WebDAV.URL = "ftp://cloud.example.com/vault"
+	if err := cfg.Validate(); err == nil {
+		t.Error("non-http webdav url should fail")
+	}
+}
+
+func TestVaultConfig_SFTP(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Vault.SFTP = SFTPConfig{Host: "files.example.com", User: "me", KeyFile: "/keys/id_ed25519", Path: "vault"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("sftp config should pass: %v", err)
+	}
+	if cfg.Vault.Poll() != DefaultPollInterval {
+		t.Errorf("sftp vault poll = %v, want %v", cfg.Vault.Poll(), DefaultPollInterval)
+	}
+
+	noAuth := cfg.Vault.SFTP
+	noAuth.KeyFile = ""
+	if err := noAuth.Validate(); err == nil {
+		t.Error("sftp without key or password should fail")
+	}
+	bigPool := cfg.Vault.SFTP
+	bigPool.Connections = 64
+	if err := bigPool.Validate(); err == nil {
+		t.Error("sftp with 64 connections should fail")
+	}
+	cfg.Vault.WebDAV.URL = "https://cloud.example.com/dav"
+	if err := cfg.Validate(); err == nil {
+		t.Error("webdav and sftp together should fail")
+	}
+}
+
+func TestVaultConfig_Git(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Vault.Git.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("git defaults should pass: %v", err)
+	}
+	cfg.Vault.Git.Message = "{{.Action"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "vault.git.message") {
+		t.Errorf("bad message template: err = %v", err)
+	}
+	cfg.Vault.Git.Message = storage.DefaultGitMessage
+	cfg.Vault.Git.AutoPush = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "remote") {
+		t.Errorf("auto_push without remote: err = %v", err)
+	}
+	cfg.Vault.Git.Remote = "origin"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("auto_push with remote should pass: %v", err)
+	}
+	cfg.Vault.ReadOnly = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("git with read_only: err = %v", err)
+	}
+	cfg.Vault.ReadOnly = false
+	cfg.Vault.SFTP = SFTPConfig{Host: "files.example.com", User: "me", Password: "secret", Path: "vault"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "local vault") {
+		t.Errorf("git with sftp: err = %v", err)
+	}
+}
+
+func TestBackupConfig(t *testing.T) {
+	cfg := NewDefaultConfig()
+	if p := cfg.Backup.Policy(); p.Dir != "./backups" || p.Keep != 10 {
+		t.Errorf("default policy = %+v", p)
+	}
+	cfg.Backup.Keep = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "backup") {
+		t.Errorf("negative keep: err = %v", err)
+	}
+	cfg.Backup.Keep = 0
+	cfg.Backup.Dir = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("empty dir should fail")
+	}
+}
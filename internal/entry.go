@@ -20,10 +20,13 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/starford/kenaz/internal/api"
+	"github.com/starford/kenaz/internal/hook"
 	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/linkcheck"
+	"github.com/starford/kenaz/internal/mcpserver"
 	"github.com/starford/kenaz/internal/noteservice"
+	"github.com/starford/kenaz/internal/reminder"
 	"github.com/starford/kenaz/internal/sse"
-	"github.com/starford/kenaz/internal/storage"
 )
 
 // Run starts the application with the given options.
@@ -60,13 +63,13 @@ func Run(ctx context.Context, opts ...Option) error {
 	}
 
 	// Initialize storage.
-	store, err := storage.NewFS(cfg.Vault.Path, cfg.Vault.IgnoreDirs)
+	store, err := cfg.Vault.Storage()
 	if err != nil {
 		return fmt.Errorf("init storage: %w", err)
 	}
 
 	// Initialize SQLite index.
-	db, err := index.Open(cfg.SQLite.Path)
+	db, err := index.Open(cfg.SQLite.Path, cfg.IndexOptions()...)
 	if err != nil {
 		return fmt.Errorf("init index: %w", err)
 	}
@@ -78,7 +81,13 @@ func Run(ctx context.Context, opts ...Option) error {
 	}
 
 	// SSE broker.
-	broker := sse.NewBroker(2 * time.Second)
+	broker := sse.NewBroker(2*time.Second, sse.WithNoteLookup(func(p string) (sse.NoteEvent, bool) {
+		n, err := db.GetNote(p)
+		if err != nil || n == nil {
+			return sse.NoteEvent{}, false
+		}
+		return sse.NoteEvent{Path: n.Path, Title: n.Title, Tags: n.Tags, Checksum: n.Checksum, UpdatedAt: &n.UpdatedAt}, true
+	}))
 	defer broker.Close()
 
 	// Ensure attachments directory exists.
@@ -88,8 +97,21 @@ func Run(ctx context.Context, opts ...Option) error {
 	}
 
 	// Build shared service and API router.
-	svc := noteservice.NewService(store, db)
-	apiRouter := api.NewRouter(svc, cfg.Auth.AuthEnabled(), cfg.Auth.Token, broker, cfg.Vault.Path)
+	svc := noteservice.NewService(store, db, append(cfg.ServiceOptions(),
+		noteservice.WithMutationHook(func(m noteservice.Mutation) {
+			broker.Publish(sse.Event{Type: "note.mutation", Data: m})
+		}),
+		noteservice.WithBatchHook(broker.PublishBatch),
+	)...)
+	var attachOpts []api.AttachmentOption
+	if p := cfg.Attachments.Pipeline(); p != nil {
+		attachOpts = append(attachOpts, api.WithPipeline(p))
+	}
+	if cfg.Attachments.ContentAddressed {
+		attachOpts = append(attachOpts, api.WithContentAddressing())
+	}
+	attachOpts = append(attachOpts, api.WithUploadTTL(cfg.Attachments.UploadTTL))
+	apiRouter := api.NewRouter(svc, cfg.Auth.AuthEnabled(), cfg.Auth.Token, broker, cfg.Vault.Path, attachOpts...)
 
 	// Build chi router.
 	r := chi.NewRouter()
@@ -97,6 +119,9 @@ func Run(ctx context.Context, opts ...Option) error {
 	r.Use(middleware.RealIP)
 	r.Use(api.SlogRequestLogger)
 	r.Use(middleware.Recoverer)
+	if c := cfg.App.HTTP.Compression; c.Enabled {
+		r.Use(middleware.Compress(c.Level, "application/json", "text/html"))
+	}
 
 	// Health check endpoints (unauthenticated).
 	r.Get("/health/live", func(w http.ResponseWriter, _ *http.Request) {
@@ -110,9 +135,35 @@ func Run(ctx context.Context, opts ...Option) error {
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
 
-	// Mount API routes under /api (includes /api/events SSE, POST /api/attachments).
+	// Prometheus metrics (unauthenticated, like the health checks).
+	r.Get("/metrics", api.MetricsHandler(func() []api.Metric {
+		busy := db.BusyStats()
+		return []api.Metric{
+			{Name: "kenaz_sqlite_busy_retries_total", Help: "Index writes retried because the database was locked.", Type: "counter", Value: float64(busy.Retries)},
+			{Name: "kenaz_sqlite_busy_failures_total", Help: "Index writes that failed after the last retry because the database was locked.", Type: "counter", Value: float64(busy.Failures)},
+		}
+	}))
+
+	// Mount API routes under /api/v1 (includes /api/v1/events SSE,
+	// POST /api/v1/attachments). /api is kept as an alias for the current
+	// version so existing clients keep working.
+	r.Mount("/api/"+api.APIVersion, apiRouter)
 	r.Mount("/api", apiRouter)
 
+	// Remote MCP clients (kenaz mcp --http) share the REST API's token auth.
+	if app.mcpHTTP {
+		mcpOpts := []mcpserver.Option{mcpserver.WithTools(cfg.MCP.Tools), mcpserver.WithLogger(logger)}
+		if p := cfg.Attachments.Pipeline(); p != nil {
+			mcpOpts = append(mcpOpts, mcpserver.WithPipeline(p))
+		}
+		if cfg.Attachments.ContentAddressed {
+			mcpOpts = append(mcpOpts, mcpserver.WithContentAddressing())
+		}
+		mcpSrv := mcpserver.New(svc, store, mcpOpts...)
+		r.With(api.AuthMiddleware(cfg.Auth.AuthEnabled(), cfg.Auth.Token)).Handle("/mcp", mcpSrv.HTTPHandler())
+		logger.Info("MCP over HTTP enabled", slog.String("endpoint", "/mcp"))
+	}
+
 	// Static attachment serving (public, no auth — these are content assets
 	// referenced by notes, analogous to images on a web page).
 	attachHandler := api.NewAttachmentHandler(cfg.Vault.Path)
@@ -167,13 +218,52 @@ func Run(ctx context.Context, opts ...Option) error {
 
 	g, gCtx := errgroup.WithContext(ctx)
 
-	// Start file watcher with SSE callback.
+	// Command hooks fire on every indexed change, including the server's
+	// own writes.
+	hookDefs := make([]hook.Hook, len(cfg.Hooks))
+	for i := range cfg.Hooks {
+		hookDefs[i] = cfg.Hooks[i].Hook()
+	}
+	hooks := hook.NewRunner(hookDefs, cfg.Vault.Path, cfg.HookConcurrency, logger)
+	defer hooks.Wait()
+
+	// Start file watcher with SSE and hook callbacks.
 	g.Go(func() error {
-		return index.Watch(gCtx, db, store, cfg.Vault.Path, logger, func(kind, path string) {
+		onChange := func(kind, path string) {
 			broker.PublishNoteEvent(kind, path)
-		})
+			hooks.Fire(kind, path)
+		}
+		if interval := cfg.Vault.Poll(); interval > 0 {
+			return index.Poll(gCtx, db, store, interval, logger, onChange)
+		}
+		return index.Watch(gCtx, db, store, cfg.Vault.Path, logger, onChange)
 	})
 
+	// Deliver reminders as they come due.
+	if rc := cfg.Reminders; rc.Enabled {
+		webhooks := &reminder.Webhooks{URLs: rc.Webhooks, Logger: logger}
+		g.Go(func() error {
+			return reminder.Run(gCtx, db, rc.Interval, rc.TaskOffset(), logger, func(r index.Reminder) {
+				broker.Publish(sse.Event{Type: reminder.EventType, Data: r})
+				webhooks.Deliver(r)
+			})
+		})
+	}
+
+	// Check external links in the background for the dead-link report.
+	if lc := cfg.LinkCheck; lc.Enabled {
+		g.Go(func() error {
+			return linkcheck.Run(gCtx, svc, lc.Interval, lc.MaxAge, logger)
+		})
+	}
+
+	// Keep the index compact and its query statistics fresh.
+	if m := cfg.SQLite.Maintenance; m.Enabled {
+		g.Go(func() error {
+			return index.Maintain(gCtx, db, m.Interval, m.Idle, m.Vacuum, logger)
+		})
+	}
+
 	// Start HTTP server.
 	g.Go(func() error {
 		logger.Info("Starting HTTP server", slog.String("address", cfg.App.HTTP.Address()))
@@ -0,0 +1,84 @@
+package graph
+
+import "slices"
+
+// maxPropagationRounds bounds label propagation, which usually settles in a
+// handful of rounds but may oscillate on some graphs.
+const maxPropagationRounds = 100
+
+// Clusters groups n nodes into communities by label propagation over
+// undirected edges: every node starts in its own community and repeatedly
+// adopts the community most common among its neighbours until none changes.
+// Nodes are visited from the best connected down, and a node keeps its
+// community when it ties for most common, otherwise ties go to the smallest
+// label, so the result is deterministic. It returns each node's cluster number; clusters are
+// numbered 0, 1, ... by decreasing size, then by their lowest node index.
+func Clusters(n int, edges [][2]int) []int {
+	adj := make([][]int, n)
+	for _, e := range edges {
+		if e[0] == e[1] {
+			continue
+		}
+		adj[e[0]] = append(adj[e[0]], e[1])
+		adj[e[1]] = append(adj[e[1]], e[0])
+	}
+	label := make([]int, n)
+	for i := range label {
+		label[i] = i
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return len(adj[b]) - len(adj[a]) })
+
+	counts := make(map[int]int)
+	for range maxPropagationRounds {
+		changed := false
+		for _, i := range order {
+			if len(adj[i]) == 0 {
+				continue
+			}
+			clear(counts)
+			for _, o := range adj[i] {
+				counts[label[o]]++
+			}
+			best, bestCount := label[i], counts[label[i]]
+			for l, c := range counts {
+				if c > bestCount || (c == bestCount && best != label[i] && (l == label[i] || l < best)) {
+					best, bestCount = l, c
+				}
+			}
+			if best != label[i] {
+				label[i], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Renumber labels by decreasing cluster size.
+	type cluster struct{ label, size int }
+	byLabel := make(map[int]*cluster)
+	var found []*cluster
+	for _, l := range label {
+		c, ok := byLabel[l]
+		if !ok {
+			c = &cluster{label: l}
+			byLabel[l] = c
+			found = append(found, c)
+		}
+		c.size++
+	}
+	slices.SortStableFunc(found, func(a, b *cluster) int { return b.size - a.size })
+	num := make(map[int]int, len(found))
+	for i, c := range found {
+		num[c.label] = i
+	}
+	out := make([]int, n)
+	for i, l := range label {
+		out[i] = num[l]
+	}
+	return out
+}
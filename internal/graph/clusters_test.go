@@ -0,0 +1,25 @@
+package graph
+
+import "testing"
+
+func TestClusters(t *testing.T) {
+	// Two dense groups joined by a single bridge (2-3), plus an isolated node.
+	edges := [][2]int{
+		{0, 1}, {1, 2}, {2, 0},
+		{3, 4}, {4, 5}, {5, 3}, {5, 6}, {6, 3},
+		{2, 3},
+	}
+	got := Clusters(8, edges)
+	want := []int{1, 1, 1, 0, 0, 0, 0, 2}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Clusters = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestClusters_Empty(t *testing.T) {
+	if got := Clusters(0, nil); len(got) != 0 {
+		t.Errorf("Clusters(0) = %v", got)
+	}
+}
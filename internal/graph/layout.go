@@ -0,0 +1,129 @@
+// Package graph implements algorithms over the note link graph.
+package graph
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// Point is a 2D position.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Layout tuning: edgeLength is the ideal distance between linked nodes;
+// gravity pulls disconnected components toward the origin.
+const (
+	edgeLength     = 50.0
+	gravity        = 0.02
+	coldIterations = 300
+	warmIterations = 60
+)
+
+// Layout computes 2D positions for nodes with the Fruchterman-Reingold
+// force-directed algorithm. edges index into nodes. Nodes found in prev keep
+// their previous position as the starting point and the simulation runs
+// cooler and shorter, so a small graph change only nudges an existing
+// layout. Other nodes start next to an already placed neighbour, or at a
+// position derived from their ID. The result is deterministic.
+func Layout(nodes []string, edges [][2]int, prev map[string]Point) []Point {
+	n := len(nodes)
+	pos := make([]Point, n)
+	placed := make([]bool, n)
+	warm := 0
+	for i, id := range nodes {
+		if p, ok := prev[id]; ok {
+			pos[i], placed[i] = p, true
+			warm++
+		}
+	}
+	adj := make([][]int, n)
+	for _, e := range edges {
+		adj[e[0]] = append(adj[e[0]], e[1])
+		adj[e[1]] = append(adj[e[1]], e[0])
+	}
+	for i, id := range nodes {
+		if placed[i] {
+			continue
+		}
+		j := hashPoint(id, edgeLength)
+		pos[i] = Point{X: j.X * math.Sqrt(float64(n)), Y: j.Y * math.Sqrt(float64(n))}
+		for _, o := range adj[i] {
+			if placed[o] {
+				pos[i] = Point{X: pos[o].X + j.X, Y: pos[o].Y + j.Y}
+				break
+			}
+		}
+		placed[i] = true
+	}
+
+	iterations, temp := coldIterations, edgeLength*math.Sqrt(float64(n))
+	if n > 0 && warm*2 >= n {
+		iterations, temp = warmIterations, edgeLength
+	}
+	k2 := edgeLength * edgeLength
+	disp := make([]Point, n)
+	for it := range iterations {
+		clear(disp)
+		for i := range n {
+			for j := i + 1; j < n; j++ {
+				dx, dy, d := delta(pos[i], pos[j])
+				f := k2 / d
+				disp[i].X += dx / d * f
+				disp[i].Y += dy / d * f
+				disp[j].X -= dx / d * f
+				disp[j].Y -= dy / d * f
+			}
+		}
+		for _, e := range edges {
+			u, v := e[0], e[1]
+			if u == v {
+				continue
+			}
+			dx, dy, d := delta(pos[u], pos[v])
+			f := d * d / edgeLength
+			disp[u].X -= dx / d * f
+			disp[u].Y -= dy / d * f
+			disp[v].X += dx / d * f
+			disp[v].Y += dy / d * f
+		}
+		t := temp * (1 - float64(it)/float64(iterations))
+		for i := range n {
+			disp[i].X -= pos[i].X * gravity * edgeLength
+			disp[i].Y -= pos[i].Y * gravity * edgeLength
+			d := math.Hypot(disp[i].X, disp[i].Y)
+			if d == 0 {
+				continue
+			}
+			step := math.Min(d, t)
+			pos[i].X += disp[i].X / d * step
+			pos[i].Y += disp[i].Y / d * step
+		}
+	}
+	for i := range pos {
+		pos[i] = Point{X: math.Round(pos[i].X*10) / 10, Y: math.Round(pos[i].Y*10) / 10}
+	}
+	return pos
+}
+
+// delta returns the vector from b to a and its length, kept above zero so
+// coincident nodes still push apart.
+func delta(a, b Point) (dx, dy, d float64) {
+	dx, dy = a.X-b.X, a.Y-b.Y
+	d = math.Hypot(dx, dy)
+	if d < 0.01 {
+		dx, d = 0.01, 0.01
+	}
+	return dx, dy, d
+}
+
+// hashPoint maps id to a stable point in [-r, r]².
+func hashPoint(id string, r float64) Point {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	v := h.Sum64()
+	x := float64(v&0xffffffff)/math.MaxUint32*2 - 1
+	y := float64(v>>32)/math.MaxUint32*2 - 1
+	return Point{X: x * r, Y: y * r}
+}
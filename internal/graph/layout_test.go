@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"math"
+	"testing"
+)
+
+func dist(a, b Point) float64 { return math.Hypot(a.X-b.X, a.Y-b.Y) }
+
+func TestLayout(t *testing.T) {
+	nodes := []string{"a", "b", "c", "d", "e", "f"}
+	// Two triangles joined by nothing.
+	edges := [][2]int{{0, 1}, {1, 2}, {2, 0}, {3, 4}, {4, 5}, {5, 3}}
+	pos := Layout(nodes, edges, nil)
+	if len(pos) != len(nodes) {
+		t.Fatalf("got %d positions", len(pos))
+	}
+	if dist(pos[0], pos[1]) >= dist(pos[0], pos[3]) {
+		t.Errorf("linked nodes are farther apart (%.1f) than unlinked ones (%.1f)", dist(pos[0], pos[1]), dist(pos[0], pos[3]))
+	}
+	for i, p := range pos {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.Abs(p.X) > 1000 || math.Abs(p.Y) > 1000 {
+			t.Errorf("node %d at %+v", i, p)
+		}
+	}
+
+	again := Layout(nodes, edges, nil)
+	for i := range pos {
+		if pos[i] != again[i] {
+			t.Fatalf("layout is not deterministic: %+v vs %+v", pos[i], again[i])
+		}
+	}
+}
+
+func TestLayout_WarmStart(t *testing.T) {
+	nodes := []string{"a", "b", "c", "d"}
+	edges := [][2]int{{0, 1}, {1, 2}, {2, 3}}
+	pos := Layout(nodes, edges, nil)
+	prev := make(map[string]Point, len(nodes))
+	for i, id := range nodes {
+		prev[id] = pos[i]
+	}
+
+	// Add a node linked to "d": existing nodes should barely move.
+	nodes = append(nodes, "e")
+	edges = append(edges, [2]int{3, 4})
+	next := Layout(nodes, edges, prev)
+	for i := range 4 {
+		if d := dist(pos[i], next[i]); d > 2*edgeLength {
+			t.Errorf("node %s moved %.1f", nodes[i], d)
+		}
+	}
+	if d := dist(next[3], next[4]); d > 3*edgeLength {
+		t.Errorf("new node placed %.1f away from its neighbour", d)
+	}
+}
@@ -0,0 +1,152 @@
+package graph
+
+import "math"
+
+// PageRank tuning: damping is the probability of following a link rather
+// than jumping to a random node; iteration stops once the ranks move less
+// than pageRankTolerance in total, or after maxPageRankRounds.
+const (
+	damping           = 0.85
+	pageRankTolerance = 1e-9
+	maxPageRankRounds = 100
+)
+
+// BetweennessSamples is how many source nodes Betweenness starts from on
+// graphs larger than that; smaller graphs are measured exactly.
+const BetweennessSamples = 256
+
+// Degrees returns how many distinct nodes link to and from each of n
+// nodes. Self-links are ignored.
+func Degrees(n int, edges [][2]int) (in, out []int) {
+	in, out = make([]int, n), make([]int, n)
+	seen := make(map[[2]int]bool, len(edges))
+	for _, e := range edges {
+		if e[0] == e[1] || seen[e] {
+			continue
+		}
+		seen[e] = true
+		out[e[0]]++
+		in[e[1]]++
+	}
+	return in, out
+}
+
+// PageRank returns the PageRank of n nodes over the directed edges: the
+// share of time a random reader following links, and now and then jumping
+// to any node, spends on each. Nodes without outgoing links pass their rank
+// to every node. The ranks sum to 1.
+func PageRank(n int, edges [][2]int) []float64 {
+	if n == 0 {
+		return []float64{}
+	}
+	_, outDeg := Degrees(n, edges)
+	type link struct{ from, to int }
+	links := make([]link, 0, len(edges))
+	seen := make(map[[2]int]bool, len(edges))
+	for _, e := range edges {
+		if e[0] != e[1] && !seen[e] {
+			seen[e] = true
+			links = append(links, link{e[0], e[1]})
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for range maxPageRankRounds {
+		dangling := 0.0
+		for i, r := range rank {
+			if outDeg[i] == 0 {
+				dangling += r
+			}
+		}
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for _, l := range links {
+			next[l.to] += damping * rank[l.from] / float64(outDeg[l.from])
+		}
+		delta := 0.0
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// Betweenness returns the betweenness centrality of n nodes, following
+// edges in either direction: the share of shortest paths between other
+// nodes that pass through each, from 0 to 1. It runs Brandes' algorithm
+// from every node, or on graphs of more than samples nodes from samples
+// evenly spread ones and scales the result, which approximates it. The
+// result is deterministic.
+func Betweenness(n int, edges [][2]int, samples int) []float64 {
+	score := make([]float64, n)
+	if n < 3 {
+		return score
+	}
+	adj := make([][]int, n)
+	seen := make(map[[2]int]bool, len(edges))
+	for _, e := range edges {
+		a, b := min(e[0], e[1]), max(e[0], e[1])
+		if a == b || seen[[2]int{a, b}] {
+			continue
+		}
+		seen[[2]int{a, b}] = true
+		adj[a] = append(adj[a], b)
+		adj[b] = append(adj[b], a)
+	}
+
+	sources := n
+	if samples > 0 && samples < n {
+		sources = samples
+	}
+	dist := make([]int, n)
+	paths := make([]float64, n)
+	dep := make([]float64, n)
+	pred := make([][]int, n)
+	order := make([]int, 0, n)
+	for k := range sources {
+		s := k * n / sources
+		for i := range n {
+			dist[i], paths[i], dep[i], pred[i] = -1, 0, 0, pred[i][:0]
+		}
+		dist[s], paths[s] = 0, 1
+		order = append(order[:0], s)
+		for q := 0; q < len(order); q++ {
+			v := order[q]
+			for _, w := range adj[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					order = append(order, w)
+				}
+				if dist[w] == dist[v]+1 {
+					paths[w] += paths[v]
+					pred[w] = append(pred[w], v)
+				}
+			}
+		}
+		for i := len(order) - 1; i > 0; i-- {
+			w := order[i]
+			for _, v := range pred[w] {
+				dep[v] += paths[v] / paths[w] * (1 + dep[w])
+			}
+			score[w] += dep[w]
+		}
+	}
+
+	// Every pair is counted from both ends; normalize by the number of
+	// ordered pairs of other nodes.
+	scale := float64(n) / float64(sources) / float64((n-1)*(n-2))
+	for i := range score {
+		score[i] *= scale
+	}
+	return score
+}
@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDegrees(t *testing.T) {
+	// 0 -> 1 twice, 0 -> 2, 2 -> 0, 1 -> 1.
+	in, out := Degrees(4, [][2]int{{0, 1}, {0, 1}, {0, 2}, {2, 0}, {1, 1}})
+	if !slices.Equal(in, []int{1, 1, 1, 0}) || !slices.Equal(out, []int{2, 0, 1, 0}) {
+		t.Errorf("Degrees = %v, %v", in, out)
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	// Everything links to 0; 3 links nowhere.
+	rank := PageRank(4, [][2]int{{1, 0}, {2, 0}, {0, 1}})
+	sum := 0.0
+	for _, r := range rank {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("ranks sum to %v, want 1", sum)
+	}
+	if rank[0] <= rank[1] || rank[1] <= rank[2] || math.Abs(rank[2]-rank[3]) > 1e-9 {
+		t.Errorf("PageRank = %v, want 0 > 1 > 2 = 3", rank)
+	}
+	if got := PageRank(0, nil); len(got) != 0 {
+		t.Errorf("PageRank(0) = %v", got)
+	}
+}
+
+func TestBetweenness(t *testing.T) {
+	// A star around 0, plus an isolated node.
+	edges := [][2]int{{0, 1}, {2, 0}, {0, 3}, {0, 4}}
+	got := Betweenness(6, edges, 0)
+	want := []float64{0.6, 0, 0, 0, 0, 0}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("Betweenness = %v, want %v", got, want)
+		}
+	}
+
+	// A path 0-1-2-3-4: sampling approximates the exact values.
+	path := [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}}
+	exact := Betweenness(5, path, 0)
+	approx := Betweenness(5, path, 3)
+	if exact[2] <= exact[1] || exact[0] != 0 {
+		t.Errorf("exact = %v", exact)
+	}
+	if approx[2] == 0 || approx[0] != 0 {
+		t.Errorf("approx = %v", approx)
+	}
+}
@@ -0,0 +1,83 @@
+package graph
+
+import "slices"
+
+// ShortestPath returns the indexes of the nodes on a shortest path from one
+// node to another, both included, following edges in either direction. Among
+// equally short paths it prefers lower node indexes. It returns nil when the
+// nodes are not connected.
+func ShortestPath(n int, edges [][2]int, from, to int) []int {
+	if paths := ShortestPaths(n, edges, from, to, 1); paths != nil {
+		return paths[0]
+	}
+	return nil
+}
+
+// ShortestPaths returns up to limit distinct shortest paths from one node to
+// another, as in ShortestPath, ordered by their node indexes. It returns nil
+// when the nodes are not connected.
+func ShortestPaths(n int, edges [][2]int, from, to, limit int) [][]int {
+	if from < 0 || from >= n || to < 0 || to >= n || limit < 1 {
+		return nil
+	}
+	adj := undirected(n, edges)
+
+	// Distances to the end node; every step of a shortest path gets one
+	// closer, and every node but the end has a neighbor one closer.
+	dist := make([]int, n)
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[to] = 0
+	queue := []int{to}
+	for len(queue) > 0 && dist[from] < 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, o := range adj[cur] {
+			if dist[o] < 0 {
+				dist[o] = dist[cur] + 1
+				queue = append(queue, o)
+			}
+		}
+	}
+	if dist[from] < 0 {
+		return nil
+	}
+
+	var out [][]int
+	path := []int{from}
+	var walk func(cur int)
+	walk = func(cur int) {
+		if cur == to {
+			out = append(out, slices.Clone(path))
+			return
+		}
+		for _, o := range adj[cur] {
+			if len(out) == limit {
+				return
+			}
+			if dist[o] == dist[cur]-1 {
+				path = append(path, o)
+				walk(o)
+				path = path[:len(path)-1]
+			}
+		}
+	}
+	walk(from)
+	return out
+}
+
+// undirected returns the sorted, distinct neighbors of each node, following
+// edges in either direction.
+func undirected(n int, edges [][2]int) [][]int {
+	adj := make([][]int, n)
+	for _, e := range edges {
+		adj[e[0]] = append(adj[e[0]], e[1])
+		adj[e[1]] = append(adj[e[1]], e[0])
+	}
+	for i := range adj {
+		slices.Sort(adj[i])
+		adj[i] = slices.Compact(adj[i])
+	}
+	return adj
+}
@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestShortestPath(t *testing.T) {
+	// 0→1→2→3 and a shortcut 0→4→3; edge 5→4 points the "wrong" way.
+	edges := [][2]int{{0, 1}, {1, 2}, {2, 3}, {0, 4}, {4, 3}, {5, 4}}
+	tests := []struct {
+		from, to int
+		want     []int
+	}{
+		{0, 3, []int{0, 4, 3}},
+		{3, 0, []int{3, 4, 0}},
+		{5, 1, []int{5, 4, 0, 1}},
+		{2, 2, []int{2}},
+		{0, 6, nil},
+		{0, 9, nil},
+	}
+	for _, tt := range tests {
+		if got := ShortestPath(7, edges, tt.from, tt.to); !slices.Equal(got, tt.want) {
+			t.Errorf("ShortestPath(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestShortestPaths(t *testing.T) {
+	// Two routes of length 2 from 0 to 3 (via 1 and via 2), a longer one via
+	// 4 and 5, and the edge 0–1 in both directions.
+	edges := [][2]int{{0, 2}, {2, 3}, {0, 1}, {1, 0}, {1, 3}, {0, 4}, {4, 5}, {5, 3}}
+	tests := []struct {
+		from, to, limit int
+		want            [][]int
+	}{
+		{0, 3, 10, [][]int{{0, 1, 3}, {0, 2, 3}}},
+		{0, 3, 1, [][]int{{0, 1, 3}}},
+		{3, 0, 10, [][]int{{3, 1, 0}, {3, 2, 0}}},
+		{4, 4, 10, [][]int{{4}}},
+		{0, 6, 10, nil},
+		{0, 3, 0, nil},
+	}
+	for _, tt := range tests {
+		got := ShortestPaths(7, edges, tt.from, tt.to, tt.limit)
+		if !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("ShortestPaths(%d, %d, %d) = %v, want %v", tt.from, tt.to, tt.limit, got, tt.want)
+		}
+	}
+}
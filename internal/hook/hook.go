@@ -0,0 +1,164 @@
+// Package hook runs user-configured shell commands when notes change.
+package hook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a hook run when its Timeout is zero.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultConcurrency is how many hook commands run at once when the
+// runner's limit is zero.
+const DefaultConcurrency = 4
+
+// maxQueued bounds the runs waiting for a free slot; runs beyond it are
+// dropped with a warning.
+const maxQueued = 1000
+
+// maxOutput caps how much of a failed hook's output is logged.
+const maxOutput = 512
+
+// Events lists the events a hook can subscribe to. "note.*" matches all of
+// them.
+var Events = []string{"note.created", "note.updated", "note.deleted"}
+
+// Hook is a command run after matching note events. The note path and the
+// change kind ("created", "updated", or "deleted") are appended to Args.
+type Hook struct {
+	Event   string
+	Args    []string
+	Timeout time.Duration
+}
+
+// matches reports whether the hook subscribes to event.
+func (h *Hook) matches(event string) bool {
+	return h.Event == event || h.Event == "note.*"
+}
+
+// Runner runs hooks in the background, so a slow script never delays
+// indexing or the SSE stream. At most its concurrency limit of commands
+// run at once; further runs queue in order. Failures and timeouts are
+// logged.
+type Runner struct {
+	hooks  []Hook
+	dir    string
+	logger *slog.Logger
+	limit  int
+	// queue feeds the workers, started on the first Fire.
+	queue chan run
+	start sync.Once
+	// wg counts queued and running runs.
+	wg sync.WaitGroup
+}
+
+// run is one queued hook invocation.
+type run struct {
+	hook              *Hook
+	event, kind, path string
+}
+
+// NewRunner returns a runner for hooks executed in dir (the vault root),
+// running at most concurrency of them at once (DefaultConcurrency if zero).
+func NewRunner(hooks []Hook, dir string, concurrency int, logger *slog.Logger) *Runner {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	return &Runner{hooks: hooks, dir: dir, logger: logger, limit: concurrency, queue: make(chan run, maxQueued)}
+}
+
+// Fire queues every hook subscribed to "note.<kind>" for path. Besides the
+// two arguments, hooks see KENAZ_EVENT, KENAZ_PATH, and KENAZ_VAULT in
+// their environment. When the queue is full the run is dropped and logged.
+func (r *Runner) Fire(kind, path string) {
+	event := "note." + kind
+	for i := range r.hooks {
+		h := &r.hooks[i]
+		if !h.matches(event) || len(h.Args) == 0 {
+			continue
+		}
+		r.start.Do(r.startWorkers)
+		r.wg.Add(1)
+		select {
+		case r.queue <- run{hook: h, event: event, kind: kind, path: path}:
+		default:
+			r.wg.Done()
+			r.logger.Warn("hook dropped, too many runs queued",
+				slog.String("command", h.Args[0]),
+				slog.String("event", event),
+				slog.String("path", path))
+		}
+	}
+}
+
+// startWorkers starts the goroutines that take runs off the queue.
+func (r *Runner) startWorkers() {
+	for range r.limit {
+		go func() {
+			for job := range r.queue {
+				r.run(job.hook, job.event, job.kind, job.path)
+				r.wg.Done()
+			}
+		}()
+	}
+}
+
+// Wait blocks until all queued hooks have finished.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) run(h *Hook, event, kind, path string) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := append(append([]string{}, h.Args[1:]...), path, kind)
+	cmd := exec.CommandContext(ctx, h.Args[0], args...)
+	cmd.Dir = r.dir
+	// Don't wait on background processes the hook left holding its output.
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(),
+		"KENAZ_EVENT="+event,
+		"KENAZ_PATH="+path,
+		"KENAZ_VAULT="+r.dir,
+	)
+	start := time.Now()
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		r.logger.Debug("hook finished",
+			slog.String("command", h.Args[0]),
+			slog.String("event", event),
+			slog.String("path", path),
+			slog.Duration("duration", time.Since(start)))
+		return
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = errors.New("timed out after " + timeout.String())
+	}
+	r.logger.Warn("hook failed",
+		slog.String("command", h.Args[0]),
+		slog.String("event", event),
+		slog.String("path", path),
+		slog.String("error", err.Error()),
+		slog.String("output", tail(string(out))))
+}
+
+// tail trims output to its last maxOutput bytes.
+func tail(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxOutput {
+		s = "…" + s[len(s)-maxOutput:]
+	}
+	return s
+}
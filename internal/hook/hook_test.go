@@ -0,0 +1,94 @@
+package hook
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRunner_PassesPathAndKind(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRunner([]Hook{
+		{Event: "note.updated", Args: []string{"sh", "-c", `echo "$1 $2 $KENAZ_EVENT" >> updated.log`, "hook"}},
+		{Event: "note.*", Args: []string{"sh", "-c", `echo "$2" >> all.log`, "hook"}},
+	}, dir, 0, discard())
+
+	r.Fire("updated", "notes/a.md")
+	r.Wait()
+	r.Fire("created", "notes/b.md")
+	r.Wait()
+
+	got, err := os.ReadFile(filepath.Join(dir, "updated.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "notes/a.md updated note.updated\n" {
+		t.Errorf("updated.log = %q", got)
+	}
+	got, err = os.ReadFile(filepath.Join(dir, "all.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "updated\ncreated\n" {
+		t.Errorf("all.log = %q", got)
+	}
+}
+
+func TestRunner_LogsFailureAndTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	r := NewRunner([]Hook{
+		{Event: "note.deleted", Args: []string{"sh", "-c", "echo boom; exit 3"}},
+		{Event: "note.deleted", Args: []string{"sh", "-c", "exec sleep 5"}, Timeout: 50 * time.Millisecond},
+	}, t.TempDir(), 0, logger)
+
+	start := time.Now()
+	r.Fire("deleted", "a.md")
+	r.Wait()
+	if time.Since(start) > 3*time.Second {
+		t.Error("timeout was not enforced")
+	}
+	out := buf.String()
+	for _, want := range []string{"exit status 3", "output=boom", "timed out after 50ms"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunner_LimitsConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	// Each run marks itself running, records how many runs are, and
+	// unmarks itself when done.
+	script := `touch "$1.run"; ls *.run | wc -l >> counts; sleep 0.1; rm "$1.run"`
+	r := NewRunner([]Hook{{Event: "note.*", Args: []string{"sh", "-c", script, "hook"}}}, dir, 2, discard())
+
+	for i := range 6 {
+		r.Fire("updated", "n"+strconv.Itoa(i)+".md")
+	}
+	r.Wait()
+
+	got, err := os.ReadFile(filepath.Join(dir, "counts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := strings.Fields(string(got))
+	if len(counts) != 6 {
+		t.Fatalf("runs = %d, want 6", len(counts))
+	}
+	for _, c := range counts {
+		if n, _ := strconv.Atoi(c); n < 1 || n > 2 {
+			t.Errorf("%s hooks ran at once, limit is 2", c)
+		}
+	}
+}
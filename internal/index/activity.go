@@ -0,0 +1,104 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WritingDay aggregates the note edits of one day.
+type WritingDay struct {
+	Date         string `json:"date" example:"2025-02-01" validate:"required"`
+	Edits        int    `json:"edits" validate:"required"`
+	WordsAdded   int    `json:"words_added" validate:"required"`
+	WordsRemoved int    `json:"words_removed" validate:"required"`
+}
+
+// recordEditSQL adds one edit with the given word counts to a day.
+const recordEditSQL = `
+	INSERT INTO writing_activity (day, edits, words_added, words_removed) VALUES (?, 1, ?, ?)
+	ON CONFLICT(day) DO UPDATE SET
+		edits         = edits + 1,
+		words_added   = words_added + excluded.words_added,
+		words_removed = words_removed + excluded.words_removed
+`
+
+// RecordEdit adds one edit with the given word counts to day (YYYY-MM-DD).
+func (db *DB) RecordEdit(day string, added, removed int) error {
+	if err := db.exec(recordEditSQL, day, added, removed); err != nil {
+		return fmt.Errorf("index: record edit: %w", err)
+	}
+	return nil
+}
+
+// trackEdit adds the change of the note at path to today's writing
+// activity when its content, now with checksum sum and body (both empty
+// when it is deleted), differs from the indexed one. Every writer
+// (the note service, the watcher, Sync, batches, and imports) indexes its
+// notes, so all of them count. A note indexed with an empty checksum,
+// as left by a forced re-index, is not counted. It runs within tx before
+// the note is written; failures are ignored, since analytics must never
+// block indexing.
+func trackEdit(tx *sql.Tx, path, sum, body string) {
+	var oldSum, oldBody string
+	err := tx.QueryRow(`SELECT checksum, body FROM notes WHERE path = ?`, path).Scan(&oldSum, &oldBody)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if sum == "" {
+			return
+		}
+	case err != nil, oldSum == sum, oldSum == "":
+		return
+	}
+	added, removed := wordDelta(wordCounts(oldBody), wordCounts(body))
+	_, _ = tx.Exec(recordEditSQL, time.Now().Format(time.DateOnly), added, removed)
+}
+
+// wordCounts counts the words of a note body.
+func wordCounts(body string) map[string]int {
+	counts := make(map[string]int)
+	for _, w := range strings.Fields(body) {
+		counts[w]++
+	}
+	return counts
+}
+
+// wordDelta returns how many words b has over a and vice versa, comparing
+// occurrence counts per word so that moved text counts as unchanged.
+func wordDelta(a, b map[string]int) (added, removed int) {
+	for w, n := range b {
+		if d := n - a[w]; d > 0 {
+			added += d
+		}
+	}
+	for w, n := range a {
+		if d := n - b[w]; d > 0 {
+			removed += d
+		}
+	}
+	return added, removed
+}
+
+// WritingActivity returns the days from through to (inclusive, YYYY-MM-DD)
+// that have edits, oldest first.
+func (db *DB) WritingActivity(from, to string) ([]WritingDay, error) {
+	rows, err := db.conn.Query(`
+		SELECT day, edits, words_added, words_removed FROM writing_activity
+		WHERE day BETWEEN ? AND ? ORDER BY day
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("index: writing activity: %w", err)
+	}
+	defer rows.Close()
+	var out []WritingDay
+	for rows.Next() {
+		var d WritingDay
+		if err := rows.Scan(&d.Date, &d.Edits, &d.WordsAdded, &d.WordsRemoved); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,50 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+)
+
+// IndexedNote is a parsed note ready to be written to the index.
+type IndexedNote struct {
+	Row   NoteRow
+	Body  string
+	Links []Link
+}
+
+// NoteBatch is a set of index changes applied together by ApplyBatch. A
+// path should appear at most once across Upserts and Deletes.
+type NoteBatch struct {
+	Upserts []IndexedNote
+	Deletes []string
+	// Trash records files moved to the trash, usually the Deletes.
+	Trash []TrashEntry
+	// Rebuild marks a batch that re-creates the index from unchanged
+	// notes, whose upserts are not writing activity.
+	Rebuild bool
+}
+
+// ApplyBatch applies b in a single transaction, so a bulk write costs one
+// commit and readers never see it half done. It gives up, writing
+// nothing, once ctx is done.
+func (db *DB) ApplyBatch(ctx context.Context, b NoteBatch) error {
+	return db.withTxContext(ctx, func(tx *sql.Tx) error {
+		changed := make([]string, 0, len(b.Upserts)+len(b.Deletes))
+		for _, n := range b.Upserts {
+			if err := db.upsertNote(tx, n.Row, n.Body, n.Links, !b.Rebuild); err != nil {
+				return err
+			}
+			changed = append(changed, n.Row.Path)
+		}
+		for _, p := range b.Deletes {
+			if err := deleteNote(tx, p); err != nil {
+				return err
+			}
+			changed = append(changed, p)
+		}
+		if err := db.resolveChangedLinks(tx, changed); err != nil {
+			return err
+		}
+		return addTrash(tx, b.Trash)
+	})
+}
@@ -0,0 +1,130 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// Card is an indexed flashcard with its review schedule. Cards never
+// reviewed have an empty Due and count as due immediately.
+type Card struct {
+	Path string `json:"path" validate:"required"`
+	// Title is the title of the note holding the card.
+	Title    string `json:"title" validate:"required"`
+	ID       string `json:"id" example:"3f2a9c1be044" validate:"required"`
+	Question string `json:"question" validate:"required"`
+	Answer   string `json:"answer" validate:"required"`
+	Line     int    `json:"line" validate:"required"`
+	// Due is the date of the next review, YYYY-MM-DD.
+	Due         string  `json:"due,omitempty" example:"2026-10-20"`
+	Ease        float64 `json:"ease" example:"2.5" validate:"required"`
+	Interval    int     `json:"interval" validate:"required"`
+	Repetitions int     `json:"repetitions" validate:"required"`
+	// Reviews and Lapses count all reviews and failed ones.
+	Reviews    int        `json:"reviews" validate:"required"`
+	Lapses     int        `json:"lapses" validate:"required"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// cardColumns selects a Card from cards c joined with notes n and
+// (left-joined) card_reviews r.
+const cardColumns = `c.path, n.title, c.card, c.question, c.answer, c.line,
+	COALESCE(r.due, ''), COALESCE(r.ease, 0), COALESCE(r.interval_days, 0), COALESCE(r.repetitions, 0),
+	COALESCE(r.reviews, 0), COALESCE(r.lapses, 0), r.reviewed_at`
+
+const cardJoins = `cards c JOIN notes n ON n.path = c.path
+	LEFT JOIN card_reviews r ON r.path = c.path AND r.card = c.card`
+
+func scanCard(s interface{ Scan(...any) error }) (Card, error) {
+	var c Card
+	var reviewed sql.NullTime
+	err := s.Scan(&c.Path, &c.Title, &c.ID, &c.Question, &c.Answer, &c.Line,
+		&c.Due, &c.Ease, &c.Interval, &c.Repetitions, &c.Reviews, &c.Lapses, &reviewed)
+	if reviewed.Valid {
+		c.ReviewedAt = &reviewed.Time
+	}
+	return c, err
+}
+
+// replaceCards replaces the flashcards stored for path within tx. Review
+// schedules are kept, so a card that survives an edit keeps its history.
+func replaceCards(tx *sql.Tx, path string, cards []parser.Card) error {
+	if _, err := tx.Exec(`DELETE FROM cards WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete old cards: %w", err)
+	}
+	for _, c := range cards {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO cards (path, card, question, answer, line) VALUES (?, ?, ?, ?, ?)`,
+			path, c.ID, c.Question, c.Answer, c.Line); err != nil {
+			return fmt.Errorf("index: insert card: %w", err)
+		}
+	}
+	return nil
+}
+
+// DueCards returns up to limit cards due on or before date (YYYY-MM-DD):
+// reviewed cards by due date, then new cards in vault order. It also returns
+// the number of due cards in total.
+func (db *DB) DueCards(date string, limit int) ([]Card, int, error) {
+	const where = ` WHERE r.due IS NULL OR r.due <= ?`
+	var total int
+	if err := db.conn.QueryRow(`SELECT count(*) FROM `+cardJoins+where, date).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("index: count due cards: %w", err)
+	}
+	rows, err := db.conn.Query(`SELECT `+cardColumns+` FROM `+cardJoins+where+`
+		ORDER BY r.due IS NULL, r.due, c.path, c.line LIMIT ?`, date, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("index: due cards: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	out := []Card{}
+	for rows.Next() {
+		c, err := scanCard(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("index: scan card: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, total, rows.Err()
+}
+
+// GetCard returns the card id of the note at path, or nil when there is
+// none.
+func (db *DB) GetCard(path, id string) (*Card, error) {
+	c, err := scanCard(db.conn.QueryRow(`SELECT `+cardColumns+` FROM `+cardJoins+`
+		WHERE c.path = ? AND c.card = ?`, path, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("index: get card: %w", err)
+	}
+	return &c, nil
+}
+
+// RecordCardReview stores c's schedule (Due, Ease, Interval, Repetitions,
+// Reviews, Lapses) as of a review at time at, and appends grade to the
+// card's review log.
+func (db *DB) RecordCardReview(c *Card, grade int, at time.Time) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			INSERT INTO card_reviews (path, card, due, ease, interval_days, repetitions, reviews, lapses, reviewed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(path, card) DO UPDATE SET
+				due = excluded.due, ease = excluded.ease, interval_days = excluded.interval_days,
+				repetitions = excluded.repetitions, reviews = excluded.reviews, lapses = excluded.lapses,
+				reviewed_at = excluded.reviewed_at
+		`, c.Path, c.ID, c.Due, c.Ease, c.Interval, c.Repetitions, c.Reviews, c.Lapses, at); err != nil {
+			return fmt.Errorf("index: save card review: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO card_review_log (path, card, grade, reviewed_at) VALUES (?, ?, ?, ?)`,
+			c.Path, c.ID, grade, at); err != nil {
+			return fmt.Errorf("index: log card review: %w", err)
+		}
+		return nil
+	})
+}
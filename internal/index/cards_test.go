@@ -0,0 +1,66 @@
+package index
+
+import (
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/parser"
+)
+
+func TestCards(t *testing.T) {
+	db := testDB(t)
+	data := []byte("# Go\n\nQ: Zero value of a map?\nA: nil\n\n## Goroutines #flashcard\nLightweight threads.\n")
+	if err := indexFile(db, "go.md", data, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	due, total, err := db.DueCards("2026-10-16", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(due) != 2 || due[0].Question != "Zero value of a map?" || due[0].Title != "Go" || due[0].Due != "" {
+		t.Fatalf("due = %d %+v", total, due)
+	}
+
+	// A reviewed card leaves the queue until its due date.
+	c := due[0]
+	c.Due, c.Ease, c.Interval, c.Repetitions, c.Reviews = "2026-10-17", 2.5, 1, 1, 1
+	at := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	if err := db.RecordCardReview(&c, 4, at); err != nil {
+		t.Fatal(err)
+	}
+	if due, total, _ = db.DueCards("2026-10-16", 10); total != 1 || due[0].Question != "Goroutines" {
+		t.Errorf("due after review = %d %+v", total, due)
+	}
+	if due, _, _ = db.DueCards("2026-10-17", 1); len(due) != 1 || due[0].ID != c.ID || due[0].Reviews != 1 || due[0].ReviewedAt == nil {
+		t.Errorf("due next day = %+v, want the reviewed card first", due)
+	}
+
+	// The schedule survives edits and moves, and comes back after a delete.
+	edited := append(data, []byte("\nQ: New?\nA: Yes\n")...)
+	if err := indexFile(db, "go.md", edited, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MoveNote("go.md", "study/go.md"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.GetCard("study/go.md", parser.CardID("Zero value of a map?"))
+	if err != nil || got == nil || got.Due != "2026-10-17" || got.Repetitions != 1 {
+		t.Fatalf("moved card = %+v, %v", got, err)
+	}
+	if err := db.DeleteNote("study/go.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ = db.DueCards("2030-01-01", 10); total != 0 {
+		t.Errorf("due after delete = %d, want 0", total)
+	}
+	if err := indexFile(db, "study/go.md", edited, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = db.GetCard("study/go.md", c.ID); got == nil || got.Reviews != 1 {
+		t.Errorf("restored card = %+v, want its schedule kept", got)
+	}
+	if got, _ = db.GetCard("study/go.md", "missing"); got != nil {
+		t.Errorf("GetCard(missing) = %+v", got)
+	}
+}
@@ -0,0 +1,121 @@
+package index
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// ReferencesFolder holds reference notes by convention: every note in it is
+// a reference, cited by its file name unless the frontmatter sets a key.
+const ReferencesFolder = "references"
+
+// resolveCiteKey is the resolution kind of "@citekey" entries.
+const resolveCiteKey = "citekey"
+
+// Reference is an indexed reference note and the notes citing it.
+type Reference struct {
+	Path string `json:"path" validate:"required"`
+	parser.Reference
+	CitedBy []string `json:"cited_by" validate:"required"`
+}
+
+// citeKeyCleanRe matches runs of characters not allowed in cite keys.
+var citeKeyCleanRe = regexp.MustCompile(`[^\p{L}\p{N}_:.+/-]+`)
+
+// noteReference returns the bibliographic record of n: its frontmatter
+// reference, or a bare one for notes in the references folder. Missing cite
+// keys are derived from the file name. Returns nil for other notes.
+func noteReference(n NoteRow) *parser.Reference {
+	ref := n.Reference
+	if ref == nil {
+		if !strings.HasPrefix(n.Path, ReferencesFolder+"/") {
+			return nil
+		}
+		ref = &parser.Reference{Title: n.Title}
+	}
+	if ref.Key == "" {
+		r := *ref
+		r.Key = strings.Trim(citeKeyCleanRe.ReplaceAllString(strings.TrimSuffix(path.Base(n.Path), ".md"), "-"), "-")
+		ref = &r
+	}
+	return ref
+}
+
+// replaceReference replaces the reference stored for n within tx, along with
+// its "@citekey" resolution entry. It runs after replaceResolution, which
+// clears the note's resolution entries.
+func replaceReference(tx *sql.Tx, n NoteRow) error {
+	if _, err := tx.Exec(`DELETE FROM refs WHERE path = ?`, n.Path); err != nil {
+		return fmt.Errorf("index: delete old reference: %w", err)
+	}
+	ref := noteReference(n)
+	if ref == nil || ref.Key == "" {
+		return nil
+	}
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return fmt.Errorf("index: encode reference: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO refs (path, citekey, doi, data) VALUES (?, ?, ?, ?)`,
+		n.Path, ref.Key, ref.DOI, string(data)); err != nil {
+		return fmt.Errorf("index: insert reference: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO resolution (key, path, kind) VALUES (?, ?, ?)`,
+		normalizeKey("@"+ref.Key), n.Path, resolveCiteKey); err != nil {
+		return fmt.Errorf("index: insert citekey resolution: %w", err)
+	}
+	return nil
+}
+
+// citeKey returns the cite key of the reference note at path, or "".
+func (db *DB) citeKey(path string) (string, error) {
+	var key string
+	err := db.conn.QueryRow(`SELECT citekey FROM refs WHERE path = ?`, path).Scan(&key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("index: cite key: %w", err)
+	}
+	return key, nil
+}
+
+// References returns every reference note, sorted by cite key, with the
+// notes citing it by [[@citekey]] or linking to it otherwise.
+func (db *DB) References() ([]Reference, error) {
+	rows, err := db.conn.Query(`SELECT path, data FROM refs ORDER BY citekey COLLATE NOCASE, path`)
+	if err != nil {
+		return nil, fmt.Errorf("index: references: %w", err)
+	}
+	out := []Reference{}
+	for rows.Next() {
+		var r Reference
+		var data string
+		if err := rows.Scan(&r.Path, &data); err != nil {
+			rows.Close() //nolint:errcheck
+			return nil, fmt.Errorf("index: scan reference: %w", err)
+		}
+		if err := json.Unmarshal([]byte(data), &r.Reference); err != nil {
+			rows.Close() //nolint:errcheck
+			return nil, fmt.Errorf("index: decode reference %s: %w", r.Path, err)
+		}
+		out = append(out, r)
+	}
+	rows.Close() //nolint:errcheck
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range out {
+		cited, err := db.Backlinks(out[i].Path)
+		if err != nil {
+			return nil, err
+		}
+		out[i].CitedBy = nonNilSlice(cited)
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReferences(t *testing.T) {
+	db := testDB(t)
+	now := time.Now()
+	files := map[string]string{
+		"papers/attention.md":     "---\ncitekey: vaswani2017\ntitle: Attention\n---\n",
+		"references/knuth1984.md": "# Literate Programming\n",
+		"reading.md":              "See [[@vaswani2017]] and [[@Knuth1984]].\n",
+		"other.md":                "Also [[papers/attention]].\n",
+	}
+	for p, data := range files {
+		if err := indexFile(db, p, []byte(data), now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refs, err := db.References()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 {
+		t.Fatalf("references = %+v, want 2", refs)
+	}
+	if refs[0].Key != "knuth1984" || refs[0].Title != "Literate Programming" || !reflect.DeepEqual(refs[0].CitedBy, []string{"reading.md"}) {
+		t.Errorf("first = %+v", refs[0])
+	}
+	if refs[1].Key != "vaswani2017" || !reflect.DeepEqual(refs[1].CitedBy, []string{"other.md", "reading.md"}) {
+		t.Errorf("second = %+v", refs[1])
+	}
+
+	if p, _ := db.ResolveLink("@vaswani2017"); p != "papers/attention.md" {
+		t.Errorf("resolve @vaswani2017 = %q", p)
+	}
+
+	_, links, err := db.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cited int
+	for _, l := range links {
+		if l.Source == "reading.md" && (l.Target == "papers/attention.md" || l.Target == "references/knuth1984.md") {
+			cited++
+		}
+	}
+	if cited != 2 {
+		t.Errorf("graph citation edges = %d, want 2: %+v", cited, links)
+	}
+
+	if err := db.MoveNote("papers/attention.md", "archive/attention.md"); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := db.ResolveLink("@vaswani2017"); p != "archive/attention.md" {
+		t.Errorf("resolve after move = %q", p)
+	}
+	if err := db.DeleteNote("archive/attention.md"); err != nil {
+		t.Fatal(err)
+	}
+	if refs, _ = db.References(); len(refs) != 1 {
+		t.Errorf("after delete = %+v", refs)
+	}
+}
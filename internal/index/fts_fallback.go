@@ -3,8 +3,12 @@
 package index
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 func initFTS(_ *sql.DB) error {
@@ -12,37 +16,211 @@ func initFTS(_ *sql.DB) error {
 	return nil
 }
 
-func ftsUpsert(_ *sql.Tx, _, _, _ string, _ []string) error {
+func ftsUpsert(_ *sql.Tx, _, _, _ string, _ []string, _ string) error {
 	// Body is already stored in the notes table; nothing extra to do.
 	return nil
 }
 
 func ftsDelete(_ *sql.Tx, _ string) error { return nil }
 
-// Search performs a LIKE-based search (fallback when FTS5 is not compiled in).
-func (db *DB) Search(query string, limit int) ([]SearchResult, error) {
+func ftsClear(_ *sql.Tx) error { return nil }
+
+// ftsMerge has no FTS segments to merge.
+func (db *DB) ftsMerge(_ context.Context) error { return nil }
+
+// ftsCount reports that there is no FTS table.
+func (db *DB) ftsCount() (int, bool, error) { return 0, false, nil }
+
+// Search performs a LIKE-based search (fallback when FTS5 is not compiled in)
+// and returns one page of results and the total number of matches. The
+// query is parsed as for FTS5 (see parseQuery) and matched on a best-effort
+// basis: every word or phrase must occur as a substring, OR offers
+// alternatives, NOT excludes the next word, prefixes match like words, and
+// NEAR only requires its words. A query with nothing to search for matches
+// nothing.
+func (db *DB) Search(query string, opts SearchOptions) ([]SearchResult, int, error) {
+	limit := opts.Limit
 	if limit <= 0 {
 		limit = 20
 	}
-	like := "%" + query + "%"
-	rows, err := db.conn.Query(`
-		SELECT path, title, substr(body, 1, 200)
+	cond, condArgs, needles := likeQuery(parseQuery(query))
+	if cond == "" {
+		return nil, 0, nil
+	}
+	scope, args := opts.scope()
+	args = append(condArgs, args...)
+	from := `
 		FROM notes
-		WHERE title LIKE ? OR body LIKE ? OR tags LIKE ?
-		LIMIT ?
-	`, like, like, like, limit)
+		WHERE ` + cond + scope
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT count(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("index: count search: %w", err)
+	}
+	// Without FTS5 there is no relevance score: title matches rank first,
+	// then the most recently updated notes.
+	rank, pageArgs := "", args
+	if opts.byRank() {
+		titleMatch := make([]string, len(needles))
+		pageArgs = append([]any{}, args...)
+		for i, n := range needles {
+			titleMatch[i] = `notes.title LIKE ? ESCAPE '\'`
+			pageArgs = append(pageArgs, likePattern(n))
+		}
+		rank = `(` + strings.Join(titleMatch, ` OR `) + `) DESC, julianday(notes.updated_at) DESC`
+	}
+	order := opts.order(rank)
+	rows, err := db.conn.Query(`
+		SELECT path, title, substr(body, 1, 200), body`+from+order+`
+		LIMIT ? OFFSET ?
+	`, append(pageArgs, limit, max(opts.Offset, 0))...)
 	if err != nil {
-		return nil, fmt.Errorf("index: search: %w", err)
+		return nil, 0, fmt.Errorf("index: search: %w", err)
 	}
 	defer rows.Close()
 
 	var out []SearchResult
 	for rows.Next() {
 		var r SearchResult
-		if err := rows.Scan(&r.Path, &r.Title, &r.Snippet); err != nil {
-			return nil, err
+		var body string
+		if err := rows.Scan(&r.Path, &r.Title, &r.Snippet, &body); err != nil {
+			return nil, 0, err
 		}
+		r.Matches = substringMatches(substringMatches(make([]Match, 0), "title", r.Title, needles), "body", body, needles)
 		out = append(out, r)
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, db.matchSections(out)
+}
+
+// likeQuery turns query tokens into an SQL condition on the notes table
+// and its arguments, and returns the words and phrases that must or may
+// match, for ranking and highlighting. Parentheses are ignored. The
+// condition is empty when no word is required.
+func likeQuery(toks []queryToken) (string, []any, []string) {
+	// groups holds the required words, each with its OR alternatives.
+	var groups [][]string
+	var groupArgs [][]any
+	var excluded []string
+	var exArgs []any
+	var needles []string
+	or, not := false, false
+	for _, t := range toks {
+		if t.kind == tokOperator {
+			or, not = t.text == "OR", t.text == "NOT"
+			continue
+		}
+		if !t.operand() {
+			continue
+		}
+		words := t.near
+		if t.kind != tokNear {
+			words = []string{t.text}
+		}
+		cols := []string{"title", "body", "tags"}
+		if t.column != "" {
+			cols = []string{t.column}
+		}
+		var conds []string
+		var args []any
+		for _, w := range words {
+			var alts []string
+			for _, c := range cols {
+				alts = append(alts, `notes.`+c+` LIKE ? ESCAPE '\'`)
+				args = append(args, likePattern(w))
+			}
+			conds = append(conds, `(`+strings.Join(alts, ` OR `)+`)`)
+		}
+		cond := strings.Join(conds, ` AND `)
+		switch {
+		case not:
+			excluded = append(excluded, `NOT (`+cond+`)`)
+			exArgs = append(exArgs, args...)
+		case or && len(groups) > 0:
+			groups[len(groups)-1] = append(groups[len(groups)-1], cond)
+			groupArgs[len(groupArgs)-1] = append(groupArgs[len(groupArgs)-1], args...)
+			needles = append(needles, words...)
+		default:
+			groups = append(groups, []string{cond})
+			groupArgs = append(groupArgs, args)
+			needles = append(needles, words...)
+		}
+		or, not = false, false
+	}
+	if len(groups) == 0 {
+		return "", nil, nil
+	}
+	var conds []string
+	var args []any
+	for i, g := range groups {
+		conds = append(conds, `(`+strings.Join(g, ` OR `)+`)`)
+		args = append(args, groupArgs[i]...)
+	}
+	return strings.Join(append(conds, excluded...), ` AND `), append(args, exArgs...), needles
+}
+
+// likePattern returns a LIKE pattern (with \ as the escape character)
+// matching text anywhere.
+func likePattern(text string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(text) + "%"
+}
+
+// substringMatches appends to ms the occurrences of the needles in text, in
+// order and without overlaps. Like SQLite's LIKE, it ignores case for ASCII
+// letters only, which keeps the byte offsets of the folded text valid for
+// text.
+func substringMatches(ms []Match, field, text string, needles []string) []Match {
+	folded := asciiLower(text)
+	type span struct{ start, end int }
+	var spans []span
+	for _, n := range needles {
+		q := asciiLower(n)
+		if q == "" {
+			continue
+		}
+		for pos := 0; ; {
+			i := strings.Index(folded[pos:], q)
+			if i < 0 {
+				break
+			}
+			spans = append(spans, span{pos + i, pos + i + len(q)})
+			pos += i + len(q)
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	pos, runes := 0, 0
+	for _, sp := range spans {
+		if len(ms) == maxMatches {
+			break
+		}
+		if sp.start < pos {
+			continue
+		}
+		runes += utf8.RuneCountInString(text[pos:sp.start])
+		m := Match{Field: field, Start: sp.start, End: sp.end, RuneStart: runes, RuneEnd: runes + utf8.RuneCountInString(text[sp.start:sp.end])}
+		ms = append(ms, m)
+		runes, pos = m.RuneEnd, sp.end
+	}
+	return ms
+}
+
+// asciiLower lower-cases the ASCII letters of s, leaving every other byte
+// in place.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
 }
@@ -3,30 +3,43 @@
 package index
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
+// initFTS creates files_fts. A table from before the translit column was
+// added is dropped and recreated; the schema version bump makes the next
+// Sync re-index every note into it.
 func initFTS(conn *sql.DB) error {
+	if _, err := conn.Exec(`SELECT translit FROM files_fts LIMIT 0`); err != nil && strings.Contains(err.Error(), "no such column") {
+		if _, err := conn.Exec(`DROP TABLE files_fts`); err != nil {
+			return err
+		}
+	}
 	_, err := conn.Exec(`
 		CREATE VIRTUAL TABLE IF NOT EXISTS files_fts USING fts5(
 			path UNINDEXED,
 			title,
 			body,
 			tags,
+			translit,
 			tokenize = 'unicode61 remove_diacritics 2'
 		);
 	`)
 	return err
 }
 
-func ftsUpsert(tx *sql.Tx, path, title, body string, tags []string) error {
+// ftsUpsert replaces the FTS entry of path. translit holds the Latin forms
+// of the note's Cyrillic words (see WithTransliteration).
+func ftsUpsert(tx *sql.Tx, path, title, body string, tags []string, translit string) error {
 	if _, err := tx.Exec(`DELETE FROM files_fts WHERE path = ?`, path); err != nil {
 		return fmt.Errorf("index: fts delete before upsert: %w", err)
 	}
-	if _, err := tx.Exec(`INSERT INTO files_fts (path, title, body, tags) VALUES (?, ?, ?, ?)`,
-		path, title, body, strings.Join(tags, " ")); err != nil {
+	if _, err := tx.Exec(`INSERT INTO files_fts (path, title, body, tags, translit) VALUES (?, ?, ?, ?, ?)`,
+		path, title, body, strings.Join(tags, " "), translit); err != nil {
 		return fmt.Errorf("index: upsert fts: %w", err)
 	}
 	return nil
@@ -39,32 +52,134 @@ func ftsDelete(tx *sql.Tx, path string) error {
 	return nil
 }
 
-// Search performs an FTS5 full-text search and returns matching results with snippets.
-func (db *DB) Search(query string, limit int) ([]SearchResult, error) {
+// ftsClear removes every FTS entry.
+func ftsClear(tx *sql.Tx) error {
+	if _, err := tx.Exec(`DELETE FROM files_fts`); err != nil {
+		return fmt.Errorf("index: fts clear: %w", err)
+	}
+	return nil
+}
+
+// Search performs an FTS5 full-text search and returns one page of matching
+// results with snippets, and the total number of matches. The query is
+// repaired so that FTS5 accepts it, and stop words are removed (see
+// ftsQuery); a query with nothing left to search for matches nothing.
+// Folder and tag scopes are applied in the same query by joining the notes
+// table, so they do not eat into the limit.
+func (db *DB) Search(query string, opts SearchOptions) ([]SearchResult, int, error) {
+	limit := opts.Limit
 	if limit <= 0 {
 		limit = 20
 	}
-	rows, err := db.conn.Query(`
-		SELECT path,
-		       title,
-		       snippet(files_fts, 2, '<b>', '</b>', '...', 64)
+	match := db.ftsQuery(query)
+	if match == "" {
+		return nil, 0, nil
+	}
+	scope, args := opts.scope()
+	args = append([]any{match}, args...)
+	from := `
 		FROM files_fts
-		WHERE files_fts MATCH ?
-		ORDER BY rank
-		LIMIT ?
-	`, query, limit)
+		JOIN notes ON notes.path = files_fts.path
+		WHERE files_fts MATCH ?` + scope
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT count(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("index: count search: %w", err)
+	}
+	rows, err := db.conn.Query(`
+		SELECT files_fts.path,
+		       files_fts.title,
+		       snippet(files_fts, 2, '<b>', '</b>', '...', 64),
+		       highlight(files_fts, 1, char(1), char(2)),
+		       highlight(files_fts, 2, char(1), char(2))`+from+opts.order("rank")+`
+		LIMIT ? OFFSET ?
+	`, append(args, limit, max(opts.Offset, 0))...)
 	if err != nil {
-		return nil, fmt.Errorf("index: search: %w", err)
+		return nil, 0, fmt.Errorf("index: search: %w", err)
 	}
 	defer rows.Close()
 
 	var out []SearchResult
 	for rows.Next() {
 		var r SearchResult
-		if err := rows.Scan(&r.Path, &r.Title, &r.Snippet); err != nil {
-			return nil, err
+		var title, body string
+		if err := rows.Scan(&r.Path, &r.Title, &r.Snippet, &title, &body); err != nil {
+			return nil, 0, err
 		}
+		r.Matches = highlightMatches(highlightMatches(make([]Match, 0), "title", title), "body", body)
 		out = append(out, r)
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, db.matchSections(out)
+}
+
+// highlightMatches appends to ms the matches marked in text, the output of
+// highlight() with \x01 and \x02 around each match, locating them in
+// the text without the markers.
+func highlightMatches(ms []Match, field, text string) []Match {
+	pos, runes := 0, 0
+	var cur Match
+	for i := 0; i < len(text); {
+		c, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		switch c {
+		case '\x01':
+			cur = Match{Field: field, Start: pos, RuneStart: runes}
+		case '\x02':
+			cur.End, cur.RuneEnd = pos, runes
+			if len(ms) < maxMatches {
+				ms = append(ms, cur)
+			}
+		default:
+			pos += size
+			runes++
+		}
+	}
+	return ms
+}
+
+// ftsMergePages is how many pages of FTS5 segments one merge step writes.
+const ftsMergePages = 500
+
+// ftsMerge merges the FTS5 index segments that accumulate as notes are
+// edited, which keeps searches from reading many small segments. It runs
+// in steps of ftsMergePages, each a write of its own so other writes can
+// go in between, until a step finds nothing to merge or ctx is done.
+func (db *DB) ftsMerge(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var done bool
+		err := db.withTxContext(ctx, func(tx *sql.Tx) error {
+			var before, after int64
+			if err := tx.QueryRow(`SELECT total_changes()`).Scan(&before); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT INTO files_fts (files_fts, rank) VALUES ('merge', ?)`, ftsMergePages); err != nil {
+				return err
+			}
+			if err := tx.QueryRow(`SELECT total_changes()`).Scan(&after); err != nil {
+				return err
+			}
+			// FTS5 counts fewer than two changes once no merge work is left.
+			done = after-before < 2
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("index: fts merge: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// ftsCount returns the number of FTS entries, and true as FTS5 is in use.
+func (db *DB) ftsCount() (int, bool, error) {
+	var n int
+	err := db.conn.QueryRow(`SELECT count(*) FROM files_fts`).Scan(&n)
+	return n, true, err
 }
@@ -28,7 +28,7 @@ func TestFTS5_SearchWithSnippet(t *testing.T) {
 		t.Fatalf("UpsertNote: %v", err)
 	}
 
-	results, err := db.Search("powerful", 10)
+	results, _, err := db.Search("powerful", SearchOptions{Limit: 10})
 	if err != nil {
 		t.Fatalf("Search: %v", err)
 	}
@@ -49,7 +49,7 @@ func TestFTS5_DeleteRemovesFromFTS(t *testing.T) {
 	_ = db.UpsertNote(NoteRow{Path: "gone.md", Checksum: "g", Tags: []string{}, UpdatedAt: time.Now()}, "vanishing content", nil)
 	_ = db.DeleteNote("gone.md")
 
-	results, _ := db.Search("vanishing", 10)
+	results, _, _ := db.Search("vanishing", SearchOptions{Limit: 10})
 	for _, r := range results {
 		if r.Path == "gone.md" {
 			t.Error("deleted note still in FTS index")
@@ -63,12 +63,71 @@ func TestFTS5_UpsertReplacesContent(t *testing.T) {
 	_ = db.UpsertNote(NoteRow{Path: "evo.md", Title: "Old", Checksum: "1", Tags: []string{}, UpdatedAt: now}, "original text", nil)
 	_ = db.UpsertNote(NoteRow{Path: "evo.md", Title: "New", Checksum: "2", Tags: []string{}, UpdatedAt: now}, "replacement text", nil)
 
-	results, _ := db.Search("original", 10)
+	results, _, _ := db.Search("original", SearchOptions{Limit: 10})
 	if len(results) != 0 {
 		t.Error("old FTS content should be gone")
 	}
-	results, _ = db.Search("replacement", 10)
+	results, _, _ = db.Search("replacement", SearchOptions{Limit: 10})
 	if len(results) != 1 || results[0].Title != "New" {
 		t.Errorf("FTS not updated: %+v", results)
 	}
 }
+
+func TestFTSSearch_StopWords(t *testing.T) {
+	db := testDB(t)
+	WithStopWords([]string{"the", "of"})(db)
+
+	now := time.Now()
+	_ = db.UpsertNote(NoteRow{Path: "rome.md", Title: "Rome", Checksum: "1", UpdatedAt: now}, "A history of Rome.", nil)
+	_ = db.UpsertNote(NoteRow{Path: "day.md", Title: "Day", Checksum: "2", UpdatedAt: now}, "The weather of the day.", nil)
+
+	// Without stop words "the" would be required and match nothing.
+	results, _, err := db.Search("the history", SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != "rome.md" {
+		t.Errorf("results = %+v, want rome.md", results)
+	}
+}
+
+func TestFTSSearch_Synonyms(t *testing.T) {
+	db := testDB(t)
+	WithSynonyms(map[string][]string{"k8s": {"kubernetes", "container orchestration"}})(db)
+
+	now := time.Now()
+	_ = db.UpsertNote(NoteRow{Path: "kube.md", Title: "Kube", Checksum: "1", UpdatedAt: now}, "Running Kubernetes at home.", nil)
+	_ = db.UpsertNote(NoteRow{Path: "orch.md", Title: "Orch", Checksum: "2", UpdatedAt: now}, "Notes on container orchestration.", nil)
+	_ = db.UpsertNote(NoteRow{Path: "other.md", Title: "Other", Checksum: "3", UpdatedAt: now}, "Containers and orchestras.", nil)
+
+	results, _, err := db.Search("k8s", SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	got := map[string]bool{}
+	for _, r := range results {
+		got[r.Path] = true
+	}
+	if len(results) != 2 || !got["kube.md"] || !got["orch.md"] {
+		t.Errorf("results = %+v, want kube.md and orch.md", results)
+	}
+}
+
+func TestFTSSearch_Transliteration(t *testing.T) {
+	db := testDB(t)
+	WithTransliteration(true)(db)
+
+	now := time.Now()
+	_ = db.UpsertNote(NoteRow{Path: "cyr.md", Title: "Руны", Checksum: "1", UpdatedAt: now}, "Руна кеназ означает факел.", nil)
+	_ = db.UpsertNote(NoteRow{Path: "lat.md", Title: "Runes", Checksum: "2", UpdatedAt: now}, "The rune dagaz means day.", nil)
+
+	for q, want := range map[string]string{"kenaz": "cyr.md", "дагаз": "lat.md"} {
+		results, _, err := db.Search(q, SearchOptions{})
+		if err != nil {
+			t.Fatalf("Search(%q): %v", q, err)
+		}
+		if len(results) != 1 || results[0].Path != want {
+			t.Errorf("Search(%q) = %+v, want %s", q, results, want)
+		}
+	}
+}
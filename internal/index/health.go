@@ -0,0 +1,104 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphHealth lists structural problems of the link graph.
+type GraphHealth struct {
+	// Orphans are notes with no links to or from other notes.
+	Orphans []string `json:"orphans" validate:"required"`
+	// BrokenLinks are wikilink targets that resolve to no note.
+	BrokenLinks []BrokenLink `json:"broken_links" validate:"required"`
+	// DuplicateTitles are titles shared by several notes, which makes
+	// links by title ambiguous.
+	DuplicateTitles []DuplicateTitle `json:"duplicate_titles" validate:"required"`
+}
+
+// BrokenLink is a wikilink target, as written, that resolves to no note.
+type BrokenLink struct {
+	Target string `json:"target" example:"meeting notes" validate:"required"`
+	// Sources are the notes linking to Target.
+	Sources []string `json:"sources" validate:"required"`
+}
+
+// DuplicateTitle is a title shared by several notes.
+type DuplicateTitle struct {
+	Title string   `json:"title" example:"Ideas" validate:"required"`
+	Paths []string `json:"paths" validate:"required"`
+}
+
+// GraphHealth reports orphan notes, broken wikilink targets, and duplicate
+// titles. Links count as resolved at index time (see Backlinks); a link of
+// a note to itself, or to one of its own headings, does not connect it,
+// while a broken link still connects its source. Titles compare
+// case-insensitively. Every list is sorted.
+func (db *DB) GraphHealth() (*GraphHealth, error) {
+	h := &GraphHealth{Orphans: []string{}, BrokenLinks: []BrokenLink{}, DuplicateTitles: []DuplicateTitle{}}
+
+	rows, err := db.conn.Query(`
+		SELECT path FROM notes
+		WHERE path NOT IN (SELECT source FROM links WHERE resolved <> source AND target_key <> '')
+			AND path NOT IN (SELECT resolved FROM links WHERE resolved <> source)
+		ORDER BY path`)
+	if err != nil {
+		return nil, fmt.Errorf("index: health orphans: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		h.Orphans = append(h.Orphans, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lrows, err := db.conn.Query(`
+		SELECT target, source FROM links
+		WHERE resolved = '' AND target_key <> ''
+		ORDER BY target, source`)
+	if err != nil {
+		return nil, fmt.Errorf("index: health links: %w", err)
+	}
+	defer lrows.Close()
+	for lrows.Next() {
+		var target, source string
+		if err := lrows.Scan(&target, &source); err != nil {
+			return nil, err
+		}
+		if n := len(h.BrokenLinks); n > 0 && h.BrokenLinks[n-1].Target == target {
+			h.BrokenLinks[n-1].Sources = append(h.BrokenLinks[n-1].Sources, source)
+			continue
+		}
+		h.BrokenLinks = append(h.BrokenLinks, BrokenLink{Target: target, Sources: []string{source}})
+	}
+	if err := lrows.Err(); err != nil {
+		return nil, err
+	}
+
+	trows, err := db.conn.Query(`
+		SELECT title, path FROM notes
+		WHERE title <> '' AND lower(title) IN (
+			SELECT lower(title) FROM notes WHERE title <> '' GROUP BY lower(title) HAVING count(*) > 1)
+		ORDER BY lower(title), path`)
+	if err != nil {
+		return nil, fmt.Errorf("index: health titles: %w", err)
+	}
+	defer trows.Close()
+	for trows.Next() {
+		var title, p string
+		if err := trows.Scan(&title, &p); err != nil {
+			return nil, err
+		}
+		if n := len(h.DuplicateTitles); n > 0 && strings.EqualFold(h.DuplicateTitles[n-1].Title, title) {
+			h.DuplicateTitles[n-1].Paths = append(h.DuplicateTitles[n-1].Paths, p)
+			continue
+		}
+		h.DuplicateTitles = append(h.DuplicateTitles, DuplicateTitle{Title: title, Paths: []string{p}})
+	}
+	return h, trows.Err()
+}
@@ -11,14 +11,22 @@ type CursorPage struct {
 // to facilitate testing with mocks.
 type NoteIndex interface {
 	UpsertNote(n NoteRow, body string, links []string) error
+	UpsertNoteLinks(n NoteRow, body string, links []Link) error
 	DeleteNote(path string) error
 	GetChecksum(path string) (string, error)
 	GetNote(path string) (*NoteRow, error)
-	ListNotes(limit, offset int, tag, sort string) ([]NoteRow, int, error)
+	ListNotes(limit, offset int, tag, sort string, f NoteFilter) ([]NoteRow, int, error)
+	LocalGraph(center string, depth int) (*LocalGraph, error)
 	ListNotesCursor(limit int, cursor, tag, folder string) (CursorPage, error)
-	Search(query string, limit int) ([]SearchResult, error)
+	Search(query string, opts SearchOptions) ([]SearchResult, int, error)
 	Graph() ([]GraphNode, []GraphLink, error)
+	GraphHealth() (*GraphHealth, error)
+	Outline(path string) ([]SectionRef, error)
+	Stats() (*Stats, error)
 	Backlinks(target string) ([]string, error)
+	BacklinkRefs(target string) ([]BacklinkRef, error)
+	ResolveLink(target string) (string, error)
+	PathByID(id string) (string, error)
 	AllPaths() (map[string]struct{}, error)
 	AllChecksums() (map[string]string, error)
 	Close() error
@@ -1,7 +1,17 @@
 package index
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -121,7 +131,7 @@ func TestSearch_Basic(t *testing.T) {
 	db := testDB(t)
 	_ = db.UpsertNote(NoteRow{Path: "s.md", Title: "Search Me", Checksum: "1", Tags: []string{}, UpdatedAt: time.Now()}, "uniqueword appears here", nil)
 
-	results, err := db.Search("uniqueword", 10)
+	results, _, err := db.Search("uniqueword", SearchOptions{Limit: 10})
 	if err != nil {
 		t.Fatalf("Search: %v", err)
 	}
@@ -130,6 +140,210 @@ func TestSearch_Basic(t *testing.T) {
 	}
 }
 
+func TestSearch_QuerySyntax(t *testing.T) {
+	db := testDB(t)
+	for path, body := range map[string]string{
+		"a.md": "alpha beta gamma",
+		"b.md": "alpha delta",
+		"c.md": "foo-bar baz 100%",
+	} {
+		_ = db.UpsertNote(NoteRow{Path: path, Checksum: path, Tags: []string{}, UpdatedAt: time.Now()}, body, nil)
+	}
+	search := func(q string) []string {
+		t.Helper()
+		res, total, err := db.Search(q, SearchOptions{})
+		if err != nil {
+			t.Fatalf("Search(%q): %v", q, err)
+		}
+		var out []string
+		for _, r := range res {
+			out = append(out, r.Path)
+		}
+		slices.Sort(out)
+		if total != len(out) {
+			t.Errorf("Search(%q) total = %d, want %d", q, total, len(out))
+		}
+		return out
+	}
+
+	// Both the FTS5 and the LIKE backends honor these.
+	for q, want := range map[string][]string{
+		"alpha":                   {"a.md", "b.md"},
+		"gamma alpha":             {"a.md"},
+		"alpha NOT beta":          {"b.md"},
+		"beta OR delta":           {"a.md", "b.md"},
+		`"beta gamma"`:            {"a.md"},
+		`"gamma beta"`:            nil,
+		"bet*":                    {"a.md"},
+		"NEAR(alpha gamma, 3)":    {"a.md"},
+		"foo-bar":                 {"c.md"},
+		`"alpha`:                  {"a.md", "b.md"},
+		"(alpha":                  {"a.md", "b.md"},
+		"alpha) AND":              {"a.md", "b.md"},
+		"OR alpha":                {"a.md", "b.md"},
+		"(beta OR delta) alpha":   {"a.md", "b.md"},
+		"(beta OR delta) NOT foo": {"a.md", "b.md"},
+		"()":                      nil,
+		`"`:                       nil,
+	} {
+		if got := search(q); !slices.Equal(got, want) {
+			t.Errorf("Search(%q) = %v, want %v", q, got, want)
+		}
+	}
+	// These only must not fail.
+	for _, q := range []string{"100%", "_", "NEAR(", "title:", "AND", "a:b:c", `\`, "^alpha", "{alpha}", "alpha + beta"} {
+		search(q)
+	}
+}
+
+func TestSearch_Scoped(t *testing.T) {
+	db := testDB(t)
+	for _, n := range []struct {
+		path string
+		tags []string
+	}{
+		{"proj/a.md", []string{"work"}},
+		{"proj/sub/b.md", nil},
+		{"project-x/c.md", []string{"work"}},
+		{"d.md", []string{"home"}},
+	} {
+		_ = db.UpsertNote(NoteRow{Path: n.path, Title: n.path, Checksum: "1", Tags: n.tags, UpdatedAt: time.Now()}, "needle in a haystack", nil)
+	}
+
+	paths := func(opts SearchOptions) []string {
+		t.Helper()
+		results, _, err := db.Search("needle", opts)
+		if err != nil {
+			t.Fatalf("Search(%+v): %v", opts, err)
+		}
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Path)
+		}
+		slices.Sort(out)
+		return out
+	}
+	if got := paths(SearchOptions{Folder: "proj/"}); !slices.Equal(got, []string{"proj/a.md", "proj/sub/b.md"}) {
+		t.Errorf("folder scope = %v", got)
+	}
+	if got := paths(SearchOptions{Tag: "work"}); !slices.Equal(got, []string{"proj/a.md", "project-x/c.md"}) {
+		t.Errorf("tag scope = %v", got)
+	}
+	if got := paths(SearchOptions{Folder: "proj", Tag: "#work"}); !slices.Equal(got, []string{"proj/a.md"}) {
+		t.Errorf("folder and tag scope = %v", got)
+	}
+	if got := paths(SearchOptions{Folder: "proj", Limit: 1}); len(got) != 1 || !strings.HasPrefix(got[0], "proj/") {
+		t.Errorf("limited folder scope = %v", got)
+	}
+}
+
+func TestSearch_PagingAndSort(t *testing.T) {
+	db := testDB(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, title := range []string{"Cherry", "apple", "Banana", "date"} {
+		path := strconv.Itoa(i) + ".md"
+		_ = db.UpsertNote(NoteRow{Path: path, Title: title, Checksum: "1", Tags: []string{}, UpdatedAt: base.Add(time.Duration(i) * time.Hour)}, "needle", nil)
+	}
+	_ = db.UpsertNote(NoteRow{Path: "other.md", Title: "Other", Checksum: "1", Tags: []string{}, UpdatedAt: base}, "haystack", nil)
+
+	titles := func(opts SearchOptions) ([]string, int) {
+		t.Helper()
+		results, total, err := db.Search("needle", opts)
+		if err != nil {
+			t.Fatalf("Search(%+v): %v", opts, err)
+		}
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Title)
+		}
+		return out, total
+	}
+	if got, total := titles(SearchOptions{Sort: SearchSortTitle}); !slices.Equal(got, []string{"apple", "Banana", "Cherry", "date"}) || total != 4 {
+		t.Errorf("by title = %v (total %d)", got, total)
+	}
+	if got, _ := titles(SearchOptions{Sort: SearchSortUpdated}); !slices.Equal(got, []string{"date", "Banana", "apple", "Cherry"}) {
+		t.Errorf("by updated_at = %v", got)
+	}
+	if got, total := titles(SearchOptions{Sort: SearchSortTitle, Limit: 2, Offset: 1}); !slices.Equal(got, []string{"Banana", "Cherry"}) || total != 4 {
+		t.Errorf("page = %v (total %d), want [Banana Cherry] of 4", got, total)
+	}
+	if got, total := titles(SearchOptions{Limit: 2, Offset: 4}); len(got) != 0 || total != 4 {
+		t.Errorf("past the end = %v (total %d), want none of 4", got, total)
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	db := testDB(t)
+	east := time.FixedZone("east", 5*3600)
+	for _, n := range []struct {
+		path             string
+		created, updated time.Time
+	}{
+		{"old.md", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		// 2024-03-01 02:00 in east is 2024-02-29 21:00 UTC.
+		{"mid.md", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 2, 0, 0, 0, east)},
+		{"new.md", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+	} {
+		_ = db.UpsertNote(NoteRow{Path: n.path, Title: n.path, Checksum: "1", Tags: []string{}, CreatedAt: n.created, UpdatedAt: n.updated}, "needle", nil)
+	}
+
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		r    DateRange
+		want []string
+	}{
+		{DateRange{UpdatedAfter: march}, []string{"new.md"}},
+		{DateRange{UpdatedBefore: march}, []string{"mid.md", "old.md"}},
+		{DateRange{UpdatedAfter: time.Date(2024, 2, 29, 21, 0, 0, 0, time.UTC), UpdatedBefore: march}, []string{"mid.md"}},
+		{DateRange{CreatedAfter: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"mid.md", "new.md"}},
+		{DateRange{CreatedBefore: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"old.md"}},
+	} {
+		rows, total, err := db.ListNotes(0, 0, "", "path", NoteFilter{DateRange: tc.r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range rows {
+			got = append(got, r.Path)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tc.want) || total != len(tc.want) {
+			t.Errorf("ListNotes(%+v) = %v (total %d), want %v", tc.r, got, total, tc.want)
+		}
+
+		results, total, err := db.Search("needle", SearchOptions{DateRange: tc.r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = got[:0]
+		for _, r := range results {
+			got = append(got, r.Path)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tc.want) || total != len(tc.want) {
+			t.Errorf("Search(%+v) = %v (total %d), want %v", tc.r, got, total, tc.want)
+		}
+	}
+}
+
+func TestSearch_Matches(t *testing.T) {
+	db := testDB(t)
+	_ = db.UpsertNote(NoteRow{Path: "m.md", Title: "A needle", Checksum: "1", Tags: []string{}, UpdatedAt: time.Now()}, "Ü needle x Needle", nil)
+
+	results, _, err := db.Search("needle", SearchOptions{})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search = %+v, %v", results, err)
+	}
+	want := []Match{
+		{Field: "title", Start: 2, End: 8, RuneStart: 2, RuneEnd: 8},
+		{Field: "body", Start: 3, End: 9, RuneStart: 2, RuneEnd: 8},
+		{Field: "body", Start: 12, End: 18, RuneStart: 11, RuneEnd: 17},
+	}
+	if !slices.Equal(results[0].Matches, want) {
+		t.Errorf("matches = %+v, want %+v", results[0].Matches, want)
+	}
+}
+
 func TestMoveNote(t *testing.T) {
 	db := testDB(t)
 	now := time.Now()
@@ -163,7 +377,7 @@ func TestMoveNote(t *testing.T) {
 		t.Errorf("backlinks for new.md = %v, want [ref.md]", bl)
 	}
 	// FTS should find the note at new path.
-	results, _ := db.Search("old body", 10)
+	results, _, _ := db.Search("old body", SearchOptions{Limit: 10})
 	if len(results) != 1 || results[0].Path != "new.md" {
 		t.Errorf("FTS search after move = %+v, want new.md", results)
 	}
@@ -290,3 +504,715 @@ func TestNotesWithPrefix_NoMatch(t *testing.T) {
 		t.Errorf("expected 0 notes, got %d", len(notes))
 	}
 }
+
+func TestResolveLink(t *testing.T) {
+	db := testDB(t)
+	_ = db.UpsertNote(NoteRow{
+		Path: "tech/kubernetes.md", Title: "Kubernetes", Checksum: "1",
+		Aliases: []string{"k8s"}, UpdatedAt: time.Now(),
+	}, "body", nil)
+	_ = db.UpsertNote(NoteRow{
+		Path: "k8s.md", Title: "Cluster notes", Checksum: "2", UpdatedAt: time.Now(),
+	}, "body", nil)
+
+	cases := map[string]string{
+		"tech/kubernetes":    "tech/kubernetes.md",
+		"tech/Kubernetes.md": "tech/kubernetes.md",
+		"kubernetes":         "tech/kubernetes.md",
+		"cluster notes":      "k8s.md",
+		"k8s":                "k8s.md", // basename beats alias
+		"missing":            "",
+	}
+	for target, want := range cases {
+		got, err := db.ResolveLink(target)
+		if err != nil {
+			t.Fatalf("ResolveLink(%q): %v", target, err)
+		}
+		if got != want {
+			t.Errorf("ResolveLink(%q) = %q, want %q", target, got, want)
+		}
+	}
+
+	// Move keeps alias entries and re-keys the path-derived ones.
+	if err := db.MoveNote("tech/kubernetes.md", "infra/kube.md"); err != nil {
+		t.Fatalf("MoveNote: %v", err)
+	}
+	if got, _ := db.ResolveLink("kube"); got != "infra/kube.md" {
+		t.Errorf("ResolveLink(kube) after move = %q", got)
+	}
+	if got, _ := db.ResolveLink("kubernetes"); got != "infra/kube.md" {
+		t.Errorf("ResolveLink(kubernetes) after move = %q", got)
+	}
+
+	if err := db.DeleteNote("infra/kube.md"); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	if got, _ := db.ResolveLink("kubernetes"); got != "" {
+		t.Errorf("ResolveLink after delete = %q, want empty", got)
+	}
+}
+
+func TestBacklinks_Normalized(t *testing.T) {
+	db := testDB(t)
+	_ = db.UpsertNote(NoteRow{Path: "my-note.md", Title: "My Note", Checksum: "1", UpdatedAt: time.Now()}, "body", nil)
+	_ = db.UpsertNote(NoteRow{Path: "a.md", Checksum: "2", UpdatedAt: time.Now()}, "body", []string{"My Note"})
+	_ = db.UpsertNote(NoteRow{Path: "b.md", Checksum: "3", UpdatedAt: time.Now()}, "body", []string{"my_note.md"})
+
+	bl, err := db.Backlinks("my-note.md")
+	if err != nil {
+		t.Fatalf("Backlinks: %v", err)
+	}
+	if len(bl) != 2 {
+		t.Errorf("backlinks = %v, want a.md and b.md", bl)
+	}
+
+	_, links, err := db.Graph()
+	if err != nil {
+		t.Fatalf("Graph: %v", err)
+	}
+	for _, l := range links {
+		if l.Target != "my-note.md" {
+			t.Errorf("link %s -> %s, want target my-note.md", l.Source, l.Target)
+		}
+	}
+}
+
+func TestLinkResolution(t *testing.T) {
+	db := testDB(t)
+	now := time.Now()
+	_ = db.UpsertNote(NoteRow{Path: "a.md", Checksum: "1", UpdatedAt: now}, "body", []string{"b", "b.md", "b#Intro", "Project Plan", "k8s", "later"})
+	_ = db.UpsertNote(NoteRow{Path: "b.md", Checksum: "2", UpdatedAt: now}, "body", nil)
+	_ = db.UpsertNote(NoteRow{Path: "plan.md", Title: "Project Plan", Checksum: "3", UpdatedAt: now}, "body", nil)
+	_ = db.UpsertNote(NoteRow{Path: "tech/kube.md", Aliases: []string{"k8s"}, Checksum: "4", UpdatedAt: now}, "body", nil)
+
+	resolved := func() map[string]string {
+		t.Helper()
+		rows, err := db.conn.Query(`SELECT target, resolved FROM links WHERE source = 'a.md'`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		out := map[string]string{}
+		for rows.Next() {
+			var target, p string
+			if err := rows.Scan(&target, &p); err != nil {
+				t.Fatal(err)
+			}
+			out[target] = p
+		}
+		return out
+	}
+	want := map[string]string{
+		"b": "b.md", "b.md": "b.md", "b#Intro": "b.md",
+		"Project Plan": "plan.md", "k8s": "tech/kube.md", "later": "",
+	}
+	if got := resolved(); !maps.Equal(got, want) {
+		t.Errorf("resolved = %v, want %v", got, want)
+	}
+
+	_, links, err := db.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights := map[string]int{}
+	for _, l := range links {
+		weights[l.Target] = l.Weight
+	}
+	if len(weights) != 4 || weights["b.md"] != 3 || weights["later"] != 1 {
+		t.Errorf("graph links = %+v, want b and b.md merged", links)
+	}
+	for _, target := range []string{"b", "B.md", "b.md"} {
+		if bl, _ := db.Backlinks(target); !slices.Equal(bl, []string{"a.md"}) {
+			t.Errorf("Backlinks(%q) = %v, want [a.md]", target, bl)
+		}
+	}
+
+	// Later changes to other notes re-resolve the links they affect.
+	_ = db.UpsertNote(NoteRow{Path: "later.md", Checksum: "5", UpdatedAt: now}, "body", nil)
+	_ = db.UpsertNote(NoteRow{Path: "plan.md", Title: "Roadmap", Checksum: "6", UpdatedAt: now}, "body", nil)
+	_ = db.DeleteNote("b.md")
+	_ = db.UpsertNote(NoteRow{Path: "sub/b.md", Checksum: "7", UpdatedAt: now}, "body", nil)
+	if err := db.MoveNote("tech/kube.md", "infra/kubernetes.md"); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{
+		"b": "sub/b.md", "b.md": "sub/b.md", "b#Intro": "sub/b.md",
+		"Project Plan": "", "k8s": "infra/kubernetes.md", "later": "later.md",
+	}
+	if got := resolved(); !maps.Equal(got, want) {
+		t.Errorf("resolved after changes = %v, want %v", got, want)
+	}
+}
+
+func TestGraph_LinkWeights(t *testing.T) {
+	f, err := os.CreateTemp("", "kenaz-test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	db, err := Open(f.Name(), WithLinkFields([]string{"related"}))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_ = indexFile(db, "my-note.md", []byte("# My Note\n"), time.Now())
+	data := []byte("---\nrelated: [my-note]\n---\n[[my-note]] and [[my-note]], also [[My Note]]. [[other]]\n")
+	if err := indexFile(db, "a.md", data, time.Now()); err != nil {
+		t.Fatalf("indexFile: %v", err)
+	}
+
+	_, links, err := db.Graph()
+	if err != nil {
+		t.Fatalf("Graph: %v", err)
+	}
+	byTarget := map[string]GraphLink{}
+	for _, l := range links {
+		if _, dup := byTarget[l.Target]; dup {
+			t.Errorf("duplicate edge a.md -> %s", l.Target)
+		}
+		byTarget[l.Target] = l
+	}
+	// Two [[my-note]], one [[My Note]], and the frontmatter link.
+	got := byTarget["my-note.md"]
+	if got.Weight != 4 || got.Type != LinkInline || !slices.Equal(got.Types, []string{LinkInline, LinkFrontmatter}) {
+		t.Errorf("my-note edge = %+v, want weight 4, types inline+frontmatter", got)
+	}
+	if got := byTarget["other"]; got.Weight != 1 || !slices.Equal(got.Types, []string{LinkInline}) {
+		t.Errorf("other edge = %+v, want weight 1", got)
+	}
+}
+
+func TestLocalGraph(t *testing.T) {
+	db := testDB(t)
+	for path, content := range map[string]string{
+		"a.md":       "---\nid: note-a\n---\n[[b]] and [[B]]\n",
+		"b.md":       "[[sub/c]] and [[ghost]]\n",
+		"sub/c.md":   "[[d]]\n",
+		"d.md":       "end\n",
+		"e.md":       "[[note-a]]\n",
+		"island.md":  "[[island]]\n",
+		"distant.md": "[[d]]\n",
+	} {
+		if err := indexFile(db, path, []byte(content), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nodes := func(g *LocalGraph) []string {
+		var out []string
+		for _, n := range g.Nodes {
+			out = append(out, n.ID+":"+strconv.Itoa(n.Distance))
+		}
+		return out
+	}
+	g, err := db.LocalGraph("a.md", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodes(g); !slices.Equal(got, []string{"a.md:0", "b.md:1", "e.md:1"}) {
+		t.Errorf("depth 1 nodes = %v", got)
+	}
+	weights := map[string]int{}
+	for _, l := range g.Links {
+		weights[l.Source+"->"+l.Target] = l.Weight
+	}
+	if len(weights) != 2 || weights["a.md->b.md"] != 2 || weights["e.md->a.md"] != 1 {
+		t.Errorf("depth 1 links = %+v, want a.md->b.md (weight 2) and e.md->a.md", g.Links)
+	}
+
+	g, err = db.LocalGraph("a.md", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodes(g); !slices.Equal(got, []string{"a.md:0", "b.md:1", "e.md:1", "ghost:2", "sub/c.md:2"}) {
+		t.Errorf("depth 2 nodes = %v", got)
+	}
+	if len(g.Links) != 4 {
+		t.Errorf("depth 2 links = %+v, want 4", g.Links)
+	}
+
+	if g, err := db.LocalGraph("missing.md", 2); err != nil || g != nil {
+		t.Errorf("missing center = %+v, %v; want nil", g, err)
+	}
+}
+
+func TestGraphHealth(t *testing.T) {
+	db := testDB(t)
+	for path, content := range map[string]string{
+		"a.md":        "[[Project Plan]] and [[ghost]]\n",
+		"plan.md":     "---\ntitle: Project Plan\n---\n[[#Goals]]\n",
+		"self.md":     "[[self]] and [[self#top]]\n",
+		"other/c.md":  "---\ntitle: project plan\n---\n[[ghost#intro]] and [[Ghost]]\n",
+		"island.md":   "alone\n",
+		"sub/ref.md":  "---\ncitekey: knuth84\n---\n",
+		"citing.md":   "[[@knuth84]] and [[@nobody]]\n",
+		"untitled.md": "",
+	} {
+		if err := indexFile(db, path, []byte(content), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := db.GraphHealth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"island.md", "self.md", "untitled.md"}; !slices.Equal(h.Orphans, want) {
+		t.Errorf("Orphans = %v, want %v", h.Orphans, want)
+	}
+	var broken []string
+	for _, l := range h.BrokenLinks {
+		broken = append(broken, l.Target+":"+strings.Join(l.Sources, ","))
+	}
+	if want := []string{"@nobody:citing.md", "Ghost:other/c.md", "ghost:a.md", "ghost#intro:other/c.md"}; !slices.Equal(broken, want) {
+		t.Errorf("BrokenLinks = %v, want %v", broken, want)
+	}
+	if len(h.DuplicateTitles) != 1 || !slices.Equal(h.DuplicateTitles[0].Paths, []string{"other/c.md", "plan.md"}) {
+		t.Errorf("DuplicateTitles = %+v, want other/c.md and plan.md", h.DuplicateTitles)
+	}
+}
+
+func TestBacklinks_Strict(t *testing.T) {
+	f, err := os.CreateTemp("", "kenaz-test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	db, err := Open(f.Name(), WithStrictLinks(true))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	_ = db.UpsertNote(NoteRow{Path: "a.md", Checksum: "1", UpdatedAt: time.Now()}, "body", []string{"My Note", "b"})
+	_ = db.UpsertNote(NoteRow{Path: "my-note.md", Checksum: "2", UpdatedAt: time.Now()}, "body", nil)
+	_ = db.UpsertNote(NoteRow{Path: "b.md", Checksum: "3", UpdatedAt: time.Now()}, "body", nil)
+	bl, err := db.Backlinks("my-note.md")
+	if err != nil {
+		t.Fatalf("Backlinks: %v", err)
+	}
+	if len(bl) != 0 {
+		t.Errorf("strict backlinks = %v, want none", bl)
+	}
+	// The extension stays optional.
+	if bl, _ := db.Backlinks("b.md"); !slices.Equal(bl, []string{"a.md"}) {
+		t.Errorf("strict backlinks of b.md = %v, want [a.md]", bl)
+	}
+
+	// Reopening without strict matching re-resolves the stored links.
+	db.Close()
+	db, err = Open(f.Name())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if bl, _ := db.Backlinks("my-note.md"); !slices.Equal(bl, []string{"a.md"}) {
+		t.Errorf("backlinks after reopen = %v, want [a.md]", bl)
+	}
+}
+
+func TestFrontmatterLinks(t *testing.T) {
+	f, err := os.CreateTemp("", "kenaz-test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	db, err := Open(f.Name(), WithLinkFields([]string{"parent", "related"}))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	data := []byte("---\nparent: \"[[projects/kenaz]]\"\nrelated: [design]\n---\nSee [[design]].\n")
+	if err := indexFile(db, "a.md", data, time.Now()); err != nil {
+		t.Fatalf("indexFile: %v", err)
+	}
+
+	refs, err := db.BacklinkRefs("projects/kenaz.md")
+	if err != nil {
+		t.Fatalf("BacklinkRefs: %v", err)
+	}
+	if len(refs) != 1 || refs[0].Source != "a.md" || refs[0].Type != LinkFrontmatter {
+		t.Errorf("refs = %+v, want a.md (frontmatter)", refs)
+	}
+
+	// A target linked from both the frontmatter and the body keeps both
+	// types; the inline link's context comes first.
+	refs, _ = db.BacklinkRefs("design")
+	if len(refs) != 1 || refs[0].Type != LinkInline || refs[0].Snippet != "See [[design]]." {
+		t.Errorf("refs = %+v, want one inline link with its snippet", refs)
+	}
+	if !slices.Equal(refs[0].Types, []string{LinkInline, LinkFrontmatter}) {
+		t.Errorf("types = %v, want inline and frontmatter", refs[0].Types)
+	}
+	if refs, _ := db.BacklinkRefs("projects/kenaz.md"); len(refs) != 1 || !slices.Equal(refs[0].Types, []string{LinkFrontmatter}) {
+		t.Errorf("frontmatter-only refs = %+v", refs)
+	}
+	if refs[0].Line != 5 || refs[0].Column != 5 {
+		t.Errorf("ref position = %d:%d, want 5:5", refs[0].Line, refs[0].Column)
+	}
+
+	_, links, err := db.Graph()
+	if err != nil {
+		t.Fatalf("Graph: %v", err)
+	}
+	types := map[string]string{}
+	for _, l := range links {
+		types[l.Target] = l.Type
+	}
+	if types["projects/kenaz"] != LinkFrontmatter || types["design"] != LinkInline {
+		t.Errorf("graph link types = %v", types)
+	}
+	for _, l := range links {
+		if l.Target == "design" && !slices.Equal(l.Types, []string{LinkInline, LinkFrontmatter}) {
+			t.Errorf("design edge types = %v", l.Types)
+		}
+	}
+}
+
+func TestNoteIDs(t *testing.T) {
+	db := testDB(t)
+	const id = "3f1c2a4e-8b7d-4c1e-9a0f-5d6e7f8a9b0c"
+	_ = db.UpsertNote(NoteRow{
+		Path: "a.md", ID: id, Title: "A", Checksum: "1", UpdatedAt: time.Now(),
+	}, "body", nil)
+	_ = db.UpsertNote(NoteRow{
+		Path: "b.md", Title: "B", Checksum: "2", UpdatedAt: time.Now(),
+	}, "see [["+id+"]]", []string{id})
+
+	if got, _ := db.PathByID(id); got != "a.md" {
+		t.Errorf("PathByID = %q, want a.md", got)
+	}
+	if err := db.MoveNote("a.md", "archive/a.md"); err != nil {
+		t.Fatalf("MoveNote: %v", err)
+	}
+	if got, _ := db.PathByID(id); got != "archive/a.md" {
+		t.Errorf("PathByID after move = %q", got)
+	}
+	if got, _ := db.ResolveLink(id); got != "archive/a.md" {
+		t.Errorf("ResolveLink(id) after move = %q", got)
+	}
+	bl, err := db.Backlinks("archive/a.md")
+	if err != nil {
+		t.Fatalf("Backlinks: %v", err)
+	}
+	if len(bl) != 1 || bl[0] != "b.md" {
+		t.Errorf("backlinks by id = %v, want [b.md]", bl)
+	}
+	n, err := db.GetNote("archive/a.md")
+	if err != nil || n == nil || n.ID != id {
+		t.Errorf("GetNote ID = %+v, err = %v", n, err)
+	}
+	if got, _ := db.PathByID("unknown"); got != "" {
+		t.Errorf("PathByID(unknown) = %q, want empty", got)
+	}
+}
+
+func TestCreatedPerDay(t *testing.T) {
+	db := testDB(t)
+	feb := func(day int) time.Time { return time.Date(2025, 2, day, 9, 0, 0, 0, time.UTC) }
+	for _, n := range []NoteRow{
+		{Path: "a.md", CreatedAt: feb(1)},
+		{Path: "b.md", CreatedAt: feb(1)},
+		{Path: "c.md", UpdatedAt: feb(3)},
+		{Path: "d.md", CreatedAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// The file's birth time is preferred to its modification time.
+		{Path: "e.md", FileCreatedAt: feb(5), UpdatedAt: feb(20)},
+	} {
+		if err := db.UpsertNote(n, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Re-indexing without a frontmatter date keeps the stored one.
+	if err := db.UpsertNote(NoteRow{Path: "c.md", UpdatedAt: feb(10)}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.CreatedPerDay("2025-02-01", "2025-02-28")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got["2025-02-01"] != 2 || got["2025-02-03"] != 1 || got["2025-02-05"] != 1 {
+		t.Errorf("CreatedPerDay = %v", got)
+	}
+}
+
+func TestOpen_TranslitToggleResetsChecksums(t *testing.T) {
+	f, err := os.CreateTemp("", "kenaz-test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	open := func(translit bool) *DB {
+		t.Helper()
+		db, err := Open(f.Name(), WithTransliteration(translit))
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		return db
+	}
+	db := open(false)
+	_ = db.UpsertNote(NoteRow{Path: "a.md", Checksum: "abc", UpdatedAt: time.Now()}, "body", nil)
+	db.Close()
+
+	db = open(false)
+	if cs, _ := db.GetChecksum("a.md"); cs != "abc" {
+		t.Errorf("unchanged setting reset checksum to %q", cs)
+	}
+	db.Close()
+
+	db = open(true)
+	defer db.Close()
+	if cs, _ := db.GetChecksum("a.md"); cs != "" {
+		t.Errorf("toggled setting kept checksum %q", cs)
+	}
+}
+
+func TestNotesUpdatedSince(t *testing.T) {
+	db := testDB(t)
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	plus3 := time.FixedZone("UTC+3", 3*3600)
+	for i, n := range []struct {
+		path string
+		at   time.Time
+	}{
+		{"old.md", base.Add(-time.Hour)},
+		{"new.md", base.Add(time.Hour)},
+		// 14:30+03:00 is 11:30 UTC, before base although it sorts after it as text.
+		{"zoned-old.md", base.Add(-30 * time.Minute).In(plus3)},
+		{"zoned-new.md", base.Add(2 * time.Hour).In(plus3)},
+	} {
+		_ = db.UpsertNote(NoteRow{Path: n.path, Checksum: strconv.Itoa(i), UpdatedAt: n.at}, "", nil)
+	}
+
+	notes, err := db.NotesUpdatedSince(base, 10)
+	if err != nil {
+		t.Fatalf("NotesUpdatedSince: %v", err)
+	}
+	var got []string
+	for _, n := range notes {
+		got = append(got, n.Path)
+	}
+	if want := []string{"zoned-new.md", "new.md"}; !slices.Equal(got, want) {
+		t.Errorf("notes = %v, want %v", got, want)
+	}
+	if notes, _ := db.NotesUpdatedSince(base, 1); len(notes) != 1 {
+		t.Errorf("limit ignored: %d notes", len(notes))
+	}
+}
+
+func TestReindex(t *testing.T) {
+	vaultDir, store, db := watcherTestEnv(t)
+	for path, content := range map[string]string{
+		"a.md":      "# A\n\nSee [[b]].\n",
+		"b.md":      "# B\n\nQ: Question?\nA: Answer\n",
+		"sub/c.md":  "# C\n\nLinks [[a]].\n",
+		"notes.txt": "not a note",
+	} {
+		full := filepath.Join(vaultDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	Sync(db, store, logger)
+
+	due, _, err := db.DueCards("2026-10-16", 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("due = %+v, %v", due, err)
+	}
+	c := due[0]
+	c.Due, c.Reviews = "2026-10-20", 1
+	if err := db.RecordCardReview(&c, 4, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// Damage the index: a stale note, a lost link, a wrong checksum.
+	_ = db.UpsertNote(NoteRow{Path: "gone.md", Checksum: "x", Tags: []string{}, UpdatedAt: time.Now()}, "body", nil)
+	if _, err := db.conn.Exec(`DELETE FROM links WHERE source = 'a.md'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`UPDATE notes SET checksum = 'bad' WHERE path = 'sub/c.md'`); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []ReindexProgress
+	p, err := Reindex(context.Background(), db, store, logger, func(p ReindexProgress) { reports = append(reports, p) })
+	if err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	if p.Total != 3 || p.Processed != 3 || len(p.Errors) != 0 {
+		t.Errorf("progress = %+v, want 3 of 3 without errors", p)
+	}
+	if len(reports) != 2 || reports[0].Processed != 0 || reports[1].Processed != 3 {
+		t.Errorf("reports = %+v, want 0 then 3 processed", reports)
+	}
+
+	if cs, _ := db.GetChecksum("gone.md"); cs != "" {
+		t.Errorf("stale note kept with checksum %q", cs)
+	}
+	if cs, _ := db.GetChecksum("sub/c.md"); cs == "bad" || cs == "" {
+		t.Errorf("checksum of sub/c.md = %q, want rebuilt", cs)
+	}
+	if bl, _ := db.Backlinks("b.md"); len(bl) != 1 || bl[0] != "a.md" {
+		t.Errorf("Backlinks(b.md) = %v, want [a.md]", bl)
+	}
+	if bl, _ := db.Backlinks("a.md"); len(bl) != 1 || bl[0] != "sub/c.md" {
+		t.Errorf("Backlinks(a.md) = %v, want [sub/c.md]", bl)
+	}
+	if due, _, _ = db.DueCards("2026-10-16", 10); len(due) != 0 {
+		t.Errorf("due after reindex = %+v, want the review schedule kept", due)
+	}
+	if res, _, err := db.Search("Question", SearchOptions{Limit: 10}); err != nil || len(res) != 1 {
+		t.Errorf("Search after reindex = %+v, %v", res, err)
+	}
+}
+
+func TestWritingActivity(t *testing.T) {
+	vaultDir, store, db := watcherTestEnv(t)
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(vaultDir, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	today := func() WritingDay {
+		t.Helper()
+		days, err := db.WritingActivity("", time.Now().Format(time.DateOnly))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(days) == 0 {
+			return WritingDay{}
+		}
+		return days[len(days)-1]
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	write("a.md", "one two three")
+	write("b.md", "four five")
+
+	// Building the index and rebuilding it are not writing.
+	Sync(db, store, logger)
+	if _, err := Reindex(context.Background(), db, store, logger, nil); err != nil {
+		t.Fatal(err)
+	}
+	if d := today(); d.Edits != 0 {
+		t.Fatalf("after initial sync and reindex = %+v", d)
+	}
+
+	// Changes made outside the service count, once.
+	write("a.md", "three one four five six")
+	if err := os.Remove(filepath.Join(vaultDir, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	Sync(db, store, logger)
+	if err := indexFile(db, "a.md", []byte("three one four five six"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// a.md: +3 -1; b.md: -2.
+	if d := today(); d.Edits != 2 || d.WordsAdded != 3 || d.WordsRemoved != 3 {
+		t.Errorf("today = %+v", d)
+	}
+}
+
+func TestStats(t *testing.T) {
+	vaultDir, store, db := watcherTestEnv(t)
+	st, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Notes != 0 || st.SyncedAt != nil || st.SizeBytes <= 0 || len(st.Folders) != 0 {
+		t.Errorf("empty stats = %+v", st)
+	}
+
+	for path, content := range map[string]string{
+		"a.md":       "[[b]] and [[c]]",
+		"b.md":       "# B",
+		"p/c.md":     "[[a]]",
+		"p/q/d.md":   "# D",
+		"p/q/e.md":   "# E",
+		"p/q/f.txt":  "not a note",
+		"pq/last.md": "# Last",
+	} {
+		full := filepath.Join(vaultDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := time.Now().Add(-time.Second)
+	if err := Sync(db, store, slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatal(err)
+	}
+
+	if st, err = db.Stats(); err != nil {
+		t.Fatal(err)
+	}
+	if st.Notes != 6 || st.Links != 3 {
+		t.Errorf("notes, links = %d, %d, want 6, 3", st.Notes, st.Links)
+	}
+	if st.FTS && st.FTSRows != 6 || !st.FTS && st.FTSRows != 0 {
+		t.Errorf("fts = %v with %d rows", st.FTS, st.FTSRows)
+	}
+	if st.SyncedAt == nil || st.SyncedAt.Before(before) {
+		t.Errorf("synced at = %v, want after %v", st.SyncedAt, before)
+	}
+	want := []FolderCount{{"", 2}, {"p", 1}, {"p/q", 2}, {"pq", 1}}
+	if !slices.Equal(st.Folders, want) {
+		t.Errorf("folders = %+v, want %+v", st.Folders, want)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	db := testDB(t)
+	body := strings.Repeat("lorem ipsum dolor sit amet ", 400)
+	for i := range 100 {
+		if err := indexFile(db, fmt.Sprintf("n%03d.md", i), []byte(fmt.Sprintf("# Note %d\n%s", i, body)), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range 90 {
+		if err := db.DeleteNote(fmt.Sprintf("n%03d.md", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := db.Optimize(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Vacuumed || r.SizeBefore <= 0 || r.SizeAfter < r.SizeBefore {
+		t.Errorf("optimize = %+v, want the size kept without vacuum", r)
+	}
+	if r, err = db.Optimize(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Vacuumed || r.SizeAfter >= r.SizeBefore {
+		t.Errorf("optimize with vacuum = %+v, want a smaller database", r)
+	}
+	st, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.OptimizedAt == nil || st.Notes != 10 {
+		t.Errorf("stats after optimize = %+v", st)
+	}
+	if res, _, err := db.Search("lorem", SearchOptions{}); err != nil || len(res) != 10 {
+		t.Errorf("search after optimize = %d results, %v", len(res), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.Optimize(ctx, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("optimize with cancelled ctx = %v, want context.Canceled", err)
+	}
+}
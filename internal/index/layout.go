@@ -0,0 +1,63 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/starford/kenaz/internal/graph"
+)
+
+// metaGraphLayout is the meta key holding the signature of the graph the
+// cached layout was computed for.
+const metaGraphLayout = "graph_layout_signature"
+
+// GraphLayout returns the cached node positions and the signature of the
+// graph they were computed for ("" when nothing is cached).
+func (db *DB) GraphLayout() (map[string]graph.Point, string, error) {
+	var sig string
+	err := db.conn.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaGraphLayout).Scan(&sig)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, "", fmt.Errorf("index: graph layout signature: %w", err)
+	}
+	rows, err := db.conn.Query(`SELECT node, x, y FROM graph_layout`)
+	if err != nil {
+		return nil, "", fmt.Errorf("index: graph layout: %w", err)
+	}
+	defer rows.Close()
+	pos := make(map[string]graph.Point)
+	for rows.Next() {
+		var node string
+		var p graph.Point
+		if err := rows.Scan(&node, &p.X, &p.Y); err != nil {
+			return nil, "", err
+		}
+		pos[node] = p
+	}
+	return pos, sig, rows.Err()
+}
+
+// SaveGraphLayout replaces the cached layout with pos, computed for the
+// graph with the given signature.
+func (db *DB) SaveGraphLayout(sig string, pos map[string]graph.Point) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM graph_layout`); err != nil {
+			return fmt.Errorf("index: clear graph layout: %w", err)
+		}
+		stmt, err := tx.Prepare(`INSERT INTO graph_layout (node, x, y) VALUES (?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("index: prepare layout insert: %w", err)
+		}
+		defer stmt.Close()
+		for node, p := range pos {
+			if _, err := stmt.Exec(node, p.X, p.Y); err != nil {
+				return fmt.Errorf("index: insert layout: %w", err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value`, metaGraphLayout, sig); err != nil {
+			return fmt.Errorf("index: save layout signature: %w", err)
+		}
+		return nil
+	})
+}
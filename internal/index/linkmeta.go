@@ -0,0 +1,68 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LinkMetadata is the cached preview of an external web page.
+type LinkMetadata struct {
+	URL         string `json:"url" example:"https://go.dev/blog" validate:"required"`
+	Title       string `json:"title,omitempty" example:"The Go Blog"`
+	Description string `json:"description,omitempty"`
+	// Image and Favicon are absolute URLs.
+	Image   string `json:"image,omitempty"`
+	Favicon string `json:"favicon,omitempty" example:"https://go.dev/favicon.ico"`
+	// Error is why the last fetch failed; the other fields are then empty.
+	Error string `json:"error,omitempty"`
+	// FetchedAt is nil for a page never fetched.
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+}
+
+// LinkMetadata returns the cached previews of urls, keyed by URL. URLs never
+// fetched are missing from the map.
+func (db *DB) LinkMetadata(urls []string) (map[string]LinkMetadata, error) {
+	out := make(map[string]LinkMetadata, len(urls))
+	if len(urls) == 0 {
+		return out, nil
+	}
+	args := make([]any, len(urls))
+	for i, u := range urls {
+		args[i] = u
+	}
+	rows, err := db.conn.Query(`
+		SELECT url, title, description, image, favicon, error, fetched_at
+		FROM link_metadata WHERE url IN (?`+strings.Repeat(",?", len(urls)-1)+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("index: link metadata: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	for rows.Next() {
+		var m LinkMetadata
+		var fetched time.Time
+		if err := rows.Scan(&m.URL, &m.Title, &m.Description, &m.Image, &m.Favicon, &m.Error, &fetched); err != nil {
+			return nil, fmt.Errorf("index: scan link metadata: %w", err)
+		}
+		m.FetchedAt = &fetched
+		out[m.URL] = m
+	}
+	return out, rows.Err()
+}
+
+// SaveLinkMetadata stores or replaces the cached preview of m.URL, fetched
+// at m.FetchedAt (now when nil).
+func (db *DB) SaveLinkMetadata(m LinkMetadata) error {
+	fetched := time.Now()
+	if m.FetchedAt != nil {
+		fetched = *m.FetchedAt
+	}
+	if err := db.exec(`
+		INSERT OR REPLACE INTO link_metadata (url, title, description, image, favicon, error, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, m.URL, m.Title, m.Description, m.Image, m.Favicon, m.Error, fetched); err != nil {
+		return fmt.Errorf("index: save link metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package index
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// LocalGraph is the neighborhood of a note: the nodes within Depth links of
+// Center, following links in either direction, and the links among them.
+type LocalGraph struct {
+	Center string           `json:"center"`
+	Depth  int              `json:"depth"`
+	Nodes  []LocalGraphNode `json:"nodes"`
+	Links  []GraphLink      `json:"links"`
+}
+
+// LocalGraphNode is a node of a LocalGraph.
+type LocalGraphNode struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	// Distance is the number of links between the node and the center.
+	Distance int `json:"distance"`
+}
+
+// graphTarget is the graph node a link l points to, as in Graph: the note
+// its target resolved to, or the target itself when unresolved.
+const graphTarget = `COALESCE(NULLIF(l.resolved, ''), l.target)`
+
+// neighborhoodCTE defines near(node, distance): the nodes within ?2 links
+// of node ?1 in either direction. Each step looks up only the links from
+// and to the nodes reached so far, by indexed source, resolved path, and
+// target, so the cost grows with the neighborhood rather than the vault.
+const neighborhoodCTE = `
+hood(node, distance) AS (
+	SELECT ?1, 0
+	UNION
+	SELECT ` + graphTarget + `, h.distance + 1
+	FROM hood h JOIN links l ON l.source = h.node
+	WHERE h.distance < ?2
+	UNION
+	SELECT l.source, h.distance + 1
+	FROM hood h JOIN links l ON l.resolved = h.node OR (l.resolved = '' AND l.target = h.node)
+	WHERE h.distance < ?2
+),
+near(node, distance) AS (
+	SELECT node, min(distance) FROM hood GROUP BY node
+)`
+
+// LocalGraph returns the nodes within depth links of the note at center,
+// following links in either direction, and the links among them. The
+// neighborhood is found in SQL with a recursive query, so only that part
+// of the graph is loaded. Nodes are ordered by distance, then ID; parallel
+// links are merged as in Graph. It returns nil when center is not indexed.
+func (db *DB) LocalGraph(center string, depth int) (*LocalGraph, error) {
+	var exists int
+	if err := db.conn.QueryRow(`SELECT count(*) FROM notes WHERE path = ?`, center).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("index: local graph center: %w", err)
+	}
+	if exists == 0 {
+		return nil, nil
+	}
+	args := []any{center, depth}
+
+	rows, err := db.conn.Query(`WITH RECURSIVE`+neighborhoodCTE+`
+		SELECT near.node, COALESCE(notes.title, ''), near.distance
+		FROM near LEFT JOIN notes ON notes.path = near.node
+		ORDER BY near.distance, near.node`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("index: local graph nodes: %w", err)
+	}
+	defer rows.Close()
+	g := &LocalGraph{Center: center, Depth: depth, Nodes: []LocalGraphNode{}, Links: []GraphLink{}}
+	for rows.Next() {
+		var n LocalGraphNode
+		if err := rows.Scan(&n.ID, &n.Title, &n.Distance); err != nil {
+			return nil, err
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lrows, err := db.conn.Query(`WITH RECURSIVE`+neighborhoodCTE+`,
+		edges(source, target, type, types, count, seq) AS (
+			SELECT l.source, `+graphTarget+`, l.type, l.types, l.count, l.rowid
+			FROM links l WHERE l.source IN (SELECT node FROM near)
+		)
+		SELECT source, target, type, types, count FROM edges
+		WHERE target IN (SELECT node FROM near)
+		ORDER BY seq`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("index: local graph links: %w", err)
+	}
+	defer lrows.Close()
+	edges := make(map[[2]string]int)
+	for lrows.Next() {
+		var l GraphLink
+		var types string
+		if err := lrows.Scan(&l.Source, &l.Target, &l.Type, &types, &l.Weight); err != nil {
+			return nil, err
+		}
+		l.Types = []string{l.Type}
+		if types != "" {
+			l.Types = strings.Split(types, ",")
+		}
+		edge := [2]string{l.Source, l.Target}
+		if i, ok := edges[edge]; ok {
+			g.Links[i].Weight += l.Weight
+			for _, t := range l.Types {
+				if !slices.Contains(g.Links[i].Types, t) {
+					g.Links[i].Types = append(g.Links[i].Types, t)
+				}
+			}
+			continue
+		}
+		edges[edge] = len(g.Links)
+		g.Links = append(g.Links, l)
+	}
+	return g, lrows.Err()
+}
@@ -0,0 +1,137 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// metaOptimizedAt records when Optimize last finished.
+const metaOptimizedAt = "optimized_at"
+
+// OptimizeResult reports what Optimize did.
+type OptimizeResult struct {
+	// SizeBefore and SizeAfter are the database size in bytes, free pages
+	// included; only VACUUM returns free pages to the file system.
+	SizeBefore int64 `json:"size_before" example:"10485760" validate:"required"`
+	SizeAfter  int64 `json:"size_after" example:"8388608" validate:"required"`
+	Vacuumed   bool  `json:"vacuumed" validate:"required"`
+	// DurationMS is how long the maintenance took, in milliseconds.
+	DurationMS int64     `json:"duration_ms" example:"850" validate:"required"`
+	FinishedAt time.Time `json:"finished_at" validate:"required"`
+}
+
+// Optimize runs the index maintenance a long-running instance needs: FTS5
+// segment merges (see ftsMerge), PRAGMA optimize to refresh the query
+// planner's statistics, and, when vacuum is set, VACUUM to rebuild the
+// file without free pages, followed by a WAL checkpoint that truncates the
+// log. Each step runs as one write, queued behind and ahead of other
+// writes, and Optimize stops once ctx is done. The time it finishes is
+// reported by Stats.
+func (db *DB) Optimize(ctx context.Context, vacuum bool) (*OptimizeResult, error) {
+	start := time.Now()
+	r := &OptimizeResult{}
+	var err error
+	if r.SizeBefore, err = db.size(); err != nil {
+		return nil, err
+	}
+	if err := db.ftsMerge(ctx); err != nil {
+		return nil, err
+	}
+	steps := []string{`PRAGMA optimize`}
+	if vacuum {
+		steps = append(steps, `VACUUM`, `PRAGMA wal_checkpoint(TRUNCATE)`)
+	}
+	for _, q := range steps {
+		if err := db.retryBusy(ctx, func() error {
+			_, err := db.conn.ExecContext(ctx, q)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("index: %s: %w", q, err)
+		}
+	}
+	r.Vacuumed = vacuum
+	if r.SizeAfter, err = db.size(); err != nil {
+		return nil, err
+	}
+	r.FinishedAt = time.Now().UTC()
+	r.DurationMS = time.Since(start).Milliseconds()
+	err = db.exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, metaOptimizedAt, strconv.FormatInt(r.FinishedAt.Unix(), 10))
+	if err != nil {
+		return nil, fmt.Errorf("index: save optimize time: %w", err)
+	}
+	return r, nil
+}
+
+// size returns the size of the database in bytes, free pages included.
+func (db *DB) size() (int64, error) {
+	var n int64
+	if err := db.conn.QueryRow(`SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("index: size: %w", err)
+	}
+	return n, nil
+}
+
+// LastWrite returns when a write to the index last succeeded, or the zero
+// time if none has since it was opened.
+func (db *DB) LastWrite() time.Time {
+	if ns := db.lastWrite.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// Maintain runs Optimize every interval until ctx is done. Each run first
+// waits until the index has seen no writes for idle, so maintenance does
+// not compete with active editing. VACUUM is included when vacuum is set.
+func Maintain(ctx context.Context, db *DB, interval, idle time.Duration, vacuum bool, logger *slog.Logger) error {
+	logger.Info("index maintenance: started",
+		slog.String("interval", interval.String()),
+		slog.String("idle", idle.String()),
+		slog.Bool("vacuum", vacuum))
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		for {
+			quiet := time.Since(db.LastWrite())
+			if quiet >= idle {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(idle - quiet):
+			}
+		}
+		r, err := db.Optimize(ctx, vacuum)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("index maintenance: failed", slog.String("error", err.Error()))
+			}
+			continue
+		}
+		logger.Info("index maintenance: done",
+			slog.Int64("size_before", r.SizeBefore),
+			slog.Int64("size_after", r.SizeAfter),
+			slog.Int64("duration_ms", r.DurationMS))
+	}
+}
+
+// Snapshot writes a consistent copy of the database to path, which must
+// not exist yet, using VACUUM INTO. It only reads, so it does not take
+// the write queue; the copy holds the last committed state.
+func (db *DB) Snapshot(ctx context.Context, path string) error {
+	if _, err := db.conn.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("index: snapshot: %w", err)
+	}
+	return nil
+}
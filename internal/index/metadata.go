@@ -0,0 +1,143 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// metaExtractors is the meta key recording which extractors built the
+// note_metadata table.
+const metaExtractors = "extractors"
+
+// MetadataValue is one value found by an extractor and the notes it
+// occurs in.
+type MetadataValue struct {
+	Value string   `json:"value" validate:"required"`
+	Paths []string `json:"paths" validate:"required"`
+}
+
+// syncExtractors clears stored checksums when the set of extractors changed
+// since note_metadata was built, so the next Sync re-indexes every note.
+func (db *DB) syncExtractors() error {
+	names := make([]string, len(db.extractors))
+	for i, e := range db.extractors {
+		names[i] = e.Name()
+	}
+	slices.Sort(names)
+	want := strings.Join(names, ",")
+	var have string
+	err := db.conn.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaExtractors).Scan(&have)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("index: read extractors setting: %w", err)
+	}
+	if have == want {
+		return nil
+	}
+	if _, err := db.conn.Exec(`UPDATE notes SET checksum = ''`); err != nil {
+		return fmt.Errorf("index: reset checksums: %w", err)
+	}
+	_, err = db.conn.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, metaExtractors, want)
+	return err
+}
+
+// replaceMetadata replaces the extractor output stored for path within tx.
+func replaceMetadata(tx *sql.Tx, path string, md map[string][]string) error {
+	if _, err := tx.Exec(`DELETE FROM note_metadata WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete old metadata: %w", err)
+	}
+	if len(md) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO note_metadata (path, key, value) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("index: prepare metadata insert: %w", err)
+	}
+	defer stmt.Close()
+	for key, vals := range md {
+		for _, v := range vals {
+			if _, err := stmt.Exec(path, key, v); err != nil {
+				return fmt.Errorf("index: insert metadata: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// NoteMetadata returns the extractor output stored for the note at path,
+// keyed by extractor name. Values are sorted.
+func (db *DB) NoteMetadata(path string) (map[string][]string, error) {
+	rows, err := db.conn.Query(`SELECT key, value FROM note_metadata WHERE path = ? ORDER BY key, value`, path)
+	if err != nil {
+		return nil, fmt.Errorf("index: note metadata: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	out := make(map[string][]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("index: scan metadata: %w", err)
+		}
+		out[key] = append(out[key], value)
+	}
+	return out, rows.Err()
+}
+
+// MetadataValues returns the values stored under key with the notes each
+// occurs in, most widespread first. A non-empty value restricts the result
+// to that value.
+func (db *DB) MetadataValues(key, value string) ([]MetadataValue, error) {
+	q := `SELECT value, path FROM note_metadata WHERE key = ?`
+	args := []any{key}
+	if value != "" {
+		q += ` AND value = ?`
+		args = append(args, value)
+	}
+	rows, err := db.conn.Query(q+` ORDER BY value, path`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("index: metadata values: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	out := []MetadataValue{}
+	for rows.Next() {
+		var v, p string
+		if err := rows.Scan(&v, &p); err != nil {
+			return nil, fmt.Errorf("index: scan metadata: %w", err)
+		}
+		if n := len(out); n > 0 && out[n-1].Value == v {
+			out[n-1].Paths = append(out[n-1].Paths, p)
+			continue
+		}
+		out = append(out, MetadataValue{Value: v, Paths: []string{p}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return len(out[i].Paths) > len(out[j].Paths) })
+	return out, nil
+}
+
+// MetadataKeys returns the distinct extractor names with stored values.
+func (db *DB) MetadataKeys() ([]string, error) {
+	rows, err := db.conn.Query(`SELECT DISTINCT key FROM note_metadata ORDER BY key`)
+	if err != nil {
+		return nil, fmt.Errorf("index: metadata keys: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	out := []string{}
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("index: scan metadata key: %w", err)
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,73 @@
+package index
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/parser"
+)
+
+func TestNoteMetadata(t *testing.T) {
+	f, err := os.CreateTemp("", "kenaz-test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	mentions, err := parser.LookupExtractor("mentions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(f.Name(), WithExtractors([]parser.Extractor{mentions}))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := indexFile(db, "a.md", []byte("Ping @alice and @bob.\n"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexFile(db, "b.md", []byte("Ask @alice.\n"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	md, err := db.NoteMetadata("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(md["mentions"], []string{"alice", "bob"}) {
+		t.Errorf("a.md metadata = %v", md)
+	}
+	vals, err := db.MetadataValues("mentions", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 || vals[0].Value != "alice" || !slices.Equal(vals[0].Paths, []string{"a.md", "b.md"}) {
+		t.Errorf("values = %+v, want alice (2 notes) first", vals)
+	}
+	keys, _ := db.MetadataKeys()
+	if !slices.Equal(keys, []string{"mentions"}) {
+		t.Errorf("keys = %v", keys)
+	}
+
+	// Re-indexing replaces values; moves and deletes carry them along.
+	if err := indexFile(db, "a.md", []byte("Ping @carol.\n"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MoveNote("a.md", "c.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteNote("b.md"); err != nil {
+		t.Fatal(err)
+	}
+	vals, _ = db.MetadataValues("mentions", "")
+	if len(vals) != 1 || vals[0].Value != "carol" || !slices.Equal(vals[0].Paths, []string{"c.md"}) {
+		t.Errorf("values after changes = %+v", vals)
+	}
+	vals, _ = db.MetadataValues("mentions", "alice")
+	if len(vals) != 0 {
+		t.Errorf("alice should be gone: %+v", vals)
+	}
+}
@@ -0,0 +1,28 @@
+package index
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/starford/kenaz/internal/storage"
+)
+
+// Poll is the watcher for providers without change notifications (e.g.
+// WebDAV): every interval it lists the vault, indexes new and changed files,
+// and drops deleted ones, calling cb (if non-nil) for each change, until ctx
+// is cancelled.
+func Poll(ctx context.Context, db *DB, store storage.Provider, interval time.Duration, logger *slog.Logger, cb EventCallback) error {
+	logger.Info("watcher: polling", slog.String("interval", interval.String()))
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("watcher: stopped")
+			return nil
+		case <-t.C:
+			reconcile(db, store, logger, cb)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package index
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Property is one frontmatter key and value. As a filter, an empty Value
+// matches any note that sets Key.
+type Property struct {
+	Key   string
+	Value string
+}
+
+// NoteProperties returns the queryable properties of a note's frontmatter:
+// every top-level field with a scalar value, or a list of scalars, one
+// entry per list item. Keys are lower-cased; nested maps are skipped.
+// Dates are written as 2006-01-02, or RFC 3339 when they carry a time.
+func NoteProperties(fm map[string]any) []Property {
+	var out []Property
+	for key, v := range fm {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		items, ok := v.([]any)
+		if !ok {
+			items = []any{v}
+		}
+		for _, item := range items {
+			if s, ok := propertyValue(item); ok {
+				out = append(out, Property{Key: key, Value: s})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Key != out[j].Key {
+			return out[i].Key < out[j].Key
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+// propertyValue formats a scalar frontmatter value, reporting false for
+// maps, lists, nulls, and empty strings.
+func propertyValue(v any) (string, bool) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case bool:
+		s = strconv.FormatBool(v)
+	case int:
+		s = strconv.Itoa(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if h, m, sec := v.Clock(); h == 0 && m == 0 && sec == 0 && v.Nanosecond() == 0 {
+			s = v.Format(time.DateOnly)
+		} else {
+			s = v.Format(time.RFC3339)
+		}
+	default:
+		return "", false
+	}
+	s = strings.TrimSpace(s)
+	return s, s != ""
+}
+
+// replaceProperties replaces the properties stored for path within tx.
+func replaceProperties(tx *sql.Tx, path string, props []Property) error {
+	if _, err := tx.Exec(`DELETE FROM properties WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete old properties: %w", err)
+	}
+	for _, p := range props {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO properties (path, key, value) VALUES (?, ?, ?)`, path, p.Key, p.Value); err != nil {
+			return fmt.Errorf("index: insert property: %w", err)
+		}
+	}
+	return nil
+}
+
+// propertyClauses returns SQL conditions on notes.path matching every key
+// of filters, and their arguments. Filters sharing a key match notes with
+// any of their values; values compare case-insensitively.
+func propertyClauses(filters []Property) ([]string, []any) {
+	byKey := map[string][]string{}
+	var keys []string
+	for _, f := range filters {
+		key := strings.ToLower(strings.TrimSpace(f.Key))
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+			byKey[key] = nil
+		}
+		byKey[key] = append(byKey[key], f.Value)
+	}
+
+	var clauses []string
+	var args []any
+	for _, key := range keys {
+		q := `notes.path IN (SELECT path FROM properties WHERE key = ?`
+		args = append(args, key)
+		var vals []string
+		for _, v := range byKey[key] {
+			if v == "" {
+				// Presence matches regardless of the other values.
+				vals = nil
+				break
+			}
+			vals = append(vals, v)
+		}
+		if len(vals) > 0 {
+			q += ` AND value IN (?` + strings.Repeat(`, ?`, len(vals)-1) + `)`
+			for _, v := range vals {
+				args = append(args, v)
+			}
+		}
+		clauses = append(clauses, q+`)`)
+	}
+	return clauses, args
+}
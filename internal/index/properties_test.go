@@ -0,0 +1,87 @@
+package index
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNoteProperties(t *testing.T) {
+	fm := map[string]any{
+		"Status":   "in-progress",
+		"due":      time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		"priority": 2,
+		"draft":    true,
+		"owners":   []any{"alice", "bob", map[string]any{"x": 1}},
+		"nested":   map[string]any{"a": "b"},
+		"empty":    "",
+		"none":     nil,
+	}
+	want := []Property{
+		{"draft", "true"},
+		{"due", "2025-03-01"},
+		{"owners", "alice"},
+		{"owners", "bob"},
+		{"priority", "2"},
+		{"status", "in-progress"},
+	}
+	if got := NoteProperties(fm); !slices.Equal(got, want) {
+		t.Errorf("NoteProperties = %v, want %v", got, want)
+	}
+}
+
+func TestListNotes_Properties(t *testing.T) {
+	db := testDB(t)
+	for path, content := range map[string]string{
+		"a.md": "---\nstatus: in-progress\nowners: [alice, bob]\n---\nA\n",
+		"b.md": "---\nstatus: Done\nowners: [alice]\n---\nB\n",
+		"c.md": "---\nstatus: todo\n---\nC\n",
+		"d.md": "no frontmatter\n",
+	} {
+		if err := indexFile(db, path, []byte(content), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths := func(props ...Property) []string {
+		t.Helper()
+		rows, total, err := db.ListNotes(0, 0, "", "path", NoteFilter{Properties: props})
+		if err != nil {
+			t.Fatalf("ListNotes(%v): %v", props, err)
+		}
+		if total != len(rows) {
+			t.Errorf("ListNotes(%v) total = %d, want %d", props, total, len(rows))
+		}
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.Path)
+		}
+		slices.Sort(out)
+		return out
+	}
+	for _, tc := range []struct {
+		props []Property
+		want  []string
+	}{
+		{[]Property{{"status", "in-progress"}}, []string{"a.md"}},
+		{[]Property{{"Status", "done"}}, []string{"b.md"}},
+		{[]Property{{"status", "done"}, {"status", "todo"}}, []string{"b.md", "c.md"}},
+		{[]Property{{"owners", "alice"}, {"status", "done"}}, []string{"b.md"}},
+		{[]Property{{"owners", ""}}, []string{"a.md", "b.md"}},
+		{[]Property{{"status", "archived"}}, nil},
+	} {
+		if got := paths(tc.props...); !slices.Equal(got, tc.want) {
+			t.Errorf("ListNotes(%v) = %v, want %v", tc.props, got, tc.want)
+		}
+	}
+
+	if err := db.MoveNote("a.md", "e.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteNote("b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(Property{"owners", "alice"}); !slices.Equal(got, []string{"e.md"}) {
+		t.Errorf("after move and delete = %v, want [e.md]", got)
+	}
+}
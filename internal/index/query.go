@@ -0,0 +1,420 @@
+package index
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/starford/kenaz/internal/slug"
+)
+
+// WithStopWords sets words dropped from full-text queries, matched
+// case-insensitively, so common words do not dominate ranking.
+func WithStopWords(words []string) Option {
+	return func(db *DB) {
+		if len(words) == 0 {
+			return
+		}
+		db.stopWords = make(map[string]struct{}, len(words))
+		for _, w := range words {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+				db.stopWords[w] = struct{}{}
+			}
+		}
+	}
+}
+
+// WithSynonyms sets query-time synonyms: a query term matching a key
+// (case-insensitively) also matches each of its synonyms, e.g.
+// "k8s" → ["kubernetes"]. Multi-word synonyms match as phrases.
+func WithSynonyms(synonyms map[string][]string) Option {
+	return func(db *DB) {
+		if len(synonyms) == 0 {
+			return
+		}
+		db.synonyms = make(map[string][]string, len(synonyms))
+		for k, vs := range synonyms {
+			k = strings.ToLower(strings.TrimSpace(k))
+			for _, v := range vs {
+				if v = strings.TrimSpace(strings.ReplaceAll(v, `"`, "")); v != "" {
+					db.synonyms[k] = append(db.synonyms[k], v)
+				}
+			}
+		}
+	}
+}
+
+// ftsOperators are the FTS5 query keywords, which are case-sensitive.
+var ftsOperators = map[string]bool{"AND": true, "OR": true, "NOT": true}
+
+// ftsColumns are the columns a query term may be restricted to with
+// "column:term".
+var ftsColumns = map[string]bool{"title": true, "body": true, "tags": true}
+
+// Kinds of query tokens.
+const (
+	tokTerm = iota
+	tokPhrase
+	tokOperator
+	tokOpen
+	tokClose
+	tokNear
+)
+
+// queryToken is one token of a full-text query, as parsed by parseQuery.
+type queryToken struct {
+	kind int
+	// text is the term or phrase (unquoted), or the operator.
+	text string
+	// prefix marks "term*" and "phrase"*; column is set by "column:".
+	prefix bool
+	column string
+	// near holds the phrases of NEAR(...), dist its distance (0 when not
+	// given).
+	near []string
+	dist int
+}
+
+// bare reports whether t is a plain word that stop words and synonyms
+// apply to.
+func (t queryToken) bare() bool {
+	return t.kind == tokTerm && !t.prefix && t.column == ""
+}
+
+// operand reports whether t can stand on either side of an operator.
+func (t queryToken) operand() bool {
+	return t.kind == tokTerm || t.kind == tokPhrase || t.kind == tokNear
+}
+
+// parseQuery splits a search query into tokens, accepting FTS5 syntax and
+// repairing what FTS5 would reject, so that any input searches for
+// something rather than failing:
+//   - "quoted phrases" match their words in order; "" inside escapes a
+//     quote, and a missing closing quote ends the phrase at the end.
+//   - term* and "phrase"* match prefixes.
+//   - title:, body:, and tags: restrict the following term or phrase to a
+//     column; other "x:" prefixes are part of the word.
+//   - NEAR(a b, N) matches its terms within N words (default 10).
+//   - AND, OR, NOT (upper case) and parentheses combine the rest;
+//     operators without an operand on both sides, unbalanced parentheses,
+//     and empty phrases are dropped.
+//
+// Other punctuation is searched literally, as if the word were quoted.
+// Quoting is the escape path: "NEAR" or "c++" match those words.
+func parseQuery(query string) []queryToken {
+	var toks []queryToken
+	rs := []rune(query)
+	column := ""
+	emit := func(t queryToken) {
+		if t.kind == tokTerm || t.kind == tokPhrase {
+			t.column, column = column, ""
+		}
+		toks = append(toks, t)
+	}
+	for i := 0; i < len(rs); {
+		switch r := rs[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			text, n := readPhrase(rs[i:])
+			i += n
+			t := queryToken{kind: tokPhrase, text: text}
+			if i < len(rs) && rs[i] == '*' {
+				t.prefix = true
+				i++
+			}
+			if strings.TrimSpace(text) == "" {
+				column = ""
+				continue
+			}
+			emit(t)
+		case r == '(':
+			toks = append(toks, queryToken{kind: tokOpen})
+			i++
+		case r == ')':
+			toks = append(toks, queryToken{kind: tokClose})
+			i++
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && rs[j] != '"' && rs[j] != '(' && rs[j] != ')' {
+				j++
+			}
+			word := string(rs[i:j])
+			i = j
+			if word == "NEAR" && i < len(rs) && rs[i] == '(' {
+				if t, n, ok := readNear(rs[i:]); ok {
+					column = ""
+					toks = append(toks, t)
+					i += n
+					continue
+				}
+			}
+			if ftsOperators[word] {
+				column = ""
+				toks = append(toks, queryToken{kind: tokOperator, text: word})
+				continue
+			}
+			if c, rest, ok := strings.Cut(word, ":"); ok && ftsColumns[c] {
+				column, word = c, rest
+				if word == "" {
+					continue
+				}
+			}
+			t := queryToken{kind: tokTerm, text: word}
+			if trimmed := strings.TrimRight(word, "*"); trimmed != word {
+				t.text, t.prefix = trimmed, true
+			}
+			if t.text != "" {
+				emit(t)
+			}
+		}
+	}
+	return balanceQuery(toks)
+}
+
+// readPhrase reads the quoted phrase at the start of rs, returning its text
+// and the number of runes read.
+func readPhrase(rs []rune) (string, int) {
+	var b strings.Builder
+	i := 1
+	for i < len(rs) {
+		if rs[i] == '"' {
+			if i+1 < len(rs) && rs[i+1] == '"' {
+				b.WriteRune('"')
+				i += 2
+				continue
+			}
+			return b.String(), i + 1
+		}
+		b.WriteRune(rs[i])
+		i++
+	}
+	return b.String(), i
+}
+
+// readNear reads "(a "b c", N)" after NEAR, reporting false when it is not
+// closed or holds no terms.
+func readNear(rs []rune) (queryToken, int, bool) {
+	t := queryToken{kind: tokNear}
+	i := 1
+	for i < len(rs) {
+		switch r := rs[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == ')':
+			return t, i + 1, len(t.near) > 0
+		case r == '"':
+			text, n := readPhrase(rs[i:])
+			if strings.TrimSpace(text) != "" {
+				t.near = append(t.near, text)
+			}
+			i += n
+		case r == ',':
+			j := i + 1
+			for j < len(rs) && rs[j] != ')' {
+				j++
+			}
+			d, err := strconv.Atoi(strings.TrimSpace(string(rs[i+1 : j])))
+			if err != nil || d < 0 || j == len(rs) {
+				return t, 0, false
+			}
+			t.dist = d
+			i = j
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune(`"(),`, rs[j]) {
+				j++
+			}
+			if j == i {
+				return t, 0, false
+			}
+			t.near = append(t.near, string(rs[i:j]))
+			i = j
+		}
+	}
+	return t, 0, false
+}
+
+// balanceQuery drops unmatched parentheses, empty groups, and operators
+// without an operand on both sides.
+func balanceQuery(toks []queryToken) []queryToken {
+	// Unmatched parentheses.
+	var open []int
+	drop := make(map[int]bool)
+	for i, t := range toks {
+		switch t.kind {
+		case tokOpen:
+			open = append(open, i)
+		case tokClose:
+			if len(open) == 0 {
+				drop[i] = true
+			} else {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	for _, i := range open {
+		drop[i] = true
+	}
+
+	// Repeat until stable: removing an operator can empty a group, and
+	// removing a group can strand an operator.
+	out := make([]queryToken, 0, len(toks))
+	for i, t := range toks {
+		if !drop[i] {
+			out = append(out, t)
+		}
+	}
+	for {
+		n := len(out)
+		var kept []queryToken
+		for _, t := range out {
+			last := queryToken{kind: tokOpen}
+			if len(kept) > 0 {
+				last = kept[len(kept)-1]
+			}
+			switch {
+			case t.kind == tokOperator && (last.kind == tokOperator || last.kind == tokOpen):
+				continue
+			case t.kind == tokClose && last.kind == tokOperator:
+				kept[len(kept)-1] = t
+				continue
+			case t.kind == tokClose && last.kind == tokOpen && len(kept) > 0:
+				kept = kept[:len(kept)-1]
+				continue
+			}
+			kept = append(kept, t)
+		}
+		for len(kept) > 0 && kept[len(kept)-1].kind == tokOperator {
+			kept = kept[:len(kept)-1]
+		}
+		if out = kept; len(out) == n {
+			return out
+		}
+	}
+}
+
+// ftsString renders toks as an FTS5 query. Words FTS5 would not accept
+// bare are quoted.
+func ftsString(toks []queryToken) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 && t.kind != tokClose && toks[i-1].kind != tokOpen {
+			b.WriteByte(' ')
+			// FTS5 only joins phrases implicitly; groups need AND.
+			if prev := toks[i-1]; (prev.kind == tokClose || prev.operand()) &&
+				(t.kind == tokOpen || prev.kind == tokClose && t.operand()) {
+				b.WriteString("AND ")
+			}
+		}
+		if t.column != "" {
+			b.WriteString(t.column + ":")
+		}
+		switch t.kind {
+		case tokTerm:
+			if isBareword(t.text) && !ftsKeyword(t.text) {
+				b.WriteString(t.text)
+			} else {
+				b.WriteString(quotePhrase(t.text))
+			}
+		case tokPhrase:
+			b.WriteString(quotePhrase(t.text))
+		case tokOperator:
+			b.WriteString(t.text)
+		case tokOpen:
+			b.WriteByte('(')
+		case tokClose:
+			b.WriteByte(')')
+		case tokNear:
+			b.WriteString("NEAR(")
+			for j, p := range t.near {
+				if j > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(quotePhrase(p))
+			}
+			if t.dist > 0 {
+				b.WriteString(", " + strconv.Itoa(t.dist))
+			}
+			b.WriteByte(')')
+		}
+		if t.prefix {
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
+
+// isBareword reports whether s may appear unquoted in an FTS5 query:
+// ASCII letters, digits, and underscores, and any non-ASCII characters.
+func isBareword(s string) bool {
+	for _, r := range s {
+		if r < 0x80 && r != '_' && !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// ftsKeyword reports whether s is an FTS5 keyword when written bare.
+func ftsKeyword(s string) bool {
+	return ftsOperators[s] || s == "NEAR"
+}
+
+// quotePhrase quotes s as an FTS5 phrase.
+func quotePhrase(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// ftsQuery rewrites query for MATCH. It is parsed and repaired (see
+// parseQuery), then stop words are removed, and terms with synonyms or,
+// with transliteration on, Cyrillic letters become groups such as
+// (k8s OR "kubernetes") or (кеназ OR "kenaz"). Only bare terms are
+// touched: quoted phrases, prefix terms, column filters, and NEAR groups
+// are kept as written, and operators left without an operand are removed
+// with the stop words. Stop words are kept when the query has nothing else.
+// An empty result means the query has nothing to search for.
+func (db *DB) ftsQuery(query string) string {
+	toks := db.dropStopWords(parseQuery(query))
+	var out []queryToken
+	for _, t := range toks {
+		var alts []string
+		if t.bare() {
+			alts = db.synonyms[strings.ToLower(t.text)]
+			if db.translit && hasCyrillic(t.text) {
+				alts = append(alts[:len(alts):len(alts)], slug.Transliterate(t.text))
+			}
+		}
+		if len(alts) == 0 {
+			out = append(out, t)
+			continue
+		}
+		out = append(out, queryToken{kind: tokOpen}, t)
+		for _, a := range alts {
+			out = append(out, queryToken{kind: tokOperator, text: "OR"}, queryToken{kind: tokPhrase, text: a})
+		}
+		out = append(out, queryToken{kind: tokClose})
+	}
+	return ftsString(out)
+}
+
+// dropStopWords removes bare stop words, and operators left dangling by
+// them, from toks; it returns toks unchanged when nothing else would remain.
+func (db *DB) dropStopWords(toks []queryToken) []queryToken {
+	if len(db.stopWords) == 0 {
+		return toks
+	}
+	var kept []queryToken
+	for _, t := range toks {
+		if _, stop := db.stopWords[strings.ToLower(t.text)]; stop && t.bare() {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	kept = balanceQuery(kept)
+	if !slices.ContainsFunc(kept, queryToken.operand) {
+		return toks
+	}
+	return kept
+}
@@ -0,0 +1,113 @@
+package index
+
+import "testing"
+
+func TestFTSQuery_StopWords(t *testing.T) {
+	db := &DB{}
+	WithStopWords([]string{"the", " A ", "and", "of"})(db)
+	tests := []struct{ in, want string }{
+		{"the history of rome", "history rome"},
+		{"The Cat", "Cat"},
+		{`"the cat" dog`, `"the cat" dog`},
+		{"the AND cat", "cat"},
+		{"cat OR the", "cat"},
+		{"cat AND the OR dog", "cat AND dog"},
+		{"the*", "the*"},
+		{"title:the cat", "title:the cat"},
+		{"the and a", "the and a"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := db.ftsQuery(tt.in); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if got := (&DB{}).ftsQuery("the cat"); got != "the cat" {
+		t.Errorf("without stop words: %q", got)
+	}
+}
+
+func TestFTSQuery_Synonyms(t *testing.T) {
+	db := &DB{}
+	WithStopWords([]string{"the"})(db)
+	WithSynonyms(map[string][]string{
+		"K8s": {"kubernetes", " k 8 s "},
+		"js":  {`java"script`},
+	})(db)
+	tests := []struct{ in, want string }{
+		{"deploy k8s", `deploy AND (k8s OR "kubernetes" OR "k 8 s")`},
+		{"the K8S cluster", `(K8S OR "kubernetes" OR "k 8 s") AND cluster`},
+		{"js*", "js*"},
+		{`"k8s docs"`, `"k8s docs"`},
+		{"js", `(js OR "javascript")`},
+	}
+	for _, tt := range tests {
+		if got := db.ftsQuery(tt.in); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFTSQuery_Transliteration(t *testing.T) {
+	db := &DB{}
+	WithSynonyms(map[string][]string{"руна": {"rune"}})(db)
+	WithTransliteration(true)(db)
+	tests := []struct{ in, want string }{
+		{"кеназ", `(кеназ OR "kenaz")`},
+		{"руна kenaz", `(руна OR "rune" OR "runa") AND kenaz`},
+		{`"кеназ руна"`, `"кеназ руна"`},
+	}
+	for _, tt := range tests {
+		if got := db.ftsQuery(tt.in); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := db.translitText("Руна Кеназ", "кеназ, 2025 and Щит"); got != "runa kenaz shchit" {
+		t.Errorf("translitText = %q", got)
+	}
+	if got := (&DB{}).translitText("кеназ"); got != "" {
+		t.Errorf("translitText without option = %q", got)
+	}
+}
+
+func TestFTSQuery_Repair(t *testing.T) {
+	db := &DB{}
+	tests := []struct{ in, want string }{
+		{"cat dog", "cat dog"},
+		{`"unbalanced phrase`, `"unbalanced phrase"`},
+		{`"say ""hi"" now"`, `"say ""hi"" now"`},
+		{"foo-bar", `"foo-bar"`},
+		{"-draft", `"-draft"`},
+		{"pre*", "pre*"},
+		{`"exact phrase"*`, `"exact phrase"*`},
+		{"c++*", `"c++"*`},
+		{"title:go", "title:go"},
+		{`body:"a b"`, `body:"a b"`},
+		{"title: go", "title:go"},
+		{"foo:bar", `"foo:bar"`},
+		{"NEAR(a b, 5)", `NEAR("a" "b", 5)`},
+		{`NEAR("a b" c)`, `NEAR("a b" "c")`},
+		{"NEAR(a b", `"NEAR" a b`},
+		{"NEAR", `"NEAR"`},
+		{"a NOT b", "a NOT b"},
+		{"(a OR b) c", "(a OR b) AND c"},
+		{"a (b) (c)", "a AND (b) AND (c)"},
+		{"NEAR(a b) c", `NEAR("a" "b") c`},
+		{`"" a`, "a"},
+		{"(a OR b", "a OR b"},
+		{"a OR b)", "a OR b"},
+		{"a (b OR) c", "a AND (b) AND c"},
+		{"a AND OR b", "a AND b"},
+		{"NOT a", "a"},
+		{"a OR", "a"},
+		{"() AND", ""},
+		{"*", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := db.ftsQuery(tt.in); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/starford/kenaz/internal/storage"
+)
+
+// reindexBatch is the number of notes Reindex processes per transaction.
+const reindexBatch = 100
+
+// noteTables hold data derived from note files only, so Reindex can clear
+// them. Review schedules and history, the trash, and caches of external
+// data are kept.
+var noteTables = []string{
+	"notes", "links", "resolution", "note_metadata", "properties",
+	"tasks", "reminders", "refs", "cards", "note_urls", "sections", "tags",
+}
+
+// ReindexProgress counts the notes a Reindex has processed.
+type ReindexProgress struct {
+	// Total is the number of notes in the vault; Processed counts those
+	// indexed or failed so far.
+	Total     int `json:"total" example:"1200" validate:"required"`
+	Processed int `json:"processed" example:"300" validate:"required"`
+	// Errors lists the notes that could not be read or parsed.
+	Errors []ReindexError `json:"errors" validate:"required"`
+}
+
+// ReindexError is a note Reindex skipped.
+type ReindexError struct {
+	Path  string `json:"path" example:"notes/broken.md" validate:"required"`
+	Error string `json:"error" validate:"required"`
+}
+
+// Reindex drops every note from the index and rebuilds it from the notes
+// in store, for when the index is damaged or the parser changed. Notes are
+// written in batches, calling progress (if not nil) after each; notes that
+// fail to read or parse are logged, counted in Errors, and skipped. Like
+// Sync, it records when it finished (see Stats). It returns an error only
+// when the index cannot be cleared or written, or when ctx is done; a
+// reindex stopped that way leaves the notes not yet written for the next
+// Sync.
+func Reindex(ctx context.Context, db *DB, store storage.Provider, logger *slog.Logger, progress func(ReindexProgress)) (ReindexProgress, error) {
+	p := ReindexProgress{Errors: []ReindexError{}}
+	metas, err := store.ListStat("")
+	if err != nil {
+		return p, err
+	}
+	p.Total = len(metas)
+
+	if err := db.withTxContext(ctx, func(tx *sql.Tx) error {
+		if err := ftsClear(tx); err != nil {
+			return err
+		}
+		for _, t := range noteTables {
+			if _, err := tx.Exec(`DELETE FROM ` + t); err != nil {
+				return fmt.Errorf("index: clear %s: %w", t, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return p, err
+	}
+
+	report := func() {
+		if progress != nil {
+			progress(p)
+		}
+	}
+	report()
+	batch := NoteBatch{Rebuild: true}
+	for i, m := range metas {
+		data, err := store.Read(m.Path)
+		if err == nil {
+			var n IndexedNote
+			if n, err = db.parseNote(m, data); err == nil {
+				n.Row.FileModTime, n.Row.FileSize = m.UpdatedAt, m.Size
+				batch.Upserts = append(batch.Upserts, n)
+			}
+		}
+		if err != nil {
+			logger.Warn("reindex: note skipped", slog.String("path", m.Path), slog.String("error", err.Error()))
+			p.Errors = append(p.Errors, ReindexError{Path: m.Path, Error: err.Error()})
+		}
+		if (i+1)%reindexBatch == 0 || i == len(metas)-1 {
+			if err := db.ApplyBatch(ctx, batch); err != nil {
+				return p, err
+			}
+			batch = NoteBatch{Rebuild: true}
+			p.Processed = i + 1
+			report()
+		}
+	}
+	return p, db.setSyncedAt(time.Now())
+}
@@ -0,0 +1,180 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// metaRemindersChecked records the time up to which reminders have been
+// delivered.
+const metaRemindersChecked = "reminders_checked_at"
+
+// Reminder sources.
+const (
+	ReminderNote = "note" // frontmatter "remind" time
+	ReminderTask = "task" // open task with a due date
+)
+
+// Reminder is a note or task that comes due at a point in time.
+type Reminder struct {
+	Path  string `json:"path" validate:"required"`
+	Title string `json:"title" validate:"required"`
+	// Text is the task text, or the note title for note reminders.
+	Text   string    `json:"text" validate:"required"`
+	At     time.Time `json:"at" validate:"required"`
+	Source string    `json:"source" enums:"note,task" validate:"required"`
+	// Line is the task's line in the note; 0 for note reminders.
+	Line int `json:"line,omitempty"`
+}
+
+// replaceTasks replaces the tasks and reminder times stored for path
+// within tx.
+func replaceTasks(tx *sql.Tx, path string, tasks []parser.Task, reminders []time.Time) error {
+	if _, err := tx.Exec(`DELETE FROM tasks WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete old tasks: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM reminders WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete old reminders: %w", err)
+	}
+	for _, t := range tasks {
+		due := ""
+		if !t.Due.IsZero() {
+			due = t.Due.Format(time.DateOnly)
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tasks (path, line, text, done, due) VALUES (?, ?, ?, ?, ?)`,
+			path, t.Line, t.Text, t.Done, due); err != nil {
+			return fmt.Errorf("index: insert task: %w", err)
+		}
+	}
+	for _, at := range reminders {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO reminders (path, at) VALUES (?, ?)`, path, at.Unix()); err != nil {
+			return fmt.Errorf("index: insert reminder: %w", err)
+		}
+	}
+	return nil
+}
+
+// Reminders returns the reminders due after after and at or before until,
+// oldest first. Open tasks come due at taskTime past midnight (local time)
+// of their due date.
+func (db *DB) Reminders(after, until time.Time, taskTime time.Duration) ([]Reminder, error) {
+	var out []Reminder
+	rows, err := db.conn.Query(`
+		SELECT r.path, n.title, r.at FROM reminders r JOIN notes n ON n.path = r.path
+		WHERE r.at > ? AND r.at <= ?`, after.Unix(), until.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("index: reminders: %w", err)
+	}
+	for rows.Next() {
+		var r Reminder
+		var at int64
+		if err := rows.Scan(&r.Path, &r.Title, &at); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("index: scan reminder: %w", err)
+		}
+		r.Text, r.At, r.Source = r.Title, time.Unix(at, 0), ReminderNote
+		out = append(out, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A task due on day d fires at d+taskTime, so only due dates between
+	// the days of after-taskTime and until-taskTime can fire.
+	from := after.Add(-taskTime).Format(time.DateOnly)
+	to := until.Add(-taskTime).Format(time.DateOnly)
+	rows, err = db.conn.Query(`
+		SELECT t.path, n.title, t.text, t.line, t.due FROM tasks t JOIN notes n ON n.path = t.path
+		WHERE t.done = 0 AND t.due >= ? AND t.due <= ?`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("index: task reminders: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+	for rows.Next() {
+		var r Reminder
+		var due string
+		if err := rows.Scan(&r.Path, &r.Title, &r.Text, &r.Line, &due); err != nil {
+			return nil, fmt.Errorf("index: scan task reminder: %w", err)
+		}
+		day, err := time.ParseInLocation(time.DateOnly, due, time.Local)
+		if err != nil {
+			continue
+		}
+		r.At, r.Source = day.Add(taskTime), ReminderTask
+		if r.At.After(after) && !r.At.After(until) {
+			out = append(out, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out, nil
+}
+
+// RemindersCheckedAt returns the time up to which reminders have been
+// delivered, or the zero time if never.
+func (db *DB) RemindersCheckedAt() (time.Time, error) {
+	var v string
+	err := db.conn.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaRemindersChecked).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("index: read reminder checkpoint: %w", err)
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// SetRemindersCheckedAt records that reminders up to t have been delivered.
+func (db *DB) SetRemindersCheckedAt(t time.Time) error {
+	err := db.exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, metaRemindersChecked, strconv.FormatInt(t.Unix(), 10))
+	if err != nil {
+		return fmt.Errorf("index: save reminder checkpoint: %w", err)
+	}
+	return nil
+}
+
+// TaskRow is an indexed open task with a due date.
+type TaskRow struct {
+	Path  string `json:"path" validate:"required"`
+	Title string `json:"title" validate:"required"`
+	Text  string `json:"text" validate:"required"`
+	Line  int    `json:"line" validate:"required"`
+	Due   string `json:"due" example:"2026-10-20" validate:"required"`
+}
+
+// OpenTasksDue returns the open tasks due on or before until (YYYY-MM-DD),
+// earliest first.
+func (db *DB) OpenTasksDue(until string) ([]TaskRow, error) {
+	rows, err := db.conn.Query(`
+		SELECT t.path, n.title, t.text, t.line, t.due FROM tasks t JOIN notes n ON n.path = t.path
+		WHERE t.done = 0 AND t.due != '' AND t.due <= ?
+		ORDER BY t.due, t.path, t.line`, until)
+	if err != nil {
+		return nil, fmt.Errorf("index: open tasks: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var out []TaskRow
+	for rows.Next() {
+		var t TaskRow
+		if err := rows.Scan(&t.Path, &t.Title, &t.Text, &t.Line, &t.Due); err != nil {
+			return nil, fmt.Errorf("index: scan task: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,68 @@
+package index
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReminders(t *testing.T) {
+	db := testDB(t)
+	day := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	data := []byte("---\ntitle: Plan\nremind: \"2026-10-20 08:00\"\n---\n" +
+		"- [ ] Ship it 📅 2026-10-20\n" +
+		"- [x] Done already 📅 2026-10-20\n" +
+		"- [ ] Later 📅 2026-10-22\n")
+	if err := indexFile(db, "plan.md", data, day); err != nil {
+		t.Fatal(err)
+	}
+
+	nine := 9 * time.Hour
+	got, err := db.Reminders(day, day.Add(24*time.Hour), nine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("reminders = %+v, want 2", got)
+	}
+	if got[0].Source != ReminderNote || got[0].Text != "Plan" || !got[0].At.Equal(day.Add(8*time.Hour)) {
+		t.Errorf("first = %+v", got[0])
+	}
+	if got[1].Source != ReminderTask || got[1].Text != "Ship it 📅 2026-10-20" || got[1].Line != 5 || !got[1].At.Equal(day.Add(nine)) {
+		t.Errorf("second = %+v", got[1])
+	}
+
+	// The window is (after, until].
+	got, _ = db.Reminders(day.Add(8*time.Hour), day.Add(nine-time.Second), nine)
+	if len(got) != 0 {
+		t.Errorf("empty window: %+v", got)
+	}
+
+	if err := db.MoveNote("plan.md", "archive/plan.md"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = db.Reminders(day, day.Add(72*time.Hour), nine)
+	if len(got) != 3 || got[2].Path != "archive/plan.md" {
+		t.Errorf("after move: %+v", got)
+	}
+	if err := db.DeleteNote("archive/plan.md"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = db.Reminders(day, day.Add(72*time.Hour), nine)
+	if len(got) != 0 {
+		t.Errorf("after delete: %+v", got)
+	}
+}
+
+func TestRemindersCheckedAt(t *testing.T) {
+	db := testDB(t)
+	if at, err := db.RemindersCheckedAt(); err != nil || !at.IsZero() {
+		t.Fatalf("initial = %v, %v", at, err)
+	}
+	now := time.Unix(time.Now().Unix(), 0)
+	if err := db.SetRemindersCheckedAt(now); err != nil {
+		t.Fatal(err)
+	}
+	if at, _ := db.RemindersCheckedAt(); !at.Equal(now) {
+		t.Errorf("checked at = %v, want %v", at, now)
+	}
+}
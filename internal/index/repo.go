@@ -5,53 +5,328 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/starford/kenaz/internal/parser"
 )
 
 // NoteRow represents a row in the notes table.
 type NoteRow struct {
 	Path      string
+	ID        string
 	Title     string
 	Checksum  string
 	Tags      []string
+	Aliases   []string
 	UpdatedAt time.Time
+	// CreatedAt is the creation date. On upsert it is the date from the
+	// frontmatter; when zero, the stored date is kept, and new notes get
+	// FileCreatedAt, else UpdatedAt (or now).
+	CreatedAt time.Time
+	// FileCreatedAt is the file's birth time, where the storage reports it.
+	FileCreatedAt time.Time
+	// FileModTime and FileSize are the file's modification time and size
+	// as listed when it was read, so Sync can skip it while both are
+	// unchanged. A zero FileModTime records them as unknown.
+	FileModTime time.Time
+	FileSize    int64
+	// Metadata holds extractor output by key; it replaces the stored values
+	// on upsert and is not read back by the note queries.
+	Metadata map[string][]string
+	// Properties replace the stored frontmatter properties (see
+	// NoteProperties) that ListNotes filters on.
+	Properties []Property
+	// Tasks and Reminders likewise replace the stored checklist items and
+	// frontmatter reminder times.
+	Tasks     []parser.Task
+	Reminders []time.Time
+	// Reference is the note's bibliographic frontmatter; notes in
+	// ReferencesFolder are references even without it.
+	Reference *parser.Reference
+	// Cards replace the stored flashcards; review schedules are kept.
+	Cards []parser.Card
+	// URLs replace the stored external http(s) URLs of the body.
+	URLs []string
+	// Sections replace the stored headings and the text under each.
+	Sections []parser.Section
+}
+
+// noteColumns is the column list read by scanNote.
+const noteColumns = `path, id, title, checksum, tags, updated_at, created_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanNote reads a NoteRow selected with noteColumns.
+func scanNote(sc rowScanner) (NoteRow, error) {
+	var n NoteRow
+	var tagsJSON string
+	var created sql.NullTime
+	if err := sc.Scan(&n.Path, &n.ID, &n.Title, &n.Checksum, &tagsJSON, &n.UpdatedAt, &created); err != nil {
+		return NoteRow{}, err
+	}
+	n.CreatedAt = created.Time
+	_ = json.Unmarshal([]byte(tagsJSON), &n.Tags)
+	n.Tags = nonNilSlice(n.Tags)
+	return n, nil
 }
 
 // SearchResult represents one search hit.
 type SearchResult struct {
-	Path    string
-	Title   string
+	Path    string `json:"path"`
+	Title   string `json:"title"`
+	Snippet string `json:"snippet"`
+	// Matches locates the query matches in the title and body, in order,
+	// at most maxMatches of them.
+	Matches []Match `json:"matches"`
+	// Section is the heading the first body match falls under, when it
+	// falls under one.
+	Section *SectionRef `json:"section,omitempty"`
+}
+
+// Match is where a search query matched in a note.
+type Match struct {
+	// Field is "title" or "body" (the note content after the frontmatter).
+	Field string `json:"field"`
+	// Start and End are byte offsets into Field; End is exclusive.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// RuneStart and RuneEnd are the same offsets counted in runes.
+	RuneStart int `json:"rune_start"`
+	RuneEnd   int `json:"rune_end"`
+}
+
+// maxMatches bounds the matches reported per search hit.
+const maxMatches = 100
+
+// Search result orders.
+const (
+	// SearchSortRank orders by relevance: FTS5 rank, or without FTS5 title
+	// matches first and then the most recently updated.
+	SearchSortRank = "rank"
+	// SearchSortUpdated orders the most recently updated first.
+	SearchSortUpdated = "updated_at"
+	// SearchSortTitle orders by title, case-insensitively.
+	SearchSortTitle = "title"
+)
+
+// SearchOptions limits, pages, orders, and scopes a search.
+type SearchOptions struct {
+	// Limit caps the number of results (default 20).
+	Limit int
+	// Offset skips that many results, for paging.
+	Offset int
+	// Sort is SearchSortRank (default), SearchSortUpdated, or
+	// SearchSortTitle; other values are treated as SearchSortRank.
+	Sort string
+	// Folder restricts results to notes under this folder, at any depth.
+	Folder string
+	// Tag restricts results to notes with this tag.
+	Tag string
+	DateRange
+}
+
+// DateRange restricts notes by when they were last updated or created.
+// Zero bounds are unset.
+type DateRange struct {
+	// UpdatedAfter keeps notes updated at or after this time.
+	UpdatedAfter time.Time
+	// UpdatedBefore keeps notes updated strictly before this time.
+	UpdatedBefore time.Time
+	// CreatedAfter keeps notes created at or after this time.
+	CreatedAfter time.Time
+	// CreatedBefore keeps notes created strictly before this time.
+	CreatedBefore time.Time
+}
+
+// clauses returns SQL conditions over the notes table for the set bounds,
+// with their arguments. They compare julianday() values, which the
+// idx_notes_*_jd expression indexes cover, so stored times in any zone
+// compare correctly.
+func (r DateRange) clauses() ([]string, []any) {
+	var clauses []string
+	var args []any
+	for _, b := range []struct {
+		cond string
+		at   time.Time
+	}{
+		{`julianday(notes.updated_at) >= julianday(?)`, r.UpdatedAfter},
+		{`julianday(notes.updated_at) < julianday(?)`, r.UpdatedBefore},
+		{`julianday(notes.created_at) >= julianday(?)`, r.CreatedAfter},
+		{`julianday(notes.created_at) < julianday(?)`, r.CreatedBefore},
+	} {
+		if !b.at.IsZero() {
+			clauses = append(clauses, b.cond)
+			args = append(args, b.at.UTC().Format("2006-01-02 15:04:05.000"))
+		}
+	}
+	return clauses, args
+}
+
+// byRank reports whether results are ordered by relevance.
+func (o SearchOptions) byRank() bool {
+	return o.Sort != SearchSortUpdated && o.Sort != SearchSortTitle
+}
+
+// order returns the ORDER BY clause for o.Sort, with rank as the
+// backend's relevance ordering. Ties are broken by path, so pages do not
+// overlap.
+func (o SearchOptions) order(rank string) string {
+	switch o.Sort {
+	case SearchSortUpdated:
+		return ` ORDER BY julianday(notes.updated_at) DESC, notes.path`
+	case SearchSortTitle:
+		return ` ORDER BY notes.title COLLATE NOCASE, notes.path`
+	default:
+		return ` ORDER BY ` + rank + `, notes.path`
+	}
+}
+
+// scope returns SQL conditions (each prefixed with " AND ") over the notes
+// table that apply the folder, tag, and date filters, with their arguments.
+func (o SearchOptions) scope() (string, []any) {
+	var sb strings.Builder
+	var args []any
+	if f := strings.Trim(o.Folder, "/"); f != "" {
+		sb.WriteString(` AND notes.path LIKE ?`)
+		args = append(args, f+"/%")
+	}
+	if t := strings.TrimPrefix(o.Tag, "#"); t != "" {
+		sb.WriteString(` AND ` + tagClause)
+		args = append(args, t)
+	}
+	dates, dateArgs := o.DateRange.clauses()
+	for _, c := range dates {
+		sb.WriteString(` AND ` + c)
+	}
+	return sb.String(), append(args, dateArgs...)
+}
+
+// Link types stored in the links table.
+const (
+	LinkInline      = "inline"
+	LinkFrontmatter = "frontmatter"
+)
+
+// Link is an outgoing edge from a note.
+type Link struct {
+	Target string
+	Type   string
+	// Snippet is the line containing the link, for backlink context.
 	Snippet string
+	// Line and Column locate the link in the source file (1-based, 0 for
+	// frontmatter links).
+	Line   int
+	Column int
+	// Count is how many times the note references Target; 0 counts as 1.
+	Count int
 }
 
-// UpsertNote inserts or replaces a note, its FTS entry, and links within a transaction.
+// UpsertNote inserts or replaces a note, its FTS entry, and inline links within a transaction.
 func (db *DB) UpsertNote(n NoteRow, body string, links []string) error {
-	tx, err := db.conn.Begin()
-	if err != nil {
-		return fmt.Errorf("index: begin tx: %w", err)
+	typed := make([]Link, len(links))
+	for i, l := range links {
+		typed[i] = Link{Target: l, Type: LinkInline}
 	}
-	defer tx.Rollback() //nolint:errcheck // best-effort on failure path
+	return db.UpsertNoteLinks(n, body, typed)
+}
+
+// UpsertNoteLinks inserts or replaces a note, its FTS entry, and typed links within a transaction.
+// A change of the note's content counts as an edit in the writing activity.
+func (db *DB) UpsertNoteLinks(n NoteRow, body string, links []Link) error {
+	return db.upsertNoteLinks(n, body, links, true)
+}
+
+// upsertNoteLinks is UpsertNoteLinks, counting the change in the writing
+// activity only when track is set.
+func (db *DB) upsertNoteLinks(n NoteRow, body string, links []Link, track bool) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := db.upsertNote(tx, n, body, links, track); err != nil {
+			return err
+		}
+		return db.resolveNoteLinks(tx, n.Path)
+	})
+}
 
+// upsertNote writes n, its derived data, and its links within tx, and with
+// track adds the change to the writing activity (see trackEdit). The caller
+// re-resolves the links the change affects (see resolveNoteLinks).
+func (db *DB) upsertNote(tx *sql.Tx, n NoteRow, body string, links []Link, track bool) error {
+	if track {
+		trackEdit(tx, n.Path, n.Checksum, body)
+	}
 	tagsJSON, _ := json.Marshal(n.Tags)
 
+	created, fromFrontmatter := n.CreatedAt, !n.CreatedAt.IsZero()
+	if !fromFrontmatter {
+		created = n.FileCreatedAt
+		if created.IsZero() {
+			created = n.UpdatedAt
+		}
+		if created.IsZero() {
+			created = time.Now()
+		}
+	}
+
+	var mtime int64
+	if !n.FileModTime.IsZero() {
+		mtime = n.FileModTime.UnixNano()
+	}
+
 	// Upsert notes table (includes body for fallback search).
-	_, err = tx.Exec(`
-		INSERT INTO notes (path, title, checksum, tags, body, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+	_, err := tx.Exec(`
+		INSERT INTO notes (path, id, title, checksum, tags, body, updated_at, created_at, file_mtime, file_size)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(path) DO UPDATE SET
+			id         = excluded.id,
 			title      = excluded.title,
 			checksum   = excluded.checksum,
 			tags       = excluded.tags,
 			body       = excluded.body,
-			updated_at = excluded.updated_at
-	`, n.Path, n.Title, n.Checksum, string(tagsJSON), body, n.UpdatedAt)
+			updated_at = excluded.updated_at,
+			created_at = CASE WHEN ? THEN excluded.created_at
+				ELSE COALESCE(notes.created_at, excluded.created_at) END,
+			file_mtime = excluded.file_mtime,
+			file_size  = excluded.file_size
+	`, n.Path, n.ID, n.Title, n.Checksum, string(tagsJSON), body, n.UpdatedAt, created, mtime, n.FileSize, fromFrontmatter)
 	if err != nil {
 		return fmt.Errorf("index: upsert note: %w", err)
 	}
 
 	// FTS upsert (no-op when FTS5 tag is absent).
-	if err := ftsUpsert(tx, n.Path, n.Title, body, n.Tags); err != nil {
+	if err := ftsUpsert(tx, n.Path, n.Title, body, n.Tags, db.translitText(n.Title, body)); err != nil {
+		return err
+	}
+
+	if err := replaceResolution(tx, n); err != nil {
+		return err
+	}
+	if err := replaceMetadata(tx, n.Path, n.Metadata); err != nil {
+		return err
+	}
+	if err := replaceTags(tx, n.Path, n.Tags); err != nil {
+		return err
+	}
+	if err := replaceProperties(tx, n.Path, n.Properties); err != nil {
+		return err
+	}
+	if err := replaceTasks(tx, n.Path, n.Tasks, n.Reminders); err != nil {
+		return err
+	}
+	if err := replaceReference(tx, n); err != nil {
+		return err
+	}
+	if err := replaceCards(tx, n.Path, n.Cards); err != nil {
+		return err
+	}
+	if err := replaceURLs(tx, n.Path, n.URLs); err != nil {
+		return err
+	}
+	if err := replaceSections(tx, n.Path, n.Sections); err != nil {
 		return err
 	}
 
@@ -60,40 +335,62 @@ func (db *DB) UpsertNote(n NoteRow, body string, links []string) error {
 		return fmt.Errorf("index: delete old links: %w", err)
 	}
 	if len(links) > 0 {
-		stmt, err := tx.Prepare(`INSERT OR IGNORE INTO links (source, target, type) VALUES (?, ?, 'inline')`)
+		stmt, err := tx.Prepare(`INSERT INTO links (source, target, target_key, type, snippet, line, col, count, types) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 		if err != nil {
 			return fmt.Errorf("index: prepare link insert: %w", err)
 		}
 		defer stmt.Close()
-		for _, target := range links {
-			if _, err := stmt.Exec(n.Path, target); err != nil {
+		for _, e := range mergeLinks(links) {
+			l := e.Link
+			if _, err := stmt.Exec(n.Path, l.Target, linkKey(l.Target), l.Type, l.Snippet, l.Line, l.Column, l.Count, strings.Join(e.types, ",")); err != nil {
 				return fmt.Errorf("index: insert link: %w", err)
 			}
 		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
-// DeleteNote removes a note, its FTS entry, and outgoing links.
-func (db *DB) DeleteNote(path string) error {
-	tx, err := db.conn.Begin()
-	if err != nil {
-		return fmt.Errorf("index: begin tx: %w", err)
-	}
-	defer tx.Rollback() //nolint:errcheck
+// mergedLink is one row of the links table: the first link to a target,
+// with the counts and distinct types of all links to it.
+type mergedLink struct {
+	Link
+	types []string
+}
 
-	if err := ftsDelete(tx, path); err != nil {
-		return fmt.Errorf("index: fts delete: %w", err)
-	}
-	if _, err := tx.Exec(`DELETE FROM links WHERE source = ?`, path); err != nil {
-		return fmt.Errorf("index: delete links: %w", err)
-	}
-	if _, err := tx.Exec(`DELETE FROM notes WHERE path = ?`, path); err != nil {
-		return fmt.Errorf("index: delete note: %w", err)
+// mergeLinks folds links with the same target into one, in order of first
+// occurrence, summing their counts. The row keeps the first link's type and
+// context.
+func mergeLinks(links []Link) []mergedLink {
+	at := make(map[string]int, len(links))
+	var out []mergedLink
+	for _, l := range links {
+		if l.Type == "" {
+			l.Type = LinkInline
+		}
+		l.Count = max(l.Count, 1)
+		i, ok := at[l.Target]
+		if !ok {
+			at[l.Target] = len(out)
+			out = append(out, mergedLink{Link: l, types: []string{l.Type}})
+			continue
+		}
+		out[i].Count += l.Count
+		if !slices.Contains(out[i].types, l.Type) {
+			out[i].types = append(out[i].types, l.Type)
+		}
 	}
+	return out
+}
 
-	return tx.Commit()
+// DeleteNote removes a note, its FTS entry, and outgoing links.
+func (db *DB) DeleteNote(path string) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := deleteNote(tx, path); err != nil {
+			return err
+		}
+		return db.resolveNoteLinks(tx, path)
+	})
 }
 
 // DeleteNotesBatch removes multiple notes, their FTS entries, and outgoing links in a single transaction.
@@ -101,25 +398,58 @@ func (db *DB) DeleteNotesBatch(paths []string) error {
 	if len(paths) == 0 {
 		return nil
 	}
-	tx, err := db.conn.Begin()
-	if err != nil {
-		return fmt.Errorf("index: begin tx: %w", err)
-	}
-	defer tx.Rollback() //nolint:errcheck
-
-	for _, path := range paths {
-		if err := ftsDelete(tx, path); err != nil {
-			return fmt.Errorf("index: fts delete %s: %w", path, err)
-		}
-		if _, err := tx.Exec(`DELETE FROM links WHERE source = ?`, path); err != nil {
-			return fmt.Errorf("index: delete links %s: %w", path, err)
-		}
-		if _, err := tx.Exec(`DELETE FROM notes WHERE path = ?`, path); err != nil {
-			return fmt.Errorf("index: delete note %s: %w", path, err)
+	return db.withTx(func(tx *sql.Tx) error {
+		for _, path := range paths {
+			if err := deleteNote(tx, path); err != nil {
+				return err
+			}
 		}
-	}
+		return db.resolveChangedLinks(tx, paths)
+	})
+}
 
-	return tx.Commit()
+// deleteNote removes the note at path and its derived data within tx,
+// counting the deletion in the writing activity. The caller re-resolves the
+// links that pointed to it.
+func deleteNote(tx *sql.Tx, path string) error {
+	trackEdit(tx, path, "", "")
+	if err := ftsDelete(tx, path); err != nil {
+		return fmt.Errorf("index: fts delete %s: %w", path, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM links WHERE source = ?`, path); err != nil {
+		return fmt.Errorf("index: delete links %s: %w", path, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM resolution WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete resolution %s: %w", path, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM note_metadata WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete metadata %s: %w", path, err)
+	}
+	if err := replaceTags(tx, path, nil); err != nil {
+		return err
+	}
+	if err := replaceProperties(tx, path, nil); err != nil {
+		return err
+	}
+	if err := replaceTasks(tx, path, nil, nil); err != nil {
+		return err
+	}
+	if err := replaceCards(tx, path, nil); err != nil {
+		return err
+	}
+	if err := replaceURLs(tx, path, nil); err != nil {
+		return err
+	}
+	if err := replaceSections(tx, path, nil); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM refs WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete reference %s: %w", path, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM notes WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete note %s: %w", path, err)
+	}
+	return nil
 }
 
 // GetChecksum returns the stored checksum for a note, or empty string if not found.
@@ -173,24 +503,73 @@ func (db *DB) AllPaths() (map[string]struct{}, error) {
 
 // GetNote returns a single note row or nil if not found.
 func (db *DB) GetNote(path string) (*NoteRow, error) {
-	var n NoteRow
-	var tagsJSON string
-	err := db.conn.QueryRow(
-		`SELECT path, title, checksum, tags, updated_at FROM notes WHERE path = ?`, path,
-	).Scan(&n.Path, &n.Title, &n.Checksum, &tagsJSON, &n.UpdatedAt)
+	n, err := scanNote(db.conn.QueryRow(`SELECT `+noteColumns+` FROM notes WHERE path = ?`, path))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("index: get note %s: %w", path, err)
 	}
-	_ = json.Unmarshal([]byte(tagsJSON), &n.Tags)
-	n.Tags = nonNilSlice(n.Tags)
 	return &n, nil
 }
 
-// ListNotes returns note rows with optional pagination and tag filter.
-func (db *DB) ListNotes(limit, offset int, tag, sort string) ([]NoteRow, int, error) {
+// CreatedPerDay counts notes by creation day (YYYY-MM-DD, in the time zone
+// the date was recorded in) for days from through to, inclusive.
+func (db *DB) CreatedPerDay(from, to string) (map[string]int, error) {
+	rows, err := db.conn.Query(`
+		SELECT substr(created_at, 1, 10) AS day, count(*) FROM notes
+		WHERE created_at IS NOT NULL AND day BETWEEN ? AND ?
+		GROUP BY day
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("index: created per day: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var n int
+		if err := rows.Scan(&day, &n); err != nil {
+			return nil, err
+		}
+		out[day] = n
+	}
+	return out, rows.Err()
+}
+
+// NotesUpdatedSince returns up to limit notes updated after since, most
+// recent first. Times are compared as instants, whatever zone they were
+// stored in.
+func (db *DB) NotesUpdatedSince(since time.Time, limit int) ([]NoteRow, error) {
+	rows, err := db.conn.Query(`SELECT `+noteColumns+` FROM notes
+		WHERE julianday(updated_at) > julianday(?)
+		ORDER BY julianday(updated_at) DESC, path
+		LIMIT ?`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("index: notes updated since: %w", err)
+	}
+	defer rows.Close()
+	var out []NoteRow
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// NoteFilter restricts ListNotes by frontmatter properties (see
+// propertyClauses) and dates.
+type NoteFilter struct {
+	Properties []Property
+	DateRange
+}
+
+// ListNotes returns note rows with optional pagination, tag filter, and
+// property and date filters.
+func (db *DB) ListNotes(limit, offset int, tag, sort string, f NoteFilter) ([]NoteRow, int, error) {
 	if limit <= 0 {
 		limit = 50
 	}
@@ -199,16 +578,26 @@ func (db *DB) ListNotes(limit, offset int, tag, sort string) ([]NoteRow, int, er
 	}
 	// Whitelist sort columns.
 	switch sort {
-	case "updated_at", "title", "path":
+	case "updated_at", "created_at", "title", "path":
 	default:
 		sort = "updated_at"
 	}
 
-	where := ""
+	var clauses []string
 	args := []any{}
 	if tag != "" {
-		where = `WHERE tags LIKE ?`
-		args = append(args, `%"`+tag+`"%`)
+		clauses = append(clauses, tagClause)
+		args = append(args, tag)
+	}
+	propClauses, propArgs := propertyClauses(f.Properties)
+	clauses = append(clauses, propClauses...)
+	args = append(args, propArgs...)
+	dateClauses, dateArgs := f.DateRange.clauses()
+	clauses = append(clauses, dateClauses...)
+	args = append(args, dateArgs...)
+	where := ""
+	if len(clauses) > 0 {
+		where = "WHERE " + strings.Join(clauses, " AND ")
 	}
 
 	// Total count.
@@ -218,7 +607,7 @@ func (db *DB) ListNotes(limit, offset int, tag, sort string) ([]NoteRow, int, er
 		return nil, 0, fmt.Errorf("index: count notes: %w", err)
 	}
 
-	q := fmt.Sprintf(`SELECT path, title, checksum, tags, updated_at FROM notes %s ORDER BY %s DESC LIMIT ? OFFSET ?`, where, sort)
+	q := fmt.Sprintf(`SELECT %s FROM notes %s ORDER BY %s DESC LIMIT ? OFFSET ?`, noteColumns, where, sort)
 	queryArgs := append(args, limit, offset)
 	rows, err := db.conn.Query(q, queryArgs...)
 	if err != nil {
@@ -228,13 +617,10 @@ func (db *DB) ListNotes(limit, offset int, tag, sort string) ([]NoteRow, int, er
 
 	var out []NoteRow
 	for rows.Next() {
-		var n NoteRow
-		var tagsJSON string
-		if err := rows.Scan(&n.Path, &n.Title, &n.Checksum, &tagsJSON, &n.UpdatedAt); err != nil {
+		n, err := scanNote(rows)
+		if err != nil {
 			return nil, 0, err
 		}
-		_ = json.Unmarshal([]byte(tagsJSON), &n.Tags)
-		n.Tags = nonNilSlice(n.Tags)
 		out = append(out, n)
 	}
 	return out, total, rows.Err()
@@ -258,8 +644,8 @@ func (db *DB) ListNotesCursor(limit int, cursor, tag, folder string) (CursorPage
 		args = append(args, folder+"%")
 	}
 	if tag != "" {
-		clauses = append(clauses, `tags LIKE ?`)
-		args = append(args, `%"`+tag+`"%`)
+		clauses = append(clauses, tagClause)
+		args = append(args, tag)
 	}
 
 	where := ""
@@ -267,7 +653,7 @@ func (db *DB) ListNotesCursor(limit int, cursor, tag, folder string) (CursorPage
 		where = "WHERE " + strings.Join(clauses, " AND ")
 	}
 
-	q := fmt.Sprintf(`SELECT path, title, checksum, tags, updated_at FROM notes %s ORDER BY path ASC LIMIT ?`, where)
+	q := fmt.Sprintf(`SELECT %s FROM notes %s ORDER BY path ASC LIMIT ?`, noteColumns, where)
 	args = append(args, limit+1) // fetch one extra to detect next page
 
 	rows, err := db.conn.Query(q, args...)
@@ -278,13 +664,10 @@ func (db *DB) ListNotesCursor(limit int, cursor, tag, folder string) (CursorPage
 
 	var out []NoteRow
 	for rows.Next() {
-		var n NoteRow
-		var tagsJSON string
-		if err := rows.Scan(&n.Path, &n.Title, &n.Checksum, &tagsJSON, &n.UpdatedAt); err != nil {
+		n, err := scanNote(rows)
+		if err != nil {
 			return CursorPage{}, err
 		}
-		_ = json.Unmarshal([]byte(tagsJSON), &n.Tags)
-		n.Tags = nonNilSlice(n.Tags)
 		out = append(out, n)
 	}
 	if err := rows.Err(); err != nil {
@@ -305,15 +688,46 @@ func (db *DB) ListNotesCursor(limit int, cursor, tag, folder string) (CursorPage
 type GraphNode struct {
 	ID    string `json:"id"`
 	Title string `json:"title,omitempty"`
+	// X and Y are the cached layout position (see noteservice.Graph).
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// Cluster is the node's cluster ID when clusters were requested (see
+	// noteservice.ClusteredGraph).
+	Cluster *int `json:"cluster,omitempty"`
+	NodeMetrics
+}
+
+// NodeMetrics holds a graph node's link counts and centrality (see
+// noteservice.Graph).
+type NodeMetrics struct {
+	// InDegree and OutDegree count the notes linking to and from the node.
+	InDegree  int `json:"in_degree"`
+	OutDegree int `json:"out_degree"`
+	// PageRank is the node's share of the graph's PageRank, which sums to 1.
+	PageRank float64 `json:"pagerank"`
+	// Betweenness is the share of shortest paths between other notes that
+	// pass through the node, from 0 to 1; approximated on large graphs.
+	Betweenness float64 `json:"betweenness"`
 }
 
 // GraphLink represents an edge in the knowledge graph.
 type GraphLink struct {
 	Source string `json:"source"`
 	Target string `json:"target"`
+	// Type is the type of the first link from Source to Target.
+	Type string `json:"type"`
+	// Types lists the distinct link types between the two notes.
+	Types []string `json:"types"`
+	// Weight is how many times Source references Target.
+	Weight int `json:"weight"`
 }
 
-// Graph returns all nodes and links for graph visualization.
+// Graph returns all nodes and links for graph visualization. Links point
+// at the note their target resolves to at index time (see ResolveLink), so
+// [[My Note]], [[my-note.md]], and a link by title or alias all attach to the
+// same node; unresolved targets become nodes of their own. Parallel links
+// between two notes are merged into one edge whose Weight is the total
+// number of references.
 func (db *DB) Graph() ([]GraphNode, []GraphLink, error) {
 	// Nodes from notes table.
 	rows, err := db.conn.Query(`SELECT path, title FROM notes`)
@@ -337,16 +751,18 @@ func (db *DB) Graph() ([]GraphNode, []GraphLink, error) {
 	}
 
 	// Links.
-	lrows, err := db.conn.Query(`SELECT source, target FROM links`)
+	lrows, err := db.conn.Query(`SELECT source, COALESCE(NULLIF(resolved, ''), target), type, types, count FROM links ORDER BY rowid`)
 	if err != nil {
 		return nil, nil, fmt.Errorf("index: graph links: %w", err)
 	}
 	defer lrows.Close()
 
 	var links []GraphLink
+	edges := make(map[[2]string]int)
 	for lrows.Next() {
 		var l GraphLink
-		if err := lrows.Scan(&l.Source, &l.Target); err != nil {
+		var types string
+		if err := lrows.Scan(&l.Source, &l.Target, &l.Type, &types, &l.Weight); err != nil {
 			return nil, nil, err
 		}
 		// Add target as a node if it is not already indexed.
@@ -354,44 +770,136 @@ func (db *DB) Graph() ([]GraphNode, []GraphLink, error) {
 			nodeSet[l.Target] = ""
 			nodes = append(nodes, GraphNode{ID: l.Target})
 		}
+		l.Types = []string{l.Type}
+		if types != "" {
+			l.Types = strings.Split(types, ",")
+		}
+		edge := [2]string{l.Source, l.Target}
+		if i, ok := edges[edge]; ok {
+			links[i].Weight += l.Weight
+			for _, t := range l.Types {
+				if !slices.Contains(links[i].Types, t) {
+					links[i].Types = append(links[i].Types, t)
+				}
+			}
+			continue
+		}
+		edges[edge] = len(links)
 		links = append(links, l)
 	}
 	return nodes, links, lrows.Err()
 }
 
-// Backlinks returns all note paths that link to the given target.
+// Backlinks returns all note paths that link to the given target, a note
+// path or any link target. Targets resolving to a note match every link
+// resolved to it at index time, so "My Note", "my-note", "my-note.md", and
+// the note's ID, title, or aliases are equivalent. Other targets match
+// unresolved links by normalized key, or verbatim with strict link matching.
 func (db *DB) Backlinks(target string) ([]string, error) {
-	rows, err := db.conn.Query(`SELECT source FROM links WHERE target = ?`, target)
+	refs, err := db.BacklinkRefs(target)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(refs))
+	var out []string
+	for _, r := range refs {
+		if _, dup := seen[r.Source]; dup {
+			continue
+		}
+		seen[r.Source] = struct{}{}
+		out = append(out, r.Source)
+	}
+	return out, nil
+}
+
+// BacklinkRef is an incoming link together with its link type and where it
+// appears in the source note: the line's text and its 1-based position
+// (all empty for frontmatter links).
+type BacklinkRef struct {
+	Source string `json:"source"`
+	// Type is the type of the first link from Source; Types lists every
+	// type Source links with, so a note linking both from its frontmatter
+	// and its body has both.
+	Type    string   `json:"type"`
+	Types   []string `json:"types"`
+	Snippet string   `json:"snippet,omitempty"`
+	Line    int      `json:"line,omitempty"`
+	Column  int      `json:"column,omitempty"`
+}
+
+// BacklinkRefs returns every link pointing at target with its type and
+// position, matched the same way as Backlinks. Links by the target note's
+// stable ID ([[<uuid>]]) and, for reference notes, by cite key
+// ([[@citekey]]) resolve to it and are included.
+func (db *DB) BacklinkRefs(target string) ([]BacklinkRef, error) {
+	resolved, err := db.resolveTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	where, args := `resolved = ?`, []any{resolved}
+	if resolved == "" {
+		where = `resolved = '' AND target_key = ?`
+		args[0] = linkKey(target)
+		if db.strictLinks {
+			where = `resolved = '' AND target = ?`
+			args[0] = target
+		}
+	}
+	rows, err := db.conn.Query(`SELECT source, type, types, snippet, line, col FROM links WHERE `+where+` ORDER BY source, type`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("index: backlinks: %w", err)
 	}
 	defer rows.Close()
 
-	var out []string
+	var out []BacklinkRef
 	for rows.Next() {
-		var s string
-		if err := rows.Scan(&s); err != nil {
+		var r BacklinkRef
+		var types string
+		if err := rows.Scan(&r.Source, &r.Type, &types, &r.Snippet, &r.Line, &r.Column); err != nil {
 			return nil, err
 		}
-		out = append(out, s)
+		r.Types = []string{r.Type}
+		if types != "" {
+			r.Types = strings.Split(types, ",")
+		}
+		out = append(out, r)
 	}
 	return out, rows.Err()
 }
 
 // MoveNote atomically updates a note's path in the index, including FTS and links.
 func (db *DB) MoveNote(oldPath, newPath string) error {
-	tx, err := db.conn.Begin()
-	if err != nil {
-		return fmt.Errorf("index: begin tx: %w", err)
-	}
-	defer tx.Rollback() //nolint:errcheck
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := db.moveNoteTx(tx, oldPath, newPath); err != nil {
+			return err
+		}
+		return db.resolveChangedLinks(tx, []string{oldPath, newPath})
+	})
+}
 
+// MoveNotesBatch atomically updates paths for multiple notes (directory rename).
+func (db *DB) MoveNotesBatch(moves []PathMove) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		paths := make([]string, 0, 2*len(moves))
+		for _, m := range moves {
+			if err := db.moveNoteTx(tx, m.OldPath, m.NewPath); err != nil {
+				return fmt.Errorf("index: batch move %s: %w", m.OldPath, err)
+			}
+			paths = append(paths, m.OldPath, m.NewPath)
+		}
+		return db.resolveChangedLinks(tx, paths)
+	})
+}
+
+// moveNoteTx re-keys a note row and everything derived from it (FTS entry,
+// resolution keys, outgoing links, and backlinks) within tx. The caller
+// re-resolves the links of both paths.
+func (db *DB) moveNoteTx(tx *sql.Tx, oldPath, newPath string) error {
 	// Read existing note data for FTS re-insert.
-	var title, body, tagsJSON, cs string
-	var updatedAt time.Time
-	err = tx.QueryRow(
-		`SELECT title, body, checksum, tags, updated_at FROM notes WHERE path = ?`, oldPath,
-	).Scan(&title, &body, &cs, &tagsJSON, &updatedAt)
+	var title, body, tagsJSON string
+	err := tx.QueryRow(
+		`SELECT title, body, tags FROM notes WHERE path = ?`, oldPath,
+	).Scan(&title, &body, &tagsJSON)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("index: move note: old path not found")
@@ -399,15 +907,9 @@ func (db *DB) MoveNote(oldPath, newPath string) error {
 		return fmt.Errorf("index: move note read: %w", err)
 	}
 
-	// Delete old note row and insert new one (path is PK, can't just UPDATE).
-	if _, err := tx.Exec(`DELETE FROM notes WHERE path = ?`, oldPath); err != nil {
-		return fmt.Errorf("index: move delete old: %w", err)
-	}
-	if _, err := tx.Exec(
-		`INSERT INTO notes (path, title, checksum, tags, body, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
-		newPath, title, cs, tagsJSON, body, updatedAt,
-	); err != nil {
-		return fmt.Errorf("index: move insert new: %w", err)
+	// Re-key the row in place so every column carries over.
+	if _, err := tx.Exec(`UPDATE notes SET path = ? WHERE path = ?`, newPath, oldPath); err != nil {
+		return fmt.Errorf("index: move note row: %w", err)
 	}
 
 	// Update FTS.
@@ -416,92 +918,55 @@ func (db *DB) MoveNote(oldPath, newPath string) error {
 	}
 	var tags []string
 	_ = json.Unmarshal([]byte(tagsJSON), &tags)
-	if err := ftsUpsert(tx, newPath, title, body, tags); err != nil {
+	if err := ftsUpsert(tx, newPath, title, body, tags, db.translitText(title, body)); err != nil {
 		return fmt.Errorf("index: move fts insert: %w", err)
 	}
 
+	if err := moveResolution(tx, oldPath, newPath); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE note_metadata SET path = ? WHERE path = ?`, newPath, oldPath); err != nil {
+		return fmt.Errorf("index: move metadata: %w", err)
+	}
+	for _, table := range []string{"properties", "tasks", "reminders", "refs", "cards", "card_review_log", "note_urls", "sections", "tags"} {
+		if _, err := tx.Exec(`UPDATE `+table+` SET path = ? WHERE path = ?`, newPath, oldPath); err != nil {
+			return fmt.Errorf("index: move %s: %w", table, err)
+		}
+	}
+	// Schedules outlive deleted notes, so stale ones may sit at newPath.
+	if _, err := tx.Exec(`UPDATE OR REPLACE card_reviews SET path = ? WHERE path = ?`, newPath, oldPath); err != nil {
+		return fmt.Errorf("index: move card_reviews: %w", err)
+	}
+
 	// Update links where this note is the source.
 	if _, err := tx.Exec(`UPDATE links SET source = ? WHERE source = ?`, newPath, oldPath); err != nil {
 		return fmt.Errorf("index: move links source: %w", err)
 	}
 	// Update links where this note is the target (backlinks).
 	// Wikilinks may store targets with or without .md extension.
-	if _, err := tx.Exec(`UPDATE links SET target = ? WHERE target = ?`, newPath, oldPath); err != nil {
+	if _, err := tx.Exec(`UPDATE links SET target = ?, target_key = ? WHERE target = ?`, newPath, normalizeKey(newPath), oldPath); err != nil {
 		return fmt.Errorf("index: move links target: %w", err)
 	}
 	oldNoExt := strings.TrimSuffix(oldPath, ".md")
 	newNoExt := strings.TrimSuffix(newPath, ".md")
 	if oldNoExt != oldPath {
-		if _, err := tx.Exec(`UPDATE links SET target = ? WHERE target = ?`, newNoExt, oldNoExt); err != nil {
+		if _, err := tx.Exec(`UPDATE links SET target = ?, target_key = ? WHERE target = ?`, newNoExt, normalizeKey(newNoExt), oldNoExt); err != nil {
 			return fmt.Errorf("index: move links target no-ext: %w", err)
 		}
 	}
-
-	return tx.Commit()
-}
-
-// MoveNotesBatch atomically updates paths for multiple notes (directory rename).
-func (db *DB) MoveNotesBatch(moves []PathMove) error {
-	tx, err := db.conn.Begin()
-	if err != nil {
-		return fmt.Errorf("index: begin tx: %w", err)
-	}
-	defer tx.Rollback() //nolint:errcheck
-
-	for _, m := range moves {
-		var title, body, tagsJSON, cs string
-		var updatedAt time.Time
-		err = tx.QueryRow(
-			`SELECT title, body, checksum, tags, updated_at FROM notes WHERE path = ?`, m.OldPath,
-		).Scan(&title, &body, &cs, &tagsJSON, &updatedAt)
-		if err != nil {
-			return fmt.Errorf("index: batch move read %s: %w", m.OldPath, err)
-		}
-		if _, err := tx.Exec(`DELETE FROM notes WHERE path = ?`, m.OldPath); err != nil {
-			return fmt.Errorf("index: batch move delete %s: %w", m.OldPath, err)
-		}
-		if _, err := tx.Exec(
-			`INSERT INTO notes (path, title, checksum, tags, body, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
-			m.NewPath, title, cs, tagsJSON, body, updatedAt,
-		); err != nil {
-			return fmt.Errorf("index: batch move insert %s: %w", m.NewPath, err)
-		}
-		if err := ftsDelete(tx, m.OldPath); err != nil {
-			return fmt.Errorf("index: batch move fts delete %s: %w", m.OldPath, err)
-		}
-		var tags []string
-		_ = json.Unmarshal([]byte(tagsJSON), &tags)
-		if err := ftsUpsert(tx, m.NewPath, title, body, tags); err != nil {
-			return fmt.Errorf("index: batch move fts insert %s: %w", m.NewPath, err)
-		}
-		if _, err := tx.Exec(`UPDATE links SET source = ? WHERE source = ?`, m.NewPath, m.OldPath); err != nil {
-			return fmt.Errorf("index: batch move links source %s: %w", m.OldPath, err)
-		}
-		if _, err := tx.Exec(`UPDATE links SET target = ? WHERE target = ?`, m.NewPath, m.OldPath); err != nil {
-			return fmt.Errorf("index: batch move links target %s: %w", m.OldPath, err)
-		}
-		oldNoExt := strings.TrimSuffix(m.OldPath, ".md")
-		newNoExt := strings.TrimSuffix(m.NewPath, ".md")
-		if oldNoExt != m.OldPath {
-			if _, err := tx.Exec(`UPDATE links SET target = ? WHERE target = ?`, newNoExt, oldNoExt); err != nil {
-				return fmt.Errorf("index: batch move links target no-ext %s: %w", m.OldPath, err)
-			}
-		}
-	}
-
-	return tx.Commit()
+	return nil
 }
 
 // PathMove represents an old→new path mapping for batch moves.
 type PathMove struct {
-	OldPath string
-	NewPath string
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
 }
 
 // NotesWithPrefix returns all notes whose path starts with the given prefix.
 func (db *DB) NotesWithPrefix(prefix string) ([]NoteRow, error) {
 	rows, err := db.conn.Query(
-		`SELECT path, title, checksum, tags, updated_at FROM notes WHERE path LIKE ?`,
+		`SELECT `+noteColumns+` FROM notes WHERE path LIKE ?`,
 		prefix+"%",
 	)
 	if err != nil {
@@ -511,13 +976,10 @@ func (db *DB) NotesWithPrefix(prefix string) ([]NoteRow, error) {
 
 	var out []NoteRow
 	for rows.Next() {
-		var n NoteRow
-		var tagsJSON string
-		if err := rows.Scan(&n.Path, &n.Title, &n.Checksum, &tagsJSON, &n.UpdatedAt); err != nil {
+		n, err := scanNote(rows)
+		if err != nil {
 			return nil, err
 		}
-		_ = json.Unmarshal([]byte(tagsJSON), &n.Tags)
-		n.Tags = nonNilSlice(n.Tags)
 		out = append(out, n)
 	}
 	return out, rows.Err()
@@ -0,0 +1,265 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Resolution kinds, in lookup priority order.
+const (
+	resolveID       = "id"
+	resolvePath     = "path"
+	resolveBasename = "basename"
+	resolveTitle    = "title"
+	resolveAlias    = "alias"
+)
+
+// separatorRe matches runs of characters that are treated as equivalent word
+// separators in note names.
+var separatorRe = regexp.MustCompile(`[\s_-]+`)
+
+// normalizeKey reduces a note name or link target to its comparison form:
+// lower-cased, without the .md extension, and with runs of spaces,
+// underscores, and dashes collapsed to a single dash. "Folder/My Note.md",
+// "folder/my-note", and "folder/My_Note" all normalize to "folder/my-note".
+func normalizeKey(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, ".md")
+	s = strings.ToLower(strings.TrimSpace(s))
+	return separatorRe.ReplaceAllString(s, "-")
+}
+
+// replaceResolution rewrites every resolution entry for a note: its stable
+// ID, path stem, basename, title, and aliases.
+func replaceResolution(tx *sql.Tx, n NoteRow) error {
+	notePath := n.Path
+	if _, err := tx.Exec(`DELETE FROM resolution WHERE path = ?`, notePath); err != nil {
+		return fmt.Errorf("index: delete resolution: %w", err)
+	}
+	if err := insertPathResolution(tx, notePath); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO resolution (key, path, kind) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("index: prepare resolution insert: %w", err)
+	}
+	defer stmt.Close()
+	if key := normalizeKey(n.ID); key != "" {
+		if _, err := stmt.Exec(key, notePath, resolveID); err != nil {
+			return fmt.Errorf("index: insert id resolution: %w", err)
+		}
+	}
+	if key := normalizeKey(n.Title); key != "" {
+		if _, err := stmt.Exec(key, notePath, resolveTitle); err != nil {
+			return fmt.Errorf("index: insert title resolution: %w", err)
+		}
+	}
+	for _, a := range n.Aliases {
+		if key := normalizeKey(a); key != "" {
+			if _, err := stmt.Exec(key, notePath, resolveAlias); err != nil {
+				return fmt.Errorf("index: insert alias resolution: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// insertPathResolution adds the path-derived keys (full stem and basename).
+func insertPathResolution(tx *sql.Tx, notePath string) error {
+	stem := normalizeKey(notePath)
+	base := normalizeKey(path.Base(notePath))
+	for _, kv := range [][2]string{{stem, resolvePath}, {base, resolveBasename}} {
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO resolution (key, path, kind) VALUES (?, ?, ?)`,
+			kv[0], notePath, kv[1],
+		); err != nil {
+			return fmt.Errorf("index: insert %s resolution: %w", kv[1], err)
+		}
+	}
+	return nil
+}
+
+// moveResolution re-keys the path-derived entries of a moved note while
+// keeping its title and alias entries.
+func moveResolution(tx *sql.Tx, oldPath, newPath string) error {
+	if _, err := tx.Exec(
+		`DELETE FROM resolution WHERE path = ? AND kind IN (?, ?)`,
+		oldPath, resolvePath, resolveBasename,
+	); err != nil {
+		return fmt.Errorf("index: move resolution delete: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE resolution SET path = ? WHERE path = ?`, newPath, oldPath); err != nil {
+		return fmt.Errorf("index: move resolution update: %w", err)
+	}
+	return insertPathResolution(tx, newPath)
+}
+
+// resolutionOrder ranks the resolution entries of one key: IDs first, then
+// full paths, basenames, titles, and aliases, with ties going to the
+// shortest path.
+const resolutionOrder = `CASE kind
+	WHEN 'id' THEN 0
+	WHEN 'path' THEN 1
+	WHEN 'basename' THEN 2
+	WHEN 'title' THEN 3
+	ELSE 4
+END, length(path), path`
+
+// linkNote is the note part of a wikilink target, without a "#heading" or
+// "#^block" suffix.
+func linkNote(target string) string {
+	note, _, _ := strings.Cut(target, "#")
+	return strings.TrimSpace(note)
+}
+
+// linkKey is the normalized key a link target resolves by.
+func linkKey(target string) string {
+	return normalizeKey(linkNote(target))
+}
+
+// resolvedLink computes the note a row of the links table points to, the
+// way ResolveLink does, from its target_key, or "" when nothing matches.
+const resolvedLink = `COALESCE((SELECT path FROM resolution
+	WHERE key = links.target_key AND links.target_key <> ''
+	ORDER BY ` + resolutionOrder + ` LIMIT 1), '')`
+
+// linkNoteSQL is linkNote of links.target, in SQL.
+const linkNoteSQL = `trim(CASE WHEN instr(links.target, '#') > 0
+	THEN substr(links.target, 1, instr(links.target, '#') - 1) ELSE links.target END)`
+
+// resolvedLinkStrict is resolvedLink under strict link matching: the note
+// part of the target must equal a note's ID, its path with or without
+// ".md", or "@" and its cite key, exactly.
+const resolvedLinkStrict = `COALESCE(
+	(SELECT path FROM notes WHERE id = ` + linkNoteSQL + ` AND id <> '' ORDER BY path LIMIT 1),
+	(SELECT path FROM notes WHERE path IN (` + linkNoteSQL + `, ` + linkNoteSQL + ` || '.md') ORDER BY path LIMIT 1),
+	(SELECT path FROM refs WHERE '@' || citekey = ` + linkNoteSQL + ` ORDER BY path LIMIT 1),
+	'')`
+
+// resolvedLinkSQL returns the resolution expression for the configured
+// link matching.
+func (db *DB) resolvedLinkSQL() string {
+	if db.strictLinks {
+		return resolvedLinkStrict
+	}
+	return resolvedLink
+}
+
+// resolveLinks recomputes links.resolved for the rows matching where.
+func (db *DB) resolveLinks(tx *sql.Tx, where string, args ...any) error {
+	if _, err := tx.Exec(`UPDATE links SET resolved = `+db.resolvedLinkSQL()+` WHERE `+where, args...); err != nil {
+		return fmt.Errorf("index: resolve links: %w", err)
+	}
+	return nil
+}
+
+// metaStrictLinks is the meta key recording whether links.resolved was
+// computed with strict link matching.
+const metaStrictLinks = "strict_links"
+
+// syncStrictLinks re-resolves every link when strict link matching was
+// switched on or off since the links were resolved.
+func (db *DB) syncStrictLinks() error {
+	want := "0"
+	if db.strictLinks {
+		want = "1"
+	}
+	var have string
+	err := db.conn.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaStrictLinks).Scan(&have)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("index: read strict links setting: %w", err)
+	}
+	if have == want || (have == "" && !db.strictLinks) {
+		return nil
+	}
+	return db.withTx(func(tx *sql.Tx) error {
+		if err := db.resolveLinks(tx, `1`); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value`, metaStrictLinks, want)
+		return err
+	})
+}
+
+// resolveAllMin is the number of changed notes from which re-resolving
+// every link is cheaper than re-resolving note by note.
+const resolveAllMin = 200
+
+// resolveChangedLinks re-resolves the links affected by writing the notes
+// at paths.
+func (db *DB) resolveChangedLinks(tx *sql.Tx, paths []string) error {
+	if len(paths) >= resolveAllMin {
+		return db.resolveLinks(tx, `1`)
+	}
+	for _, p := range paths {
+		if err := db.resolveNoteLinks(tx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveTarget resolves a note path or link target as links are resolved
+// at index time, returning "" when it matches no note.
+func (db *DB) resolveTarget(target string) (string, error) {
+	var p string
+	err := db.conn.QueryRow(`SELECT `+db.resolvedLinkSQL()+` FROM (SELECT ? AS target, ? AS target_key) AS links`,
+		target, linkKey(target)).Scan(&p)
+	if err != nil {
+		return "", fmt.Errorf("index: resolve %s: %w", target, err)
+	}
+	return p, nil
+}
+
+// resolveNoteLinks re-resolves the links a change to the note at path can
+// affect: its own, those resolved to it so far, and those whose target
+// matches one of its resolution keys. It runs after the note is written,
+// moved away, or deleted.
+func (db *DB) resolveNoteLinks(tx *sql.Tx, path string) error {
+	return db.resolveLinks(tx, `source = ?1 OR resolved = ?1
+		OR target_key IN (SELECT key FROM resolution WHERE path = ?1)`, path)
+}
+
+// ResolveLink maps a wikilink target (ID, path, basename, title, alias, or
+// "@citekey") to an indexed note path. Stable IDs win over full paths, full
+// paths over basenames, basenames over titles, and titles over aliases; ties
+// go to the shortest path.
+// Returns an empty string when nothing matches.
+func (db *DB) ResolveLink(target string) (string, error) {
+	key := normalizeKey(target)
+	if key == "" {
+		return "", nil
+	}
+	var p string
+	err := db.conn.QueryRow(`SELECT path FROM resolution WHERE key = ? ORDER BY `+resolutionOrder+` LIMIT 1`, key).Scan(&p)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("index: resolve link %s: %w", target, err)
+	}
+	return p, nil
+}
+
+// PathByID returns the path of the note whose frontmatter id matches, or an
+// empty string when no note has that ID.
+func (db *DB) PathByID(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", nil
+	}
+	var p string
+	err := db.conn.QueryRow(`SELECT path FROM notes WHERE id = ? ORDER BY path LIMIT 1`, id).Scan(&p)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("index: path by id %s: %w", id, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,167 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DefaultBusyTimeout is how long SQLite itself waits for a lock before a
+// statement fails with SQLITE_BUSY.
+const DefaultBusyTimeout = 5 * time.Second
+
+// BusyRetry controls how write transactions that fail because another
+// connection (e.g. a second kenaz process on the same index) holds the
+// database lock are retried. The first retry waits Backoff; each further one
+// waits twice as long, up to MaxBackoff.
+type BusyRetry struct {
+	// MaxRetries is the number of retries after the first attempt; 0
+	// disables retrying.
+	MaxRetries int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultBusyRetry is the retry policy of a DB opened without
+// WithBusyRetry.
+var DefaultBusyRetry = BusyRetry{MaxRetries: 5, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+
+// WithBusyTimeout sets how long SQLite waits for a lock before reporting
+// SQLITE_BUSY (default DefaultBusyTimeout).
+func WithBusyTimeout(d time.Duration) Option {
+	return func(db *DB) {
+		if d > 0 {
+			db.busyTimeout = d
+		}
+	}
+}
+
+// WithBusyRetry sets how writes failing with SQLITE_BUSY or SQLITE_LOCKED
+// are retried (default DefaultBusyRetry).
+func WithBusyRetry(r BusyRetry) Option {
+	return func(db *DB) {
+		db.retry = r
+	}
+}
+
+// BusyStats counts lock contention on writes since the index was opened.
+type BusyStats struct {
+	// Retries counts writes retried after SQLITE_BUSY or SQLITE_LOCKED.
+	Retries uint64 `json:"retries"`
+	// Failures counts writes that still failed after the last retry.
+	Failures uint64 `json:"failures"`
+}
+
+// busyCounters holds the BusyStats counters.
+type busyCounters struct {
+	retries  atomic.Uint64
+	failures atomic.Uint64
+}
+
+// BusyStats returns the lock contention counters.
+func (db *DB) BusyStats() BusyStats {
+	return BusyStats{Retries: db.busy.retries.Load(), Failures: db.busy.failures.Load()}
+}
+
+// isBusy reports whether err is SQLite's "database is locked" or "table is
+// locked".
+func isBusy(err error) bool {
+	var se sqlite3.Error
+	return errors.As(err, &se) && (se.Code == sqlite3.ErrBusy || se.Code == sqlite3.ErrLocked)
+}
+
+// acquireWrite waits until no other write of this DB runs, or until ctx
+// is done, and returns the function that ends the write. Writes queue
+// here rather than on SQLite's lock, so API, MCP, watcher, and sync writes
+// never fail with a busy error against each other; only other processes
+// on the same file can still cause one.
+func (db *DB) acquireWrite(ctx context.Context) (release func(), err error) {
+	select {
+	case db.writeSlot <- struct{}{}:
+		return func() { <-db.writeSlot }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("index: wait for write: %w", ctx.Err())
+	}
+}
+
+// retryBusy runs op as the only write of this DB, running it again with
+// backoff while it fails with a busy error, up to the configured number of
+// retries. It gives up with ctx's error once ctx is done.
+func (db *DB) retryBusy(ctx context.Context, op func() error) error {
+	release, err := db.acquireWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	wait := db.retry.Backoff
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil {
+			db.lastWrite.Store(time.Now().UnixNano())
+			return nil
+		}
+		if !isBusy(err) {
+			return err
+		}
+		if attempt >= db.retry.MaxRetries {
+			db.busy.failures.Add(1)
+			return err
+		}
+		db.busy.retries.Add(1)
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("index: retry write: %w", ctx.Err())
+		}
+		wait *= 2
+		if db.retry.MaxBackoff > 0 {
+			wait = min(wait, db.retry.MaxBackoff)
+		}
+	}
+}
+
+// withTx runs fn in a transaction and commits it, retrying the whole
+// transaction while it fails with a busy error. fn may run more than once
+// and must not have effects outside tx.
+func (db *DB) withTx(fn func(tx *sql.Tx) error) error {
+	return db.withTxContext(context.Background(), fn)
+}
+
+// withTxContext is withTx, giving up once ctx is done: while waiting for
+// other writes or between retries, and by rolling back a transaction in
+// progress.
+func (db *DB) withTxContext(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return db.retryBusy(ctx, func() error {
+		tx, err := db.conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("index: begin tx: %w", err)
+		}
+		defer tx.Rollback() //nolint:errcheck // best-effort on failure path
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		db.gen.Add(1)
+		return nil
+	})
+}
+
+// exec runs a single write statement, retrying it while it fails with a
+// busy error.
+func (db *DB) exec(query string, args ...any) error {
+	return db.retryBusy(context.Background(), func() error {
+		_, err := db.conn.Exec(query, args...)
+		return err
+	})
+}
@@ -0,0 +1,108 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBusyRetry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kenaz.db")
+	open := func(r BusyRetry) *DB {
+		db, err := Open(path, WithBusyTimeout(time.Millisecond), WithBusyRetry(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	holder := open(BusyRetry{})
+	patient := open(BusyRetry{MaxRetries: 20, Backoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	impatient := open(BusyRetry{})
+
+	// Another connection holds the write lock for a while.
+	conn, err := holder.conn.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), `BEGIN IMMEDIATE`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := impatient.RecordEdit("2026-10-16", 1, 0); err == nil || !isBusy(err) {
+		t.Fatalf("write under lock without retries: err = %v, want busy", err)
+	}
+	if s := impatient.BusyStats(); s.Retries != 0 || s.Failures != 1 {
+		t.Errorf("impatient stats = %+v", s)
+	}
+
+	// Retries stop once the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := patient.ApplyBatch(ctx, NoteBatch{Deletes: []string{"a.md"}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("write under lock with cancelled context: err = %v, want deadline exceeded", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn.ExecContext(context.Background(), `ROLLBACK`) //nolint:errcheck
+	}()
+	if err := indexFile(patient, "a.md", []byte("# A\n"), time.Now()); err != nil {
+		t.Fatalf("write retried past the lock: %v", err)
+	}
+	if s := patient.BusyStats(); s.Retries == 0 || s.Failures != 0 {
+		t.Errorf("patient stats = %+v, want retries and no failures", s)
+	}
+	if n, _ := patient.GetNote("a.md"); n == nil {
+		t.Error("note not written")
+	}
+}
+
+func TestWriteQueue(t *testing.T) {
+	db := testDB(t)
+	db.retry = BusyRetry{}
+
+	// Concurrent writers queue instead of failing on the lock.
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := fmt.Sprintf("n%d.md", i)
+			if err := indexFile(db, p, []byte("# "+p), time.Now()); err != nil {
+				t.Errorf("write %s: %v", p, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if s := db.BusyStats(); s.Retries != 0 || s.Failures != 0 {
+		t.Errorf("stats = %+v, want no contention", s)
+	}
+
+	// A write waiting for another one gives up when its context is done.
+	release, err := db.acquireWrite(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	b := NoteBatch{Deletes: []string{"n0.md"}}
+	if err := db.ApplyBatch(ctx, b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued write err = %v, want deadline exceeded", err)
+	}
+	release()
+	if n, _ := db.GetNote("n0.md"); n == nil {
+		t.Error("cancelled batch was applied")
+	}
+	if err := db.ApplyBatch(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := db.GetNote("n0.md"); n != nil {
+		t.Error("batch not applied after the other write ended")
+	}
+}
@@ -4,8 +4,12 @@ package index
 import (
 	"database/sql"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/starford/kenaz/internal/parser"
 )
 
 const coreSchemaSQL = `
@@ -27,16 +31,257 @@ CREATE TABLE IF NOT EXISTS links (
 
 CREATE INDEX IF NOT EXISTS idx_links_source ON links(source);
 CREATE INDEX IF NOT EXISTS idx_links_target ON links(target);
+
+CREATE TABLE IF NOT EXISTS resolution (
+	key  TEXT NOT NULL,
+	path TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	UNIQUE(key, path, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_resolution_key ON resolution(key);
+CREATE INDEX IF NOT EXISTS idx_resolution_path ON resolution(path);
+
+CREATE TABLE IF NOT EXISTS note_metadata (
+	path  TEXT NOT NULL,
+	key   TEXT NOT NULL,
+	value TEXT NOT NULL,
+	UNIQUE(path, key, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_metadata_key ON note_metadata(key, value);
+
+CREATE TABLE IF NOT EXISTS properties (
+	path  TEXT NOT NULL,
+	key   TEXT NOT NULL,
+	value TEXT NOT NULL COLLATE NOCASE,
+	UNIQUE(path, key, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_properties_key ON properties(key, value);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	path TEXT NOT NULL,
+	line INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	done INTEGER NOT NULL DEFAULT 0,
+	due  TEXT NOT NULL DEFAULT '',
+	UNIQUE(path, line)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_due ON tasks(due);
+
+CREATE TABLE IF NOT EXISTS reminders (
+	path TEXT NOT NULL,
+	at   INTEGER NOT NULL,
+	UNIQUE(path, at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_at ON reminders(at);
+
+CREATE TABLE IF NOT EXISTS refs (
+	path    TEXT PRIMARY KEY,
+	citekey TEXT NOT NULL,
+	doi     TEXT NOT NULL DEFAULT '',
+	data    TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refs_citekey ON refs(citekey);
+
+CREATE TABLE IF NOT EXISTS cards (
+	path     TEXT NOT NULL,
+	card     TEXT NOT NULL,
+	question TEXT NOT NULL,
+	answer   TEXT NOT NULL,
+	line     INTEGER NOT NULL,
+	UNIQUE(path, card)
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+	path TEXT NOT NULL,
+	tag  TEXT NOT NULL COLLATE NOCASE,
+	UNIQUE(path, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tags_tag ON tags(tag);
+
+CREATE TABLE IF NOT EXISTS sections (
+	path       TEXT NOT NULL,
+	seq        INTEGER NOT NULL,
+	level      INTEGER NOT NULL,
+	heading    TEXT NOT NULL,
+	anchor     TEXT NOT NULL,
+	line       INTEGER NOT NULL,
+	byte_start INTEGER NOT NULL,
+	byte_end   INTEGER NOT NULL,
+	body       TEXT NOT NULL,
+	PRIMARY KEY(path, seq)
+);
+
+CREATE TABLE IF NOT EXISTS card_reviews (
+	path          TEXT NOT NULL,
+	card          TEXT NOT NULL,
+	due           TEXT NOT NULL,
+	ease          REAL NOT NULL,
+	interval_days INTEGER NOT NULL,
+	repetitions   INTEGER NOT NULL,
+	reviews       INTEGER NOT NULL,
+	lapses        INTEGER NOT NULL,
+	reviewed_at   DATETIME NOT NULL,
+	PRIMARY KEY(path, card)
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_reviews_due ON card_reviews(due);
+
+CREATE TABLE IF NOT EXISTS card_review_log (
+	path        TEXT NOT NULL,
+	card        TEXT NOT NULL,
+	grade       INTEGER NOT NULL,
+	reviewed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_review_log_card ON card_review_log(path, card);
+
+CREATE TABLE IF NOT EXISTS graph_layout (
+	node TEXT PRIMARY KEY,
+	x    REAL NOT NULL,
+	y    REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS writing_activity (
+	day           TEXT PRIMARY KEY,
+	edits         INTEGER NOT NULL DEFAULT 0,
+	words_added   INTEGER NOT NULL DEFAULT 0,
+	words_removed INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS note_urls (
+	path TEXT NOT NULL,
+	url  TEXT NOT NULL,
+	UNIQUE(path, url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_urls_url ON note_urls(url);
+
+CREATE TABLE IF NOT EXISTS url_checks (
+	url        TEXT PRIMARY KEY,
+	status     INTEGER NOT NULL DEFAULT 0,
+	error      TEXT NOT NULL DEFAULT '',
+	checked_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS trash (
+	path       TEXT PRIMARY KEY,
+	title      TEXT NOT NULL DEFAULT '',
+	deleted_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS link_metadata (
+	url         TEXT PRIMARY KEY,
+	title       TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	image       TEXT NOT NULL DEFAULT '',
+	favicon     TEXT NOT NULL DEFAULT '',
+	error       TEXT NOT NULL DEFAULT '',
+	fetched_at  DATETIME NOT NULL
+);
 `
 
+// columnAdditions lists columns added after the initial schema. They are
+// applied with ALTER TABLE when missing, so fresh and existing databases
+// converge on the same shape.
+var columnAdditions = []struct{ table, column, decl string }{
+	{"links", "target_key", "TEXT NOT NULL DEFAULT ''"},
+	{"notes", "id", "TEXT NOT NULL DEFAULT ''"},
+	{"notes", "created_at", "DATETIME"},
+	{"links", "snippet", "TEXT NOT NULL DEFAULT ''"},
+	{"links", "line", "INTEGER NOT NULL DEFAULT 0"},
+	{"links", "col", "INTEGER NOT NULL DEFAULT 0"},
+	{"links", "count", "INTEGER NOT NULL DEFAULT 1"},
+	{"links", "types", "TEXT NOT NULL DEFAULT ''"},
+	{"links", "resolved", "TEXT NOT NULL DEFAULT ''"},
+	{"notes", "file_mtime", "INTEGER NOT NULL DEFAULT 0"},
+	{"notes", "file_size", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// lateSchemaSQL holds indexes over columns from columnAdditions.
+const lateSchemaSQL = `
+CREATE INDEX IF NOT EXISTS idx_links_target_key ON links(target_key);
+CREATE INDEX IF NOT EXISTS idx_links_resolved ON links(resolved);
+CREATE INDEX IF NOT EXISTS idx_notes_id ON notes(id);
+CREATE INDEX IF NOT EXISTS idx_notes_created_at ON notes(created_at);
+CREATE INDEX IF NOT EXISTS idx_notes_updated_jd ON notes(julianday(updated_at));
+CREATE INDEX IF NOT EXISTS idx_notes_created_jd ON notes(julianday(created_at));
+`
+
+// schemaVersion is bumped whenever a change requires existing notes to be
+// re-parsed (new derived tables or columns). Open clears stored checksums when
+// the database is older, so the next Sync re-indexes every note.
+const schemaVersion = 18
+
 // DB wraps a sql.DB with index-specific operations.
 type DB struct {
-	conn *sql.DB
+	conn        *sql.DB
+	strictLinks bool
+	linkFields  []string
+	stopWords   map[string]struct{}
+	synonyms    map[string][]string
+	translit    bool
+	extractors  []parser.Extractor
+	busyTimeout time.Duration
+	retry       BusyRetry
+	busy        busyCounters
+	// writeSlot holds a token while a write runs; see acquireWrite.
+	writeSlot chan struct{}
+	// lastWrite is when a write last succeeded, in Unix nanoseconds.
+	lastWrite atomic.Int64
+	// gen counts committed write transactions; titles is reloaded when
+	// it changes.
+	gen    atomic.Uint64
+	titles titleCache
+}
+
+// Option configures a DB.
+type Option func(*DB)
+
+// WithStrictLinks disables case- and spacing-tolerant link matching: a
+// wikilink target then resolves only when it equals a note's ID, its path
+// with or without ".md", or "@" and its cite key.
+func WithStrictLinks(strict bool) Option {
+	return func(db *DB) {
+		db.strictLinks = strict
+	}
+}
+
+// WithLinkFields sets the frontmatter fields that are indexed as typed links
+// (type "frontmatter"), e.g. related, parent, source.
+func WithLinkFields(fields []string) Option {
+	return func(db *DB) {
+		db.linkFields = fields
+	}
+}
+
+// WithExtractors sets additional parser extractors whose output is stored
+// in the note_metadata table (see NoteMetadata and MetadataValues).
+func WithExtractors(exts []parser.Extractor) Option {
+	return func(db *DB) {
+		db.extractors = exts
+	}
 }
 
 // Open opens (or creates) the SQLite database and applies the schema.
-func Open(dsn string) (*DB, error) {
-	conn, err := sql.Open("sqlite3", dsn+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+func Open(dsn string, opts ...Option) (*DB, error) {
+	db := &DB{busyTimeout: DefaultBusyTimeout, retry: DefaultBusyRetry, writeSlot: make(chan struct{}, 1)}
+	for _, opt := range opts {
+		opt(db)
+	}
+	conn, err := sql.Open("sqlite3", fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=%d&_foreign_keys=on",
+		dsn, db.busyTimeout.Milliseconds()))
 	if err != nil {
 		return nil, fmt.Errorf("index: open db: %w", err)
 	}
@@ -54,10 +299,89 @@ func Open(dsn string) (*DB, error) {
 		conn.Close()
 		return nil, fmt.Errorf("index: apply fts schema: %w", err)
 	}
-	return &DB{conn: conn}, nil
+	if err := migrate(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("index: migrate: %w", err)
+	}
+	db.conn = conn
+	if err := db.syncTranslit(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := db.syncExtractors(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := db.syncStrictLinks(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// migrate adds missing columns, then compares PRAGMA user_version with
+// schemaVersion and, for databases created by an older build, forces a full
+// re-index on the next Sync.
+func migrate(conn *sql.DB) error {
+	for _, c := range columnAdditions {
+		if err := ensureColumn(conn, c.table, c.column, c.decl); err != nil {
+			return err
+		}
+	}
+	if _, err := conn.Exec(lateSchemaSQL); err != nil {
+		return err
+	}
+
+	var version int
+	if err := conn.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= schemaVersion {
+		return nil
+	}
+	if _, err := conn.Exec(`UPDATE notes SET checksum = ''`); err != nil {
+		return err
+	}
+	_, err := conn.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion))
+	return err
 }
 
 // Close closes the underlying database connection.
 func (db *DB) Close() error {
 	return db.conn.Close()
 }
+
+// ensureColumn adds column to table unless it already exists.
+func ensureColumn(conn *sql.DB, table, column, decl string) error {
+	exists, err := hasColumn(conn, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = conn.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl))
+	return err
+}
+
+// hasColumn reports whether table has a column with the given name.
+func hasColumn(conn *sql.DB, table, column string) (bool, error) {
+	rows, err := conn.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cid        int
+			name, typ  string
+			notNull    int
+			dflt       sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &primaryKey); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,89 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// SectionRef is a heading of a note: an outline entry, or the section a
+// search hit falls in.
+type SectionRef struct {
+	Level   int    `json:"level" example:"2" validate:"required"`
+	Heading string `json:"heading" example:"Install" validate:"required"`
+	// Anchor is the heading's id, as in "#heading" link fragments.
+	Anchor string `json:"anchor" example:"install" validate:"required"`
+	Line   int    `json:"line" example:"12" validate:"required"`
+}
+
+// replaceSections replaces the sections stored for path within tx.
+func replaceSections(tx *sql.Tx, path string, sections []parser.Section) error {
+	if _, err := tx.Exec(`DELETE FROM sections WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete old sections: %w", err)
+	}
+	for i, s := range sections {
+		if _, err := tx.Exec(`
+			INSERT INTO sections (path, seq, level, heading, anchor, line, byte_start, byte_end, body)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			path, i, s.Level, s.Heading, s.Anchor, s.Line, s.Start, s.End, s.Body); err != nil {
+			return fmt.Errorf("index: insert section: %w", err)
+		}
+	}
+	return nil
+}
+
+// Outline returns the headings of the note at path in document order, or
+// nil when it is not indexed.
+func (db *DB) Outline(path string) ([]SectionRef, error) {
+	var exists int
+	if err := db.conn.QueryRow(`SELECT count(*) FROM notes WHERE path = ?`, path).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("index: outline note: %w", err)
+	}
+	if exists == 0 {
+		return nil, nil
+	}
+	rows, err := db.conn.Query(`
+		SELECT level, heading, anchor, line FROM sections
+		WHERE path = ? AND level > 0 ORDER BY seq`, path)
+	if err != nil {
+		return nil, fmt.Errorf("index: outline: %w", err)
+	}
+	defer rows.Close()
+	out := []SectionRef{}
+	for rows.Next() {
+		var s SectionRef
+		if err := rows.Scan(&s.Level, &s.Heading, &s.Anchor, &s.Line); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// matchSections sets the Section of each result to the heading its first
+// body match falls under, if any.
+func (db *DB) matchSections(results []SearchResult) error {
+	for i := range results {
+		r := &results[i]
+		at := slices.IndexFunc(r.Matches, func(m Match) bool { return m.Field == "body" })
+		if at < 0 {
+			continue
+		}
+		start := r.Matches[at].Start
+		var s SectionRef
+		err := db.conn.QueryRow(`
+			SELECT level, heading, anchor, line FROM sections
+			WHERE path = ? AND level > 0 AND byte_start <= ? AND byte_end > ?`,
+			r.Path, start, start).Scan(&s.Level, &s.Heading, &s.Anchor, &s.Line)
+		switch {
+		case err == nil:
+			r.Section = &s
+		case !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("index: match section: %w", err)
+		}
+	}
+	return nil
+}
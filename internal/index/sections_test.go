@@ -0,0 +1,55 @@
+package index
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSections(t *testing.T) {
+	db := testDB(t)
+	data := []byte("---\ntitle: Guide\n---\nIntro text.\n\n# Setup\nInstall the zebra package.\n\n## Usage\nRun it daily.\n")
+	if err := indexFile(db, "guide.md", data, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	outline, err := db.Outline("guide.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SectionRef{{1, "Setup", "setup", 6}, {2, "Usage", "usage", 9}}
+	if len(outline) != len(want) || outline[0] != want[0] || outline[1] != want[1] {
+		t.Errorf("Outline = %+v, want %+v", outline, want)
+	}
+	if outline, err := db.Outline("missing.md"); err != nil || outline != nil {
+		t.Errorf("Outline(missing) = %v, %v, want nil", outline, err)
+	}
+
+	for query, want := range map[string]string{"zebra": "setup", "daily": "usage", "intro": ""} {
+		res, _, err := db.Search(query, SearchOptions{})
+		if err != nil || len(res) != 1 {
+			t.Fatalf("Search(%q) = %+v, %v", query, res, err)
+		}
+		got := ""
+		if res[0].Section != nil {
+			got = res[0].Section.Anchor
+		}
+		if got != want {
+			t.Errorf("Search(%q) section = %q, want %q", query, got, want)
+		}
+	}
+
+	if err := db.MoveNote("guide.md", "docs/guide.md"); err != nil {
+		t.Fatal(err)
+	}
+	if outline, _ := db.Outline("docs/guide.md"); len(outline) != 2 {
+		t.Errorf("Outline after move = %+v", outline)
+	}
+	if err := db.DeleteNote("docs/guide.md"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	_ = db.conn.QueryRow(`SELECT count(*) FROM sections`).Scan(&n)
+	if n != 0 {
+		t.Errorf("%d sections left after delete", n)
+	}
+}
@@ -0,0 +1,107 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metaSyncedAt records when a Sync or Reindex last finished.
+const metaSyncedAt = "synced_at"
+
+// Stats summarizes what the index holds, to compare against the vault.
+type Stats struct {
+	Notes int `json:"notes" example:"1200" validate:"required"`
+	Links int `json:"links" example:"4800" validate:"required"`
+	// FTS reports whether search uses an FTS5 table; FTSRows counts its
+	// entries, which equals Notes when it is in step. Without FTS5,
+	// search reads the notes table and FTSRows is 0.
+	FTS     bool `json:"fts" validate:"required"`
+	FTSRows int  `json:"fts_rows" example:"1200" validate:"required"`
+	// SizeBytes is the size of the database file, free pages included.
+	SizeBytes int64 `json:"size_bytes" example:"10485760" validate:"required"`
+	// SyncedAt is when a vault sync or reindex last finished.
+	SyncedAt *time.Time `json:"synced_at,omitempty"`
+	// OptimizedAt is when index maintenance (see Optimize) last finished.
+	OptimizedAt *time.Time `json:"optimized_at,omitempty"`
+	// Folders counts the notes directly in each folder, root ("") first.
+	Folders []FolderCount `json:"folders" validate:"required"`
+}
+
+// FolderCount is the number of notes directly in a folder.
+type FolderCount struct {
+	Folder string `json:"folder" example:"projects" validate:"required"`
+	Notes  int    `json:"notes" example:"42" validate:"required"`
+}
+
+// Stats returns note, link, and full-text entry counts, the database size,
+// the last sync and maintenance times, and the notes per folder.
+func (db *DB) Stats() (*Stats, error) {
+	s := &Stats{Folders: []FolderCount{}}
+	if err := db.conn.QueryRow(`SELECT (SELECT count(*) FROM notes), (SELECT count(*) FROM links)`).Scan(&s.Notes, &s.Links); err != nil {
+		return nil, fmt.Errorf("index: stats counts: %w", err)
+	}
+	var err error
+	if s.FTSRows, s.FTS, err = db.ftsCount(); err != nil {
+		return nil, fmt.Errorf("index: stats fts: %w", err)
+	}
+	if s.SizeBytes, err = db.size(); err != nil {
+		return nil, err
+	}
+	if s.SyncedAt, err = db.metaTime(metaSyncedAt); err != nil {
+		return nil, err
+	}
+	if s.OptimizedAt, err = db.metaTime(metaOptimizedAt); err != nil {
+		return nil, err
+	}
+
+	// rtrim strips the file name, leaving the folder with a trailing "/".
+	rows, err := db.conn.Query(`
+		SELECT rtrim(path, replace(path, '/', '')) AS folder, count(*) FROM notes
+		GROUP BY folder ORDER BY folder`)
+	if err != nil {
+		return nil, fmt.Errorf("index: stats folders: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f FolderCount
+		if err := rows.Scan(&f.Folder, &f.Notes); err != nil {
+			return nil, err
+		}
+		f.Folder = strings.TrimSuffix(f.Folder, "/")
+		s.Folders = append(s.Folders, f)
+	}
+	return s, rows.Err()
+}
+
+// metaTime returns the time recorded in meta under key as Unix seconds,
+// or nil if none is.
+func (db *DB) metaTime(key string) (*time.Time, error) {
+	var v string
+	err := db.conn.QueryRow(`SELECT value FROM meta WHERE key = ?`, key).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("index: read %s: %w", key, err)
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	t := time.Unix(sec, 0).UTC()
+	return &t, nil
+}
+
+// setSyncedAt records that the index was synced with the vault at t.
+func (db *DB) setSyncedAt(t time.Time) error {
+	err := db.exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, metaSyncedAt, strconv.FormatInt(t.Unix(), 10))
+	if err != nil {
+		return fmt.Errorf("index: save sync time: %w", err)
+	}
+	return nil
+}
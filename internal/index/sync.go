@@ -1,9 +1,12 @@
 package index
 
 import (
+	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/starford/kenaz/internal/checksum"
+	"github.com/starford/kenaz/internal/models"
 	"github.com/starford/kenaz/internal/parser"
 	"github.com/starford/kenaz/internal/storage"
 )
@@ -11,22 +14,31 @@ import (
 // Sync walks the vault and brings the index up to date:
 //   - new/changed files are parsed and upserted
 //   - files removed from disk are deleted from the index
+//
+// Files whose modification time and size match the ones stored when they
+// were last indexed are skipped without being read. Others are read and
+// hashed, and only re-parsed when their checksum changed. Changes count as
+// writing activity, except on the first Sync of an empty index.
+//
+// The time it finishes is reported by Stats.
 func Sync(db *DB, store storage.Provider, logger *slog.Logger) error {
-	metas, err := store.List("")
+	metas, err := store.ListStat("")
 	if err != nil {
 		return err
 	}
 
-	checksums, err := db.AllChecksums()
+	stamps, err := db.fileStamps()
 	if err != nil {
 		return err
 	}
 
+	track := len(stamps) > 0
 	disk := make(map[string]struct{}, len(metas))
 	for _, m := range metas {
 		disk[m.Path] = struct{}{}
 
-		if checksums[m.Path] == m.Checksum {
+		st, known := stamps[m.Path]
+		if known && st.matches(m) {
 			continue
 		}
 
@@ -35,7 +47,14 @@ func Sync(db *DB, store storage.Provider, logger *slog.Logger) error {
 			logger.Warn("sync: read failed", slog.String("path", m.Path), slog.String("error", err.Error()))
 			continue
 		}
-		if err := indexFile(db, m.Path, data); err != nil {
+		if known && st.checksum == checksum.Sum(data) {
+			// Touched but unchanged: only record the new stamp.
+			if err := db.setFileStamp(m); err != nil {
+				logger.Warn("sync: stamp failed", slog.String("path", m.Path), slog.String("error", err.Error()))
+			}
+			continue
+		}
+		if err := indexNote(db, m, data, track); err != nil {
 			logger.Warn("sync: index failed", slog.String("path", m.Path), slog.String("error", err.Error()))
 		} else {
 			logger.Debug("sync: indexed", slog.String("path", m.Path))
@@ -43,7 +62,7 @@ func Sync(db *DB, store storage.Provider, logger *slog.Logger) error {
 	}
 
 	// Remove stale entries.
-	for p := range checksums {
+	for p := range stamps {
 		if _, ok := disk[p]; !ok {
 			if err := db.DeleteNote(p); err != nil {
 				logger.Warn("sync: delete failed", slog.String("path", p), slog.String("error", err.Error()))
@@ -53,22 +72,127 @@ func Sync(db *DB, store storage.Provider, logger *slog.Logger) error {
 		}
 	}
 
+	if err := db.setSyncedAt(time.Now()); err != nil {
+		logger.Warn("sync: record sync time failed", slog.String("error", err.Error()))
+	}
 	return nil
 }
 
-// indexFile parses data and upserts it into the DB.
-func indexFile(db *DB, path string, data []byte) error {
-	res, err := parser.Parse(data)
+// Parse parses note data using the DB's configured parser options
+// (frontmatter link fields and extractors).
+func (db *DB) Parse(data []byte) (*parser.Result, error) {
+	return parser.Parse(data, parser.WithLinkFields(db.linkFields...), parser.WithExtractors(db.extractors...))
+}
+
+// NoteLinks returns the typed outgoing links of a parsed note: inline
+// wikilinks first, then frontmatter links.
+func NoteLinks(res *parser.Result) []Link {
+	out := make([]Link, 0, len(res.Links)+len(res.FrontmatterLinks))
+	for _, r := range res.LinkRefs {
+		out = append(out, Link{Target: r.Target, Type: LinkInline, Snippet: r.Snippet, Line: r.Line, Column: r.Column, Count: r.Count})
+	}
+	for _, l := range res.FrontmatterLinks {
+		out = append(out, Link{Target: l, Type: LinkFrontmatter})
+	}
+	return out
+}
+
+// indexFile parses data and upserts it into the DB. modTime is recorded as
+// the note's updated_at.
+func indexFile(db *DB, path string, data []byte, modTime time.Time) error {
+	n, err := db.parseNote(models.NoteMetadata{Path: path, UpdatedAt: modTime}, data)
+	if err != nil {
+		return err
+	}
+	return db.UpsertNoteLinks(n.Row, n.Body, n.Links)
+}
+
+// indexNote parses data, the content of the listed file m, and upserts it
+// into the DB with m's modification time and size, which let later Syncs
+// skip the file while it is unchanged. With track, a change counts as
+// writing activity.
+func indexNote(db *DB, m models.NoteMetadata, data []byte, track bool) error {
+	n, err := db.parseNote(m, data)
 	if err != nil {
 		return err
 	}
+	n.Row.FileModTime, n.Row.FileSize = m.UpdatedAt, m.Size
+	return db.upsertNoteLinks(n.Row, n.Body, n.Links, track)
+}
+
+// parseNote parses data into the row, body, and links stored for the note
+// described by m. Its modification time is recorded as updated_at, and its
+// birth time is the creation date when the frontmatter sets none.
+func (db *DB) parseNote(m models.NoteMetadata, data []byte) (IndexedNote, error) {
+	res, err := db.Parse(data)
+	if err != nil {
+		return IndexedNote{}, err
+	}
 	cs := checksum.Sum(data)
 
 	row := NoteRow{
-		Path:     path,
-		Title:    res.Title,
-		Checksum: cs,
-		Tags:     res.Tags,
+		Path:          m.Path,
+		ID:            res.ID,
+		Title:         res.Title,
+		Checksum:      cs,
+		Tags:          res.Tags,
+		Aliases:       res.Aliases,
+		UpdatedAt:     m.UpdatedAt,
+		CreatedAt:     res.Created,
+		FileCreatedAt: m.CreatedAt,
+		Metadata:      res.Metadata,
+		Properties:    NoteProperties(res.Frontmatter),
+		Tasks:         res.Tasks,
+		Reminders:     res.Reminders,
+		Reference:     res.Reference,
+		Cards:         res.Cards,
+		URLs:          parser.URLs(res),
+		Sections:      res.Sections,
 	}
-	return db.UpsertNote(row, res.Body, res.Links)
+	return IndexedNote{Row: row, Body: res.Body, Links: NoteLinks(res)}, nil
+}
+
+// fileStamp is what Sync compares a listed file against: the checksum,
+// modification time (Unix nanoseconds, 0 when unknown), and size stored
+// when the note was last indexed.
+type fileStamp struct {
+	checksum string
+	mtime    int64
+	size     int64
+}
+
+// matches reports whether the listed file m is unchanged since the note
+// was indexed. An empty checksum, as left by a forced re-index, never
+// matches.
+func (s fileStamp) matches(m models.NoteMetadata) bool {
+	return s.checksum != "" && s.mtime != 0 && s.mtime == m.UpdatedAt.UnixNano() && s.size == m.Size
+}
+
+// fileStamps returns the stamp of every indexed note by path.
+func (db *DB) fileStamps() (map[string]fileStamp, error) {
+	rows, err := db.conn.Query(`SELECT path, checksum, file_mtime, file_size FROM notes`)
+	if err != nil {
+		return nil, fmt.Errorf("index: file stamps: %w", err)
+	}
+	defer rows.Close()
+	out := make(map[string]fileStamp)
+	for rows.Next() {
+		var p string
+		var s fileStamp
+		if err := rows.Scan(&p, &s.checksum, &s.mtime, &s.size); err != nil {
+			return nil, err
+		}
+		out[p] = s
+	}
+	return out, rows.Err()
+}
+
+// setFileStamp records the modification time and size of the listed file
+// m for its note.
+func (db *DB) setFileStamp(m models.NoteMetadata) error {
+	err := db.exec(`UPDATE notes SET file_mtime = ?, file_size = ? WHERE path = ?`, m.UpdatedAt.UnixNano(), m.Size, m.Path)
+	if err != nil {
+		return fmt.Errorf("index: set file stamp: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,23 @@
+package index
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// replaceTags replaces the tags stored for path within tx.
+func replaceTags(tx *sql.Tx, path string, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM tags WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete old tags: %w", err)
+	}
+	for _, t := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (path, tag) VALUES (?, ?)`, path, t); err != nil {
+			return fmt.Errorf("index: insert tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// tagClause is the SQL condition on notes.path matching notes with the tag
+// given as its argument, case-insensitively.
+const tagClause = `notes.path IN (SELECT path FROM tags WHERE tag = ?)`
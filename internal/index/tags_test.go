@@ -0,0 +1,66 @@
+package index
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTagFilters(t *testing.T) {
+	db := testDB(t)
+	for path, content := range map[string]string{
+		"a.md": "---\ntags: [go, Work]\n---\nA note.\n",
+		"b.md": "B is about #golang.\n",
+		"c.md": "C about #go_lang and #Go.\n",
+	} {
+		if err := indexFile(db, path, []byte(content), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(tag string) []string {
+		t.Helper()
+		rows, total, err := db.ListNotes(0, 0, tag, "path", NoteFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.Path)
+		}
+		if total != len(out) {
+			t.Errorf("ListNotes(%q) total = %d, want %d", tag, total, len(out))
+		}
+		slices.Sort(out)
+		return out
+	}
+	for tag, want := range map[string][]string{
+		"go":     {"a.md", "c.md"},
+		"work":   {"a.md"},
+		"golang": {"b.md"},
+		"go%":    nil,
+		"go_ang": nil,
+	} {
+		if got := list(tag); !slices.Equal(got, want) {
+			t.Errorf("ListNotes(tag %q) = %v, want %v", tag, got, want)
+		}
+	}
+
+	page, err := db.ListNotesCursor(10, "", "GO", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Notes) != 2 || page.Notes[0].Path != "a.md" || page.Notes[1].Path != "c.md" {
+		t.Errorf("ListNotesCursor(tag GO) = %+v", page.Notes)
+	}
+
+	if err := db.MoveNote("a.md", "x/a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteNote("c.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got := list("go"); !slices.Equal(got, []string{"x/a.md"}) {
+		t.Errorf("after move and delete = %v, want [x/a.md]", got)
+	}
+}
@@ -0,0 +1,69 @@
+package index
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NoteTitle is what a quick switcher matches a note by. Aliases are the
+// note's alias keys from the resolution table, normalized like link
+// targets (see normalizeKey).
+type NoteTitle struct {
+	Path    string
+	Title   string
+	Aliases []string
+}
+
+// titleCache keeps every note's NoteTitle in memory so lookups on each
+// keystroke do not query SQLite.
+type titleCache struct {
+	mu      sync.Mutex
+	gen     uint64
+	loaded  bool
+	entries []NoteTitle
+}
+
+// NoteTitles returns the path, title, and aliases of every note, sorted by
+// path. The list is cached and reloaded only after index writes; callers
+// must not modify it.
+func (db *DB) NoteTitles() ([]NoteTitle, error) {
+	c := &db.titles
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Read the generation before loading: a write committed meanwhile
+	// bumps it, so the next call reloads.
+	gen := db.gen.Load()
+	if c.loaded && c.gen == gen {
+		return c.entries, nil
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT n.path, n.title, COALESCE(r.key, '') FROM notes n
+		LEFT JOIN resolution r ON r.path = n.path AND r.kind = ?
+		ORDER BY n.path, r.rowid
+	`, resolveAlias)
+	if err != nil {
+		return nil, fmt.Errorf("index: note titles: %w", err)
+	}
+	defer rows.Close()
+	var out []NoteTitle
+	for rows.Next() {
+		var t NoteTitle
+		var alias string
+		if err := rows.Scan(&t.Path, &t.Title, &alias); err != nil {
+			return nil, fmt.Errorf("index: scan note title: %w", err)
+		}
+		if len(out) == 0 || out[len(out)-1].Path != t.Path {
+			out = append(out, t)
+		}
+		if alias != "" {
+			last := &out[len(out)-1]
+			last.Aliases = append(last.Aliases, alias)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("index: note titles: %w", err)
+	}
+	c.entries, c.gen, c.loaded = out, gen, true
+	return out, nil
+}
@@ -0,0 +1,81 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/starford/kenaz/internal/slug"
+)
+
+// WithTransliteration makes full-text search match across Cyrillic and Latin
+// spellings: the Latin transliteration of every Cyrillic word is indexed
+// alongside the note, so "kenaz" finds "кеназ", and Cyrillic query terms
+// also match their transliteration, so "кеназ" finds "kenaz".
+func WithTransliteration(enabled bool) Option {
+	return func(db *DB) {
+		db.translit = enabled
+	}
+}
+
+// metaTranslit is the meta key recording whether the FTS entries were built
+// with transliteration.
+const metaTranslit = "fts_translit"
+
+// syncTranslit clears stored checksums when transliteration was switched on
+// or off since the FTS entries were built, so the next Sync re-indexes every
+// note with the new setting.
+func (db *DB) syncTranslit() error {
+	want := "0"
+	if db.translit {
+		want = "1"
+	}
+	var have string
+	err := db.conn.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaTranslit).Scan(&have)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("index: read translit setting: %w", err)
+	}
+	if have == want || (have == "" && !db.translit) {
+		return nil
+	}
+	if _, err := db.conn.Exec(`UPDATE notes SET checksum = ''`); err != nil {
+		return fmt.Errorf("index: reset checksums: %w", err)
+	}
+	_, err = db.conn.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, metaTranslit, want)
+	return err
+}
+
+// translitText returns the distinct Latin transliterations of the Cyrillic
+// words in texts, space-separated, or "" when transliteration is off.
+func (db *DB) translitText(texts ...string) string {
+	if !db.translit {
+		return ""
+	}
+	seen := make(map[string]struct{})
+	var out []string
+	for _, text := range texts {
+		words := strings.FieldsFunc(text, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			if !hasCyrillic(w) {
+				continue
+			}
+			lat := slug.Transliterate(w)
+			if _, dup := seen[lat]; dup {
+				continue
+			}
+			seen[lat] = struct{}{}
+			out = append(out, lat)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// hasCyrillic reports whether s contains a Cyrillic letter.
+func hasCyrillic(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return unicode.Is(unicode.Cyrillic, r) }) >= 0
+}
@@ -0,0 +1,76 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TrashEntry is a file moved to the trash.
+type TrashEntry struct {
+	// Path is where the file was before it was deleted.
+	Path string `json:"path" example:"projects/old-plan.md" validate:"required"`
+	// Title is the note title at deletion, "" for attachments.
+	Title     string    `json:"title,omitempty" example:"Old plan"`
+	DeletedAt time.Time `json:"deleted_at" validate:"required"`
+}
+
+// AddTrash records entries as trashed, replacing earlier entries for the
+// same paths.
+func (db *DB) AddTrash(entries []TrashEntry) error {
+	return db.withTx(func(tx *sql.Tx) error {
+		return addTrash(tx, entries)
+	})
+}
+
+// addTrash records entries within tx.
+func addTrash(tx *sql.Tx, entries []TrashEntry) error {
+	for _, e := range entries {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO trash (path, title, deleted_at) VALUES (?, ?, ?)`,
+			e.Path, e.Title, e.DeletedAt); err != nil {
+			return fmt.Errorf("index: add trash: %w", err)
+		}
+	}
+	return nil
+}
+
+// Trash returns the trashed files, most recently deleted first.
+func (db *DB) Trash() ([]TrashEntry, error) {
+	rows, err := db.conn.Query(`SELECT path, title, deleted_at FROM trash ORDER BY deleted_at DESC, path`)
+	if err != nil {
+		return nil, fmt.Errorf("index: trash: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+	out := []TrashEntry{}
+	for rows.Next() {
+		var e TrashEntry
+		if err := rows.Scan(&e.Path, &e.Title, &e.DeletedAt); err != nil {
+			return nil, fmt.Errorf("index: scan trash: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// TrashEntry returns the trash entry for path, or nil if path is not in the
+// trash.
+func (db *DB) TrashEntry(path string) (*TrashEntry, error) {
+	e := TrashEntry{Path: path}
+	err := db.conn.QueryRow(`SELECT title, deleted_at FROM trash WHERE path = ?`, path).Scan(&e.Title, &e.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("index: trash entry: %w", err)
+	}
+	return &e, nil
+}
+
+// RemoveTrash drops the trash entry for path.
+func (db *DB) RemoveTrash(path string) error {
+	if err := db.exec(`DELETE FROM trash WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: remove trash: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package index
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// URLCheck is the outcome of checking an external URL.
+type URLCheck struct {
+	URL string
+	// Status is the HTTP status answered, 0 when the request failed.
+	Status int
+	// Error is why the request failed.
+	Error     string
+	CheckedAt time.Time
+}
+
+// DeadLink is an external URL whose last check answered 404 or 410 or
+// failed, with the notes linking to it.
+type DeadLink struct {
+	URL string `json:"url" example:"https://example.com/gone" validate:"required"`
+	// Status is the HTTP status of the last check, 0 when it failed.
+	Status int `json:"status" example:"404" validate:"required"`
+	// Error is why the last check failed, e.g. a timeout.
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at" validate:"required"`
+	// Notes are the paths of the notes linking to URL.
+	Notes []string `json:"notes" validate:"required"`
+}
+
+// deadCheck selects url_checks rows c of dead links.
+const deadCheck = `(c.status IN (404, 410) OR c.error != '')`
+
+// replaceURLs replaces the external URLs stored for path within tx.
+func replaceURLs(tx *sql.Tx, path string, urls []string) error {
+	if _, err := tx.Exec(`DELETE FROM note_urls WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("index: delete old urls: %w", err)
+	}
+	for _, u := range urls {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO note_urls (path, url) VALUES (?, ?)`, path, u); err != nil {
+			return fmt.Errorf("index: insert url: %w", err)
+		}
+	}
+	return nil
+}
+
+// URLsToCheck returns up to limit distinct URLs linked from notes that were
+// never checked or last checked before before, never-checked ones first,
+// then the oldest checks.
+func (db *DB) URLsToCheck(before time.Time, limit int) ([]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT u.url FROM note_urls u
+		LEFT JOIN url_checks c ON c.url = u.url
+		WHERE c.url IS NULL OR c.checked_at < ?
+		GROUP BY u.url
+		ORDER BY c.checked_at IS NOT NULL, c.checked_at, u.url
+		LIMIT ?`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("index: urls to check: %w", err)
+	}
+	return scanStrings(rows)
+}
+
+// NotesLinkingURL returns the paths of the notes linking to url.
+func (db *DB) NotesLinkingURL(url string) ([]string, error) {
+	rows, err := db.conn.Query(`SELECT path FROM note_urls WHERE url = ? ORDER BY path`, url)
+	if err != nil {
+		return nil, fmt.Errorf("index: notes linking url: %w", err)
+	}
+	return scanStrings(rows)
+}
+
+// SaveURLCheck records the result of checking c.URL, replacing the previous
+// one.
+func (db *DB) SaveURLCheck(c URLCheck) error {
+	if err := db.exec(`INSERT OR REPLACE INTO url_checks (url, status, error, checked_at) VALUES (?, ?, ?, ?)`,
+		c.URL, c.Status, c.Error, c.CheckedAt); err != nil {
+		return fmt.Errorf("index: save url check: %w", err)
+	}
+	return nil
+}
+
+// DeadLinks returns the URLs still linked from notes whose last check
+// answered 404 or 410 or failed, by URL.
+func (db *DB) DeadLinks() ([]DeadLink, error) {
+	rows, err := db.conn.Query(`
+		SELECT c.url, c.status, c.error, c.checked_at, u.path
+		FROM url_checks c JOIN note_urls u ON u.url = c.url
+		WHERE ` + deadCheck + `
+		ORDER BY c.url, u.path`)
+	if err != nil {
+		return nil, fmt.Errorf("index: dead links: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	out := []DeadLink{}
+	for rows.Next() {
+		var d DeadLink
+		var path string
+		if err := rows.Scan(&d.URL, &d.Status, &d.Error, &d.CheckedAt, &path); err != nil {
+			return nil, fmt.Errorf("index: scan dead link: %w", err)
+		}
+		if n := len(out); n > 0 && out[n-1].URL == d.URL {
+			out[n-1].Notes = append(out[n-1].Notes, path)
+			continue
+		}
+		d.Notes = []string{path}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// URLCheckCounts returns the number of distinct URLs linked from notes and
+// how many of them have been checked.
+func (db *DB) URLCheckCounts() (urls, checked int, err error) {
+	err = db.conn.QueryRow(`
+		SELECT count(DISTINCT u.url), count(DISTINCT c.url)
+		FROM note_urls u LEFT JOIN url_checks c ON c.url = u.url`).Scan(&urls, &checked)
+	if err != nil {
+		return 0, 0, fmt.Errorf("index: url check counts: %w", err)
+	}
+	return urls, checked, nil
+}
+
+// scanStrings reads a single text column from rows and closes them.
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer rows.Close() //nolint:errcheck
+	out := []string{}
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, fmt.Errorf("index: scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,62 @@
+package index
+
+import (
+	"testing"
+	"time"
+)
+
+func TestURLChecks(t *testing.T) {
+	db := testDB(t)
+	now := time.Now()
+	if err := indexFile(db, "a.md", []byte("See https://gone.example/x and https://ok.example/\n"), now); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexFile(db, "b.md", []byte("Also https://gone.example/x\n"), now); err != nil {
+		t.Fatal(err)
+	}
+
+	urls, err := db.URLsToCheck(now, 10)
+	if err != nil || len(urls) != 2 {
+		t.Fatalf("to check = %v, %v; want both URLs", urls, err)
+	}
+	if err := db.SaveURLCheck(URLCheck{URL: "https://gone.example/x", Status: 404, CheckedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveURLCheck(URLCheck{URL: "https://ok.example/", Status: 200, CheckedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if urls, _ := db.URLsToCheck(now.Add(-time.Hour), 10); len(urls) != 0 {
+		t.Errorf("fresh checks still due: %v", urls)
+	}
+	if urls, _ := db.URLsToCheck(now.Add(time.Hour), 1); len(urls) != 1 {
+		t.Errorf("limit ignored: %v", urls)
+	}
+
+	dead, err := db.DeadLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].URL != "https://gone.example/x" || dead[0].Status != 404 || len(dead[0].Notes) != 2 {
+		t.Fatalf("dead = %+v", dead)
+	}
+	if n, c, err := db.URLCheckCounts(); err != nil || n != 2 || c != 2 {
+		t.Errorf("counts = %d, %d, %v", n, c, err)
+	}
+
+	// Moving and deleting notes carry their URLs along.
+	if err := db.MoveNote("b.md", "archive/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if notes, _ := db.NotesLinkingURL("https://gone.example/x"); len(notes) != 2 || notes[1] != "archive/b.md" {
+		t.Errorf("after move: %v", notes)
+	}
+	if err := db.DeleteNote("a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteNote("archive/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if dead, _ := db.DeadLinks(); len(dead) != 0 {
+		t.Errorf("dead after delete = %+v", dead)
+	}
+}
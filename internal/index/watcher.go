@@ -60,7 +60,7 @@ func Watch(ctx context.Context, db *DB, store storage.Provider, vaultRoot string
 			return nil
 
 		case <-reconcileCh:
-			reconcileAfterRename(db, store, logger, cb)
+			reconcile(db, store, logger, cb)
 
 		case ev, ok := <-w.Events:
 			if !ok {
@@ -91,7 +91,7 @@ func Watch(ctx context.Context, db *DB, store storage.Provider, vaultRoot string
 			}
 
 			rel, relErr := filepath.Rel(vaultRoot, absPath)
-			if relErr != nil {
+			if relErr != nil || storage.InReserved(filepath.ToSlash(rel)) {
 				continue
 			}
 
@@ -102,7 +102,7 @@ func Watch(ctx context.Context, db *DB, store storage.Provider, vaultRoot string
 					logger.Warn("watcher: read failed", slog.String("path", rel), slog.String("error", readErr.Error()))
 					continue
 				}
-				if idxErr := indexFile(db, rel, data); idxErr != nil {
+				if idxErr := indexFile(db, rel, data, time.Now()); idxErr != nil {
 					logger.Warn("watcher: index failed", slog.String("path", rel), slog.String("error", idxErr.Error()))
 					continue
 				}
@@ -151,10 +151,10 @@ func Watch(ctx context.Context, db *DB, store storage.Provider, vaultRoot string
 	}
 }
 
-// reconcileAfterRename does a lightweight sync using batch lookups:
-// finds index entries without a corresponding file on disk and removes them,
-// and finds on-disk files that are not indexed and indexes them.
-func reconcileAfterRename(db *DB, store storage.Provider, logger *slog.Logger, cb EventCallback) {
+// reconcile does a lightweight sync using batch lookups: finds index
+// entries without a corresponding file in the vault and removes them, and
+// indexes files that are new or whose checksum changed.
+func reconcile(db *DB, store storage.Provider, logger *slog.Logger, cb EventCallback) {
 	checksums, err := db.AllChecksums()
 	if err != nil {
 		logger.Warn("reconcile: all checksums failed", slog.String("error", err.Error()))
@@ -184,17 +184,22 @@ func reconcileAfterRename(db *DB, store storage.Provider, logger *slog.Logger, c
 	}
 
 	for p, cs := range disk {
-		if checksums[p] == cs {
+		old, known := checksums[p]
+		if old == cs {
 			continue
 		}
 		data, readErr := store.Read(p)
 		if readErr != nil {
 			continue
 		}
-		if idxErr := indexFile(db, p, data); idxErr == nil {
-			logger.Debug("reconcile: indexed new", slog.String("path", p))
+		if idxErr := indexFile(db, p, data, time.Now()); idxErr == nil {
+			kind := "created"
+			if known {
+				kind = "updated"
+			}
+			logger.Debug("reconcile: indexed", slog.String("path", p), slog.String("op", kind))
 			if cb != nil {
-				cb("created", p)
+				cb(kind, p)
 			}
 		}
 	}
@@ -207,14 +212,14 @@ func indexNewDir(db *DB, store storage.Provider, vaultRoot, dirPath string, logg
 			return nil
 		}
 		rel, relErr := filepath.Rel(vaultRoot, path)
-		if relErr != nil {
+		if relErr != nil || storage.InReserved(filepath.ToSlash(rel)) {
 			return nil
 		}
 		data, readErr := store.Read(rel)
 		if readErr != nil {
 			return nil
 		}
-		if idxErr := indexFile(db, rel, data); idxErr == nil {
+		if idxErr := indexFile(db, rel, data, time.Now()); idxErr == nil {
 			logger.Debug("watcher: indexed from new dir", slog.String("path", rel))
 			if cb != nil {
 				cb("created", rel)
@@ -231,6 +236,9 @@ func addDirsRecursive(w *fsnotify.Watcher, root string) error {
 			return err
 		}
 		if d.IsDir() {
+			if storage.ReservedDir(d.Name()) {
+				return fs.SkipDir
+			}
 			return w.Add(path)
 		}
 		return nil
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -157,3 +158,95 @@ func TestWatcher_RenameReconciles(t *testing.T) {
 		return oldCS == "" && newCS != ""
 	}, "rename reconciliation failed: old path should be removed and new path indexed")
 }
+
+func TestPoll_DetectsChanges(t *testing.T) {
+	vaultDir, store, db := watcherTestEnv(t)
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	if err := store.Write("keep.md", []byte("# Keep")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Write("gone.md", []byte("# Gone")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(db, store, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	events := map[string]string{}
+	go Poll(ctx, db, store, 20*time.Millisecond, logger, func(kind, path string) {
+		mu.Lock()
+		events[path] = kind
+		mu.Unlock()
+	})
+
+	_ = os.WriteFile(filepath.Join(vaultDir, "keep.md"), []byte("# Keep\nedited"), 0o644)
+	_ = os.WriteFile(filepath.Join(vaultDir, "new.md"), []byte("# New"), 0o644)
+	_ = os.Remove(filepath.Join(vaultDir, "gone.md"))
+
+	eventually(t, 2*time.Second, 20*time.Millisecond, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 3
+	}, "poll did not report all changes")
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]string{"keep.md": "updated", "new.md": "created", "gone.md": "deleted"}
+	for p, kind := range want {
+		if events[p] != kind {
+			t.Errorf("%s: event %q, want %q", p, events[p], kind)
+		}
+	}
+}
+
+// readCounter counts the files read through a storage.Provider.
+type readCounter struct {
+	storage.Provider
+	reads []string
+}
+
+func (r *readCounter) Read(path string) ([]byte, error) {
+	r.reads = append(r.reads, path)
+	return r.Provider.Read(path)
+}
+
+func TestSync_SkipsUnchangedFiles(t *testing.T) {
+	vaultDir, store, db := watcherTestEnv(t)
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	_ = os.WriteFile(filepath.Join(vaultDir, "a.md"), []byte("# A"), 0o644)
+	_ = os.WriteFile(filepath.Join(vaultDir, "b.md"), []byte("# B"), 0o644)
+
+	syncReads := func(want ...string) {
+		t.Helper()
+		rc := &readCounter{Provider: store}
+		if err := Sync(db, rc, logger); err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(rc.reads)
+		if !slices.Equal(rc.reads, want) {
+			t.Errorf("read %v, want %v", rc.reads, want)
+		}
+	}
+	syncReads("a.md", "b.md")
+	syncReads()
+
+	// A touched file is hashed once, then skipped again.
+	later := time.Now().Add(time.Hour)
+	_ = os.Chtimes(filepath.Join(vaultDir, "a.md"), later, later)
+	syncReads("a.md")
+	syncReads()
+
+	_ = os.WriteFile(filepath.Join(vaultDir, "b.md"), []byte("# B edited"), 0o644)
+	syncReads("b.md")
+	if n, _ := db.GetNote("b.md"); n == nil || n.Title != "B edited" {
+		t.Errorf("b.md = %+v, want title %q", n, "B edited")
+	}
+
+	// Cleared checksums force every file to be read again.
+	if _, err := db.conn.Exec(`UPDATE notes SET checksum = ''`); err != nil {
+		t.Fatal(err)
+	}
+	syncReads("a.md", "b.md")
+}
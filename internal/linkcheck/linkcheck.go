@@ -0,0 +1,52 @@
+// Package linkcheck checks the external links in notes in the background.
+package linkcheck
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// batchSize caps the URLs checked per tick, so a large vault is worked
+// through over several ticks instead of flooding remote hosts.
+const batchSize = 100
+
+// Checker checks up to limit URLs last checked more than maxAge ago and
+// returns how many it checked. noteservice.Service satisfies it.
+type Checker interface {
+	CheckStaleLinks(ctx context.Context, maxAge time.Duration, limit int) (int, error)
+}
+
+// Run checks stale links until ctx is cancelled: once at start and then
+// every interval. Results are kept in the index, so a restart picks up
+// where the previous run stopped.
+func Run(ctx context.Context, c Checker, interval, maxAge time.Duration, logger *slog.Logger) error {
+	logger.Info("link check: started",
+		slog.String("interval", interval.String()),
+		slog.String("max_age", maxAge.String()))
+
+	check := func() {
+		n, err := c.CheckStaleLinks(ctx, maxAge, batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("link check: failed", slog.String("error", err.Error()))
+			}
+			return
+		}
+		if n > 0 {
+			logger.Info("link check: checked links", slog.Int("count", n))
+		}
+	}
+
+	check()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			check()
+		}
+	}
+}
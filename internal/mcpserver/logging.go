@@ -0,0 +1,75 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxLoggedArgRunes caps string arguments in the call log; longer values
+// (note content, data URIs) are logged by length only.
+const maxLoggedArgRunes = 80
+
+// WithLogger logs every tool call to l: tool name, an argument summary,
+// duration, and outcome ("ok", "tool_error" for errors reported to the
+// client, or "error").
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) { s.logger = l }
+}
+
+// logCalls is tool handler middleware that writes one record per call to
+// the server's logger.
+func (s *Server) logCalls(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		res, err := next(ctx, req)
+
+		lvl, outcome := slog.LevelInfo, "ok"
+		attrs := []slog.Attr{
+			slog.String("tool", req.Params.Name),
+			slog.Any("args", summarizeArgs(req.GetArguments())),
+			slog.String("duration", time.Since(start).String()),
+		}
+		switch {
+		case err != nil:
+			lvl, outcome = slog.LevelWarn, "error"
+			attrs = append(attrs, slog.String("error", err.Error()))
+		case res != nil && res.IsError:
+			lvl, outcome = slog.LevelWarn, "tool_error"
+			attrs = append(attrs, slog.String("error", resultMessage(res)))
+		}
+		attrs = append(attrs, slog.String("outcome", outcome))
+		s.logger.LogAttrs(ctx, lvl, "mcp tool call", attrs...)
+		return res, err
+	}
+}
+
+// summarizeArgs returns args with long strings replaced by their length, so
+// the log shows what was asked without copying note bodies into it.
+func summarizeArgs(args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if str, ok := v.(string); ok {
+			if n := utf8.RuneCountInString(str); n > maxLoggedArgRunes {
+				v = fmt.Sprintf("<%d chars>", n)
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// resultMessage returns the first text item of res.
+func resultMessage(res *mcp.CallToolResult) string {
+	for _, c := range res.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			return tc.Text
+		}
+	}
+	return ""
+}
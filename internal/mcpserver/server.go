@@ -1,99 +1,95 @@
 // Package mcpserver provides an MCP (Model Context Protocol) server
-// that exposes Kenaz tools for LLM integration via stdio transport.
+// that exposes Kenaz tools for LLM integration via the stdio or streamable
+// HTTP transport.
 package mcpserver
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/index"
 	"github.com/starford/kenaz/internal/noteservice"
 	"github.com/starford/kenaz/internal/storage"
 )
 
 // Server wraps the MCP server with Kenaz tools.
 type Server struct {
-	mcp   *server.MCPServer
-	svc   *noteservice.Service
-	store storage.Provider
+	mcp      *server.MCPServer
+	svc      *noteservice.Service
+	store    storage.Provider
+	pipeline *asset.Pipeline
+	allowed  []string
+	logger   *slog.Logger
+
+	// contentAddressed stores uploaded assets as <sha256>.<ext>.
+	contentAddressed bool
 }
 
-// New creates a new MCP server with all Kenaz tools registered.
-func New(svc *noteservice.Service, store storage.Provider) *Server {
+// Option configures a Server.
+type Option func(*Server)
+
+// WithPipeline runs p (virus scan, image optimization) on every asset
+// fetched by upload_asset before it is stored.
+func WithPipeline(p *asset.Pipeline) Option {
+	return func(s *Server) { s.pipeline = p }
+}
+
+// WithContentAddressing makes upload_asset store assets under their
+// content hash, attachments/<sha256>.<ext>, recording the requested file
+// name in attachments/.names.json.
+func WithContentAddressing() Option {
+	return func(s *Server) { s.contentAddressed = true }
+}
+
+// WithTools registers only the named tools, so a deployment can leave out
+// e.g. delete_note or upload_asset. An empty list registers every tool.
+func WithTools(names []string) Option {
+	return func(s *Server) {
+		if len(names) > 0 {
+			s.allowed = names
+		}
+	}
+}
+
+// New creates a new MCP server with the Kenaz tools registered (all of them
+// unless restricted by WithTools). When svc is read-only, the tools that
+// change the vault return an error.
+func New(svc *noteservice.Service, store storage.Provider, opts ...Option) *Server {
 	s := &Server{svc: svc, store: store}
+	for _, o := range opts {
+		o(s)
+	}
 
-	s.mcp = server.NewMCPServer(
-		"Kenaz",
-		"1.0.0",
+	serverOpts := []server.ServerOption{
 		server.WithToolCapabilities(false),
 		server.WithResourceCapabilities(false, false),
-	)
-
-	s.mcp.AddTool(mcp.NewTool("search_notes",
-		mcp.WithDescription("Full-text search through notes content and titles."),
-		mcp.WithString("query", mcp.Required(), mcp.Description("Search query string")),
-	), s.searchNotes)
-
-	s.mcp.AddTool(mcp.NewTool("read_note",
-		mcp.WithDescription("Read the full content of a Markdown note."),
-		mcp.WithString("path", mcp.Required(), mcp.Description("Relative path to the note (e.g. folder/note.md)")),
-	), s.readNote)
-
-	s.mcp.AddTool(mcp.NewTool("create_note",
-		mcp.WithDescription("Create a new Markdown note at the specified path. "+
-			"Content MUST follow the canonical note format (YAML frontmatter with title, "+
-			"optional tags, Markdown body with [[wikilinks]]). "+
-			"Language policy: file/directory names must be in English; frontmatter values and body content may use any language. "+
-			"Read the contract first via the get_note_contract tool or the kenaz://note-format resource."),
-		mcp.WithString("path", mcp.Required(), mcp.Description("Relative path for the new note (must end with .md)")),
-		mcp.WithString("content", mcp.Required(), mcp.Description("Markdown content following the Kenaz note format contract")),
-	), s.createNote)
-
-	s.mcp.AddTool(mcp.NewTool("update_note",
-		mcp.WithDescription("Update an existing Markdown note at the specified path. "+
-			"Content MUST follow the canonical note format. "+
-			"Language policy: file/directory names must be in English; frontmatter values and body content may use any language. "+
-			"Optionally provide a checksum for optimistic concurrency (SHA-256 of current content)."),
-		mcp.WithString("path", mcp.Required(), mcp.Description("Relative path to the note")),
-		mcp.WithString("content", mcp.Required(), mcp.Description("Updated Markdown content")),
-		mcp.WithString("checksum", mcp.Description("SHA-256 checksum of the current content for conflict detection")),
-	), s.updateNote)
-
-	s.mcp.AddTool(mcp.NewTool("delete_note",
-		mcp.WithDescription("Delete an existing note at the specified path."),
-		mcp.WithString("path", mcp.Required(), mcp.Description("Relative path to the note to delete")),
-	), s.deleteNote)
-
-	s.mcp.AddTool(mcp.NewTool("get_note_contract",
-		mcp.WithDescription("Returns the canonical Kenaz note format contract. "+
-			"Call this before creating or updating notes to ensure correct structure."),
-	), s.getNoteContract)
-
-	s.mcp.AddTool(mcp.NewTool("list_notes",
-		mcp.WithDescription("List notes with cursor-based pagination. Returns JSON with paths and a nextCursor for the next page."),
-		mcp.WithString("folder", mcp.Description("Optional folder prefix to filter by (e.g. 'projects/kenaz')")),
-		mcp.WithString("cursor", mcp.Description("Cursor from a previous response to fetch the next page")),
-		mcp.WithString("tag", mcp.Description("Optional tag to filter by")),
-		mcp.WithNumber("limit", mcp.Description("Max notes to return per page (default 50)")),
-	), s.listNotes)
-
-	s.mcp.AddTool(mcp.NewTool("get_backlinks",
-		mcp.WithDescription("Find all notes that link to the specified note."),
-		mcp.WithString("path", mcp.Required(), mcp.Description("Path of the note to find backlinks for")),
-	), s.getBacklinks)
-
-	s.mcp.AddTool(mcp.NewTool("upload_asset",
-		mcp.WithDescription("Download a file from a URL or base64 data URI and save it as an attachment. "+
-			"The file is stored in the shared attachments/ directory. "+
-			"Returns savedPath and markdownImage ready to paste into a note. "+
-			"Supported formats: png, jpg, jpeg, gif, webp, svg, pdf. Max size: 10 MB."),
-		mcp.WithString("url", mcp.Required(), mcp.Description("HTTP/HTTPS URL or base64 data URI (e.g. data:image/png;base64,...)")),
-		mcp.WithString("filename", mcp.Description("Optional filename; if omitted, extracted from URL or generated as UUID")),
-	), s.uploadAsset)
+	}
+	if s.logger != nil {
+		serverOpts = append(serverOpts, server.WithToolHandlerMiddleware(s.logCalls))
+	}
+	s.mcp = server.NewMCPServer("Kenaz", "1.0.0", serverOpts...)
+
+	for _, t := range s.tools() {
+		if !s.enabled(t.Tool.Name) {
+			continue
+		}
+		if svc.ReadOnly() && writeTools[t.Tool.Name] {
+			t.Handler = readOnlyTool
+		}
+		s.mcp.AddTools(t)
+	}
 
 	// Resource: note format contract.
 	s.mcp.AddResource(
@@ -107,11 +103,154 @@ func New(svc *noteservice.Service, store storage.Provider) *Server {
 	return s
 }
 
+// tools returns every Kenaz tool with its handler, in registration order.
+func (s *Server) tools() []server.ServerTool {
+	return []server.ServerTool{
+		{Tool: mcp.NewTool("search_notes",
+			mcp.WithDescription("Full-text search through notes content and titles."),
+			mcp.WithString("query", mcp.Required(), mcp.Description(`Search query: words, "phrases", prefix*, OR, NOT, NEAR(a b, N)`)),
+			mcp.WithString("folder", mcp.Description("Optional folder to search within (e.g. 'projects/kenaz')")),
+			mcp.WithString("tag", mcp.Description("Optional tag the results must have")),
+		), Handler: s.searchNotes},
+		{Tool: mcp.NewTool("read_note",
+			mcp.WithDescription("Read the full content of a Markdown note. "+
+				"A second text item carries the content checksum to pass to update_note."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Relative path to the note (e.g. folder/note.md)")),
+		), Handler: s.readNote},
+		{Tool: mcp.NewTool("create_note",
+			mcp.WithDescription("Create a new Markdown note at the specified path. "+
+				"Content MUST follow the canonical note format (YAML frontmatter with title, "+
+				"optional tags, Markdown body with [[wikilinks]]). "+
+				"Language policy: file/directory names must be in English; frontmatter values and body content may use any language. "+
+				"Read the contract first via the get_note_contract tool or the kenaz://note-format resource. "+
+				"Omit path to have one derived from the title: an English kebab-case file name "+
+				"(Cyrillic is transliterated) under folder, with a numeric suffix when taken."),
+			mcp.WithString("path", mcp.Description("Relative path for the new note (must end with .md); omit to derive it from the title")),
+			mcp.WithString("content", mcp.Required(), mcp.Description("Markdown content following the Kenaz note format contract")),
+			mcp.WithString("title", mcp.Description("Title to derive the path from when path is omitted (default: the content's title)")),
+			mcp.WithString("folder", mcp.Description("Folder for a derived path (e.g. 'meetings'); ignored with path")),
+		), Handler: s.createNote},
+		{Tool: mcp.NewTool("update_note",
+			mcp.WithDescription("Update an existing Markdown note at the specified path. "+
+				"Content MUST follow the canonical note format. "+
+				"Language policy: file/directory names must be in English; frontmatter values and body content may use any language. "+
+				"Provide the checksum returned by read_note for optimistic concurrency; "+
+				"the server may be configured to require it."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Relative path to the note")),
+			mcp.WithString("content", mcp.Required(), mcp.Description("Updated Markdown content")),
+			mcp.WithString("checksum", mcp.Description("SHA-256 checksum of the current content for conflict detection")),
+		), Handler: s.updateNote},
+		{Tool: mcp.NewTool("append_note",
+			mcp.WithDescription("Append content to the end of an existing note, on a new line. "+
+				"Use this instead of read_note + update_note for log-style notes: "+
+				"no checksum is needed and concurrent appends never overwrite each other."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Relative path to the note")),
+			mcp.WithString("content", mcp.Required(), mcp.Description("Markdown block to append")),
+			mcp.WithString("heading", mcp.Description("Optional heading (e.g. 'Inbox' or '## Inbox'); "+
+				"the content is inserted at the end of that section instead of the note")),
+		), Handler: s.appendNote},
+		{Tool: mcp.NewTool("patch_note",
+			mcp.WithDescription("Edit one part of an existing note without resending the whole file. "+
+				"op 'append' adds content at the end of the note or of the heading's section, "+
+				"'prepend' at the start of the body or of the section, and 'replace_heading' replaces "+
+				"the body of the heading's section (the heading line is kept)."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Relative path to the note")),
+			mcp.WithString("op", mcp.Required(), mcp.Description("Operation"),
+				mcp.Enum(noteservice.PatchAppend, noteservice.PatchPrepend, noteservice.PatchReplaceHeading)),
+			mcp.WithString("content", mcp.Description("Markdown block to insert; may be empty only for replace_heading")),
+			mcp.WithString("heading", mcp.Description("Heading of the section (e.g. 'Log' or '## Log'); required for replace_heading")),
+			mcp.WithString("checksum", mcp.Description("Optional SHA-256 checksum from read_note; the patch fails if the note changed since")),
+		), Handler: s.patchNote},
+		{Tool: mcp.NewTool("delete_note",
+			mcp.WithDescription("Delete an existing note at the specified path. "+
+				"It is moved to the trash and can be restored via the REST API."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Relative path to the note to delete")),
+		), Handler: s.deleteNote},
+		{Tool: mcp.NewTool("get_note_contract",
+			mcp.WithDescription("Returns the canonical Kenaz note format contract. "+
+				"Call this before creating or updating notes to ensure correct structure."),
+		), Handler: s.getNoteContract},
+		{Tool: mcp.NewTool("list_notes",
+			mcp.WithDescription("List notes with cursor-based pagination. Returns JSON with paths and a nextCursor for the next page."),
+			mcp.WithString("folder", mcp.Description("Optional folder prefix to filter by (e.g. 'projects/kenaz')")),
+			mcp.WithString("cursor", mcp.Description("Cursor from a previous response to fetch the next page")),
+			mcp.WithString("tag", mcp.Description("Optional tag to filter by")),
+			mcp.WithNumber("limit", mcp.Description("Max notes to return per page (default 50)")),
+		), Handler: s.listNotes},
+		{Tool: mcp.NewTool("get_recent_changes",
+			mcp.WithDescription("List notes created or modified since a timestamp, most recent first, "+
+				"to catch up on vault changes. Returns JSON [{path, kind, title, updated_at}] with kind "+
+				"'created' or 'updated'. Deleted notes are not listed."),
+			mcp.WithString("since", mcp.Required(), mcp.Description("RFC 3339 timestamp, e.g. 2025-02-01T09:00:00Z")),
+			mcp.WithNumber("limit", mcp.Description("Max notes to return (default 50, max 200)")),
+		), Handler: s.getRecentChanges},
+		{Tool: mcp.NewTool("get_daily_note",
+			mcp.WithDescription("Get today's daily note, creating it from the daily template if it does not exist. "+
+				"Returns the note path, then its content. Use append_note on the path to add entries."),
+			mcp.WithString("date", mcp.Description("Optional day as YYYY-MM-DD (default: today)")),
+		), Handler: s.getDailyNote},
+		{Tool: mcp.NewTool("get_backlinks",
+			mcp.WithDescription("Find all notes that link to the specified note. "+
+				"Links declared in frontmatter fields (e.g. related, parent) are marked with (frontmatter); "+
+				"body links are followed by the line they appear on."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Path of the note to find backlinks for")),
+		), Handler: s.getBacklinks},
+		{Tool: mcp.NewTool("upload_asset",
+			mcp.WithDescription("Download a file from a URL or base64 data URI and save it as an attachment. "+
+				"The file is stored in the shared attachments/ directory. "+
+				"Returns savedPath and markdownImage ready to paste into a note. "+
+				"Supported formats: png, jpg, jpeg, gif, webp, svg, pdf. Max size: 10 MB."),
+			mcp.WithString("url", mcp.Required(), mcp.Description("HTTP/HTTPS URL or base64 data URI (e.g. data:image/png;base64,...)")),
+			mcp.WithString("filename", mcp.Description("Optional filename; if omitted, extracted from URL or generated as UUID")),
+		), Handler: s.uploadAsset},
+	}
+}
+
+// writeTools are the tools that change the vault. On a read-only vault
+// they stay listed but fail with readOnlyTool.
+var writeTools = map[string]bool{
+	"create_note":  true,
+	"update_note":  true,
+	"append_note":  true,
+	"patch_note":   true,
+	"delete_note":  true,
+	"upload_asset": true,
+}
+
+// readOnlyTool answers a call to a write tool on a read-only vault.
+func readOnlyTool(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return mcp.NewToolResultError("vault is read-only"), nil
+}
+
+// ToolNames returns the names of all tools the server can register.
+func ToolNames() []string {
+	tools := (&Server{}).tools()
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Tool.Name
+	}
+	return names
+}
+
+// enabled reports whether the tool is registered under the configured
+// allowlist.
+func (s *Server) enabled(name string) bool {
+	return s.allowed == nil || slices.Contains(s.allowed, name)
+}
+
 // ServeStdio starts the MCP server on stdin/stdout.
 func (s *Server) ServeStdio() error {
 	return server.ServeStdio(s.mcp)
 }
 
+// HTTPHandler returns the server as a streamable HTTP transport handler, to
+// be mounted at the MCP endpoint. Kenaz sends no server-initiated messages,
+// so the standalone GET stream is disabled: every connection is a request
+// that completes, which keeps graceful shutdown prompt.
+func (s *Server) HTTPHandler() http.Handler {
+	return server.NewStreamableHTTPServer(s.mcp, server.WithDisableStreaming(true))
+}
+
 // MCPServer returns the underlying server for testing.
 func (s *Server) MCPServer() *server.MCPServer {
 	return s.mcp
@@ -122,7 +261,14 @@ func (s *Server) searchNotes(ctx context.Context, req mcp.CallToolRequest) (*mcp
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	results, err := s.svc.Search(ctx, query, 20)
+	opts := index.SearchOptions{Limit: 20}
+	if v, err := req.RequireString("folder"); err == nil {
+		opts.Folder = v
+	}
+	if v, err := req.RequireString("tag"); err == nil {
+		opts.Tag = v
+	}
+	results, _, err := s.svc.Search(ctx, query, opts)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -139,10 +285,32 @@ func (s *Server) readNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.Ca
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("not found: %s", path)), nil
 	}
-	return mcp.NewToolResultText(note.Content), nil
+	res := mcp.NewToolResultText(note.Content)
+	res.Content = append(res.Content, mcp.NewTextContent("checksum: "+note.Checksum))
+	return res, nil
 }
 
 func (s *Server) createNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	content, err := req.RequireString("content")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	path, _ := req.RequireString("path")
+	if path == "" {
+		title, _ := req.RequireString("title")
+		folder, _ := req.RequireString("folder")
+		note, err := s.svc.CreateNoteTitled(ctx, title, folder, []byte(content))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		path = note.Path
+	} else if _, err := s.svc.CreateNote(ctx, path, []byte(content)); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("created: %s", path)), nil
+}
+
+func (s *Server) updateNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	path, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
@@ -152,13 +320,21 @@ func (s *Server) createNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	if _, err := s.svc.CreateNote(ctx, path, []byte(content)); err != nil {
+	cs := ""
+	if v, csErr := req.RequireString("checksum"); csErr == nil {
+		cs = v
+	}
+
+	if _, err := s.svc.UpdateNote(ctx, path, []byte(content), cs); err != nil {
+		if errors.Is(err, apperr.ErrPreconditionRequired) {
+			return mcp.NewToolResultError("checksum is required: read the note first and pass its checksum"), nil
+		}
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("created: %s", path)), nil
+	return mcp.NewToolResultText(fmt.Sprintf("updated: %s", path)), nil
 }
 
-func (s *Server) updateNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (s *Server) appendNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	path, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
@@ -168,15 +344,56 @@ func (s *Server) updateNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
+	heading := ""
+	if v, hErr := req.RequireString("heading"); hErr == nil {
+		heading = v
+	}
+
+	if _, err := s.svc.AppendNote(ctx, path, content, heading); err != nil {
+		if errors.Is(err, noteservice.ErrHeadingNotFound) {
+			return mcp.NewToolResultError(fmt.Sprintf("heading not found in %s: %s", path, heading)), nil
+		}
+		if errors.Is(err, apperr.ErrNotFound) {
+			return mcp.NewToolResultError(fmt.Sprintf("not found: %s", path)), nil
+		}
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("appended: %s", path)), nil
+}
+
+func (s *Server) patchNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	path, err := req.RequireString("path")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	op, err := req.RequireString("op")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	patch := noteservice.Patch{Op: op}
+	if v, hErr := req.RequireString("heading"); hErr == nil {
+		patch.Heading = v
+	}
+	if v, cErr := req.RequireString("content"); cErr == nil {
+		patch.Content = v
+	}
 	cs := ""
 	if v, csErr := req.RequireString("checksum"); csErr == nil {
 		cs = v
 	}
 
-	if _, err := s.svc.UpdateNote(ctx, path, []byte(content), cs); err != nil {
+	if _, err := s.svc.PatchNote(ctx, path, patch, cs); err != nil {
+		switch {
+		case errors.Is(err, noteservice.ErrHeadingNotFound):
+			return mcp.NewToolResultError(fmt.Sprintf("heading not found in %s: %s", path, patch.Heading)), nil
+		case errors.Is(err, apperr.ErrNotFound):
+			return mcp.NewToolResultError(fmt.Sprintf("not found: %s", path)), nil
+		case errors.Is(err, apperr.ErrConflict):
+			return mcp.NewToolResultError("checksum mismatch: the note changed, read it again"), nil
+		}
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("updated: %s", path)), nil
+	return mcp.NewToolResultText(fmt.Sprintf("patched: %s", path)), nil
 }
 
 func (s *Server) deleteNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -184,7 +401,7 @@ func (s *Server) deleteNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	if err := s.svc.DeleteNote(ctx, path); err != nil {
+	if _, err := s.svc.DeleteNote(ctx, path); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("not found: %s", path)), nil //nolint:nilerr
 	}
 	return mcp.NewToolResultText(fmt.Sprintf("deleted: %s", path)), nil
@@ -244,17 +461,67 @@ func (s *Server) readNoteFormatResource(_ context.Context, _ mcp.ReadResourceReq
 	}, nil
 }
 
+func (s *Server) getRecentChanges(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	raw, err := req.RequireString("since")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	since, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return mcp.NewToolResultError("since must be an RFC 3339 timestamp (e.g. 2025-02-01T09:00:00Z)"), nil //nolint:nilerr
+	}
+	limit := 0
+	if v, err := req.RequireFloat("limit"); err == nil {
+		limit = int(v)
+	}
+
+	changes, err := s.svc.RecentChanges(ctx, since, limit)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	out, _ := json.MarshalIndent(changes, "", "  ")
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+func (s *Server) getDailyNote(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	day := time.Now()
+	if v, err := req.RequireString("date"); err == nil && v != "" {
+		d, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			return mcp.NewToolResultError("date must be YYYY-MM-DD"), nil //nolint:nilerr
+		}
+		day = d
+	}
+	note, err := s.svc.DailyNote(ctx, day)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	res := mcp.NewToolResultText("path: " + note.Path)
+	res.Content = append(res.Content, mcp.NewTextContent(note.Content))
+	return res, nil
+}
+
 func (s *Server) getBacklinks(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	path, err := req.RequireString("path")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	bl, err := s.svc.Backlinks(ctx, path)
+	refs, err := s.svc.BacklinkRefs(ctx, path)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	if len(bl) == 0 {
+	if len(refs) == 0 {
 		return mcp.NewToolResultText("no backlinks found"), nil
 	}
-	return mcp.NewToolResultText(strings.Join(bl, "\n")), nil
+	lines := make([]string, len(refs))
+	for i, r := range refs {
+		lines[i] = r.Source
+		if slices.Contains(r.Types, index.LinkFrontmatter) {
+			lines[i] += " (frontmatter)"
+		}
+		if r.Snippet != "" {
+			lines[i] += ": " + r.Snippet
+		}
+	}
+	return mcp.NewToolResultText(strings.Join(lines, "\n")), nil
 }
@@ -1,12 +1,19 @@
 package mcpserver
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 
@@ -15,7 +22,7 @@ import (
 	"github.com/starford/kenaz/internal/storage"
 )
 
-func testServer(t *testing.T) (*Server, storage.Provider) {
+func testServer(t *testing.T, opts ...noteservice.Option) (*Server, storage.Provider) {
 	t.Helper()
 
 	vaultDir := t.TempDir()
@@ -37,7 +44,7 @@ func testServer(t *testing.T) (*Server, storage.Provider) {
 	}
 	t.Cleanup(func() { db.Close() })
 
-	svc := noteservice.NewService(store, db)
+	svc := noteservice.NewService(store, db, opts...)
 	srv := New(svc, store)
 	return srv, store
 }
@@ -67,8 +74,16 @@ func callTool(t *testing.T, srv *Server, name string, args map[string]any) *mcp.
 		result, err = srv.listNotes(ctx, req)
 	case "update_note":
 		result, err = srv.updateNote(ctx, req)
+	case "append_note":
+		result, err = srv.appendNote(ctx, req)
+	case "patch_note":
+		result, err = srv.patchNote(ctx, req)
 	case "delete_note":
 		result, err = srv.deleteNote(ctx, req)
+	case "get_recent_changes":
+		result, err = srv.getRecentChanges(ctx, req)
+	case "get_daily_note":
+		result, err = srv.getDailyNote(ctx, req)
 	case "get_backlinks":
 		result, err = srv.getBacklinks(ctx, req)
 	case "get_note_contract":
@@ -110,8 +125,33 @@ func TestCreateAndReadNote(t *testing.T) {
 		"path": "test.md",
 	})
 	text = resultText(r)
-	if text != "# Test\nHello" {
-		t.Errorf("read result = %q", text)
+	if !strings.HasPrefix(text, "---\nid: ") || !strings.HasSuffix(text, "---\n# Test\nHello") {
+		t.Errorf("read result = %q, want content with an assigned id", text)
+	}
+}
+
+func TestCreateNote_DerivedPath(t *testing.T) {
+	srv, _ := testServer(t)
+
+	r := callTool(t, srv, "create_note", map[string]any{
+		"content": "# Заметки о встрече\nПовестка",
+		"folder":  "meetings",
+	})
+	if text := resultText(r); text != "created: meetings/zametki-o-vstreche.md" {
+		t.Errorf("create result = %q", text)
+	}
+	r = callTool(t, srv, "create_note", map[string]any{
+		"content": "Повестка",
+		"title":   "Заметки о встрече",
+		"folder":  "meetings",
+	})
+	if text := resultText(r); text != "created: meetings/zametki-o-vstreche-2.md" {
+		t.Errorf("second create result = %q", text)
+	}
+
+	r = callTool(t, srv, "create_note", map[string]any{"content": "no title here"})
+	if !r.IsError {
+		t.Errorf("create without path or title = %q, want an error", resultText(r))
 	}
 }
 
@@ -280,8 +320,60 @@ func TestGetBacklinks(t *testing.T) {
 
 	r := callTool(t, srv, "get_backlinks", map[string]any{"path": "b"})
 	text := resultText(r)
-	if text != "a.md" {
-		t.Errorf("backlinks = %q, want a.md", text)
+	if text != "a.md: links to [[b]]" {
+		t.Errorf("backlinks = %q, want a.md with its snippet", text)
+	}
+}
+
+func TestGetRecentChanges(t *testing.T) {
+	srv, _ := testServer(t)
+	since := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	_ = callTool(t, srv, "create_note", map[string]any{"path": "fresh.md", "content": "# Fresh"})
+
+	r := callTool(t, srv, "get_recent_changes", map[string]any{"since": since})
+	var changes []noteservice.RecentChange
+	if err := json.Unmarshal([]byte(resultText(r)), &changes); err != nil {
+		t.Fatalf("unmarshal %q: %v", resultText(r), err)
+	}
+	if len(changes) != 1 || changes[0].Path != "fresh.md" || changes[0].Kind != noteservice.MutationCreated || changes[0].Title != "Fresh" {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	r = callTool(t, srv, "get_recent_changes", map[string]any{"since": future})
+	if text := resultText(r); text != "[]" {
+		t.Errorf("future since = %q, want []", text)
+	}
+
+	r = callTool(t, srv, "get_recent_changes", map[string]any{"since": "yesterday"})
+	if !r.IsError {
+		t.Error("invalid since accepted")
+	}
+}
+
+func TestGetDailyNote(t *testing.T) {
+	srv, _ := testServer(t)
+
+	r := callTool(t, srv, "get_daily_note", map[string]any{"date": "2025-02-03"})
+	if r.IsError || len(r.Content) != 2 {
+		t.Fatalf("get_daily_note = %+v", r)
+	}
+	if text := resultText(r); text != "path: journal/2025-02-03.md" {
+		t.Errorf("path = %q", text)
+	}
+	if c := r.Content[1].(mcp.TextContent).Text; !strings.Contains(c, "# 2025-02-03") {
+		t.Errorf("content = %q", c)
+	}
+
+	// The second call returns the same note instead of recreating it.
+	_ = callTool(t, srv, "append_note", map[string]any{"path": "journal/2025-02-03.md", "content": "- entry"})
+	r = callTool(t, srv, "get_daily_note", map[string]any{"date": "2025-02-03"})
+	if c := r.Content[1].(mcp.TextContent).Text; !strings.Contains(c, "- entry") {
+		t.Errorf("content after append = %q", c)
+	}
+
+	if r := callTool(t, srv, "get_daily_note", map[string]any{"date": "03.02.2025"}); !r.IsError {
+		t.Error("invalid date accepted")
 	}
 }
 
@@ -342,6 +434,23 @@ func TestUpdateNoteChecksumConflict(t *testing.T) {
 	}
 }
 
+func TestReadNoteChecksum(t *testing.T) {
+	srv, store := testServer(t)
+	if err := store.Write("c.md", []byte("# C\n")); err != nil {
+		t.Fatal(err)
+	}
+	r := callTool(t, srv, "read_note", map[string]any{"path": "c.md"})
+	if len(r.Content) != 2 {
+		t.Fatalf("content items = %d, want 2", len(r.Content))
+	}
+	tc, _ := r.Content[1].(mcp.TextContent)
+	cs := strings.TrimPrefix(tc.Text, "checksum: ")
+	r = callTool(t, srv, "update_note", map[string]any{"path": "c.md", "content": "# C2\n", "checksum": cs})
+	if r.IsError {
+		t.Errorf("update with read_note checksum failed: %s", resultText(r))
+	}
+}
+
 func TestDeleteNote(t *testing.T) {
 	srv, _ := testServer(t)
 
@@ -560,3 +669,163 @@ func TestUploadAssetSVGInvalid(t *testing.T) {
 		t.Error("expected error for invalid SVG content")
 	}
 }
+
+func TestAppendNote(t *testing.T) {
+	srv, _ := testServer(t)
+
+	callTool(t, srv, "create_note", map[string]any{"path": "log.md", "content": "# Log"})
+	r := callTool(t, srv, "append_note", map[string]any{"path": "log.md", "content": "- one"})
+	if text := resultText(r); text != "appended: log.md" {
+		t.Errorf("append result = %q", text)
+	}
+	r = callTool(t, srv, "read_note", map[string]any{"path": "log.md"})
+	if text := resultText(r); !strings.HasSuffix(text, "# Log\n- one\n") {
+		t.Errorf("content = %q", text)
+	}
+
+	r = callTool(t, srv, "append_note", map[string]any{"path": "missing.md", "content": "x"})
+	if !r.IsError {
+		t.Error("append to missing note should fail")
+	}
+}
+
+func TestPatchNote(t *testing.T) {
+	srv, _ := testServer(t)
+
+	callTool(t, srv, "create_note", map[string]any{"path": "log.md", "content": "# Log\n\n## Today\n- old\n\n## Later\n- keep\n"})
+	r := callTool(t, srv, "patch_note", map[string]any{
+		"path": "log.md", "op": "replace_heading", "heading": "Today", "content": "- new",
+	})
+	if text := resultText(r); text != "patched: log.md" {
+		t.Errorf("patch result = %q", text)
+	}
+	r = callTool(t, srv, "read_note", map[string]any{"path": "log.md"})
+	if text := resultText(r); !strings.HasSuffix(text, "## Today\n- new\n\n## Later\n- keep\n") {
+		t.Errorf("content = %q", text)
+	}
+
+	r = callTool(t, srv, "patch_note", map[string]any{"path": "log.md", "op": "replace_heading", "heading": "Missing"})
+	if !r.IsError || !strings.Contains(resultText(r), "heading not found") {
+		t.Errorf("missing heading result = %q", resultText(r))
+	}
+	r = callTool(t, srv, "patch_note", map[string]any{"path": "log.md", "op": "rewrite", "content": "x"})
+	if !r.IsError {
+		t.Error("unknown op should fail")
+	}
+}
+
+func TestWithTools(t *testing.T) {
+	all, _ := testServer(t)
+	if got := len(all.MCPServer().ListTools()); got != len(ToolNames()) {
+		t.Errorf("default registers %d tools, want %d", got, len(ToolNames()))
+	}
+
+	srv := New(all.svc, all.store, WithTools([]string{"read_note", "search_notes"}))
+	tools := srv.MCPServer().ListTools()
+	if len(tools) != 2 || tools["read_note"] == nil || tools["search_notes"] == nil {
+		t.Errorf("registered tools = %v", slices.Collect(maps.Keys(tools)))
+	}
+	if srv.MCPServer().GetTool("delete_note") != nil {
+		t.Error("delete_note registered despite allowlist")
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	base, _ := testServer(t)
+	var buf bytes.Buffer
+	srv := New(base.svc, base.store, WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	handler := srv.logCalls(srv.MCPServer().GetTool("create_note").Handler)
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "create_note"
+	req.Params.Arguments = map[string]any{"path": "a.md", "content": strings.Repeat("x", 100)}
+	for range 2 {
+		if _, err := handler(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log lines = %d, want 2:\n%s", len(lines), buf.String())
+	}
+	var ok, dup struct {
+		Tool    string         `json:"tool"`
+		Args    map[string]any `json:"args"`
+		Outcome string         `json:"outcome"`
+		Error   string         `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &ok); err != nil {
+		t.Fatal(err)
+	}
+	if ok.Tool != "create_note" || ok.Outcome != "ok" || ok.Args["path"] != "a.md" || ok.Args["content"] != "<100 chars>" {
+		t.Errorf("first call logged as %+v", ok)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &dup); err != nil {
+		t.Fatal(err)
+	}
+	if dup.Outcome != "tool_error" || dup.Error == "" {
+		t.Errorf("duplicate create logged as %+v", dup)
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	srv, _ := testServer(t)
+	ts := httptest.NewServer(srv.HTTPHandler())
+	t.Cleanup(ts.Close)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Result struct {
+			ServerInfo struct {
+				Name string `json:"name"`
+			} `json:"serverInfo"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Result.ServerInfo.Name != "Kenaz" {
+		t.Errorf("initialize: status %d, server %q", resp.StatusCode, out.Result.ServerInfo.Name)
+	}
+	if resp.Header.Get("Mcp-Session-Id") == "" {
+		t.Error("no session id")
+	}
+}
+
+func TestReadOnlyTools(t *testing.T) {
+	srv, store := testServer(t, noteservice.WithReadOnly(true))
+	if err := store.Write("a.md", []byte("# A\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		tool := srv.MCPServer().GetTool(name)
+		if tool == nil {
+			t.Fatalf("%s not registered", name)
+		}
+		req := mcp.CallToolRequest{}
+		req.Params.Name = name
+		req.Params.Arguments = args
+		r, err := tool.Handler(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	if r := call("read_note", map[string]any{"path": "a.md"}); r.IsError {
+		t.Errorf("read_note failed on a read-only vault: %v", r.Content)
+	}
+	for _, name := range []string{"create_note", "update_note", "append_note", "patch_note", "delete_note", "upload_asset"} {
+		r := call(name, map[string]any{"path": "a.md", "content": "# B\n", "op": "append", "url": "data:image/png;base64,AA=="})
+		if !r.IsError || !strings.Contains(r.Content[0].(mcp.TextContent).Text, "read-only") {
+			t.Errorf("%s on a read-only vault = %+v, want a read-only error", name, r.Content)
+		}
+	}
+}
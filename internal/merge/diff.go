@@ -0,0 +1,87 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each hunk.
+const diffContext = 3
+
+// Diff returns a unified diff turning a into b, with name in the ---/+++
+// headers, or "" when they are equal.
+func Diff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+	x, y := splitLines(a), splitLines(b)
+	m := matches(x, y)
+
+	// ops is the edit script: ' ' keep, '-' delete from x, '+' insert from y.
+	type op struct {
+		kind byte
+		line string
+	}
+	var ops []op
+	j := 0
+	for i := range x {
+		if m[i] < 0 {
+			ops = append(ops, op{'-', x[i]})
+			continue
+		}
+		for ; j < m[i]; j++ {
+			ops = append(ops, op{'+', y[j]})
+		}
+		ops = append(ops, op{' ', x[i]})
+		j++
+	}
+	for ; j < len(y); j++ {
+		ops = append(ops, op{'+', y[j]})
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", name, name)
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		// Grow the hunk while changes are within 2*context lines of each other.
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			} else if k-end >= 2*diffContext {
+				break
+			}
+		}
+		lo, hi := max(0, start-diffContext), min(len(ops), end+diffContext)
+
+		// Line numbers (1-based) of the hunk start in a and b.
+		la, lb := 1, 1
+		for _, o := range ops[:lo] {
+			if o.kind != '+' {
+				la++
+			}
+			if o.kind != '-' {
+				lb++
+			}
+		}
+		na, nb := 0, 0
+		for _, o := range ops[lo:hi] {
+			if o.kind != '+' {
+				na++
+			}
+			if o.kind != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", la, na, lb, nb)
+		for _, o := range ops[lo:hi] {
+			out.WriteByte(o.kind)
+			out.WriteString(o.line)
+		}
+		start = hi
+	}
+	return out.String()
+}
@@ -0,0 +1,232 @@
+// Package merge implements line-based diffs and three-way merges (diff3)
+// for note content.
+package merge
+
+import "strings"
+
+// Conflict markers written into the merged text around each conflict.
+const (
+	MarkerCurrent  = "<<<<<<< current"
+	MarkerSep      = "======="
+	MarkerIncoming = ">>>>>>> incoming"
+)
+
+// Conflict is a region changed differently on both sides. Line is the
+// 1-based line of the opening marker in the merged text.
+type Conflict struct {
+	Line     int    `json:"line" example:"3" validate:"required"`
+	Base     string `json:"base" validate:"required"`
+	Current  string `json:"current" validate:"required"`
+	Incoming string `json:"incoming" validate:"required"`
+}
+
+// Result is the outcome of a three-way merge.
+type Result struct {
+	// Merged is the merged text; conflicting regions are wrapped in
+	// git-style markers.
+	Merged    string
+	Conflicts []Conflict
+}
+
+// Clean reports whether the merge had no conflicts.
+func (r Result) Clean() bool { return len(r.Conflicts) == 0 }
+
+// Merge combines current and incoming, both derived from base. Changes made
+// on one side only are applied; overlapping changes that differ are
+// reported as conflicts.
+func Merge(base, current, incoming string) Result {
+	o, a, b := splitLines(base), splitLines(current), splitLines(incoming)
+	ma, mb := matches(o, a), matches(o, b)
+
+	var out []string
+	var conflicts []Conflict
+	emit := func(lines []string) { out = append(out, lines...) }
+
+	i, ia, ib := 0, 0, 0
+	for i < len(o) || ia < len(a) || ib < len(b) {
+		// Stable line: unchanged on both sides.
+		if i < len(o) && ma[i] == ia && mb[i] == ib {
+			emit(o[i : i+1])
+			i, ia, ib = i+1, ia+1, ib+1
+			continue
+		}
+		// Unstable chunk up to the next base line kept by both sides.
+		j := i
+		for j < len(o) && (ma[j] < 0 || mb[j] < 0) {
+			j++
+		}
+		ja, jb := len(a), len(b)
+		if j < len(o) {
+			ja, jb = ma[j], mb[j]
+		}
+		co, ca, cb := o[i:j], a[ia:ja], b[ib:jb]
+		switch {
+		case equal(ca, co):
+			emit(cb)
+		case equal(cb, co), equal(ca, cb):
+			emit(ca)
+		default:
+			conflicts = append(conflicts, Conflict{
+				Line:     len(out) + 1,
+				Base:     joinLines(co),
+				Current:  joinLines(ca),
+				Incoming: joinLines(cb),
+			})
+			emit([]string{MarkerCurrent + "\n"})
+			emit(ca)
+			emit([]string{MarkerSep + "\n"})
+			emit(cb)
+			emit([]string{MarkerIncoming + "\n"})
+		}
+		i, ia, ib = j, ja, jb
+	}
+	return Result{Merged: strings.Join(out, ""), Conflicts: conflicts}
+}
+
+// splitLines splits s into lines that keep their trailing "\n". A missing
+// final newline is added so every line compares and joins uniformly; a
+// merge of newline-terminated inputs is therefore unchanged.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return strings.SplitAfter(s, "\n")[:strings.Count(s, "\n")]
+}
+
+func joinLines(lines []string) string { return strings.Join(lines, "") }
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matches returns, for each line of o, the index of the line of x it is
+// paired with in a longest common subsequence, or -1. Pairs are monotonic.
+// It uses the linear-space variant of Myers' O(ND) algorithm, which splits
+// the inputs at the middle snake of an optimal edit script and recurses on
+// both halves, so memory stays O(N+M) however much the texts differ. Past
+// maxSnakeCost the search settles for a good split instead of the best, as
+// git does, which bounds the time of near-total rewrites at the price of a
+// common subsequence that may not be the longest.
+func matches(o, x []string) []int {
+	m := make([]int, len(o))
+	for i := range m {
+		m[i] = -1
+	}
+	size := 2*((len(o)+len(x)+1)/2) + 3
+	s := &myers{a: o, b: x, m: m, vf: make([]int, size), vb: make([]int, size)}
+	s.compare(0, len(o), 0, len(x))
+	return m
+}
+
+// maxSnakeCost is the number of edits middleSnake searches on each side
+// before giving up on an optimal split.
+const maxSnakeCost = 1024
+
+// myers holds the inputs, result, and scratch space of matches.
+type myers struct {
+	a, b []string
+	m    []int
+	// vf and vb are the furthest reaching forward and backward paths by
+	// diagonal, reused by every middleSnake.
+	vf, vb []int
+}
+
+// compare pairs the lines of a[a0:a1] with those of b[b0:b1].
+func (s *myers) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && s.a[a0] == s.b[b0] {
+		s.m[a0] = b0
+		a0, b0 = a0+1, b0+1
+	}
+	for a0 < a1 && b0 < b1 && s.a[a1-1] == s.b[b1-1] {
+		a1, b1 = a1-1, b1-1
+		s.m[a1] = b1
+	}
+	if a0 == a1 || b0 == b1 {
+		return
+	}
+	// With the common ends trimmed, the edit script has at least two
+	// edits, so both halves are strictly smaller problems.
+	x, y, u, v := s.middleSnake(a0, a1, b0, b1)
+	for i := x; i < u; i++ {
+		s.m[a0+i] = b0 + y + i - x
+	}
+	s.compare(a0, a0+x, b0, b0+y)
+	s.compare(a0+u, a1, b0+v, b1)
+}
+
+// middleSnake returns the middle snake of an optimal edit script turning
+// a[a0:a1] into b[b0:b1]: the diagonal run from (x, y) to (u, v), relative
+// to (a0, b0), where the forward and backward searches meet. When they have
+// not met after maxSnakeCost edits, it returns an empty snake at the end
+// of the forward path that got furthest.
+func (s *myers) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta&1 != 0
+	maxD := (n + m + 1) / 2
+	off := maxD + 1
+	vf, vb := s.vf, s.vb
+	vf[off+1], vb[off+1] = 0, 0
+	for d := 0; d <= min(maxD, maxSnakeCost); d++ {
+		// Forward paths, on diagonals k = i - j.
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && vf[off+k-1] < vf[off+k+1]) {
+				i = vf[off+k+1]
+			} else {
+				i = vf[off+k-1] + 1
+			}
+			j := i - k
+			x0, y0 := i, j
+			for i < n && j < m && s.a[a0+i] == s.b[b0+j] {
+				i, j = i+1, j+1
+			}
+			vf[off+k] = i
+			if r := delta - k; odd && r >= -(d-1) && r <= d-1 && i+vb[off+r] >= n {
+				return x0, y0, i, j
+			}
+		}
+		// Backward paths, on diagonals r = delta - k counted from the ends.
+		for r := -d; r <= d; r += 2 {
+			var i int
+			if r == -d || (r != d && vb[off+r-1] < vb[off+r+1]) {
+				i = vb[off+r+1]
+			} else {
+				i = vb[off+r-1] + 1
+			}
+			j := i - r
+			x0, y0 := i, j
+			for i < n && j < m && s.a[a1-1-i] == s.b[b1-1-j] {
+				i, j = i+1, j+1
+			}
+			vb[off+r] = i
+			if k := delta - r; !odd && k >= -d && k <= d && i+vf[off+k] >= n {
+				return n - i, m - j, n - x0, m - y0
+			}
+		}
+	}
+	// Paths may have stepped off the grid; clamp them back onto it. Any
+	// point other than the corners splits the problem, if not optimally.
+	for k := -maxSnakeCost; k <= maxSnakeCost; k += 2 {
+		i := min(vf[off+k], n)
+		j := min(max(i-k, 0), m)
+		if i+j > x+y && i+j < n+m {
+			x, y = i, j
+		}
+	}
+	if x+y == 0 {
+		x, y = n/2, m/2
+	}
+	return x, y, x, y
+}
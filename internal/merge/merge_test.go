@@ -0,0 +1,151 @@
+package merge
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name, current, incoming, want string
+		conflicts                     int
+	}{
+		{"identical", base, base, base, 0},
+		{"current only", "a\nB\nc\nd\ne\n", base, "a\nB\nc\nd\ne\n", 0},
+		{"incoming only", base, "a\nb\nc\nd\nE\nf\n", "a\nb\nc\nd\nE\nf\n", 0},
+		{"disjoint edits", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"same edit", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", 0},
+		{"delete vs keep", "a\nc\nd\ne\n", base, "a\nc\nd\ne\n", 0},
+		{
+			"conflict", "a\nX\nc\nd\ne\n", "a\nY\nc\nd\ne\n",
+			"a\n" + MarkerCurrent + "\nX\n" + MarkerSep + "\nY\n" + MarkerIncoming + "\nc\nd\ne\n", 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Merge(base, tt.current, tt.incoming)
+			if res.Merged != tt.want {
+				t.Errorf("merged =\n%s\nwant\n%s", res.Merged, tt.want)
+			}
+			if len(res.Conflicts) != tt.conflicts {
+				t.Errorf("conflicts = %d, want %d", len(res.Conflicts), tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestMerge_ConflictHunk(t *testing.T) {
+	res := Merge("title\nbody\n", "title\nmine\n", "title\ntheirs\nmore\n")
+	if res.Clean() || len(res.Conflicts) != 1 {
+		t.Fatalf("conflicts = %+v", res.Conflicts)
+	}
+	c := res.Conflicts[0]
+	if c.Line != 2 || c.Base != "body\n" || c.Current != "mine\n" || c.Incoming != "theirs\nmore\n" {
+		t.Errorf("conflict = %+v", c)
+	}
+	if lines := strings.Split(res.Merged, "\n"); lines[c.Line-1] != MarkerCurrent {
+		t.Errorf("line %d = %q, want marker", c.Line, lines[c.Line-1])
+	}
+}
+
+func TestMatches(t *testing.T) {
+	o := splitLines("a\nb\nc\na\nb\nb\na\n")
+	x := splitLines("c\nb\na\nb\na\nc\n")
+	m := matches(o, x)
+	n, last := 0, -1
+	for i, j := range m {
+		if j < 0 {
+			continue
+		}
+		if j <= last || o[i] != x[j] {
+			t.Fatalf("bad match %d->%d in %v", i, j, m)
+		}
+		last = j
+		n++
+	}
+	if n != 4 { // LCS of the classic Myers example has length 4
+		t.Errorf("matched %d lines, want 4: %v", n, m)
+	}
+}
+
+func TestMatches_Optimal(t *testing.T) {
+	// Compare the match count with a dynamic-programming LCS on random
+	// inputs over a small alphabet, which have many equal lines.
+	r := rand.New(rand.NewPCG(1, 2))
+	random := func() []string {
+		lines := make([]string, r.IntN(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.IntN(4)))
+		}
+		return lines
+	}
+	for range 500 {
+		o, x := random(), random()
+		lcs := make([][]int, len(o)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(x)+1)
+		}
+		for i := len(o) - 1; i >= 0; i-- {
+			for j := len(x) - 1; j >= 0; j-- {
+				if o[i] == x[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		n, last := 0, -1
+		for i, j := range matches(o, x) {
+			if j < 0 {
+				continue
+			}
+			if j <= last || o[i] != x[j] {
+				t.Fatalf("bad match %d->%d for %q, %q", i, j, o, x)
+			}
+			last = j
+			n++
+		}
+		if n != lcs[0][0] {
+			t.Fatalf("matched %d lines of %q and %q, want %d", n, o, x, lcs[0][0])
+		}
+	}
+}
+
+func TestMatches_LinearMemory(t *testing.T) {
+	// A full rewrite is the worst case: every line differs.
+	const lines = 6000
+	o, x := make([]string, lines), make([]string, lines)
+	for i := range lines {
+		o[i], x[i] = fmt.Sprintf("old %d\n", i), fmt.Sprintf("new %d\n", i)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	m := matches(o, x)
+	runtime.ReadMemStats(&after)
+	if got := after.TotalAlloc - before.TotalAlloc; got > 1<<20 {
+		t.Errorf("matches allocated %d bytes for %d lines, want under 1 MiB", got, lines)
+	}
+	for i, j := range m {
+		if j >= 0 {
+			t.Fatalf("line %d matched %d", i, j)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if d := Diff("a.md", "same\n", "same\n"); d != "" {
+		t.Errorf("equal diff = %q", d)
+	}
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\nTWO\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+	want := "--- a/n.md\n+++ b/n.md\n" +
+		"@@ -1,5 +1,5 @@\n 1\n-2\n+TWO\n 3\n 4\n 5\n" +
+		"@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n"
+	if d := Diff("n.md", a, b); d != want {
+		t.Errorf("diff =\n%s\nwant\n%s", d, want)
+	}
+}
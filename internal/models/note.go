@@ -8,4 +8,9 @@ type NoteMetadata struct {
 	Path      string    `json:"path"`
 	Checksum  string    `json:"checksum"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// CreatedAt is the file's creation (birth) time where the storage
+	// records one, else zero.
+	CreatedAt time.Time `json:"created_at"`
+	// Size is the file size in bytes.
+	Size int64 `json:"size"`
 }
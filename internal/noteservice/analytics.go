@@ -0,0 +1,122 @@
+package noteservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/starford/kenaz/internal/index"
+)
+
+// WritingStats summarizes writing activity over a range of days.
+type WritingStats struct {
+	From string `json:"from" example:"2025-01-01" validate:"required"`
+	To   string `json:"to" example:"2025-01-31" validate:"required"`
+	// Days lists every day of the range, oldest first.
+	Days         []index.WritingDay `json:"days" validate:"required"`
+	WordsAdded   int                `json:"words_added" validate:"required"`
+	WordsRemoved int                `json:"words_removed" validate:"required"`
+	// CurrentStreak counts consecutive days with edits up to today (or up
+	// to yesterday, while today has none yet).
+	CurrentStreak int `json:"current_streak" validate:"required"`
+	// LongestStreak is the longest run of consecutive days with edits ever.
+	LongestStreak int `json:"longest_streak" validate:"required"`
+}
+
+// WritingStats returns per-day edits and words added/removed for the days
+// from through to (inclusive), plus writing streaks.
+func (s *Service) WritingStats(_ context.Context, from, to time.Time) (*WritingStats, error) {
+	today := time.Now()
+	all, err := s.db.WritingActivity("", today.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	st := &WritingStats{From: from.Format(time.DateOnly), To: to.Format(time.DateOnly), Days: []index.WritingDay{}}
+	byDay := make(map[string]index.WritingDay, len(all))
+	for _, d := range all {
+		byDay[d.Date] = d
+	}
+	for d := from; d.Format(time.DateOnly) <= st.To; d = d.AddDate(0, 0, 1) {
+		day := byDay[d.Format(time.DateOnly)]
+		day.Date = d.Format(time.DateOnly)
+		st.Days = append(st.Days, day)
+		st.WordsAdded += day.WordsAdded
+		st.WordsRemoved += day.WordsRemoved
+	}
+
+	st.LongestStreak = longestStreak(all)
+	d := today
+	if _, ok := byDay[d.Format(time.DateOnly)]; !ok {
+		d = d.AddDate(0, 0, -1)
+	}
+	for ; byDay[d.Format(time.DateOnly)].Edits > 0; d = d.AddDate(0, 0, -1) {
+		st.CurrentStreak++
+	}
+	return st, nil
+}
+
+// longestStreak returns the longest run of consecutive dates in days, which
+// are sorted oldest first.
+func longestStreak(days []index.WritingDay) int {
+	longest, run := 0, 0
+	var prev time.Time
+	for _, d := range days {
+		t, err := time.Parse(time.DateOnly, d.Date)
+		if err != nil || d.Edits == 0 {
+			run = 0
+			continue
+		}
+		if run > 0 && t.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = t
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// HeatmapDay is one cell of an activity heatmap.
+type HeatmapDay struct {
+	Date  string `json:"date" example:"2025-02-01" validate:"required"`
+	Count int    `json:"count" validate:"required"`
+	// Level buckets Count relative to the busiest day: 0 (none) to 4.
+	Level int `json:"level" enums:"0,1,2,3,4" validate:"required"`
+}
+
+// Heatmap is GitHub-style edit activity for the year up to To.
+type Heatmap struct {
+	From  string       `json:"from" example:"2024-02-02" validate:"required"`
+	To    string       `json:"to" example:"2025-02-01" validate:"required"`
+	Days  []HeatmapDay `json:"days" validate:"required"`
+	Total int          `json:"total" validate:"required"`
+	Max   int          `json:"max" validate:"required"`
+}
+
+// heatmapLevels is the number of non-zero heatmap levels.
+const heatmapLevels = 4
+
+// Heatmap returns per-day edit counts for the 365 days ending on to.
+func (s *Service) Heatmap(_ context.Context, to time.Time) (*Heatmap, error) {
+	from := to.AddDate(0, 0, -364)
+	hm := &Heatmap{From: from.Format(time.DateOnly), To: to.Format(time.DateOnly)}
+	rows, err := s.db.WritingActivity(hm.From, hm.To)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, r := range rows {
+		counts[r.Date] = r.Edits
+		hm.Total += r.Edits
+		hm.Max = max(hm.Max, r.Edits)
+	}
+	for d := from; d.Format(time.DateOnly) <= hm.To; d = d.AddDate(0, 0, 1) {
+		day := HeatmapDay{Date: d.Format(time.DateOnly), Count: counts[d.Format(time.DateOnly)]}
+		if day.Count > 0 {
+			day.Level = (day.Count*heatmapLevels + hm.Max - 1) / hm.Max
+		}
+		hm.Days = append(hm.Days, day)
+	}
+	return hm, nil
+}
@@ -0,0 +1,79 @@
+package noteservice
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/index"
+)
+
+func TestWritingStats(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "one two three")
+	if _, err := svc.UpdateNote(ctx, "a.md", []byte("three one four five"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.DeleteNote(ctx, "a.md"); err != nil {
+		t.Fatal(err)
+	}
+	// Batch writes count like single ones.
+	if _, _, err := svc.Batch(ctx, []BatchOp{{Op: "create", Path: "b.md", Content: "six seven"}}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now()
+	st, err := svc.WritingStats(ctx, today.AddDate(0, 0, -6), today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Days) != 7 {
+		t.Fatalf("days = %d, want 7", len(st.Days))
+	}
+	// create +3, update +2 -1, delete -4, batch create +2.
+	d := st.Days[6]
+	if d.Edits != 4 || d.WordsAdded != 7 || d.WordsRemoved != 5 {
+		t.Errorf("today = %+v", d)
+	}
+	if st.WordsAdded != 7 || st.CurrentStreak != 1 || st.LongestStreak != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestLongestStreak(t *testing.T) {
+	days := []index.WritingDay{
+		{Date: "2025-01-01", Edits: 1}, {Date: "2025-01-02", Edits: 2},
+		{Date: "2025-01-05", Edits: 1}, {Date: "2025-01-06", Edits: 1}, {Date: "2025-01-07", Edits: 1},
+		{Date: "2025-01-09", Edits: 1},
+	}
+	if got := longestStreak(days); got != 3 {
+		t.Errorf("longestStreak = %d, want 3", got)
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "x")
+	for i := range 3 {
+		if _, err := svc.UpdateNote(ctx, "a.md", []byte(fmt.Sprint(i)), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hm, err := svc.Heatmap(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hm.Days) != 365 || hm.Total != 4 || hm.Max != 4 {
+		t.Fatalf("heatmap = %d days, total %d, max %d", len(hm.Days), hm.Total, hm.Max)
+	}
+	if d := hm.Days[364]; d.Count != 4 || d.Level != 4 || d.Date != time.Now().Format(time.DateOnly) {
+		t.Errorf("today = %+v", d)
+	}
+	if hm.Days[0].Level != 0 {
+		t.Errorf("first day = %+v", hm.Days[0])
+	}
+}
@@ -0,0 +1,190 @@
+package noteservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/checksum"
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// DefaultInboxPath is where Capture appends when no inbox is configured.
+const DefaultInboxPath = "inbox.md"
+
+// captureTimeLayout is the timestamp prefix of captured entries.
+const captureTimeLayout = "2006-01-02 15:04"
+
+// WithInboxPath sets the note Capture appends to.
+func WithInboxPath(p string) Option {
+	return func(s *Service) {
+		if p != "" {
+			s.inboxPath = p
+		}
+	}
+}
+
+// InboxPath returns the note Capture appends to.
+func (s *Service) InboxPath() string {
+	return s.inboxPath
+}
+
+// Capture appends text to the inbox note as a timestamped bullet, followed
+// by tags as #tags, creating the inbox if it does not exist. Lines after the
+// first are indented under the bullet.
+func (s *Service) Capture(_ context.Context, text string, tags []string) (*NoteDetail, error) {
+	var b strings.Builder
+	b.WriteString(bullet(time.Now().Format(captureTimeLayout), text))
+	for _, t := range tags {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "#")
+		if t == "" {
+			continue
+		}
+		b.WriteString(" #")
+		b.WriteString(t)
+	}
+	return s.appendBlock(s.inboxPath, b.String(), "", func() ([]byte, error) {
+		return []byte("# Inbox\n\n"), nil
+	})
+}
+
+// ErrHeadingNotFound is returned by AppendNote when the note has no section
+// with the requested heading. It wraps apperr.ErrNotFound.
+var ErrHeadingNotFound = fmt.Errorf("heading %w", apperr.ErrNotFound)
+
+// AppendNote appends content to the end of an existing note, starting on a
+// new line, and re-indexes it. With a heading ("Inbox" or "## Inbox",
+// matched case-insensitively) the content goes to the end of that section
+// instead, before any trailing blank lines. Unlike a read-modify-write
+// through UpdateNote, concurrent appends never overwrite each other.
+func (s *Service) AppendNote(_ context.Context, path, content, heading string) (*NoteDetail, error) {
+	return s.appendBlock(path, content, heading, nil)
+}
+
+// appendBlock appends block as new line(s) at the end of the note at p, or
+// of its section under heading if set, and re-indexes it. When the note does not exist it is created with the content
+// from initial, or apperr.ErrNotFound is returned if initial is nil. Appends are
+// serialized, so concurrent callers never lose each other's blocks.
+func (s *Service) appendBlock(p, block, heading string, initial func() ([]byte, error)) (*NoteDetail, error) {
+	return s.modify(p, "", initial, func(content []byte) ([]byte, error) {
+		return insertBlock(content, block, heading)
+	})
+}
+
+// modify rewrites the note at p with edit and re-indexes it, holding
+// appendMu so in-place edits never lose each other's changes. A non-empty
+// ifMatch must equal the checksum of the stored note (apperr.ErrConflict
+// otherwise). A missing note is created from initial, or
+// apperr.ErrNotFound is returned if initial is nil.
+func (s *Service) modify(p, ifMatch string, initial func() ([]byte, error), edit func([]byte) ([]byte, error)) (*NoteDetail, error) {
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
+
+	existing, err := s.store.Read(p)
+	exists := err == nil
+	switch {
+	case exists:
+		if ifMatch != "" && ifMatch != checksum.Sum(existing) {
+			return nil, apperr.ErrConflict
+		}
+	case errors.Is(err, os.ErrNotExist) && initial != nil:
+	case errors.Is(err, os.ErrNotExist):
+		return nil, apperr.ErrNotFound
+	default:
+		return nil, err
+	}
+
+	var content []byte
+	if exists {
+		content = append(content, existing...)
+	} else if content, err = initial(); err != nil {
+		return nil, err
+	}
+	content, err = edit(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return s.create(p, content)
+	}
+	if err := s.store.Write(p, content); err != nil {
+		return nil, err
+	}
+	if err := s.IndexFile(p, content); err != nil {
+		return nil, err
+	}
+	m := s.record(MutationUpdated, p, existing, content)
+	note, err := s.buildNoteDetail(p, content)
+	if err != nil {
+		return nil, err
+	}
+	note.MutationID = m.ID
+	return note, nil
+}
+
+// insertBlock returns content with block inserted as whole lines at the end,
+// or at the end of the first section whose heading matches heading.
+func insertBlock(content []byte, block, heading string) ([]byte, error) {
+	at := len(content)
+	if heading != "" {
+		h, ok := findHeading(content, heading)
+		if !ok {
+			return nil, ErrHeadingNotFound
+		}
+		at = sectionBodyEnd(content, h)
+	}
+	return insertLines(content, at, block), nil
+}
+
+// findHeading returns the first heading of content whose text matches
+// heading ("Inbox" or "## Inbox"), case-insensitively.
+func findHeading(content []byte, heading string) (parser.Heading, bool) {
+	want := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(heading), "#"))
+	for _, h := range parser.Headings(content) {
+		if strings.EqualFold(h.Text, want) {
+			return h, true
+		}
+	}
+	return parser.Heading{}, false
+}
+
+// sectionBodyEnd returns the offset after the last non-blank line of the
+// section under h, or h.End if the section is empty.
+func sectionBodyEnd(content []byte, h parser.Heading) int {
+	body := bytes.TrimRight(content[h.End:h.SectionEnd], " \t\r\n")
+	at := h.End + len(body)
+	if len(body) > 0 {
+		if at < len(content) && content[at] == '\r' {
+			at++
+		}
+		if at < len(content) && content[at] == '\n' {
+			at++
+		}
+	}
+	return at
+}
+
+// insertLines returns content with block inserted as whole lines at offset
+// at, which must be at the start of a line or the end of content.
+func insertLines(content []byte, at int, block string) []byte {
+	out := make([]byte, 0, len(content)+len(block)+2)
+	out = append(out, content[:at]...)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	out = append(out, strings.TrimRight(block, "\n")...)
+	out = append(out, '\n')
+	return append(out, content[at:]...)
+}
+
+// bullet formats a list item "- <stamp> <text>", indenting further lines of
+// text under it.
+func bullet(stamp, text string) string {
+	return "- " + stamp + " " + strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n  ")
+}
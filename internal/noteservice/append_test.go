@@ -0,0 +1,62 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCapture_Concurrent(t *testing.T) {
+	svc := testService(t, WithInboxPath("log/inbox.md"))
+	ctx := context.Background()
+
+	const n = 20
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Capture(ctx, "entry", nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	note, err := svc.GetNote(ctx, "log/inbox.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(note.Content, " entry\n"); got != n {
+		t.Errorf("entries = %d, want %d:\n%s", got, n, note.Content)
+	}
+	if note.ID == "" {
+		t.Error("created inbox has no id")
+	}
+}
+
+func TestInsertBlock(t *testing.T) {
+	doc := "# Title\n\n## Inbox\n- a\n\n## Empty\n\n## Last\ntext"
+	cases := []struct {
+		name, heading, want string
+	}{
+		{"end", "", doc + "\n- b\n"},
+		{"section", "inbox", "# Title\n\n## Inbox\n- a\n- b\n\n## Empty\n\n## Last\ntext"},
+		{"hashes", "## Empty", "# Title\n\n## Inbox\n- a\n\n## Empty\n- b\n\n## Last\ntext"},
+		{"last section", "Last", doc + "\n- b\n"},
+	}
+	for _, c := range cases {
+		got, err := insertBlock([]byte(doc), "- b\n", c.heading)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if string(got) != c.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", c.name, got, c.want)
+		}
+	}
+	if _, err := insertBlock([]byte(doc), "x", "Missing"); !errors.Is(err, ErrHeadingNotFound) {
+		t.Errorf("missing heading: err = %v", err)
+	}
+}
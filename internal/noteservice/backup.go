@@ -0,0 +1,27 @@
+package noteservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/backup"
+)
+
+// WithBackups enables Backup, writing archives to p.Dir and keeping those
+// p keeps.
+func WithBackups(p backup.Policy) Option {
+	return func(s *Service) {
+		s.backups = p
+	}
+}
+
+// Backup writes a backup archive of the vault and, with withIndex, the
+// index, then applies the retention policy (see backup.Create). It fails
+// with apperr.ErrNotFound when backups are not configured.
+func (s *Service) Backup(ctx context.Context, withIndex bool) (*backup.Info, error) {
+	if s.backups.Dir == "" {
+		return nil, fmt.Errorf("%w: backups are not configured", apperr.ErrNotFound)
+	}
+	return backup.Create(ctx, s.store, s.db, s.backups, withIndex)
+}
@@ -0,0 +1,277 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/checksum"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/merge"
+	"github.com/starford/kenaz/internal/parser"
+	"github.com/starford/kenaz/internal/storage"
+)
+
+// Batch operations.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MaxBatchOps caps the operations in one Batch call.
+const MaxBatchOps = 1000
+
+// ErrInvalidBatch is returned by Batch for an empty or oversized batch.
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// WithBatchHook registers fn to be called after every Batch that changed
+// notes, with the changed paths (e.g. to publish one event for the whole
+// batch instead of one per note).
+func WithBatchHook(fn func(paths []string)) Option {
+	return func(s *Service) { s.onBatch = fn }
+}
+
+// BatchOp is one operation of a Batch.
+type BatchOp struct {
+	// Op is "create", "update", or "delete".
+	Op   string `json:"op" example:"create" validate:"required"`
+	Path string `json:"path" example:"imports/meeting.md" validate:"required"`
+	// Content is the note content for create and update.
+	Content string `json:"content,omitempty"`
+	// Checksum is the checksum an update or delete is based on, like
+	// If-Match; required for updates with vault.require_if_match.
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// BatchItem is the outcome of one BatchOp.
+type BatchItem struct {
+	Op   string `json:"op" validate:"required"`
+	Path string `json:"path" validate:"required"`
+	// Checksum is the note's new checksum after a create or update.
+	Checksum string `json:"checksum,omitempty"`
+	// Err is why the operation failed; nil on success.
+	Err error `json:"-"`
+}
+
+// Batch runs ops in order and returns one item per op. Failed operations
+// do not stop the others. Files are written one by one, but the index is
+// updated in a single transaction at the end and the batch hook runs once.
+// Created and updated notes get the same handling as CreateNote and
+// UpdateNote (an id, a revision of the replaced content); deleted notes go
+// to the trash. Batch operations are not recorded for Undo.
+//
+// With dryRun nothing is written: the items report what each operation
+// would do, later operations see the effect of earlier ones, and the
+// returned changes hold a diff per created or updated note.
+func (s *Service) Batch(ctx context.Context, ops []BatchOp, dryRun bool) ([]BatchItem, []PlannedChange, error) {
+	if len(ops) == 0 || len(ops) > MaxBatchOps {
+		return nil, nil, fmt.Errorf("%w: need 1 to %d operations, got %d", ErrInvalidBatch, MaxBatchOps, len(ops))
+	}
+	var plan *batchPlan
+	if dryRun {
+		plan = &batchPlan{files: make(map[string][]byte)}
+	}
+	items := make([]BatchItem, len(ops))
+	// Only the last change to each path reaches the index.
+	final := make(map[string]*index.IndexedNote)
+	var b index.NoteBatch
+	var order []string
+	now := time.Now()
+	for i, op := range ops {
+		items[i] = BatchItem{Op: op.Op, Path: op.Path}
+		if err := checkBatchPath(op.Path); err != nil {
+			items[i].Err = err
+			continue
+		}
+		switch op.Op {
+		case BatchCreate, BatchUpdate:
+			if op.Content == "" {
+				items[i].Err = fmt.Errorf("%w: %s needs content", ErrInvalidBatch, op.Op)
+				continue
+			}
+			n, err := s.batchWrite(op, plan)
+			if err != nil {
+				items[i].Err = err
+				continue
+			}
+			items[i].Checksum = n.Row.Checksum
+			final[op.Path] = n
+		case BatchDelete:
+			e, err := s.batchDelete(op, now, plan)
+			if err != nil {
+				items[i].Err = err
+				continue
+			}
+			final[op.Path] = nil
+			b.Trash = append(b.Trash, e)
+		default:
+			items[i].Err = fmt.Errorf("%w: unknown op %q", ErrInvalidBatch, op.Op)
+			continue
+		}
+		order = append(order, op.Path)
+	}
+	if plan != nil {
+		return items, plan.changes, nil
+	}
+
+	var changed []string
+	for _, p := range order {
+		n, ok := final[p]
+		if !ok {
+			continue // already applied
+		}
+		delete(final, p)
+		changed = append(changed, p)
+		if n != nil {
+			b.Upserts = append(b.Upserts, *n)
+			continue
+		}
+		b.Deletes = append(b.Deletes, p)
+	}
+	if len(changed) == 0 {
+		return items, nil, nil
+	}
+	// The files are written already; index them even if the caller is gone.
+	if err := s.db.ApplyBatch(context.WithoutCancel(ctx), b); err != nil {
+		return nil, nil, err
+	}
+	if s.onBatch != nil {
+		s.onBatch(changed)
+	}
+	return items, nil, nil
+}
+
+// batchPlan records the changes of a dry-run Batch or ImportVault in place
+// of writing them.
+type batchPlan struct {
+	// files holds the content each changed path would have; nil for
+	// deleted paths.
+	files   map[string][]byte
+	changes []PlannedChange
+}
+
+// batchRead reads p as the batch so far left it: through plan on a dry
+// run, from the vault otherwise.
+func (s *Service) batchRead(p string, plan *batchPlan) ([]byte, error) {
+	if plan != nil {
+		if data, ok := plan.files[p]; ok {
+			if data == nil {
+				return nil, os.ErrNotExist
+			}
+			return data, nil
+		}
+	}
+	return s.store.Read(p)
+}
+
+// batchWrite writes the note of a create or update op, or records it in
+// plan on a dry run, and returns it parsed for the index.
+func (s *Service) batchWrite(op BatchOp, plan *batchPlan) (*index.IndexedNote, error) {
+	content := []byte(op.Content)
+	existing, err := s.batchRead(op.Path, plan)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if op.Op == BatchCreate {
+		if exists {
+			return nil, apperr.ErrAlreadyExists
+		}
+		res, err := s.db.Parse(content)
+		if err != nil {
+			return nil, err
+		}
+		if res.ID == "" {
+			content = parser.SetFrontmatterField(content, "id", uuid.New().String())
+		}
+	} else {
+		if !exists {
+			return nil, apperr.ErrNotFound
+		}
+		if err := s.checkBatchMatch(op, existing); err != nil {
+			return nil, err
+		}
+	}
+	if plan != nil {
+		action := ChangeCreate
+		if exists {
+			action = ChangeUpdate
+		}
+		plan.files[op.Path] = content
+		plan.changes = append(plan.changes, PlannedChange{Action: action, Path: op.Path, Diff: merge.Diff(op.Path, string(existing), string(content))})
+	} else {
+		if exists {
+			if err := s.saveRevision(op.Path, existing, content); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.store.Write(op.Path, content); err != nil {
+			return nil, err
+		}
+	}
+	n, err := s.indexedNote(op.Path, content)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// batchDelete moves the note of a delete op to the trash, or records it in
+// plan on a dry run, and returns its trash entry.
+func (s *Service) batchDelete(op BatchOp, now time.Time, plan *batchPlan) (index.TrashEntry, error) {
+	existing, err := s.batchRead(op.Path, plan)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return index.TrashEntry{}, apperr.ErrNotFound
+		}
+		return index.TrashEntry{}, err
+	}
+	if op.Checksum != "" && op.Checksum != checksum.Sum(existing) {
+		return index.TrashEntry{}, apperr.ErrConflict
+	}
+	e := index.TrashEntry{Path: op.Path, DeletedAt: now}
+	if res, err := s.db.Parse(existing); err == nil {
+		e.Title = res.Title
+	}
+	if plan != nil {
+		plan.files[op.Path] = nil
+		plan.changes = append(plan.changes, PlannedChange{Action: ChangeDelete, Path: op.Path})
+		return e, nil
+	}
+	if err := s.moveToTrash(op.Path); err != nil {
+		return index.TrashEntry{}, err
+	}
+	return e, nil
+}
+
+// checkBatchMatch applies UpdateNote's optimistic concurrency rules to an
+// update op.
+func (s *Service) checkBatchMatch(op BatchOp, existing []byte) error {
+	if op.Checksum == "" {
+		if s.requireIfMatch {
+			return apperr.ErrPreconditionRequired
+		}
+		return nil
+	}
+	if op.Checksum != checksum.Sum(existing) {
+		return apperr.ErrConflict
+	}
+	return nil
+}
+
+// checkBatchPath rejects paths that are not notes or lie in the trash or
+// the state folder.
+func checkBatchPath(p string) error {
+	if !strings.HasSuffix(p, ".md") || path.Clean(p) != p || strings.HasPrefix(p, "../") || path.IsAbs(p) || storage.InReserved(p) {
+		return fmt.Errorf("%w: %q", apperr.ErrInvalidPath, p)
+	}
+	return nil
+}
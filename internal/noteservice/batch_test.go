@@ -0,0 +1,108 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestBatch(t *testing.T) {
+	var hooked [][]string
+	svc := testService(t, WithBatchHook(func(paths []string) { hooked = append(hooked, paths) }))
+	ctx := context.Background()
+	createNote(t, svc, "old.md", "# Old\n")
+	createNote(t, svc, "keep.md", "# Keep\n")
+
+	items, _, err := svc.Batch(ctx, []BatchOp{
+		{Op: BatchCreate, Path: "imports/a.md", Content: "# A\nsee [[imports/b]]\n"},
+		{Op: BatchCreate, Path: "imports/b.md", Content: "# B\n"},
+		{Op: BatchUpdate, Path: "imports/b.md", Content: "# B2\n"},
+		{Op: BatchDelete, Path: "old.md"},
+		{Op: BatchCreate, Path: "keep.md", Content: "# Dup\n"},
+		{Op: BatchUpdate, Path: "keep.md", Content: "# K\n", Checksum: "stale"},
+		{Op: BatchDelete, Path: "missing.md"},
+		{Op: BatchCreate, Path: ".trash/x.md", Content: "x"},
+		{Op: "rename", Path: "keep.md"},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErr := []error{nil, nil, nil, nil, apperr.ErrAlreadyExists, apperr.ErrConflict, apperr.ErrNotFound, apperr.ErrInvalidPath, ErrInvalidBatch}
+	for i, it := range items {
+		if (wantErr[i] == nil) != (it.Err == nil) || (wantErr[i] != nil && !errors.Is(it.Err, wantErr[i])) {
+			t.Errorf("item %d (%s %s) err = %v, want %v", i, it.Op, it.Path, it.Err, wantErr[i])
+		}
+	}
+	if items[0].Checksum == "" {
+		t.Error("created item has no checksum")
+	}
+
+	if row, _ := svc.db.GetNote("imports/b.md"); row == nil || row.Title != "B2" {
+		t.Errorf("imports/b.md row = %+v", row)
+	}
+	if bl, _ := svc.db.Backlinks("imports/b.md"); !slices.Contains(bl, "imports/a.md") {
+		t.Errorf("backlinks of b = %v", bl)
+	}
+	if row, _ := svc.db.GetNote("old.md"); row != nil {
+		t.Error("deleted note still indexed")
+	}
+	if e, _ := svc.db.TrashEntry("old.md"); e == nil || e.Title != "Old" {
+		t.Errorf("trash entry = %+v", e)
+	}
+	if len(hooked) != 1 || !slices.Equal(hooked[0], []string{"imports/a.md", "imports/b.md", "old.md"}) {
+		t.Errorf("batch hook calls = %v", hooked)
+	}
+
+	if _, _, err := svc.Batch(ctx, nil, false); !errors.Is(err, ErrInvalidBatch) {
+		t.Errorf("empty batch err = %v", err)
+	}
+}
+
+func TestBatchDryRun(t *testing.T) {
+	var hooked int
+	svc := testService(t, WithBatchHook(func([]string) { hooked++ }))
+	ctx := context.Background()
+	createNote(t, svc, "keep.md", "# Keep\n")
+
+	items, changes, err := svc.Batch(ctx, []BatchOp{
+		{Op: BatchCreate, Path: "new.md", Content: "---\nid: new\n---\n# New\n"},
+		{Op: BatchUpdate, Path: "new.md", Content: "---\nid: new\n---\n# New 2\n"},
+		{Op: BatchUpdate, Path: "keep.md", Content: "# Kept\n"},
+		{Op: BatchDelete, Path: "keep.md"},
+		{Op: BatchDelete, Path: "keep.md"},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[1].Err != nil || items[3].Err != nil || !errors.Is(items[4].Err, apperr.ErrNotFound) {
+		t.Errorf("items = %+v", items)
+	}
+	var actions []string
+	for _, c := range changes {
+		actions = append(actions, c.Action+" "+c.Path)
+	}
+	want := []string{"create new.md", "update new.md", "update keep.md", "delete keep.md"}
+	if !slices.Equal(actions, want) {
+		t.Errorf("changes = %v, want %v", actions, want)
+	}
+	if !strings.Contains(changes[2].Diff, "-# Keep") || !strings.Contains(changes[2].Diff, "+# Kept") {
+		t.Errorf("update diff = %q", changes[2].Diff)
+	}
+
+	if _, err := svc.store.Read("new.md"); err == nil {
+		t.Error("dry run wrote new.md")
+	}
+	if got, _ := svc.store.Read("keep.md"); !strings.Contains(string(got), "# Keep\n") {
+		t.Errorf("dry run changed keep.md: %q", got)
+	}
+	if row, _ := svc.db.GetNote("new.md"); row != nil {
+		t.Error("dry run indexed new.md")
+	}
+	if hooked != 0 {
+		t.Errorf("batch hook ran %d times on a dry run", hooked)
+	}
+}
@@ -0,0 +1,245 @@
+package noteservice
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// DefaultBookmarksFolder is where bookmark notes are created when no folder
+// is configured.
+const DefaultBookmarksFolder = "bookmarks"
+
+// bookmarkType is the frontmatter "type" of bookmark notes.
+const bookmarkType = "bookmark"
+
+// WithBookmarksFolder sets the folder bookmark notes are created in and
+// listed from.
+func WithBookmarksFolder(folder string) Option {
+	return func(s *Service) {
+		if f := strings.Trim(folder, "/"); f != "" {
+			s.bookmarksFolder = f
+		}
+	}
+}
+
+// Bookmark is a saved web page: a note with type "bookmark" and the page's
+// URL in its frontmatter.
+type Bookmark struct {
+	Path        string   `json:"path" validate:"required"`
+	URL         string   `json:"url" validate:"required"`
+	Title       string   `json:"title" validate:"required"`
+	Description string   `json:"description,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	Site        string   `json:"site" example:"go.dev" validate:"required"`
+	Tags        []string `json:"tags" validate:"required"`
+	Read        bool     `json:"read" validate:"required"`
+	// Saved is when the bookmark was created (RFC 3339).
+	Saved string `json:"saved,omitempty"`
+}
+
+// BookmarkFilter selects bookmarks in ListBookmarks. Zero fields match
+// everything.
+type BookmarkFilter struct {
+	Tag    string
+	Site   string
+	Unread bool
+	// Query is a full-text search over the bookmark notes.
+	Query string
+}
+
+// bookmarkFrontmatter is the frontmatter written for a new bookmark, in
+// field order.
+type bookmarkFrontmatter struct {
+	Type        string   `yaml:"type"`
+	URL         string   `yaml:"url"`
+	Title       string   `yaml:"title"`
+	Description string   `yaml:"description,omitempty"`
+	Image       string   `yaml:"image,omitempty"`
+	Site        string   `yaml:"site"`
+	Tags        []string `yaml:"tags,flow"`
+	Read        bool     `yaml:"read"`
+	Saved       string   `yaml:"saved"`
+}
+
+// CreateBookmark saves b as a note under the bookmarks folder, named after
+// its title (or site), with the description quoted in the body so full-text
+// search finds it. It fails with apperr.ErrAlreadyExists when the URL is
+// already bookmarked.
+func (s *Service) CreateBookmark(ctx context.Context, b Bookmark) (*NoteDetail, error) {
+	u, err := url.Parse(b.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: bookmark url must be http(s)", apperr.ErrInvalidPath)
+	}
+	existing, err := s.ListBookmarks(ctx, BookmarkFilter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range existing {
+		if e.URL == b.URL {
+			return nil, fmt.Errorf("%w: %s is bookmarked in %s", apperr.ErrAlreadyExists, b.URL, e.Path)
+		}
+	}
+
+	site := strings.TrimPrefix(u.Hostname(), "www.")
+	title := strings.TrimSpace(b.Title)
+	if title == "" {
+		title = site
+	}
+	tags := []string{bookmarkType}
+	for _, t := range b.Tags {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "#")
+		if t != "" && !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	fm, err := yaml.Marshal(bookmarkFrontmatter{
+		Type:        bookmarkType,
+		URL:         b.URL,
+		Title:       title,
+		Description: b.Description,
+		Image:       b.Image,
+		Site:        site,
+		Tags:        tags,
+		Saved:       time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var body strings.Builder
+	body.WriteString("---\n")
+	body.Write(fm)
+	body.WriteString("---\n# " + title + "\n\n")
+	if b.Description != "" {
+		body.WriteString("> " + strings.Join(strings.Split(b.Description, "\n"), "\n> ") + "\n\n")
+	}
+	body.WriteString("<" + b.URL + ">\n")
+
+	p, err := s.SuggestPath(ctx, title, s.bookmarksFolder)
+	if err != nil {
+		return nil, err
+	}
+	return s.create(p, []byte(body.String()))
+}
+
+// ListBookmarks returns the bookmarks under the bookmarks folder matching f,
+// newest first.
+func (s *Service) ListBookmarks(_ context.Context, f BookmarkFilter) ([]Bookmark, error) {
+	rows, err := s.db.NotesWithPrefix(s.bookmarksFolder + "/")
+	if err != nil {
+		return nil, err
+	}
+	var hits map[string]struct{}
+	if strings.TrimSpace(f.Query) != "" {
+		res, _, err := s.db.Search(f.Query, index.SearchOptions{Folder: s.bookmarksFolder, Limit: len(rows) + 1})
+		if err != nil {
+			return nil, err
+		}
+		hits = make(map[string]struct{}, len(res))
+		for _, r := range res {
+			hits[r.Path] = struct{}{}
+		}
+	}
+
+	out := []Bookmark{}
+	for _, row := range rows {
+		if hits != nil {
+			if _, ok := hits[row.Path]; !ok {
+				continue
+			}
+		}
+		data, err := s.store.Read(row.Path)
+		if err != nil {
+			continue
+		}
+		res, err := s.db.Parse(data)
+		if err != nil {
+			continue
+		}
+		b, ok := bookmarkFrom(row.Path, res)
+		if !ok {
+			continue
+		}
+		if f.Tag != "" && !slices.Contains(b.Tags, f.Tag) {
+			continue
+		}
+		if f.Site != "" && b.Site != strings.TrimPrefix(f.Site, "www.") {
+			continue
+		}
+		if f.Unread && b.Read {
+			continue
+		}
+		out = append(out, b)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Saved != out[j].Saved {
+			return out[i].Saved > out[j].Saved
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out, nil
+}
+
+// bookmarkFrom reads a Bookmark from a parsed note, reporting false when
+// the note is not a bookmark.
+func bookmarkFrom(p string, res *parser.Result) (Bookmark, bool) {
+	fm := res.Frontmatter
+	rawURL, _ := fm["url"].(string)
+	if rawURL == "" || (fm["type"] != nil && fm["type"] != bookmarkType) {
+		return Bookmark{}, false
+	}
+	b := Bookmark{
+		Path:  p,
+		URL:   rawURL,
+		Title: res.Title,
+		Tags:  nonNilSlice(res.Tags),
+	}
+	b.Description, _ = fm["description"].(string)
+	b.Image, _ = fm["image"].(string)
+	b.Read, _ = fm["read"].(bool)
+	b.Site, _ = fm["site"].(string)
+	if b.Site == "" {
+		if u, err := url.Parse(rawURL); err == nil {
+			b.Site = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+	}
+	switch v := fm["saved"].(type) {
+	case string:
+		b.Saved = v
+	case time.Time:
+		b.Saved = v.Format(time.RFC3339)
+	}
+	if b.Title == "" {
+		b.Title = path.Base(p)
+	}
+	return b, true
+}
+
+// MarkBookmarkRead sets the "read" frontmatter field of the bookmark at p.
+func (s *Service) MarkBookmarkRead(ctx context.Context, p string, read bool) (*NoteDetail, error) {
+	note, err := s.GetNote(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.Parse([]byte(note.Content))
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := bookmarkFrom(p, res); !ok {
+		return nil, fmt.Errorf("%w: %s is not a bookmark", apperr.ErrNotFound, p)
+	}
+	content := parser.SetFrontmatterField([]byte(note.Content), "read", fmt.Sprint(read))
+	return s.UpdateNote(ctx, p, content, note.Checksum)
+}
@@ -0,0 +1,65 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestBookmarks(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+
+	note, err := svc.CreateBookmark(ctx, Bookmark{
+		URL:         "https://www.example.com/post",
+		Title:       "Example Post",
+		Description: "A walkthrough of sourdough baking",
+		Tags:        []string{"#cooking"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Path != "bookmarks/example-post.md" {
+		t.Errorf("path = %q", note.Path)
+	}
+	if !strings.Contains(note.Content, "type: bookmark\n") || !strings.Contains(note.Content, "> A walkthrough") {
+		t.Errorf("content:\n%s", note.Content)
+	}
+	if _, err := svc.CreateBookmark(ctx, Bookmark{URL: "https://go.dev/doc", Tags: []string{"golang"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateBookmark(ctx, Bookmark{URL: "https://www.example.com/post"}); !errors.Is(err, apperr.ErrAlreadyExists) {
+		t.Errorf("duplicate err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := svc.CreateBookmark(ctx, Bookmark{URL: "ftp://example.com/x"}); !errors.Is(err, apperr.ErrInvalidPath) {
+		t.Errorf("ftp err = %v, want ErrInvalidPath", err)
+	}
+
+	all, err := svc.ListBookmarks(ctx, BookmarkFilter{})
+	if err != nil || len(all) != 2 {
+		t.Fatalf("list = %+v, %v", all, err)
+	}
+	if b, _ := svc.ListBookmarks(ctx, BookmarkFilter{Site: "example.com"}); len(b) != 1 || b[0].Title != "Example Post" {
+		t.Errorf("site filter = %+v", b)
+	}
+	if b, _ := svc.ListBookmarks(ctx, BookmarkFilter{Tag: "golang"}); len(b) != 1 || b[0].Title != "go.dev" {
+		t.Errorf("tag filter = %+v", b)
+	}
+	if b, _ := svc.ListBookmarks(ctx, BookmarkFilter{Query: "sourdough"}); len(b) != 1 || b[0].Path != note.Path {
+		t.Errorf("query = %+v", b)
+	}
+
+	if _, err := svc.MarkBookmarkRead(ctx, note.Path, true); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := svc.ListBookmarks(ctx, BookmarkFilter{Unread: true}); len(b) != 1 || b[0].Site != "go.dev" {
+		t.Errorf("unread = %+v", b)
+	}
+	createNote(t, svc, "plain.md", "# Plain\n")
+	if _, err := svc.MarkBookmarkRead(ctx, "plain.md", true); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("non-bookmark err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,269 @@
+package noteservice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/checksum"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/merge"
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// frontmatterKeyRe matches the frontmatter keys EditFrontmatter may set or
+// remove.
+var frontmatterKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// EditedNote is one note changed by a bulk edit.
+type EditedNote struct {
+	Path string `json:"path" validate:"required"`
+	// MutationID identifies the note's write for Undo; empty on dry runs
+	// and when undo is disabled.
+	MutationID string `json:"mutation_id,omitempty"`
+}
+
+// BulkEdit reports the notes a tag rename or frontmatter edit changed, or
+// would change on a dry run, whose Changes hold a diff per note.
+type BulkEdit struct {
+	Notes   []EditedNote    `json:"notes" validate:"required"`
+	DryRun  bool            `json:"dry_run"`
+	Changes []PlannedChange `json:"changes,omitempty"`
+}
+
+// FrontmatterEdit describes a frontmatter change to many notes: Set fields
+// are added or replaced, Unset fields removed. The notes are Paths when
+// given, otherwise every note, optionally restricted to Folder and Tag.
+type FrontmatterEdit struct {
+	Paths  []string
+	Folder string
+	Tag    string
+	Set    map[string]any
+	Unset  []string
+	DryRun bool
+}
+
+// RenameTag renames the tag from to to in every note that has it, both in
+// the frontmatter tags list and as inline #tags. Nested tags move with it
+// ("from/x" becomes "to/x"). Notes that already have to end up with it
+// once, so renaming onto an existing tag merges the two. Each note is
+// written like UpdateNote and is undoable on its own.
+func (s *Service) RenameTag(ctx context.Context, from, to string, dryRun bool) (*BulkEdit, error) {
+	if !parser.ValidTag(from) || !parser.ValidTag(to) {
+		return nil, fmt.Errorf("%w: invalid tag name", apperr.ErrInvalidPath)
+	}
+	if from == to {
+		return nil, fmt.Errorf("%w: tag is renamed to itself", apperr.ErrInvalidPath)
+	}
+	rename := func(tag string) string {
+		if tag == from {
+			return to
+		}
+		if rest, ok := strings.CutPrefix(tag, from+"/"); ok {
+			return to + "/" + rest
+		}
+		return tag
+	}
+	rows, err := s.selectNotes("", "")
+	if err != nil {
+		return nil, err
+	}
+	rows = slices.DeleteFunc(rows, func(row index.NoteRow) bool {
+		return !slices.ContainsFunc(row.Tags, func(tag string) bool { return rename(tag) != tag })
+	})
+
+	res, err := s.bulkEdit(ctx, rows, dryRun, func(data []byte) ([]byte, error) {
+		parsed, err := s.db.Parse(data)
+		if err != nil {
+			return data, nil
+		}
+		head := data[:len(data)-len(parsed.Body)]
+		updated := append(slices.Clone(head), parser.RenameInlineTags(parsed.Body, rename)...)
+		list, ok := parsed.Frontmatter["tags"].([]any)
+		if !ok {
+			return updated, nil
+		}
+		var tags []any
+		changed := false
+		for _, item := range list {
+			if tag, ok := item.(string); ok {
+				if renamed := rename(tag); renamed != tag {
+					item, changed = renamed, true
+				}
+			}
+			if !slices.Contains(tags, item) {
+				tags = append(tags, item)
+			}
+		}
+		if !changed {
+			return updated, nil
+		}
+		v, err := yamlValue(tags)
+		if err != nil {
+			return nil, err
+		}
+		return parser.SetFrontmatterField(updated, "tags", v), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun && len(res.Notes) > 0 {
+		slog.Info("tag renamed", slog.String("from", from), slog.String("to", to), slog.Int("notes", len(res.Notes)))
+	}
+	return res, nil
+}
+
+// EditFrontmatter applies e to the frontmatter of its notes, creating a
+// frontmatter block where a note has none. Values are written as YAML;
+// lists and maps in flow style. Notes left as they were are not written.
+// Each changed note is written like UpdateNote and is undoable on its own.
+func (s *Service) EditFrontmatter(ctx context.Context, e FrontmatterEdit) (*BulkEdit, error) {
+	if len(e.Set) == 0 && len(e.Unset) == 0 {
+		return nil, fmt.Errorf("%w: nothing to set or unset", apperr.ErrInvalidPath)
+	}
+	keys := slices.Sorted(maps.Keys(e.Set))
+	for _, k := range append(slices.Clone(keys), e.Unset...) {
+		if !frontmatterKeyRe.MatchString(k) || k == "id" {
+			return nil, fmt.Errorf("%w: cannot edit frontmatter key %q", apperr.ErrInvalidPath, k)
+		}
+	}
+	for _, k := range e.Unset {
+		if _, ok := e.Set[k]; ok {
+			return nil, fmt.Errorf("%w: key %q is both set and unset", apperr.ErrInvalidPath, k)
+		}
+	}
+	values := make(map[string]string, len(keys))
+	for _, k := range keys {
+		v, err := yamlValue(e.Set[k])
+		if err != nil {
+			return nil, fmt.Errorf("%w: value of %q: %v", apperr.ErrInvalidPath, k, err)
+		}
+		values[k] = v
+	}
+
+	var rows []index.NoteRow
+	if len(e.Paths) > 0 {
+		for _, p := range e.Paths {
+			row, err := s.db.GetNote(p)
+			if err != nil {
+				return nil, err
+			}
+			if row == nil {
+				return nil, fmt.Errorf("%w: %s", apperr.ErrNotFound, p)
+			}
+			rows = append(rows, *row)
+		}
+	} else {
+		var err error
+		if rows, err = s.selectNotes(e.Folder, e.Tag); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := s.bulkEdit(ctx, rows, e.DryRun, func(data []byte) ([]byte, error) {
+		updated := data
+		for _, k := range keys {
+			updated = parser.SetFrontmatterField(updated, k, values[k])
+		}
+		return parser.RemoveFrontmatterFields(updated, e.Unset...), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !e.DryRun && len(res.Notes) > 0 {
+		slog.Info("frontmatter edited",
+			slog.Any("set", keys), slog.Any("unset", e.Unset),
+			slog.Int("notes", len(res.Notes)))
+	}
+	return res, nil
+}
+
+// selectNotes returns the notes under folder (all notes when empty) that
+// have tag (any tag when empty), in path order.
+func (s *Service) selectNotes(folder, tag string) ([]index.NoteRow, error) {
+	prefix := ""
+	if folder != "" {
+		clean, err := cleanFolder(folder)
+		if err != nil {
+			return nil, err
+		}
+		prefix = clean + "/"
+	}
+	rows, err := s.db.NotesWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if tag != "" {
+		rows = slices.DeleteFunc(rows, func(row index.NoteRow) bool { return !slices.Contains(row.Tags, tag) })
+	}
+	slices.SortFunc(rows, func(a, b index.NoteRow) int { return strings.Compare(a.Path, b.Path) })
+	return rows, nil
+}
+
+// bulkEdit runs edit over the content of each note in rows and writes the
+// notes it changed like UpdateNote (against the checksum they were read
+// with), or on a dry run collects a diff per note.
+func (s *Service) bulkEdit(ctx context.Context, rows []index.NoteRow, dryRun bool, edit func(data []byte) ([]byte, error)) (*BulkEdit, error) {
+	res := &BulkEdit{Notes: []EditedNote{}, DryRun: dryRun}
+	for _, row := range rows {
+		data, err := s.store.Read(row.Path)
+		if err != nil {
+			continue
+		}
+		updated, err := edit(data)
+		if err != nil {
+			return nil, fmt.Errorf("edit %s: %w", row.Path, err)
+		}
+		if bytes.Equal(updated, data) {
+			continue
+		}
+		note := EditedNote{Path: row.Path}
+		if dryRun {
+			res.Changes = append(res.Changes, PlannedChange{Action: ChangeUpdate, Path: row.Path, Diff: merge.Diff(row.Path, string(data), string(updated))})
+		} else {
+			detail, err := s.UpdateNote(ctx, row.Path, updated, checksum.Sum(data))
+			if err != nil {
+				return nil, fmt.Errorf("edit %s: %w", row.Path, err)
+			}
+			note.MutationID = detail.MutationID
+		}
+		res.Notes = append(res.Notes, note)
+	}
+	return res, nil
+}
+
+// yamlValue renders v as a YAML value that fits on the line of its
+// frontmatter key: lists and maps in flow style, multi-line strings
+// double-quoted.
+func yamlValue(v any) (string, error) {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		return "", err
+	}
+	var flow func(*yaml.Node)
+	flow = func(n *yaml.Node) {
+		switch {
+		case n.Kind == yaml.SequenceNode || n.Kind == yaml.MappingNode:
+			n.Style = yaml.FlowStyle
+		case n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "\n"):
+			n.Style = yaml.DoubleQuotedStyle
+		}
+		for _, c := range n.Content {
+			flow(c)
+		}
+	}
+	flow(&n)
+	out, err := yaml.Marshal(&n)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
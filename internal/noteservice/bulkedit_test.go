@@ -0,0 +1,111 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestRenameTag(t *testing.T) {
+	svc := testService(t, WithUndoWindow(time.Minute))
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "---\ntags:\n  - draft\n  - final\n---\nSee #draft/old and #drafty.\n")
+	createNote(t, svc, "b.md", "Inline #draft only.\n")
+	createNote(t, svc, "c.md", "Nothing #else.\n")
+
+	preview, err := svc.RenameTag(ctx, "draft", "final", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Notes) != 2 || len(preview.Changes) != 2 || !strings.Contains(preview.Changes[1].Diff, "+Inline #final only.") {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if note, _ := svc.GetNote(ctx, "b.md"); !strings.Contains(note.Content, "#draft") {
+		t.Error("dry run wrote the note")
+	}
+
+	res, err := svc.RenameTag(ctx, "draft", "final", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Notes) != 2 || res.Notes[0].MutationID == "" {
+		t.Fatalf("result = %+v", res)
+	}
+	note, _ := svc.GetNote(ctx, "a.md")
+	if !strings.Contains(note.Content, "tags: [final]\n") || !strings.Contains(note.Content, "See #final/old and #drafty.") {
+		t.Errorf("content:\n%s", note.Content)
+	}
+	if row, _ := svc.db.GetNote("b.md"); row == nil || len(row.Tags) != 1 || row.Tags[0] != "final" {
+		t.Errorf("b.md row = %+v", row)
+	}
+
+	for _, bad := range [][2]string{{"", "x"}, {"a b", "x"}, {"x", "x"}} {
+		if _, err := svc.RenameTag(ctx, bad[0], bad[1], true); !errors.Is(err, apperr.ErrInvalidPath) {
+			t.Errorf("%q: err = %v, want ErrInvalidPath", bad, err)
+		}
+	}
+}
+
+func TestEditFrontmatter(t *testing.T) {
+	svc := testService(t, WithUndoWindow(time.Minute))
+	ctx := context.Background()
+	createNote(t, svc, "projects/a.md", "---\nid: a\nstatus: draft\nowner: me\n---\n# A\n")
+	createNote(t, svc, "projects/b.md", "# B\n")
+	createNote(t, svc, "other.md", "# Other\n")
+
+	preview, err := svc.EditFrontmatter(ctx, FrontmatterEdit{
+		Folder: "projects",
+		Set:    map[string]any{"status": "done", "labels": []any{"x", "y z"}},
+		Unset:  []string{"owner"},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Changes) != 2 || !strings.Contains(preview.Changes[0].Diff, "-owner: me") {
+		t.Fatalf("preview = %+v", preview)
+	}
+
+	res, err := svc.EditFrontmatter(ctx, FrontmatterEdit{
+		Folder: "projects",
+		Set:    map[string]any{"status": "done", "labels": []any{"x", "y z"}},
+		Unset:  []string{"owner"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Notes) != 2 || res.Notes[0].MutationID == "" {
+		t.Fatalf("result = %+v", res)
+	}
+	a, _ := svc.GetNote(ctx, "projects/a.md")
+	if a.Frontmatter["status"] != "done" || a.Frontmatter["owner"] != nil || a.Frontmatter["id"] != "a" {
+		t.Errorf("a.md frontmatter = %v", a.Frontmatter)
+	}
+	b, _ := svc.GetNote(ctx, "projects/b.md")
+	if labels, _ := b.Frontmatter["labels"].([]any); len(labels) != 2 || labels[1] != "y z" {
+		t.Errorf("b.md frontmatter = %v", b.Frontmatter)
+	}
+
+	// Notes already as requested are not written again.
+	again, err := svc.EditFrontmatter(ctx, FrontmatterEdit{Paths: []string{"projects/a.md"}, Set: map[string]any{"status": "done"}})
+	if err != nil || len(again.Notes) != 0 {
+		t.Errorf("repeat edit = %+v, %v", again, err)
+	}
+	if _, err := svc.EditFrontmatter(ctx, FrontmatterEdit{Paths: []string{"missing.md"}, Unset: []string{"x"}}); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing note: err = %v, want ErrNotFound", err)
+	}
+	for _, bad := range []FrontmatterEdit{
+		{},
+		{Set: map[string]any{"id": "x"}},
+		{Set: map[string]any{"a b": 1}},
+		{Set: map[string]any{"x": 1}, Unset: []string{"x"}},
+	} {
+		if _, err := svc.EditFrontmatter(ctx, bad); !errors.Is(err, apperr.ErrInvalidPath) {
+			t.Errorf("%+v: err = %v, want ErrInvalidPath", bad, err)
+		}
+	}
+}
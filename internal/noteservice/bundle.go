@@ -0,0 +1,146 @@
+package noteservice
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+)
+
+// Bundle depth bounds: how many link hops ExportBundle follows from the
+// exported note.
+const (
+	DefaultBundleDepth = 1
+	MaxBundleDepth     = 5
+)
+
+// attachmentsDir is the vault folder attachments are stored in and
+// served from (/attachments/<name>).
+const attachmentsDir = "attachments"
+
+// attachmentRefRe matches an attachment URL in a Markdown link or image
+// target or an HTML attribute: the name, then an optional query (the
+// ?v=<hash> cache buster).
+var attachmentRefRe = regexp.MustCompile(`([("']\s*)/attachments/([^\s)"'?#<>]+)(\?[^\s)"'#<>]*)?`)
+
+// BundleFile is a file in an exported bundle.
+type BundleFile struct {
+	// Path is the file's vault-relative path, also used inside the bundle.
+	Path string
+	Data []byte
+}
+
+// Bundle is a self-contained export of notes and the attachments they use.
+type Bundle struct {
+	// Root is the exported note.
+	Root string
+	// Notes come first, the root note leading, then attachments.
+	Files []BundleFile
+}
+
+// ExportBundle collects the note at notePath, the notes it links to or
+// embeds (body wikilinks and frontmatter links) up to depth hops away, and
+// every attachment those notes reference. Attachment URLs
+// (/attachments/<name>) are rewritten relative to each note, so the bundle
+// keeps working when unpacked anywhere; wikilink embeds of attachments
+// (![[diagram.png]]) are bundled as they are. Missing attachments and
+// unresolved links are skipped.
+func (s *Service) ExportBundle(ctx context.Context, notePath string, depth int) (*Bundle, error) {
+	if depth < 0 || depth > MaxBundleDepth {
+		return nil, fmt.Errorf("%w: depth must be between 0 and %d", apperr.ErrInvalidPath, MaxBundleDepth)
+	}
+	if _, err := s.GetNote(ctx, notePath); err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{Root: notePath}
+	seen := map[string]bool{notePath: true}
+	var attachments []string
+	seenAttachment := map[string]bool{}
+	addAttachment := func(name string) {
+		if name == "" || strings.Contains(name, "/") || seenAttachment[name] {
+			return
+		}
+		seenAttachment[name] = true
+		attachments = append(attachments, name)
+	}
+
+	level := []string{notePath}
+	for hop := 0; len(level) > 0; hop++ {
+		var next []string
+		for _, p := range level {
+			data, err := s.store.Read(p)
+			if err != nil {
+				continue
+			}
+			for _, m := range attachmentRefRe.FindAllSubmatch(data, -1) {
+				if name, err := url.PathUnescape(string(m[2])); err == nil {
+					addAttachment(name)
+				}
+			}
+			b.Files = append(b.Files, BundleFile{Path: p, Data: relativeAttachments(data, p)})
+
+			res, err := s.db.Parse(data)
+			if err != nil {
+				continue
+			}
+			for _, l := range index.NoteLinks(res) {
+				target, err := s.db.ResolveLink(l.Target)
+				if err != nil {
+					return nil, err
+				}
+				if target == "" {
+					// Not a note: an embedded attachment such as ![[diagram.png]].
+					if ext := path.Ext(l.Target); ext != "" && ext != ".md" {
+						addAttachment(path.Base(l.Target))
+					}
+					continue
+				}
+				if hop < depth && !seen[target] {
+					seen[target] = true
+					next = append(next, target)
+				}
+			}
+		}
+		level = next
+	}
+
+	for _, name := range attachments {
+		p := attachmentsDir + "/" + name
+		data, err := s.store.Read(p)
+		if err != nil {
+			continue
+		}
+		b.Files = append(b.Files, BundleFile{Path: p, Data: data})
+	}
+	return b, nil
+}
+
+// relativeAttachments rewrites the attachment URLs in the note at notePath
+// to paths relative to its folder, dropping cache-busting queries.
+func relativeAttachments(data []byte, notePath string) []byte {
+	prefix := strings.Repeat("../", strings.Count(notePath, "/")) + attachmentsDir + "/"
+	return attachmentRefRe.ReplaceAll(data, []byte("${1}"+prefix+"${2}"))
+}
+
+// WriteZip writes the bundle as a zip archive, keeping vault paths.
+func (b *Bundle) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, f := range b.Files {
+		fw, err := zw.Create(f.Path)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
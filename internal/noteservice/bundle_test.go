@@ -0,0 +1,72 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestExportBundle(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	if err := svc.store.Write("attachments/a.png", []byte("png-a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.store.Write("attachments/b.pdf", []byte("pdf-b")); err != nil {
+		t.Fatal(err)
+	}
+	createNote(t, svc, "projects/root.md", "# Root\n\n![diagram](/attachments/a.png?v=abc)\n\n[[Child]] ![[b.pdf]] ![missing](/attachments/none.png)\n")
+	createNote(t, svc, "child.md", "# Child\n\n![again](/attachments/a.png)\n\n[[Grandchild]]\n")
+	createNote(t, svc, "grandchild.md", "# Grandchild\n")
+
+	files := func(b *Bundle) map[string]string {
+		out := map[string]string{}
+		for _, f := range b.Files {
+			out[f.Path] = string(f.Data)
+		}
+		return out
+	}
+
+	b, err := svc.ExportBundle(ctx, "projects/root.md", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := files(b)
+	if len(got) != 4 || got["attachments/a.png"] != "png-a" || got["attachments/b.pdf"] != "pdf-b" {
+		t.Fatalf("bundle files = %v, want root, child and two attachments", got)
+	}
+	if b.Files[0].Path != "projects/root.md" {
+		t.Errorf("first file = %s, want the root note", b.Files[0].Path)
+	}
+	if want := "# Root\n\n![diagram](../attachments/a.png)\n\n[[Child]] ![[b.pdf]] ![missing](../attachments/none.png)\n"; !strings.HasSuffix(got["projects/root.md"], want) {
+		t.Errorf("root content = %q, want body %q", got["projects/root.md"], want)
+	}
+	if want := "# Child\n\n![again](attachments/a.png)\n\n[[Grandchild]]\n"; !strings.HasSuffix(got["child.md"], want) {
+		t.Errorf("child content = %q, want body %q", got["child.md"], want)
+	}
+
+	b, err = svc.ExportBundle(ctx, "projects/root.md", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files(b)["grandchild.md"]; !ok {
+		t.Error("depth 2: grandchild.md missing")
+	}
+	b, err = svc.ExportBundle(ctx, "projects/root.md", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := files(b); len(got) != 3 {
+		t.Errorf("depth 0: files = %v, want the root note and its attachments", got)
+	}
+
+	if _, err := svc.ExportBundle(ctx, "nope.md", 1); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing note: err = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.ExportBundle(ctx, "child.md", MaxBundleDepth+1); !errors.Is(err, apperr.ErrInvalidPath) {
+		t.Errorf("depth too large: err = %v, want ErrInvalidPath", err)
+	}
+}
@@ -0,0 +1,47 @@
+package noteservice
+
+import (
+	"context"
+	"time"
+)
+
+// CalendarDay is one day of a CalendarMonth.
+type CalendarDay struct {
+	Date string `json:"date" example:"2025-02-01" validate:"required"`
+	// Notes is the number of notes created that day.
+	Notes int `json:"notes" validate:"required"`
+	// DailyNote is the path of the day's daily note, if it exists.
+	DailyNote string `json:"daily_note,omitempty" example:"journal/2025-02-01.md"`
+}
+
+// CalendarMonth summarizes note activity for each day of a month.
+type CalendarMonth struct {
+	Month string        `json:"month" example:"2025-02" validate:"required"`
+	Days  []CalendarDay `json:"days" validate:"required"`
+}
+
+// Calendar returns per-day created-note counts and daily-note paths for the
+// month containing t.
+func (s *Service) Calendar(_ context.Context, t time.Time) (*CalendarMonth, error) {
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	last := first.AddDate(0, 1, -1)
+	counts, err := s.db.CreatedPerDay(first.Format(time.DateOnly), last.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	out := &CalendarMonth{Month: first.Format("2006-01")}
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		day := CalendarDay{Date: d.Format(time.DateOnly), Notes: counts[d.Format(time.DateOnly)]}
+		p := s.DailyPath(d)
+		row, err := s.db.GetNote(p)
+		if err != nil {
+			return nil, err
+		}
+		if row != nil {
+			day.DailyNote = p
+		}
+		out.Days = append(out.Days, day)
+	}
+	return out, nil
+}
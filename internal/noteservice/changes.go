@@ -0,0 +1,42 @@
+package noteservice
+
+import (
+	"context"
+	"time"
+)
+
+// maxRecentChanges caps RecentChanges.
+const maxRecentChanges = 200
+
+// RecentChange is a note created or modified after a point in time.
+type RecentChange struct {
+	Path      string    `json:"path"`
+	Kind      string    `json:"kind" enums:"created,updated"`
+	Title     string    `json:"title,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RecentChanges returns up to limit notes (default 50, at most 200) whose
+// modification time is after since, most recent first. Kind is
+// MutationCreated when the note's creation date is also after since.
+// Changes made outside Kenaz are included once indexed; deleted notes are
+// not, as the index keeps no record of them.
+func (s *Service) RecentChanges(_ context.Context, since time.Time, limit int) ([]RecentChange, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, maxRecentChanges)
+	rows, err := s.db.NotesUpdatedSince(since, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RecentChange, len(rows))
+	for i, r := range rows {
+		kind := MutationUpdated
+		if r.CreatedAt.After(since) {
+			kind = MutationCreated
+		}
+		out[i] = RecentChange{Path: r.Path, Kind: kind, Title: r.Title, UpdatedAt: r.UpdatedAt}
+	}
+	return out, nil
+}
@@ -0,0 +1,81 @@
+package noteservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/starford/kenaz/internal/index"
+)
+
+// Citations returns the reference notes sorted by cite key, each with the
+// notes citing it. A non-empty notePath restricts the result to the
+// references that note cites.
+func (s *Service) Citations(ctx context.Context, notePath string) ([]index.Reference, error) {
+	if notePath != "" {
+		if _, err := s.GetNote(ctx, notePath); err != nil {
+			return nil, err
+		}
+	}
+	refs, err := s.db.References()
+	if err != nil {
+		return nil, err
+	}
+	if notePath == "" {
+		return refs, nil
+	}
+	out := []index.Reference{}
+	for _, r := range refs {
+		if slices.Contains(r.CitedBy, notePath) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// BibTeX renders refs as a BibTeX database, one entry per reference.
+func BibTeX(refs []index.Reference) string {
+	var b strings.Builder
+	for i, r := range refs {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		typ := r.Type
+		switch {
+		case typ != "":
+		case r.Journal != "":
+			typ = "article"
+		case r.Publisher != "":
+			typ = "book"
+		default:
+			typ = "misc"
+		}
+		fmt.Fprintf(&b, "@%s{%s,\n", typ, r.Key)
+		field := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "  %s = {%s},\n", name, value)
+			}
+		}
+		field("title", bibEscape(r.Title))
+		field("author", bibEscape(strings.Join(r.Authors, " and ")))
+		field("year", bibEscape(r.Year))
+		field("journal", bibEscape(r.Journal))
+		field("publisher", bibEscape(r.Publisher))
+		field("volume", bibEscape(r.Volume))
+		field("number", bibEscape(r.Number))
+		field("pages", bibEscape(r.Pages))
+		field("doi", strings.NewReplacer("{", "", "}", "").Replace(r.DOI))
+		field("url", strings.NewReplacer("{", "", "}", "").Replace(r.URL))
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// bibEscape escapes LaTeX special characters in a BibTeX text field and
+// drops braces, which would otherwise have to balance.
+var bibEscape = strings.NewReplacer(
+	"{", "", "}", "",
+	`\`, "",
+	"&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+).Replace
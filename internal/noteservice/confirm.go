@@ -0,0 +1,56 @@
+package noteservice
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// confirmTTL is how long the confirm token of a folder delete preview
+// stays valid.
+const confirmTTL = 5 * time.Minute
+
+// confirmTokens holds the tokens handed out by DeleteFolder previews. Each
+// is random, single-use, and bound to the folder and the checksum of the
+// listing it previewed.
+type confirmTokens struct {
+	mu      sync.Mutex
+	pending map[string]pendingConfirm
+}
+
+type pendingConfirm struct {
+	folder  string
+	listing string
+	expires time.Time
+}
+
+// issue returns a new token for the previewed listing of folder.
+func (c *confirmTokens) issue(folder, listing string) string {
+	token := rand.Text()
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, p := range c.pending {
+		if now.After(p.expires) {
+			delete(c.pending, t)
+		}
+	}
+	if c.pending == nil {
+		c.pending = map[string]pendingConfirm{}
+	}
+	c.pending[token] = pendingConfirm{folder: folder, listing: listing, expires: now.Add(confirmTTL)}
+	return token
+}
+
+// redeem consumes token and reports whether it was issued for folder and
+// listing and has not expired.
+func (c *confirmTokens) redeem(token, folder, listing string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[token]
+	if !ok {
+		return false
+	}
+	delete(c.pending, token)
+	return p.folder == folder && p.listing == listing && time.Now().Before(p.expires)
+}
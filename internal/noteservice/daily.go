@@ -0,0 +1,108 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// Daily note defaults.
+const (
+	DefaultDailyFolder = "journal"
+	DefaultDailyFormat = "2006-01-02"
+)
+
+// dailyEntryLayout is the timestamp prefix of entries appended with
+// AppendDaily.
+const dailyEntryLayout = "15:04"
+
+// DailyNotes configures where daily notes live and how new ones start.
+type DailyNotes struct {
+	// Folder holds the daily notes (default "journal").
+	Folder string
+	// Format is the Go time layout of the file name without ".md"
+	// (default "2006-01-02"); it may contain "/" for nested folders.
+	Format string
+	// Template is the vault path of a note whose content seeds new daily
+	// notes, with "{{date}}" replaced by the date as YYYY-MM-DD. Empty, or
+	// a missing note, starts them with a "# <date>" heading.
+	Template string
+}
+
+// WithDailyNotes configures daily notes. Empty fields keep their defaults.
+func WithDailyNotes(d DailyNotes) Option {
+	return func(s *Service) {
+		if d.Folder != "" {
+			s.daily.Folder = d.Folder
+		}
+		if d.Format != "" {
+			s.daily.Format = d.Format
+		}
+		s.daily.Template = d.Template
+	}
+}
+
+// DailyPath returns the path of the daily note for the day of t.
+func (s *Service) DailyPath(t time.Time) string {
+	return path.Join(s.daily.Folder, t.Format(s.daily.Format)+".md")
+}
+
+// AppendDaily appends text to today's daily note as a bullet prefixed with
+// the current time, creating the note from the template if it does not
+// exist.
+func (s *Service) AppendDaily(_ context.Context, text string) (*NoteDetail, error) {
+	now := time.Now()
+	return s.appendBlock(s.DailyPath(now), bullet(now.Format(dailyEntryLayout), text), "", func() ([]byte, error) {
+		return s.dailyContent(now)
+	})
+}
+
+// DailyNote returns the daily note for the day of t, creating it from the
+// template first if it does not exist. Creation is serialized with appends,
+// so concurrent callers never create it twice. In a read-only vault a
+// missing daily note fails with apperr.ErrNotFound.
+func (s *Service) DailyNote(_ context.Context, t time.Time) (*NoteDetail, error) {
+	p := s.DailyPath(t)
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
+
+	data, err := s.store.Read(p)
+	if err == nil {
+		return s.buildNoteDetail(p, data)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if s.readOnly {
+		return nil, fmt.Errorf("%w: %s", apperr.ErrNotFound, p)
+	}
+	content, err := s.dailyContent(t)
+	if err != nil {
+		return nil, err
+	}
+	return s.create(p, content)
+}
+
+// dailyContent renders the initial content of the daily note for t.
+func (s *Service) dailyContent(t time.Time) ([]byte, error) {
+	date := t.Format(time.DateOnly)
+	if s.daily.Template != "" {
+		data, err := s.store.Read(s.daily.Template)
+		switch {
+		case err == nil:
+			// The new note gets its own id.
+			data = parser.RemoveFrontmatterFields(data, "id")
+			return []byte(strings.ReplaceAll(string(data), "{{date}}", date)), nil
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+	}
+	return []byte("# " + date + "\n\n"), nil
+}
@@ -0,0 +1,77 @@
+package noteservice
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendDaily_Template(t *testing.T) {
+	svc := testService(t, WithDailyNotes(DailyNotes{Template: "templates/daily.md"}))
+	ctx := context.Background()
+	createNote(t, svc, "templates/daily.md", "---\ntags: [journal]\n---\n# {{date}}\n\n## Log\n")
+	tmpl, _ := svc.GetNote(ctx, "templates/daily.md")
+
+	if _, err := svc.AppendDaily(ctx, "first"); err != nil {
+		t.Fatal(err)
+	}
+	note, err := svc.AppendDaily(ctx, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now().Format(time.DateOnly)
+	if note.Path != "journal/"+today+".md" {
+		t.Errorf("path = %q", note.Path)
+	}
+	if note.ID == "" || note.ID == tmpl.ID {
+		t.Errorf("daily note id = %q, template id = %q", note.ID, tmpl.ID)
+	}
+	if !strings.Contains(note.Content, "# "+today+"\n\n## Log\n- ") {
+		t.Errorf("template not applied:\n%s", note.Content)
+	}
+	if i, j := strings.Index(note.Content, " first\n- "), strings.Index(note.Content, " second\n"); i < 0 || j < i {
+		t.Errorf("entries not appended in order:\n%s", note.Content)
+	}
+	if note.Title != today {
+		t.Errorf("title = %q, want %q", note.Title, today)
+	}
+}
+
+func TestAppendDaily_NoTemplate(t *testing.T) {
+	svc := testService(t, WithDailyNotes(DailyNotes{Folder: "log", Format: "2006/01/02", Template: "missing.md"}))
+	note, err := svc.AppendDaily(context.Background(), "entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "log/" + time.Now().Format("2006/01/02") + ".md"; note.Path != want {
+		t.Errorf("path = %q, want %q", note.Path, want)
+	}
+	if !strings.Contains(note.Content, "# "+time.Now().Format(time.DateOnly)+"\n\n- ") {
+		t.Errorf("content = %q", note.Content)
+	}
+}
+
+func TestDailyNote(t *testing.T) {
+	svc := testService(t, WithDailyNotes(DailyNotes{Template: "templates/daily.md"}))
+	ctx := context.Background()
+	createNote(t, svc, "templates/daily.md", "# {{date}}\n\n## Log\n")
+	day := time.Date(2025, 2, 3, 0, 0, 0, 0, time.Local)
+
+	note, err := svc.DailyNote(ctx, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Path != "journal/2025-02-03.md" || !strings.Contains(note.Content, "# 2025-02-03\n\n## Log\n") {
+		t.Errorf("daily note = %s:\n%s", note.Path, note.Content)
+	}
+
+	again, err := svc.DailyNote(ctx, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != note.ID || again.Checksum != note.Checksum {
+		t.Errorf("existing note changed: id %q → %q", note.ID, again.ID)
+	}
+}
@@ -0,0 +1,37 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/storage"
+)
+
+// GitPush reports a push of the vault history.
+type GitPush struct {
+	// Head is the latest commit pushed.
+	Head *storage.Commit `json:"head,omitempty"`
+}
+
+// PushVault pushes the vault history to the configured git remote (see
+// storage.Git). It fails with apperr.ErrNotFound when the vault is not
+// versioned with git and apperr.ErrConflict when no remote is configured.
+func (s *Service) PushVault(ctx context.Context) (*GitPush, error) {
+	v, ok := s.store.(storage.Versioned)
+	if !ok {
+		return nil, fmt.Errorf("%w: the vault is not versioned with git", apperr.ErrNotFound)
+	}
+	if err := v.Push(ctx); err != nil {
+		if errors.Is(err, storage.ErrNoRemote) {
+			return nil, fmt.Errorf("%w: no git remote configured", apperr.ErrConflict)
+		}
+		return nil, err
+	}
+	head, err := v.LastCommit("")
+	if err != nil {
+		return nil, err
+	}
+	return &GitPush{Head: head}, nil
+}
@@ -0,0 +1,381 @@
+package noteservice
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/graph"
+	"github.com/starford/kenaz/internal/index"
+)
+
+// layoutGraph fills in node positions from the cached layout, recomputing
+// it first when the graph's nodes or links changed since it was cached.
+// Cached positions seed the recomputation, so unchanged parts of the graph
+// stay where clients last drew them.
+func (s *Service) layoutGraph(nodes []index.GraphNode, links []index.GraphLink) error {
+	ids, edges := graphEdges(nodes, links)
+	sig := graphSignature(ids, edges)
+
+	s.layoutMu.Lock()
+	defer s.layoutMu.Unlock()
+	pos, cached, err := s.db.GraphLayout()
+	if err != nil {
+		return err
+	}
+	if cached != sig {
+		pts := graph.Layout(ids, edges, pos)
+		pos = make(map[string]graph.Point, len(ids))
+		for i, id := range ids {
+			pos[id] = pts[i]
+		}
+		if err := s.db.SaveGraphLayout(sig, pos); err != nil {
+			return err
+		}
+	}
+	for i := range nodes {
+		p := pos[nodes[i].ID]
+		nodes[i].X, nodes[i].Y = p.X, p.Y
+	}
+	return nil
+}
+
+// graphMetrics returns the link counts and centrality of every node,
+// recomputed only when the graph's nodes or links changed since the last
+// call.
+func (s *Service) graphMetrics(nodes []index.GraphNode, links []index.GraphLink) map[string]index.NodeMetrics {
+	ids, edges := graphEdges(nodes, links)
+	sig := graphSignature(ids, edges)
+
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	if s.metrics != nil && s.metricsSig == sig {
+		return s.metrics
+	}
+	in, out := graph.Degrees(len(ids), edges)
+	rank := graph.PageRank(len(ids), edges)
+	between := graph.Betweenness(len(ids), edges, graph.BetweennessSamples)
+	m := make(map[string]index.NodeMetrics, len(ids))
+	for i, id := range ids {
+		m[id] = index.NodeMetrics{InDegree: in[i], OutDegree: out[i], PageRank: rank[i], Betweenness: between[i]}
+	}
+	s.metrics, s.metricsSig = m, sig
+	return m
+}
+
+// NodeMetrics is a row of the graph metrics table.
+type NodeMetrics struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	index.NodeMetrics
+}
+
+// GraphMetrics lists the metrics of every graph node.
+type GraphMetrics struct {
+	// Nodes is sorted by decreasing PageRank, then by ID.
+	Nodes []NodeMetrics `json:"nodes"`
+}
+
+// GraphMetrics returns the in and out degree, PageRank, and betweenness of
+// every graph node, so clients can size nodes by importance. PageRank
+// follows links in their direction; betweenness follows them either way and
+// is sampled on graphs of more than graph.BetweennessSamples nodes. The
+// metrics are cached until the graph changes.
+func (s *Service) GraphMetrics(_ context.Context) (*GraphMetrics, error) {
+	nodes, links, err := s.db.Graph()
+	if err != nil {
+		return nil, err
+	}
+	metrics := s.graphMetrics(nodes, links)
+	res := &GraphMetrics{Nodes: make([]NodeMetrics, len(nodes))}
+	for i, n := range nodes {
+		res.Nodes[i] = NodeMetrics{ID: n.ID, Title: n.Title, NodeMetrics: metrics[n.ID]}
+	}
+	slices.SortFunc(res.Nodes, func(a, b NodeMetrics) int {
+		if c := cmp.Compare(b.PageRank, a.PageRank); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return res, nil
+}
+
+// Cluster is a group of densely linked notes.
+type Cluster struct {
+	ID    int      `json:"id"`
+	Size  int      `json:"size"`
+	Nodes []string `json:"nodes"`
+}
+
+// GraphClusters assigns every graph node to a cluster.
+type GraphClusters struct {
+	Clusters []Cluster `json:"clusters"`
+	// Assignments maps node IDs to cluster IDs.
+	Assignments map[string]int `json:"assignments"`
+}
+
+// clusterCache holds the cluster labels of the graph with signature sig
+// (see graphSignature), so they are recomputed only when the graph changes.
+type clusterCache struct {
+	mu     sync.Mutex
+	sig    string
+	labels []int
+}
+
+// clusterLabels returns graph.Clusters of the graph, from the cache while
+// the graph is unchanged.
+func (s *Service) clusterLabels(ids []string, edges [][2]int) []int {
+	sig := graphSignature(ids, edges)
+	c := &s.clusters
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.labels == nil || c.sig != sig {
+		c.sig, c.labels = sig, graph.Clusters(len(ids), edges)
+	}
+	return c.labels
+}
+
+// Clusters groups the graph's nodes into communities of densely linked notes
+// by label propagation (see graph.Clusters). Cluster IDs run from 0 for the
+// largest cluster; unlinked nodes each form a cluster of their own.
+func (s *Service) Clusters(_ context.Context) (*GraphClusters, error) {
+	nodes, links, err := s.db.Graph()
+	if err != nil {
+		return nil, err
+	}
+	ids, edges := graphEdges(nodes, links)
+	labels := s.clusterLabels(ids, edges)
+
+	res := &GraphClusters{Clusters: []Cluster{}, Assignments: make(map[string]int, len(ids))}
+	for i, id := range ids {
+		c := labels[i]
+		for len(res.Clusters) <= c {
+			res.Clusters = append(res.Clusters, Cluster{ID: len(res.Clusters)})
+		}
+		res.Clusters[c].Nodes = append(res.Clusters[c].Nodes, id)
+		res.Clusters[c].Size++
+		res.Assignments[id] = c
+	}
+	return res, nil
+}
+
+// ClusteredGraph is Graph with every node's Cluster set, as assigned by
+// Clusters.
+func (s *Service) ClusteredGraph(ctx context.Context) ([]index.GraphNode, []index.GraphLink, error) {
+	nodes, links, err := s.Graph(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids, edges := graphEdges(nodes, links)
+	labels := s.clusterLabels(ids, edges)
+	for i := range nodes {
+		j, _ := slices.BinarySearch(ids, nodes[i].ID)
+		c := labels[j]
+		nodes[i].Cluster = &c
+	}
+	return nodes, links, nil
+}
+
+// ErrNoPath is returned by Path when the notes are not connected. It wraps
+// apperr.ErrNotFound.
+var ErrNoPath = fmt.Errorf("path %w", apperr.ErrNotFound)
+
+// MaxGraphPaths is the most shortest chains Path returns.
+const MaxGraphPaths = 20
+
+// GraphChain is a chain of links connecting two notes.
+type GraphChain struct {
+	// Nodes lists the node IDs from the start note to the end note.
+	Nodes []string `json:"nodes"`
+	// Links holds the link between each pair of consecutive nodes, in its
+	// original direction.
+	Links []index.GraphLink `json:"links"`
+}
+
+// GraphPath is a shortest chain of links connecting two notes.
+type GraphPath struct {
+	GraphChain
+	// Alternatives are further chains as short, when more were asked for.
+	Alternatives []GraphChain `json:"alternatives,omitempty"`
+}
+
+// Path returns up to limit shortest chains of links between two notes (one
+// when limit is 0 or less, at most MaxGraphPaths), following links in either
+// direction; among equally short chains, those through lower node IDs come
+// first. from and to are graph node IDs, or any link target that resolves to
+// a note. It returns apperr.ErrNotFound when either end is not in the graph
+// and ErrNoPath when they are not connected.
+func (s *Service) Path(_ context.Context, from, to string, limit int) (*GraphPath, error) {
+	nodes, links, err := s.db.Graph()
+	if err != nil {
+		return nil, err
+	}
+	ids, edges := graphEdges(nodes, links)
+	lookup := func(target string) (int, error) {
+		if i, ok := slices.BinarySearch(ids, target); ok {
+			return i, nil
+		}
+		p, err := s.db.ResolveLink(target)
+		if err != nil {
+			return 0, err
+		}
+		if i, ok := slices.BinarySearch(ids, p); ok && p != "" {
+			return i, nil
+		}
+		return 0, apperr.ErrNotFound
+	}
+	fi, err := lookup(from)
+	if err != nil {
+		return nil, err
+	}
+	ti, err := lookup(to)
+	if err != nil {
+		return nil, err
+	}
+	paths := graph.ShortestPaths(len(ids), edges, fi, ti, min(max(limit, 1), MaxGraphPaths))
+	if paths == nil {
+		return nil, ErrNoPath
+	}
+
+	chains := make([]GraphChain, len(paths))
+	for i, steps := range paths {
+		chains[i] = graphChain(ids, links, steps)
+	}
+	return &GraphPath{GraphChain: chains[0], Alternatives: chains[1:]}, nil
+}
+
+// graphChain returns the chain through the nodes at steps of ids.
+func graphChain(ids []string, links []index.GraphLink, steps []int) GraphChain {
+	c := GraphChain{Nodes: make([]string, len(steps)), Links: []index.GraphLink{}}
+	for i, n := range steps {
+		c.Nodes[i] = ids[n]
+	}
+	for i := 1; i < len(c.Nodes); i++ {
+		a, b := c.Nodes[i-1], c.Nodes[i]
+		for _, l := range links {
+			if (l.Source == a && l.Target == b) || (l.Source == b && l.Target == a) {
+				c.Links = append(c.Links, l)
+				break
+			}
+		}
+	}
+	return c
+}
+
+// Local graph depth limits, in links from the center note.
+const (
+	DefaultLocalGraphDepth = 2
+	MaxLocalGraphDepth     = 5
+)
+
+// LocalGraph returns the neighborhood of the note at notePath: the nodes
+// within depth links of it (DefaultLocalGraphDepth when depth is 0 or less,
+// at most MaxLocalGraphDepth), following links in either direction, and the
+// links among them. It returns apperr.ErrNotFound when the note is not
+// indexed.
+func (s *Service) LocalGraph(_ context.Context, notePath string, depth int) (*index.LocalGraph, error) {
+	if depth <= 0 {
+		depth = DefaultLocalGraphDepth
+	}
+	g, err := s.db.LocalGraph(notePath, min(depth, MaxLocalGraphDepth))
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, fmt.Errorf("%w: %s", apperr.ErrNotFound, notePath)
+	}
+	return g, nil
+}
+
+// GraphHealth reports orphan notes, broken wikilinks, and duplicate titles
+// (see index.DB.GraphHealth). Targets with a file extension other than .md
+// that name a vault file, by path or file name, are attachments rather than
+// broken links.
+func (s *Service) GraphHealth(_ context.Context) (*index.GraphHealth, error) {
+	h, err := s.db.GraphHealth()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(h.BrokenLinks, isAttachmentLink) {
+		return h, nil
+	}
+	files, err := s.store.ListFiles("")
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, 2*len(files))
+	for _, f := range files {
+		names[strings.ToLower(f)] = true
+		names[strings.ToLower(path.Base(f))] = true
+	}
+	broken := h.BrokenLinks[:0]
+	for _, l := range h.BrokenLinks {
+		if isAttachmentLink(l) && names[strings.ToLower(strings.TrimPrefix(attachmentTarget(l.Target), "/"))] {
+			continue
+		}
+		broken = append(broken, l)
+	}
+	h.BrokenLinks = broken
+	return h, nil
+}
+
+// attachmentTarget is a link target without its "#fragment".
+func attachmentTarget(target string) string {
+	target, _, _ = strings.Cut(target, "#")
+	return strings.TrimSpace(target)
+}
+
+// isAttachmentLink reports whether l targets a file other than a note.
+func isAttachmentLink(l index.BrokenLink) bool {
+	ext := path.Ext(attachmentTarget(l.Target))
+	return ext != "" && !strings.EqualFold(ext, ".md")
+}
+
+// graphEdges returns the graph's node IDs in sorted order and its links as
+// sorted pairs of indexes into them.
+func graphEdges(nodes []index.GraphNode, links []index.GraphLink) ([]string, [][2]int) {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	slices.Sort(ids)
+	at := make(map[string]int, len(ids))
+	for i, id := range ids {
+		at[id] = i
+	}
+	edges := make([][2]int, 0, len(links))
+	for _, l := range links {
+		edges = append(edges, [2]int{at[l.Source], at[l.Target]})
+	}
+	slices.SortFunc(edges, func(a, b [2]int) int {
+		if c := a[0] - b[0]; c != 0 {
+			return c
+		}
+		return a[1] - b[1]
+	})
+	return ids, edges
+}
+
+// graphSignature identifies a graph by its sorted node IDs and edges.
+func graphSignature(ids []string, edges [][2]int) string {
+	h := sha256.New()
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+	}
+	var buf []byte
+	for _, e := range edges {
+		buf = binary.BigEndian.AppendUint32(buf[:0], uint32(e[0]))
+		buf = binary.BigEndian.AppendUint32(buf, uint32(e[1]))
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
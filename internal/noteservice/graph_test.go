@@ -0,0 +1,193 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestGraphLayoutCache(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "[[b]]")
+	createNote(t, svc, "b.md", "B")
+
+	nodes, _, err := svc.Graph(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sig, _ := svc.db.GraphLayout()
+	if sig == "" || len(nodes) != 2 || (nodes[0].X == 0 && nodes[0].Y == 0) {
+		t.Fatalf("layout not computed: sig %q, nodes %+v", sig, nodes)
+	}
+
+	again, _, _ := svc.Graph(ctx)
+	if _, sig2, _ := svc.db.GraphLayout(); sig2 != sig || again[0] != nodes[0] {
+		t.Error("unchanged graph was re-laid out")
+	}
+
+	createNote(t, svc, "c.md", "[[a]]")
+	nodes, _, _ = svc.Graph(ctx)
+	if _, sig3, _ := svc.db.GraphLayout(); sig3 == sig || len(nodes) != 3 {
+		t.Errorf("layout not refreshed after a change: %d nodes", len(nodes))
+	}
+}
+
+func TestGraphMetrics(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "[[hub]]")
+	createNote(t, svc, "b.md", "[[hub]] [[hub]]")
+	createNote(t, svc, "hub.md", "[[c]]")
+	createNote(t, svc, "c.md", "C")
+
+	res, err := svc.GraphMetrics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Nodes) != 4 || res.Nodes[0].ID != "c.md" || res.Nodes[1].ID != "hub.md" {
+		t.Fatalf("metrics = %+v, want c.md then hub.md first", res.Nodes)
+	}
+	hub := res.Nodes[1]
+	if hub.InDegree != 2 || hub.OutDegree != 1 || hub.Betweenness <= 0 {
+		t.Errorf("hub = %+v", hub)
+	}
+
+	nodes, _, err := svc.Graph(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodes {
+		if n.ID == "hub.md" && n.NodeMetrics != hub.NodeMetrics {
+			t.Errorf("graph node metrics = %+v, want %+v", n.NodeMetrics, hub.NodeMetrics)
+		}
+	}
+
+	createNote(t, svc, "d.md", "[[a]]")
+	res, _ = svc.GraphMetrics(ctx)
+	if len(res.Nodes) != 5 {
+		t.Errorf("metrics not refreshed after a change: %d nodes", len(res.Nodes))
+	}
+}
+
+func TestClusters(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "[[b]] [[c]]")
+	createNote(t, svc, "b.md", "[[c]]")
+	createNote(t, svc, "c.md", "C")
+	createNote(t, svc, "x.md", "[[y]]")
+	createNote(t, svc, "y.md", "Y")
+	createNote(t, svc, "lonely.md", "no links")
+
+	res, err := svc.Clusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Clusters) != 3 {
+		t.Fatalf("clusters = %+v", res.Clusters)
+	}
+	if c := res.Clusters[0]; c.Size != 3 || len(c.Nodes) != 3 {
+		t.Errorf("largest cluster = %+v", c)
+	}
+	as := res.Assignments
+	if as["a.md"] != 0 || as["b.md"] != 0 || as["c.md"] != 0 {
+		t.Errorf("a/b/c not clustered together: %v", as)
+	}
+	if as["x.md"] != as["y.md"] || as["x.md"] == as["lonely.md"] || as["x.md"] == 0 {
+		t.Errorf("x/y cluster wrong: %v", as)
+	}
+}
+
+func TestClusteredGraph(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "[[b]]")
+	createNote(t, svc, "b.md", "B")
+	createNote(t, svc, "x.md", "X")
+
+	clusters := func() map[string]int {
+		t.Helper()
+		nodes, _, err := svc.ClusteredGraph(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]int{}
+		for _, n := range nodes {
+			if n.Cluster == nil {
+				t.Fatalf("node %s has no cluster", n.ID)
+			}
+			out[n.ID] = *n.Cluster
+		}
+		return out
+	}
+	c := clusters()
+	if c["a.md"] != 0 || c["b.md"] != 0 || c["x.md"] != 1 {
+		t.Errorf("clusters = %v", c)
+	}
+	sig := svc.clusters.sig
+	clusters()
+	if svc.clusters.sig != sig {
+		t.Error("unchanged graph was re-clustered")
+	}
+
+	// Linking into the graph invalidates the cached clusters.
+	createNote(t, svc, "y.md", "[[x]] [[a]]")
+	if c := clusters(); svc.clusters.sig == sig || len(c) != 4 {
+		t.Errorf("clusters not refreshed after a change: %v", c)
+	}
+	if nodes, _, _ := svc.Graph(ctx); nodes[0].Cluster != nil {
+		t.Errorf("Graph set clusters: %+v", nodes[0])
+	}
+}
+
+func TestPath(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "[[b]]")
+	createNote(t, svc, "b.md", "[[c]]")
+	createNote(t, svc, "d.md", "[[c]]")
+	createNote(t, svc, "e.md", "alone")
+
+	p, err := svc.Path(ctx, "a.md", "d", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.md", "b.md", "c", "d.md"}; !slices.Equal(p.Nodes, want) {
+		t.Errorf("nodes = %v, want %v", p.Nodes, want)
+	}
+	if len(p.Links) != 3 || p.Links[2].Source != "d.md" || p.Links[2].Target != "c" {
+		t.Errorf("links = %+v", p.Links)
+	}
+
+	if _, err := svc.Path(ctx, "a.md", "e.md", 1); !errors.Is(err, ErrNoPath) {
+		t.Errorf("unconnected: err = %v", err)
+	}
+	if _, err := svc.Path(ctx, "a.md", "missing.md", 1); !errors.Is(err, apperr.ErrNotFound) || errors.Is(err, ErrNoPath) {
+		t.Errorf("missing: err = %v", err)
+	}
+}
+
+func TestPath_Alternatives(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "[[b]] [[c]]")
+	createNote(t, svc, "b.md", "[[d]]")
+	createNote(t, svc, "c.md", "[[d]]")
+	createNote(t, svc, "d.md", "D")
+
+	p, err := svc.Path(ctx, "a.md", "d.md", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p.Nodes, []string{"a.md", "b.md", "d.md"}) || len(p.Alternatives) != 1 ||
+		!slices.Equal(p.Alternatives[0].Nodes, []string{"a.md", "c.md", "d.md"}) || len(p.Alternatives[0].Links) != 2 {
+		t.Errorf("path = %+v", p)
+	}
+	if p, _ := svc.Path(ctx, "a.md", "d.md", 1); len(p.Alternatives) != 0 {
+		t.Errorf("limit 1 alternatives = %+v", p.Alternatives)
+	}
+}
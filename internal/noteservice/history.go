@@ -0,0 +1,162 @@
+package noteservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/checksum"
+	"github.com/starford/kenaz/internal/storage"
+)
+
+const (
+	// historyDir holds note revisions: the revisions of a note at p are
+	// the files historyDir/p/<id>.md.
+	historyDir = storage.StateDir + "/history"
+	// revisionIDLayout formats the time a revision was saved as its ID, so
+	// IDs sort by age.
+	revisionIDLayout = "20060102T150405.000000000Z"
+)
+
+// WithHistory keeps up to n earlier versions of each note, saved whenever
+// UpdateNote overwrites it. Zero disables revision history.
+func WithHistory(n int) Option {
+	return func(s *Service) {
+		s.revisions = max(n, 0)
+	}
+}
+
+// Revision is an earlier version of a note.
+type Revision struct {
+	ID string `json:"id" example:"20250203T101500.123456789Z" validate:"required"`
+	// SavedAt is when the version was replaced.
+	SavedAt time.Time `json:"saved_at" validate:"required"`
+	// Content and Checksum are only set when a single revision is fetched.
+	Content  string `json:"content,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// Revisions lists the saved revisions of the note at p, newest first. It
+// fails with apperr.ErrNotFound when the note does not exist and has no
+// revisions (a trashed note keeps its history).
+func (s *Service) Revisions(_ context.Context, p string) ([]Revision, error) {
+	ids, err := s.revisionIDs(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		if _, err := s.store.Read(p); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, apperr.ErrNotFound
+			}
+			return nil, err
+		}
+	}
+	out := make([]Revision, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		t, _ := time.Parse(revisionIDLayout, ids[i])
+		out = append(out, Revision{ID: ids[i], SavedAt: t})
+	}
+	return out, nil
+}
+
+// Revision returns the revision id of the note at p with its content, or
+// apperr.ErrNotFound.
+func (s *Service) Revision(_ context.Context, p, id string) (*Revision, error) {
+	t, err := time.Parse(revisionIDLayout, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: revision %s", apperr.ErrNotFound, id)
+	}
+	data, err := s.store.Read(revisionPath(p, id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: revision %s", apperr.ErrNotFound, id)
+		}
+		return nil, err
+	}
+	return &Revision{ID: id, SavedAt: t, Content: string(data), Checksum: checksum.Sum(data)}, nil
+}
+
+// RestoreRevision writes revision id back as the content of the note at p
+// via UpdateNote, so the version it replaces is saved as a revision in
+// turn and ifMatch works as for UpdateNote.
+func (s *Service) RestoreRevision(ctx context.Context, p, id, ifMatch string) (*NoteDetail, error) {
+	rev, err := s.Revision(ctx, p, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.UpdateNote(ctx, p, []byte(rev.Content), ifMatch)
+}
+
+// saveRevision keeps prev, the content the note at p is about to be
+// overwritten with next, as a revision and drops the oldest revisions
+// beyond the configured number. Nothing is saved when history is disabled
+// or the content does not change.
+func (s *Service) saveRevision(p string, prev, next []byte) error {
+	if s.revisions == 0 || bytes.Equal(prev, next) {
+		return nil
+	}
+	id := time.Now().UTC().Format(revisionIDLayout)
+	if err := s.store.Write(revisionPath(p, id), prev); err != nil {
+		return fmt.Errorf("save revision: %w", err)
+	}
+	ids, err := s.revisionIDs(p)
+	if err != nil {
+		return err
+	}
+	for _, old := range ids[:max(len(ids)-s.revisions, 0)] {
+		if err := s.store.Delete(revisionPath(p, old)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// revisionIDs returns the IDs of the revisions of the note at p, oldest
+// first.
+func (s *Service) revisionIDs(p string) ([]string, error) {
+	dir := path.Join(historyDir, p)
+	exists, err := s.store.DirExists(dir)
+	if err != nil || !exists {
+		return nil, err
+	}
+	files, err := s.store.ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(files))
+	for _, f := range files {
+		if path.Dir(f) != dir {
+			continue
+		}
+		id := strings.TrimSuffix(path.Base(f), ".md")
+		if _, err := time.Parse(revisionIDLayout, id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// moveHistory moves the revisions kept under the note or folder oldPath to
+// newPath.
+func (s *Service) moveHistory(oldPath, newPath string) error {
+	src := path.Join(historyDir, oldPath)
+	exists, err := s.store.DirExists(src)
+	if err != nil || !exists {
+		return err
+	}
+	return s.store.Move(src, path.Join(historyDir, newPath))
+}
+
+// revisionPath returns where revision id of the note at p is stored.
+func revisionPath(p, id string) string {
+	return path.Join(historyDir, p, id+".md")
+}
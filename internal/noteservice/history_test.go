@@ -0,0 +1,88 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestRevisions(t *testing.T) {
+	svc := testService(t, WithHistory(2))
+	ctx := context.Background()
+	createNote(t, svc, "plan.md", "# v1\n")
+	for _, c := range []string{"# v2\n", "# v2\n", "# v3\n", "# v4\n"} {
+		if _, err := svc.UpdateNote(ctx, "plan.md", []byte(c), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revs, err := svc.Revisions(ctx, "plan.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 || !revs[0].SavedAt.After(revs[1].SavedAt) {
+		t.Fatalf("revisions = %+v, want the 2 newest", revs)
+	}
+	rev, err := svc.Revision(ctx, "plan.md", revs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.Content != "# v3\n" || rev.Checksum == "" {
+		t.Errorf("newest revision = %+v", rev)
+	}
+	if old, _ := svc.Revision(ctx, "plan.md", revs[1].ID); old == nil || old.Content != "# v2\n" {
+		t.Errorf("oldest kept revision = %+v", old)
+	}
+
+	restored, err := svc.RestoreRevision(ctx, "plan.md", revs[1].ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Content != "# v2\n" {
+		t.Errorf("restored content = %q", restored.Content)
+	}
+	if revs, _ := svc.Revisions(ctx, "plan.md"); len(revs) != 2 {
+		t.Errorf("revisions after restore = %d", len(revs))
+	} else if rev, _ := svc.Revision(ctx, "plan.md", revs[0].ID); rev.Content != "# v4\n" {
+		t.Errorf("restore did not keep the replaced content: %q", rev.Content)
+	}
+
+	if _, err := svc.Revision(ctx, "plan.md", "../../plan"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("bad id err = %v", err)
+	}
+	if _, err := svc.Revisions(ctx, "missing.md"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing note err = %v", err)
+	}
+	if notes, _ := svc.db.NotesWithPrefix(""); len(notes) != 1 {
+		t.Errorf("revisions were indexed: %d notes", len(notes))
+	}
+}
+
+func TestRevisions_Rename(t *testing.T) {
+	svc := testService(t, WithHistory(5))
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "# A")
+	if _, err := svc.UpdateNote(ctx, "a.md", []byte("# A2"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.RenameNote(ctx, "a.md", "b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if revs, err := svc.Revisions(ctx, "b.md"); err != nil || len(revs) != 1 {
+		t.Errorf("revisions after rename = %+v, %v", revs, err)
+	}
+}
+
+func TestRevisions_Disabled(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "# A")
+	if _, err := svc.UpdateNote(ctx, "a.md", []byte("# A2"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if revs, err := svc.Revisions(ctx, "a.md"); err != nil || len(revs) != 0 {
+		t.Errorf("revisions = %+v, %v", revs, err)
+	}
+}
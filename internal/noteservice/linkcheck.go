@@ -0,0 +1,131 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/index"
+)
+
+// checkWorkers is the number of URLs checked in parallel.
+const checkWorkers = 4
+
+// URLChecker returns the HTTP status an external URL answers with.
+// asset.CheckURL is the one used in production.
+type URLChecker func(ctx context.Context, url string) (int, error)
+
+// WithLinkChecker checks external links with check. Without it link checks
+// fail and the dead-link report only holds earlier results.
+func WithLinkChecker(check URLChecker) Option {
+	return func(s *Service) { s.checkURL = check }
+}
+
+// DeadLinks is the dead external link report.
+type DeadLinks struct {
+	// Links are the dead URLs, by URL.
+	Links []index.DeadLink `json:"links" validate:"required"`
+	// URLs counts the distinct http(s) URLs linked from notes, Checked
+	// those checked at least once.
+	URLs    int `json:"urls" validate:"required"`
+	Checked int `json:"checked" validate:"required"`
+}
+
+// DeadLinks reports the external URLs linked from notes whose last check
+// answered 404 or 410 or failed (timeout, DNS, or connection error).
+func (s *Service) DeadLinks(_ context.Context) (*DeadLinks, error) {
+	links, err := s.db.DeadLinks()
+	if err != nil {
+		return nil, err
+	}
+	urls, checked, err := s.db.URLCheckCounts()
+	if err != nil {
+		return nil, err
+	}
+	return &DeadLinks{Links: links, URLs: urls, Checked: checked}, nil
+}
+
+// CheckStaleLinks checks up to limit URLs linked from notes that were never
+// checked or last checked more than maxAge ago, and returns how many it
+// checked.
+func (s *Service) CheckStaleLinks(ctx context.Context, maxAge time.Duration, limit int) (int, error) {
+	urls, err := s.db.URLsToCheck(time.Now().Add(-maxAge), limit)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.checkLinks(ctx, urls); err != nil {
+		return 0, err
+	}
+	return len(urls), nil
+}
+
+// RecheckDeadLinks checks url again, or every dead link when url is empty,
+// and returns the updated report. It fails with apperr.ErrNotFound when no
+// note links to url.
+func (s *Service) RecheckDeadLinks(ctx context.Context, url string) (*DeadLinks, error) {
+	var urls []string
+	if url != "" {
+		notes, err := s.db.NotesLinkingURL(url)
+		if err != nil {
+			return nil, err
+		}
+		if len(notes) == 0 {
+			return nil, fmt.Errorf("%w: no note links to %s", apperr.ErrNotFound, url)
+		}
+		urls = []string{url}
+	} else {
+		dead, err := s.db.DeadLinks()
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range dead {
+			urls = append(urls, d.URL)
+		}
+	}
+	if err := s.checkLinks(ctx, urls); err != nil {
+		return nil, err
+	}
+	return s.DeadLinks(ctx)
+}
+
+// checkLinks checks urls in parallel and records the results. URLs on
+// blocked hosts are recorded as checked with neither status nor error, so
+// they are not reported as dead.
+func (s *Service) checkLinks(ctx context.Context, urls []string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	if s.checkURL == nil {
+		return errors.New("link checking is not configured")
+	}
+	checks := make([]index.URLCheck, len(urls))
+	sem := make(chan struct{}, checkWorkers)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			status, err := s.checkURL(ctx, u)
+			c := index.URLCheck{URL: u, Status: status, CheckedAt: time.Now()}
+			if err != nil && !errors.Is(err, asset.ErrBlockedHost) {
+				c.Error = err.Error()
+			}
+			checks[i] = c
+		})
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		// Checks cut short by shutdown are not results.
+		return err
+	}
+	for _, c := range checks {
+		if err := s.db.SaveURLCheck(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/asset"
+)
+
+func TestDeadLinks(t *testing.T) {
+	var calls atomic.Int32
+	status := map[string]int{"https://ok.example/": 200, "https://gone.example/": 404}
+	check := func(_ context.Context, url string) (int, error) {
+		calls.Add(1)
+		switch url {
+		case "https://slow.example/":
+			return 0, errors.New("timeout")
+		case "http://localhost/":
+			return 0, fmt.Errorf("%w: localhost", asset.ErrBlockedHost)
+		}
+		return status[url], nil
+	}
+	svc := testService(t, WithLinkChecker(check))
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "https://ok.example/ https://gone.example/ https://slow.example/\n")
+	createNote(t, svc, "b.md", "https://gone.example/ http://localhost/\n")
+
+	n, err := svc.CheckStaleLinks(ctx, time.Hour, 100)
+	if err != nil || n != 4 {
+		t.Fatalf("checked %d, %v; want 4", n, err)
+	}
+	report, err := svc.DeadLinks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.URLs != 4 || report.Checked != 4 || len(report.Links) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	gone, slow := report.Links[0], report.Links[1]
+	if gone.URL != "https://gone.example/" || gone.Status != 404 || len(gone.Notes) != 2 {
+		t.Errorf("gone = %+v", gone)
+	}
+	if slow.URL != "https://slow.example/" || slow.Error != "timeout" || slow.CheckedAt.IsZero() {
+		t.Errorf("slow = %+v", slow)
+	}
+
+	// Fresh checks are not repeated.
+	if n, _ := svc.CheckStaleLinks(ctx, time.Hour, 100); n != 0 {
+		t.Errorf("rechecked %d fresh links", n)
+	}
+
+	// A manual recheck of every dead link picks up the fix.
+	status["https://gone.example/"] = 200
+	before := calls.Load()
+	report, err = svc.RecheckDeadLinks(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load()-before != 2 || len(report.Links) != 1 || report.Links[0].URL != "https://slow.example/" {
+		t.Errorf("after recheck: %+v", report)
+	}
+
+	if _, err := svc.RecheckDeadLinks(ctx, "https://unknown.example/"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("unlinked url: err = %v, want ErrNotFound", err)
+	}
+}
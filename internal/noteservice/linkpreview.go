@@ -0,0 +1,121 @@
+package noteservice
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/parser"
+)
+
+const (
+	// DefaultLinkPreviewTTL is how long fetched page metadata is reused.
+	DefaultLinkPreviewTTL = 7 * 24 * time.Hour
+
+	// maxPreviewFetches caps the pages one LinkPreviews call downloads;
+	// later calls fetch the rest.
+	maxPreviewFetches = 20
+	// previewWorkers is the number of pages downloaded in parallel.
+	previewWorkers = 4
+)
+
+// PageFetcher downloads the metadata of a web page. asset.FetchPage, which
+// blocks loopback and cloud metadata hosts, is the one used in production.
+type PageFetcher func(ctx context.Context, url string) (*asset.Page, error)
+
+// WithLinkPreviews makes LinkPreviews download the pages of external links
+// with fetch, caching the metadata in the index for ttl (default
+// DefaultLinkPreviewTTL). Without it only cached metadata is returned.
+func WithLinkPreviews(fetch PageFetcher, ttl time.Duration) Option {
+	return func(s *Service) {
+		s.fetchPage = fetch
+		s.previewTTL = ttl
+		if ttl <= 0 {
+			s.previewTTL = DefaultLinkPreviewTTL
+		}
+	}
+}
+
+// LinkPreviews lists the external links of a note with their page metadata.
+type LinkPreviews struct {
+	Path string `json:"path" example:"reading/go.md" validate:"required"`
+	// Links holds every http(s) URL in the body, in order of appearance.
+	// Pages not fetched yet carry only their URL.
+	Links []index.LinkMetadata `json:"links" validate:"required"`
+}
+
+// LinkPreviews returns the http(s) URLs in the body of the note at notePath
+// with their cached title, description, image, and favicon. With link
+// previews enabled, pages never fetched or fetched longer than the TTL ago
+// are downloaded first (at most 20 per call); failed fetches are cached
+// with their error and retried after the TTL as well.
+func (s *Service) LinkPreviews(ctx context.Context, notePath string) (*LinkPreviews, error) {
+	note, err := s.GetNote(ctx, notePath)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.Parse([]byte(note.Content))
+	if err != nil {
+		return nil, err
+	}
+	urls := parser.URLs(res)
+	cached, err := s.db.LinkMetadata(urls)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.fetchPage != nil {
+		now := time.Now()
+		var stale []string
+		for _, u := range urls {
+			if m, ok := cached[u]; !ok || now.Sub(*m.FetchedAt) > s.previewTTL {
+				stale = append(stale, u)
+			}
+		}
+		if len(stale) > maxPreviewFetches {
+			stale = stale[:maxPreviewFetches]
+		}
+		for _, m := range s.fetchPreviews(ctx, stale, now) {
+			if err := s.db.SaveLinkMetadata(m); err != nil {
+				return nil, err
+			}
+			cached[m.URL] = m
+		}
+	}
+
+	out := &LinkPreviews{Path: notePath, Links: make([]index.LinkMetadata, len(urls))}
+	for i, u := range urls {
+		m, ok := cached[u]
+		if !ok {
+			m = index.LinkMetadata{URL: u}
+		}
+		out.Links[i] = m
+	}
+	return out, nil
+}
+
+// fetchPreviews downloads the pages at urls in parallel, recording a failed
+// fetch as metadata with an error.
+func (s *Service) fetchPreviews(ctx context.Context, urls []string, now time.Time) []index.LinkMetadata {
+	out := make([]index.LinkMetadata, len(urls))
+	sem := make(chan struct{}, previewWorkers)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			m := index.LinkMetadata{URL: u, FetchedAt: &now}
+			page, err := s.fetchPage(ctx, u)
+			if err != nil {
+				m.Error = err.Error()
+			} else {
+				m.Title, m.Description, m.Image, m.Favicon = page.Title, page.Description, page.Image, page.Favicon
+			}
+			out[i] = m
+		})
+	}
+	wg.Wait()
+	return out
+}
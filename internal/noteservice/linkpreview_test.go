@@ -0,0 +1,67 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/asset"
+)
+
+func TestLinkPreviews(t *testing.T) {
+	var calls atomic.Int32
+	fetch := func(_ context.Context, url string) (*asset.Page, error) {
+		calls.Add(1)
+		if url == "https://down.example/" {
+			return nil, errors.New("fetch failed: HTTP 503")
+		}
+		return &asset.Page{URL: url, Title: "Title of " + url, Favicon: "https://example.com/favicon.ico"}, nil
+	}
+	svc := testService(t, WithLinkPreviews(fetch, time.Hour))
+	ctx := context.Background()
+	createNote(t, svc, "links.md", "# Links\n\nSee https://example.com/a, [docs](https://example.com/b) and https://down.example/.\nAgain: https://example.com/a\n")
+
+	got, err := svc.LinkPreviews(ctx, "links.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Links) != 3 || calls.Load() != 3 {
+		t.Fatalf("links = %+v after %d fetches, want 3 distinct URLs fetched once", got.Links, calls.Load())
+	}
+	a, b, down := got.Links[0], got.Links[1], got.Links[2]
+	if a.URL != "https://example.com/a" || a.Title != "Title of https://example.com/a" || a.Favicon == "" || a.FetchedAt == nil {
+		t.Errorf("first link = %+v", a)
+	}
+	if b.URL != "https://example.com/b" {
+		t.Errorf("second link = %+v", b)
+	}
+	if down.Error == "" || down.Title != "" {
+		t.Errorf("failed link = %+v, want an error", down)
+	}
+
+	// Cached, including the failure, until the TTL passes.
+	if _, err := svc.LinkPreviews(ctx, "links.md"); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("second call fetched again: %d fetches", calls.Load())
+	}
+
+	// Without fetching, only cached metadata is returned.
+	svc.fetchPage = nil
+	createNote(t, svc, "more.md", "https://example.com/a https://new.example/\n")
+	got, err = svc.LinkPreviews(ctx, "more.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Links) != 2 || got.Links[0].Title == "" || got.Links[1].FetchedAt != nil {
+		t.Errorf("cache-only links = %+v", got.Links)
+	}
+
+	if _, err := svc.LinkPreviews(ctx, "missing.md"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing note: err = %v, want ErrNotFound", err)
+	}
+}
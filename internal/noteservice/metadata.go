@@ -0,0 +1,18 @@
+package noteservice
+
+import (
+	"context"
+
+	"github.com/starford/kenaz/internal/index"
+)
+
+// MetadataKeys returns the names of the extractors with indexed values.
+func (s *Service) MetadataKeys(_ context.Context) ([]string, error) {
+	return s.db.MetadataKeys()
+}
+
+// MetadataValues returns the values indexed under key with the notes each
+// occurs in, most widespread first, optionally restricted to value.
+func (s *Service) MetadataValues(_ context.Context, key, value string) ([]index.MetadataValue, error) {
+	return s.db.MetadataValues(key, value)
+}
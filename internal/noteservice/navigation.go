@@ -0,0 +1,202 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/parser"
+)
+
+const (
+	// parentField is the frontmatter field naming a note's parent note.
+	parentField = "parent"
+	// maxParentDepth bounds the parent chain followed for breadcrumbs.
+	maxParentDepth = 32
+)
+
+// Crumb is one step of a breadcrumb trail.
+type Crumb struct {
+	// Path is the folder path for folders and the note path for notes.
+	Path string `json:"path" example:"projects/kenaz" validate:"required"`
+	// Title is the folder name for folders and the note title for notes.
+	Title string `json:"title" example:"kenaz" validate:"required"`
+}
+
+// Breadcrumbs locates a note in the vault.
+type Breadcrumbs struct {
+	Path  string `json:"path" example:"projects/kenaz/api.md" validate:"required"`
+	Title string `json:"title" example:"API" validate:"required"`
+	// Folders are the folders containing the note, outermost first.
+	Folders []Crumb `json:"folders" validate:"required"`
+	// Parents is the chain of notes named by the "parent" frontmatter
+	// field, root first and ending with the note's direct parent.
+	Parents []Crumb `json:"parents" validate:"required"`
+}
+
+// Breadcrumbs returns the folder ancestry of the note at notePath and its
+// chain of parent notes. The chain follows the "parent" frontmatter field
+// (a wikilink or path; the first entry of a list) and stops at a note
+// without one, at a target that does not resolve, or where it would loop.
+func (s *Service) Breadcrumbs(ctx context.Context, notePath string) (*Breadcrumbs, error) {
+	note, err := s.GetNote(ctx, notePath)
+	if err != nil {
+		return nil, err
+	}
+	out := &Breadcrumbs{Path: notePath, Title: note.Title, Folders: []Crumb{}, Parents: []Crumb{}}
+
+	dir := path.Dir(notePath)
+	for dir != "." {
+		out.Folders = append(out.Folders, Crumb{Path: dir, Title: path.Base(dir)})
+		dir = path.Dir(dir)
+	}
+	slices.Reverse(out.Folders)
+
+	seen := map[string]bool{notePath: true}
+	data := []byte(note.Content)
+	for len(out.Parents) < maxParentDepth {
+		parent, err := s.parentOf(data)
+		if err != nil {
+			return nil, err
+		}
+		if parent == "" || seen[parent] {
+			break
+		}
+		seen[parent] = true
+		data, err = s.store.Read(parent)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			return nil, err
+		}
+		res, err := s.db.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		out.Parents = append(out.Parents, Crumb{Path: parent, Title: res.Title})
+	}
+	slices.Reverse(out.Parents)
+	return out, nil
+}
+
+// parentOf resolves the "parent" frontmatter field of a note to a note
+// path, returning "" when it is unset or does not resolve.
+func (s *Service) parentOf(data []byte) (string, error) {
+	res, err := parser.Parse(data, parser.WithLinkFields(parentField))
+	if err != nil || len(res.FrontmatterLinks) == 0 {
+		return "", nil
+	}
+	return s.db.ResolveLink(res.FrontmatterLinks[0])
+}
+
+// Outline is the heading structure of a note.
+type Outline struct {
+	Path string `json:"path" example:"guides/setup.md" validate:"required"`
+	// Headings are the note's headings in document order.
+	Headings []index.SectionRef `json:"headings" validate:"required"`
+}
+
+// Outline returns the headings of the note at notePath as indexed, with
+// the anchors "#heading" links target.
+func (s *Service) Outline(_ context.Context, notePath string) (*Outline, error) {
+	headings, err := s.db.Outline(notePath)
+	if err != nil {
+		return nil, err
+	}
+	if headings == nil {
+		return nil, fmt.Errorf("%w: %s", apperr.ErrNotFound, notePath)
+	}
+	return &Outline{Path: notePath, Headings: headings}, nil
+}
+
+// SitemapNode is a folder or note in the vault sitemap.
+type SitemapNode struct {
+	// Name is the folder name or note file name.
+	Name string `json:"name" example:"api.md" validate:"required"`
+	Path string `json:"path" example:"projects/kenaz/api.md" validate:"required"`
+	// Title is set for notes only.
+	Title string `json:"title,omitempty" example:"API"`
+	// Children lists a folder's subfolders and then its notes, each by
+	// name. Nil for notes.
+	Children []*SitemapNode `json:"children,omitempty"`
+}
+
+// Sitemap is the folder tree of the notes in the vault or a folder.
+type Sitemap struct {
+	// Folder is the folder the tree starts at, "" for the vault root.
+	Folder string `json:"folder"`
+	// Notes counts the notes in the tree.
+	Notes    int            `json:"notes" validate:"required"`
+	Children []*SitemapNode `json:"children" validate:"required"`
+}
+
+// Sitemap returns the notes under folder ("" for the whole vault) as a
+// tree of folders. Folders holding no notes are left out.
+func (s *Service) Sitemap(_ context.Context, folder string) (*Sitemap, error) {
+	prefix := ""
+	if strings.Trim(folder, "/") != "" {
+		f, err := cleanFolder(folder)
+		if err != nil {
+			return nil, err
+		}
+		folder, prefix = f, f+"/"
+	} else {
+		folder = ""
+	}
+	rows, err := s.db.NotesWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if folder != "" && len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no notes under %s", apperr.ErrNotFound, folder)
+	}
+
+	root := &SitemapNode{Path: folder}
+	folders := map[string]*SitemapNode{folder: root}
+	var dirNode func(dir string) *SitemapNode
+	dirNode = func(dir string) *SitemapNode {
+		if n, ok := folders[dir]; ok {
+			return n
+		}
+		parent := path.Dir(dir)
+		if parent == "." {
+			parent = ""
+		}
+		n := &SitemapNode{Name: path.Base(dir), Path: dir, Children: []*SitemapNode{}}
+		folders[dir] = n
+		p := dirNode(parent)
+		p.Children = append(p.Children, n)
+		return n
+	}
+	for _, r := range rows {
+		dir := path.Dir(r.Path)
+		if dir == "." {
+			dir = ""
+		}
+		p := dirNode(dir)
+		p.Children = append(p.Children, &SitemapNode{Name: path.Base(r.Path), Path: r.Path, Title: r.Title})
+	}
+	for _, n := range folders {
+		sortSitemap(n.Children)
+	}
+	return &Sitemap{Folder: folder, Notes: len(rows), Children: nonNilSlice(root.Children)}, nil
+}
+
+// sortSitemap orders folders before notes, each by name.
+func sortSitemap(nodes []*SitemapNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		fi, fj := nodes[i].Children != nil, nodes[j].Children != nil
+		if fi != fj {
+			return fi
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+}
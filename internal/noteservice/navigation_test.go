@@ -0,0 +1,81 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestBreadcrumbs(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "home.md", "# Home\n")
+	createNote(t, svc, "projects/kenaz/index.md", "---\nparent: \"[[Home]]\"\n---\n# Kenaz\n")
+	createNote(t, svc, "projects/kenaz/api/rest.md", "---\nparent: \"[[projects/kenaz/index|Kenaz]]\"\n---\n# REST\n")
+	createNote(t, svc, "loop-a.md", "---\nparent: \"[[loop-b]]\"\n---\n# A\n")
+	createNote(t, svc, "loop-b.md", "---\nparent: \"[[loop-a]]\"\n---\n# B\n")
+
+	got, err := svc.Breadcrumbs(ctx, "projects/kenaz/api/rest.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "REST" || len(got.Folders) != 3 || got.Folders[0].Path != "projects" || got.Folders[2].Path != "projects/kenaz/api" || got.Folders[2].Title != "api" {
+		t.Errorf("folders = %+v", got)
+	}
+	if len(got.Parents) != 2 || got.Parents[0].Path != "home.md" || got.Parents[1].Title != "Kenaz" {
+		t.Errorf("parents = %+v", got.Parents)
+	}
+
+	got, err = svc.Breadcrumbs(ctx, "loop-a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Folders) != 0 || len(got.Parents) != 1 || got.Parents[0].Path != "loop-b.md" {
+		t.Errorf("cycle = %+v", got)
+	}
+
+	if _, err := svc.Breadcrumbs(ctx, "missing.md"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing note: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "zeta.md", "# Zeta\n")
+	createNote(t, svc, "projects/b.md", "# B\n")
+	createNote(t, svc, "projects/kenaz/a.md", "# A\n")
+	createNote(t, svc, "areas/x.md", "# X\n")
+
+	got, err := svc.Sitemap(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Notes != 4 || len(got.Children) != 3 {
+		t.Fatalf("sitemap = %+v", got)
+	}
+	areas, projects, zeta := got.Children[0], got.Children[1], got.Children[2]
+	if areas.Name != "areas" || projects.Path != "projects" || zeta.Title != "Zeta" || zeta.Children != nil {
+		t.Errorf("top level = %+v, %+v, %+v", areas, projects, zeta)
+	}
+	if len(projects.Children) != 2 || projects.Children[0].Path != "projects/kenaz" || projects.Children[1].Name != "b.md" {
+		t.Errorf("projects = %+v", projects.Children)
+	}
+
+	got, err = svc.Sitemap(ctx, "/projects/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Folder != "projects" || got.Notes != 2 || len(got.Children) != 2 || got.Children[0].Children[0].Path != "projects/kenaz/a.md" {
+		t.Errorf("folder sitemap = %+v", got)
+	}
+
+	if _, err := svc.Sitemap(ctx, "nowhere"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("empty folder: err = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Sitemap(ctx, "../up"); !errors.Is(err, apperr.ErrInvalidPath) {
+		t.Errorf("traversal: err = %v, want ErrInvalidPath", err)
+	}
+}
@@ -0,0 +1,111 @@
+package noteservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/starford/kenaz/internal/parser"
+)
+
+// Patch operations.
+const (
+	// PatchAppend adds content at the end of the note or section.
+	PatchAppend = "append"
+	// PatchPrepend adds content at the start of the body (after the
+	// frontmatter) or of the section (after its heading).
+	PatchPrepend = "prepend"
+	// PatchReplaceHeading replaces the body of a section, keeping its
+	// heading line.
+	PatchReplaceHeading = "replace_heading"
+)
+
+// ErrInvalidPatch is returned by PatchNote for an unknown operation or
+// missing arguments.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// Patch is a partial edit of a note.
+type Patch struct {
+	// Op is "append", "prepend", or "replace_heading".
+	Op string `json:"op" example:"replace_heading" validate:"required"`
+	// Heading selects the section ("Log" or "## Log", case-insensitive);
+	// required for replace_heading, optional otherwise.
+	Heading string `json:"heading,omitempty" example:"## Log"`
+	// Content is inserted as whole lines. It may be empty only for
+	// replace_heading, which then empties the section.
+	Content string `json:"content" example:"- 10:30 Deployed v2"`
+}
+
+// PatchNote applies p to the note at path and re-indexes it, so a section can
+// be changed without sending the whole note. Patches and appends to a note
+// are serialized and applied to its latest content, so ifMatch (the
+// checksum of the note the edit is based on) is optional even when
+// vault.require_if_match is set; a stale one yields apperr.ErrConflict.
+// Unknown operations and missing arguments yield ErrInvalidPatch, and a
+// heading that is not found ErrHeadingNotFound.
+func (s *Service) PatchNote(_ context.Context, path string, p Patch, ifMatch string) (*NoteDetail, error) {
+	switch {
+	case p.Op != PatchAppend && p.Op != PatchPrepend && p.Op != PatchReplaceHeading:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, p.Op)
+	case p.Op == PatchReplaceHeading && strings.TrimSpace(p.Heading) == "":
+		return nil, fmt.Errorf("%w: replace_heading needs a heading", ErrInvalidPatch)
+	case p.Op != PatchReplaceHeading && strings.TrimSpace(p.Content) == "":
+		return nil, fmt.Errorf("%w: %s needs content", ErrInvalidPatch, p.Op)
+	}
+	return s.modify(path, ifMatch, nil, func(content []byte) ([]byte, error) {
+		return applyPatch(content, p)
+	})
+}
+
+// applyPatch returns content with p applied.
+func applyPatch(content []byte, p Patch) ([]byte, error) {
+	var h parser.Heading
+	if p.Heading != "" {
+		var ok bool
+		if h, ok = findHeading(content, p.Heading); !ok {
+			return nil, ErrHeadingNotFound
+		}
+	}
+	switch p.Op {
+	case PatchAppend:
+		return insertBlock(content, p.Content, p.Heading)
+	case PatchPrepend:
+		at := parser.BodyStart(content)
+		if p.Heading != "" {
+			at = h.End
+		}
+		return insertLines(content, skipBlankLines(content, at), p.Content), nil
+	default:
+		end := sectionBodyEnd(content, h)
+		out := append([]byte{}, content[:h.End]...)
+		if len(out) > 0 && out[len(out)-1] != '\n' {
+			out = append(out, '\n')
+		}
+		if block := strings.TrimRight(p.Content, "\n"); block != "" {
+			out = append(out, block...)
+			out = append(out, '\n')
+		}
+		return append(out, content[end:]...), nil
+	}
+}
+
+// skipBlankLines returns the offset of the first non-blank line at or after
+// at, or at itself when only blank lines follow.
+func skipBlankLines(content []byte, at int) int {
+	for pos := at; pos < len(content); {
+		nl := bytes.IndexByte(content[pos:], '\n')
+		if nl < 0 {
+			if len(bytes.TrimSpace(content[pos:])) == 0 {
+				return at
+			}
+			return pos
+		}
+		if len(bytes.TrimSpace(content[pos:pos+nl])) > 0 {
+			return pos
+		}
+		pos += nl + 1
+	}
+	return at
+}
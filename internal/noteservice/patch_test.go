@@ -0,0 +1,76 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestApplyPatch(t *testing.T) {
+	doc := "---\ntitle: Log\n---\n\n# Log\n\n## Today\n\n- a\n\n## Empty\n## Last\ntext"
+	cases := []struct {
+		name string
+		p    Patch
+		want string
+	}{
+		{"prepend body", Patch{Op: PatchPrepend, Content: "> pinned"},
+			"---\ntitle: Log\n---\n\n> pinned\n# Log\n\n## Today\n\n- a\n\n## Empty\n## Last\ntext"},
+		{"prepend section", Patch{Op: PatchPrepend, Heading: "today", Content: "- b"},
+			"---\ntitle: Log\n---\n\n# Log\n\n## Today\n\n- b\n- a\n\n## Empty\n## Last\ntext"},
+		{"append section", Patch{Op: PatchAppend, Heading: "## Today", Content: "- b\n"},
+			"---\ntitle: Log\n---\n\n# Log\n\n## Today\n\n- a\n- b\n\n## Empty\n## Last\ntext"},
+		{"replace section", Patch{Op: PatchReplaceHeading, Heading: "Today", Content: "- x\n- y"},
+			"---\ntitle: Log\n---\n\n# Log\n\n## Today\n- x\n- y\n\n## Empty\n## Last\ntext"},
+		{"replace empty section", Patch{Op: PatchReplaceHeading, Heading: "Empty", Content: "- z"},
+			"---\ntitle: Log\n---\n\n# Log\n\n## Today\n\n- a\n\n## Empty\n- z\n## Last\ntext"},
+		{"clear last section", Patch{Op: PatchReplaceHeading, Heading: "Last"},
+			"---\ntitle: Log\n---\n\n# Log\n\n## Today\n\n- a\n\n## Empty\n## Last\n"},
+	}
+	for _, c := range cases {
+		got, err := applyPatch([]byte(doc), c.p)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if string(got) != c.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", c.name, got, c.want)
+		}
+	}
+	if _, err := applyPatch([]byte(doc), Patch{Op: PatchPrepend, Heading: "Missing", Content: "x"}); !errors.Is(err, ErrHeadingNotFound) {
+		t.Errorf("missing heading: err = %v", err)
+	}
+}
+
+func TestPatchNote(t *testing.T) {
+	svc := testService(t, WithRequireIfMatch(true))
+	ctx := context.Background()
+	note, err := svc.CreateNote(ctx, "log.md", []byte("# Log\n\n## Done\n- old\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.PatchNote(ctx, "log.md", Patch{Op: PatchReplaceHeading, Heading: "Done", Content: "- new"}, "")
+	if err != nil {
+		t.Fatalf("patch without checksum: %v", err)
+	}
+	if !strings.HasSuffix(got.Content, "## Done\n- new\n") {
+		t.Errorf("content = %q", got.Content)
+	}
+
+	if _, err := svc.PatchNote(ctx, "log.md", Patch{Op: PatchAppend, Content: "x"}, note.Checksum); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("stale checksum: err = %v, want ErrConflict", err)
+	}
+	if _, err := svc.PatchNote(ctx, "log.md", Patch{Op: PatchAppend, Content: "x"}, got.Checksum); err != nil {
+		t.Errorf("current checksum: %v", err)
+	}
+	for _, p := range []Patch{{Op: "delete"}, {Op: PatchReplaceHeading, Content: "x"}, {Op: PatchPrepend}} {
+		if _, err := svc.PatchNote(ctx, "log.md", p, ""); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("%+v: err = %v, want ErrInvalidPatch", p, err)
+		}
+	}
+	if _, err := svc.PatchNote(ctx, "missing.md", Patch{Op: PatchAppend, Content: "x"}, ""); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing note: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,94 @@
+package noteservice
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+)
+
+// ReindexStatus is the state of the running or last reindex.
+type ReindexStatus struct {
+	Running    bool       `json:"running" validate:"required"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	index.ReindexProgress
+	// Error is why the last reindex stopped early.
+	Error string `json:"error,omitempty"`
+}
+
+// reindexState tracks the background reindex.
+type reindexState struct {
+	mu     sync.Mutex
+	status ReindexStatus
+}
+
+// StartReindex starts rebuilding the index from the vault in the background
+// (see index.Reindex) and returns its initial status; ReindexStatus reports
+// its progress. It fails with apperr.ErrConflict while a reindex runs.
+func (s *Service) StartReindex(_ context.Context) (ReindexStatus, error) {
+	r := &s.reindex
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status.Running {
+		return r.status, fmt.Errorf("%w: a reindex is already running", apperr.ErrConflict)
+	}
+	now := time.Now()
+	r.status = ReindexStatus{Running: true, StartedAt: &now, ReindexProgress: index.ReindexProgress{Errors: []index.ReindexError{}}}
+
+	go func() {
+		p, err := index.Reindex(context.Background(), s.db, s.store, slog.Default(), func(p index.ReindexProgress) {
+			r.mu.Lock()
+			r.status.ReindexProgress = p
+			r.mu.Unlock()
+		})
+		done := time.Now()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.status.Running, r.status.FinishedAt, r.status.ReindexProgress = false, &done, p
+		if err != nil {
+			slog.Error("reindex failed", slog.String("error", err.Error()))
+			r.status.Error = err.Error()
+		}
+	}()
+	return r.status, nil
+}
+
+// ReindexStatus returns the state of the running or last reindex; Running
+// is false and StartedAt nil when none was started.
+func (s *Service) ReindexStatus(_ context.Context) ReindexStatus {
+	s.reindex.mu.Lock()
+	defer s.reindex.mu.Unlock()
+	return s.reindex.status
+}
+
+// IndexStats is index.Stats with the number of notes in the vault, which
+// Notes equals when the index is in sync.
+type IndexStats struct {
+	index.Stats
+	VaultNotes int `json:"vault_notes" example:"1200" validate:"required"`
+}
+
+// IndexStats reports what the index holds (see index.DB.Stats) and how
+// many notes the vault has.
+func (s *Service) IndexStats(_ context.Context) (*IndexStats, error) {
+	st, err := s.db.Stats()
+	if err != nil {
+		return nil, err
+	}
+	metas, err := s.store.ListStat("")
+	if err != nil {
+		return nil, err
+	}
+	return &IndexStats{Stats: *st, VaultNotes: len(metas)}, nil
+}
+
+// OptimizeIndex runs index maintenance now (see index.DB.Optimize),
+// including VACUUM when vacuum is set. It stops when ctx is done.
+func (s *Service) OptimizeIndex(ctx context.Context, vacuum bool) (*index.OptimizeResult, error) {
+	return s.db.Optimize(ctx, vacuum)
+}
@@ -0,0 +1,55 @@
+package noteservice
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/starford/kenaz/internal/parser"
+	"github.com/starford/kenaz/internal/render"
+)
+
+// noteURLPrefix is the API URL notes are served under; rendered wikilinks
+// point there.
+const noteURLPrefix = "/api/notes/"
+
+// NoteHTML renders the body of the note at notePath (frontmatter excluded)
+// to sanitized HTML. Wikilinks that resolve link to the note's API URL
+// (/api/notes/<path>, with a "#heading" anchor kept as the heading id),
+// embedded attachments point at /attachments/<name>, and unresolved links
+// are rendered as spans with the "unresolved" class.
+func (s *Service) NoteHTML(ctx context.Context, notePath string) (string, error) {
+	note, err := s.GetNote(ctx, notePath)
+	if err != nil {
+		return "", err
+	}
+	data := []byte(note.Content)
+
+	var resolveErr error
+	out := render.HTML(string(data[parser.BodyStart(data):]), render.Options{
+		Link: func(target string) string {
+			p, err := s.db.ResolveLink(target)
+			if err != nil {
+				resolveErr = err
+				return ""
+			}
+			if p == "" {
+				return ""
+			}
+			return noteURL(p)
+		},
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return out, nil
+}
+
+// noteURL returns the API URL of the note at p, escaping each segment.
+func noteURL(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return noteURLPrefix + strings.Join(segs, "/")
+}
@@ -0,0 +1,125 @@
+package noteservice
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/checksum"
+	"github.com/starford/kenaz/internal/merge"
+)
+
+// Replace describes a vault-wide find and replace. Exactly one of Query (a
+// literal) and Regex (RE2 syntax; Replacement may use $1 and ${name}) is set.
+// Folder and Tag restrict the notes searched. Only note bodies are changed;
+// frontmatter is left alone.
+type Replace struct {
+	Query       string
+	Regex       string
+	Replacement string
+	IgnoreCase  bool
+	Folder      string
+	Tag         string
+	DryRun      bool
+}
+
+// ReplacedNote is one note changed by a replacement.
+type ReplacedNote struct {
+	Path    string `json:"path" validate:"required"`
+	Matches int    `json:"matches" validate:"required"`
+	// MutationID identifies the note's write for Undo; empty on dry runs
+	// and when undo is disabled.
+	MutationID string `json:"mutation_id,omitempty"`
+}
+
+// ReplaceResult reports the notes a replacement changed, or would change on a
+// dry run, whose Changes hold a diff per note.
+type ReplaceResult struct {
+	Notes   []ReplacedNote  `json:"notes" validate:"required"`
+	Matches int             `json:"matches" validate:"required"`
+	DryRun  bool            `json:"dry_run"`
+	Changes []PlannedChange `json:"changes,omitempty"`
+}
+
+// pattern compiles the replacement's search into a regexp; literal queries
+// are quoted, and their replacement is taken literally.
+func (r Replace) pattern() (*regexp.Regexp, bool, error) {
+	if (r.Query == "") == (r.Regex == "") {
+		return nil, false, fmt.Errorf("%w: set exactly one of query and regex", apperr.ErrInvalidPath)
+	}
+	expr, literal := r.Regex, false
+	if r.Query != "" {
+		expr, literal = regexp.QuoteMeta(r.Query), true
+	}
+	if r.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: invalid regex: %v", apperr.ErrInvalidPath, err)
+	}
+	return re, literal, nil
+}
+
+// ReplaceAll applies r to every matching note in path order. Each changed note
+// is written like UpdateNote (against the checksum it was read with), so it
+// is re-indexed, counted in writing analytics, and undoable on its own.
+func (s *Service) ReplaceAll(ctx context.Context, r Replace) (*ReplaceResult, error) {
+	re, literal, err := r.pattern()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.selectNotes(r.Folder, r.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ReplaceResult{Notes: []ReplacedNote{}, DryRun: r.DryRun}
+	for _, row := range rows {
+		data, err := s.store.Read(row.Path)
+		if err != nil {
+			continue
+		}
+		parsed, err := s.db.Parse(data)
+		if err != nil {
+			continue
+		}
+		content := parsed.Body
+		head := string(data[:len(data)-len(content)])
+		n := len(re.FindAllStringIndex(content, -1))
+		if n == 0 {
+			continue
+		}
+		var body string
+		if literal {
+			body = re.ReplaceAllLiteralString(content, r.Replacement)
+		} else {
+			body = re.ReplaceAllString(content, r.Replacement)
+		}
+		if body == content {
+			continue
+		}
+		updated := head + body
+		note := ReplacedNote{Path: row.Path, Matches: n}
+		if r.DryRun {
+			res.Changes = append(res.Changes, PlannedChange{Action: ChangeUpdate, Path: row.Path, Diff: merge.Diff(row.Path, string(data), updated)})
+		} else {
+			detail, err := s.UpdateNote(ctx, row.Path, []byte(updated), checksum.Sum(data))
+			if err != nil {
+				return nil, fmt.Errorf("replace in %s: %w", row.Path, err)
+			}
+			note.MutationID = detail.MutationID
+		}
+		res.Notes = append(res.Notes, note)
+		res.Matches += n
+	}
+	if !r.DryRun && len(res.Notes) > 0 {
+		slog.Info("find and replace applied",
+			slog.String("query", r.Query), slog.String("regex", r.Regex),
+			slog.String("folder", r.Folder), slog.String("tag", r.Tag),
+			slog.Int("notes", len(res.Notes)), slog.Int("matches", res.Matches))
+	}
+	return res, nil
+}
@@ -0,0 +1,54 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestReplaceAll(t *testing.T) {
+	svc := testService(t, WithUndoWindow(time.Minute))
+	ctx := context.Background()
+	createNote(t, svc, "projects/a.md", "---\ntitle: Kenaz v1\n---\nKenaz v1 ships. kenaz V1 too.\n")
+	createNote(t, svc, "projects/b.md", "No match here.\n")
+	createNote(t, svc, "other.md", "Kenaz v1 elsewhere.\n")
+
+	preview, err := svc.ReplaceAll(ctx, Replace{Query: "Kenaz v1", Replacement: "Kenaz v2", IgnoreCase: true, Folder: "projects", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Matches != 2 || len(preview.Notes) != 1 || preview.Notes[0].Path != "projects/a.md" || len(preview.Changes) != 1 {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if d := preview.Changes[0].Diff; !strings.Contains(d, "-Kenaz v1 ships. kenaz V1 too.") || !strings.Contains(d, "+Kenaz v2 ships. Kenaz v2 too.") {
+		t.Errorf("diff:\n%s", d)
+	}
+	if note, _ := svc.GetNote(ctx, "projects/a.md"); strings.Contains(note.Content, "v2") {
+		t.Error("dry run wrote the note")
+	}
+
+	res, err := svc.ReplaceAll(ctx, Replace{Regex: `v(\d)`, Replacement: "version $1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Matches != 2 || len(res.Notes) != 2 || res.Notes[0].MutationID == "" {
+		t.Fatalf("result = %+v", res)
+	}
+	note, _ := svc.GetNote(ctx, "projects/a.md")
+	if !strings.Contains(note.Content, "\nKenaz version 1 ships. kenaz V1 too.\n") || !strings.Contains(note.Content, "title: Kenaz v1\n") {
+		t.Errorf("content:\n%s", note.Content)
+	}
+	if _, err := svc.Undo(ctx, res.Notes[1].MutationID); err != nil {
+		t.Errorf("undo: %v", err)
+	}
+
+	for _, bad := range []Replace{{}, {Query: "a", Regex: "b"}, {Regex: "("}} {
+		if _, err := svc.ReplaceAll(ctx, bad); !errors.Is(err, apperr.ErrInvalidPath) {
+			t.Errorf("%+v: err = %v, want ErrInvalidPath", bad, err)
+		}
+	}
+}
@@ -1,31 +1,59 @@
 package noteservice
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
-	"regexp"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
 	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/backup"
 	"github.com/starford/kenaz/internal/checksum"
 	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/merge"
 	"github.com/starford/kenaz/internal/parser"
+	"github.com/starford/kenaz/internal/slug"
 	"github.com/starford/kenaz/internal/storage"
 )
 
 // NoteDetail is the full representation of a note.
 type NoteDetail struct {
-	Path        string         `json:"path" validate:"required"`
+	Path string `json:"path" validate:"required"`
+	// ID is the stable note identifier from the frontmatter "id" field.
+	ID          string         `json:"id,omitempty"`
 	Title       string         `json:"title" validate:"required"`
 	Content     string         `json:"content" validate:"required"`
 	Checksum    string         `json:"checksum" validate:"required"`
 	Tags        []string       `json:"tags" validate:"required"`
 	Frontmatter map[string]any `json:"frontmatter,omitempty"`
 	Backlinks   []string       `json:"backlinks" validate:"required"`
-	UpdatedAt   time.Time      `json:"updated_at" validate:"required"`
+	// BacklinkRefs lists incoming links with their type (inline or frontmatter).
+	BacklinkRefs []index.BacklinkRef `json:"backlink_refs" validate:"required"`
+	// Metadata holds the values found by the configured extractors
+	// (vault.extractors), keyed by extractor name.
+	Metadata map[string][]string `json:"metadata,omitempty"`
+	// Footnotes lists the note's footnotes in rendered order.
+	Footnotes []parser.Footnote `json:"footnotes,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at" validate:"required"`
+	// CreatedAt is the frontmatter creation date, else the file's birth
+	// time or first index time (see index.NoteRow).
+	CreatedAt time.Time `json:"created_at" validate:"required"`
+	// LastCommit is the latest commit of the note when the vault is
+	// versioned with git (vault.git).
+	LastCommit *storage.Commit `json:"last_commit,omitempty"`
+	// MutationID identifies the write that produced this note, for Undo.
+	// Empty on reads and when undo is disabled.
+	MutationID string `json:"mutation_id,omitempty"`
 }
 
 // NoteListItem is a lightweight item in a list response.
@@ -35,17 +63,109 @@ type NoteListItem struct {
 	Checksum  string    `json:"checksum" validate:"required"`
 	Tags      []string  `json:"tags" validate:"required"`
 	UpdatedAt time.Time `json:"updated_at" validate:"required"`
+	CreatedAt time.Time `json:"created_at" validate:"required"`
+}
+
+// MergePreview is the result of merging an edit made against an older
+// version of a note into the note's current content.
+type MergePreview struct {
+	// Merged is the merged content; conflicts are wrapped in git-style
+	// markers.
+	Merged    string           `json:"merged" validate:"required"`
+	Clean     bool             `json:"clean" validate:"required"`
+	Conflicts []merge.Conflict `json:"conflicts" validate:"required"`
+	// Checksum is that of the current stored content; send it as If-Match
+	// when saving the resolved result.
+	Checksum string `json:"checksum" validate:"required"`
 }
 
 // Service coordinates storage and index operations.
 type Service struct {
-	store storage.Provider
-	db    *index.DB
+	store          storage.Provider
+	db             *index.DB
+	requireIfMatch bool
+	readOnly       bool
+	undo           undoLog
+	onMutation     func(Mutation)
+	inboxPath      string
+	daily          DailyNotes
+	// bookmarksFolder holds bookmark notes.
+	bookmarksFolder string
+	// appendMu serializes appends so each read-modify-write sees the
+	// previous one's result.
+	appendMu sync.Mutex
+	// layoutMu guards recomputing the cached graph layout.
+	layoutMu sync.Mutex
+	// metrics caches the graph metrics for the graph with signature
+	// metricsSig, guarded by metricsMu.
+	metricsMu  sync.Mutex
+	metricsSig string
+	metrics    map[string]index.NodeMetrics
+	// suggest holds the note titles prepared for SuggestNotes.
+	suggest suggestCache
+	// clusters holds the cluster labels of the graph last clustered.
+	clusters clusterCache
+	// fetchPage, when set, downloads link previews, cached for previewTTL.
+	fetchPage  PageFetcher
+	previewTTL time.Duration
+	// checkURL checks external links for the dead-link report.
+	checkURL URLChecker
+	// revisions is how many earlier versions of a note are kept.
+	revisions int
+	// onBatch is called with the paths changed by a Batch.
+	onBatch func(paths []string)
+	// reindex tracks the background reindex.
+	reindex reindexState
+	// backups says where Backup writes archives and which it keeps.
+	backups backup.Policy
+	// pipeline vets and transforms attachments written by ImportVault.
+	pipeline *asset.Pipeline
+	// confirms holds the tokens of pending DeleteFolder previews.
+	confirms confirmTokens
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithReadOnly, when on, makes the vault read-only: every change to it,
+// from any method, fails with apperr.ErrReadOnly, while reads, search, and
+// the graph keep working. The index is still kept in sync with the vault.
+func WithReadOnly(on bool) Option {
+	return func(s *Service) {
+		s.readOnly = on
+		if on {
+			s.store = storage.ReadOnly{Provider: s.store}
+		}
+	}
+}
+
+// ReadOnly reports whether the vault is read-only (see WithReadOnly).
+func (s *Service) ReadOnly() bool {
+	return s.readOnly
+}
+
+// WithRequireIfMatch makes UpdateNote reject writes that carry no checksum
+// with apperr.ErrPreconditionRequired, so no client can silently overwrite
+// another's changes.
+func WithRequireIfMatch(require bool) Option {
+	return func(s *Service) {
+		s.requireIfMatch = require
+	}
 }
 
 // NewService creates a new note service.
-func NewService(store storage.Provider, db *index.DB) *Service {
-	return &Service{store: store, db: db}
+func NewService(store storage.Provider, db *index.DB, opts ...Option) *Service {
+	s := &Service{
+		store:           store,
+		db:              db,
+		inboxPath:       DefaultInboxPath,
+		daily:           DailyNotes{Folder: DefaultDailyFolder, Format: DefaultDailyFormat},
+		bookmarksFolder: DefaultBookmarksFolder,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
 }
 
 // GetNote reads a note from storage, parses it, and enriches with backlinks.
@@ -60,22 +180,58 @@ func (s *Service) GetNote(_ context.Context, path string) (*NoteDetail, error) {
 	return s.buildNoteDetail(path, data)
 }
 
-// CreateNote writes a new note and indexes it.
+// GetNoteByID looks a note up by its stable frontmatter ID.
+func (s *Service) GetNoteByID(ctx context.Context, id string) (*NoteDetail, error) {
+	p, err := s.db.PathByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if p == "" {
+		return nil, apperr.ErrNotFound
+	}
+	return s.GetNote(ctx, p)
+}
+
+// CreateNote writes a new note and indexes it. Notes without an "id"
+// frontmatter field are assigned a fresh UUID so links by ID survive renames.
 func (s *Service) CreateNote(_ context.Context, path string, content []byte) (*NoteDetail, error) {
 	if _, err := s.store.Read(path); err == nil {
 		return nil, apperr.ErrAlreadyExists
 	}
+	return s.create(path, content)
+}
+
+// create writes and indexes a note known not to exist, assigning an id if
+// it has none.
+func (s *Service) create(path string, content []byte) (*NoteDetail, error) {
+	res, err := s.db.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if res.ID == "" {
+		content = parser.SetFrontmatterField(content, "id", uuid.New().String())
+	}
 	if err := s.store.Write(path, content); err != nil {
 		return nil, err
 	}
 	if err := s.IndexFile(path, content); err != nil {
 		return nil, err
 	}
-	return s.buildNoteDetail(path, content)
+	m := s.record(MutationCreated, path, nil, content)
+	note, err := s.buildNoteDetail(path, content)
+	if err != nil {
+		return nil, err
+	}
+	note.MutationID = m.ID
+	return note, nil
 }
 
-// UpdateNote writes updated content with optimistic concurrency.
+// UpdateNote writes updated content with optimistic concurrency. The
+// content it replaces is kept as a revision when history is enabled.
 func (s *Service) UpdateNote(_ context.Context, path string, content []byte, ifMatch string) (*NoteDetail, error) {
+	if ifMatch == "" && s.requireIfMatch {
+		return nil, apperr.ErrPreconditionRequired
+	}
 	existing, err := s.store.Read(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -86,26 +242,126 @@ func (s *Service) UpdateNote(_ context.Context, path string, content []byte, ifM
 	if ifMatch != "" && ifMatch != checksum.Sum(existing) {
 		return nil, apperr.ErrConflict
 	}
+	if err := s.saveRevision(path, existing, content); err != nil {
+		return nil, err
+	}
 	if err := s.store.Write(path, content); err != nil {
 		return nil, err
 	}
 	if err := s.IndexFile(path, content); err != nil {
 		return nil, err
 	}
-	return s.buildNoteDetail(path, content)
+	m := s.record(MutationUpdated, path, existing, content)
+	note, err := s.buildNoteDetail(path, content)
+	if err != nil {
+		return nil, err
+	}
+	note.MutationID = m.ID
+	return note, nil
 }
 
-// DeleteNote removes a note from storage and index.
-func (s *Service) DeleteNote(_ context.Context, path string) error {
-	if err := s.store.Delete(path); err != nil {
-		return err
+// dateFields are the frontmatter timestamps reset on a copy.
+var dateFields = []string{"created_at", "updated_at"}
+
+// CopyOptions adjusts the frontmatter of a copied note.
+type CopyOptions struct {
+	// ResetDates sets created_at/updated_at fields present in the
+	// frontmatter to the current time.
+	ResetDates bool
+	// StripFields are frontmatter fields removed from the copy, e.g.
+	// "created" for a note cloned from last week's. "id" is ignored: the
+	// copy always gets a fresh one.
+	StripFields []string
+}
+
+// CopyNote duplicates the note at src to dst and indexes the copy. The copy
+// always gets a fresh id so links by ID keep pointing at the original; opts
+// controls what else of the frontmatter changes.
+func (s *Service) CopyNote(_ context.Context, src, dst string, opts CopyOptions) (*NoteDetail, error) {
+	data, err := s.store.Read(src)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, apperr.ErrNotFound
+		}
+		return nil, err
+	}
+	if _, err := s.store.Read(dst); err == nil {
+		return nil, apperr.ErrAlreadyExists
+	}
+	if len(opts.StripFields) > 0 {
+		data = parser.RemoveFrontmatterFields(data, opts.StripFields...)
 	}
-	return s.db.DeleteNote(path)
+	data = parser.SetFrontmatterField(data, "id", uuid.New().String())
+	if opts.ResetDates {
+		res, err := s.db.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		now := `"` + time.Now().UTC().Format(time.RFC3339) + `"`
+		for _, f := range dateFields {
+			if _, ok := res.Frontmatter[f]; ok {
+				data = parser.SetFrontmatterField(data, f, now)
+			}
+		}
+	}
+	if err := s.store.Write(dst, data); err != nil {
+		return nil, err
+	}
+	if err := s.IndexFile(dst, data); err != nil {
+		return nil, err
+	}
+	m := s.record(MutationCreated, dst, nil, data)
+	note, err := s.buildNoteDetail(dst, data)
+	if err != nil {
+		return nil, err
+	}
+	note.MutationID = m.ID
+	return note, nil
+}
+
+// PreviewMerge three-way merges content (edited from base) with the note's
+// current content without writing anything.
+func (s *Service) PreviewMerge(_ context.Context, path string, base, content []byte) (*MergePreview, error) {
+	current, err := s.store.Read(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, apperr.ErrNotFound
+		}
+		return nil, err
+	}
+	res := merge.Merge(string(base), string(current), string(content))
+	return &MergePreview{
+		Merged:    res.Merged,
+		Clean:     res.Clean(),
+		Conflicts: nonNilSlice(res.Conflicts),
+		Checksum:  checksum.Sum(current),
+	}, nil
+}
+
+// DeleteNote moves a note to the trash (storage.TrashDir) and drops it from
+// the index; RestoreTrash brings it back. It returns the ID of the mutation
+// for Undo ("" when undo is disabled).
+func (s *Service) DeleteNote(_ context.Context, path string) (string, error) {
+	if storage.InReserved(path) {
+		return "", apperr.ErrNotFound
+	}
+	prev, err := s.store.Read(path)
+	if err != nil {
+		return "", err
+	}
+	if err := s.trashFiles([]string{path}); err != nil {
+		return "", err
+	}
+	return s.record(MutationDeleted, path, prev, nil).ID, nil
 }
 
-// DeleteDir removes a directory and all notes within it from storage and index.
+// DeleteDir moves a directory and everything in it to the trash, drops its
+// notes from the index, and returns their paths.
 func (s *Service) DeleteDir(_ context.Context, prefix string) ([]string, error) {
 	dirPath := strings.TrimSuffix(prefix, "/")
+	if storage.InReserved(dirPath) {
+		return nil, apperr.ErrNotFound
+	}
 
 	// Check if the directory exists on disk.
 	exists, err := s.store.DirExists(dirPath)
@@ -127,11 +383,16 @@ func (s *Service) DeleteDir(_ context.Context, prefix string) ([]string, error)
 		for i, n := range notes {
 			paths[i] = n.Path
 		}
-		if err := s.db.DeleteNotesBatch(paths); err != nil {
-			return nil, err
-		}
 	}
 
+	files, err := s.store.ListFiles(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.trashFiles(files); err != nil {
+		return nil, err
+	}
+	// Only empty directories are left.
 	if err := s.store.DeleteDir(dirPath); err != nil {
 		return nil, err
 	}
@@ -139,14 +400,92 @@ func (s *Service) DeleteDir(_ context.Context, prefix string) ([]string, error)
 	return paths, nil
 }
 
+// FolderDelete lists the files DeleteFolder removed or, on a dry run, would
+// remove.
+type FolderDelete struct {
+	Notes       []string `json:"notes" validate:"required"`
+	Attachments []string `json:"attachments" validate:"required"`
+	// ConfirmToken must be passed back to perform the deletion. It is random,
+	// single-use, and valid for a few minutes, and only for the listing
+	// previewed.
+	ConfirmToken string `json:"confirm_token" validate:"required"`
+	DryRun       bool   `json:"dry_run"`
+}
+
+// DeleteFolder removes every file under folder by moving it to the trash
+// (storage.TrashDir) and drops the notes from the index. A dry run only
+// reports what would be removed. Otherwise confirm must be the ConfirmToken
+// of a preview of the same folder: an empty token yields
+// apperr.ErrPreconditionRequired, and an unknown, used, or expired one, or
+// one whose folder listing has changed since, apperr.ErrConflict.
+func (s *Service) DeleteFolder(_ context.Context, folder string, dryRun bool, confirm string) (*FolderDelete, error) {
+	folder, err := cleanFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	if storage.InReserved(folder) {
+		return nil, apperr.ErrInvalidPath
+	}
+	exists, err := s.store.DirExists(folder)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apperr.ErrNotFound
+	}
+	files, err := s.store.ListFiles(folder)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	res := &FolderDelete{Notes: []string{}, Attachments: []string{}, DryRun: dryRun}
+	for _, f := range files {
+		if strings.HasSuffix(f, ".md") {
+			res.Notes = append(res.Notes, f)
+		} else {
+			res.Attachments = append(res.Attachments, f)
+		}
+	}
+	listing := checksum.Sum([]byte(strings.Join(files, "\n")))
+	if dryRun {
+		res.ConfirmToken = s.confirms.issue(folder, listing)
+		return res, nil
+	}
+	if confirm == "" {
+		return nil, apperr.ErrPreconditionRequired
+	}
+	if !s.confirms.redeem(confirm, folder, listing) {
+		return nil, apperr.ErrConflict
+	}
+	res.ConfirmToken = confirm
+
+	if err := s.trashFiles(files); err != nil {
+		return nil, err
+	}
+	// Only empty directories are left.
+	if err := s.store.DeleteDir(folder); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 // ListDirs returns all directory paths from the vault filesystem.
 func (s *Service) ListDirs() ([]string, error) {
 	return s.store.ListDirs()
 }
 
-// ListNotes returns paginated notes with optional tag filter.
-func (s *Service) ListNotes(_ context.Context, limit, offset int, tag, sort string) ([]NoteListItem, int, error) {
-	rows, total, err := s.db.ListNotes(limit, offset, tag, sort)
+// Property filters ListNotes by a frontmatter key and value.
+type Property = index.Property
+
+// NoteFilter restricts ListNotes by frontmatter properties and dates.
+type NoteFilter = index.NoteFilter
+
+// ListNotes returns paginated notes with an optional tag filter, restricted
+// by f. A note must match every property key; filters repeating a key match
+// any of their values, and an empty value matches any note that sets the
+// key.
+func (s *Service) ListNotes(_ context.Context, limit, offset int, tag, sort string, f NoteFilter) ([]NoteListItem, int, error) {
+	rows, total, err := s.db.ListNotes(limit, offset, tag, sort, f)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -158,6 +497,7 @@ func (s *Service) ListNotes(_ context.Context, limit, offset int, tag, sort stri
 			Checksum:  r.Checksum,
 			Tags:      nonNilSlice(r.Tags),
 			UpdatedAt: r.UpdatedAt,
+			CreatedAt: r.CreatedAt,
 		}
 	}
 	return items, total, nil
@@ -183,19 +523,38 @@ func (s *Service) ListNotesCursor(_ context.Context, limit int, cursor, tag, fol
 			Checksum:  r.Checksum,
 			Tags:      nonNilSlice(r.Tags),
 			UpdatedAt: r.UpdatedAt,
+			CreatedAt: r.CreatedAt,
 		}
 	}
 	return CursorPage{Notes: items, NextCursor: page.NextCursor}, nil
 }
 
-// Search delegates full-text search to the index.
-func (s *Service) Search(_ context.Context, query string, limit int) ([]index.SearchResult, error) {
-	return s.db.Search(query, limit)
+// SearchOptions limits, pages, orders, and scopes Search.
+type SearchOptions = index.SearchOptions
+
+// Search delegates full-text search, optionally scoped to a folder or tag,
+// to the index. It returns one page of results and the total number of
+// matches.
+func (s *Service) Search(_ context.Context, query string, opts SearchOptions) ([]index.SearchResult, int, error) {
+	return s.db.Search(query, opts)
 }
 
-// Graph returns all nodes and links for graph visualization.
+// Graph returns all nodes and links for graph visualization, with node
+// positions from the cached force-directed layout and node metrics (see
+// GraphMetrics).
 func (s *Service) Graph(_ context.Context) ([]index.GraphNode, []index.GraphLink, error) {
-	return s.db.Graph()
+	nodes, links, err := s.db.Graph()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.layoutGraph(nodes, links); err != nil {
+		return nil, nil, err
+	}
+	metrics := s.graphMetrics(nodes, links)
+	for i := range nodes {
+		nodes[i].NodeMetrics = metrics[nodes[i].ID]
+	}
+	return nodes, links, nil
 }
 
 // Backlinks returns all note paths that link to the given target.
@@ -203,45 +562,184 @@ func (s *Service) Backlinks(_ context.Context, target string) ([]string, error)
 	return s.db.Backlinks(target)
 }
 
+// BacklinkRefs returns all links to the given target together with their type.
+func (s *Service) BacklinkRefs(_ context.Context, target string) ([]index.BacklinkRef, error) {
+	return s.db.BacklinkRefs(target)
+}
+
+// ResolveLink maps a wikilink target (path, basename, title, alias, or
+// "@citekey") to the path of an indexed note.
+func (s *Service) ResolveLink(_ context.Context, target string) (string, error) {
+	p, err := s.db.ResolveLink(target)
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return "", apperr.ErrNotFound
+	}
+	return p, nil
+}
+
 // IndexFile parses data and upserts it into the index.
 // Exported so that sync and watcher can reuse it.
 func (s *Service) IndexFile(path string, data []byte) error {
-	res, err := parser.Parse(data)
+	n, err := s.indexedNote(path, data)
 	if err != nil {
 		return err
 	}
+	return s.db.UpsertNoteLinks(n.Row, n.Body, n.Links)
+}
+
+// indexedNote parses data, the content of the note at path, into its index
+// row, body, and links.
+func (s *Service) indexedNote(path string, data []byte) (index.IndexedNote, error) {
+	res, err := s.db.Parse(data)
+	if err != nil {
+		return index.IndexedNote{}, err
+	}
 	cs := checksum.Sum(data)
-	return s.db.UpsertNote(index.NoteRow{
-		Path:      path,
-		Title:     res.Title,
-		Checksum:  cs,
-		Tags:      nonNilSlice(res.Tags),
-		UpdatedAt: time.Now(),
-	}, res.Body, res.Links)
+	return index.IndexedNote{
+		Row: index.NoteRow{
+			Path:       path,
+			ID:         res.ID,
+			Title:      res.Title,
+			Checksum:   cs,
+			Tags:       nonNilSlice(res.Tags),
+			Aliases:    res.Aliases,
+			UpdatedAt:  time.Now(),
+			CreatedAt:  res.Created,
+			Metadata:   res.Metadata,
+			Properties: index.NoteProperties(res.Frontmatter),
+			Tasks:      res.Tasks,
+			Reminders:  res.Reminders,
+			Reference:  res.Reference,
+			Cards:      res.Cards,
+			URLs:       parser.URLs(res),
+			Sections:   res.Sections,
+		},
+		Body:  res.Body,
+		Links: index.NoteLinks(res),
+	}, nil
 }
 
 // buildNoteDetail constructs a NoteDetail from raw data without re-reading the file.
 func (s *Service) buildNoteDetail(path string, data []byte) (*NoteDetail, error) {
-	res, err := parser.Parse(data)
+	res, err := s.db.Parse(data)
 	if err != nil {
 		return nil, err
 	}
-	bl, err := s.db.Backlinks(path)
+	refs, err := s.db.BacklinkRefs(path)
 	if err != nil {
 		return nil, err
 	}
+	var bl []string
+	seen := make(map[string]struct{}, len(refs))
+	for _, r := range refs {
+		if _, dup := seen[r.Source]; !dup {
+			seen[r.Source] = struct{}{}
+			bl = append(bl, r.Source)
+		}
+	}
+	row, err := s.db.GetNote(path)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	created := res.Created
+	if row != nil {
+		created = row.CreatedAt
+	}
+	if created.IsZero() {
+		created = now
+	}
+	var last *storage.Commit
+	if v, ok := s.store.(storage.Versioned); ok {
+		if last, err = v.LastCommit(path); err != nil {
+			return nil, err
+		}
+	}
 	return &NoteDetail{
-		Path:        path,
-		Title:       res.Title,
-		Content:     string(data),
-		Checksum:    checksum.Sum(data),
-		Tags:        nonNilSlice(res.Tags),
-		Frontmatter: res.Frontmatter,
-		Backlinks:   nonNilSlice(bl),
-		UpdatedAt:   time.Now(),
+		Path:         path,
+		ID:           res.ID,
+		Title:        res.Title,
+		Content:      string(data),
+		Checksum:     checksum.Sum(data),
+		Tags:         nonNilSlice(res.Tags),
+		Frontmatter:  res.Frontmatter,
+		Backlinks:    nonNilSlice(bl),
+		BacklinkRefs: nonNilSlice(refs),
+		Metadata:     res.Metadata,
+		Footnotes:    res.Footnotes,
+		UpdatedAt:    now,
+		CreatedAt:    created,
+		LastCommit:   last,
 	}, nil
 }
 
+// maxSlugSuffix bounds the collision search in SuggestPath.
+const maxSlugSuffix = 1000
+
+// SuggestPath derives a contract-compliant note path from a title: an English
+// kebab-case file name (Cyrillic is transliterated) under folder. If the path
+// is taken, a numeric suffix is appended ("-2", "-3", ...).
+func (s *Service) SuggestPath(_ context.Context, title, folder string) (string, error) {
+	dir := strings.Trim(folder, "/")
+	if dir != "" {
+		dir = path.Clean(dir)
+		if dir == ".." || strings.HasPrefix(dir, "../") || path.IsAbs(dir) {
+			return "", apperr.ErrInvalidPath
+		}
+	}
+	base := slug.Make(title)
+	for i := 1; i <= maxSlugSuffix; i++ {
+		name := base + ".md"
+		if i > 1 {
+			name = fmt.Sprintf("%s-%d.md", base, i)
+		}
+		candidate := path.Join(dir, name)
+		if _, err := s.store.Read(candidate); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return candidate, nil
+			}
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: no free name for %q", apperr.ErrAlreadyExists, base)
+}
+
+// CreateNoteTitled creates a note at the path SuggestPath derives from title
+// under folder, for callers that only know the title. An empty title is
+// taken from content (its frontmatter title or first H1); content without a
+// frontmatter title gets title added, and empty content becomes an H1.
+func (s *Service) CreateNoteTitled(ctx context.Context, title, folder string, content []byte) (*NoteDetail, error) {
+	title = strings.TrimSpace(title)
+	res, err := s.db.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = res.Title
+	}
+	if title == "" {
+		return nil, fmt.Errorf("%w: a title is required to derive the path", apperr.ErrInvalidPath)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		content = []byte("# " + title + "\n")
+	}
+	if _, ok := res.Frontmatter["title"]; !ok {
+		v, err := yaml.Marshal(title)
+		if err != nil {
+			return nil, err
+		}
+		content = parser.SetFrontmatterField(content, "title", strings.TrimSuffix(string(v), "\n"))
+	}
+	p, err := s.SuggestPath(ctx, title, folder)
+	if err != nil {
+		return nil, err
+	}
+	return s.create(p, content)
+}
+
 // RenameNote moves a single note to a new path and updates wikilinks in referencing notes.
 func (s *Service) RenameNote(_ context.Context, oldPath, newPath string) (*NoteDetail, error) {
 	// Verify old note exists.
@@ -271,6 +769,9 @@ func (s *Service) RenameNote(_ context.Context, oldPath, newPath string) (*NoteD
 	if err := s.db.MoveNote(oldPath, newPath); err != nil {
 		return nil, err
 	}
+	if err := s.moveHistory(oldPath, newPath); err != nil {
+		return nil, err
+	}
 
 	// Rewrite wikilinks in all backlinking notes.
 	s.rewriteBacklinks(backlinkSources, oldPath, oldNoExt, newPath, newNoExt)
@@ -278,15 +779,105 @@ func (s *Service) RenameNote(_ context.Context, oldPath, newPath string) (*NoteD
 	return s.buildNoteDetail(newPath, data)
 }
 
+// Planned change actions.
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeMove   = "move"
+	ChangeDelete = "delete"
+)
+
+// PlannedChange describes one file a bulk mutation changes, as reported by
+// its dry run. Diff is a unified diff of the content (empty for pure moves
+// and deletes).
+type PlannedChange struct {
+	Action  string `json:"action" enums:"create,update,move,delete" validate:"required"`
+	Path    string `json:"path" validate:"required"`
+	NewPath string `json:"new_path,omitempty"`
+	Diff    string `json:"diff,omitempty"`
+}
+
+// FolderMove reports the notes relocated by MoveFolder and the notes whose
+// wikilinks were rewritten (by their paths after the move). A dry run fills
+// in Changes, with a diff for every rewritten note, and writes nothing.
+type FolderMove struct {
+	Moved     []index.PathMove `json:"moved" validate:"required"`
+	Rewritten []string         `json:"rewritten" validate:"required"`
+	DryRun    bool             `json:"dry_run"`
+	Changes   []PlannedChange  `json:"changes,omitempty"`
+}
+
+// MoveFolder relocates every note under from to the same relative path under
+// to, updates the index, and rewrites wikilinks that targeted the moved notes
+// by their folder-qualified paths, including links between moved notes.
+func (s *Service) MoveFolder(_ context.Context, from, to string, dryRun bool) (*FolderMove, error) {
+	from, err := cleanFolder(from)
+	if err != nil {
+		return nil, err
+	}
+	to, err = cleanFolder(to)
+	if err != nil {
+		return nil, err
+	}
+	if from == to || strings.HasPrefix(to+"/", from+"/") {
+		return nil, fmt.Errorf("%w: cannot move a folder into itself", apperr.ErrInvalidPath)
+	}
+	moves, updates, err := s.moveDir(from+"/", to+"/", dryRun)
+	if err != nil {
+		return nil, err
+	}
+	res := &FolderMove{Moved: moves, Rewritten: []string{}, DryRun: dryRun}
+	for _, u := range updates {
+		res.Rewritten = append(res.Rewritten, u.Path)
+	}
+	if dryRun {
+		for _, m := range moves {
+			res.Changes = append(res.Changes, PlannedChange{Action: ChangeMove, Path: m.OldPath, NewPath: m.NewPath})
+		}
+		res.Changes = append(res.Changes, updates...)
+	}
+	return res, nil
+}
+
+// cleanFolder normalizes a vault-relative folder path without slashes at
+// either end, rejecting the vault root and traversal.
+func cleanFolder(p string) (string, error) {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return "", apperr.ErrInvalidPath
+	}
+	p = path.Clean(p)
+	if p == ".." || strings.HasPrefix(p, "../") || p == "." {
+		return "", apperr.ErrInvalidPath
+	}
+	return p, nil
+}
+
 // RenameDir renames a directory and all notes within it, updating wikilinks.
 func (s *Service) RenameDir(_ context.Context, oldPrefix, newPrefix string) ([]string, error) {
+	moves, _, err := s.moveDir(oldPrefix, newPrefix, false)
+	if err != nil {
+		return nil, err
+	}
+	newPaths := make([]string, len(moves))
+	for i, m := range moves {
+		newPaths[i] = m.NewPath
+	}
+	return newPaths, nil
+}
+
+// moveDir moves the directory oldPrefix (with trailing slash) to newPrefix
+// and rewrites wikilinks to the moved notes. It returns the moves and an
+// update (path after the move, diff) per note whose links were rewritten.
+// With dryRun nothing is written.
+func (s *Service) moveDir(oldPrefix, newPrefix string, dryRun bool) ([]index.PathMove, []PlannedChange, error) {
 	// Find all notes under old prefix.
 	notes, err := s.db.NotesWithPrefix(oldPrefix)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if len(notes) == 0 {
-		return nil, apperr.ErrNotFound
+		return nil, nil, apperr.ErrNotFound
 	}
 
 	// Build move list and collect all backlinks.
@@ -308,45 +899,91 @@ func (s *Service) RenameDir(_ context.Context, oldPrefix, newPrefix string) ([]s
 	// Verify no conflicts at new paths.
 	for _, m := range moves {
 		if _, err := s.store.Read(m.NewPath); err == nil {
-			return nil, fmt.Errorf("%w: %s", apperr.ErrAlreadyExists, m.NewPath)
+			return nil, nil, fmt.Errorf("%w: %s", apperr.ErrAlreadyExists, m.NewPath)
+		}
+	}
+
+	newPathOf := make(map[string]string, len(moves))
+	for _, m := range moves {
+		newPathOf[m.OldPath] = m.NewPath
+	}
+	if dryRun {
+		var updates []PlannedChange
+		for b := range allBacklinks {
+			data, err := s.store.Read(b)
+			if err != nil {
+				continue
+			}
+			dst := b
+			if np, ok := newPathOf[b]; ok {
+				dst = np
+			}
+			if updated := applyMoves(string(data), moves); updated != string(data) {
+				updates = append(updates, PlannedChange{Action: ChangeUpdate, Path: dst, Diff: merge.Diff(dst, string(data), updated)})
+			}
 		}
+		sort.Slice(updates, func(i, j int) bool { return updates[i].Path < updates[j].Path })
+		return moves, updates, nil
 	}
 
 	// Rename directory on filesystem (os.Rename handles dirs).
 	dirOld := strings.TrimSuffix(oldPrefix, "/")
 	dirNew := strings.TrimSuffix(newPrefix, "/")
 	if err := s.store.Move(dirOld, dirNew); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Update index in batch.
 	if err := s.db.MoveNotesBatch(moves); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	// Rewrite wikilinks in all backlinking notes (exclude notes being moved — their paths changed).
-	movedSet := make(map[string]struct{}, len(moves))
-	for _, m := range moves {
-		movedSet[m.NewPath] = struct{}{}
-		movedSet[m.OldPath] = struct{}{}
+	if err := s.moveHistory(dirOld, dirNew); err != nil {
+		return nil, nil, err
 	}
-	for _, m := range moves {
-		oldNoExt := strings.TrimSuffix(m.OldPath, ".md")
-		newNoExt := strings.TrimSuffix(m.NewPath, ".md")
-		var sources []string
-		for b := range allBacklinks {
-			if _, isMoved := movedSet[b]; !isMoved {
-				sources = append(sources, b)
-			}
+
+	// Rewrite wikilinks in all backlinking notes. Sources that were moved
+	// themselves are read at their new path.
+	var updates []PlannedChange
+	for b := range allBacklinks {
+		src := b
+		if np, ok := newPathOf[b]; ok {
+			src = np
+		}
+		if s.rewriteMovedLinks(src, moves) {
+			updates = append(updates, PlannedChange{Action: ChangeUpdate, Path: src})
 		}
-		s.rewriteBacklinks(sources, m.OldPath, oldNoExt, m.NewPath, newNoExt)
 	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Path < updates[j].Path })
+	return moves, updates, nil
+}
 
-	newPaths := make([]string, len(moves))
-	for i, m := range moves {
-		newPaths[i] = m.NewPath
+// rewriteMovedLinks rewrites wikilinks in src that target any of the moved
+// notes and reports whether src changed.
+func (s *Service) rewriteMovedLinks(src string, moves []index.PathMove) bool {
+	data, err := s.store.Read(src)
+	if err != nil {
+		return false
 	}
-	return newPaths, nil
+	updated := applyMoves(string(data), moves)
+	if updated == string(data) {
+		return false
+	}
+	if err := s.store.Write(src, []byte(updated)); err != nil {
+		return false
+	}
+	_ = s.IndexFile(src, []byte(updated))
+	return true
+}
+
+// applyMoves rewrites wikilinks in content that target any of the moved
+// paths, with or without the .md extension, in a single pass.
+func applyMoves(content string, moves []index.PathMove) string {
+	renames := make(map[string]string, 2*len(moves))
+	for _, m := range moves {
+		renames[m.OldPath] = m.NewPath
+		renames[strings.TrimSuffix(m.OldPath, ".md")] = strings.TrimSuffix(m.NewPath, ".md")
+	}
+	return rewriteTargets(content, renames)
 }
 
 // rewriteBacklinks rewrites wikilink references in backlinking notes.
@@ -360,10 +997,7 @@ func (s *Service) rewriteBacklinks(sources []string, oldPath, oldNoExt, newPath,
 		if err != nil {
 			continue
 		}
-		updated := rewriteWikilinks(string(data), oldPath, newPath)
-		if oldNoExt != oldPath {
-			updated = rewriteWikilinks(updated, oldNoExt, newNoExt)
-		}
+		updated := rewriteTargets(string(data), map[string]string{oldPath: newPath, oldNoExt: newNoExt})
 		if updated == string(data) {
 			continue
 		}
@@ -374,20 +1008,32 @@ func (s *Service) rewriteBacklinks(sources []string, oldPath, oldNoExt, newPath,
 	}
 }
 
-// rewriteWikilinks replaces [[oldTarget]] and [[oldTarget|alias]] with new target.
-func rewriteWikilinks(content, oldTarget, newTarget string) string {
-	// Match [[oldTarget]] and [[oldTarget|...]]
-	escaped := regexp.QuoteMeta(oldTarget)
-	re := regexp.MustCompile(`\[\[` + escaped + `(\|[^\]]*?)?\]\]`)
-	return re.ReplaceAllStringFunc(content, func(match string) string {
-		// Extract alias part if present.
-		inner := match[2 : len(match)-2] // strip [[ and ]]
-		if idx := strings.Index(inner, "|"); idx >= 0 {
-			alias := inner[idx:] // includes the |
-			return "[[" + newTarget + alias + "]]"
+// rewriteTargets rewrites [[old]], [[old#heading]] and [[old|alias]] to the
+// new target for every old → new pair in renames. Only the target text
+// located by the parser is patched, so headings, aliases, and the rest of the
+// content are kept byte for byte, and each link is rewritten at most once.
+func rewriteTargets(content string, renames map[string]string) string {
+	var b strings.Builder
+	last := 0
+	for _, w := range parser.Wikilinks([]byte(content)) {
+		old := w.Target
+		repl, ok := renames[old]
+		if !ok {
+			old, _, _ = strings.Cut(w.Target, "#")
+			repl, ok = renames[old]
 		}
-		return "[[" + newTarget + "]]"
-	})
+		if !ok || repl == old {
+			continue
+		}
+		b.WriteString(content[last:w.Start])
+		b.WriteString(repl)
+		last = w.Start + len(old)
+	}
+	if last == 0 {
+		return content
+	}
+	b.WriteString(content[last:])
+	return b.String()
 }
 
 // mergeUnique merges two string slices, deduplicating.
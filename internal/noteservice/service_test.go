@@ -6,13 +6,14 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/starford/kenaz/internal/apperr"
 	"github.com/starford/kenaz/internal/index"
 	"github.com/starford/kenaz/internal/storage"
 )
 
-func testService(t *testing.T) *Service {
+func testService(t *testing.T, opts ...Option) *Service {
 	t.Helper()
 	vaultDir := t.TempDir()
 	store, err := storage.NewFS(vaultDir, nil)
@@ -30,7 +31,7 @@ func testService(t *testing.T) *Service {
 		t.Fatal(err)
 	}
 	t.Cleanup(func() { _ = db.Close() })
-	return NewService(store, db)
+	return NewService(store, db, opts...)
 }
 
 func createNote(t *testing.T, svc *Service, path, content string) {
@@ -195,7 +196,127 @@ func TestRenameDir_Conflict(t *testing.T) {
 	}
 }
 
-func TestRewriteWikilinks(t *testing.T) {
+func TestMoveFolder(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "proj/a.md", "# A\nSee [[proj/b]]")
+	createNote(t, svc, "proj/sub/b.md", "# B sub")
+	createNote(t, svc, "proj/b.md", "# B")
+	createNote(t, svc, "index.md", "[[proj/a|A]] and [[proj/sub/b.md]]")
+	createNote(t, svc, "other.md", "[[elsewhere]]")
+
+	plan, err := svc.MoveFolder(ctx, "proj", "archive/2024", true)
+	if err != nil {
+		t.Fatalf("MoveFolder dry run: %v", err)
+	}
+	if !plan.DryRun || len(plan.Changes) != 5 || strings.Join(plan.Rewritten, ",") != "archive/2024/a.md,index.md" {
+		t.Errorf("plan = %+v", plan)
+	}
+	if c := plan.Changes[4]; c.Path != "index.md" || !strings.Contains(c.Diff, "+[[archive/2024/a|A]]") {
+		t.Errorf("index.md change = %+v", c)
+	}
+	if _, err := svc.GetNote(ctx, "proj/a.md"); err != nil {
+		t.Fatal("dry run moved a note")
+	}
+
+	res, err := svc.MoveFolder(ctx, "/proj/", "archive/2024", false)
+	if err != nil {
+		t.Fatalf("MoveFolder: %v", err)
+	}
+	if len(res.Moved) != 3 {
+		t.Errorf("moved = %+v", res.Moved)
+	}
+	if strings.Join(res.Rewritten, ",") != "archive/2024/a.md,index.md" {
+		t.Errorf("rewritten = %v", res.Rewritten)
+	}
+	a, err := svc.GetNote(ctx, "archive/2024/a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(a.Content, "[[archive/2024/b]]") {
+		t.Errorf("moved note links not rewritten: %s", a.Content)
+	}
+	idx, _ := svc.GetNote(ctx, "index.md")
+	if !strings.Contains(idx.Content, "[[archive/2024/a|A]]") || !strings.Contains(idx.Content, "[[archive/2024/sub/b.md]]") {
+		t.Errorf("index.md = %s", idx.Content)
+	}
+
+	for _, tc := range [][2]string{{"archive", "archive/x"}, {"archive", "archive"}, {"", "x"}, {"../x", "y"}} {
+		if _, err := svc.MoveFolder(ctx, tc[0], tc[1], false); !errors.Is(err, apperr.ErrInvalidPath) {
+			t.Errorf("MoveFolder(%q, %q) err = %v, want ErrInvalidPath", tc[0], tc[1], err)
+		}
+	}
+	if _, err := svc.MoveFolder(ctx, "missing", "x", false); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing folder err = %v", err)
+	}
+}
+
+func TestDeleteFolder(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "old/a.md", "# A")
+	createNote(t, svc, "old/sub/b.md", "# B")
+	if err := svc.store.Write("old/sub/pic.png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+
+	preview, err := svc.DeleteFolder(ctx, "old", true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Notes) != 2 || len(preview.Attachments) != 1 || preview.ConfirmToken == "" {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if _, err := svc.GetNote(ctx, "old/a.md"); err != nil {
+		t.Error("dry run deleted a note")
+	}
+
+	if _, err := svc.DeleteFolder(ctx, "old", false, ""); !errors.Is(err, apperr.ErrPreconditionRequired) {
+		t.Errorf("no token err = %v", err)
+	}
+	createNote(t, svc, "old/c.md", "# C")
+	if _, err := svc.DeleteFolder(ctx, "old", false, preview.ConfirmToken); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("stale token err = %v", err)
+	}
+
+	preview, _ = svc.DeleteFolder(ctx, "old", true, "")
+	if again, _ := svc.DeleteFolder(ctx, "old", true, ""); again.ConfirmToken == preview.ConfirmToken {
+		t.Error("previews share a confirm token")
+	}
+	createNote(t, svc, "other/a.md", "# A")
+	if _, err := svc.DeleteFolder(ctx, "other", false, preview.ConfirmToken); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("token of another folder err = %v", err)
+	}
+	// Tokens are single-use, even when rejected.
+	if _, err := svc.DeleteFolder(ctx, "old", false, preview.ConfirmToken); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("used token err = %v", err)
+	}
+
+	preview, _ = svc.DeleteFolder(ctx, "old", true, "")
+	for tok, p := range svc.confirms.pending {
+		p.expires = time.Now().Add(-time.Second)
+		svc.confirms.pending[tok] = p
+	}
+	if _, err := svc.DeleteFolder(ctx, "old", false, preview.ConfirmToken); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("expired token err = %v", err)
+	}
+
+	preview, _ = svc.DeleteFolder(ctx, "old", true, "")
+	if _, err := svc.DeleteFolder(ctx, "old", false, preview.ConfirmToken); err != nil {
+		t.Fatalf("DeleteFolder: %v", err)
+	}
+	if exists, _ := svc.store.DirExists("old"); exists {
+		t.Error("folder still exists")
+	}
+	if _, err := svc.store.Read(".trash/old/sub/pic.png"); err != nil {
+		t.Errorf("attachment not in trash: %v", err)
+	}
+	if n, _ := svc.db.NotesWithPrefix("old/"); len(n) != 0 {
+		t.Errorf("notes still indexed: %v", n)
+	}
+}
+
+func TestRewriteTargets(t *testing.T) {
 	tests := []struct {
 		name      string
 		content   string
@@ -210,17 +331,29 @@ func TestRewriteWikilinks(t *testing.T) {
 		{"with md ext", "[[old.md]]", "old.md", "new.md", "[[new.md]]"},
 		{"no wikilinks", "plain text", "old", "new", "plain text"},
 		{"path with slashes", "[[dir/old]]", "dir/old", "dir/new", "[[dir/new]]"},
+		{"with heading", "[[old#Plan|see plan]]", "old", "new", "[[new#Plan|see plan]]"},
+		{"prefix only", "[[older]]", "old", "new", "[[older]]"},
+		{"padded", "[[ old | x ]]", "old", "new", "[[ new | x ]]"},
+		{"in frontmatter", "---\nparent: \"[[old]]\"\n---\nbody", "old", "new", "---\nparent: \"[[new]]\"\n---\nbody"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := rewriteWikilinks(tt.content, tt.oldTarget, tt.newTarget)
+			got := rewriteTargets(tt.content, map[string]string{tt.oldTarget: tt.newTarget})
 			if got != tt.want {
-				t.Errorf("rewriteWikilinks(%q, %q, %q) = %q, want %q", tt.content, tt.oldTarget, tt.newTarget, got, tt.want)
+				t.Errorf("rewriteTargets(%q, %q → %q) = %q, want %q", tt.content, tt.oldTarget, tt.newTarget, got, tt.want)
 			}
 		})
 	}
 }
 
+func TestApplyMoves_Swap(t *testing.T) {
+	moves := []index.PathMove{{OldPath: "a.md", NewPath: "b.md"}, {OldPath: "b.md", NewPath: "c.md"}}
+	got := applyMoves("[[a]] and [[b.md#Top]]", moves)
+	if want := "[[b]] and [[c.md#Top]]"; got != want {
+		t.Errorf("applyMoves = %q, want %q", got, want)
+	}
+}
+
 func TestMergeUnique(t *testing.T) {
 	got := mergeUnique([]string{"a", "b"}, []string{"b", "c"})
 	if len(got) != 3 {
@@ -246,3 +379,49 @@ func TestMergeUnique(t *testing.T) {
 		t.Errorf("mergeUnique(nil, nil) = %v, want nil or empty", got)
 	}
 }
+
+func TestUpdateNote_RequireIfMatch(t *testing.T) {
+	svc := testService(t, WithRequireIfMatch(true))
+	ctx := context.Background()
+	createNote(t, svc, "a.md", "# A\n")
+
+	if _, err := svc.UpdateNote(ctx, "a.md", []byte("# B\n"), ""); !errors.Is(err, apperr.ErrPreconditionRequired) {
+		t.Fatalf("unconditional update err = %v, want ErrPreconditionRequired", err)
+	}
+	note, err := svc.GetNote(ctx, "a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.UpdateNote(ctx, "a.md", []byte("# B\n"), note.Checksum); err != nil {
+		t.Errorf("conditional update: %v", err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	rw := testService(t)
+	ctx := context.Background()
+	createNote(t, rw, "a.md", "# A\n[[b]]\n")
+	svc := NewService(rw.store, rw.db, WithReadOnly(true))
+	if !svc.ReadOnly() || rw.ReadOnly() {
+		t.Fatal("ReadOnly does not follow WithReadOnly")
+	}
+
+	if note, err := svc.GetNote(ctx, "a.md"); err != nil || note.Title != "A" {
+		t.Errorf("GetNote = %+v, %v", note, err)
+	}
+	if _, err := svc.CreateNote(ctx, "b.md", []byte("# B\n")); !errors.Is(err, apperr.ErrReadOnly) {
+		t.Errorf("CreateNote err = %v, want ErrReadOnly", err)
+	}
+	if _, err := svc.UpdateNote(ctx, "a.md", []byte("# A2\n"), ""); !errors.Is(err, apperr.ErrReadOnly) {
+		t.Errorf("UpdateNote err = %v, want ErrReadOnly", err)
+	}
+	if _, err := svc.DeleteNote(ctx, "a.md"); !errors.Is(err, apperr.ErrReadOnly) {
+		t.Errorf("DeleteNote err = %v, want ErrReadOnly", err)
+	}
+	if _, err := svc.DailyNote(ctx, time.Now()); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing daily note err = %v, want ErrNotFound", err)
+	}
+	if note, err := svc.GetNote(ctx, "a.md"); err != nil || note.Title != "A" {
+		t.Errorf("a.md after refused writes = %+v, %v", note, err)
+	}
+}
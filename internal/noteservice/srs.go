@@ -0,0 +1,76 @@
+package noteservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/srs"
+)
+
+// Bounds of the number of cards DueCards returns.
+const (
+	defaultDueCards = 50
+	maxDueCards     = 500
+)
+
+// DueCards is the review queue for a day.
+type DueCards struct {
+	// Date is the reference date, YYYY-MM-DD.
+	Date  string       `json:"date" example:"2026-10-16" validate:"required"`
+	Cards []index.Card `json:"cards" validate:"required"`
+	// Total counts all due cards, including those beyond the limit.
+	Total int `json:"total" validate:"required"`
+}
+
+// DueCards returns up to limit flashcards (default 50, at most 500) due on
+// the local date of now: overdue and due reviews first, then new cards.
+func (s *Service) DueCards(_ context.Context, now time.Time, limit int) (*DueCards, error) {
+	if limit <= 0 {
+		limit = defaultDueCards
+	}
+	limit = min(limit, maxDueCards)
+	date := now.Format(time.DateOnly)
+	cards, total, err := s.db.DueCards(date, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &DueCards{Date: date, Cards: cards, Total: total}, nil
+}
+
+// ReviewCard grades the card id of the note at notePath (0 = forgotten
+// through 5 = perfect recall), reschedules it with SM-2 from the local date
+// of now, and returns the updated card. It fails with apperr.ErrNotFound for
+// an unknown card and apperr.ErrInvalidPath for a grade out of range.
+func (s *Service) ReviewCard(_ context.Context, notePath, id string, grade int, now time.Time) (*index.Card, error) {
+	if grade < srs.MinGrade || grade > srs.MaxGrade {
+		return nil, fmt.Errorf("%w: grade must be between %d and %d", apperr.ErrInvalidPath, srs.MinGrade, srs.MaxGrade)
+	}
+	c, err := s.db.GetCard(notePath, id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%w: card %s in %s", apperr.ErrNotFound, id, notePath)
+	}
+
+	st := srs.New()
+	if c.Reviews > 0 {
+		st = srs.State{Ease: c.Ease, Interval: c.Interval, Repetitions: c.Repetitions}
+	}
+	st = st.Review(grade)
+	c.Ease, c.Interval, c.Repetitions = st.Ease, st.Interval, st.Repetitions
+	c.Due = now.AddDate(0, 0, st.Interval).Format(time.DateOnly)
+	c.Reviews++
+	if grade < srs.PassGrade {
+		c.Lapses++
+	}
+	at := now.Truncate(time.Second)
+	c.ReviewedAt = &at
+	if err := s.db.RecordCardReview(c, grade, at); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
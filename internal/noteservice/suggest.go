@@ -0,0 +1,271 @@
+package noteservice
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/starford/kenaz/internal/index"
+)
+
+// Suggestion limits.
+const (
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 50
+)
+
+// Fields a suggestion can match on.
+const (
+	SuggestTitle = "title"
+	SuggestAlias = "alias"
+	SuggestPath  = "path"
+)
+
+// Suggestion is a note matching a quick-switcher query.
+type Suggestion struct {
+	Path  string `json:"path" example:"projects/kenaz-roadmap.md" validate:"required"`
+	Title string `json:"title" example:"Kenaz Roadmap" validate:"required"`
+	// Field is what matched: "title", "alias", or "path".
+	Field string `json:"field" example:"title" validate:"required"`
+	// Alias is the matching alias when Field is "alias", normalized for
+	// link resolution ("annie-smith").
+	Alias string `json:"alias,omitempty"`
+	// Positions are the rune offsets of the matched characters in the
+	// matched field (for "path", the path without ".md"), for highlighting.
+	Positions []int `json:"positions" validate:"required"`
+	Score     int   `json:"score" example:"182" validate:"required"`
+}
+
+// Scoring weights for fuzzyScore.
+const (
+	scoreMatch       = 16
+	scoreConsecutive = 12
+	scoreWordStart   = 10
+	scoreFirstChar   = 8
+	scoreGapStart    = 3
+	scoreGapExtend   = 1
+	scorePrefix      = 40
+	scoreExact       = 100
+)
+
+// fieldPenalty lowers matches on less specific fields, so a title match
+// ranks above an equally good path match.
+var fieldPenalty = map[string]int{SuggestTitle: 0, SuggestAlias: 5, SuggestPath: 15}
+
+// suggestField is a title, alias, or path prepared for matching.
+type suggestField struct {
+	kind, text string
+	// lower is text lower-cased and orig text itself, as runes of equal
+	// length; flat is lower without whitespace.
+	lower, orig []rune
+	flat        string
+}
+
+// suggestEntry is a note prepared for matching.
+type suggestEntry struct {
+	path, title string
+	fields      []suggestField
+}
+
+// suggestCache holds the prepared entries for the note titles last
+// returned by the index, so each keystroke only scores.
+type suggestCache struct {
+	mu      sync.Mutex
+	src     []index.NoteTitle
+	entries []suggestEntry
+}
+
+// suggestEntries returns the prepared entries for notes, rebuilding them
+// when the index handed out a different list.
+func (s *Service) suggestEntries(notes []index.NoteTitle) []suggestEntry {
+	c := &s.suggest
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.src) == len(notes) && (len(notes) == 0 || &c.src[0] == &notes[0]) {
+		return c.entries
+	}
+	entries := make([]suggestEntry, len(notes))
+	for i, n := range notes {
+		e := suggestEntry{path: n.Path, title: n.Title}
+		e.fields = append(e.fields, newSuggestField(SuggestTitle, n.Title))
+		for _, a := range n.Aliases {
+			e.fields = append(e.fields, newSuggestField(SuggestAlias, a))
+		}
+		e.fields = append(e.fields, newSuggestField(SuggestPath, strings.TrimSuffix(n.Path, ".md")))
+		entries[i] = e
+	}
+	c.src, c.entries = notes, entries
+	return entries
+}
+
+// newSuggestField prepares text for matching.
+func newSuggestField(kind, text string) suggestField {
+	lower := []rune(strings.ToLower(text))
+	orig := []rune(text)
+	if len(orig) != len(lower) {
+		// Lower-casing changed the length; look for word starts in lower.
+		orig = lower
+	}
+	return suggestField{kind: kind, text: text, lower: lower, orig: orig, flat: strings.Join(strings.Fields(string(lower)), "")}
+}
+
+// SuggestNotes returns up to limit notes (DefaultSuggestLimit when limit
+// is 0 or less, at most MaxSuggestLimit) whose title, alias, or path contains
+// the characters of q in order, case-insensitively, best first. Matches
+// score higher for consecutive characters, characters at word starts, and
+// prefix or exact matches. Titles are kept in memory, so lookups stay fast
+// enough to run on every keystroke. An empty query yields no results.
+func (s *Service) SuggestNotes(_ context.Context, q string, limit int) ([]Suggestion, error) {
+	if limit <= 0 {
+		limit = DefaultSuggestLimit
+	}
+	limit = min(limit, MaxSuggestLimit)
+	// Spaces in the query match anything, so "kenaz road" finds
+	// "kenaz-roadmap".
+	query := []rune(strings.ToLower(strings.Join(strings.Fields(q), "")))
+	if len(query) == 0 {
+		return []Suggestion{}, nil
+	}
+	notes, err := s.db.NoteTitles()
+	if err != nil {
+		return nil, err
+	}
+	entries := s.suggestEntries(notes)
+
+	// Score every note by its best field, then fill in the highlight
+	// positions for the ones returned.
+	type hit struct {
+		entry *suggestEntry
+		field *suggestField
+		score int
+	}
+	var hits []hit
+	for i := range entries {
+		e := &entries[i]
+		best := hit{score: math.MinInt}
+		for j := range e.fields {
+			f := &e.fields[j]
+			score, ok := fuzzyScore(query, f, nil)
+			if !ok {
+				continue
+			}
+			if score -= fieldPenalty[f.kind]; score > best.score {
+				best = hit{entry: e, field: f, score: score}
+			}
+		}
+		if best.entry != nil {
+			hits = append(hits, best)
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.entry.path) != len(b.entry.path) {
+			return len(a.entry.path) < len(b.entry.path)
+		}
+		return a.entry.path < b.entry.path
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	out := make([]Suggestion, len(hits))
+	for i, h := range hits {
+		pos := make([]int, 0, len(query))
+		fuzzyScore(query, h.field, &pos)
+		out[i] = Suggestion{Path: h.entry.path, Title: h.entry.title, Field: h.field.kind, Positions: pos, Score: h.score}
+		if h.field.kind == SuggestAlias {
+			out[i].Alias = h.field.text
+		}
+	}
+	return out, nil
+}
+
+// fuzzyScore matches query (lower-cased runes) as a subsequence of f and
+// scores the match, appending the matched rune offsets to positions when
+// it is not nil. The match found first is tightened from its end
+// backwards, so "ab" in "a-x-ab" scores the adjacent pair.
+func fuzzyScore(query []rune, f *suggestField, positions *[]int) (int, bool) {
+	t := f.lower
+	if len(query) > len(t) {
+		return 0, false
+	}
+
+	// Forward pass: where the first complete match ends.
+	qi, end := 0, -1
+	for i := 0; i < len(t) && qi < len(query); i++ {
+		if t[i] == query[qi] {
+			if qi++; qi == len(query) {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return 0, false
+	}
+	// Backward pass: the latest start that still matches up to end.
+	start := end
+	for i, qj := end, len(query)-1; i >= 0 && qj >= 0; i-- {
+		if t[i] == query[qj] {
+			start = i
+			qj--
+		}
+	}
+
+	score, prev := 0, -1
+	qi = 0
+	for i := start; i <= end && qi < len(query); i++ {
+		if t[i] != query[qi] {
+			continue
+		}
+		score += scoreMatch
+		switch {
+		case i == 0:
+			score += scoreFirstChar + scoreWordStart
+		case isWordStart(f.orig, i):
+			score += scoreWordStart
+		}
+		if prev >= 0 {
+			if i == prev+1 {
+				score += scoreConsecutive
+			} else {
+				score -= scoreGapStart + scoreGapExtend*(i-prev-2)
+			}
+		}
+		if positions != nil {
+			*positions = append(*positions, i)
+		}
+		prev = i
+		qi++
+	}
+
+	switch q := string(query); {
+	case f.flat == q:
+		score += scoreExact + scorePrefix
+	case strings.HasPrefix(f.flat, q):
+		score += scorePrefix
+	}
+	// Prefer shorter texts among otherwise equal matches.
+	score -= len(t) / 8
+	return score, true
+}
+
+// isWordStart reports whether text[i] begins a word: it follows a
+// separator or is an upper-case letter after a lower-case one.
+func isWordStart(text []rune, i int) bool {
+	p, c := text[i-1], text[i]
+	switch {
+	case p == '/' || p == '-' || p == '_' || p == '.' || unicode.IsSpace(p):
+		return true
+	case unicode.IsLower(p) && unicode.IsUpper(c):
+		return true
+	case !unicode.IsLetter(p) && !unicode.IsDigit(p) && (unicode.IsLetter(c) || unicode.IsDigit(c)):
+		return true
+	}
+	return false
+}
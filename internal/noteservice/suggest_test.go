@@ -0,0 +1,79 @@
+package noteservice
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSuggestNotes(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "projects/kenaz-roadmap.md", "# Kenaz Roadmap\n")
+	createNote(t, svc, "archive/old-kenaz-notes.md", "# Old notes about kenaz\n")
+	createNote(t, svc, "people/ann.md", "---\naliases: [Annie Smith]\n---\n# Ann\n")
+	createNote(t, svc, "ideas.md", "# Ideas\n")
+
+	paths := func(res []Suggestion) []string {
+		var out []string
+		for _, r := range res {
+			out = append(out, r.Path)
+		}
+		return out
+	}
+
+	res, err := svc.SuggestNotes(ctx, "knzrd", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(res); !slices.Equal(got, []string{"projects/kenaz-roadmap.md"}) {
+		t.Errorf("knzrd = %v", got)
+	}
+	if r := res[0]; r.Field != SuggestTitle || !slices.Equal(r.Positions, []int{0, 2, 4, 6, 9}) {
+		t.Errorf("knzrd match = %+v", r)
+	}
+
+	// A prefix match on the title beats a match further into a title.
+	res, _ = svc.SuggestNotes(ctx, "kenaz", 0)
+	if got := paths(res); len(got) != 2 || got[0] != "projects/kenaz-roadmap.md" {
+		t.Errorf("kenaz = %v", got)
+	}
+
+	res, _ = svc.SuggestNotes(ctx, "annie sm", 0)
+	if len(res) != 1 || res[0].Field != SuggestAlias || res[0].Alias != "annie-smith" {
+		t.Errorf("alias match = %+v", res)
+	}
+
+	res, _ = svc.SuggestNotes(ctx, "arch/old", 0)
+	if len(res) != 1 || res[0].Field != SuggestPath {
+		t.Errorf("path match = %+v", res)
+	}
+
+	if res, _ := svc.SuggestNotes(ctx, "e", 2); len(res) != 2 {
+		t.Errorf("limit 2 returned %d", len(res))
+	}
+	if res, _ := svc.SuggestNotes(ctx, "  ", 0); res == nil || len(res) != 0 {
+		t.Errorf("blank query = %v", res)
+	}
+	if res, _ := svc.SuggestNotes(ctx, "zzz", 0); len(res) != 0 {
+		t.Errorf("no match = %v", res)
+	}
+
+	// The in-memory titles follow writes.
+	createNote(t, svc, "zebra.md", "# Zebra\n")
+	if _, err := svc.RenameNote(ctx, "ideas.md", "brainstorm.md"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := svc.SuggestNotes(ctx, "zebra", 0); len(res) != 1 {
+		t.Errorf("new note not suggested: %v", res)
+	}
+	if res, _ := svc.SuggestNotes(ctx, "brainst", 0); len(res) != 1 || res[0].Title != "Ideas" {
+		t.Errorf("renamed note = %+v", res)
+	}
+	if _, err := svc.DeleteNote(ctx, "zebra.md"); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := svc.SuggestNotes(ctx, "zebra", 0); len(res) != 0 {
+		t.Errorf("deleted note still suggested: %v", res)
+	}
+}
@@ -0,0 +1,50 @@
+package noteservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/starford/kenaz/internal/index"
+)
+
+// upcomingDays is how far past today UpcomingTasks looks.
+const upcomingDays = 7
+
+// UpcomingTasks groups the vault's open tasks with a due date into an
+// agenda.
+type UpcomingTasks struct {
+	// Date is the reference date, YYYY-MM-DD.
+	Date    string          `json:"date" example:"2026-10-16" validate:"required"`
+	Overdue []index.TaskRow `json:"overdue" validate:"required"`
+	Today   []index.TaskRow `json:"today" validate:"required"`
+	// ThisWeek holds tasks due in the seven days after today.
+	ThisWeek []index.TaskRow `json:"this_week" validate:"required"`
+}
+
+// UpcomingTasks returns the open tasks that are overdue, due today, or due
+// within the week after today, relative to the local date of now.
+func (s *Service) UpcomingTasks(_ context.Context, now time.Time) (*UpcomingTasks, error) {
+	today := now.Format(time.DateOnly)
+	until := now.AddDate(0, 0, upcomingDays).Format(time.DateOnly)
+	rows, err := s.db.OpenTasksDue(until)
+	if err != nil {
+		return nil, err
+	}
+	out := &UpcomingTasks{
+		Date:     today,
+		Overdue:  []index.TaskRow{},
+		Today:    []index.TaskRow{},
+		ThisWeek: []index.TaskRow{},
+	}
+	for _, t := range rows {
+		switch {
+		case t.Due < today:
+			out.Overdue = append(out.Overdue, t)
+		case t.Due == today:
+			out.Today = append(out.Today, t)
+		default:
+			out.ThisWeek = append(out.ThisWeek, t)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package noteservice
+
+import (
+	"context"
+	"time"
+)
+
+// Timeline orderings.
+const (
+	TimelineByUpdated = "updated"
+	TimelineByCreated = "created"
+)
+
+// TimelineGroup holds the notes of one day, newest first.
+type TimelineGroup struct {
+	Date  string         `json:"date" example:"2025-02-01" validate:"required"`
+	Notes []NoteListItem `json:"notes" validate:"required"`
+}
+
+// TimelinePage is a page of notes grouped by day.
+type TimelinePage struct {
+	By     string          `json:"by" enums:"updated,created" validate:"required"`
+	Groups []TimelineGroup `json:"groups" validate:"required"`
+	// Total is the number of notes across all pages.
+	Total int `json:"total" validate:"required"`
+}
+
+// Timeline returns a page of notes ordered by modification (by ==
+// TimelineByUpdated) or creation date, newest first, grouped by day. A day
+// can be split across pages.
+func (s *Service) Timeline(_ context.Context, by string, limit, offset int) (*TimelinePage, error) {
+	col := "updated_at"
+	if by == TimelineByCreated {
+		col = "created_at"
+	} else {
+		by = TimelineByUpdated
+	}
+	rows, total, err := s.db.ListNotes(limit, offset, "", col, NoteFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TimelinePage{By: by, Groups: []TimelineGroup{}, Total: total}
+	for _, r := range rows {
+		t := r.UpdatedAt
+		if by == TimelineByCreated {
+			t = r.CreatedAt
+		}
+		day := t.Format(time.DateOnly)
+		if n := len(page.Groups); n == 0 || page.Groups[n-1].Date != day {
+			page.Groups = append(page.Groups, TimelineGroup{Date: day})
+		}
+		g := &page.Groups[len(page.Groups)-1]
+		g.Notes = append(g.Notes, NoteListItem{
+			Path:      r.Path,
+			Title:     r.Title,
+			Checksum:  r.Checksum,
+			Tags:      nonNilSlice(r.Tags),
+			UpdatedAt: r.UpdatedAt,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	return page, nil
+}
@@ -0,0 +1,472 @@
+package noteservice
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/asset"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/merge"
+	"github.com/starford/kenaz/internal/parser"
+	"github.com/starford/kenaz/internal/storage"
+)
+
+// Import conflict strategies: what ImportVault does with an archive file
+// whose path already exists in the vault.
+const (
+	ImportSkip      = "skip"
+	ImportOverwrite = "overwrite"
+	ImportRename    = "rename"
+)
+
+// Import outcomes of a file, reported in ImportItem.Status.
+const (
+	ImportCreated     = "created"
+	ImportOverwritten = "overwritten"
+	ImportRenamed     = "renamed"
+	ImportSkipped     = "skipped"
+	ImportFailed      = "failed"
+)
+
+// MaxImportSize caps the total uncompressed size of an imported archive.
+const MaxImportSize = 1 << 30 // 1 GB
+
+// ErrInvalidImport is returned by ImportVault for an archive it cannot
+// read or an unknown conflict strategy.
+var ErrInvalidImport = errors.New("invalid import")
+
+// VaultExport is a selection of vault files to export, written by WriteZip.
+type VaultExport struct {
+	// Paths are the vault-relative paths of the exported files, sorted.
+	Paths []string
+	store storage.Provider
+}
+
+// ExportVault selects the files to export: the whole vault (notes and
+// attachments, but not the trash or .kenaz), or only the notes under
+// folder and, with tag, those tagged tag. A subset also carries the
+// attachments its notes reference, by URL (/attachments/<name>) or as a
+// wikilink embed, so it is complete when imported elsewhere. A folder that
+// does not exist fails with apperr.ErrNotFound.
+func (s *Service) ExportVault(ctx context.Context, folder, tag string) (*VaultExport, error) {
+	folder = strings.Trim(folder, "/")
+	if folder != "" {
+		if !fs.ValidPath(folder) || storage.InReserved(folder) {
+			return nil, fmt.Errorf("%w: %q", apperr.ErrInvalidPath, folder)
+		}
+		ok, err := s.store.DirExists(folder)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: folder %s", apperr.ErrNotFound, folder)
+		}
+	}
+	e := &VaultExport{store: s.store}
+	if folder == "" && tag == "" {
+		files, err := s.store.ListFiles("")
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(files)
+		e.Paths = files
+		return e, nil
+	}
+
+	var notes []string
+	if tag == "" {
+		files, err := s.store.ListFiles(folder)
+		if err != nil {
+			return nil, err
+		}
+		notes = files
+	} else {
+		prefix := ""
+		if folder != "" {
+			prefix = folder + "/"
+		}
+		cursor := ""
+		for {
+			page, err := s.db.ListNotesCursor(500, cursor, tag, prefix)
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range page.Notes {
+				notes = append(notes, n.Path)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+	}
+
+	seen := make(map[string]bool, len(notes))
+	for _, p := range notes {
+		seen[p] = true
+	}
+	attachments, err := s.store.ListFiles(attachmentsDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(attachments))
+	for _, p := range attachments {
+		stored[p] = true
+	}
+	e.Paths = notes
+	for _, p := range notes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !strings.HasSuffix(p, ".md") {
+			continue
+		}
+		data, err := s.store.Read(p)
+		if err != nil {
+			continue
+		}
+		names, err := s.referencedAttachments(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if a := attachmentsDir + "/" + name; stored[a] && !seen[a] {
+				seen[a] = true
+				e.Paths = append(e.Paths, a)
+			}
+		}
+	}
+	slices.Sort(e.Paths)
+	return e, nil
+}
+
+// referencedAttachments returns the names of the attachments a note
+// references, by URL or as a wikilink embed (![[diagram.png]]).
+func (s *Service) referencedAttachments(data []byte) ([]string, error) {
+	var names []string
+	for _, m := range attachmentRefRe.FindAllSubmatch(data, -1) {
+		if name, err := url.PathUnescape(string(m[2])); err == nil && name != "" && !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	res, err := s.db.Parse(data)
+	if err != nil {
+		return names, nil
+	}
+	for _, l := range index.NoteLinks(res) {
+		if ext := path.Ext(l.Target); ext == "" || ext == ".md" {
+			continue
+		}
+		target, err := s.db.ResolveLink(l.Target)
+		if err != nil {
+			return nil, err
+		}
+		if target == "" {
+			names = append(names, path.Base(l.Target))
+		}
+	}
+	return names, nil
+}
+
+// WriteZip streams the exported files to w as a zip archive, keeping vault
+// paths, so ImportVault can unpack it into another vault. Files deleted
+// since ExportVault are left out.
+func (e *VaultExport) WriteZip(ctx context.Context, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, p := range e.Paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := e.store.Read(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: p, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ImportItem is the outcome of importing one archive file.
+type ImportItem struct {
+	// Path is the file's path in the archive.
+	Path string `json:"path" example:"projects/roadmap.md" validate:"required"`
+	// Status is "created", "overwritten", "renamed", "skipped", or "failed".
+	Status string `json:"status" example:"created" validate:"required"`
+	// RenamedTo is where a conflicting file was written with the rename
+	// strategy.
+	RenamedTo string `json:"renamed_to,omitempty" example:"projects/roadmap-2.md"`
+	// Error is why the file failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ImportResult reports what ImportVault did, or would do on a dry run,
+// whose Changes list the files it would write, with a diff per note.
+type ImportResult struct {
+	Created     int             `json:"created" example:"120" validate:"required"`
+	Overwritten int             `json:"overwritten" example:"0" validate:"required"`
+	Renamed     int             `json:"renamed" example:"2" validate:"required"`
+	Skipped     int             `json:"skipped" example:"3" validate:"required"`
+	Failed      int             `json:"failed" example:"0" validate:"required"`
+	Files       []ImportItem    `json:"files" validate:"required"`
+	DryRun      bool            `json:"dry_run"`
+	Changes     []PlannedChange `json:"changes,omitempty"`
+}
+
+// WithPipeline runs the attachments ImportVault writes through p, like
+// uploads. Without it only SVGs are sanitized.
+func WithPipeline(p *asset.Pipeline) Option {
+	return func(s *Service) { s.pipeline = p }
+}
+
+// ImportVault unpacks a zip archive of size bytes, read from r, into the
+// vault: notes and attachments keep their archive paths, and the notes are
+// indexed in one transaction with a single batch hook, like Batch. Files
+// whose path exists are handled by conflict: ImportSkip (the default)
+// leaves the vault file, ImportOverwrite replaces it (keeping a revision
+// of a replaced note), and ImportRename writes the file next to it with a
+// numeric suffix ("-2", "-3", ...); links to it are not rewritten. Files
+// identical to the vault's are skipped under every strategy. Files are
+// written as they are, except that a note whose id another vault note
+// already has, such as a renamed copy, gets a new one, and that every
+// other file goes through the upload pipeline (see WithPipeline) first:
+// files it rejects, such as infected ones, fail on their own. Kept
+// originals (see asset.OriginalName) are not resized.
+//
+// The archive is checked before anything is written: entries outside the
+// vault, such as "../x", or in the trash, .kenaz, or .git fail the import
+// with apperr.ErrInvalidPath; an unreadable archive, or one larger than
+// MaxImportSize uncompressed, with ErrInvalidImport. Notes that do not
+// parse fail on their own without stopping the others. With dryRun nothing
+// is written; the result reports what the import would do.
+func (s *Service) ImportVault(ctx context.Context, r io.ReaderAt, size int64, conflict string, dryRun bool) (*ImportResult, error) {
+	switch conflict {
+	case "":
+		conflict = ImportSkip
+	case ImportSkip, ImportOverwrite, ImportRename:
+	default:
+		return nil, fmt.Errorf("%w: unknown conflict strategy %q", ErrInvalidImport, conflict)
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	var files []*zip.File
+	var total uint64
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := checkImportPath(f.Name); err != nil {
+			return nil, err
+		}
+		total += f.UncompressedSize64
+		if total > MaxImportSize {
+			return nil, fmt.Errorf("%w: archive exceeds %d bytes uncompressed", ErrInvalidImport, MaxImportSize)
+		}
+		files = append(files, f)
+	}
+
+	var plan *batchPlan
+	if dryRun {
+		plan = &batchPlan{files: make(map[string][]byte)}
+	}
+	res := &ImportResult{Files: make([]ImportItem, 0, len(files)), DryRun: dryRun}
+	var b index.NoteBatch
+	var changed []string
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		item, n := s.importFile(ctx, f, conflict, plan)
+		switch item.Status {
+		case ImportCreated:
+			res.Created++
+		case ImportOverwritten:
+			res.Overwritten++
+		case ImportRenamed:
+			res.Renamed++
+		case ImportSkipped:
+			res.Skipped++
+		case ImportFailed:
+			res.Failed++
+		}
+		res.Files = append(res.Files, item)
+		if n != nil {
+			b.Upserts = append(b.Upserts, *n)
+			changed = append(changed, n.Row.Path)
+		}
+	}
+	if plan != nil {
+		res.Changes = plan.changes
+		return res, nil
+	}
+	if len(changed) == 0 {
+		return res, nil
+	}
+	// The files are written already; index them even if the caller is gone.
+	if err := s.db.ApplyBatch(context.WithoutCancel(ctx), b); err != nil {
+		return nil, err
+	}
+	if s.onBatch != nil {
+		s.onBatch(changed)
+	}
+	return res, nil
+}
+
+// importFile writes one archive file according to conflict, or records it
+// in plan on a dry run, and returns its outcome and, for a written note,
+// the note parsed for the index.
+func (s *Service) importFile(ctx context.Context, f *zip.File, conflict string, plan *batchPlan) (ImportItem, *index.IndexedNote) {
+	item := ImportItem{Path: f.Name}
+	fail := func(err error) (ImportItem, *index.IndexedNote) {
+		item.Status, item.Error = ImportFailed, err.Error()
+		return item, nil
+	}
+	content, err := readZipFile(f)
+	if err != nil {
+		return fail(err)
+	}
+	note := strings.HasSuffix(f.Name, ".md")
+	var original []byte
+	if !note {
+		p := s.pipeline
+		if p != nil && asset.IsOriginalName(f.Name) {
+			unresized := *p
+			unresized.Images = asset.ImageOptions{}
+			p = &unresized
+		}
+		res, err := p.Process(ctx, f.Name, content)
+		if err != nil {
+			return fail(err)
+		}
+		content, original = res.Data, res.Original
+	}
+	dst := f.Name
+	item.Status = ImportCreated
+	existing, err := s.batchRead(dst, plan)
+	switch {
+	case err == nil && bytes.Equal(existing, content):
+		item.Status = ImportSkipped
+		return item, nil
+	case err == nil && conflict == ImportSkip:
+		item.Status = ImportSkipped
+		return item, nil
+	case err == nil && conflict == ImportRename:
+		if dst, err = s.freePath(dst, plan); err != nil {
+			return fail(err)
+		}
+		item.Status, item.RenamedTo, existing = ImportRenamed, dst, nil
+	case err == nil:
+		item.Status = ImportOverwritten
+	case !errors.Is(err, os.ErrNotExist):
+		return fail(err)
+	}
+
+	if note {
+		parsed, err := s.db.Parse(content)
+		if err != nil {
+			return fail(err)
+		}
+		owner, err := s.db.PathByID(parsed.ID)
+		if err != nil {
+			return fail(err)
+		}
+		if owner != "" && owner != dst {
+			content = parser.SetFrontmatterField(content, "id", uuid.New().String())
+		}
+	}
+	if plan != nil {
+		change := PlannedChange{Action: ChangeCreate, Path: dst}
+		if item.Status == ImportOverwritten {
+			change.Action = ChangeUpdate
+		}
+		if note {
+			change.Diff = merge.Diff(dst, string(existing), string(content))
+		}
+		plan.files[dst] = content
+		plan.changes = append(plan.changes, change)
+		if original != nil {
+			plan.changes = append(plan.changes, PlannedChange{Action: ChangeCreate, Path: asset.OriginalName(dst)})
+		}
+		return item, nil
+	}
+	if note && item.Status == ImportOverwritten {
+		if err := s.saveRevision(dst, existing, content); err != nil {
+			return fail(err)
+		}
+	}
+	if original != nil {
+		if err := s.store.Write(asset.OriginalName(dst), original); err != nil {
+			return fail(err)
+		}
+	}
+	if err := s.store.Write(dst, content); err != nil {
+		return fail(err)
+	}
+	if !note {
+		return item, nil
+	}
+	n, err := s.indexedNote(dst, content)
+	if err != nil {
+		return fail(err)
+	}
+	return item, &n
+}
+
+// freePath returns p with the first numeric suffix ("-2", "-3", ...) that
+// names no vault file, or none planned in plan.
+func (s *Service) freePath(p string, plan *batchPlan) (string, error) {
+	ext := path.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	for i := 2; i <= maxSlugSuffix; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, err := s.batchRead(candidate, plan); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return candidate, nil
+			}
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%w: no free name for %q", apperr.ErrAlreadyExists, p)
+}
+
+// checkImportPath rejects archive paths outside the vault or inside the
+// trash, the state folder, or a git repository.
+func checkImportPath(p string) error {
+	if !fs.ValidPath(p) || p == "." || storage.InReserved(p) || p == ".git" || strings.HasPrefix(p, ".git/") {
+		return fmt.Errorf("%w: %q", apperr.ErrInvalidPath, p)
+	}
+	return nil
+}
+
+// readZipFile returns the content of an archive file.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,260 @@
+package noteservice
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/asset"
+)
+
+// testArchive returns a zip archive holding files.
+func testArchive(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// importArchive imports an archive holding files.
+func importArchive(t *testing.T, svc *Service, files map[string]string, conflict string) (*ImportResult, error) {
+	t.Helper()
+	a := testArchive(t, files)
+	return svc.ImportVault(context.Background(), a, a.Size(), conflict, false)
+}
+
+func TestExportVault(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	for p, content := range map[string]string{
+		"attachments/a.png":   "png-a",
+		"attachments/b.pdf":   "pdf-b",
+		"attachments/c.jpg":   "jpg-c",
+		"projects/notes.txt":  "plain",
+		".trash/deleted.md":   "# Deleted",
+		".kenaz/state/x.json": "{}",
+	} {
+		if err := svc.store.Write(p, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createNote(t, svc, "projects/plan.md", "---\ntags: [work]\n---\n# Plan\n\n![a](/attachments/a.png?v=1)\n")
+	createNote(t, svc, "projects/old.md", "# Old\n")
+	createNote(t, svc, "journal.md", "---\ntags: [work]\n---\n# Journal\n\n![[b.pdf]]\n")
+
+	for _, tc := range []struct {
+		folder, tag string
+		want        []string
+	}{
+		{"", "", []string{"attachments/a.png", "attachments/b.pdf", "attachments/c.jpg", "journal.md", "projects/notes.txt", "projects/old.md", "projects/plan.md"}},
+		{"projects", "", []string{"attachments/a.png", "projects/notes.txt", "projects/old.md", "projects/plan.md"}},
+		{"", "work", []string{"attachments/a.png", "attachments/b.pdf", "journal.md", "projects/plan.md"}},
+		{"projects/", "work", []string{"attachments/a.png", "projects/plan.md"}},
+	} {
+		e, err := svc.ExportVault(ctx, tc.folder, tc.tag)
+		if err != nil {
+			t.Fatalf("ExportVault(%q, %q): %v", tc.folder, tc.tag, err)
+		}
+		if !slices.Equal(e.Paths, tc.want) {
+			t.Errorf("ExportVault(%q, %q) = %v, want %v", tc.folder, tc.tag, e.Paths, tc.want)
+		}
+	}
+
+	if _, err := svc.ExportVault(ctx, "missing", ""); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("missing folder = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.ExportVault(ctx, "../up", ""); !errors.Is(err, apperr.ErrInvalidPath) {
+		t.Errorf("folder outside the vault = %v, want ErrInvalidPath", err)
+	}
+
+	e, err := svc.ExportVault(ctx, "projects", "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := e.WriteZip(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "attachments/a.png" || zr.File[1].Name != "projects/plan.md" {
+		t.Errorf("archive entries = %v", zr.File)
+	}
+}
+
+func TestImportVault(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	var batches [][]string
+	svc.onBatch = func(paths []string) { batches = append(batches, paths) }
+	createNote(t, svc, "a.md", "---\nid: note-a\n---\n# A\n")
+	createNote(t, svc, "same.md", "---\nid: note-same\n---\n# Same\n")
+
+	archive := map[string]string{
+		"a.md":              "---\nid: note-a\n---\n# A imported\n",
+		"same.md":           "---\nid: note-same\n---\n# Same\n",
+		"new/b.md":          "# B\n",
+		"attachments/x.png": "png",
+	}
+
+	res, err := importArchive(t, svc, archive, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != 2 || res.Skipped != 2 || res.Failed != 0 {
+		t.Errorf("skip import = %+v", res)
+	}
+	if got, _ := svc.store.Read("a.md"); !strings.Contains(string(got), "# A\n") {
+		t.Errorf("skip overwrote a.md: %q", got)
+	}
+	if row, err := svc.db.GetNote("new/b.md"); err != nil || row == nil || row.Title != "B" {
+		t.Errorf("new/b.md not indexed: %+v, %v", row, err)
+	}
+	if len(batches) != 1 || !slices.Equal(batches[0], []string{"new/b.md"}) {
+		t.Errorf("batch hook calls = %v", batches)
+	}
+
+	res, err = importArchive(t, svc, archive, ImportRename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Renamed != 1 || res.Skipped != 3 {
+		t.Errorf("rename import = %+v", res)
+	}
+	if res.Files[0].Path != "a.md" || res.Files[0].RenamedTo != "a-2.md" {
+		t.Errorf("renamed item = %+v", res.Files[0])
+	}
+	row, err := svc.db.GetNote("a-2.md")
+	if err != nil || row == nil || row.Title != "A imported" {
+		t.Fatalf("a-2.md = %+v, %v", row, err)
+	}
+	if row.ID == "note-a" {
+		t.Error("renamed note kept the id of a.md")
+	}
+
+	res, err = importArchive(t, svc, archive, ImportOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Overwritten != 1 || res.Skipped != 3 {
+		t.Errorf("overwrite import = %+v", res)
+	}
+	if row, _ := svc.db.GetNote("a.md"); row == nil || row.Title != "A imported" || row.ID != "note-a" {
+		t.Errorf("overwritten a.md = %+v", row)
+	}
+
+	bad := map[string]string{"ok.md": "# OK\n", "../escape.md": "# Escape\n"}
+	if _, err := importArchive(t, svc, bad, ""); !errors.Is(err, apperr.ErrInvalidPath) {
+		t.Errorf("archive escaping the vault = %v, want ErrInvalidPath", err)
+	}
+	if _, err := svc.store.Read("ok.md"); err == nil {
+		t.Error("nothing should be written from a rejected archive")
+	}
+	for _, p := range []string{".trash/x.md", ".git/config"} {
+		if _, err := importArchive(t, svc, map[string]string{p: "x"}, ""); !errors.Is(err, apperr.ErrInvalidPath) {
+			t.Errorf("import of %s = %v, want ErrInvalidPath", p, err)
+		}
+	}
+	if _, err := importArchive(t, svc, archive, "merge"); !errors.Is(err, ErrInvalidImport) {
+		t.Errorf("unknown strategy = %v, want ErrInvalidImport", err)
+	}
+	if _, err := svc.ImportVault(ctx, strings.NewReader("not a zip"), 9, "", false); !errors.Is(err, ErrInvalidImport) {
+		t.Errorf("not a zip = %v, want ErrInvalidImport", err)
+	}
+}
+
+func TestImportVaultDryRun(t *testing.T) {
+	svc := testService(t)
+	createNote(t, svc, "a.md", "---\nid: note-a\n---\n# A\n")
+
+	a := testArchive(t, map[string]string{
+		"a.md":              "---\nid: note-a\n---\n# A imported\n",
+		"a-2.md":            "# Taken\n",
+		"attachments/x.png": "png",
+	})
+	res, err := svc.ImportVault(context.Background(), a, a.Size(), ImportRename, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.DryRun || res.Created != 2 || res.Renamed != 1 {
+		t.Fatalf("dry-run import = %+v", res)
+	}
+	paths := map[string]PlannedChange{}
+	for _, c := range res.Changes {
+		paths[c.Path] = c
+	}
+	// The archive's a-2.md is planned first, so a.md moves on to a-3.md.
+	if c, ok := paths["a-2.md"]; !ok || c.Action != ChangeCreate {
+		t.Errorf("changes = %+v", res.Changes)
+	}
+	if c, ok := paths["a-3.md"]; !ok || !strings.Contains(c.Diff, "+# A imported") || strings.Contains(c.Diff, "-# A") {
+		t.Errorf("changes = %+v", res.Changes)
+	}
+	if c := paths["attachments/x.png"]; c.Action != ChangeCreate || c.Diff != "" {
+		t.Errorf("attachment change = %+v", c)
+	}
+	for _, p := range []string{"a-2.md", "a-3.md", "attachments/x.png"} {
+		if _, err := svc.store.Read(p); err == nil {
+			t.Errorf("dry run wrote %s", p)
+		}
+	}
+}
+
+// rejectScanner reports every file whose content contains "EICAR" as
+// infected.
+type rejectScanner struct{}
+
+func (rejectScanner) Scan(_ context.Context, name string, r io.Reader) error {
+	data, _ := io.ReadAll(r)
+	if bytes.Contains(data, []byte("EICAR")) {
+		return fmt.Errorf("%w: %s", asset.ErrInfected, name)
+	}
+	return nil
+}
+
+func TestImportVaultPipeline(t *testing.T) {
+	svc := testService(t, WithPipeline(&asset.Pipeline{Scanner: rejectScanner{}}))
+	res, err := importArchive(t, svc, map[string]string{
+		"attachments/x.svg": `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script><rect/></svg>`,
+		"attachments/v.bin": "EICAR",
+		"n.md":              "EICAR is only text in a note.\n",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != 2 || res.Failed != 1 {
+		t.Fatalf("import = %+v", res)
+	}
+	if svg, err := svc.store.Read("attachments/x.svg"); err != nil || strings.Contains(string(svg), "script") {
+		t.Errorf("imported SVG = %q, %v", svg, err)
+	}
+	if _, err := svc.store.Read("attachments/v.bin"); err == nil {
+		t.Error("infected attachment was written")
+	}
+}
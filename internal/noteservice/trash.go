@@ -0,0 +1,137 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/storage"
+)
+
+// trashFiles moves files (vault-relative) into storage.TrashDir, keeping
+// their relative paths, records them in the index's trash table, and drops
+// the notes among them from the index, so trashed notes no longer show up in
+// search, links, or the graph. A file already in the trash under the same
+// path is replaced.
+func (s *Service) trashFiles(files []string) error {
+	now := time.Now()
+	entries := make([]index.TrashEntry, 0, len(files))
+	var notes []string
+	var moveErr error
+	for _, f := range files {
+		title := ""
+		isNote := strings.HasSuffix(f, ".md")
+		if isNote {
+			if row, err := s.db.GetNote(f); err == nil && row != nil {
+				title = row.Title
+			}
+		}
+		if moveErr = s.moveToTrash(f); moveErr != nil {
+			break
+		}
+		entries = append(entries, index.TrashEntry{Path: f, Title: title, DeletedAt: now})
+		if isNote {
+			notes = append(notes, f)
+		}
+	}
+	// Record what was moved even when a later move failed.
+	if len(notes) > 0 {
+		if err := s.db.DeleteNotesBatch(notes); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		if err := s.db.AddTrash(entries); err != nil {
+			return err
+		}
+	}
+	return moveErr
+}
+
+// moveToTrash moves the file at f into the trash, replacing an earlier
+// trashed copy.
+func (s *Service) moveToTrash(f string) error {
+	dst := path.Join(storage.TrashDir, f)
+	_ = s.store.Delete(dst) // not every provider overwrites on Move
+	return s.store.Move(f, dst)
+}
+
+// Trash lists the files in the trash, most recently deleted first.
+func (s *Service) Trash(_ context.Context) ([]index.TrashEntry, error) {
+	return s.db.Trash()
+}
+
+// RestoreTrash moves the trashed file that was at p back and, for a note,
+// re-indexes it and returns it (nil for attachments). It fails with
+// apperr.ErrNotFound when p is not in the trash and apperr.ErrAlreadyExists
+// when a file has been created at p since.
+func (s *Service) RestoreTrash(_ context.Context, p string) (*NoteDetail, error) {
+	src, err := s.trashed(p)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.store.Read(p); err == nil {
+		return nil, fmt.Errorf("%w: %s", apperr.ErrAlreadyExists, p)
+	}
+	if err := s.store.Move(src, p); err != nil {
+		return nil, s.trashGone(p, err)
+	}
+	if err := s.db.RemoveTrash(p); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(p, ".md") {
+		return nil, nil
+	}
+	content, err := s.store.Read(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.IndexFile(p, content); err != nil {
+		return nil, err
+	}
+	return s.buildNoteDetail(p, content)
+}
+
+// PurgeTrash permanently deletes the trashed file that was at p. It fails
+// with apperr.ErrNotFound when p is not in the trash.
+func (s *Service) PurgeTrash(_ context.Context, p string) error {
+	src, err := s.trashed(p)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(src); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.db.RemoveTrash(p)
+}
+
+// trashed returns where the file trashed from p is kept.
+func (s *Service) trashed(p string) (string, error) {
+	e, err := s.db.TrashEntry(p)
+	if err != nil {
+		return "", err
+	}
+	if e == nil {
+		return "", fmt.Errorf("%w: %s is not in the trash", apperr.ErrNotFound, p)
+	}
+	return path.Join(storage.TrashDir, p), nil
+}
+
+// trashGone handles err from moving the trashed file of p: when the file is
+// gone (e.g. the trash folder was emptied by hand) the entry is dropped and
+// apperr.ErrNotFound returned.
+func (s *Service) trashGone(p string, err error) error {
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := s.db.RemoveTrash(p); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s is not in the trash", apperr.ErrNotFound, p)
+}
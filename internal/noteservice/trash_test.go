@@ -0,0 +1,91 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestTrash(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "plan.md", "# Plan\nshipping soon\n")
+	createNote(t, svc, "home.md", "# Home\nsee [[plan]]\n")
+
+	if _, err := svc.DeleteNote(ctx, "plan.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.store.Read(".trash/plan.md"); err != nil {
+		t.Errorf("note not in trash: %v", err)
+	}
+	if row, _ := svc.db.GetNote("plan.md"); row != nil {
+		t.Error("trashed note still indexed")
+	}
+	entries, err := svc.Trash(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Path != "plan.md" || entries[0].Title != "Plan" || entries[0].DeletedAt.IsZero() {
+		t.Errorf("trash = %+v", entries)
+	}
+
+	createNote(t, svc, "plan.md", "# New plan\n")
+	if _, err := svc.RestoreTrash(ctx, "plan.md"); !errors.Is(err, apperr.ErrAlreadyExists) {
+		t.Errorf("restore over existing note err = %v", err)
+	}
+	if _, err := svc.DeleteNote(ctx, "plan.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	note, err := svc.RestoreTrash(ctx, "plan.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Title != "New plan" {
+		t.Errorf("restored title = %q, want the latest trashed copy", note.Title)
+	}
+	if row, _ := svc.db.GetNote("plan.md"); row == nil {
+		t.Error("restored note not indexed")
+	}
+	if entries, _ := svc.Trash(ctx); len(entries) != 0 {
+		t.Errorf("trash after restore = %+v", entries)
+	}
+	if _, err := svc.RestoreTrash(ctx, "plan.md"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("restore twice err = %v", err)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	svc := testService(t)
+	ctx := context.Background()
+	createNote(t, svc, "old/a.md", "# A")
+	if err := svc.store.Write("old/pic.png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.DeleteDir(ctx, "old/"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := svc.Trash(ctx); len(entries) != 2 {
+		t.Fatalf("trash = %+v", entries)
+	}
+
+	if err := svc.PurgeTrash(ctx, "old/a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.store.Read(".trash/old/a.md"); err == nil {
+		t.Error("purged note still in trash folder")
+	}
+	if err := svc.PurgeTrash(ctx, "old/a.md"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("purge twice err = %v", err)
+	}
+
+	note, err := svc.RestoreTrash(ctx, "old/pic.png")
+	if err != nil || note != nil {
+		t.Errorf("restore attachment = %v, %v", note, err)
+	}
+	if _, err := svc.store.Read("old/pic.png"); err != nil {
+		t.Errorf("attachment not restored: %v", err)
+	}
+}
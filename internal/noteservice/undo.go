@@ -0,0 +1,199 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/checksum"
+)
+
+// Mutation kinds.
+const (
+	MutationCreated = "created"
+	MutationUpdated = "updated"
+	MutationDeleted = "deleted"
+)
+
+// maxUndoEntries bounds the undo log regardless of the window.
+const maxUndoEntries = 1000
+
+// maxUndoBytes bounds the previous note content the undo log holds; the
+// oldest entries are dropped to stay under it.
+const maxUndoBytes = 64 << 20
+
+// Mutation is a recorded change to one note that can be undone with Undo
+// until the undo window passes.
+type Mutation struct {
+	ID   string    `json:"id" validate:"required"`
+	Kind string    `json:"kind" enums:"created,updated,deleted" validate:"required"`
+	Path string    `json:"path" validate:"required"`
+	At   time.Time `json:"at" validate:"required"`
+
+	prev  []byte // content before the mutation; nil if the note did not exist
+	after string // checksum after the mutation; "" if the note was deleted
+}
+
+// undoLog keeps recent mutations in memory, oldest first.
+type undoLog struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries []*Mutation
+	// size is the total length of the entries' previous content, kept
+	// within maxBytes (maxUndoBytes when zero).
+	size     int
+	maxBytes int
+}
+
+// WithUndoWindow keeps the previous content of mutated notes for d so the
+// change can be reverted with Undo. Zero disables undo.
+func WithUndoWindow(d time.Duration) Option {
+	return func(s *Service) {
+		s.undo.window = d
+	}
+}
+
+// WithMutationHook registers fn to be called after every recorded mutation
+// (e.g. to publish its ID to SSE clients).
+func WithMutationHook(fn func(Mutation)) Option {
+	return func(s *Service) {
+		s.onMutation = fn
+	}
+}
+
+// record logs a mutation of path from prev (nil when the note did not exist)
+// to after (nil when it was deleted) for undo. It returns the zero Mutation
+// when undo is disabled.
+func (s *Service) record(kind, path string, prev, after []byte) Mutation {
+	l := &s.undo
+	if l.window <= 0 {
+		return Mutation{}
+	}
+	m := &Mutation{ID: uuid.New().String(), Kind: kind, Path: path, At: time.Now().UTC(), prev: prev}
+	if after != nil {
+		m.after = checksum.Sum(after)
+	}
+
+	l.mu.Lock()
+	l.pruneLocked(m.At)
+	budget := l.maxBytes
+	if budget <= 0 {
+		budget = maxUndoBytes
+	}
+	for len(l.entries) > 0 && (len(l.entries) >= maxUndoEntries || l.size+len(prev) > budget) {
+		l.dropLocked(0)
+	}
+	l.entries = append(l.entries, m)
+	l.size += len(prev)
+	l.mu.Unlock()
+
+	if s.onMutation != nil {
+		s.onMutation(*m)
+	}
+	return *m
+}
+
+// pruneLocked drops entries older than the window.
+func (l *undoLog) pruneLocked(now time.Time) {
+	for len(l.entries) > 0 && now.Sub(l.entries[0].At) > l.window {
+		l.dropLocked(0)
+	}
+}
+
+// dropLocked removes the i-th entry.
+func (l *undoLog) dropLocked(i int) {
+	l.size -= len(l.entries[i].prev)
+	l.entries = append(l.entries[:i], l.entries[i+1:]...)
+}
+
+// take removes and returns the mutation with the given ID if it is still
+// within the window.
+func (l *undoLog) take(id string) *Mutation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pruneLocked(time.Now().UTC())
+	for i, m := range l.entries {
+		if m.ID == id {
+			l.dropLocked(i)
+			return m
+		}
+	}
+	return nil
+}
+
+// putBack re-inserts a mutation taken by take (used when undo fails).
+func (l *undoLog) putBack(m *Mutation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.size += len(m.prev)
+	for i, e := range l.entries {
+		if e.At.After(m.At) {
+			l.entries = append(l.entries[:i], append([]*Mutation{m}, l.entries[i:]...)...)
+			return
+		}
+	}
+	l.entries = append(l.entries, m)
+}
+
+// Undo reverts the mutation with the given ID: a created note is moved to
+// the trash, an updated or deleted note gets its previous content back. It
+// fails with apperr.ErrNotFound when the ID is unknown or past the undo
+// window, and with apperr.ErrConflict when the note changed after the
+// mutation. The undo is itself recorded; the returned mutation can be
+// undone to redo.
+func (s *Service) Undo(_ context.Context, id string) (*Mutation, error) {
+	m := s.undo.take(id)
+	if m == nil {
+		return nil, apperr.ErrNotFound
+	}
+	res, err := s.revert(m)
+	if err != nil {
+		if errors.Is(err, apperr.ErrConflict) {
+			s.undo.putBack(m)
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) revert(m *Mutation) (*Mutation, error) {
+	current, err := s.store.Read(m.Path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if exists != (m.after != "") || (exists && checksum.Sum(current) != m.after) {
+		return nil, apperr.ErrConflict
+	}
+	if !exists {
+		current = nil
+	}
+
+	var kind string
+	switch {
+	case m.prev == nil:
+		// Like any other delete, undoing a create can be restored from the trash.
+		if err := s.trashFiles([]string{m.Path}); err != nil {
+			return nil, err
+		}
+		kind = MutationDeleted
+	default:
+		if err := s.store.Write(m.Path, m.prev); err != nil {
+			return nil, err
+		}
+		if err := s.IndexFile(m.Path, m.prev); err != nil {
+			return nil, err
+		}
+		kind = MutationUpdated
+		if !exists {
+			kind = MutationCreated
+		}
+	}
+	res := s.record(kind, m.Path, current, m.prev)
+	return &res, nil
+}
@@ -0,0 +1,141 @@
+package noteservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestUndo(t *testing.T) {
+	var hooked []Mutation
+	svc := testService(t, WithUndoWindow(time.Minute), WithMutationHook(func(m Mutation) { hooked = append(hooked, m) }))
+	ctx := context.Background()
+
+	created, err := svc.CreateNote(ctx, "u.md", []byte("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := svc.UpdateNote(ctx, "u.md", []byte("v2"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.MutationID == "" || updated.MutationID == "" || len(hooked) != 2 || hooked[1].ID != updated.MutationID {
+		t.Fatalf("mutation ids = %q %q, hooked = %+v", created.MutationID, updated.MutationID, hooked)
+	}
+
+	// Undo the update, then redo it by undoing the undo.
+	undo, err := svc.Undo(ctx, updated.MutationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := svc.GetNote(ctx, "u.md"); n.Content != created.Content {
+		t.Errorf("after undo content = %q", n.Content)
+	}
+	if _, err := svc.Undo(ctx, updated.MutationID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("second undo err = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Undo(ctx, undo.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := svc.GetNote(ctx, "u.md"); n.Content != "v2" {
+		t.Errorf("after redo content = %q", n.Content)
+	}
+
+	// The create cannot be undone while later changes are in place.
+	if _, err := svc.Undo(ctx, created.MutationID); !errors.Is(err, apperr.ErrConflict) {
+		t.Errorf("stale undo err = %v, want ErrConflict", err)
+	}
+
+	// Delete and restore.
+	delID, err := svc.DeleteNote(ctx, "u.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Undo(ctx, delID); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := svc.GetNote(ctx, "u.md"); err != nil || n.Content != "v2" {
+		t.Errorf("restored note = %+v, %v", n, err)
+	}
+}
+
+func TestUndo_Window(t *testing.T) {
+	svc := testService(t, WithUndoWindow(time.Minute))
+	ctx := context.Background()
+	n, err := svc.CreateNote(ctx, "w.md", []byte("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.undo.entries[0].At = time.Now().Add(-2 * time.Minute)
+	if _, err := svc.Undo(ctx, n.MutationID); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("expired undo err = %v, want ErrNotFound", err)
+	}
+
+	off := testService(t)
+	n, err = off.CreateNote(ctx, "w.md", []byte("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.MutationID != "" {
+		t.Errorf("undo disabled but mutation id = %q", n.MutationID)
+	}
+}
+
+func TestUndo_Create(t *testing.T) {
+	svc := testService(t, WithUndoWindow(time.Minute))
+	ctx := context.Background()
+	n, err := svc.CreateNote(ctx, "c.md", []byte("draft"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Undo(ctx, n.MutationID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetNote(ctx, "c.md"); !errors.Is(err, apperr.ErrNotFound) {
+		t.Errorf("undone create still readable: %v", err)
+	}
+	trash, err := svc.Trash(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].Path != "c.md" {
+		t.Fatalf("trash = %+v, want c.md", trash)
+	}
+	if _, err := svc.RestoreTrash(ctx, "c.md"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUndo_ByteBudget(t *testing.T) {
+	svc := testService(t, WithUndoWindow(time.Minute))
+	svc.undo.maxBytes = 10
+	ctx := context.Background()
+	n, err := svc.CreateNote(ctx, "b.md", []byte("12345678"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := svc.UpdateNote(ctx, "b.md", []byte("abcdefgh"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keeping both updates' previous content (16 bytes) would exceed the
+	// budget, so the oldest entries are dropped until the new one fits.
+	second, err := svc.UpdateNote(ctx, "b.md", []byte("v3"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.undo.size != 8 || len(svc.undo.entries) != 1 || svc.undo.entries[0].ID != second.MutationID {
+		t.Fatalf("undo log size = %d, entries = %d", svc.undo.size, len(svc.undo.entries))
+	}
+	for _, id := range []string{n.MutationID, first.MutationID} {
+		if _, err := svc.Undo(ctx, id); !errors.Is(err, apperr.ErrNotFound) {
+			t.Errorf("evicted undo err = %v, want ErrNotFound", err)
+		}
+	}
+	if _, err := svc.Undo(ctx, second.MutationID); err != nil {
+		t.Fatal(err)
+	}
+}
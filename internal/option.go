@@ -4,7 +4,8 @@ package internal
 type Option func(*application)
 
 type application struct {
-	config *Config
+	config  *Config
+	mcpHTTP bool
 }
 
 // WithConfig sets the application configuration.
@@ -13,3 +14,11 @@ func WithConfig(cfg *Config) Option {
 		a.config = cfg
 	}
 }
+
+// WithMCPOverHTTP also serves the MCP server at /mcp, behind the same token
+// auth as the REST API.
+func WithMCPOverHTTP() Option {
+	return func(a *application) {
+		a.mcpHTTP = true
+	}
+}
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Extractor derives extra metadata values from a parsed note, e.g. the
+// people it mentions or the URLs it cites. Values are stored in the index
+// under the extractor's name and can be queried by key and value.
+type Extractor interface {
+	Name() string
+	Extract(res *Result) []string
+}
+
+// FuncExtractor is an Extractor made from a name and a function.
+type FuncExtractor struct {
+	Key string
+	Fn  func(res *Result) []string
+}
+
+// Name implements Extractor.
+func (f FuncExtractor) Name() string { return f.Key }
+
+// Extract implements Extractor.
+func (f FuncExtractor) Extract(res *Result) []string { return f.Fn(res) }
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = map[string]Extractor{}
+)
+
+// RegisterExtractor makes e available by name to LookupExtractor, so it can
+// be enabled from configuration. It panics if the name is empty or already
+// taken, like database/sql.Register.
+func RegisterExtractor(e Extractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	name := e.Name()
+	if name == "" {
+		panic("parser: extractor name is empty")
+	}
+	if _, dup := extractors[name]; dup {
+		panic("parser: extractor registered twice: " + name)
+	}
+	extractors[name] = e
+}
+
+// LookupExtractor returns the registered extractor called name.
+func LookupExtractor(name string) (Extractor, error) {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	e, ok := extractors[name]
+	if !ok {
+		return nil, fmt.Errorf("parser: unknown extractor %q", name)
+	}
+	return e, nil
+}
+
+// ExtractorNames returns the names of all registered extractors, sorted.
+func ExtractorNames() []string {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	names := make([]string, 0, len(extractors))
+	for name := range extractors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// runExtractors applies exts to res, dropping empty and duplicate values.
+func runExtractors(res *Result, exts []Extractor) map[string][]string {
+	if len(exts) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(exts))
+	for _, e := range exts {
+		seen := make(map[string]struct{})
+		var vals []string
+		for _, v := range e.Extract(res) {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if _, dup := seen[v]; dup {
+				continue
+			}
+			seen[v] = struct{}{}
+			vals = append(vals, v)
+		}
+		if len(vals) > 0 {
+			out[e.Name()] = vals
+		}
+	}
+	return out
+}
+
+// Built-in extractors.
+var (
+	urlRe     = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+	mentionRe = regexp.MustCompile(`(?:^|[\s(])@([\p{L}\p{N}_][\p{L}\p{N}_.-]*)`)
+	isbnRe    = regexp.MustCompile(`\bISBN(?:-1[03])?:?\s*(\d[\d -]{8,15}[\dX])\b`)
+)
+
+func init() {
+	RegisterExtractor(FuncExtractor{Key: "urls", Fn: extractURLs})
+	RegisterExtractor(FuncExtractor{Key: "mentions", Fn: extractMentions})
+	RegisterExtractor(FuncExtractor{Key: "isbn", Fn: extractISBNs})
+}
+
+// extractURLs returns the http(s) URLs in the body, without trailing
+// punctuation.
+func extractURLs(res *Result) []string {
+	var out []string
+	for _, u := range urlRe.FindAllString(res.Body, -1) {
+		out = append(out, strings.TrimRight(u, ".,;:!?"))
+	}
+	return out
+}
+
+// URLs returns the distinct http(s) URLs in the body, in order of first
+// appearance, as the "urls" extractor finds them.
+func URLs(res *Result) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, u := range extractURLs(res) {
+		if !seen[u] {
+			seen[u] = true
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// extractMentions returns @name mentions in the body, lowercased. E-mail
+// addresses are not mentions since the @ must follow whitespace.
+func extractMentions(res *Result) []string {
+	var out []string
+	for _, m := range mentionRe.FindAllStringSubmatch(res.Body, -1) {
+		out = append(out, strings.ToLower(strings.TrimRight(m[1], ".-")))
+	}
+	return out
+}
+
+// extractISBNs returns ISBN-10/13 numbers written as "ISBN ..." in the body,
+// normalized to digits, keeping those with a valid check digit.
+func extractISBNs(res *Result) []string {
+	var out []string
+	for _, m := range isbnRe.FindAllStringSubmatch(res.Body, -1) {
+		isbn := strings.NewReplacer("-", "", " ", "").Replace(m[1])
+		if validISBN(isbn) {
+			out = append(out, isbn)
+		}
+	}
+	return out
+}
+
+// validISBN checks the length and check digit of a normalized ISBN.
+func validISBN(s string) bool {
+	switch len(s) {
+	case 10:
+		sum := 0
+		for i, c := range s {
+			d := int(c - '0')
+			if c == 'X' && i == 9 {
+				d = 10
+			} else if c < '0' || c > '9' {
+				return false
+			}
+			sum += (10 - i) * d
+		}
+		return sum%11 == 0
+	case 13:
+		sum := 0
+		for i, c := range s {
+			if c < '0' || c > '9' {
+				return false
+			}
+			d := int(c - '0')
+			if i%2 == 1 {
+				d *= 3
+			}
+			sum += d
+		}
+		return sum%10 == 0
+	}
+	return false
+}
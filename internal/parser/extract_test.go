@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse_BuiltinExtractors(t *testing.T) {
+	var exts []Extractor
+	for _, name := range []string{"urls", "mentions", "isbn"} {
+		e, err := LookupExtractor(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exts = append(exts, e)
+	}
+	input := []byte("---\ntitle: Reading\n---\n" +
+		"Ask @Alice and (@bob.smith) about https://example.com/a?b=1.\n" +
+		"Mail alice@example.com, see https://example.com/a?b=1 again.\n" +
+		"ISBN 978-0-306-40615-7 and ISBN-10: 0-306-40615-2; not ISBN 978-0-306-40615-8.\n")
+	r, err := Parse(input, WithExtractors(exts...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"urls":     {"https://example.com/a?b=1"},
+		"mentions": {"alice", "bob.smith"},
+		"isbn":     {"9780306406157", "0306406152"},
+	}
+	for k, v := range want {
+		if !slices.Equal(r.Metadata[k], v) {
+			t.Errorf("%s = %q, want %q", k, r.Metadata[k], v)
+		}
+	}
+}
+
+func TestParse_CustomExtractor(t *testing.T) {
+	owner := FuncExtractor{Key: "owner", Fn: func(res *Result) []string {
+		s, _ := res.Frontmatter["owner"].(string)
+		return []string{s}
+	}}
+	r, err := Parse([]byte("---\nowner: ops\n---\nbody\n"), WithExtractors(owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(r.Metadata["owner"], []string{"ops"}) {
+		t.Errorf("owner = %q", r.Metadata["owner"])
+	}
+
+	r, err = Parse([]byte("no frontmatter\n"), WithExtractors(owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Metadata["owner"]; ok {
+		t.Errorf("empty values should be dropped: %v", r.Metadata)
+	}
+}
+
+func TestRegisterExtractor(t *testing.T) {
+	if _, err := LookupExtractor("nope"); err == nil {
+		t.Error("unknown extractor should fail")
+	}
+	if !slices.Contains(ExtractorNames(), "urls") {
+		t.Errorf("names = %v", ExtractorNames())
+	}
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "twice") {
+			t.Errorf("duplicate register: recover = %v", r)
+		}
+	}()
+	RegisterExtractor(FuncExtractor{Key: "urls", Fn: extractURLs})
+}
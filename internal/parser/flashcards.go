@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// Card is a flashcard: a "Q: ... / A: ..." block, or a heading tagged
+// #flashcard whose section is the answer.
+type Card struct {
+	// ID identifies the card within its note; it is derived from the
+	// question, so editing the question makes a new card.
+	ID       string
+	Question string
+	// Answer is the answer's Markdown, lines joined with "\n".
+	Answer string
+	// Line is the 1-based line of the question.
+	Line int
+}
+
+var (
+	cardTagRe  = regexp.MustCompile(`(?:^|\s)#flashcards?\b`)
+	questionRe = regexp.MustCompile(`^[ \t]*Q:[ \t]*(.*)$`)
+	answerRe   = regexp.MustCompile(`^[ \t]*A:[ \t]*(.*)$`)
+)
+
+// CardID returns the ID of a card with the given question: a short hash of
+// its whitespace-normalized text.
+func CardID(question string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(question), " ")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// extractCards returns the flashcards of body, which starts at byte offset
+// base of data, in document order. A Q/A block is a line starting with "Q:",
+// optionally continued on the following lines, then a line starting with
+// "A:"; the answer runs to the next blank line, "Q:" line, or heading. A
+// heading tagged #flashcard asks its text without the tag, answered by the
+// section under it up to the next heading of the same or a higher level.
+// Markers inside fenced code are ignored, and cards without a question or
+// answer, or repeating an earlier question, are dropped.
+func extractCards(data []byte, body string) []Card {
+	base := len(data) - len(body)
+	first := 1 + strings.Count(string(data[:base]), "\n")
+
+	var out []Card
+	seen := make(map[string]struct{})
+	add := func(q []string, a []string, line int) {
+		question := strings.TrimSpace(strings.Join(q, "\n"))
+		answer := strings.TrimSpace(strings.Join(a, "\n"))
+		if question == "" || answer == "" {
+			return
+		}
+		id := CardID(question)
+		if _, dup := seen[id]; dup {
+			return
+		}
+		seen[id] = struct{}{}
+		out = append(out, Card{ID: id, Question: question, Answer: answer, Line: line})
+	}
+
+	const (
+		none = iota
+		inQuestion
+		inAnswer
+	)
+	state := none
+	var q, a []string
+	qLine := 0
+	flushQA := func() {
+		if state == inAnswer {
+			add(q, a, qLine)
+		}
+		state, q, a = none, nil, nil
+	}
+
+	// Open #flashcard section.
+	secLevel, secLine := 0, 0
+	var secQ string
+	var secA []string
+	flushSection := func() {
+		if secLevel > 0 {
+			add([]string{secQ}, secA, secLine)
+		}
+		secLevel, secA = 0, nil
+	}
+
+	fence := ""
+	for i, l := range strings.Split(body, "\n") {
+		l = strings.TrimRight(l, "\r")
+		line := first + i
+		trimmed := strings.TrimSpace(l)
+
+		if fence != "" || strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			switch {
+			case fence != "" && strings.HasPrefix(trimmed, fence):
+				fence = ""
+			case fence == "":
+				fence = trimmed[:3]
+			}
+			// Code belongs to whatever answer is open.
+			if state == inAnswer {
+				a = append(a, l)
+			} else if state == inQuestion {
+				state, q = none, nil
+			}
+			if secLevel > 0 {
+				secA = append(secA, l)
+			}
+			continue
+		}
+
+		indented := strings.TrimLeft(l, " ")
+		if level, text, ok := atxHeading(indented); ok && len(l)-len(indented) < 4 {
+			flushQA()
+			if secLevel > 0 && level <= secLevel {
+				flushSection()
+			}
+			if secLevel == 0 && cardTagRe.MatchString(text) {
+				secLevel, secLine = level, line
+				secQ = strings.TrimSpace(cardTagRe.ReplaceAllString(text, ""))
+				continue
+			}
+			if secLevel > 0 {
+				secA = append(secA, l)
+			}
+			continue
+		}
+		if secLevel > 0 {
+			secA = append(secA, l)
+		}
+
+		switch {
+		case questionRe.MatchString(l):
+			flushQA()
+			state, qLine = inQuestion, line
+			q = []string{questionRe.FindStringSubmatch(l)[1]}
+		case state == inQuestion && answerRe.MatchString(l):
+			state = inAnswer
+			a = []string{answerRe.FindStringSubmatch(l)[1]}
+		case trimmed == "":
+			flushQA()
+		case state == inQuestion:
+			q = append(q, trimmed)
+		case state == inAnswer:
+			a = append(a, l)
+		}
+	}
+	flushQA()
+	flushSection()
+	return out
+}
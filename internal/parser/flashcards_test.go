@@ -0,0 +1,34 @@
+package parser
+
+import "testing"
+
+func TestExtractCards(t *testing.T) {
+	data := "---\ntitle: Go\n---\n# Go\n\n" +
+		"Q: What does defer do?\nA: Runs a call when the\nfunction returns.\n\n" +
+		"Q: Zero value\nof a map?\nA: nil\n" +
+		"Q: No answer\n\n" +
+		"```\nQ: in code\nA: ignored\n```\n" +
+		"## Goroutines #flashcard\n\nLightweight threads.\n\n```go\ngo f()\n```\n### Detail\nMultiplexed onto OS threads.\n" +
+		"## Channels\nQ: What does defer do?\nA: duplicate\n"
+	got := extractCards([]byte(data), data[len("---\ntitle: Go\n---\n"):])
+	want := []Card{
+		{Question: "What does defer do?", Answer: "Runs a call when the\nfunction returns.", Line: 6},
+		{Question: "Zero value\nof a map?", Answer: "nil", Line: 10},
+		{Question: "Goroutines", Answer: "Lightweight threads.\n\n```go\ngo f()\n```\n### Detail\nMultiplexed onto OS threads.", Line: 19},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d cards: %+v", len(got), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Question != w.Question || g.Answer != w.Answer || g.Line != w.Line {
+			t.Errorf("card %d = %+v, want %+v", i, g, w)
+		}
+		if g.ID != CardID(w.Question) || len(g.ID) != 12 {
+			t.Errorf("card %d id = %q", i, g.ID)
+		}
+	}
+	if CardID("What  does\ndefer do?") != got[0].ID {
+		t.Error("CardID should ignore whitespace differences")
+	}
+}
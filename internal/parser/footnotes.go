@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Footnote is a Markdown footnote: a "[^label]: text" definition and the
+// "[^label]" references to it.
+type Footnote struct {
+	Label string `json:"label" validate:"required"`
+	// Number is the footnote's position in rendered output, in order of
+	// first reference (1-based); 0 when it is never referenced.
+	Number int `json:"number"`
+	// Text is the definition with continuation lines joined; empty when the
+	// label is referenced but not defined.
+	Text string `json:"text"`
+	// Line is the 1-based line of the definition; 0 when undefined.
+	Line int `json:"line"`
+	// Refs are the 1-based lines referencing the footnote.
+	Refs []int `json:"refs"`
+}
+
+var (
+	footnoteDefRe = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:[ \t]?(.*)$`)
+	footnoteRefRe = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+	codeSpanRe    = regexp.MustCompile("`[^`\n]*`")
+)
+
+// extractFootnotes returns the footnotes of body, which starts at byte offset
+// base of data: referenced ones in order of first reference, then those only
+// defined. Fenced code and inline code spans are skipped.
+func extractFootnotes(data []byte, body string) []Footnote {
+	base := len(data) - len(body)
+	line := 1 + strings.Count(string(data[:base]), "\n")
+
+	byLabel := make(map[string]*Footnote)
+	var order []string
+	get := func(label string) *Footnote {
+		key := strings.ToLower(label)
+		if f, ok := byLabel[key]; ok {
+			return f
+		}
+		f := &Footnote{Label: label, Refs: []int{}}
+		byLabel[key] = f
+		order = append(order, key)
+		return f
+	}
+
+	var def *Footnote // definition taking continuation lines
+	var text []string
+	flush := func() {
+		if def != nil {
+			def.Text = strings.TrimSpace(strings.Join(text, " "))
+		}
+		def, text = nil, nil
+	}
+
+	fence := ""
+	blank := false
+	for _, l := range strings.Split(body, "\n") {
+		l = strings.TrimRight(l, "\r")
+		trimmed := strings.TrimSpace(l)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			fence = "```"
+		case strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence = "~~~"
+		case def != nil && trimmed != "" && (strings.HasPrefix(l, "    ") || strings.HasPrefix(l, "\t") ||
+			!blank && !strings.ContainsAny(trimmed[:1], "#>-*+|")):
+			// Continuation: indented, or a lazy paragraph line directly after
+			// the definition.
+			if m := footnoteDefRe.FindStringSubmatch(l); m == nil {
+				text = append(text, trimmed)
+				addFootnoteRefs(get, l, line)
+				break
+			}
+			fallthrough
+		default:
+			if m := footnoteDefRe.FindStringSubmatch(l); m != nil {
+				flush()
+				f := get(m[1])
+				if f.Line == 0 {
+					def, f.Line = f, line
+					text = []string{m[2]}
+				}
+				addFootnoteRefs(get, m[2], line)
+				break
+			}
+			if trimmed != "" {
+				flush()
+				addFootnoteRefs(get, l, line)
+			}
+		}
+		blank = trimmed == ""
+		line++
+	}
+	flush()
+
+	var out []Footnote
+	n := 0
+	for _, key := range order {
+		if f := byLabel[key]; len(f.Refs) > 0 {
+			n++
+			f.Number = n
+			out = append(out, *f)
+		}
+	}
+	for _, key := range order {
+		if f := byLabel[key]; len(f.Refs) == 0 {
+			out = append(out, *f)
+		}
+	}
+	return out
+}
+
+// addFootnoteRefs records the footnote references on line l outside inline
+// code.
+func addFootnoteRefs(get func(string) *Footnote, l string, line int) {
+	l = codeSpanRe.ReplaceAllStringFunc(l, func(s string) string { return strings.Repeat(" ", len(s)) })
+	for _, m := range footnoteRefRe.FindAllStringSubmatch(l, -1) {
+		f := get(m[1])
+		if n := len(f.Refs); n == 0 || f.Refs[n-1] != line {
+			f.Refs = append(f.Refs, line)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse_Footnotes(t *testing.T) {
+	data := []byte("---\ntitle: Essay\n---\n" +
+		"Claim one.[^b] Claim two.[^a]\n" +
+		"\n" +
+		"Again[^b] and `[^code]`.\n" +
+		"\n" +
+		"[^a]: First source,\n" +
+		"    continued here.\n" +
+		"[^b]: Second source with [[link]].\n" +
+		"[^unused]: Never cited.\n" +
+		"\n" +
+		"```\n[^x]: in code\n```\n" +
+		"See [^missing].\n")
+	r, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Footnote{
+		{Label: "b", Number: 1, Text: "Second source with [[link]].", Line: 10, Refs: []int{4, 6}},
+		{Label: "a", Number: 2, Text: "First source, continued here.", Line: 8, Refs: []int{4}},
+		{Label: "missing", Number: 3, Refs: []int{16}},
+		{Label: "unused", Text: "Never cited.", Line: 11, Refs: []int{}},
+	}
+	if !reflect.DeepEqual(r.Footnotes, want) {
+		t.Errorf("footnotes =\n%+v\nwant\n%+v", r.Footnotes, want)
+	}
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+)
+
+// frontmatterBounds locates the YAML frontmatter block. It returns the byte
+// offsets of the first line after the opening delimiter and of the closing
+// delimiter line. ok is false when data has no (well-formed) frontmatter.
+func frontmatterBounds(data []byte) (start, end int, ok bool) {
+	const delim = "---"
+	lead := len(data) - len(bytes.TrimLeft(data, "\n\r"))
+	if !bytes.HasPrefix(data[lead:], []byte(delim)) {
+		return 0, 0, false
+	}
+	nl := bytes.IndexByte(data[lead:], '\n')
+	if nl < 0 {
+		return 0, 0, false
+	}
+	start = lead + nl + 1
+	if bytes.HasPrefix(data[start:], []byte(delim)) {
+		return start, start, true
+	}
+	idx := bytes.Index(data[start:], []byte("\n"+delim))
+	if idx < 0 {
+		return 0, 0, false
+	}
+	return start, start + idx + 1, true
+}
+
+// fieldLine reports whether line sets the top-level frontmatter key.
+func fieldLine(line, key string) bool {
+	return strings.HasPrefix(line, key+":")
+}
+
+// isContinuation reports whether line belongs to the value of the preceding
+// key (an indented line or a block-sequence item).
+func isContinuation(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "- ")
+}
+
+// BodyStart returns the offset of the line after the closing frontmatter
+// delimiter, or 0 when data has no frontmatter.
+func BodyStart(data []byte) int {
+	_, end, ok := frontmatterBounds(data)
+	if !ok {
+		return 0
+	}
+	if nl := bytes.IndexByte(data[end:], '\n'); nl >= 0 {
+		return end + nl + 1
+	}
+	return len(data)
+}
+
+// SetFrontmatterField sets a top-level scalar frontmatter field, replacing an
+// existing "key:" line (and any indented continuation lines) or inserting it
+// as the first field. A frontmatter block is created when data has none.
+// value is written verbatim and must already be valid YAML.
+func SetFrontmatterField(data []byte, key, value string) []byte {
+	line := key + ": " + value + "\n"
+	start, end, ok := frontmatterBounds(data)
+	if !ok {
+		return append([]byte("---\n"+line+"---\n"), data...)
+	}
+	block := string(data[start:end])
+	lines := strings.SplitAfter(block, "\n")
+	var out strings.Builder
+	replaced := false
+	skipping := false
+	for _, l := range lines {
+		if skipping {
+			if isContinuation(l) {
+				continue
+			}
+			skipping = false
+		}
+		if !replaced && fieldLine(l, key) {
+			out.WriteString(line)
+			replaced = true
+			skipping = true
+			continue
+		}
+		out.WriteString(l)
+	}
+	newBlock := out.String()
+	if !replaced {
+		newBlock = line + newBlock
+	}
+	res := make([]byte, 0, len(data)+len(line))
+	res = append(res, data[:start]...)
+	res = append(res, newBlock...)
+	res = append(res, data[end:]...)
+	return res
+}
+
+// RemoveFrontmatterFields deletes the given top-level keys (and their
+// indented continuation lines) from the frontmatter. Data without
+// frontmatter is returned unchanged.
+func RemoveFrontmatterFields(data []byte, keys ...string) []byte {
+	start, end, ok := frontmatterBounds(data)
+	if !ok || len(keys) == 0 {
+		return data
+	}
+	lines := strings.SplitAfter(string(data[start:end]), "\n")
+	var out strings.Builder
+	skipping := false
+	for _, l := range lines {
+		if skipping {
+			if isContinuation(l) {
+				continue
+			}
+			skipping = false
+		}
+		drop := false
+		for _, k := range keys {
+			if fieldLine(l, k) {
+				drop = true
+				break
+			}
+		}
+		if drop {
+			skipping = true
+			continue
+		}
+		out.WriteString(l)
+	}
+	res := make([]byte, 0, len(data))
+	res = append(res, data[:start]...)
+	res = append(res, out.String()...)
+	res = append(res, data[end:]...)
+	return res
+}
@@ -0,0 +1,43 @@
+package parser
+
+import "testing"
+
+func TestSetFrontmatterField(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"no frontmatter", "# Title\n", "---\nid: x\n---\n# Title\n"},
+		{"insert", "---\ntitle: T\n---\nBody\n", "---\nid: x\ntitle: T\n---\nBody\n"},
+		{"replace", "---\ntitle: T\nid: old\n---\nBody\n", "---\ntitle: T\nid: x\n---\nBody\n"},
+		{"replace list", "---\nid:\n  - a\n  - b\ntitle: T\n---\n", "---\nid: x\ntitle: T\n---\n"},
+		{"empty block", "---\n---\nBody\n", "---\nid: x\n---\nBody\n"},
+	}
+	for _, c := range cases {
+		got := string(SetFrontmatterField([]byte(c.in), "id", "x"))
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRemoveFrontmatterFields(t *testing.T) {
+	in := "---\ntitle: T\ncreated: 2025-01-01\ntags:\n  - a\nid: x\n---\nBody\n"
+	got := string(RemoveFrontmatterFields([]byte(in), "created", "tags"))
+	want := "---\ntitle: T\nid: x\n---\nBody\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := string(RemoveFrontmatterFields([]byte("Body\n"), "id")); got != "Body\n" {
+		t.Errorf("no frontmatter: got %q", got)
+	}
+}
+
+func TestParse_ID(t *testing.T) {
+	r, err := Parse([]byte("---\nid: 0b5c7f1e-1111-4222-8333-944455556666\n---\nBody\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "0b5c7f1e-1111-4222-8333-944455556666" {
+		t.Errorf("id = %q", r.ID)
+	}
+}
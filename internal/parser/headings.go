@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Heading is an ATX heading ("## Title") with byte offsets into the parsed
+// data, so callers can edit a note section by section.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	// Start is the offset of the heading line, End that of the line after it.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// SectionEnd is the offset of the next heading of the same or a higher
+	// level, or len(data).
+	SectionEnd int `json:"section_end"`
+}
+
+// Headings returns the ATX headings of data in document order, skipping the
+// frontmatter and fenced code blocks.
+func Headings(data []byte) []Heading {
+	return headingsFrom(data, BodyStart(data))
+}
+
+// headingsFrom returns the ATX headings of data from offset pos on.
+func headingsFrom(data []byte, pos int) []Heading {
+	var out []Heading
+	fence := ""
+	for pos < len(data) {
+		next := len(data)
+		if nl := bytes.IndexByte(data[pos:], '\n'); nl >= 0 {
+			next = pos + nl + 1
+		}
+		line := strings.TrimRight(string(data[pos:next]), "\r\n")
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"):
+			fence = "```"
+		case strings.HasPrefix(trimmed, "~~~"):
+			fence = "~~~"
+		case len(line)-len(trimmed) < 4:
+			if level, text, ok := atxHeading(trimmed); ok {
+				out = append(out, Heading{Level: level, Text: text, Start: pos, End: next})
+			}
+		}
+		pos = next
+	}
+
+	for i := range out {
+		out[i].SectionEnd = len(data)
+		for _, h := range out[i+1:] {
+			if h.Level <= out[i].Level {
+				out[i].SectionEnd = h.Start
+				break
+			}
+		}
+	}
+	return out
+}
+
+// atxHeading parses "#... text #..." into its level and text.
+func atxHeading(line string) (int, string, bool) {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+	text := strings.TrimSpace(rest)
+	// Optional closing sequence: "## Title ##".
+	if t := strings.TrimRight(text, "#"); t != text && (t == "" || strings.HasSuffix(t, " ")) {
+		text = strings.TrimSpace(t)
+	}
+	return level, text, true
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeadings(t *testing.T) {
+	data := "---\ntitle: '# not a heading'\n---\n# Title\n\n## Inbox ##\n- a\n\n```\n## in code\n```\n### Sub\n## Done\n#nospace\n"
+	got := Headings([]byte(data))
+	want := []struct {
+		level int
+		text  string
+	}{{1, "Title"}, {2, "Inbox"}, {3, "Sub"}, {2, "Done"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d headings: %+v", len(got), got)
+	}
+	for i, w := range want {
+		if got[i].Level != w.level || got[i].Text != w.text {
+			t.Errorf("heading %d = %d %q, want %d %q", i, got[i].Level, got[i].Text, w.level, w.text)
+		}
+		if line := data[got[i].Start:got[i].End]; line[0] != '#' || line[len(line)-1] != '\n' {
+			t.Errorf("heading %d offsets point at %q", i, line)
+		}
+	}
+	if got[0].SectionEnd != len(data) {
+		t.Errorf("h1 section ends at %d, want %d", got[0].SectionEnd, len(data))
+	}
+	if got[1].SectionEnd != got[3].Start {
+		t.Errorf("Inbox section ends at %d, want %d", got[1].SectionEnd, got[3].Start)
+	}
+}
+
+func TestSections(t *testing.T) {
+	data := "---\ntitle: T\n---\nIntro [[x]].\n\n# Setup\nInstall it.\n\n## Notes\n```\n# not a heading\n```\n## Notes\n"
+	res, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Section{
+		{Level: 0, Line: 4, Body: "Intro [[x]]."},
+		{Level: 1, Heading: "Setup", Anchor: "setup", Line: 6, Body: "Install it."},
+		{Level: 2, Heading: "Notes", Anchor: "notes", Line: 9, Body: "```\n# not a heading\n```"},
+		{Level: 2, Heading: "Notes", Anchor: "notes-1", Line: 13},
+	}
+	if len(res.Sections) != len(want) {
+		t.Fatalf("got %d sections: %+v", len(res.Sections), res.Sections)
+	}
+	for i, w := range want {
+		got := res.Sections[i]
+		w.Start, w.End = got.Start, got.End
+		if got != w {
+			t.Errorf("section %d = %+v, want %+v", i, got, w)
+		}
+		if !strings.Contains(res.Body[got.Start:got.End], got.Body) {
+			t.Errorf("section %d offsets point at %q", i, res.Body[got.Start:got.End])
+		}
+	}
+	if res.Sections[3].End != len(res.Body) {
+		t.Errorf("last section ends at %d, want %d", res.Sections[3].End, len(res.Body))
+	}
+}
@@ -0,0 +1,107 @@
+package parser
+
+import "strings"
+
+// maskMath returns s with the ASCII bytes of $inline$ and $$display$$ math
+// blanked to spaces, so wikilinks and tags inside formulas (e.g. the matrix
+// $[[1,2],[3,4]]$ or $\#S$) are not extracted. Non-ASCII bytes are kept,
+// so byte offsets and character columns stay valid. Fenced code and inline
+// code spans are left alone.
+func maskMath(s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	b := []byte(s)
+	fence := ""
+	block := false
+	for start := 0; start < len(b); {
+		end := len(b)
+		if nl := strings.IndexByte(s[start:], '\n'); nl >= 0 {
+			end = start + nl
+		}
+		trimmed := strings.TrimSpace(s[start:end])
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case block:
+			if i := strings.Index(s[start:end], "$$"); i >= 0 {
+				blankASCII(b, start, start+i+2)
+				block = maskInlineMath(b, s, start+i+2, end)
+			} else {
+				blankASCII(b, start, end)
+			}
+		case strings.HasPrefix(trimmed, "```"):
+			fence = "```"
+		case strings.HasPrefix(trimmed, "~~~"):
+			fence = "~~~"
+		default:
+			block = maskInlineMath(b, s, start, end)
+		}
+		start = end + 1
+	}
+	return string(b)
+}
+
+// maskInlineMath blanks the math spans in s[start:end], one line, reporting
+// whether a $$ display block opens without closing on it.
+func maskInlineMath(b []byte, s string, start, end int) bool {
+	for i := start; i < end; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			n := 1
+			for i+n < end && s[i+n] == '`' {
+				n++
+			}
+			if j := strings.Index(s[i+n:end], s[i:i+n]); j >= 0 {
+				i += n + j + n - 1
+			} else {
+				i += n - 1
+			}
+		case '$':
+			if i+1 < end && s[i+1] == '$' {
+				j := strings.Index(s[i+2:end], "$$")
+				if j < 0 {
+					blankASCII(b, i, end)
+					return true
+				}
+				blankASCII(b, i, i+2+j+2)
+				i += 2 + j + 1
+				continue
+			}
+			// Pandoc's rule: the opening $ is followed by a non-space, the
+			// closing $ preceded by a non-space and not followed by a digit,
+			// so prices like "$5 and $10" are not math.
+			if i+1 >= end || s[i+1] == ' ' || s[i+1] == '\t' {
+				continue
+			}
+			for j := i + 1; j < end; j++ {
+				if s[j] == '\\' {
+					j++
+					continue
+				}
+				if s[j] != '$' {
+					continue
+				}
+				if s[j-1] != ' ' && s[j-1] != '\t' && (j+1 >= end || s[j+1] < '0' || s[j+1] > '9') {
+					blankASCII(b, i, j+1)
+					i = j
+				}
+				break
+			}
+		}
+	}
+	return false
+}
+
+// blankASCII replaces the ASCII bytes of b[from:to] with spaces.
+func blankASCII(b []byte, from, to int) {
+	for i := from; i < to && i < len(b); i++ {
+		if b[i] < 0x80 && b[i] != '\n' {
+			b[i] = ' '
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse_IgnoresMath(t *testing.T) {
+	data := []byte("---\ntitle: M\n---\n" +
+		"Matrix $[[1,2],[3,4]]$ then [[real]] #tag\n" +
+		"Set size $\\#S = n$ and cost $5 or $10 #money\n" +
+		"$$\n" +
+		"A = [[a]] #nottag\n" +
+		"$$\n" +
+		"Inline display $$x = [[y]]$$ and `$[[code]]$` after ünï $[[z]]$ [[après]]\n" +
+		"```\n$[[fenced]]$\n```\n")
+	r, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Code is not math, so its links still count.
+	if want := []string{"real", "code", "après", "fenced"}; !reflect.DeepEqual(r.Links, want) {
+		t.Errorf("links = %q, want %q", r.Links, want)
+	}
+	if want := []string{"tag", "money"}; !reflect.DeepEqual(r.Tags, want) {
+		t.Errorf("tags = %q, want %q", r.Tags, want)
+	}
+	for _, ref := range r.LinkRefs {
+		if ref.Target == "après" && (ref.Line != 9 || ref.Column != 65) {
+			t.Errorf("après at %d:%d, want 9:65", ref.Line, ref.Column)
+		}
+	}
+}
+
+func TestWikilinks_IgnoresMath(t *testing.T) {
+	var got []string
+	for _, w := range Wikilinks([]byte("---\nrelated: \"[[a]]\"\n---\n$[[1,2]]$ [[b]] $$[[c]]$$\n")) {
+		got = append(got, w.Target)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %q, want %q", got, want)
+	}
+}
@@ -5,13 +5,15 @@ import (
 	"bytes"
 	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	wikilinkRe = regexp.MustCompile(`\[\[(.*?)\]\]`)
-	tagRe      = regexp.MustCompile(`(?:^|\s)#([A-Za-z][A-Za-z0-9_/-]*)`)
+	tagRe     = regexp.MustCompile(`(?:^|\s)#([A-Za-z][A-Za-z0-9_/-]*)`)
+	tagNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_/-]*$`)
 )
 
 // Result holds the output of parsing a Markdown file.
@@ -19,28 +21,124 @@ type Result struct {
 	Frontmatter map[string]any
 	Body        string
 	Links       []string
-	Tags        []string
-	Title       string
+	// LinkRefs holds, for each entry of Links, the context of its first
+	// occurrence.
+	LinkRefs []LinkRef
+	// FrontmatterLinks are targets taken from the configured frontmatter
+	// link fields (see WithLinkFields).
+	FrontmatterLinks []string
+	Tags             []string
+	Aliases          []string
+	Title            string
+	// ID is the stable note identifier from the frontmatter "id" field.
+	ID string
+	// Created is the creation date from the frontmatter "created",
+	// "created_at", or "date" field; zero when none is set or parses.
+	Created time.Time
+	// Metadata holds the values found by each extractor passed with
+	// WithExtractors, keyed by extractor name. Extractors that found
+	// nothing are omitted.
+	Metadata map[string][]string
+	// Tasks are the checklist items in the body.
+	Tasks []Task
+	// Reminders are the times in the frontmatter "remind" field.
+	Reminders []time.Time
+	// Footnotes are the body's footnotes, in rendered order.
+	Footnotes []Footnote
+	// Reference is the note's bibliographic record, when its frontmatter
+	// has a cite key or DOI.
+	Reference *Reference
+	// Cards are the body's flashcards, in document order.
+	Cards []Card
+	// Sections are the body's headings with the text under each, in
+	// document order.
+	Sections []Section
+}
+
+// LinkRef is the context of a wikilink in the body.
+type LinkRef struct {
+	Target string
+	// Snippet is the trimmed line containing the link, cut to
+	// maxSnippetRunes.
+	Snippet string
+	// Line and Column locate the link in the file (1-based; Column counts
+	// characters, not bytes).
+	Line   int
+	Column int
+	// Count is how many times the body links to Target.
+	Count int
+}
+
+// maxSnippetRunes caps the length of a link snippet.
+const maxSnippetRunes = 200
+
+// Option configures Parse.
+type Option func(*options)
+
+type options struct {
+	linkFields []string
+	extractors []Extractor
+}
+
+// WithLinkFields makes Parse treat the named frontmatter fields (e.g.
+// "related", "parent", "source") as links. Values may be a single string or
+// a list, written either as bare targets or as [[wikilinks]].
+func WithLinkFields(fields ...string) Option {
+	return func(o *options) {
+		o.linkFields = fields
+	}
+}
+
+// WithExtractors runs the given extractors over the parsed note and stores
+// their output in Result.Metadata.
+func WithExtractors(exts ...Extractor) Option {
+	return func(o *options) {
+		o.extractors = exts
+	}
 }
 
 // Parse extracts frontmatter, body, wikilinks, and tags from raw Markdown bytes.
-func Parse(data []byte) (*Result, error) {
+func Parse(data []byte, opts ...Option) (*Result, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	fm, body, err := splitFrontmatter(data)
 	if err != nil {
 		return nil, err
 	}
 
-	links := extractLinks(body)
-	tags := extractTags(body, fm)
+	refs := extractLinks(data, body)
+	links := make([]string, len(refs))
+	for i, r := range refs {
+		links[i] = r.Target
+	}
+	fmLinks := extractFrontmatterLinks(fm, o.linkFields)
+	tags := extractTags(maskMath(body), fm)
+	aliases := extractAliases(fm)
 	title := deriveTitle(fm, body)
 
-	return &Result{
-		Frontmatter: fm,
-		Body:        body,
-		Links:       links,
-		Tags:        tags,
-		Title:       title,
-	}, nil
+	res := &Result{
+		Frontmatter:      fm,
+		Body:             body,
+		Links:            links,
+		LinkRefs:         refs,
+		FrontmatterLinks: fmLinks,
+		Tags:             tags,
+		Aliases:          aliases,
+		Title:            title,
+		ID:               stringField(fm, "id"),
+		Created:          extractCreated(fm),
+		Tasks:            extractTasks(data, body),
+		Reminders:        extractReminders(fm),
+		Footnotes:        extractFootnotes(data, body),
+		Reference:        extractReference(fm, title),
+		Cards:            extractCards(data, body),
+		Sections:         extractSections(data, body),
+	}
+	res.Metadata = runExtractors(res, o.extractors)
+	return res, nil
 }
 
 // splitFrontmatter separates YAML frontmatter (between leading --- delimiters)
@@ -75,27 +173,85 @@ func splitFrontmatter(data []byte) (map[string]any, string, error) {
 	return fm, body, nil
 }
 
-// extractLinks returns deduplicated wikilink targets, normalising aliases.
-func extractLinks(body string) []string {
-	matches := wikilinkRe.FindAllStringSubmatch(body, -1)
-	seen := make(map[string]struct{}, len(matches))
+// extractLinks returns deduplicated wikilink targets, normalising aliases,
+// with the line and position each first occurs at and how often it occurs.
+// body starts at byte offset base of data. Links inside math are skipped.
+func extractLinks(data []byte, body string) []LinkRef {
+	base := len(data) - len(body)
+	seen := make(map[string]int)
+	var out []LinkRef
+	for _, w := range scanWikilinks(string(data[:base])+maskMath(body), base) {
+		if i, ok := seen[w.Target]; ok {
+			out[i].Count++
+			continue
+		}
+		seen[w.Target] = len(out)
+		out = append(out, LinkRef{
+			Target:  w.Target,
+			Snippet: lineAround(body, w.Start-base, w.End-base),
+			Line:    w.Line,
+			Column:  w.Column,
+			Count:   1,
+		})
+	}
+	return out
+}
+
+// lineAround returns the trimmed line of s containing s[start:end], cut to
+// maxSnippetRunes around the match when longer.
+func lineAround(s string, start, end int) string {
+	from := strings.LastIndexByte(s[:start], '\n') + 1
+	to := len(s)
+	if i := strings.IndexByte(s[end:], '\n'); i >= 0 {
+		to = end + i
+	}
+	line := []rune(strings.TrimSpace(s[from:to]))
+	if len(line) <= maxSnippetRunes {
+		return string(line)
+	}
+	// Start the cut shortly before the link.
+	at := utf8.RuneCountInString(strings.TrimLeft(s[from:start], " \t"))
+	lo := max(0, min(at-maxSnippetRunes/4, len(line)-maxSnippetRunes))
+	return strings.TrimSpace(string(line[lo : lo+maxSnippetRunes]))
+}
+
+// extractFrontmatterLinks collects link targets from the given frontmatter
+// fields, stripping [[ ]] and |alias suffixes.
+func extractFrontmatterLinks(fm map[string]any, fields []string) []string {
+	if fm == nil || len(fields) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{})
 	var out []string
-	for _, m := range matches {
-		raw := m[1]
-		// Handle aliases: [[Target|Alias]] → Target.
-		target := raw
-		if i := strings.Index(raw, "|"); i >= 0 {
-			target = raw[:i]
+	add := func(v any) {
+		s, ok := v.(string)
+		if !ok {
+			return
 		}
-		target = strings.TrimSpace(target)
-		if target == "" {
-			continue
+		s = strings.TrimSpace(s)
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "[["), "]]")
+		if i := strings.Index(s, "|"); i >= 0 {
+			s = s[:i]
 		}
-		if _, ok := seen[target]; ok {
-			continue
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return
+		}
+		if _, dup := seen[s]; dup {
+			return
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	for _, field := range fields {
+		switch v := fm[field].(type) {
+		case []any:
+			for _, item := range v {
+				add(item)
+			}
+		default:
+			add(v)
 		}
-		seen[target] = struct{}{}
-		out = append(out, target)
 	}
 	return out
 }
@@ -138,6 +294,91 @@ func extractTags(body string, fm map[string]any) []string {
 	return out
 }
 
+// ValidTag reports whether tag is a valid tag name, as written after the
+// "#" of an inline tag.
+func ValidTag(tag string) bool {
+	return tagNameRe.MatchString(tag)
+}
+
+// RenameInlineTags returns body with every inline #tag replaced by
+// rename(tag); rename returns its argument for tags it leaves alone.
+func RenameInlineTags(body string, rename func(tag string) string) string {
+	return tagRe.ReplaceAllStringFunc(body, func(m string) string {
+		i := strings.IndexByte(m, '#')
+		return m[:i+1] + rename(m[i+1:])
+	})
+}
+
+// extractAliases collects alternate note names from the frontmatter "aliases"
+// field, which may be a YAML list or a single string.
+func extractAliases(fm map[string]any) []string {
+	if fm == nil {
+		return nil
+	}
+	var raw []any
+	switch v := fm["aliases"].(type) {
+	case []any:
+		raw = v
+	case string:
+		raw = []any{v}
+	default:
+		return nil
+	}
+	seen := make(map[string]struct{}, len(raw))
+	var out []string
+	for _, item := range raw {
+		s, ok := item.(string)
+		if !ok {
+			continue
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if _, dup := seen[s]; dup {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
+
+// stringField returns a trimmed string frontmatter value, or "" when the key
+// is missing or not a string.
+func stringField(fm map[string]any, key string) string {
+	if fm == nil {
+		return ""
+	}
+	s, _ := fm[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// createdFields are the frontmatter keys holding a note's creation date, in
+// order of preference.
+var createdFields = []string{"created", "created_at", "date"}
+
+// createdLayouts are the accepted string date formats, besides the YAML
+// timestamps yaml.v3 already decodes to time.Time.
+var createdLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", time.DateTime, time.DateOnly}
+
+// extractCreated returns the first creation date found in createdFields.
+func extractCreated(fm map[string]any) time.Time {
+	for _, k := range createdFields {
+		switch v := fm[k].(type) {
+		case time.Time:
+			return v
+		case string:
+			for _, layout := range createdLayouts {
+				if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
 // deriveTitle returns the frontmatter "title" if present, otherwise the first
 // H1 heading, otherwise empty string.
 func deriveTitle(fm map[string]any, body string) string {
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestParse_FrontmatterAndBody(t *testing.T) {
@@ -49,17 +51,64 @@ func TestParse_InvalidYAMLFallback(t *testing.T) {
 
 func TestExtractLinks_Basic(t *testing.T) {
 	body := "See [[Note A]] and [[Note B|alias]].\nAlso [[Note A]] again."
-	links := extractLinks(body)
+	links := extractLinks([]byte(body), body)
 	if len(links) != 2 {
 		t.Fatalf("len(links) = %d, want 2", len(links))
 	}
-	if links[0] != "Note A" || links[1] != "Note B" {
+	if links[0].Target != "Note A" || links[1].Target != "Note B" {
 		t.Errorf("links = %v", links)
 	}
+	if links[0].Snippet != "See [[Note A]] and [[Note B|alias]]." {
+		t.Errorf("snippet = %q", links[0].Snippet)
+	}
+	if links[0].Count != 2 || links[1].Count != 1 {
+		t.Errorf("counts = %d, %d, want 2, 1", links[0].Count, links[1].Count)
+	}
+}
+
+func TestExtractLinks_Snippet(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	body := "# Title\n\n  - item with [[a]]  \n" + long + "[[b]] " + long
+	links := extractLinks([]byte(body), body)
+	if len(links) != 2 {
+		t.Fatalf("links = %v", links)
+	}
+	if links[0].Snippet != "- item with [[a]]" {
+		t.Errorf("snippet a = %q", links[0].Snippet)
+	}
+	b := links[1].Snippet
+	if !strings.Contains(b, "[[b]]") || utf8.RuneCountInString(b) > maxSnippetRunes {
+		t.Errorf("snippet b (%d runes) = %q", utf8.RuneCountInString(b), b)
+	}
+}
+
+func TestWikilinks_Positions(t *testing.T) {
+	data := []byte("---\nparent: \"[[p]]\"\n---\n# Тема\n\nsee [[ a ]] and [[b#h|x]]\n")
+	links := Wikilinks(data)
+	if len(links) != 3 {
+		t.Fatalf("links = %+v", links)
+	}
+	for _, w := range links {
+		if got := string(data[w.Start:w.End]); got != w.Target {
+			t.Errorf("range %d:%d = %q, want %q", w.Start, w.End, got, w.Target)
+		}
+	}
+	if l := links[0]; l.Line != 2 || l.Column != 10 {
+		t.Errorf("p at %d:%d, want 2:10", l.Line, l.Column)
+	}
+	if l := links[2]; l.Target != "b#h" || l.Line != 6 || l.Column != 17 {
+		t.Errorf("b at %+v, want 6:17", l)
+	}
+
+	r, _ := Parse(data)
+	if len(r.LinkRefs) != 2 || r.LinkRefs[0].Line != 6 || r.LinkRefs[0].Column != 5 {
+		t.Errorf("body link refs = %+v, want a at 6:5", r.LinkRefs)
+	}
 }
 
 func TestExtractLinks_EmptyTarget(t *testing.T) {
-	links := extractLinks("see [[ ]] and [[|alias]]")
+	body := "see [[ ]] and [[|alias]]"
+	links := extractLinks([]byte(body), body)
 	if len(links) != 0 {
 		t.Errorf("expected no links, got %v", links)
 	}
@@ -77,6 +126,23 @@ func TestExtractTags_InlineAndFrontmatter(t *testing.T) {
 	}
 }
 
+func TestRenameInlineTags(t *testing.T) {
+	body := "#alpha at the start, #alpha/beta nested, #alphabet apart, a#alpha in a word."
+	got := RenameInlineTags(body, func(tag string) string {
+		if tag == "alpha" || strings.HasPrefix(tag, "alpha/") {
+			return "omega" + strings.TrimPrefix(tag, "alpha")
+		}
+		return tag
+	})
+	want := "#omega at the start, #omega/beta nested, #alphabet apart, a#alpha in a word."
+	if got != want {
+		t.Errorf("RenameInlineTags = %q, want %q", got, want)
+	}
+	if !ValidTag("a/b-c_1") || ValidTag("1a") || ValidTag("a b") || ValidTag("") {
+		t.Error("ValidTag accepts or rejects the wrong names")
+	}
+}
+
 func TestDeriveTitle_FrontmatterOverH1(t *testing.T) {
 	fm := map[string]any{"title": "FM Title"}
 	body := "# H1 Title\ntext"
@@ -92,3 +158,66 @@ func TestDeriveTitle_H1Fallback(t *testing.T) {
 		t.Errorf("title = %q, want %q", title, "My Heading")
 	}
 }
+
+func TestParse_Aliases(t *testing.T) {
+	input := []byte("---\ntitle: Kubernetes\naliases:\n  - k8s\n  - K8S cluster\n  - k8s\n---\nBody\n")
+	r, err := Parse(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Aliases) != 2 || r.Aliases[0] != "k8s" || r.Aliases[1] != "K8S cluster" {
+		t.Errorf("aliases = %v, want [k8s K8S cluster]", r.Aliases)
+	}
+
+	r, err = Parse([]byte("---\naliases: single\n---\nBody\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Aliases) != 1 || r.Aliases[0] != "single" {
+		t.Errorf("aliases = %v, want [single]", r.Aliases)
+	}
+}
+
+func TestParse_FrontmatterLinks(t *testing.T) {
+	input := []byte("---\nparent: \"[[projects/kenaz|Kenaz]]\"\nrelated:\n  - design-doc\n  - \"[[roadmap]]\"\nsource: 42\n---\nSee [[inline]].\n")
+	r, err := Parse(input, WithLinkFields("parent", "related", "source"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"projects/kenaz", "design-doc", "roadmap"}
+	if len(r.FrontmatterLinks) != len(want) {
+		t.Fatalf("frontmatter links = %v, want %v", r.FrontmatterLinks, want)
+	}
+	for i, w := range want {
+		if r.FrontmatterLinks[i] != w {
+			t.Errorf("frontmatter link[%d] = %q, want %q", i, r.FrontmatterLinks[i], w)
+		}
+	}
+	if len(r.Links) != 1 || r.Links[0] != "inline" {
+		t.Errorf("inline links = %v, want [inline]", r.Links)
+	}
+
+	// Without configured fields nothing is extracted.
+	r, _ = Parse(input)
+	if len(r.FrontmatterLinks) != 0 {
+		t.Errorf("frontmatter links without option = %v, want none", r.FrontmatterLinks)
+	}
+}
+
+func TestParse_Created(t *testing.T) {
+	cases := map[string]string{
+		"created: 2025-01-20\n":                  "2025-01-20",
+		"created_at: \"2025-01-20T10:00:00Z\"\n": "2025-01-20",
+		"date: \"2025-01-20 23:15\"\n":           "2025-01-20",
+		"created: soon\n":                        "0001-01-01",
+	}
+	for fm, want := range cases {
+		res, err := Parse([]byte("---\n" + fm + "---\nBody"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Created.Format("2006-01-02"); got != want {
+			t.Errorf("%q: Created = %s, want %s", fm, got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Reference is the bibliographic record of a reference note: a note whose
+// frontmatter has a cite key ("citekey", "citation_key", or "bibtex_key") or
+// a "doi". Other notes cite it with [[@citekey]].
+type Reference struct {
+	// Key is the cite key; empty when the note only has a DOI, in which case
+	// the index derives one from the file name.
+	Key string `json:"key"`
+	// Type is the BibTeX entry type ("entry_type" or "bibtex_type"), e.g.
+	// article or book; empty when not given.
+	Type      string   `json:"type,omitempty"`
+	Title     string   `json:"title"`
+	Authors   []string `json:"authors,omitempty"`
+	Year      string   `json:"year,omitempty"`
+	DOI       string   `json:"doi,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Journal   string   `json:"journal,omitempty"`
+	Publisher string   `json:"publisher,omitempty"`
+	Volume    string   `json:"volume,omitempty"`
+	Number    string   `json:"number,omitempty"`
+	Pages     string   `json:"pages,omitempty"`
+}
+
+// citeKeyFields are the frontmatter keys holding a cite key, in order of
+// preference.
+var citeKeyFields = []string{"citekey", "citation_key", "bibtex_key"}
+
+// citeKeyRe matches the characters BibTeX allows in a cite key.
+var citeKeyRe = regexp.MustCompile(`^[\p{L}\p{N}_:.+/-]+$`)
+
+// ValidCiteKey reports whether key can be used as a BibTeX cite key.
+func ValidCiteKey(key string) bool {
+	return citeKeyRe.MatchString(key)
+}
+
+// extractReference reads the bibliographic frontmatter fields, returning nil
+// when the note has neither a cite key nor a DOI.
+func extractReference(fm map[string]any, title string) *Reference {
+	if fm == nil {
+		return nil
+	}
+	ref := &Reference{
+		DOI:       normalizeDOI(scalarField(fm, "doi")),
+		Type:      strings.ToLower(firstField(fm, "entry_type", "bibtex_type")),
+		Title:     title,
+		Authors:   listField(fm, "authors", "author"),
+		Year:      scalarField(fm, "year"),
+		URL:       scalarField(fm, "url"),
+		Journal:   firstField(fm, "journal", "container"),
+		Publisher: scalarField(fm, "publisher"),
+		Volume:    scalarField(fm, "volume"),
+		Number:    firstField(fm, "number", "issue"),
+		Pages:     scalarField(fm, "pages"),
+	}
+	for _, f := range citeKeyFields {
+		if k := strings.TrimPrefix(scalarField(fm, f), "@"); ValidCiteKey(k) {
+			ref.Key = k
+			break
+		}
+	}
+	if ref.Key == "" && ref.DOI == "" {
+		return nil
+	}
+	return ref
+}
+
+// normalizeDOI strips resolver prefixes from a DOI, so
+// "https://doi.org/10.1000/x" and "doi:10.1000/x" both become "10.1000/x".
+func normalizeDOI(s string) string {
+	for _, p := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if len(s) > len(p) && strings.EqualFold(s[:len(p)], p) {
+			return s[len(p):]
+		}
+	}
+	return s
+}
+
+// scalarField returns a string, number, or boolean frontmatter value as
+// trimmed text; YAML decodes "year: 2020" as an int.
+func scalarField(fm map[string]any, key string) string {
+	switch v := fm[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case int, int64, float64, bool:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// firstField returns the first non-empty scalarField of keys.
+func firstField(fm map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s := scalarField(fm, k); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// listField returns the values of the first of keys that is set, accepting a
+// list or a single string with entries separated by " and " (BibTeX style)
+// or semicolons.
+func listField(fm map[string]any, keys ...string) []string {
+	for _, k := range keys {
+		var out []string
+		switch v := fm[k].(type) {
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+					out = append(out, strings.TrimSpace(s))
+				}
+			}
+		case string:
+			for _, part := range strings.Split(strings.ReplaceAll(v, " and ", ";"), ";") {
+				if s := strings.TrimSpace(part); s != "" {
+					out = append(out, s)
+				}
+			}
+		}
+		if len(out) > 0 {
+			return out
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse_Reference(t *testing.T) {
+	r, err := Parse([]byte("---\ntitle: Attention Is All You Need\ncitekey: \"@vaswani2017\"\n" +
+		"author: Vaswani, Ashish and Shazeer, Noam\nyear: 2017\ndoi: https://doi.org/10.48550/arXiv.1706.03762\n" +
+		"journal: NeurIPS\n---\nNotes.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Reference{
+		Key:     "vaswani2017",
+		Title:   "Attention Is All You Need",
+		Authors: []string{"Vaswani, Ashish", "Shazeer, Noam"},
+		Year:    "2017",
+		DOI:     "10.48550/arXiv.1706.03762",
+		Journal: "NeurIPS",
+	}
+	if !reflect.DeepEqual(r.Reference, want) {
+		t.Errorf("reference = %+v, want %+v", r.Reference, want)
+	}
+
+	r, _ = Parse([]byte("---\ndoi: 10.1000/xyz\nauthors: [Knuth]\n---\n# TAOCP\n"))
+	if r.Reference == nil || r.Reference.Key != "" || r.Reference.DOI != "10.1000/xyz" || r.Reference.Title != "TAOCP" {
+		t.Errorf("doi only = %+v", r.Reference)
+	}
+
+	r, _ = Parse([]byte("---\ncitekey: \"has space\"\n---\nbody\n"))
+	if r.Reference != nil {
+		t.Errorf("invalid key = %+v, want nil", r.Reference)
+	}
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/starford/kenaz/internal/slug"
+)
+
+// Section is the part of a note's body under one heading, up to the next
+// heading of any level. The text before the first heading, when not blank,
+// is a section with Level 0 and no heading.
+type Section struct {
+	Level   int
+	Heading string
+	// Anchor is the id of the heading in rendered HTML, which "#heading"
+	// fragments of wikilinks target: its slug, with "-1", "-2", ...
+	// appended to repeats in document order.
+	Anchor string
+	// Line is the 1-based line of the heading, or of the body start.
+	Line int
+	// Start and End are byte offsets into the body; End is exclusive.
+	Start int
+	End   int
+	// Body is the section's text below its heading, trimmed.
+	Body string
+}
+
+// extractSections splits body, which ends data, into its sections in
+// document order.
+func extractSections(data []byte, body string) []Section {
+	base := len(data) - len(body)
+	first := 1 + strings.Count(string(data[:base]), "\n")
+
+	var out []Section
+	ids := make(map[string]int)
+	pos, line := 0, first
+	// add appends s, whose text runs from offset text to end.
+	add := func(s Section, text, end int) {
+		line += strings.Count(body[pos:s.Start], "\n")
+		pos = s.Start
+		s.Line, s.End = line, end
+		s.Body = strings.TrimSpace(body[text:end])
+		out = append(out, s)
+	}
+	hs := headingsFrom([]byte(body), 0)
+	end := len(body)
+	if len(hs) > 0 {
+		end = hs[0].Start
+	}
+	if strings.TrimSpace(body[:end]) != "" {
+		add(Section{}, 0, end)
+	}
+	for i, h := range hs {
+		end := len(body)
+		if i+1 < len(hs) {
+			end = hs[i+1].Start
+		}
+		anchor := slug.Make(h.Text)
+		if n := ids[anchor]; n > 0 {
+			ids[anchor] = n + 1
+			anchor += "-" + strconv.Itoa(n)
+		} else {
+			ids[anchor] = 1
+		}
+		add(Section{Level: h.Level, Heading: h.Text, Anchor: anchor, Start: h.Start}, h.End, end)
+	}
+	return out
+}
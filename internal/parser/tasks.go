@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Task is a Markdown checklist item ("- [ ] text").
+type Task struct {
+	Text string
+	Done bool
+	// Due is the date from a "📅 2006-01-02" or "due:2006-01-02" marker, at
+	// midnight local time; zero when the task has none.
+	Due time.Time
+	// Line is the task's 1-based line in the file.
+	Line int
+}
+
+var (
+	taskRe = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*)$`)
+	dueRe  = regexp.MustCompile(`(?:📅|\bdue:)\s*(\d{4}-\d{2}-\d{2})\b`)
+)
+
+// extractTasks returns the checklist items of body, which starts at byte
+// offset base of data, skipping fenced code blocks.
+func extractTasks(data []byte, body string) []Task {
+	base := len(data) - len(body)
+	line := 1 + strings.Count(string(data[:base]), "\n")
+	var out []Task
+	fence := ""
+	for _, l := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(l)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"):
+			fence = "```"
+		case strings.HasPrefix(trimmed, "~~~"):
+			fence = "~~~"
+		default:
+			if m := taskRe.FindStringSubmatch(strings.TrimRight(l, "\r")); m != nil {
+				t := Task{Text: strings.TrimSpace(m[2]), Done: m[1] != " ", Line: line}
+				if d := dueRe.FindStringSubmatch(m[2]); d != nil {
+					t.Due, _ = time.ParseInLocation(time.DateOnly, d[1], time.Local)
+				}
+				out = append(out, t)
+			}
+		}
+		line++
+	}
+	return out
+}
+
+// remindLayouts are the accepted string formats of frontmatter "remind"
+// values, read in local time.
+var remindLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", time.DateTime, time.DateOnly}
+
+// extractReminders returns the times in the frontmatter "remind" field, a
+// single value or a list.
+func extractReminders(fm map[string]any) []time.Time {
+	var vals []any
+	switch v := fm["remind"].(type) {
+	case nil:
+		return nil
+	case []any:
+		vals = v
+	default:
+		vals = []any{v}
+	}
+	var out []time.Time
+	for _, v := range vals {
+		switch v := v.(type) {
+		case time.Time:
+			out = append(out, v)
+		case string:
+			for _, layout := range remindLayouts {
+				if t, err := time.ParseInLocation(layout, strings.TrimSpace(v), time.Local); err == nil {
+					out = append(out, t)
+					break
+				}
+			}
+		}
+	}
+	return out
+}
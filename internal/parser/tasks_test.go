@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_Tasks(t *testing.T) {
+	input := []byte("---\ntitle: Plan\n---\n" +
+		"# Plan\n" +
+		"- [ ] Write spec 📅 2026-10-20\n" +
+		"  * [x] Book room due:2026-10-18\n" +
+		"1. [ ] Call back\n" +
+		"```\n- [ ] not a task\n```\n" +
+		"- [] not a task either\n")
+	r, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Tasks) != 3 {
+		t.Fatalf("tasks = %+v, want 3", r.Tasks)
+	}
+	first := r.Tasks[0]
+	if first.Text != "Write spec 📅 2026-10-20" || first.Done || first.Line != 5 {
+		t.Errorf("first = %+v", first)
+	}
+	if want := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local); !first.Due.Equal(want) {
+		t.Errorf("due = %v, want %v", first.Due, want)
+	}
+	if !r.Tasks[1].Done || r.Tasks[1].Due.IsZero() || r.Tasks[1].Line != 6 {
+		t.Errorf("second = %+v", r.Tasks[1])
+	}
+	if !r.Tasks[2].Due.IsZero() || r.Tasks[2].Text != "Call back" {
+		t.Errorf("third = %+v", r.Tasks[2])
+	}
+}
+
+func TestParse_Reminders(t *testing.T) {
+	r, err := Parse([]byte("---\nremind:\n  - \"2026-10-20 09:30\"\n  - 2026-11-01T08:00:00Z\n  - soon\n---\nbody\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Reminders) != 2 {
+		t.Fatalf("reminders = %v, want 2", r.Reminders)
+	}
+	if want := time.Date(2026, 10, 20, 9, 30, 0, 0, time.Local); !r.Reminders[0].Equal(want) {
+		t.Errorf("first = %v, want %v", r.Reminders[0], want)
+	}
+	if want := time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC); !r.Reminders[1].Equal(want) {
+		t.Errorf("second = %v, want %v", r.Reminders[1], want)
+	}
+
+	r, _ = Parse([]byte("---\nremind: \"2026-10-20\"\n---\n"))
+	if len(r.Reminders) != 1 {
+		t.Errorf("single value: reminders = %v", r.Reminders)
+	}
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var wikilinkRe = regexp.MustCompile(`\[\[(.*?)\]\]`)
+
+// Wikilink is one [[target]] occurrence in a file.
+type Wikilink struct {
+	// Target is the trimmed text before any "|alias", including a
+	// "#heading" suffix.
+	Target string
+	// Start and End are the byte range of Target in the file, so it can be
+	// replaced without touching the rest of the link.
+	Start, End int
+	// Line and Column locate the link's opening brackets (1-based; Column
+	// counts characters, not bytes).
+	Line   int
+	Column int
+}
+
+// Wikilinks returns every wikilink in data, frontmatter included, in file
+// order. Empty targets and links inside $math$ are skipped.
+func Wikilinks(data []byte) []Wikilink {
+	s := string(data)
+	base := 0
+	if _, end, ok := frontmatterBounds(data); ok {
+		base = end
+	}
+	return scanWikilinks(s[:base]+maskMath(s[base:]), 0)
+}
+
+// scanWikilinks returns the wikilinks of s at or after byte offset from.
+func scanWikilinks(s string, from int) []Wikilink {
+	var out []Wikilink
+	line, lineStart := 1+strings.Count(s[:from], "\n"), strings.LastIndexByte(s[:from], '\n')+1
+	for _, m := range wikilinkRe.FindAllStringSubmatchIndex(s[from:], -1) {
+		start, end := from+m[2], from+m[3]
+		if i := strings.IndexByte(s[start:end], '|'); i >= 0 {
+			end = start + i
+		}
+		raw := s[start:end]
+		target := strings.TrimSpace(raw)
+		if target == "" {
+			continue
+		}
+		start += len(raw) - len(strings.TrimLeftFunc(raw, unicode.IsSpace))
+		end = start + len(target)
+
+		open := from + m[0]
+		line += strings.Count(s[lineStart:open], "\n")
+		lineStart = strings.LastIndexByte(s[:open], '\n') + 1
+		out = append(out, Wikilink{
+			Target: target,
+			Start:  start,
+			End:    end,
+			Line:   line,
+			Column: utf8.RuneCountInString(s[lineStart:open]) + 1,
+		})
+	}
+	return out
+}
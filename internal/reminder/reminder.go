@@ -0,0 +1,116 @@
+// Package reminder delivers note and task reminders as they come due.
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/starford/kenaz/internal/index"
+)
+
+// EventType is the SSE event and webhook "event" field of a due reminder.
+const EventType = "reminder.due"
+
+// Run delivers reminders until ctx is cancelled: every interval it calls
+// deliver for each reminder that came due since the previous check. The
+// checkpoint is kept in the index, so reminders that came due while the
+// server was down are delivered on the next start; on the very first start
+// only future reminders are delivered.
+func Run(ctx context.Context, db *index.DB, interval, taskTime time.Duration, logger *slog.Logger, deliver func(index.Reminder)) error {
+	last, err := db.RemindersCheckedAt()
+	if err != nil {
+		return err
+	}
+	if last.IsZero() {
+		last = time.Now()
+		if err := db.SetRemindersCheckedAt(last); err != nil {
+			return err
+		}
+	}
+	logger.Info("reminders: scheduler started", slog.String("interval", interval.String()))
+
+	check := func() {
+		now := time.Now()
+		due, err := db.Reminders(last, now, taskTime)
+		if err != nil {
+			logger.Warn("reminders: query failed", slog.String("error", err.Error()))
+			return
+		}
+		for _, r := range due {
+			logger.Info("reminder due", slog.String("path", r.Path), slog.String("text", r.Text))
+			deliver(r)
+		}
+		if err := db.SetRemindersCheckedAt(now); err != nil {
+			logger.Warn("reminders: checkpoint failed", slog.String("error", err.Error()))
+		}
+		last = now
+	}
+
+	check()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			check()
+		}
+	}
+}
+
+// webhookTimeout bounds a single webhook request.
+const webhookTimeout = 10 * time.Second
+
+// Webhooks posts due reminders as JSON to a list of URLs:
+//
+//	{"event": "reminder.due", "reminder": {"path": ..., "text": ..., "at": ...}}
+//
+// Failed deliveries are logged and not retried.
+type Webhooks struct {
+	URLs   []string
+	Client *http.Client
+	Logger *slog.Logger
+}
+
+// Deliver posts r to every URL.
+func (w *Webhooks) Deliver(r index.Reminder) {
+	body, err := json.Marshal(map[string]any{"event": EventType, "reminder": r})
+	if err != nil {
+		return
+	}
+	for _, u := range w.URLs {
+		if err := w.post(u, body); err != nil {
+			w.Logger.Warn("reminders: webhook failed",
+				slog.String("url", u),
+				slog.String("path", r.Path),
+				slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (w *Webhooks) post(url string, body []byte) error {
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/starford/kenaz/internal/index"
+)
+
+func discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRun_DeliversDueReminders(t *testing.T) {
+	db, err := index.Open(filepath.Join(t.TempDir(), "kenaz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	now := time.Now()
+	row := index.NoteRow{
+		Path:      "a.md",
+		Title:     "Alpha",
+		Checksum:  "x",
+		UpdatedAt: now,
+		// One reminder missed while the server was down, one in the future.
+		Reminders: []time.Time{now.Add(-time.Minute), now.Add(time.Hour)},
+	}
+	if err := db.UpsertNoteLinks(row, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetRemindersCheckedAt(now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var got []index.Reminder
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, db, 10*time.Millisecond, 9*time.Hour, discard(), func(r index.Reminder) {
+			mu.Lock()
+			got = append(got, r)
+			mu.Unlock()
+		})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 || got[0].Path != "a.md" || got[0].Text != "Alpha" {
+		t.Errorf("delivered = %+v, want the missed reminder once", got)
+	}
+	if at, _ := db.RemindersCheckedAt(); at.Before(now.Add(-time.Second)) {
+		t.Errorf("checkpoint not advanced: %v", at)
+	}
+}
+
+func TestWebhooks_Deliver(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	wh := &Webhooks{URLs: []string{srv.URL}, Logger: discard()}
+	wh.Deliver(index.Reminder{Path: "a.md", Text: "Call Bob", Source: index.ReminderTask})
+
+	if body["event"] != EventType {
+		t.Errorf("event = %v", body["event"])
+	}
+	r, _ := body["reminder"].(map[string]any)
+	if r["path"] != "a.md" || r["text"] != "Call Bob" {
+		t.Errorf("reminder = %v", r)
+	}
+}
@@ -0,0 +1,285 @@
+// Package render converts note Markdown to sanitized HTML, so clients and
+// preview integrations without a wikilink-aware renderer can show notes.
+//
+// Markdown is parsed by goldmark with the GFM (tables, task lists,
+// strikethrough, autolinks) and footnote extensions, plus the two
+// extensions of this package: [[wikilinks]] / ![[embeds]] and ==highlight==.
+// Its output always goes through a bluemonday policy, which drops scripts,
+// event handlers, and any URL scheme but http(s), mailto, and relative URLs.
+package render
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/starford/kenaz/internal/slug"
+)
+
+// AttachmentsPrefix is the URL attachments are served under; embeds of
+// files that are not notes (![[diagram.png]]) point there.
+const AttachmentsPrefix = "/attachments/"
+
+// Options supplies what the renderer cannot know about the vault.
+type Options struct {
+	// Link returns the href of the note a wikilink target (without any
+	// "#heading" suffix) resolves to, or "" when it does not resolve.
+	// Nil leaves every wikilink unresolved.
+	Link func(target string) string
+}
+
+// imageExts are the embed extensions rendered as <img>.
+var imageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".svg": true, ".avif": true, ".bmp": true,
+}
+
+// policy is what rendered HTML may keep: bluemonday's user content policy,
+// plus the ids, classes, and checkboxes the renderer itself emits.
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(false)
+	p.RequireNoFollowOnFullyQualifiedLinks(true)
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w:-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w -]+$`)).OnElements("a", "span", "code", "div")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-[a-z]+$`)).OnElements("a", "div")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("style").Matching(regexp.MustCompile(`^text-align:(left|right|center)$`)).OnElements("th", "td")
+	return p
+}
+
+// HTML renders the Markdown body of a note (without frontmatter) to an HTML
+// fragment.
+func HTML(body string, opts Options) string {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote, highlight{}, wikilinks{opts: opts}),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		// Raw HTML is passed on to the sanitizer, which keeps the harmless part.
+		goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+	)
+	ctx := parser.NewContext(parser.WithIDs(&anchors{seen: map[string]int{}}))
+	var b bytes.Buffer
+	// Writing to a bytes.Buffer does not fail, and neither do the node
+	// renderers.
+	_ = md.Convert([]byte(body), &b, parser.WithContext(ctx))
+	return policy.Sanitize(b.String())
+}
+
+// Anchor returns the id of the heading with the given text, as used for
+// "#heading" fragments of wikilinks. Repeated headings get "-1", "-2", ...
+// appended in document order.
+func Anchor(text string) string {
+	return slug.Make(text)
+}
+
+// anchors hands out heading ids the way parser.Sections numbers them.
+type anchors struct {
+	seen map[string]int
+}
+
+func (a *anchors) Generate(value []byte, _ ast.NodeKind) []byte {
+	id := Anchor(string(value))
+	if n := a.seen[id]; n > 0 {
+		a.seen[id] = n + 1
+		id += "-" + strconv.Itoa(n)
+	} else {
+		a.seen[id] = 1
+	}
+	return []byte(id)
+}
+
+func (a *anchors) Put(value []byte) {
+	a.seen[string(value)]++
+}
+
+// kindWikilink is the node kind of [[wikilinks]] and ![[embeds]].
+var kindWikilink = ast.NewNodeKind("Wikilink")
+
+// wikilinkNode holds the raw contents between the brackets.
+type wikilinkNode struct {
+	ast.BaseInline
+	inner string
+	embed bool
+}
+
+func (n *wikilinkNode) Kind() ast.NodeKind { return kindWikilink }
+
+func (n *wikilinkNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Inner": n.inner}, nil)
+}
+
+// wikilinks is the goldmark extension for [[wikilinks]] and ![[embeds]].
+type wikilinks struct {
+	opts Options
+}
+
+func (e wikilinks) Extend(m goldmark.Markdown) {
+	// Ahead of the link parser (200), which would take "[[" as a link.
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(wikilinkParser{}, 199)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(wikilinkRenderer(e), 199)))
+}
+
+type wikilinkParser struct{}
+
+func (wikilinkParser) Trigger() []byte { return []byte{'[', '!'} }
+
+func (wikilinkParser) Parse(_ ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	embed := bytes.HasPrefix(line, []byte("![["))
+	open := 2
+	if embed {
+		open = 3
+	} else if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line[open:], []byte("]]"))
+	if end <= 0 {
+		return nil
+	}
+	block.Advance(open + end + 2)
+	// Inside table cells the alias pipe is written escaped ("[[a\|b]]").
+	inner := strings.ReplaceAll(string(line[open:open+end]), `\|`, "|")
+	return &wikilinkNode{inner: inner, embed: embed}
+}
+
+type wikilinkRenderer wikilinks
+
+func (r wikilinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindWikilink, func(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			l := n.(*wikilinkNode)
+			_, _ = w.WriteString(r.wikilink(l.inner, l.embed))
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// wikilink renders the contents of [[inner]] or ![[inner]]. Targets that
+// resolve to a note link to it; embeds of other files become attachment
+// images or links; the rest are marked as unresolved.
+func (r wikilinkRenderer) wikilink(inner string, embed bool) string {
+	target, label, _ := strings.Cut(inner, "|")
+	target, label = strings.TrimSpace(target), strings.TrimSpace(label)
+	note, heading, _ := strings.Cut(target, "#")
+	note = strings.TrimSpace(note)
+	if label == "" {
+		label = target
+		if note == "" {
+			label = strings.TrimSpace(heading)
+		}
+	}
+
+	if embed {
+		if ext := strings.ToLower(path.Ext(note)); ext != "" && ext != ".md" {
+			src := AttachmentsPrefix + url.PathEscape(path.Base(note))
+			if imageExts[ext] {
+				alt := path.Base(note)
+				if label != target {
+					alt = label
+				}
+				return `<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `">`
+			}
+			return `<a class="embed" href="` + html.EscapeString(src) + `">` + html.EscapeString(label) + "</a>"
+		}
+	}
+
+	href := ""
+	switch {
+	case note == "":
+		href = "#"
+	case r.opts.Link != nil:
+		href = r.opts.Link(note)
+	}
+	if href == "" {
+		return `<span class="wikilink unresolved">` + html.EscapeString(label) + "</span>"
+	}
+	if heading = strings.TrimSpace(heading); heading != "" {
+		href = strings.TrimSuffix(href, "#") + "#" + Anchor(heading)
+	}
+	class := "wikilink"
+	if embed {
+		class += " embed"
+	}
+	return `<a class="` + class + `" href="` + html.EscapeString(href) + `">` + html.EscapeString(label) + "</a>"
+}
+
+// kindHighlight is the node kind of ==highlighted== text.
+var kindHighlight = ast.NewNodeKind("Highlight")
+
+type highlightNode struct {
+	ast.BaseInline
+}
+
+func (n *highlightNode) Kind() ast.NodeKind { return kindHighlight }
+
+func (n *highlightNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// highlight is the goldmark extension for ==highlight==, rendered as <mark>.
+// It mirrors goldmark's strikethrough extension.
+type highlight struct{}
+
+func (highlight) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(highlightParser{}, 500)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(highlightRenderer{}, 500)))
+}
+
+type highlightDelimiter struct{}
+
+func (highlightDelimiter) IsDelimiter(b byte) bool { return b == '=' }
+
+func (highlightDelimiter) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char
+}
+
+func (highlightDelimiter) OnMatch(int) ast.Node { return &highlightNode{} }
+
+type highlightParser struct{}
+
+func (highlightParser) Trigger() []byte { return []byte{'='} }
+
+func (highlightParser) Parse(_ ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 2, highlightDelimiter{})
+	if node == nil || node.OriginalLength != 2 || before == '=' {
+		return nil
+	}
+	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
+	block.Advance(node.OriginalLength)
+	pc.PushDelimiter(node)
+	return node
+}
+
+func (highlightParser) CloseBlock(ast.Node, parser.Context) {}
+
+type highlightRenderer struct{}
+
+func (highlightRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindHighlight, func(w util.BufWriter, _ []byte, _ ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.WriteString("<mark>")
+		} else {
+			_, _ = w.WriteString("</mark>")
+		}
+		return ast.WalkContinue, nil
+	})
+}
@@ -0,0 +1,58 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	link := func(target string) string {
+		if target == "Other note" || target == "other" {
+			return "/api/notes/other.md"
+		}
+		return ""
+	}
+	cases := []struct {
+		name, in, want string
+	}{
+		{"heading", "# Hello *world*\n## Hello world", "<h1 id=\"hello-world\">Hello <em>world</em></h1>\n<h2 id=\"hello-world-1\">Hello world</h2>\n"},
+		{"tag is not a heading", "#tag line", "<p>#tag line</p>\n"},
+		{"paragraph", "one\ntwo  \nthree", "<p>one\ntwo<br>\nthree</p>\n"},
+		{"emphasis", "**bold** and _it_ and ~~gone~~ and ==hi== and snake_case_name", "<p><strong>bold</strong> and <em>it</em> and <del>gone</del> and <mark>hi</mark> and snake_case_name</p>\n"},
+		{"spaced stars", "2 * 3 * 4", "<p>2 * 3 * 4</p>\n"},
+		{"code span", "use `<b>*x*</b>`", "<p>use <code>&lt;b&gt;*x*&lt;/b&gt;</code></p>\n"},
+		{"fence", "```go\nif a < b {}\n```\nafter", "<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n<p>after</p>\n"},
+		{"raw html sanitized", "<script>alert(1)</script>\n\nsay <b onclick=\"x()\">hi</b>", "\n<p>say <b>hi</b></p>\n"},
+		{"link", `[site](https://example.com "Ex")`, "<p><a href=\"https://example.com\" title=\"Ex\" rel=\"nofollow\">site</a></p>\n"},
+		{"unsafe link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"image", "![pic](/attachments/a.png)", "<p><img src=\"/attachments/a.png\" alt=\"pic\"></p>\n"},
+		{"autolink", "<https://example.com/a?b=1&c=2>", "<p><a href=\"https://example.com/a?b=1&amp;c=2\" rel=\"nofollow\">https://example.com/a?b=1&amp;c=2</a></p>\n"},
+		{"wikilink", "see [[Other note|the other]] and [[other#Some Part]]", "<p>see <a class=\"wikilink\" href=\"/api/notes/other.md\">the other</a> and <a class=\"wikilink\" href=\"/api/notes/other.md#some-part\">other#Some Part</a></p>\n"},
+		{"unresolved wikilink", "[[nowhere]]", "<p><span class=\"wikilink unresolved\">nowhere</span></p>\n"},
+		{"same note heading", "[[#Intro]]", "<p><a class=\"wikilink\" href=\"#intro\">Intro</a></p>\n"},
+		{"embed image", "![[diagram.png]]", "<p><img src=\"/attachments/diagram.png\" alt=\"diagram.png\"></p>\n"},
+		{"embed file", "![[report final.pdf]]", "<p><a class=\"embed\" href=\"/attachments/report%20final.pdf\">report final.pdf</a></p>\n"},
+		{"embed note", "![[other]]", "<p><a class=\"wikilink embed\" href=\"/api/notes/other.md\">other</a></p>\n"},
+		{"list", "- a\n- b\n  - c\n- [x] done\n- [ ] todo", "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n<li><input disabled=\"\" type=\"checkbox\"> todo</li>\n</ul>\n"},
+		{"ordered", "3. c\n4. d", "<ol start=\"3\">\n<li>c</li>\n<li>d</li>\n</ol>\n"},
+		{"quote", "> quoted\n> **text**", "<blockquote>\n<p>quoted\n<strong>text</strong></p>\n</blockquote>\n"},
+		{"rule", "a\n\n---\n\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{"table", "| a | b |\n|:--|--:|\n| [[other\\|x]] | `\\|` |", "<table>\n<thead>\n<tr>\n<th style=\"text-align:left\">a</th>\n<th style=\"text-align:right\">b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td style=\"text-align:left\"><a class=\"wikilink\" href=\"/api/notes/other.md\">x</a></td>\n<td style=\"text-align:right\"><code>|</code></td>\n</tr>\n</tbody>\n</table>\n"},
+		{"escape", `\*not em\*`, "<p>*not em*</p>\n"},
+		{"footnote", "claim[^1]\n\n[^1]: source", "<p>claim<sup id=\"fnref:1\"><a href=\"#fn:1\" class=\"footnote-ref\" role=\"doc-noteref\">1</a></sup></p>\n<div class=\"footnotes\" role=\"doc-endnotes\">\n<hr>\n<ol>\n<li id=\"fn:1\">\n<p>source\u00a0<a href=\"#fnref:1\" class=\"footnote-backref\" role=\"doc-backlink\">\u21a9\ufe0e</a></p>\n</li>\n</ol>\n</div>\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := HTML(tc.in, Options{Link: link}); got != tc.want {
+				t.Errorf("HTML(%q)\n got: %q\nwant: %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTML_NilLink(t *testing.T) {
+	got := HTML("[[other]]", Options{})
+	if !strings.Contains(got, `class="wikilink unresolved"`) {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,67 @@
+// Package slug derives contract-compliant note file names (English,
+// kebab-case) from arbitrary titles, transliterating Cyrillic text.
+package slug
+
+import "strings"
+
+// maxLen caps the slug length (in bytes, all ASCII) to keep paths readable.
+const maxLen = 80
+
+// fallback is returned when a title yields no usable characters.
+const fallback = "untitled"
+
+// cyrillic maps lower-case Cyrillic letters (Russian, Ukrainian, Belarusian)
+// to their Latin transliteration.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e",
+	'ё': "yo", 'є': "ye", 'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ў': "u", 'ф': "f", 'х': "kh",
+	'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Transliterate lower-cases s and replaces Cyrillic letters with Latin
+// equivalents. Other characters are kept as-is.
+func Transliterate(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToLower(s) {
+		if lat, ok := cyrillic[r]; ok {
+			b.WriteString(lat)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Make converts a title into a kebab-case slug of ASCII letters and digits.
+// "Нотатки зустрічі 2025" becomes "notatki-zustrichi-2025". Titles without
+// any usable characters yield "untitled".
+func Make(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range Transliterate(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			// Punctuation, whitespace, and characters without a
+			// transliteration are dropped but still separate words.
+			dash = true
+		}
+	}
+	out := b.String()
+	if len(out) > maxLen {
+		out = strings.TrimRight(out[:maxLen], "-")
+	}
+	if out == "" {
+		return fallback
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	cases := map[string]string{
+		"Hello World":             "hello-world",
+		"  Weekly standup: 2025!": "weekly-standup-2025",
+		"Нотатки зустрічі":        "notatki-zustrichi",
+		"Щука и ёж":               "shchuka-i-yozh",
+		"Kenaz — кеназ":           "kenaz-kenaz",
+		"already-kebab_case":      "already-kebab-case",
+		"日本語":                     "untitled",
+		"":                        "untitled",
+	}
+	for in, want := range cases {
+		if got := Make(in); got != want {
+			t.Errorf("Make(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMake_Truncates(t *testing.T) {
+	long := ""
+	for i := 0; i < 30; i++ {
+		long += "word "
+	}
+	got := Make(long)
+	if len(got) > maxLen {
+		t.Errorf("len = %d, want <= %d", len(got), maxLen)
+	}
+	if got[len(got)-1] == '-' {
+		t.Errorf("slug ends with dash: %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+// Package srs schedules flashcard reviews with the SM-2 spaced-repetition
+// algorithm.
+package srs
+
+import "math"
+
+// Grade bounds: 0 is a blackout, 5 a perfect recall. Grades below
+// PassGrade count as lapses and restart the card's intervals.
+const (
+	MinGrade  = 0
+	MaxGrade  = 5
+	PassGrade = 3
+)
+
+// Ease factor bounds. New cards start at DefaultEase; SM-2 never lets it
+// drop below MinEase.
+const (
+	DefaultEase = 2.5
+	MinEase     = 1.3
+)
+
+// State is a card's review schedule.
+type State struct {
+	// Ease multiplies the interval after each successful review.
+	Ease float64
+	// Interval is the number of days until the next review.
+	Interval int
+	// Repetitions counts successful reviews since the last lapse.
+	Repetitions int
+}
+
+// New returns the state of a card that was never reviewed.
+func New() State {
+	return State{Ease: DefaultEase}
+}
+
+// Review returns the state after answering with grade, which is clamped to
+// [MinGrade, MaxGrade]. A passing grade schedules the card 1 day, then 6
+// days, then the previous interval times the ease factor ahead; a failing
+// one resets it to 1 day. The ease factor moves with every grade.
+func (s State) Review(grade int) State {
+	grade = min(max(grade, MinGrade), MaxGrade)
+	if s.Ease == 0 {
+		s.Ease = DefaultEase
+	}
+	if grade < PassGrade {
+		s.Repetitions = 0
+		s.Interval = 1
+	} else {
+		switch s.Repetitions {
+		case 0:
+			s.Interval = 1
+		case 1:
+			s.Interval = 6
+		default:
+			s.Interval = int(math.Round(float64(s.Interval) * s.Ease))
+		}
+		s.Repetitions++
+	}
+	miss := float64(MaxGrade - grade)
+	s.Ease = max(s.Ease+0.1-miss*(0.08+miss*0.02), MinEase)
+	return s
+}
@@ -0,0 +1,45 @@
+package srs
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReview(t *testing.T) {
+	s := New()
+	steps := []struct {
+		grade       int
+		interval    int
+		repetitions int
+		ease        float64
+	}{
+		{4, 1, 1, 2.5},
+		{5, 6, 2, 2.6},
+		{4, 16, 3, 2.6},
+		{3, 42, 4, 2.46},
+		{1, 1, 0, 1.92},
+		{4, 1, 1, 1.92},
+		{4, 6, 2, 1.92},
+		{4, 12, 3, 1.92},
+	}
+	for i, st := range steps {
+		s = s.Review(st.grade)
+		if s.Interval != st.interval || s.Repetitions != st.repetitions || math.Abs(s.Ease-st.ease) > 1e-9 {
+			t.Fatalf("step %d (grade %d) = %+v, want interval %d, repetitions %d, ease %.2f",
+				i, st.grade, s, st.interval, st.repetitions, st.ease)
+		}
+	}
+}
+
+func TestReview_EaseFloorAndClamp(t *testing.T) {
+	s := New()
+	for range 10 {
+		s = s.Review(-3)
+	}
+	if s.Ease != MinEase || s.Interval != 1 || s.Repetitions != 0 {
+		t.Errorf("after repeated blackouts = %+v, want ease %.1f, interval 1", s, MinEase)
+	}
+	if got := (State{}).Review(9); got.Ease != DefaultEase+0.1 || got.Interval != 1 {
+		t.Errorf("zero state, grade 9 = %+v, want default ease + 0.1 and interval 1", got)
+	}
+}
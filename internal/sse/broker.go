@@ -15,9 +15,42 @@ type Event struct {
 	Data any `json:"data"`
 }
 
+// NoteEvent is the payload of note.created, note.updated, and note.deleted
+// events. With a note lookup configured, created and updated events also
+// carry the note's indexed fields, so clients can patch note lists without
+// fetching the note; deleted events only carry the path.
+type NoteEvent struct {
+	Path      string     `json:"path"`
+	Title     string     `json:"title,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Checksum  string     `json:"checksum,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
 type noteEventReq struct {
 	kind string
-	path string
+	note NoteEvent
+}
+
+// batchMute is how long note events for the paths of a published batch are
+// dropped, covering the watcher re-reporting the batch's own writes.
+const batchMute = 5 * time.Second
+
+// BatchEvent is the payload of notes.batch events.
+type BatchEvent struct {
+	Paths []string `json:"paths"`
+}
+
+// Option configures a Broker.
+type Option func(*Broker)
+
+// WithNoteLookup sets the function that fills in created and updated note
+// events from the index. It runs in the publisher's goroutine, not the
+// broker loop; when it reports false the event carries only the path.
+func WithNoteLookup(lookup func(path string) (NoteEvent, bool)) Option {
+	return func(b *Broker) {
+		b.lookup = lookup
+	}
 }
 
 // Broker manages SSE client connections and broadcasts events.
@@ -27,11 +60,13 @@ type noteEventReq struct {
 // through channels, so no mutexes are required.
 type Broker struct {
 	graphMin time.Duration
+	lookup   func(path string) (NoteEvent, bool)
 
 	subscribeCh   chan chan []byte
 	unsubscribeCh chan chan []byte
 	publishCh     chan Event
 	noteEventCh   chan noteEventReq
+	batchCh       chan []string
 	countReqCh    chan chan int
 
 	stopCh  chan struct{}
@@ -40,7 +75,7 @@ type Broker struct {
 }
 
 // NewBroker creates a new SSE broker with the given graph throttle interval.
-func NewBroker(graphThrottle time.Duration) *Broker {
+func NewBroker(graphThrottle time.Duration, opts ...Option) *Broker {
 	if graphThrottle <= 0 {
 		graphThrottle = 2 * time.Second
 	}
@@ -51,10 +86,14 @@ func NewBroker(graphThrottle time.Duration) *Broker {
 		unsubscribeCh: make(chan chan []byte),
 		publishCh:     make(chan Event, 256),
 		noteEventCh:   make(chan noteEventReq, 256),
+		batchCh:       make(chan []string, 16),
 		countReqCh:    make(chan chan int),
 		stopCh:        make(chan struct{}),
 		stopped:       make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(b)
+	}
 
 	go b.run()
 	return b
@@ -65,6 +104,9 @@ func (b *Broker) run() {
 
 	clients := make(map[chan []byte]struct{})
 	var lastGraph time.Time
+	// muted holds, per path of a recent batch, until when its note events
+	// are dropped.
+	muted := make(map[string]time.Time)
 
 	broadcast := func(event Event) {
 		payload, err := json.Marshal(event.Data)
@@ -104,7 +146,13 @@ func (b *Broker) run() {
 			broadcast(event)
 
 		case req := <-b.noteEventCh:
-			data := map[string]string{"path": req.path}
+			if until, ok := muted[req.note.Path]; ok {
+				if time.Now().Before(until) {
+					continue
+				}
+				delete(muted, req.note.Path)
+			}
+			data := req.note
 			switch req.kind {
 			case "created":
 				broadcast(Event{Type: "note.created", Data: data})
@@ -120,6 +168,20 @@ func (b *Broker) run() {
 				broadcast(Event{Type: "graph.updated", Data: map[string]string{}})
 			}
 
+		case paths := <-b.batchCh:
+			now := time.Now()
+			for p, until := range muted {
+				if now.After(until) {
+					delete(muted, p)
+				}
+			}
+			for _, p := range paths {
+				muted[p] = now.Add(batchMute)
+			}
+			broadcast(Event{Type: "notes.batch", Data: BatchEvent{Paths: paths}})
+			lastGraph = now
+			broadcast(Event{Type: "graph.updated", Data: map[string]string{}})
+
 		case resp := <-b.countReqCh:
 			resp <- len(clients)
 		}
@@ -195,17 +257,46 @@ func (b *Broker) Publish(event Event) {
 }
 
 // PublishNoteEvent publishes a note change and a throttled graph.updated event.
+// kind is created, updated, or deleted.
 func (b *Broker) PublishNoteEvent(kind, path string) {
 	if b.closed.Load() {
 		return
 	}
+	note := NoteEvent{Path: path}
+	if b.lookup != nil && kind != "deleted" {
+		if n, ok := b.lookup(path); ok {
+			note = n
+		}
+	}
 	select {
-	case b.noteEventCh <- noteEventReq{kind: kind, path: path}:
+	case b.noteEventCh <- noteEventReq{kind: kind, note: note}:
+	case <-b.stopped:
+	}
+}
+
+// PublishBatch publishes one notes.batch event listing the paths a bulk
+// write changed and one graph.updated event, and drops the note events for
+// those paths that arrive shortly after (the watcher seeing the same
+// writes), so a large batch does not flood clients.
+func (b *Broker) PublishBatch(paths []string) {
+	if b.closed.Load() {
+		return
+	}
+	select {
+	case b.batchCh <- paths:
 	case <-b.stopped:
 	}
 }
 
 // ServeHTTP is the SSE endpoint handler (GET /api/events).
+//
+//	@Summary		Subscribe to vault changes
+//	@Description	Server-Sent Events stream of note and index changes; see docs/specs/04_realtime_updates.md.
+//	@Tags			events
+//	@Produce		text/event-stream
+//	@Success		200	{string}	string	"Event stream"
+//	@Security		BearerAuth
+//	@Router			/events [get]
 func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
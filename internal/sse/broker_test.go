@@ -85,6 +85,77 @@ loop:
 	}
 }
 
+func TestPublishBatch(t *testing.T) {
+	b := NewBroker(time.Hour)
+	defer b.Close()
+	ch := b.Subscribe()
+	defer b.Unsubscribe(ch)
+
+	b.PublishBatch([]string{"a.md", "b.md"})
+	// The watcher reporting the batch's own writes is muted.
+	b.PublishNoteEvent("created", "a.md")
+	b.PublishNoteEvent("deleted", "b.md")
+	b.PublishNoteEvent("updated", "c.md")
+
+	var events []string
+	deadline := time.After(time.Second)
+	for len(events) < 3 {
+		select {
+		case msg := <-ch:
+			events = append(events, strings.SplitN(string(msg), "\n", 2)[0])
+		case <-deadline:
+			t.Fatalf("timeout; got %q", events)
+		}
+	}
+	want := []string{"event: notes.batch", "event: graph.updated", "event: note.updated"}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("events = %q, want %q", events, want)
+			break
+		}
+	}
+}
+
+func TestPublishNoteEvent_Lookup(t *testing.T) {
+	at := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	b := NewBroker(time.Hour, WithNoteLookup(func(path string) (NoteEvent, bool) {
+		if path != "a.md" {
+			return NoteEvent{}, false
+		}
+		return NoteEvent{Path: path, Title: "A", Tags: []string{"go"}, Checksum: "c1", UpdatedAt: &at}, true
+	}))
+	defer b.Close()
+	ch := b.Subscribe()
+	defer b.Unsubscribe(ch)
+
+	b.PublishNoteEvent("updated", "a.md")
+	b.PublishNoteEvent("created", "missing.md")
+	b.PublishNoteEvent("deleted", "a.md")
+
+	var notes []string
+	deadline := time.After(time.Second)
+	for len(notes) < 3 {
+		select {
+		case msg := <-ch:
+			if s := string(msg); !strings.Contains(s, "graph.updated") {
+				notes = append(notes, s)
+			}
+		case <-deadline:
+			t.Fatalf("timeout; got %q", notes)
+		}
+	}
+	want := `data: {"path":"a.md","title":"A","tags":["go"],"checksum":"c1","updated_at":"2026-10-16T09:30:00Z"}`
+	if !strings.Contains(notes[0], want) {
+		t.Errorf("updated event = %q, want %s", notes[0], want)
+	}
+	if !strings.Contains(notes[1], `data: {"path":"missing.md"}`) {
+		t.Errorf("created event without index row = %q", notes[1])
+	}
+	if !strings.Contains(notes[2], "event: note.deleted\ndata: {\"path\":\"a.md\"}") {
+		t.Errorf("deleted event = %q", notes[2])
+	}
+}
+
 func TestSSEHandler(t *testing.T) {
 	b := NewBroker(100 * time.Millisecond)
 	defer b.Close()
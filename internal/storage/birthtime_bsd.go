@@ -0,0 +1,19 @@
+//go:build darwin || freebsd || netbsd
+
+package storage
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// birthTime returns when the file was created, or the zero time when the
+// file system does not record it.
+func birthTime(_ string, info fs.FileInfo) time.Time {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Birthtimespec.Sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(st.Birthtimespec.Unix())
+}
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"io/fs"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// birthTime returns when the file at path was created, or the zero time
+// when the file system does not record it. Linux reports it through
+// statx only.
+func birthTime(path string, _ fs.FileInfo) time.Time {
+	var st unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &st); err != nil || st.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}
+	}
+	return time.Unix(st.Btime.Sec, int64(st.Btime.Nsec))
+}
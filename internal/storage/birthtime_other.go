@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+
+package storage
+
+import (
+	"io/fs"
+	"time"
+)
+
+// birthTime returns the zero time: file creation times are not read on
+// this platform.
+func birthTime(string, fs.FileInfo) time.Time {
+	return time.Time{}
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// birthTime returns when the file was created, or the zero time when it is
+// unknown.
+func birthTime(_ string, info fs.FileInfo) time.Time {
+	d, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(0, d.CreationTime.Nanoseconds())
+}
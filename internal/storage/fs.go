@@ -41,6 +41,9 @@ func NewFS(root string, ignoreDirs []string) (*FS, error) {
 
 // isIgnored returns true if the directory name should be skipped.
 func (f *FS) isIgnored(name string) bool {
+	if ReservedDir(name) {
+		return true
+	}
 	_, ok := f.ignoreSet[name]
 	return ok
 }
@@ -69,6 +72,17 @@ func (f *FS) safePath(rel string) (string, error) {
 
 // List walks dir (relative to root) and returns metadata for every .md file.
 func (f *FS) List(dir string) ([]models.NoteMetadata, error) {
+	return f.list(dir, true)
+}
+
+// ListStat is List without reading the files: Checksum is left empty.
+func (f *FS) ListStat(dir string) ([]models.NoteMetadata, error) {
+	return f.list(dir, false)
+}
+
+// list walks dir and returns metadata for every .md file, with checksums
+// when sum is set.
+func (f *FS) list(dir string, sum bool) ([]models.NoteMetadata, error) {
 	base, err := f.safePath(dir)
 	if err != nil {
 		return nil, err
@@ -91,16 +105,21 @@ func (f *FS) List(dir string) ([]models.NoteMetadata, error) {
 		if err != nil {
 			return err
 		}
-		data, err := os.ReadFile(p)
-		if err != nil {
-			return err
-		}
 		rel, _ := filepath.Rel(f.root, p)
-		out = append(out, models.NoteMetadata{
+		m := models.NoteMetadata{
 			Path:      rel,
-			Checksum:  checksum.Sum(data),
 			UpdatedAt: info.ModTime(),
-		})
+			CreatedAt: birthTime(p, info),
+			Size:      info.Size(),
+		}
+		if sum {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			m.Checksum = checksum.Sum(data)
+		}
+		out = append(out, m)
 		return nil
 	})
 	if err != nil {
@@ -230,6 +249,34 @@ func (f *FS) ListDirs() ([]string, error) {
 	return dirs, nil
 }
 
+// ListFiles returns every file under dir (relative to root), skipping
+// TrashDir and StateDir.
+func (f *FS) ListFiles(dir string) ([]string, error) {
+	base, err := f.safePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	err = filepath.WalkDir(base, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			if ReservedDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(f.root, p)
+		out = append(out, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: list files: %w", err)
+	}
+	return out, nil
+}
+
 // Move renames a file within the vault.
 func (f *FS) Move(oldPath, newPath string) error {
 	absOld, err := f.safePath(oldPath)
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func tempVault(t *testing.T) *FS {
@@ -87,6 +88,25 @@ func TestList(t *testing.T) {
 	if len(items) != 2 {
 		t.Errorf("len = %d, want 2", len(items))
 	}
+	for _, it := range items {
+		// Birth times are zero where the file system does not record them.
+		if c := it.CreatedAt; !c.IsZero() && (c.After(time.Now()) || c.After(it.UpdatedAt.Add(time.Second))) {
+			t.Errorf("%s CreatedAt = %v, UpdatedAt = %v", it.Path, c, it.UpdatedAt)
+		}
+	}
+
+	stats, err := s.ListStat("")
+	if err != nil {
+		t.Fatalf("ListStat: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Errorf("ListStat len = %d, want 2", len(stats))
+	}
+	for _, it := range stats {
+		if it.Checksum != "" || it.Size != 1 {
+			t.Errorf("ListStat %s: checksum %q, size %d", it.Path, it.Checksum, it.Size)
+		}
+	}
 }
 
 func TestTraversalBlocked(t *testing.T) {
@@ -232,6 +252,29 @@ func TestList_SkipsIgnoredDirs(t *testing.T) {
 	}
 }
 
+func TestListFiles_SkipsTrash(t *testing.T) {
+	s := tempVaultWithIgnore(t, []string{"attachments"})
+	for _, p := range []string{"d/a.md", "d/attachments/img.png", "d/.trash/old.md", ".trash/d/b.md"} {
+		if err := s.Write(p, []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := s.ListFiles("d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0] != "d/a.md" || files[1] != "d/attachments/img.png" {
+		t.Errorf("ListFiles = %v", files)
+	}
+	notes, err := s.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 {
+		t.Errorf("List includes trashed notes: %v", notes)
+	}
+}
+
 func TestNewFS_NonExistentDir(t *testing.T) {
 	_, err := NewFS("/tmp/kenaz-does-not-exist-"+t.Name(), nil)
 	if err == nil {
@@ -0,0 +1,396 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Default commit author, used when GitOptions leaves it empty.
+const (
+	DefaultGitAuthorName  = "Kenaz"
+	DefaultGitAuthorEmail = "kenaz@localhost"
+)
+
+// DefaultGitMessage is the commit message template used when
+// GitOptions.Message is empty.
+const DefaultGitMessage = `{{.Action}} {{.Path}}{{with .From}} (from {{.}}){{end}}`
+
+// Git actions, as passed to the commit message template.
+const (
+	GitCreate  = "create"
+	GitUpdate  = "update"
+	GitDelete  = "delete"
+	GitMove    = "move"
+	GitRestore = "restore"
+)
+
+// GitOptions configures a Git provider. AuthorName and AuthorEmail sign
+// the commits (default DefaultGitAuthorName and DefaultGitAuthorEmail).
+// Message is a text/template rendered with a GitChange (default
+// DefaultGitMessage). Remote, when set, is where Push sends the history,
+// to Branch (default the current branch); with AutoPush every commit is
+// pushed in the background.
+type GitOptions struct {
+	AuthorName  string
+	AuthorEmail string
+	Message     string
+	Remote      string
+	Branch      string
+	AutoPush    bool
+}
+
+// GitChange describes a commit, for the message template.
+type GitChange struct {
+	// Action is GitCreate, GitUpdate, GitDelete, GitMove, or GitRestore
+	// (out of the trash).
+	Action string
+	Path   string
+	// From is the previous path of a moved file.
+	From string
+}
+
+// Commit is a commit of the vault history.
+type Commit struct {
+	Hash    string    `json:"hash" example:"3f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a39" validate:"required"`
+	Author  string    `json:"author" example:"Kenaz" validate:"required"`
+	Email   string    `json:"email" example:"kenaz@localhost" validate:"required"`
+	Time    time.Time `json:"time" validate:"required"`
+	Message string    `json:"message" example:"update projects/kenaz-roadmap.md" validate:"required"`
+}
+
+// Versioned is implemented by providers that record the history of the
+// vault, such as Git.
+type Versioned interface {
+	// LastCommit returns the latest commit touching path, or the latest
+	// commit when path is empty; nil if there is none.
+	LastCommit(path string) (*Commit, error)
+	// Push sends the history to the configured remote.
+	Push(ctx context.Context) error
+}
+
+// ErrNoRemote is returned by Push when no remote is configured.
+var ErrNoRemote = errors.New("storage: no git remote configured")
+
+// Git implements Provider on a local vault that is a git working tree: it
+// commits every Write, Delete, DeleteDir, and Move, so the vault gets a
+// history for free and can be pushed offsite. Changes inside TrashDir and
+// StateDir are not committed; moving a file to the trash commits its
+// deletion. The git command must be installed.
+type Git struct {
+	*FS
+	opts GitOptions
+	msg  *template.Template
+
+	// mu serializes each file operation with its commit, so commits never
+	// race on the git index.
+	mu sync.Mutex
+	// pushMu serializes pushes.
+	pushMu sync.Mutex
+}
+
+var _ Versioned = (*Git)(nil)
+
+// NewGit wraps the vault in f. A vault that is not the top of a git
+// working tree of its own, including one inside another repository, is
+// initialized as a repository, and its current files committed.
+func NewGit(f *FS, opts GitOptions) (*Git, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("storage: git: %w", err)
+	}
+	if opts.AuthorName == "" {
+		opts.AuthorName = DefaultGitAuthorName
+	}
+	if opts.AuthorEmail == "" {
+		opts.AuthorEmail = DefaultGitAuthorEmail
+	}
+	if opts.Message == "" {
+		opts.Message = DefaultGitMessage
+	}
+	msg, err := template.New("message").Parse(opts.Message)
+	if err != nil {
+		return nil, fmt.Errorf("storage: git message template: %w", err)
+	}
+	g := &Git{FS: f, opts: opts, msg: msg}
+
+	ctx := context.Background()
+	own, err := g.ownsRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !own {
+		if _, err := g.git(ctx, "init", "-q"); err != nil {
+			return nil, err
+		}
+		if _, err := g.git(ctx, "add", "-A", "--", ".", ":(exclude)"+TrashDir, ":(exclude)"+StateDir); err != nil {
+			return nil, err
+		}
+		if err := g.commit(ctx, "initial import"); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// ownsRepo reports whether the vault is the top of a git working tree.
+// A vault in a subdirectory of another repository is not: committing
+// there would sweep in the changes staged for the enclosing project.
+func (g *Git) ownsRepo(ctx context.Context) (bool, error) {
+	inTree, err := g.test(ctx, "rev-parse", "--is-inside-work-tree")
+	if err != nil || !inTree {
+		return false, err
+	}
+	out, err := g.git(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return false, err
+	}
+	top, err := filepath.EvalSymlinks(filepath.FromSlash(strings.TrimSpace(out)))
+	if err != nil {
+		return false, fmt.Errorf("storage: git top level: %w", err)
+	}
+	root, err := filepath.EvalSymlinks(g.root)
+	if err != nil {
+		return false, fmt.Errorf("storage: git top level: %w", err)
+	}
+	return top == root, nil
+}
+
+// ListFiles is FS.ListFiles without the repository's own files.
+func (g *Git) ListFiles(dir string) ([]string, error) {
+	files, err := g.FS.ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := files[:0]
+	for _, f := range files {
+		if f != ".git" && !strings.HasPrefix(f, ".git/") {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// Write writes the file and commits it.
+func (g *Git) Write(path string, content []byte) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	action := GitUpdate
+	if abs, err := g.safePath(path); err == nil {
+		if _, err := os.Stat(abs); errors.Is(err, os.ErrNotExist) {
+			action = GitCreate
+		}
+	}
+	if err := g.FS.Write(path, content); err != nil {
+		return err
+	}
+	g.record(GitChange{Action: action, Path: path}, path)
+	return nil
+}
+
+// Delete removes the file and commits its deletion.
+func (g *Git) Delete(path string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.FS.Delete(path); err != nil {
+		return err
+	}
+	g.record(GitChange{Action: GitDelete, Path: path}, path)
+	return nil
+}
+
+// DeleteDir removes the directory and commits the deletion of its files.
+func (g *Git) DeleteDir(path string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.FS.DeleteDir(path); err != nil {
+		return err
+	}
+	g.record(GitChange{Action: GitDelete, Path: path}, path)
+	return nil
+}
+
+// Move renames the file or directory and commits the move. A move into
+// the trash is committed as a deletion, one out of it as a restore.
+func (g *Git) Move(oldPath, newPath string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.FS.Move(oldPath, newPath); err != nil {
+		return err
+	}
+	c := GitChange{Action: GitMove, Path: newPath, From: oldPath}
+	switch {
+	case InTrash(newPath):
+		c = GitChange{Action: GitDelete, Path: oldPath}
+	case InTrash(oldPath):
+		c = GitChange{Action: GitRestore, Path: newPath}
+	}
+	g.record(c, oldPath, newPath)
+	return nil
+}
+
+// LastCommit returns the latest commit touching path, or the latest commit
+// when path is empty; nil if there is none.
+func (g *Git) LastCommit(path string) (*Commit, error) {
+	args := []string{"log", "-1", "--format=%H%x00%an%x00%ae%x00%aI%x00%s"}
+	if path != "" {
+		args = append(args, "--", path)
+	}
+	out, err := g.git(context.Background(), args...)
+	if err != nil {
+		// A repository without commits has no log.
+		if ok, _ := g.test(context.Background(), "rev-parse", "-q", "--verify", "HEAD"); !ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	fields := strings.SplitN(strings.TrimSpace(out), "\x00", 5)
+	if len(fields) < 5 {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("storage: git commit time: %w", err)
+	}
+	return &Commit{Hash: fields[0], Author: fields[1], Email: fields[2], Time: t, Message: fields[4]}, nil
+}
+
+// Push pushes the current branch to the configured remote, to
+// GitOptions.Branch when set. It fails with ErrNoRemote without a remote.
+func (g *Git) Push(ctx context.Context) error {
+	if g.opts.Remote == "" {
+		return ErrNoRemote
+	}
+	g.pushMu.Lock()
+	defer g.pushMu.Unlock()
+	ref := "HEAD"
+	if g.opts.Branch != "" {
+		ref = "HEAD:" + g.opts.Branch
+	}
+	_, err := g.git(ctx, "push", "-q", g.opts.Remote, ref)
+	return err
+}
+
+// record commits the changes to paths, described by c, and pushes them
+// when AutoPush is set. The file operation has already succeeded, so a
+// failing commit is logged rather than returned; the changes are then
+// committed with the next change to the same paths.
+func (g *Git) record(c GitChange, paths ...string) {
+	ctx := context.Background()
+	err := g.stage(ctx, paths...)
+	if err == nil {
+		var msg strings.Builder
+		if err = g.msg.Execute(&msg, c); err == nil {
+			err = g.commit(ctx, msg.String())
+		}
+	}
+	if err != nil {
+		slog.Warn("git commit failed", slog.String("path", c.Path), slog.String("error", err.Error()))
+		return
+	}
+	if g.opts.AutoPush && g.opts.Remote != "" {
+		go func() {
+			if err := g.Push(context.Background()); err != nil {
+				slog.Warn("git push failed", slog.String("error", err.Error()))
+			}
+		}()
+	}
+}
+
+// stage adds the current state of paths, outside TrashDir and StateDir,
+// to the git index: their content if they exist, their removal otherwise.
+// Paths ignored by .gitignore are skipped.
+func (g *Git) stage(ctx context.Context, paths ...string) error {
+	for _, p := range paths {
+		if InReserved(p) {
+			continue
+		}
+		abs, err := g.safePath(p)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(abs); errors.Is(err, os.ErrNotExist) {
+			if _, err := g.git(ctx, "rm", "-r", "-q", "--cached", "--ignore-unmatch", "--", p); err != nil {
+				return err
+			}
+			continue
+		}
+		ignored, err := g.test(ctx, "check-ignore", "-q", "--", p)
+		if err != nil {
+			return err
+		}
+		if ignored {
+			continue
+		}
+		if _, err := g.git(ctx, "add", "-A", "--", p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit commits the staged changes, if there are any.
+func (g *Git) commit(ctx context.Context, msg string) error {
+	clean, err := g.test(ctx, "diff", "--cached", "--quiet")
+	if err != nil || clean {
+		return err
+	}
+	_, err = g.git(ctx, "commit", "-q", "--no-verify", "-m", msg)
+	return err
+}
+
+// git runs git in the vault with the configured identity and returns its
+// output.
+func (g *Git) git(ctx context.Context, args ...string) (string, error) {
+	cmd := g.command(ctx, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("storage: git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// test runs git in the vault and reports whether it exited with 0; exit
+// status 1 reports false, any other failure an error.
+func (g *Git) test(ctx context.Context, args ...string) (bool, error) {
+	cmd := g.command(ctx, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exit) && exit.ExitCode() == 1:
+		return false, nil
+	case errors.As(err, &exit) && args[0] == "rev-parse":
+		// Not a git repository (exit status 128).
+		return false, nil
+	}
+	return false, fmt.Errorf("storage: git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+}
+
+// command prepares a git command run in the vault. Author and committer
+// come from the options, so the repository needs no identity configured,
+// and git never prompts for credentials.
+func (g *Git) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", g.root}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+g.opts.AuthorName,
+		"GIT_AUTHOR_EMAIL="+g.opts.AuthorEmail,
+		"GIT_COMMITTER_NAME="+g.opts.AuthorName,
+		"GIT_COMMITTER_EMAIL="+g.opts.AuthorEmail,
+		"GIT_TERMINAL_PROMPT=0",
+	)
+	return cmd
+}
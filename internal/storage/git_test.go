@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func gitVault(t *testing.T, opts GitOptions) *Git {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	f := tempVault(t)
+	if err := os.WriteFile(filepath.Join(f.root, "existing.md"), []byte("# Existing"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGit(f, opts)
+	if err != nil {
+		t.Fatalf("NewGit: %v", err)
+	}
+	return g
+}
+
+// gitLog returns the commit subjects, newest first.
+func gitLog(t *testing.T, g *Git) []string {
+	t.Helper()
+	out, err := g.git(context.Background(), "log", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(out), "\n")
+}
+
+func TestGit(t *testing.T) {
+	g := gitVault(t, GitOptions{AuthorName: "Ann", AuthorEmail: "ann@example.com"})
+
+	if err := g.Write("a.md", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Write("a.md", []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged content makes no commit.
+	if err := g.Write("a.md", []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Move("a.md", "dir/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Write(StateDir+"/x.md", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Move("dir/b.md", TrashDir+"/b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Move(TrashDir+"/b.md", "b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Delete("existing.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"delete existing.md",
+		"restore b.md",
+		"delete dir/b.md",
+		"move dir/b.md (from a.md)",
+		"update a.md",
+		"create a.md",
+		"initial import",
+	}
+	if got := gitLog(t, g); !slices.Equal(got, want) {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+	out, err := g.git(context.Background(), "ls-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(out); !slices.Equal(got, []string{"b.md"}) {
+		t.Errorf("tracked files = %v, want [b.md]", got)
+	}
+
+	c, err := g.LastCommit("b.md")
+	if err != nil || c == nil {
+		t.Fatalf("LastCommit = %v, %v", c, err)
+	}
+	if c.Message != "restore b.md" || c.Author != "Ann" || c.Email != "ann@example.com" || len(c.Hash) != 40 || c.Time.IsZero() {
+		t.Errorf("LastCommit = %+v", c)
+	}
+	if c, err := g.LastCommit("missing.md"); err != nil || c != nil {
+		t.Errorf("LastCommit(missing) = %v, %v, want nil", c, err)
+	}
+
+	files, err := g.ListFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(files, []string{"b.md"}) {
+		t.Errorf("ListFiles = %v, want [b.md]", files)
+	}
+}
+
+func TestGit_MessageAndPush(t *testing.T) {
+	g := gitVault(t, GitOptions{Message: "kenaz: {{.Action}} {{.Path}}"})
+	if err := g.Push(context.Background()); !errors.Is(err, ErrNoRemote) {
+		t.Errorf("Push without remote = %v, want ErrNoRemote", err)
+	}
+
+	remote := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	g.opts.Remote, g.opts.Branch = remote, "backup"
+	if err := g.Write("a.md", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("git", "-C", remote, "log", "--format=%s", "backup").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git log: %v: %s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "kenaz: create a.md\ninitial import" {
+		t.Errorf("pushed log = %q", got)
+	}
+}
+
+func TestGit_InsideAnotherRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	parent := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", parent).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	vault := filepath.Join(parent, "notes")
+	if err := os.MkdirAll(vault, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFS(vault, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGit(f, GitOptions{})
+	if err != nil {
+		t.Fatalf("NewGit: %v", err)
+	}
+	if err := g.Write("a.md", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, ".git")); err != nil {
+		t.Errorf("vault has no repository of its own: %v", err)
+	}
+	if out, err := exec.Command("git", "-C", parent, "rev-parse", "-q", "--verify", "HEAD").CombinedOutput(); err == nil {
+		t.Errorf("commit landed in the enclosing repository: %s", out)
+	}
+	if got := gitLog(t, g); !slices.Equal(got, []string{"create a.md"}) {
+		t.Errorf("log = %v", got)
+	}
+
+	// The vault's own repository is reused.
+	if _, err := NewGit(f, GitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitLog(t, g); len(got) != 1 {
+		t.Errorf("log after reopening = %v", got)
+	}
+}
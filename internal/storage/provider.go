@@ -1,12 +1,45 @@
 // Package storage defines the vault file-system abstraction.
 package storage
 
-import "github.com/starford/kenaz/internal/models"
+import (
+	"strings"
+
+	"github.com/starford/kenaz/internal/models"
+)
+
+// TrashDir is the vault-relative folder holding deleted files. It is never
+// listed, watched, or indexed.
+const TrashDir = ".trash"
+
+// StateDir is the vault-relative folder holding Kenaz's own files, such as
+// note revisions. Like TrashDir it is never listed, watched, or indexed.
+const StateDir = ".kenaz"
+
+// InTrash reports whether the vault-relative path lies inside TrashDir.
+func InTrash(rel string) bool {
+	return rel == TrashDir || strings.HasPrefix(rel, TrashDir+"/")
+}
+
+// ReservedDir reports whether a directory with this name is skipped by
+// listings and the watcher: TrashDir or StateDir.
+func ReservedDir(name string) bool {
+	return name == TrashDir || name == StateDir
+}
+
+// InReserved reports whether the vault-relative path lies inside TrashDir
+// or StateDir.
+func InReserved(rel string) bool {
+	return InTrash(rel) || rel == StateDir || strings.HasPrefix(rel, StateDir+"/")
+}
 
 // Provider is the interface for vault file operations.
 type Provider interface {
 	// List returns metadata for every .md file under dir (relative to vault root).
 	List(dir string) ([]models.NoteMetadata, error)
+	// ListStat is List without reading the files: Checksum is empty, so
+	// callers can compare UpdatedAt and Size first and read only the
+	// files that changed.
+	ListStat(dir string) ([]models.NoteMetadata, error)
 	// Read returns the raw bytes of the file at path (relative to vault root).
 	Read(path string) ([]byte, error)
 	// Write atomically writes content to path (relative to vault root).
@@ -19,6 +52,10 @@ type Provider interface {
 	DeleteDir(path string) error
 	// ListDirs returns all directory paths relative to vault root.
 	ListDirs() ([]string, error)
+	// ListFiles returns the paths (relative to vault root) of all files under
+	// dir, of any type, including ignored directories but not TrashDir or
+	// StateDir.
+	ListFiles(dir string) ([]string, error)
 	// Move renames oldPath to newPath (both relative to vault root).
 	Move(oldPath, newPath string) error
 }
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+// ReadOnly wraps a Provider and refuses every change to the vault: Write,
+// Delete, DeleteDir, and Move fail with apperr.ErrReadOnly, while reads and
+// listings pass through.
+type ReadOnly struct {
+	Provider
+}
+
+// Write refuses to write the file.
+func (ReadOnly) Write(path string, _ []byte) error {
+	return fmt.Errorf("%w: write %s", apperr.ErrReadOnly, path)
+}
+
+// Delete refuses to remove the file.
+func (ReadOnly) Delete(path string) error {
+	return fmt.Errorf("%w: delete %s", apperr.ErrReadOnly, path)
+}
+
+// DeleteDir refuses to remove the directory.
+func (ReadOnly) DeleteDir(path string) error {
+	return fmt.Errorf("%w: delete %s", apperr.ErrReadOnly, path)
+}
+
+// Move refuses to rename the file or directory.
+func (ReadOnly) Move(oldPath, _ string) error {
+	return fmt.Errorf("%w: move %s", apperr.ErrReadOnly, oldPath)
+}
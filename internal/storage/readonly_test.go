@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/starford/kenaz/internal/apperr"
+)
+
+func TestReadOnly(t *testing.T) {
+	f := tempVault(t)
+	if err := f.Write("a.md", []byte("# A")); err != nil {
+		t.Fatal(err)
+	}
+	ro := ReadOnly{Provider: f}
+
+	if got, err := ro.Read("a.md"); err != nil || string(got) != "# A" {
+		t.Errorf("Read = %q, %v", got, err)
+	}
+	if files, err := ro.ListFiles(""); err != nil || len(files) != 1 {
+		t.Errorf("ListFiles = %v, %v", files, err)
+	}
+	for name, err := range map[string]error{
+		"Write":     ro.Write("b.md", []byte("# B")),
+		"Delete":    ro.Delete("a.md"),
+		"DeleteDir": ro.DeleteDir("dir"),
+		"Move":      ro.Move("a.md", "c.md"),
+	} {
+		if !errors.Is(err, apperr.ErrReadOnly) {
+			t.Errorf("%s = %v, want ErrReadOnly", name, err)
+		}
+	}
+	if got, err := f.Read("a.md"); err != nil || string(got) != "# A" {
+		t.Errorf("vault changed: a.md = %q, %v", got, err)
+	}
+}
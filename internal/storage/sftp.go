@@ -0,0 +1,530 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/starford/kenaz/internal/checksum"
+	"github.com/starford/kenaz/internal/models"
+)
+
+// SFTPOptions locates a vault on a remote server. Host is "host" or
+// "host:port" (default port 22). Authentication uses the private key in
+// KeyFile, Password, or both. The server's host key must be listed in
+// KnownHosts (default ~/.ssh/known_hosts). Path is the vault directory on
+// the server. Connections is the size of the connection pool (default
+// DefaultSFTPConnections).
+type SFTPOptions struct {
+	Host        string
+	User        string
+	KeyFile     string
+	Password    string
+	KnownHosts  string
+	Path        string
+	Connections int
+}
+
+// DefaultSFTPConnections is the connection pool size used when
+// SFTPOptions.Connections is not set.
+const DefaultSFTPConnections = 4
+
+// SFTP implements Provider over SFTP, for vaults living on a remote server.
+// Operations are spread over a pool of connections, each opened on first
+// use, so a large download does not hold up the others. A lost connection
+// is redialed once per operation. There is no change notification over
+// SFTP; pair it with the polling watcher (index.Poll).
+type SFTP struct {
+	root      string // absolute vault directory on the server
+	ignoreSet map[string]struct{}
+	sums      sumCache
+
+	dial func() (*sftp.Client, error) // nil when clients cannot be (re)dialed
+	mu   sync.Mutex
+	pool []*sftp.Client // nil slots are dialed on first use
+	next atomic.Uint64
+}
+
+// NewSFTP connects to the server described by opts and returns a provider
+// rooted at opts.Path, which must be an existing directory. ignoreDirs
+// specifies directory base names to exclude from listing.
+func NewSFTP(opts SFTPOptions, ignoreDirs []string) (*SFTP, error) {
+	cfg, err := sshConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	addr := opts.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	dial := func() (*sftp.Client, error) {
+		conn, err := ssh.Dial("tcp", addr, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("storage: ssh dial %s: %w", addr, err)
+		}
+		c, err := sftp.NewClient(conn)
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("storage: sftp session: %w", err)
+		}
+		return c, nil
+	}
+	client, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSFTP(client, opts.Path, ignoreDirs)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	s.dial = dial
+	n := opts.Connections
+	if n <= 0 {
+		n = DefaultSFTPConnections
+	}
+	for len(s.pool) < n {
+		s.pool = append(s.pool, nil)
+	}
+	return s, nil
+}
+
+// newSFTP wraps an established client; root is resolved against the
+// server's working directory.
+func newSFTP(client *sftp.Client, root string, ignoreDirs []string) (*SFTP, error) {
+	if !path.IsAbs(root) {
+		wd, err := client.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("storage: sftp getwd: %w", err)
+		}
+		root = path.Join(wd, root)
+	}
+	root = path.Clean(root)
+	info, err := client.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("storage: stat root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("storage: root is not a directory: %s", root)
+	}
+	ignoreSet := make(map[string]struct{}, len(ignoreDirs))
+	for _, d := range ignoreDirs {
+		ignoreSet[d] = struct{}{}
+	}
+	return &SFTP{root: root, ignoreSet: ignoreSet, pool: []*sftp.Client{client}}, nil
+}
+
+// sshConfig builds the SSH client configuration for opts.
+func sshConfig(opts SFTPOptions) (*ssh.ClientConfig, error) {
+	var auth []ssh.AuthMethod
+	if opts.KeyFile != "" {
+		key, err := os.ReadFile(opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("storage: read sftp key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("storage: parse sftp key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if opts.Password != "" {
+		auth = append(auth, ssh.Password(opts.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("storage: sftp needs a key file or password")
+	}
+
+	known := opts.KnownHosts
+	if known == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("storage: locate known_hosts: %w", err)
+		}
+		known = path.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeys, err := knownhosts.New(known)
+	if err != nil {
+		return nil, fmt.Errorf("storage: load known_hosts: %w", err)
+	}
+	return &ssh.ClientConfig{
+		User:            opts.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         30 * time.Second,
+	}, nil
+}
+
+// do runs fn with the next client of the pool, in turn. If the connection
+// was lost and the provider can redial, it reconnects and runs fn once
+// more.
+func (s *SFTP) do(fn func(c *sftp.Client) error) error {
+	i := int(s.next.Add(1) % uint64(len(s.pool)))
+	c, err := s.conn(i, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(c)
+	if s.dial == nil || !errors.Is(err, sftp.ErrSSHFxConnectionLost) {
+		return err
+	}
+	if c, err = s.conn(i, c); err != nil {
+		return err
+	}
+	return fn(c)
+}
+
+// conn returns the client in pool slot i, dialing a new one if the slot is
+// empty or still holds lost, a client whose connection was lost.
+func (s *SFTP) conn(i int, lost *sftp.Client) (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.pool[i]
+	if c != nil && c != lost {
+		return c, nil
+	}
+	fresh, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		_ = c.Close()
+	}
+	s.pool[i] = fresh
+	return fresh, nil
+}
+
+// Close closes the connections to the server.
+func (s *SFTP) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, c := range s.pool {
+		if c != nil {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isIgnored returns true if the directory name should be skipped.
+func (s *SFTP) isIgnored(name string) bool {
+	if ReservedDir(name) {
+		return true
+	}
+	_, ok := s.ignoreSet[name]
+	return ok
+}
+
+// safePath resolves a vault-relative path against the remote root,
+// rejecting absolute paths and any that escape it.
+func (s *SFTP) safePath(rel string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(rel, "\\", "/"))
+	switch {
+	case cleaned == ".":
+		return s.root, nil
+	case path.IsAbs(cleaned):
+		return "", fmt.Errorf("storage: absolute paths not allowed: %s", rel)
+	case cleaned == ".." || strings.HasPrefix(cleaned, "../"):
+		return "", fmt.Errorf("storage: path escapes vault root: %s", rel)
+	}
+	return path.Join(s.root, cleaned), nil
+}
+
+// rel returns the vault-relative form of the remote path p.
+func (s *SFTP) rel(p string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(p, s.root), "/")
+}
+
+// walk calls fn for every file under dir (a remote path), skipping
+// directories for which descend returns false. It lists one directory per
+// request rather than stat-ing each entry.
+func (s *SFTP) walk(c *sftp.Client, dir string, descend func(name string) bool, fn func(p string, info os.FileInfo) error) error {
+	entries, err := c.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := path.Join(dir, e.Name())
+		if e.IsDir() {
+			if descend(e.Name()) {
+				if err := s.walk(c, p, descend, fn); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if e.Mode().IsRegular() {
+			if err := fn(p, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// List walks dir and returns metadata for every .md file. Only files whose
+// size or mtime changed since the last listing are downloaded to compute
+// their checksum.
+func (s *SFTP) List(dir string) ([]models.NoteMetadata, error) {
+	return s.list(dir, true)
+}
+
+// ListStat is List without downloading any file: Checksum is left empty.
+func (s *SFTP) ListStat(dir string) ([]models.NoteMetadata, error) {
+	return s.list(dir, false)
+}
+
+// list walks dir and returns metadata for every .md file, with checksums
+// when sum is set.
+func (s *SFTP) list(dir string, sum bool) ([]models.NoteMetadata, error) {
+	base, err := s.safePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []models.NoteMetadata
+	err = s.do(func(c *sftp.Client) error {
+		out = out[:0]
+		return s.walk(c, base, func(name string) bool { return !s.isIgnored(name) }, func(p string, info os.FileInfo) error {
+			if !strings.HasSuffix(p, ".md") {
+				return nil
+			}
+			rel := s.rel(p)
+			m := models.NoteMetadata{Path: rel, UpdatedAt: info.ModTime(), Size: info.Size()}
+			if sum {
+				stamp := strconv.FormatInt(info.Size(), 10) + "@" + strconv.FormatInt(info.ModTime().UnixNano(), 10)
+				var ok bool
+				if m.Checksum, ok = s.sums.get(rel, stamp); !ok {
+					data, err := readFile(c, p)
+					if err != nil {
+						return err
+					}
+					m.Checksum = checksum.Sum(data)
+					s.sums.put(rel, stamp, m.Checksum)
+				}
+			}
+			out = append(out, m)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: list: %w", err)
+	}
+	return out, nil
+}
+
+// readFile returns the contents of the remote file p.
+func readFile(c *sftp.Client, p string) ([]byte, error) {
+	f, err := c.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Read returns the raw bytes of a vault file.
+func (s *SFTP) Read(rel string) ([]byte, error) {
+	p, err := s.safePath(rel)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	err = s.do(func(c *sftp.Client) error {
+		data, err = readFile(c, p)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: read %s: %w", rel, err)
+	}
+	return data, nil
+}
+
+// Write atomically writes content on the server: temp file in the same
+// directory → rename over the target (posix-rename where the server
+// supports it).
+func (s *SFTP) Write(rel string, content []byte) error {
+	p, err := s.safePath(rel)
+	if err != nil {
+		return err
+	}
+	s.sums.forget(s.rel(p))
+	err = s.do(func(c *sftp.Client) error {
+		dir := path.Dir(p)
+		if err := c.MkdirAll(dir); err != nil {
+			return fmt.Errorf("mkdir: %w", err)
+		}
+		tmp := path.Join(dir, ".kenaz-tmp-"+uuid.NewString())
+		f, err := c.Create(tmp)
+		if err != nil {
+			return fmt.Errorf("create temp: %w", err)
+		}
+		if _, err := f.Write(content); err != nil {
+			_ = f.Close()
+			_ = c.Remove(tmp)
+			return fmt.Errorf("write temp: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			_ = c.Remove(tmp)
+			return fmt.Errorf("close temp: %w", err)
+		}
+		if err := rename(c, tmp, p); err != nil {
+			_ = c.Remove(tmp)
+			return fmt.Errorf("rename: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("storage: write %s: %w", rel, err)
+	}
+	return nil
+}
+
+// rename moves from onto to, replacing it. Plain SFTP rename fails when the
+// target exists, so servers without posix-rename get a remove first.
+func rename(c *sftp.Client, from, to string) error {
+	if _, ok := c.HasExtension("posix-rename@openssh.com"); ok {
+		return c.PosixRename(from, to)
+	}
+	if err := c.Remove(to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return c.Rename(from, to)
+}
+
+// Delete removes a file from the vault.
+func (s *SFTP) Delete(rel string) error {
+	p, err := s.safePath(rel)
+	if err != nil {
+		return err
+	}
+	s.sums.forget(s.rel(p))
+	if err := s.do(func(c *sftp.Client) error { return c.Remove(p) }); err != nil {
+		return fmt.Errorf("storage: delete %s: %w", rel, err)
+	}
+	return nil
+}
+
+// DirExists reports whether the given relative path is an existing
+// directory.
+func (s *SFTP) DirExists(rel string) (bool, error) {
+	p, err := s.safePath(rel)
+	if err != nil {
+		return false, err
+	}
+	var info os.FileInfo
+	err = s.do(func(c *sftp.Client) error {
+		info, err = c.Stat(p)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("storage: stat dir %s: %w", rel, err)
+	}
+	return info.IsDir(), nil
+}
+
+// DeleteDir removes a directory and all its contents from the vault.
+func (s *SFTP) DeleteDir(rel string) error {
+	p, err := s.safePath(rel)
+	if err != nil {
+		return err
+	}
+	s.sums.forget(s.rel(p))
+	if err := s.do(func(c *sftp.Client) error { return c.RemoveAll(p) }); err != nil {
+		return fmt.Errorf("storage: delete dir %s: %w", rel, err)
+	}
+	return nil
+}
+
+// ListDirs returns all directory paths (relative to vault root).
+func (s *SFTP) ListDirs() ([]string, error) {
+	var dirs []string
+	var visit func(c *sftp.Client, dir string) error
+	visit = func(c *sftp.Client, dir string) error {
+		entries, err := c.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.IsDir() || s.isIgnored(e.Name()) {
+				continue
+			}
+			p := path.Join(dir, e.Name())
+			dirs = append(dirs, s.rel(p))
+			if err := visit(c, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := s.do(func(c *sftp.Client) error {
+		dirs = dirs[:0]
+		return visit(c, s.root)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: list dirs: %w", err)
+	}
+	return dirs, nil
+}
+
+// ListFiles returns every file under dir (relative to root), skipping
+// TrashDir and StateDir.
+func (s *SFTP) ListFiles(dir string) ([]string, error) {
+	base, err := s.safePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	err = s.do(func(c *sftp.Client) error {
+		out = out[:0]
+		return s.walk(c, base, func(name string) bool { return !ReservedDir(name) }, func(p string, _ os.FileInfo) error {
+			out = append(out, s.rel(p))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: list files: %w", err)
+	}
+	return out, nil
+}
+
+// Move renames a file within the vault.
+func (s *SFTP) Move(oldPath, newPath string) error {
+	from, err := s.safePath(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := s.safePath(newPath)
+	if err != nil {
+		return err
+	}
+	s.sums.forget(s.rel(from))
+	s.sums.forget(s.rel(to))
+	err = s.do(func(c *sftp.Client) error {
+		if err := c.MkdirAll(path.Dir(to)); err != nil {
+			return fmt.Errorf("mkdir for move: %w", err)
+		}
+		return rename(c, from, to)
+	})
+	if err != nil {
+		return fmt.Errorf("storage: move: %w", err)
+	}
+	return nil
+}
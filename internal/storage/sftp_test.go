@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// tempSFTP serves a temp dir from an in-process SFTP server and returns a
+// provider rooted at it, plus the dir.
+func tempSFTP(t *testing.T, ignoreDirs []string) (*SFTP, string) {
+	t.Helper()
+	dir := t.TempDir()
+	client, _ := pipeClient(t)
+	s, err := newSFTP(client, dir, ignoreDirs)
+	if err != nil {
+		t.Fatalf("newSFTP: %v", err)
+	}
+	return s, dir
+}
+
+// pipeClient returns a client connected to a new in-process SFTP server,
+// and the server.
+func pipeClient(t *testing.T) (*sftp.Client, *sftp.Server) {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	srv, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{sr, sw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	client, err := sftp.NewClientPipe(cr, cw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		srv.Close() // closes the client's read side, so Close does not block
+		client.Close()
+	})
+	return client, srv
+}
+
+func TestSFTP_ReadWriteMoveDelete(t *testing.T) {
+	s, dir := tempSFTP(t, nil)
+	if err := s.Write("a/b/c.md", []byte("deep")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := s.Write("a/b/c.md", []byte("deeper")); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if got, err := s.Read("a/b/c.md"); err != nil || string(got) != "deeper" {
+		t.Fatalf("Read = %q, %v", got, err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "a", "b"))
+	if len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+
+	if err := s.Move("a/b/c.md", "x/y.md"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x", "y.md")); err != nil {
+		t.Errorf("moved file missing: %v", err)
+	}
+	if _, err := s.Read("a/b/c.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Read after move: err = %v, want ErrNotExist", err)
+	}
+
+	if err := s.Delete("x/y.md"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete("x/y.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("second Delete: err = %v, want ErrNotExist", err)
+	}
+	if ok, err := s.DirExists("a/b"); err != nil || !ok {
+		t.Errorf("DirExists(a/b) = %v, %v", ok, err)
+	}
+	if err := s.DeleteDir("a"); err != nil {
+		t.Fatalf("DeleteDir: %v", err)
+	}
+	if ok, _ := s.DirExists("a"); ok {
+		t.Error("dir still exists after DeleteDir")
+	}
+}
+
+func TestSFTP_List(t *testing.T) {
+	s, dir := tempSFTP(t, []string{"attachments"})
+	for _, p := range []string{"one.md", "sub/two.md", "attachments/skip.md", TrashDir + "/old.md", "sub/img.png"} {
+		if err := s.Write(p, []byte("# "+p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	metas, err := s.List("")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var paths []string
+	for _, m := range metas {
+		paths = append(paths, m.Path)
+	}
+	slices.Sort(paths)
+	if !slices.Equal(paths, []string{"one.md", "sub/two.md"}) {
+		t.Errorf("List = %v", paths)
+	}
+
+	// A changed file gets a new checksum.
+	before := metas
+	if err := os.WriteFile(filepath.Join(dir, "one.md"), []byte("# one, edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	after, err := s.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums := map[string]string{}
+	for _, m := range before {
+		sums[m.Path] = m.Checksum
+	}
+	for _, m := range after {
+		if changed := sums[m.Path] != m.Checksum; changed != (m.Path == "one.md") {
+			t.Errorf("%s: checksum changed = %v", m.Path, changed)
+		}
+	}
+
+	dirs, err := s.ListDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(dirs, []string{"sub"}) {
+		t.Errorf("ListDirs = %v", dirs)
+	}
+	files, err := s.ListFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	if !slices.Equal(files, []string{"attachments/skip.md", "one.md", "sub/img.png", "sub/two.md"}) {
+		t.Errorf("ListFiles = %v", files)
+	}
+}
+
+func TestSFTP_TraversalBlocked(t *testing.T) {
+	s, _ := tempSFTP(t, nil)
+	for _, p := range []string{"../escape.md", "/etc/passwd", "a/../../x.md"} {
+		if err := s.Write(p, []byte("x")); err == nil {
+			t.Errorf("Write(%q) should fail", p)
+		}
+	}
+}
+
+func TestSFTP_Pool(t *testing.T) {
+	s, _ := tempSFTP(t, nil)
+	var servers []*sftp.Server
+	s.dial = func() (*sftp.Client, error) {
+		c, srv := pipeClient(t)
+		servers = append(servers, srv)
+		return c, nil
+	}
+	s.pool = append(s.pool, nil, nil)
+
+	for range 2 {
+		if err := s.Write("a.md", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(servers) != 2 || slices.Contains(s.pool, nil) {
+		t.Fatalf("after two operations: %d dials, pool %v; want each connection opened on first use", len(servers), s.pool)
+	}
+	for range 3 {
+		if _, err := s.Read("a.md"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(servers) != 2 {
+		t.Errorf("%d dials, want the pool reused", len(servers))
+	}
+
+	// Drop the connection of the first dialed client.
+	lost := s.pool[1]
+	servers[0].Close()
+	for range len(s.pool) {
+		if got, err := s.Read("a.md"); err != nil || string(got) != "a" {
+			t.Fatalf("Read over a lost connection = %q, %v", got, err)
+		}
+	}
+	if len(servers) != 3 || s.pool[1] == lost {
+		t.Errorf("lost connection not redialed: %d dials", len(servers))
+	}
+}
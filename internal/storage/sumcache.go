@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"strings"
+	"sync"
+)
+
+// sumCache remembers content checksums of remote files by path, keyed on a
+// stamp (ETag, or size and mtime) from the directory listing, so List only
+// downloads files that changed since the last call.
+type sumCache struct {
+	mu   sync.Mutex
+	sums map[string]stampedSum
+}
+
+type stampedSum struct {
+	stamp string
+	sum   string
+}
+
+// get returns the cached checksum of rel if its stamp is unchanged.
+func (c *sumCache) get(rel, stamp string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.sums[rel]
+	return s.sum, ok && s.stamp == stamp
+}
+
+// put records the checksum of rel at stamp.
+func (c *sumCache) put(rel, stamp, sum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sums == nil {
+		c.sums = make(map[string]stampedSum)
+	}
+	c.sums[rel] = stampedSum{stamp: stamp, sum: sum}
+}
+
+// forget drops cached checksums for rel and everything below it.
+func (c *sumCache) forget(rel string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p := range c.sums {
+		if p == rel || strings.HasPrefix(p, rel+"/") {
+			delete(c.sums, p)
+		}
+	}
+}
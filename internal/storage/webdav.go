@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/studio-b12/gowebdav"
+
+	"github.com/starford/kenaz/internal/checksum"
+	"github.com/starford/kenaz/internal/models"
+)
+
+// WebDAV implements Provider over a WebDAV collection, e.g. a Nextcloud or
+// ownCloud folder, so a remote vault can be served without a local sync.
+// There is no change notification over WebDAV; pair it with the polling
+// watcher (index.Poll).
+type WebDAV struct {
+	client    *gowebdav.Client
+	ignoreSet map[string]struct{}
+	sums      sumCache
+}
+
+// NewWebDAV creates a WebDAV provider rooted at the collection rawURL,
+// authenticating with user and password when user is non-empty. It checks
+// that the collection is reachable. ignoreDirs specifies directory base
+// names to exclude from listing.
+func NewWebDAV(rawURL, user, password string, ignoreDirs []string) (*WebDAV, error) {
+	c := gowebdav.NewClient(rawURL, user, password)
+	info, err := c.Stat("/")
+	if err != nil {
+		return nil, fmt.Errorf("storage: webdav root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("storage: webdav root is not a collection: %s", rawURL)
+	}
+	ignoreSet := make(map[string]struct{}, len(ignoreDirs))
+	for _, d := range ignoreDirs {
+		ignoreSet[d] = struct{}{}
+	}
+	return &WebDAV{client: c, ignoreSet: ignoreSet}, nil
+}
+
+// isIgnored returns true if the directory name should be skipped.
+func (w *WebDAV) isIgnored(name string) bool {
+	if ReservedDir(name) {
+		return true
+	}
+	_, ok := w.ignoreSet[name]
+	return ok
+}
+
+// safePath cleans a vault-relative path into a collection path, rejecting
+// absolute paths and any that escape the root.
+func (w *WebDAV) safePath(rel string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(rel, "\\", "/"))
+	switch {
+	case cleaned == ".":
+		return "/", nil
+	case path.IsAbs(cleaned):
+		return "", fmt.Errorf("storage: absolute paths not allowed: %s", rel)
+	case cleaned == ".." || strings.HasPrefix(cleaned, "../"):
+		return "", fmt.Errorf("storage: path escapes vault root: %s", rel)
+	}
+	return "/" + cleaned, nil
+}
+
+// wrapDAV annotates a client error, mapping 404 to fs.ErrNotExist so callers
+// can test for missing files as with the local provider.
+func wrapDAV(op, p string, err error) error {
+	if gowebdav.IsErrNotFound(err) {
+		return fmt.Errorf("storage: %s %s: %w", op, p, fs.ErrNotExist)
+	}
+	return fmt.Errorf("storage: %s %s: %w", op, p, err)
+}
+
+// walk calls fn for every file under dir (a collection path), descending
+// into subcollections for which descend returns true.
+func (w *WebDAV) walk(dir string, descend func(name string) bool, fn func(p string, info os.FileInfo) error) error {
+	entries, err := w.client.ReadDir(dir)
+	if err != nil {
+		return wrapDAV("list", dir, err)
+	}
+	for _, e := range entries {
+		p := path.Join(dir, e.Name())
+		if e.IsDir() {
+			if !descend(e.Name()) {
+				continue
+			}
+			if err := w.walk(p, descend, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(p, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List walks dir and returns metadata for every .md file. Only files whose
+// ETag (or size and mtime) changed since the last listing are downloaded
+// to compute their checksum.
+func (w *WebDAV) List(dir string) ([]models.NoteMetadata, error) {
+	return w.list(dir, true)
+}
+
+// ListStat is List without downloading any file: Checksum is left empty.
+func (w *WebDAV) ListStat(dir string) ([]models.NoteMetadata, error) {
+	return w.list(dir, false)
+}
+
+// list walks dir and returns metadata for every .md file, with checksums
+// when sum is set.
+func (w *WebDAV) list(dir string, sum bool) ([]models.NoteMetadata, error) {
+	base, err := w.safePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []models.NoteMetadata
+	err = w.walk(base, func(name string) bool { return !w.isIgnored(name) }, func(p string, info os.FileInfo) error {
+		if !strings.HasSuffix(p, ".md") {
+			return nil
+		}
+		rel := strings.TrimPrefix(p, "/")
+		m := models.NoteMetadata{Path: rel, UpdatedAt: info.ModTime(), Size: info.Size()}
+		if sum {
+			var err error
+			if m.Checksum, err = w.checksum(rel, info); err != nil {
+				return err
+			}
+		}
+		out = append(out, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// checksum returns the content checksum of rel, downloading it only when
+// its ETag (or size and mtime) changed.
+func (w *WebDAV) checksum(rel string, info os.FileInfo) (string, error) {
+	stamp := strconv.FormatInt(info.Size(), 10) + "@" + info.ModTime().UTC().String()
+	if f, ok := info.(gowebdav.File); ok && f.ETag() != "" {
+		stamp = f.ETag()
+	}
+	if sum, ok := w.sums.get(rel, stamp); ok {
+		return sum, nil
+	}
+	data, err := w.Read(rel)
+	if err != nil {
+		return "", err
+	}
+	sum := checksum.Sum(data)
+	w.sums.put(rel, stamp, sum)
+	return sum, nil
+}
+
+// Read returns the raw bytes of a vault file.
+func (w *WebDAV) Read(rel string) ([]byte, error) {
+	p, err := w.safePath(rel)
+	if err != nil {
+		return nil, err
+	}
+	data, err := w.client.Read(p)
+	if err != nil {
+		return nil, wrapDAV("read", rel, err)
+	}
+	return data, nil
+}
+
+// Write uploads content with a single PUT, creating parent collections as
+// needed. WebDAV servers replace the resource only once the upload is
+// complete, so readers never see a partial file.
+func (w *WebDAV) Write(rel string, content []byte) error {
+	p, err := w.safePath(rel)
+	if err != nil {
+		return err
+	}
+	w.sums.forget(strings.TrimPrefix(p, "/"))
+	if err := w.client.Write(p, content, 0o644); err != nil {
+		return wrapDAV("write", rel, err)
+	}
+	return nil
+}
+
+// Delete removes a file from the vault.
+func (w *WebDAV) Delete(rel string) error {
+	p, err := w.safePath(rel)
+	if err != nil {
+		return err
+	}
+	// DELETE succeeds on a missing resource; report it like the local
+	// provider does.
+	if _, err := w.client.Stat(p); err != nil {
+		return wrapDAV("delete", rel, err)
+	}
+	w.sums.forget(strings.TrimPrefix(p, "/"))
+	if err := w.client.Remove(p); err != nil {
+		return wrapDAV("delete", rel, err)
+	}
+	return nil
+}
+
+// DirExists reports whether the given relative path is an existing
+// collection.
+func (w *WebDAV) DirExists(rel string) (bool, error) {
+	p, err := w.safePath(rel)
+	if err != nil {
+		return false, err
+	}
+	info, err := w.client.Stat(p)
+	if err != nil {
+		if gowebdav.IsErrNotFound(err) {
+			return false, nil
+		}
+		return false, wrapDAV("stat dir", rel, err)
+	}
+	return info.IsDir(), nil
+}
+
+// DeleteDir removes a collection and all its contents from the vault.
+func (w *WebDAV) DeleteDir(rel string) error {
+	p, err := w.safePath(rel)
+	if err != nil {
+		return err
+	}
+	w.sums.forget(strings.TrimPrefix(p, "/"))
+	if err := w.client.RemoveAll(p); err != nil {
+		return wrapDAV("delete dir", rel, err)
+	}
+	return nil
+}
+
+// ListDirs returns all collection paths (relative to vault root).
+func (w *WebDAV) ListDirs() ([]string, error) {
+	var dirs []string
+	var visit func(dir string) error
+	visit = func(dir string) error {
+		entries, err := w.client.ReadDir(dir)
+		if err != nil {
+			return wrapDAV("list dirs", dir, err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() || w.isIgnored(e.Name()) {
+				continue
+			}
+			p := path.Join(dir, e.Name())
+			dirs = append(dirs, strings.TrimPrefix(p, "/"))
+			if err := visit(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit("/"); err != nil {
+		return nil, err
+	}
+	return dirs, nil
+}
+
+// ListFiles returns every file under dir (relative to root), skipping
+// TrashDir and StateDir.
+func (w *WebDAV) ListFiles(dir string) ([]string, error) {
+	base, err := w.safePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	err = w.walk(base, func(name string) bool { return !ReservedDir(name) }, func(p string, _ os.FileInfo) error {
+		out = append(out, strings.TrimPrefix(p, "/"))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Move renames a file within the vault with a WebDAV MOVE, creating the
+// destination's parent collections as needed.
+func (w *WebDAV) Move(oldPath, newPath string) error {
+	from, err := w.safePath(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := w.safePath(newPath)
+	if err != nil {
+		return err
+	}
+	if parent := path.Dir(to); parent != "/" {
+		if _, err := w.client.Stat(parent); gowebdav.IsErrNotFound(err) {
+			if err := w.client.MkdirAll(parent, 0o755); err != nil {
+				return wrapDAV("mkdir for move", newPath, err)
+			}
+		}
+	}
+	w.sums.forget(strings.TrimPrefix(from, "/"))
+	w.sums.forget(strings.TrimPrefix(to, "/"))
+	if err := w.client.Rename(from, to, true); err != nil {
+		return wrapDAV("move", oldPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+// tempDAV serves a temp dir over WebDAV and returns a provider for it, the
+// dir, and a counter of GET requests.
+func tempDAV(t *testing.T, ignoreDirs []string) (*WebDAV, string, *atomic.Int32) {
+	t.Helper()
+	dir := t.TempDir()
+	var gets atomic.Int32
+	h := &webdav.Handler{FileSystem: webdav.Dir(dir), LockSystem: webdav.NewMemLS()}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	s, err := NewWebDAV(ts.URL, "", "", ignoreDirs)
+	if err != nil {
+		t.Fatalf("NewWebDAV: %v", err)
+	}
+	return s, dir, &gets
+}
+
+func TestWebDAV_ReadWriteMoveDelete(t *testing.T) {
+	s, dir, _ := tempDAV(t, nil)
+	if err := s.Write("a/b/c.md", []byte("deep")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got, err := s.Read("a/b/c.md"); err != nil || string(got) != "deep" {
+		t.Fatalf("Read = %q, %v", got, err)
+	}
+	if ok, err := s.DirExists("a/b"); err != nil || !ok {
+		t.Errorf("DirExists(a/b) = %v, %v", ok, err)
+	}
+
+	if err := s.Move("a/b/c.md", "x/y.md"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x", "y.md")); err != nil {
+		t.Errorf("moved file missing: %v", err)
+	}
+	if _, err := s.Read("a/b/c.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Read after move: err = %v, want ErrNotExist", err)
+	}
+
+	if err := s.Delete("x/y.md"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete("x/y.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("second Delete: err = %v, want ErrNotExist", err)
+	}
+	if err := s.DeleteDir("a"); err != nil {
+		t.Fatalf("DeleteDir: %v", err)
+	}
+	if ok, _ := s.DirExists("a"); ok {
+		t.Error("dir still exists after DeleteDir")
+	}
+}
+
+func TestWebDAV_List(t *testing.T) {
+	s, _, gets := tempDAV(t, []string{"attachments"})
+	for _, p := range []string{"one.md", "sub/two.md", "attachments/skip.md", TrashDir + "/old.md", "sub/img.png"} {
+		if err := s.Write(p, []byte("# "+p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	metas, err := s.List("")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var paths []string
+	for _, m := range metas {
+		paths = append(paths, m.Path)
+	}
+	slices.Sort(paths)
+	if !slices.Equal(paths, []string{"one.md", "sub/two.md"}) {
+		t.Errorf("List = %v", paths)
+	}
+
+	// Unchanged files are not downloaded again.
+	before := gets.Load()
+	if _, err := s.List(""); err != nil {
+		t.Fatal(err)
+	}
+	if n := gets.Load() - before; n != 0 {
+		t.Errorf("second List downloaded %d files, want 0", n)
+	}
+	stats, err := s.ListStat("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range stats {
+		if m.Checksum != "" || m.Size == 0 {
+			t.Errorf("ListStat %s: checksum %q, size %d", m.Path, m.Checksum, m.Size)
+		}
+	}
+
+	dirs, err := s.ListDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(dirs, []string{"sub"}) {
+		t.Errorf("ListDirs = %v", dirs)
+	}
+	files, err := s.ListFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	if !slices.Equal(files, []string{"attachments/skip.md", "one.md", "sub/img.png", "sub/two.md"}) {
+		t.Errorf("ListFiles = %v", files)
+	}
+}
+
+func TestWebDAV_TraversalBlocked(t *testing.T) {
+	s, _, _ := tempDAV(t, nil)
+	for _, p := range []string{"../escape.md", "/etc/passwd", "a/../../x.md"} {
+		if err := s.Write(p, []byte("x")); err == nil {
+			t.Errorf("Write(%q) should fail", p)
+		}
+	}
+}
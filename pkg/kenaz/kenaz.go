@@ -0,0 +1,260 @@
+// Package kenaz embeds a Kenaz vault in a Go program: the same note
+// operations, full-text search, and link graph the HTTP and MCP servers
+// expose, without running either.
+//
+//	v, err := kenaz.Open("./vault")
+//	if err != nil { ... }
+//	defer v.Close()
+//	note, err := v.CreateNote(ctx, "ideas/kenaz.md", "# Kenaz\n\nSee [[runes]].\n")
+//	hits, err := v.Search(ctx, "runes", kenaz.SearchOptions{})
+//
+// A Vault may be used concurrently. Changes made to the files by other
+// programs are picked up by Sync, or continuously by Watch.
+package kenaz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/starford/kenaz/internal/apperr"
+	"github.com/starford/kenaz/internal/index"
+	"github.com/starford/kenaz/internal/noteservice"
+	"github.com/starford/kenaz/internal/parser"
+	"github.com/starford/kenaz/internal/storage"
+)
+
+// Types returned by Vault methods.
+type (
+	// Note is a note with its parsed metadata and backlinks.
+	Note = noteservice.NoteDetail
+	// NoteListItem is a note in a listing.
+	NoteListItem = noteservice.NoteListItem
+	// SearchOptions limits, pages, orders, and scopes a search.
+	SearchOptions = index.SearchOptions
+	// SearchResult is one full-text search hit.
+	SearchResult = index.SearchResult
+	// BacklinkRef is an incoming link with its location.
+	BacklinkRef = index.BacklinkRef
+	// GraphNode is a note in the link graph.
+	GraphNode = index.GraphNode
+	// GraphLink is a link between two notes.
+	GraphLink = index.GraphLink
+	// MetadataValue is a value found by an extractor and the notes it
+	// occurs in.
+	MetadataValue = index.MetadataValue
+	// Extractor derives metadata values from a parsed note; see
+	// WithExtractors.
+	Extractor = parser.Extractor
+	// ParseResult is the parsed note an Extractor inspects.
+	ParseResult = parser.Result
+	// FuncExtractor is an Extractor made from a name and a function.
+	FuncExtractor = parser.FuncExtractor
+)
+
+// Errors returned by Vault methods; test with errors.Is.
+var (
+	ErrNotFound      = apperr.ErrNotFound
+	ErrAlreadyExists = apperr.ErrAlreadyExists
+	ErrConflict      = apperr.ErrConflict
+	ErrInvalidPath   = apperr.ErrInvalidPath
+)
+
+// Option configures Open.
+type Option func(*options)
+
+type options struct {
+	indexPath   string
+	ignoreDirs  []string
+	strictLinks bool
+	linkFields  []string
+	extractors  []Extractor
+	logger      *slog.Logger
+}
+
+// WithIndexPath keeps the search index in the SQLite file at p, so
+// reopening the vault only re-indexes changed notes. By default the index
+// lives in memory and is rebuilt on every Open.
+func WithIndexPath(p string) Option {
+	return func(o *options) { o.indexPath = p }
+}
+
+// WithIgnoreDirs sets the directory names skipped when listing the vault
+// (default .git, .obsidian, attachments).
+func WithIgnoreDirs(dirs ...string) Option {
+	return func(o *options) { o.ignoreDirs = dirs }
+}
+
+// WithStrictLinks disables case- and spacing-tolerant wikilink matching.
+func WithStrictLinks(strict bool) Option {
+	return func(o *options) { o.strictLinks = strict }
+}
+
+// WithLinkFields sets the frontmatter fields whose values are indexed as
+// links (default related, parent, source).
+func WithLinkFields(fields ...string) Option {
+	return func(o *options) { o.linkFields = fields }
+}
+
+// WithExtractors indexes the values found by exts (e.g. ticket numbers or
+// people), queryable with Metadata.
+func WithExtractors(exts ...Extractor) Option {
+	return func(o *options) { o.extractors = exts }
+}
+
+// WithLogger receives index sync and watcher warnings (default: discarded).
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) { o.logger = l }
+}
+
+// Vault is an open Kenaz vault.
+type Vault struct {
+	root   string
+	store  storage.Provider
+	db     *index.DB
+	svc    *noteservice.Service
+	logger *slog.Logger
+}
+
+// Open opens the vault directory at vaultPath, creating it if needed, and
+// brings the index up to date.
+func Open(vaultPath string, opts ...Option) (*Vault, error) {
+	o := options{
+		indexPath:  ":memory:",
+		ignoreDirs: []string{".git", ".obsidian", "attachments"},
+		linkFields: []string{"related", "parent", "source"},
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := os.MkdirAll(vaultPath, 0o755); err != nil {
+		return nil, fmt.Errorf("kenaz: create vault dir: %w", err)
+	}
+	store, err := storage.NewFS(vaultPath, o.ignoreDirs)
+	if err != nil {
+		return nil, fmt.Errorf("kenaz: %w", err)
+	}
+	db, err := index.Open(o.indexPath,
+		index.WithStrictLinks(o.strictLinks),
+		index.WithLinkFields(o.linkFields),
+		index.WithExtractors(o.extractors),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("kenaz: %w", err)
+	}
+	v := &Vault{
+		root:   vaultPath,
+		store:  store,
+		db:     db,
+		svc:    noteservice.NewService(store, db),
+		logger: o.logger,
+	}
+	if err := v.Sync(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return v, nil
+}
+
+// Close closes the index.
+func (v *Vault) Close() error {
+	return v.db.Close()
+}
+
+// Sync re-indexes notes changed on disk since the last sync and drops
+// deleted ones.
+func (v *Vault) Sync() error {
+	if err := index.Sync(v.db, v.store, v.logger); err != nil {
+		return fmt.Errorf("kenaz: sync: %w", err)
+	}
+	return nil
+}
+
+// Watch keeps the index in sync with changes made to the vault by other
+// programs until ctx is cancelled. onChange, if non-nil, is called after
+// each change with kind "created", "updated", or "deleted".
+func (v *Vault) Watch(ctx context.Context, onChange func(kind, path string)) error {
+	return index.Watch(ctx, v.db, v.store, v.root, v.logger, onChange)
+}
+
+// Note returns the note at path.
+func (v *Vault) Note(ctx context.Context, path string) (*Note, error) {
+	return v.svc.GetNote(ctx, path)
+}
+
+// CreateNote writes a new note at path; it fails with ErrAlreadyExists if
+// one exists.
+func (v *Vault) CreateNote(ctx context.Context, path, content string) (*Note, error) {
+	return v.svc.CreateNote(ctx, path, []byte(content))
+}
+
+// CreateNoteTitled writes a new note under folder at an English kebab-case
+// file name derived from title (Cyrillic is transliterated), or from the
+// content's title when title is empty, and returns it with its path.
+func (v *Vault) CreateNoteTitled(ctx context.Context, title, folder, content string) (*Note, error) {
+	return v.svc.CreateNoteTitled(ctx, title, folder, []byte(content))
+}
+
+// UpdateNote replaces the content of the note at path. With a non-empty
+// checksum (from a previous read) it fails with ErrConflict if the note
+// has changed since.
+func (v *Vault) UpdateNote(ctx context.Context, path, content, checksum string) (*Note, error) {
+	return v.svc.UpdateNote(ctx, path, []byte(content), checksum)
+}
+
+// AppendNote appends content to the note at path, or to the end of its
+// section under heading if set.
+func (v *Vault) AppendNote(ctx context.Context, path, content, heading string) (*Note, error) {
+	return v.svc.AppendNote(ctx, path, content, heading)
+}
+
+// RenameNote moves a note and rewrites links to it across the vault.
+func (v *Vault) RenameNote(ctx context.Context, oldPath, newPath string) (*Note, error) {
+	return v.svc.RenameNote(ctx, oldPath, newPath)
+}
+
+// DeleteNote moves the note at path to the vault's trash.
+func (v *Vault) DeleteNote(ctx context.Context, path string) error {
+	_, err := v.svc.DeleteNote(ctx, path)
+	return err
+}
+
+// Notes lists notes, optionally only those with tag, sorted by sort
+// ("updated_at" (default), "created_at", "title", or "path"), returning a
+// page and the total count.
+func (v *Vault) Notes(ctx context.Context, tag, sort string, limit, offset int) ([]NoteListItem, int, error) {
+	return v.svc.ListNotes(ctx, limit, offset, tag, sort, noteservice.NoteFilter{})
+}
+
+// Search runs a full-text query over note titles and content, returning
+// the page of hits selected by opts.Limit and opts.Offset in opts.Sort order.
+func (v *Vault) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	hits, _, err := v.svc.Search(ctx, query, opts)
+	return hits, err
+}
+
+// Backlinks returns the links pointing at the note at path.
+func (v *Vault) Backlinks(ctx context.Context, path string) ([]BacklinkRef, error) {
+	return v.svc.BacklinkRefs(ctx, path)
+}
+
+// Graph returns the link graph: every note and every link between notes.
+func (v *Vault) Graph(ctx context.Context) ([]GraphNode, []GraphLink, error) {
+	return v.svc.Graph(ctx)
+}
+
+// Metadata returns the values extractor key found, with the notes each
+// occurs in, optionally only value.
+func (v *Vault) Metadata(ctx context.Context, key, value string) ([]MetadataValue, error) {
+	return v.svc.MetadataValues(ctx, key, value)
+}
+
+// DailyNote returns the daily note for day, creating it if needed.
+func (v *Vault) DailyNote(ctx context.Context, day time.Time) (*Note, error) {
+	return v.svc.DailyNote(ctx, day)
+}
@@ -0,0 +1,104 @@
+package kenaz
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestVault(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	v, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	if _, err := v.CreateNote(ctx, "a.md", "# Alpha\n\nSee [[b]] about runes.\n"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := v.CreateNote(ctx, "b.md", "# Beta\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.CreateNote(ctx, "b.md", "# Again\n"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("duplicate create: err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := v.UpdateNote(ctx, "b.md", "# Beta 2\n", "stale"); !errors.Is(err, ErrConflict) {
+		t.Errorf("stale update: err = %v, want ErrConflict", err)
+	}
+	if _, err := v.UpdateNote(ctx, "b.md", "# Beta 2\n", b.Checksum); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := v.Search(ctx, "runes", SearchOptions{})
+	if err != nil || len(hits) != 1 || hits[0].Path != "a.md" {
+		t.Errorf("Search = %+v, %v", hits, err)
+	}
+	refs, err := v.Backlinks(ctx, "b.md")
+	if err != nil || len(refs) != 1 || refs[0].Source != "a.md" {
+		t.Errorf("Backlinks = %+v, %v", refs, err)
+	}
+	nodes, links, err := v.Graph(ctx)
+	if err != nil || len(nodes) != 2 || len(links) != 1 {
+		t.Errorf("Graph = %d nodes, %d links, %v", len(nodes), len(links), err)
+	}
+
+	if err := v.DeleteNote(ctx, "b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Note(ctx, "b.md"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("read deleted: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestOpen_IndexPath(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(t.TempDir(), "kenaz.db")
+	if err := os.WriteFile(filepath.Join(dir, "n.md"), []byte("# Note\n\nkenaz embedding\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := Open(dir, WithIndexPath(dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Close()
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Fatalf("index file not created: %v", err)
+	}
+
+	v, err = Open(dir, WithIndexPath(dbPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+	hits, err := v.Search(context.Background(), "embedding", SearchOptions{})
+	if err != nil || len(hits) != 1 {
+		t.Errorf("Search after reopen = %+v, %v", hits, err)
+	}
+}
+
+func TestWithExtractors(t *testing.T) {
+	ctx := context.Background()
+	tickets := FuncExtractor{Key: "tickets", Fn: func(res *ParseResult) []string {
+		return regexp.MustCompile(`\bKNZ-\d+\b`).FindAllString(res.Body, -1)
+	}}
+	v, err := Open(t.TempDir(), WithExtractors(tickets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.Close()
+
+	if _, err := v.CreateNote(ctx, "a.md", "Fixes KNZ-12 and KNZ-7.\n"); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := v.Metadata(ctx, "tickets", "KNZ-7")
+	if err != nil || len(vals) != 1 || vals[0].Paths[0] != "a.md" {
+		t.Errorf("Metadata = %+v, %v", vals, err)
+	}
+}